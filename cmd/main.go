@@ -19,6 +19,7 @@ import (
 	"github.com/saasuke-labs/kotomi/pkg/logging"
 	"github.com/saasuke-labs/kotomi/pkg/moderation"
 	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // @title Kotomi API
@@ -77,6 +78,11 @@ func main() {
 	logger := slog.New(contextHandler)
 	slog.SetDefault(logger)
 
+	// Maintenance commands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-aggregates" {
+		os.Exit(rebuildAggregates(os.Args[2:], logger))
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -150,7 +156,7 @@ func main() {
 
 	// Initialize AI moderation
 	// Note: Moderation requires SQL database (not available with Firestore)
-	var moderationConfigStore *moderation.ConfigStore
+	var moderationConfigStore moderation.ConfigRepository
 	if sqlDB != nil {
 		moderationConfigStore = moderation.NewConfigStore(sqlDB)
 	}
@@ -171,14 +177,31 @@ func main() {
 	// Initialize notification queue
 	// Note: Notifications require SQL database (not available with Firestore)
 	var notificationQueue *notifications.Queue
+	notificationWorkersDone := make(chan struct{})
 	if sqlDB != nil {
 		notificationQueue = notifications.NewQueue(sqlDB, 30*time.Second, 10)
-		go notificationQueue.Start(ctx)
+		go func() {
+			defer close(notificationWorkersDone)
+			notificationQueue.StartWorkers(ctx, 4)
+		}()
 		logger.Info("notification queue processor started")
 	} else {
+		close(notificationWorkersDone)
 		logger.Warn("notification queue disabled - requires SQL database")
 	}
 
+	// Outbound webhooks are delivered in the background
+	// Note: Webhooks require SQL database (not available with Firestore)
+	var events *webhooks.Dispatcher
+	if sqlDB != nil {
+		events = webhooks.NewDispatcher(sqlDB)
+	}
+
+	// Purge expired kotomi-auth sessions in the background
+	if sqlDB != nil {
+		go auth.NewKotomiAuthStore(sqlDB).StartSessionJanitor(ctx, time.Hour)
+	}
+
 	// Create server configuration
 	cfg := server.Config{
 		CommentStore:          store,
@@ -188,6 +211,7 @@ func main() {
 		Moderator:             moderator,
 		ModerationConfigStore: moderationConfigStore,
 		NotificationQueue:     notificationQueue,
+		Events:                events,
 		Logger:                logger,
 	}
 
@@ -208,31 +232,38 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
-	// Start server in a goroutine
-	go func() {
-		logger.Info("server starting", "port", port, "address", "http://localhost:"+port)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	// Serve until SIGINT/SIGTERM, then drain in-flight requests
+	shutdownTimeout := server.ShutdownTimeoutFromEnv()
+	logger.Info("server starting", "port", port, "address", "http://localhost:"+port)
+	if err := server.ListenAndServe(ctx, httpServer, shutdownTimeout, logger); err != nil {
+		if ctx.Err() == nil {
 			logger.Error("server failed", "error", err)
 			log.Fatalf("Server failed: %v", err)
 		}
-	}()
-
-	// Wait for interrupt signal
-	<-ctx.Done()
+		logger.Error("server shutdown error", "error", err)
+	}
 	stop()
 
-	logger.Info("shutting down gracefully...")
-
-	// Create shutdown context with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// The HTTP server has stopped, so nothing else can reach the store
+	// once the notification workers are done
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
-	// Shutdown server
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		logger.Error("server shutdown error", "error", err)
+	// Let in-flight notification sends finish before closing the database
+	select {
+	case <-notificationWorkersDone:
+		logger.Info("notification workers stopped")
+	case <-shutdownCtx.Done():
+		logger.Warn("timed out waiting for notification workers")
+	}
+
+	// Webhook deliveries still retrying record their attempts in the database
+	if err := events.Wait(shutdownCtx); err != nil {
+		logger.Warn("timed out waiting for webhook deliveries")
 	}
 
 	// Close database connection
+	logger.Info("closing database")
 	if err := store.Close(); err != nil {
 		logger.Error("error closing database", "error", err)
 	}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+)
+
+// rebuildAggregates runs the rebuild-aggregates maintenance command, which
+// backfills the daily analytics aggregates of one site or every site, and
+// returns the process exit code
+func rebuildAggregates(args []string, logger *slog.Logger) int {
+	flags := flag.NewFlagSet("rebuild-aggregates", flag.ContinueOnError)
+	siteID := flags.String("site", "", "ID of the site to rebuild (default: every site)")
+	fromFlag := flags.String("from", "", "first day to rebuild, YYYY-MM-DD (default: every day before -to)")
+	toFlag := flags.String("to", "", "last day to rebuild, YYYY-MM-DD (default: today)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var from, to time.Time
+	var err error
+	if *fromFlag != "" {
+		if from, err = time.Parse("2006-01-02", *fromFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -from date: %v\n", err)
+			return 2
+		}
+	}
+	if *toFlag != "" {
+		if to, err = time.Parse("2006-01-02", *toFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -to date: %v\n", err)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbConfig := db.ConfigFromEnv()
+	store, err := db.NewStore(ctx, dbConfig)
+	if err != nil {
+		logger.Error("failed to initialize database store", "error", err, "provider", dbConfig.Provider)
+		return 1
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	if sqlDB == nil {
+		logger.Error("analytics aggregates require a SQL database", "provider", dbConfig.Provider)
+		return 1
+	}
+
+	siteIDs := []string{*siteID}
+	if *siteID == "" {
+		rows, err := sqlDB.QueryContext(ctx, `SELECT id FROM sites ORDER BY id`)
+		if err != nil {
+			logger.Error("failed to list sites", "error", err)
+			return 1
+		}
+		siteIDs = nil
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				logger.Error("failed to list sites", "error", err)
+				return 1
+			}
+			siteIDs = append(siteIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			logger.Error("failed to list sites", "error", err)
+			return 1
+		}
+	}
+
+	analyticsStore := analytics.NewStore(sqlDB)
+	analyticsStore.SetLogger(logger)
+	failed := 0
+	for _, id := range siteIDs {
+		started := time.Now()
+		if err := analyticsStore.RebuildAggregates(ctx, id, from, to); err != nil {
+			logger.Error("failed to rebuild analytics aggregates", "site_id", id, "error", err)
+			failed++
+			continue
+		}
+		logger.Info("rebuilt analytics aggregates", "site_id", id, "duration_ms", float64(time.Since(started).Microseconds())/1000)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
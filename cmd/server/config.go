@@ -9,6 +9,7 @@ import (
 	"github.com/saasuke-labs/kotomi/pkg/db"
 	"github.com/saasuke-labs/kotomi/pkg/moderation"
 	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // Config holds the configuration for creating a Server
@@ -18,7 +19,8 @@ type Config struct {
 	Templates             *template.Template
 	Auth0Config           *auth.Auth0Config
 	Moderator             moderation.Moderator
-	ModerationConfigStore *moderation.ConfigStore
+	ModerationConfigStore moderation.ConfigRepository
 	NotificationQueue     *notifications.Queue
+	Events                *webhooks.Dispatcher // Optional; delivers comment and reaction webhooks
 	Logger                *slog.Logger
 }
@@ -226,13 +226,3 @@ func (s *ServerHandlers) ShowLoginPage(w http.ResponseWriter, r *http.Request) {
 		apierrors.WriteError(w, apierrors.InternalServerError("Template error").WithRequestID(middleware.GetRequestID(r)))
 	}
 }
-
-// DeprecationMiddleware adds deprecation headers to legacy API routes
-func DeprecationMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Deprecation", "true")
-		w.Header().Set("Sunset", "2027-12-31")
-		w.Header().Set("Link", `</api/v1>; rel="alternate"`)
-		next.ServeHTTP(w, r)
-	})
-}
@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
 	"github.com/saasuke-labs/kotomi/pkg/comments"
 	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
 	"github.com/saasuke-labs/kotomi/pkg/logging"
@@ -15,6 +22,7 @@ import (
 	"github.com/saasuke-labs/kotomi/pkg/models"
 	"github.com/saasuke-labs/kotomi/pkg/moderation"
 	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // PostComments creates a new comment for a page
@@ -26,9 +34,11 @@ import (
 // @Param siteId path string true "Site ID"
 // @Param pageId path string true "Page ID"
 // @Param comment body comments.Comment true "Comment to create"
+// @Param Idempotency-Key header string false "Client-generated key; replays within an hour return the original comment"
 // @Success 200 {object} comments.Comment
 // @Failure 400 {string} string "Invalid JSON or missing required fields"
 // @Failure 401 {string} string "Authentication required"
+// @Failure 409 {string} string "Idempotency-Key still being processed"
 // @Failure 500 {string} string "Failed to add comment"
 // @Security BearerAuth
 // @Router /site/{siteId}/page/{pageId}/comments [post]
@@ -60,61 +70,259 @@ func (s *ServerHandlers) PostComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Authors blocked by the site owner can't post new comments
+	if s.DB != nil {
+		blocked, err := models.NewBlockedAuthorStore(s.DB).IsBlocked(ctx, siteId, user.ID)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to check blocked authors", "error", err)
+			apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to create comment"), middleware.GetRequestID(r))
+			return
+		}
+		if blocked {
+			apierrors.WriteErrorWithRequestID(w, apierrors.Forbidden("You are blocked from commenting on this site"), middleware.GetRequestID(r))
+			return
+		}
+	}
+
 	// Decode body as a Comment
 	var comment comments.Comment
 	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
 		apierrors.WriteErrorWithRequestID(w, apierrors.InvalidJSON("Invalid JSON format").WithDetails(err.Error()), middleware.GetRequestID(r))
 		return
 	}
+	// Moderation fields are decided here, never by the client
+	comment.Status = ""
+	comment.ModeratedBy = ""
+	comment.ModeratedAt = time.Time{}
+	comment.Sentiment = ""
 	
 	// Validate required fields
 	if comment.Text == "" {
 		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("Text is required"), middleware.GetRequestID(r))
 		return
 	}
-	
+
+	// Apply the site's content policy so disallowed markup is never stored,
+	// then enforce its maximum comment length
+	settings := s.siteSettings(ctx, siteId)
+	comment.Text = comments.PrepareText(comment.Text, settings.ContentPolicy)
+	if strings.TrimSpace(comment.Text) == "" {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("Text is required"), middleware.GetRequestID(r))
+		return
+	}
+	if settings.ExceedsMaxCommentLength(comment.Text) {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError(
+			fmt.Sprintf("Text exceeds maximum length of %d characters", settings.MaxCommentLength)), middleware.GetRequestID(r))
+		return
+	}
+
+	if err := comments.ValidateAttachments(comment.Attachments); err != nil {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("Invalid attachments").WithDetails(err.Error()), middleware.GetRequestID(r))
+		return
+	}
+
+	// Tag the comment with its language so owners can filter by it
+	comment.Language = comments.DetectLanguage(comment.Text)
+
 	// Set user information from authenticated user
 	comment.ID = uuid.NewString()
+
+	// Replayed Idempotency-Keys return the comment created by the first request
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	var idempotency *comments.IdempotencyStore
+	if idempotencyKey != "" && s.DB != nil {
+		if err := comments.ValidateIdempotencyKey(idempotencyKey); err != nil {
+			apierrors.WriteErrorWithRequestID(w, apierrors.BadRequest("Invalid Idempotency-Key header").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
+
+		idempotency = comments.NewIdempotencyStore(s.DB, comments.DefaultIdempotencyTTL)
+		existingID, reserved, err := idempotency.Reserve(ctx, siteId, user.ID, idempotencyKey, comment.ID)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to reserve idempotency key", "error", err)
+			apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to add comment").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
+		if !reserved {
+			existing, err := s.CommentStore.GetCommentByIDForSite(ctx, existingID, siteId)
+			if err != nil || existing == nil {
+				// The first request hasn't stored its comment yet
+				apierrors.WriteErrorWithRequestID(w, apierrors.Conflict("A request with this Idempotency-Key is still being processed"), middleware.GetRequestID(r))
+				return
+			}
+			s.Logger.InfoContext(ctx, "replayed idempotent comment request", "comment_id", existing.ID)
+			w.Header().Set("Idempotent-Replayed", "true")
+			s.WriteJsonResponse(w, existing)
+			return
+		}
+	}
+
+	// Authors must wait out the site's cooldown between comments
+	if remaining := s.commentCooldownRemaining(ctx, siteId, user.ID, settings); remaining > 0 {
+		if idempotency != nil {
+			if err := idempotency.Release(ctx, siteId, user.ID, idempotencyKey); err != nil {
+				s.Logger.WarnContext(ctx, "failed to release idempotency key", "error", err)
+			}
+		}
+		retryAfter := int(math.Ceil(remaining.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		apierrors.WriteErrorWithRequestID(w, apierrors.RateLimitExceeded(
+			fmt.Sprintf("You are commenting too quickly. Please try again in %d seconds.", retryAfter)), middleware.GetRequestID(r))
+		return
+	}
+
 	comment.AuthorID = user.ID
 	comment.Author = user.Name
 	comment.AuthorEmail = user.Email
 	comment.CreatedAt = time.Now()
 	comment.UpdatedAt = time.Now()
+	for i := range comment.Attachments {
+		comment.Attachments[i].ID = uuid.NewString()
+		comment.Attachments[i].CommentID = comment.ID
+		comment.Attachments[i].MimeType = strings.ToLower(comment.Attachments[i].MimeType)
+		comment.Attachments[i].CreatedAt = comment.CreatedAt
+	}
 
 	// Enrich context with comment_id for logging
 	ctx = logging.WithCommentID(ctx, comment.ID)
 
-	// Apply AI moderation if enabled
+	// Apply the site's word blocklist and link rule, then AI moderation if enabled
+	var moderationEvent *moderation.Event
 	if s.Moderator != nil && s.ModerationConfigStore != nil {
 		config, err := s.ModerationConfigStore.GetBySiteID(ctx, siteId)
-		if err == nil && config != nil && config.Enabled {
-			// Analyze comment with AI moderation
-			result, err := s.Moderator.AnalyzeComment(comment.Text, *config)
-			if err != nil {
+		if err != nil {
+			config = nil
+		}
+
+		blockedWord, blocked, err := s.ModerationConfigStore.MatchBlockedWords(ctx, siteId, comment.Text)
+		if err != nil {
+			s.Logger.WarnContext(ctx, "blocked word check failed", "error", err)
+		} else if blocked {
+			comment.Status = moderation.BlockedWordActionReject
+			if config != nil {
+				comment.Status = config.BlockedWordAction
+			}
+			s.Logger.InfoContext(ctx, "comment contains blocked word",
+				"word", blockedWord,
+				"status", comment.Status)
+			moderationEvent = &moderation.Event{
+				Decision: comment.Status,
+				Source:   moderation.SourceBlocklist,
+				Reason:   fmt.Sprintf("Contains blocked word %q", blockedWord),
+			}
+		}
+
+		// Link-heavy comments are caught without an AI call
+		linkHeld := false
+		if !blocked {
+			linkConfig := moderation.DefaultModerationConfig()
+			if config != nil {
+				linkConfig = *config
+			}
+			if reason, tripped := moderation.CheckLinks(comment.Text, linkConfig.MaxLinks); tripped {
+				comment.Status = linkConfig.LinkAction
+				linkHeld = comment.Status == moderation.BlockedWordActionFlag
+				s.Logger.InfoContext(ctx, "comment tripped link rule",
+					"reason", reason,
+					"status", comment.Status)
+				moderationEvent = &moderation.Event{
+					Decision: comment.Status,
+					Source:   moderation.SourceLinks,
+					Reason:   reason,
+				}
+			}
+		}
+
+		// A rejection by either rule is final; a comment held for its links can
+		// still be rejected by AI moderation but not approved
+		if !blocked && comment.Status != moderation.BlockedWordActionReject && config != nil && config.Enabled {
+			// Authors with a proven track record skip the AI call entirely;
+			// everyone else is analyzed with AI moderation
+			if score, trusted := s.trustedAuthorScore(ctx, siteId, user.ID, *config); trusted && !linkHeld {
+				comment.Status = "approved"
+				s.Logger.InfoContext(ctx, "trusted author skipped AI moderation",
+					"reputation_score", score,
+					"threshold", config.TrustedReputationThreshold)
+				moderationEvent = &moderation.Event{
+					Decision: comment.Status,
+					Source:   moderation.SourceReputation,
+					Reason:   fmt.Sprintf("Author reputation %d exceeds trusted threshold %d", score, config.TrustedReputationThreshold),
+				}
+			} else if result, err := s.analyzeComment(r, siteId, comment, *config); err != nil {
 				s.Logger.ErrorContext(ctx, "AI moderation failed", "error", err)
 				// Continue with default status on error
 			} else {
-				// Determine status based on moderation result
-				comment.Status = moderation.DetermineStatus(result, *config)
 				s.Logger.InfoContext(ctx, "AI moderation completed",
 					"decision", result.Decision,
 					"confidence", result.Confidence,
 					"reason", result.Reason)
+				comment.Sentiment = moderation.NormalizeSentiment(result.Sentiment)
+				// Determine status based on moderation result
+				if status := moderation.DetermineStatus(result, *config); !linkHeld || status == "rejected" {
+					comment.Status = status
+					moderationEvent = &moderation.Event{
+						Decision:   comment.Status,
+						Source:     moderation.SourceAI,
+						Confidence: &result.Confidence,
+						Reason:     result.Reason,
+					}
+				}
 			}
 		}
 	}
 
-	// Set default status if not set by moderation
+	// Comments moderation didn't decide get the site's default status
 	if comment.Status == "" {
-		comment.Status = "pending"
+		comment.Status = settings.DefaultCommentStatus
 	}
 
 	if err := s.CommentStore.AddPageComment(ctx, siteId, pageId, comment); err != nil {
+		if idempotency != nil {
+			if releaseErr := idempotency.Release(ctx, siteId, user.ID, idempotencyKey); releaseErr != nil {
+				s.Logger.WarnContext(ctx, "failed to release idempotency key", "error", releaseErr)
+			}
+		}
+		if errors.Is(err, comments.ErrInvalidParent) {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("Invalid parent comment").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
+		if errors.Is(err, comments.ErrInvalidAttachment) {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("Invalid attachments").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
 		s.Logger.ErrorContext(ctx, "failed to add comment", "error", err)
 		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to add comment").WithDetails(err.Error()), middleware.GetRequestID(r))
 		return
 	}
 
+	s.AnalyticsCache.InvalidateSite(siteId)
+	s.Events.Dispatch(siteId, webhooks.EventCommentCreated, webhooks.NewCommentData(comment, pageId))
+
+	// Track the author as a commenter on the site for analytics and verification
+	if s.DB != nil {
+		if err := models.NewUserStore(s.DB).RecordCommenter(ctx, siteId, user.ID, user.Name, user.Email); err != nil {
+			s.Logger.WarnContext(ctx, "failed to record commenter", "error", err)
+		}
+		// Commenting follows the page unless the author unsubscribed from it
+		if settings.AutoSubscribe {
+			if err := notifications.NewSubscriptionStore(s.DB).SubscribeImplicitly(siteId, pageId, user.ID); err != nil {
+				s.Logger.WarnContext(ctx, "failed to subscribe author to page", "error", err)
+			}
+		}
+	}
+
+	if moderationEvent != nil && s.DB != nil {
+		moderationEvent.CommentID = comment.ID
+		moderationEvent.SiteID = siteId
+		if err := moderation.NewEventStore(s.DB).Record(ctx, *moderationEvent); err != nil {
+			s.Logger.WarnContext(ctx, "failed to record moderation event", "error", err)
+		}
+		// Automatic decisions count towards the author's reputation like manual ones
+		if err := models.NewReputationStore(s.DB).ApplyStatusChange(ctx, siteId, user.ID, "", comment.Status); err != nil {
+			s.Logger.WarnContext(ctx, "failed to update reputation", "error", err)
+		}
+	}
+
 	// Enqueue notification for new comment (if notifications are enabled)
 	if s.NotificationQueue != nil {
 		// Get site and page info for notification
@@ -127,26 +335,37 @@ func (s *ServerHandlers) PostComments(w http.ResponseWriter, r *http.Request) {
 				// Get notification settings
 				notifStore := notifications.NewStore(s.DB)
 				settings, err := notifStore.GetSettings(siteId)
-				if err == nil && settings != nil && settings.Enabled && settings.NotifyNewComment {
+				if err == nil && settings != nil && settings.Enabled {
 					// Build comment URL (placeholder - should be configured per site)
 					commentURL := fmt.Sprintf("%s?comment=%s", page.Path, comment.ID)
-					unsubscribeURL := fmt.Sprintf("/unsubscribe?site=%s", siteId)
-					
-					// Enqueue notification
-					err = s.NotificationQueue.EnqueueNewComment(
-						siteId,
-						site.Name,
-						page.Title,
-						commentURL,
-						comment.Author,
-						comment.Text,
-						settings.OwnerEmail,
-						unsubscribeURL,
-					)
-					if err != nil {
-						s.Logger.WarnContext(ctx, "failed to enqueue notification", "error", err)
-					} else {
-						s.Logger.InfoContext(ctx, "enqueued new comment notification")
+
+					if settings.NewCommentMode() == notifications.NewCommentModeImmediate {
+						// Enqueue notification
+						err = s.NotificationQueue.EnqueueNewComment(
+							siteId,
+							site.Name,
+							page.Title,
+							commentURL,
+							comment.Author,
+							comment.Text,
+							settings.OwnerEmail,
+						)
+						if err != nil {
+							s.Logger.WarnContext(ctx, "failed to enqueue notification", "error", err)
+						} else {
+							s.Logger.InfoContext(ctx, "enqueued new comment notification")
+						}
+					}
+
+					if settings.NotifyReply {
+						notified := s.enqueueReplyNotifications(ctx, siteId, page.Title, commentURL, comment)
+						// Comments held for review notify subscribers once approved
+						if comment.Status == "approved" {
+							if err := s.NotificationQueue.EnqueuePageSubscribers(siteId, pageId, page.Title, commentURL,
+								comment.AuthorID, comment.Author, comment.Text, notified); err != nil {
+								s.Logger.WarnContext(ctx, "failed to enqueue subscriber notifications", "error", err)
+							}
+						}
 					}
 				}
 			}
@@ -156,18 +375,85 @@ func (s *ServerHandlers) PostComments(w http.ResponseWriter, r *http.Request) {
 	s.WriteJsonResponse(w, comment)
 }
 
+// enqueueReplyNotifications notifies the author of the parent comment and any
+// @mentioned users of the site. Each address is notified at most once and the
+// commenter never notifies themselves; unsubscribed recipients are skipped by
+// the queue. It returns the normalized addresses handled, the commenter's included.
+func (s *ServerHandlers) enqueueReplyNotifications(ctx context.Context, siteID, pageTitle, commentURL string, comment comments.Comment) map[string]bool {
+	notified := map[string]bool{strings.ToLower(strings.TrimSpace(comment.AuthorEmail)): true}
+	notify := func(email, originalText string) {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if key == "" || notified[key] {
+			return
+		}
+		notified[key] = true
+		if err := s.NotificationQueue.EnqueueCommentReply(siteID, pageTitle, commentURL, comment.Author, comment.Text, originalText, email); err != nil {
+			s.Logger.WarnContext(ctx, "failed to enqueue reply notification", "error", err)
+		}
+	}
+
+	if comment.ParentID != "" {
+		parent, err := s.CommentStore.GetCommentByIDForSite(ctx, comment.ParentID, siteID)
+		if err != nil {
+			s.Logger.WarnContext(ctx, "failed to load parent comment for notification", "parent_id", comment.ParentID, "error", err)
+		} else if parent.AuthorID != comment.AuthorID {
+			notify(parent.AuthorEmail, parent.Text)
+		}
+	}
+
+	mentions := comments.ParseMentions(comment.Text)
+	if len(mentions) == 0 || s.DB == nil {
+		return notified
+	}
+	users, err := models.NewUserStore(s.DB).ListBySite(ctx, siteID)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to load users for mentions", "error", err)
+		return notified
+	}
+	byHandle := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		if handle := comments.MentionHandle(u.Name); handle != "" {
+			byHandle[handle] = u
+		}
+	}
+	for _, name := range mentions {
+		if u, ok := byHandle[name]; ok && u.ID != comment.AuthorID {
+			notify(u.Email, "")
+		}
+	}
+	return notified
+}
+
 // GetComments retrieves all comments for a page
 // @Summary Get comments for a page
-// @Description Retrieve all comments for a specific page
+// @Description Retrieve the approved comments for a specific page, each with its reaction counts. The site owner also sees unapproved comments.
 // @Tags comments
 // @Produce json
 // @Param siteId path string true "Site ID"
 // @Param pageId path string true "Page ID"
-// @Success 200 {array} comments.Comment
-// @Failure 400 {string} string "Invalid URL"
-// @Failure 500 {string} string "Failed to retrieve comments"
+// @Param render query string false "Set to html to include rendered_html"
+// @Param order query string false "oldest (default), newest or most_reacted"
+// @Param since query string false "RFC 3339 time; only comments created or updated after it are returned, wrapped in a CommentsSinceResponse"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Success 200 {array} CommentResponse
+// @Success 304 {string} string "Not modified"
+// @Failure 400 {string} string "Invalid URL or since"
+// @Failure 500 {string} string "Failed to retrieve comments or reaction counts"
 // @Router /site/{siteId}/page/{pageId}/comments [get]
 func (s *ServerHandlers) GetComments(w http.ResponseWriter, r *http.Request) {
+	s.getComments(w, r, false)
+}
+
+// GetCommentsV2 retrieves a page's comments like GetComments, but wraps the
+// list in a CommentsSinceResponse so clients can start polling with ?since=
+// from the first load
+func (s *ServerHandlers) GetCommentsV2(w http.ResponseWriter, r *http.Request) {
+	s.getComments(w, r, true)
+}
+
+// getComments writes a page's comments, as a bare list or, when wrapped is
+// set, together with the server time
+func (s *ServerHandlers) getComments(w http.ResponseWriter, r *http.Request, wrapped bool) {
 	vars := mux.Vars(r)
 	ctx := r.Context()
 	
@@ -188,14 +474,478 @@ func (s *ServerHandlers) GetComments(w http.ResponseWriter, r *http.Request) {
 	ctx = logging.WithSiteID(ctx, siteId)
 	ctx = logging.WithPageID(ctx, pageId)
 	
-	commentsData, err := s.CommentStore.GetPageComments(ctx, siteId, pageId)
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = comments.OrderOldest
+	}
+	if !comments.IsValidOrder(order) {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("order must be oldest, newest or most_reacted"), middleware.GetRequestID(r))
+		return
+	}
+
+	// Only the site owner sees comments that haven't been approved
+	ownerView := s.isSiteOwner(r, siteId)
+
+	settings := s.siteSettings(ctx, siteId)
+	render := ""
+	if wantsRenderedHTML(r) {
+		render = settings.ContentPolicy
+	}
+	gravatar := ""
+	if settings.GravatarEnabled {
+		gravatar = settings.GravatarStyle
+	}
+
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("since must be an RFC 3339 time").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
+		s.getCommentsSince(ctx, w, r, siteId, pageId, since, ownerView, settings, render)
+		return
+	}
+
+	// Taken before the query, like a poll's, so a wrapped list can seed since
+	serverTime := time.Now().UTC()
+
+	// Let polling clients revalidate without the comments being loaded
+	etag, lastModified, err := s.pageCommentsValidator(ctx, siteId, pageId, order, ownerView, render, gravatar, wrapped)
 	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to compute comments ETag", "error", err)
+	} else if writeCacheHeaders(w, r, etag, lastModified, ownerView) {
+		return
+	}
+
+	commentsData, err := s.CommentStore.GetPageCommentsOrdered(ctx, siteId, pageId, order)
+	if err != nil {
+		if errors.Is(err, comments.ErrInvalidOrder) {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("Unsupported comment order").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
 		s.Logger.ErrorContext(ctx, "failed to retrieve comments", "error", err)
 		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to retrieve comments").WithDetails(err.Error()), middleware.GetRequestID(r))
 		return
 	}
+	if !ownerView {
+		commentsData = comments.ApprovedThread(commentsData)
+	}
+
+	response, err := s.withReactionCounts(ctx, commentsData)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to retrieve reaction counts", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to retrieve reaction counts"), middleware.GetRequestID(r))
+		return
+	}
+	decorateComments(response, settings, render)
+
+	if wrapped {
+		s.WriteJsonResponse(w, CommentsSinceResponse{Comments: response, ServerTime: serverTime})
+		return
+	}
+	s.WriteJsonResponse(w, response)
+}
+
+// CommentsSinceResponse holds the comments changed since the requested time,
+// or a v2 page's full list. Clients pass ServerTime as the next since, so their
+// own clock never matters.
+type CommentsSinceResponse struct {
+	Comments   []CommentResponse `json:"comments"`
+	ServerTime time.Time         `json:"server_time"`
+}
+
+// getCommentsSince writes the page's comments created or updated after since,
+// oldest first. Viewers other than the site owner only get approved comments,
+// threaded under their nearest approved ancestor as in the full list.
+func (s *ServerHandlers) getCommentsSince(ctx context.Context, w http.ResponseWriter, r *http.Request, siteID, pageID string, since time.Time, ownerView bool, settings *models.SiteSettings, render string) {
+	// Taken before the query, so a comment written while it runs is
+	// returned by the next poll rather than missed
+	serverTime := time.Now().UTC()
+
+	changed, err := s.CommentStore.GetPageCommentsSince(ctx, siteID, pageID, since)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to retrieve comments", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to retrieve comments").WithDetails(err.Error()), middleware.GetRequestID(r))
+		return
+	}
+
+	if !ownerView {
+		changed, err = s.approvedSince(ctx, siteID, pageID, changed)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to retrieve comments", "error", err)
+			apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to retrieve comments").WithDetails(err.Error()), middleware.GetRequestID(r))
+			return
+		}
+	}
+
+	response, err := s.withReactionCounts(ctx, changed)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to retrieve reaction counts", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to retrieve reaction counts"), middleware.GetRequestID(r))
+		return
+	}
+	decorateComments(response, settings, render)
+
+	w.Header().Set("Cache-Control", "no-store")
+	s.WriteJsonResponse(w, CommentsSinceResponse{Comments: response, ServerTime: serverTime})
+}
+
+// approvedSince keeps the approved comments in changed. Replies are
+// re-threaded against the whole page, since their ancestors usually predate
+// since; the page is only loaded when there is a reply to re-thread.
+func (s *ServerHandlers) approvedSince(ctx context.Context, siteID, pageID string, changed []comments.Comment) ([]comments.Comment, error) {
+	approved := []comments.Comment{}
+	hasReply := false
+	for _, c := range changed {
+		if c.Status == "approved" {
+			approved = append(approved, c)
+			hasReply = hasReply || c.ParentID != ""
+		}
+	}
+	if !hasReply {
+		return approved, nil
+	}
+
+	all, err := s.CommentStore.GetPageComments(ctx, siteID, pageID)
+	if err != nil {
+		return nil, err
+	}
+	parents := map[string]string{}
+	for _, c := range comments.ApprovedThread(all) {
+		parents[c.ID] = c.ParentID
+	}
+	for i := range approved {
+		approved[i].ParentID = parents[approved[i].ID]
+	}
+	return approved, nil
+}
+
+// decorateComments fills in each comment's avatar and, when render is set,
+// its rendered HTML
+func decorateComments(response []CommentResponse, settings *models.SiteSettings, render string) {
+	for i := range response {
+		response[i].Avatar = settings.AvatarFor(response[i].AuthorAvatarURL, response[i].AuthorEmail)
+		if render != "" {
+			response[i].RenderedHTML = comments.RenderHTML(response[i].Text, render)
+		}
+	}
+}
+
+// withReactionCounts pairs each comment with its reaction counts, loaded in a
+// single query. Without a SQL database every comment gets an empty list.
+func (s *ServerHandlers) withReactionCounts(ctx context.Context, commentsData []comments.Comment) ([]CommentResponse, error) {
+	counts := map[string][]models.ReactionCount{}
+	if s.DB != nil {
+		ids := make([]string, len(commentsData))
+		for i, c := range commentsData {
+			ids[i] = c.ID
+		}
+		var err error
+		counts, err = models.NewReactionStore(s.DB).GetReactionCountsForComments(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response := make([]CommentResponse, len(commentsData))
+	for i, c := range commentsData {
+		reactions := counts[c.ID]
+		if reactions == nil {
+			reactions = []models.ReactionCount{}
+		}
+		response[i] = CommentResponse{Comment: c, Reactions: reactions}
+	}
+	return response, nil
+}
+
+// CommentResponse is a single comment together with its reaction counts
+type CommentResponse struct {
+	comments.Comment
+	Reactions []models.ReactionCount `json:"reactions"`
+	// RenderedHTML is the comment's Markdown rendered to sanitized HTML, only
+	// present when the request asks for it with ?render=html
+	RenderedHTML string `json:"rendered_html,omitempty"`
+	// Avatar is the author's avatar_url, or a Gravatar for their email when
+	// they have none and the site allows it
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// wantsRenderedHTML reports whether the request asked for rendered comment HTML
+func wantsRenderedHTML(r *http.Request) bool {
+	return r.URL.Query().Get("render") == "html"
+}
+
+// GetComment retrieves a single comment with its reaction counts
+// @Summary Get a comment
+// @Description Retrieve a single comment and its reaction counts. Pending comments are only visible to their author or the site owner.
+// @Tags comments
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param commentId path string true "Comment ID"
+// @Param render query string false "Set to html to include rendered_html"
+// @Success 200 {object} CommentResponse
+// @Failure 404 {string} string "Comment not found"
+// @Failure 500 {string} string "Failed to retrieve reaction counts"
+// @Router /site/{siteId}/comments/{commentId} [get]
+func (s *ServerHandlers) GetComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["commentId"]
+	siteID := vars["siteId"]
+
+	// Enrich context with site_id and comment_id for automatic logging
+	ctx := r.Context()
+	ctx = logging.WithSiteID(ctx, siteID)
+	ctx = logging.WithCommentID(ctx, commentID)
+
+	comment, err := s.CommentStore.GetCommentByIDForSite(ctx, commentID, siteID)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	// Unapproved comments are indistinguishable from missing ones for everyone
+	// but their author and the site owner
+	if comment.Status != "approved" && !s.canViewPendingComment(r, comment) {
+		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	counts := []models.ReactionCount{}
+	if s.DB != nil {
+		counts, err = models.NewReactionStore(s.DB).GetReactionCounts(ctx, commentID)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to retrieve reaction counts", "error", err)
+			apierrors.WriteError(w, apierrors.DatabaseError("Failed to retrieve reaction counts").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
+		if counts == nil {
+			counts = []models.ReactionCount{}
+		}
+	}
+
+	settings := s.siteSettings(ctx, siteID)
+	response := CommentResponse{Comment: *comment, Reactions: counts}
+	response.Avatar = settings.AvatarFor(comment.AuthorAvatarURL, comment.AuthorEmail)
+	if wantsRenderedHTML(r) {
+		response.RenderedHTML = comments.RenderHTML(comment.Text, settings.ContentPolicy)
+	}
+	s.WriteJsonResponse(w, response)
+}
+
+// defaultMyCommentsPageSize and maxMyCommentsPageSize bound the limit
+// parameter of GetMyComments
+const (
+	defaultMyCommentsPageSize = 20
+	maxMyCommentsPageSize     = 100
+)
+
+// MyCommentsResponse is a page of the requesting user's comments on a site
+type MyCommentsResponse struct {
+	Comments []comments.Comment `json:"comments"`
+	Limit    int                `json:"limit"`
+	Offset   int                `json:"offset"`
+}
+
+// GetMyComments lists the authenticated user's comments on a site
+// @Summary List my comments
+// @Description List the authenticated user's comments on a site across all pages, newest first, with each comment's page path. Pending and rejected comments are included.
+// @Tags comments
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of comments to skip"
+// @Success 200 {object} MyCommentsResponse
+// @Failure 400 {string} string "Invalid limit or offset"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 500 {string} string "Failed to retrieve comments"
+// @Security BearerAuth
+// @Router /site/{siteId}/users/me/comments [get]
+func (s *ServerHandlers) GetMyComments(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	ctx := logging.WithSiteID(r.Context(), siteID)
+
+	user := middleware.GetUserFromContext(ctx)
+	if user == nil {
+		apierrors.WriteErrorWithRequestID(w, apierrors.Unauthorized("Authentication required"), middleware.GetRequestID(r))
+		return
+	}
+
+	limit, offset := defaultMyCommentsPageSize, 0
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxMyCommentsPageSize {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError(fmt.Sprintf("limit must be between 1 and %d", maxMyCommentsPageSize)), middleware.GetRequestID(r))
+			return
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("offset must be a non-negative integer"), middleware.GetRequestID(r))
+			return
+		}
+		offset = n
+	}
+
+	authored, err := s.CommentStore.GetCommentsByAuthor(ctx, siteID, user.ID, limit, offset)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to retrieve user's comments", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to retrieve comments"), middleware.GetRequestID(r))
+		return
+	}
 
-	s.WriteJsonResponse(w, commentsData)
+	s.WriteJsonResponse(w, MyCommentsResponse{Comments: authored, Limit: limit, Offset: offset})
+}
+
+// defaultSearchResults and maxSearchResults bound the limit parameter of
+// SearchComments
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 50
+)
+
+// CommentSearchResult is a comment matching a search, with an HTML snippet of
+// its text around the match
+type CommentSearchResult struct {
+	comments.Comment
+	Snippet string `json:"snippet"`
+}
+
+// CommentSearchResponse lists the approved comments on a page matching a query
+type CommentSearchResponse struct {
+	Query   string                `json:"query"`
+	Results []CommentSearchResult `json:"results"`
+}
+
+// SearchComments searches the approved comments on a page
+// @Summary Search comments on a page
+// @Description Search the approved comments on a page by text, newest first. Each result has an HTML snippet with matches wrapped in <mark>.
+// @Tags comments
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param pageId path string true "Page ID"
+// @Param q query string true "Search text (3 to 100 characters)"
+// @Param limit query int false "Maximum results (default 20, max 50)"
+// @Success 200 {object} CommentSearchResponse
+// @Failure 400 {string} string "Query too short or too long, or invalid limit"
+// @Failure 500 {string} string "Failed to search comments"
+// @Router /site/{siteId}/page/{pageId}/comments/search [get]
+func (s *ServerHandlers) SearchComments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID, pageID := vars["siteId"], vars["pageId"]
+	ctx := logging.WithSiteID(r.Context(), siteID)
+
+	query := strings.Join(strings.Fields(r.URL.Query().Get("q")), " ")
+	if n := utf8.RuneCountInString(query); n < comments.MinSearchQueryLength || n > comments.MaxSearchQueryLength {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError(fmt.Sprintf("q must be between %d and %d characters",
+			comments.MinSearchQueryLength, comments.MaxSearchQueryLength)), middleware.GetRequestID(r))
+		return
+	}
+
+	limit := defaultSearchResults
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchResults {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError(fmt.Sprintf("limit must be between 1 and %d", maxSearchResults)), middleware.GetRequestID(r))
+			return
+		}
+		limit = n
+	}
+
+	matches, err := s.CommentStore.SearchApprovedPageComments(ctx, siteID, pageID, query, limit)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to search comments", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to search comments"), middleware.GetRequestID(r))
+		return
+	}
+
+	results := make([]CommentSearchResult, len(matches))
+	for i, c := range matches {
+		results[i] = CommentSearchResult{Comment: c, Snippet: comments.SearchSnippet(c.Text, query)}
+	}
+	s.WriteJsonResponse(w, CommentSearchResponse{Query: query, Results: results})
+}
+
+// maxCommentCountPages caps how many pages one GetCommentCounts request can ask about
+const maxCommentCountPages = 1000
+
+// CommentCountsResponse maps page IDs to their number of approved comments
+type CommentCountsResponse struct {
+	Counts map[string]int `json:"counts"`
+}
+
+// GetCommentCounts returns approved comment counts for a batch of pages
+// @Summary Get comment counts for pages
+// @Description Get the number of approved comments on each of a site's pages, e.g. for badges next to article links. Pages without comments are returned with 0.
+// @Tags comments
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param pages query string true "Comma-separated page IDs (at most 1000)"
+// @Success 200 {object} CommentCountsResponse
+// @Failure 400 {string} string "Missing pages or too many pages"
+// @Failure 500 {string} string "Failed to count comments"
+// @Router /site/{siteId}/comment-counts [get]
+func (s *ServerHandlers) GetCommentCounts(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	ctx := logging.WithSiteID(r.Context(), siteID)
+
+	pageIDs := []string{}
+	seen := map[string]bool{}
+	for _, id := range strings.Split(r.URL.Query().Get("pages"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		pageIDs = append(pageIDs, id)
+	}
+	if len(pageIDs) == 0 {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("pages is required"), middleware.GetRequestID(r))
+		return
+	}
+	if len(pageIDs) > maxCommentCountPages {
+		apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError(fmt.Sprintf("at most %d pages can be counted at once", maxCommentCountPages)), middleware.GetRequestID(r))
+		return
+	}
+
+	counts, err := s.CommentStore.GetCommentCounts(ctx, siteID, pageIDs)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to count comments", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.DatabaseError("Failed to count comments"), middleware.GetRequestID(r))
+		return
+	}
+	s.WriteJsonResponse(w, CommentCountsResponse{Counts: counts})
+}
+
+// canViewPendingComment reports whether the caller is the comment's author
+// (via JWT) or the owner of its site (via an admin session)
+func (s *ServerHandlers) canViewPendingComment(r *http.Request, comment *comments.Comment) bool {
+	if user := middleware.GetUserFromContext(r.Context()); user != nil && user.ID == comment.AuthorID {
+		return true
+	}
+	return s.isSiteOwner(r, comment.SiteID)
+}
+
+// isSiteOwner reports whether the caller has an admin session for the owner of siteID
+func (s *ServerHandlers) isSiteOwner(r *http.Request, siteID string) bool {
+	if s.DB == nil {
+		return false
+	}
+	session, err := auth.GetSession(r)
+	if err != nil {
+		return false
+	}
+	adminUserID, ok := session.Values[auth.SessionKeyUserID].(string)
+	if !ok || adminUserID == "" {
+		return false
+	}
+	site, err := models.NewSiteStore(s.DB).GetByID(r.Context(), siteID)
+	if err != nil {
+		return false
+	}
+	return site.OwnerID == adminUserID
 }
 
 // UpdateComment updates a comment's text (owner only)
@@ -206,12 +956,13 @@ func (s *ServerHandlers) GetComments(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param siteId path string true "Site ID"
 // @Param commentId path string true "Comment ID"
-// @Param update body object{text=string} true "Updated comment text"
+// @Param update body object{text=string,updated_at=string} true "Updated comment text, and the updated_at of the version being edited"
 // @Success 200 {object} comments.Comment
 // @Failure 400 {string} string "Invalid JSON or missing required fields"
 // @Failure 401 {string} string "Authentication required"
 // @Failure 403 {string} string "Forbidden - not the comment owner"
 // @Failure 404 {string} string "Comment not found"
+// @Failure 409 {string} string "Comment changed since updated_at; refetch and retry"
 // @Failure 500 {string} string "Failed to update comment"
 // @Security BearerAuth
 // @Router /site/{siteId}/comments/{commentId} [put]
@@ -235,6 +986,8 @@ func (s *ServerHandlers) UpdateComment(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var updateReq struct {
 		Text string `json:"text"`
+		// UpdatedAt is the version of the comment the edit was made against
+		UpdatedAt *time.Time `json:"updated_at"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&updateReq); err != nil {
 		apierrors.WriteError(w, apierrors.InvalidJSON("Invalid request body").WithRequestID(middleware.GetRequestID(r)))
@@ -246,34 +999,51 @@ func (s *ServerHandlers) UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the comment to verify ownership
-	comment, err := s.CommentStore.GetCommentByID(ctx, commentID)
+	// Get the comment to verify ownership; comments from other sites are not found
+	comment, err := s.CommentStore.GetCommentByIDForSite(ctx, commentID, siteID)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
 
-	// Verify the comment belongs to this site
-	if comment.SiteID != siteID {
-		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
-		return
-	}
-
 	// Verify ownership - user can only edit their own comments
 	if comment.AuthorID != user.ID {
 		apierrors.WriteError(w, apierrors.Forbidden("Forbidden - you can only edit your own comments").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
 
+	// Edits follow the site's content policy like new comments
+	updateReq.Text = comments.PrepareText(updateReq.Text, s.siteSettings(ctx, siteID).ContentPolicy)
+	if strings.TrimSpace(updateReq.Text) == "" {
+		apierrors.WriteError(w, apierrors.ValidationError("Text is required").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	// Only overwrite the version the client edited; without one, the version
+	// just read still guards against a concurrent write
+	expectedUpdatedAt := comment.UpdatedAt
+	if updateReq.UpdatedAt != nil {
+		expectedUpdatedAt = *updateReq.UpdatedAt
+	}
+
 	// Update the comment text
-	if err := s.CommentStore.UpdateCommentText(ctx, commentID, updateReq.Text); err != nil {
+	if err := s.CommentStore.UpdateCommentText(ctx, commentID, updateReq.Text, expectedUpdatedAt); err != nil {
+		if errors.Is(err, comments.ErrConcurrentModification) {
+			apierrors.WriteError(w, apierrors.Conflict("Comment was changed since it was loaded; refetch it and retry").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
+		if errors.Is(err, comments.ErrCommentNotFound) {
+			apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
 		s.Logger.ErrorContext(ctx, "failed to update comment", "error", err)
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to update comment").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
+	s.AnalyticsCache.InvalidateSite(siteID)
 
 	// Retrieve and return the updated comment
-	updatedComment, err := s.CommentStore.GetCommentByID(ctx, commentID)
+	updatedComment, err := s.CommentStore.GetCommentByIDForSite(ctx, commentID, siteID)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to retrieve updated comment").WithRequestID(middleware.GetRequestID(r)))
 		return
@@ -312,19 +1082,13 @@ func (s *ServerHandlers) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the comment to verify ownership
-	comment, err := s.CommentStore.GetCommentByID(ctx, commentID)
+	// Get the comment to verify ownership; comments from other sites are not found
+	comment, err := s.CommentStore.GetCommentByIDForSite(ctx, commentID, siteID)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
 
-	// Verify the comment belongs to this site
-	if comment.SiteID != siteID {
-		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
-		return
-	}
-
 	// Verify ownership - user can only delete their own comments
 	if comment.AuthorID != user.ID {
 		apierrors.WriteError(w, apierrors.Forbidden("Forbidden - you can only delete your own comments").WithRequestID(middleware.GetRequestID(r)))
@@ -337,6 +1101,100 @@ func (s *ServerHandlers) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to delete comment").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
+	s.AnalyticsCache.InvalidateSite(siteID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// trustedAuthorScore looks up the author's reputation and reports whether it
+// exceeds the site's trusted threshold. Lookup failures count as untrusted.
+func (s *ServerHandlers) trustedAuthorScore(ctx context.Context, siteID, userID string, config moderation.ModerationConfig) (int, bool) {
+	if s.DB == nil {
+		return 0, false
+	}
+	author, err := models.NewUserStore(s.DB).GetBySiteAndID(ctx, siteID, userID)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to load author reputation", "error", err)
+		return 0, false
+	}
+	if author == nil {
+		return 0, false
+	}
+	return author.ReputationScore, config.IsTrusted(author.ReputationScore)
+}
+
+// siteSettings returns a site's settings, falling back to the defaults without
+// a SQL database or when they can't be loaded
+func (s *ServerHandlers) siteSettings(ctx context.Context, siteID string) *models.SiteSettings {
+	if s.DB == nil {
+		return models.DefaultSiteSettings(siteID)
+	}
+	settings, err := models.NewSiteSettingsStore(s.DB).GetBySiteID(ctx, siteID)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to load site settings, using defaults", "error", err)
+		return models.DefaultSiteSettings(siteID)
+	}
+	return settings
+}
+
+// commentCooldownRemaining returns how long userID must still wait before
+// commenting on siteID again. Authors trusted by the site's moderation config
+// have no cooldown.
+func (s *ServerHandlers) commentCooldownRemaining(ctx context.Context, siteID, userID string, settings *models.SiteSettings) time.Duration {
+	if s.DB == nil || settings.CommentCooldownSeconds <= 0 {
+		return 0
+	}
+
+	last, err := models.NewUserStore(s.DB).LastCommentAt(ctx, siteID, userID)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to load author's last comment", "error", err)
+		return 0
+	}
+	remaining := time.Until(last.Add(time.Duration(settings.CommentCooldownSeconds) * time.Second))
+	if last.IsZero() || remaining <= 0 {
+		return 0
+	}
+
+	if s.ModerationConfigStore != nil {
+		if config, err := s.ModerationConfigStore.GetBySiteID(ctx, siteID); err == nil && config != nil {
+			if _, trusted := s.trustedAuthorScore(ctx, siteID, userID, *config); trusted {
+				return 0
+			}
+		}
+	}
+
+	return remaining
+}
+
+// analyzeComment runs moderation for a new comment. Sites with an Akismet key are
+// checked with Akismet; moderators that accept comment metadata receive it.
+func (s *ServerHandlers) analyzeComment(r *http.Request, siteID string, comment comments.Comment, config moderation.ModerationConfig) (*moderation.ModerationResult, error) {
+	moderator := s.Moderator
+	if config.AkismetAPIKey != "" {
+		moderator = moderation.NewAkismetModerator(config.AkismetAPIKey)
+	}
+
+	detailed, ok := moderator.(moderation.DetailedModerator)
+	if !ok {
+		return moderator.AnalyzeComment(comment.Text, config)
+	}
+
+	details := moderation.CommentDetails{
+		Text:        comment.Text,
+		Author:      comment.Author,
+		AuthorEmail: comment.AuthorEmail,
+		UserIP:      middleware.ClientIP(r),
+		UserAgent:   r.UserAgent(),
+	}
+	if s.DB != nil {
+		site, err := models.NewSiteStore(s.DB).GetByID(r.Context(), siteID)
+		if err == nil && site != nil && site.Domain != "" {
+			details.SiteURL = site.Domain
+			if !strings.Contains(details.SiteURL, "://") {
+				details.SiteURL = "https://" + details.SiteURL
+			}
+		}
+	}
+
+	return detailed.AnalyzeCommentDetails(details, config)
+}
@@ -0,0 +1,1742 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/moderation"
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
+)
+
+// getCommentRequest builds a GET request for a comment, optionally carrying a
+// JWT user
+func getCommentRequest(siteID, commentID string, user *models.KotomiUser) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+siteID+"/comments/"+commentID, nil)
+	req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "commentId": commentID})
+	if user != nil {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+	}
+	return req
+}
+
+// withAdminSession attaches an admin session cookie for adminUserID to req
+func withAdminSession(t *testing.T, req *http.Request, adminUserID string) *http.Request {
+	t.Helper()
+	session, err := auth.GetSession(req)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	session.Values[auth.SessionKeyUserID] = adminUserID
+	rr := httptest.NewRecorder()
+	if err := session.Save(req, rr); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	for _, cookie := range rr.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestGetComment_Visibility(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(store.GetDB()).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	otherSite, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Other", "other.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	now := time.Now()
+	for _, c := range []comments.Comment{
+		{ID: "approved-1", AuthorID: "author-1", Author: "Alice", Text: "Visible", Status: "approved", CreatedAt: now, UpdatedAt: now},
+		{ID: "pending-1", AuthorID: "author-1", Author: "Alice", Text: "Awaiting review", Status: "pending", CreatedAt: now, UpdatedAt: now},
+		{ID: "rejected-1", AuthorID: "author-1", Author: "Alice", Text: "Spam", Status: "rejected", CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := store.AddPageComment(ctx, site.ID, "page-1", c); err != nil {
+			t.Fatalf("Failed to add comment %s: %v", c.ID, err)
+		}
+	}
+
+	author := &models.KotomiUser{ID: "author-1", Name: "Alice"}
+	stranger := &models.KotomiUser{ID: "author-2", Name: "Bob"}
+
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		wantStatus int
+	}{
+		{"approved comment is public", func() *http.Request { return getCommentRequest(site.ID, "approved-1", nil) }, http.StatusOK},
+		{"pending comment hidden from anonymous callers", func() *http.Request { return getCommentRequest(site.ID, "pending-1", nil) }, http.StatusNotFound},
+		{"pending comment hidden from other users", func() *http.Request { return getCommentRequest(site.ID, "pending-1", stranger) }, http.StatusNotFound},
+		{"pending comment visible to its author", func() *http.Request { return getCommentRequest(site.ID, "pending-1", author) }, http.StatusOK},
+		{"pending comment visible to the site owner", func() *http.Request {
+			return withAdminSession(t, getCommentRequest(site.ID, "pending-1", nil), owner.ID)
+		}, http.StatusOK},
+		{"pending comment hidden from other admins", func() *http.Request {
+			return withAdminSession(t, getCommentRequest(site.ID, "pending-1", nil), "someone-else")
+		}, http.StatusNotFound},
+		{"rejected comment hidden from anonymous callers", func() *http.Request { return getCommentRequest(site.ID, "rejected-1", nil) }, http.StatusNotFound},
+		{"rejected comment hidden from other users", func() *http.Request { return getCommentRequest(site.ID, "rejected-1", stranger) }, http.StatusNotFound},
+		{"rejected comment visible to the site owner", func() *http.Request {
+			return withAdminSession(t, getCommentRequest(site.ID, "rejected-1", nil), owner.ID)
+		}, http.StatusOK},
+		{"comment from another site is not found", func() *http.Request { return getCommentRequest(otherSite.ID, "approved-1", author) }, http.StatusNotFound},
+		{"missing comment is not found", func() *http.Request { return getCommentRequest(site.ID, "missing", author) }, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.GetComment(rr, tt.request())
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetComment_IncludesReactionCounts(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(store.GetDB()).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	now := time.Now()
+	if err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	allowed, err := models.NewAllowedReactionStore(store.GetDB()).Create(ctx, site.ID, "thumbs_up", "👍", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+	reactions := models.NewReactionStore(store.GetDB())
+	for _, userID := range []string{"user-1", "user-2"} {
+		if _, err := reactions.AddReaction(ctx, "comment-1", allowed.ID, userID); err != nil {
+			t.Fatalf("Failed to add reaction: %v", err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	h.GetComment(rr, getCommentRequest(site.ID, "comment-1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var body CommentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.ID != "comment-1" || body.Text != "Hello" {
+		t.Errorf("Unexpected comment in response: %+v", body.Comment)
+	}
+	if len(body.Reactions) != 1 || body.Reactions[0].Count != 2 {
+		t.Errorf("Expected one reaction with count 2, got %+v", body.Reactions)
+	}
+}
+
+func TestGetComments_IncludesReactionCounts(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(store.GetDB()).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	now := time.Now()
+	for _, id := range []string{"comment-1", "comment-2"} {
+		if err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+			ID: id, AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved", CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	allowed, err := models.NewAllowedReactionStore(store.GetDB()).Create(ctx, site.ID, "heart", "❤️", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+	if _, err := models.NewReactionStore(store.GetDB()).AddReaction(ctx, "comment-1", allowed.ID, "user-1"); err != nil {
+		t.Fatalf("Failed to add reaction: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/page/page-1/comments", nil)
+	req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+	rr := httptest.NewRecorder()
+	h.GetComments(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var body []CommentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(body))
+	}
+	for _, c := range body {
+		want := 0
+		if c.ID == "comment-1" {
+			want = 1
+		}
+		if len(c.Reactions) != want {
+			t.Errorf("%s: expected %d reaction types, got %+v", c.ID, want, c.Reactions)
+		}
+	}
+}
+
+// countingModerator flags every comment for review and counts how often it is called
+type countingModerator struct {
+	calls int
+}
+
+func (m *countingModerator) AnalyzeComment(text string, config moderation.ModerationConfig) (*moderation.ModerationResult, error) {
+	m.calls++
+	return &moderation.ModerationResult{Decision: "flag", Confidence: 0.5, Reason: "Needs review"}, nil
+}
+
+func TestPostComments_TrustedAuthorSkipsModeration(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	config := moderation.DefaultModerationConfig()
+	config.Enabled = true
+	config.TrustedReputationThreshold = 5
+	configStore := moderation.NewConfigStore(sqlDB)
+	if err := configStore.Create(ctx, site.ID, config); err != nil {
+		t.Fatalf("Failed to create moderation config: %v", err)
+	}
+	moderator := &countingModerator{}
+	h.Moderator = moderator
+	h.ModerationConfigStore = configStore
+
+	userStore := models.NewUserStore(sqlDB)
+	for id, score := range map[string]int{"trusted": 10, "untrusted": 5} {
+		if err := userStore.CreateOrUpdate(ctx, &models.User{ID: id, SiteID: site.ID, Name: id}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if err := userStore.UpdateReputationScore(ctx, site.ID, id, score); err != nil {
+			t.Fatalf("Failed to set reputation: %v", err)
+		}
+	}
+
+	post := func(userID string) comments.Comment {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(`{"text":"Nice post"}`))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: userID, Name: userID}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var comment comments.Comment
+		if err := json.NewDecoder(rr.Body).Decode(&comment); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return comment
+	}
+	eventSource := func(commentID string) string {
+		t.Helper()
+		var source string
+		if err := sqlDB.QueryRow(`SELECT source FROM moderation_events WHERE comment_id = ?`, commentID).Scan(&source); err != nil {
+			t.Fatalf("Expected a moderation event for %s: %v", commentID, err)
+		}
+		return source
+	}
+
+	t.Run("trusted author bypasses AI moderation", func(t *testing.T) {
+		comment := post("trusted")
+		if comment.Status != "approved" {
+			t.Errorf("Expected trusted author's comment to be approved, got %s", comment.Status)
+		}
+		if moderator.calls != 0 {
+			t.Errorf("Expected the moderator not to be called, got %d calls", moderator.calls)
+		}
+		if source := eventSource(comment.ID); source != moderation.SourceReputation {
+			t.Errorf("Expected moderation event source %q, got %q", moderation.SourceReputation, source)
+		}
+	})
+
+	t.Run("untrusted author goes through AI moderation", func(t *testing.T) {
+		comment := post("untrusted")
+		if comment.Status != "pending" {
+			t.Errorf("Expected untrusted author's comment to be pending, got %s", comment.Status)
+		}
+		if moderator.calls != 1 {
+			t.Errorf("Expected the moderator to be called once, got %d calls", moderator.calls)
+		}
+		if source := eventSource(comment.ID); source != moderation.SourceAI {
+			t.Errorf("Expected moderation event source %q, got %q", moderation.SourceAI, source)
+		}
+	})
+}
+
+func TestPostComments_LinkRule(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	config := moderation.DefaultModerationConfig()
+	config.Enabled = true
+	config.LinkAction = moderation.BlockedWordActionReject
+	configStore := moderation.NewConfigStore(sqlDB)
+	if err := configStore.Create(ctx, site.ID, config); err != nil {
+		t.Fatalf("Failed to create moderation config: %v", err)
+	}
+	moderator := &countingModerator{}
+	h.Moderator = moderator
+	h.ModerationConfigStore = configStore
+
+	post := func(userID, text string) comments.Comment {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"text": text})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(string(body)))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: userID, Name: userID}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var comment comments.Comment
+		if err := json.NewDecoder(rr.Body).Decode(&comment); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return comment
+	}
+	eventSource := func(commentID string) string {
+		t.Helper()
+		var source string
+		if err := sqlDB.QueryRow(`SELECT source FROM moderation_events WHERE comment_id = ?`, commentID).Scan(&source); err != nil {
+			t.Fatalf("Expected a moderation event for %s: %v", commentID, err)
+		}
+		return source
+	}
+
+	t.Run("link-heavy comment is rejected without AI", func(t *testing.T) {
+		comment := post("spammer", "deals https://a.example https://b.example https://c.example https://d.example")
+		if comment.Status != "rejected" {
+			t.Errorf("Expected link-heavy comment to be rejected, got %s", comment.Status)
+		}
+		if moderator.calls != 0 {
+			t.Errorf("Expected the moderator not to be called, got %d calls", moderator.calls)
+		}
+		if source := eventSource(comment.ID); source != moderation.SourceLinks {
+			t.Errorf("Expected moderation event source %q, got %q", moderation.SourceLinks, source)
+		}
+	})
+
+	t.Run("comment with a single link goes through AI moderation", func(t *testing.T) {
+		comment := post("reader", "I wrote a longer follow-up on this at https://example.com/follow-up, thanks for the inspiration!")
+		if comment.Status != "pending" {
+			t.Errorf("Expected the AI decision (pending), got %s", comment.Status)
+		}
+		if moderator.calls != 1 {
+			t.Errorf("Expected the moderator to be called once, got %d calls", moderator.calls)
+		}
+		if source := eventSource(comment.ID); source != moderation.SourceAI {
+			t.Errorf("Expected moderation event source %q, got %q", moderation.SourceAI, source)
+		}
+	})
+}
+
+func TestPostComments_BlockedAuthor(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	// Posting twice in a row would otherwise hit the comment cooldown
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.CommentCooldownSeconds = 0
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	post := func() *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(`{"text":"Nice post"}`))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "troll", Name: "Troll"}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		return rr
+	}
+
+	if rr := post(); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 before blocking, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	blockedStore := models.NewBlockedAuthorStore(sqlDB)
+	if _, err := blockedStore.Block(ctx, site.ID, "troll", owner.ID, "spam"); err != nil {
+		t.Fatalf("Block failed: %v", err)
+	}
+	if rr := post(); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 while blocked, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The comment posted before the block stays
+	existing, err := store.GetPageComments(ctx, site.ID, "page-1")
+	if err != nil || len(existing) != 1 {
+		t.Errorf("Expected the earlier comment to remain, got %d (%v)", len(existing), err)
+	}
+
+	if err := blockedStore.Unblock(ctx, site.ID, "troll"); err != nil {
+		t.Fatalf("Unblock failed: %v", err)
+	}
+	if rr := post(); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 after unblocking, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPostComments_Cooldown(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	post := func(userID string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(`{"text":"Nice post"}`))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: userID, Name: userID}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		return rr
+	}
+
+	if rr := post("alice"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected first comment to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr := post("alice")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 within the cooldown, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if retry := rr.Header().Get("Retry-After"); retry != "15" {
+		t.Errorf("Expected Retry-After 15, got %q", retry)
+	}
+
+	// Other authors aren't affected
+	if rr := post("bob"); rr.Code != http.StatusOK {
+		t.Errorf("Expected another author to post, got %d", rr.Code)
+	}
+
+	// Once the window has passed alice may post again
+	if _, err := sqlDB.Exec(`UPDATE comments SET created_at = ? WHERE author_id = 'alice'`, time.Now().Add(-16*time.Second)); err != nil {
+		t.Fatalf("Failed to age comment: %v", err)
+	}
+	if rr := post("alice"); rr.Code != http.StatusOK {
+		t.Errorf("Expected comment after the cooldown to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("trusted authors skip the cooldown", func(t *testing.T) {
+		config := moderation.DefaultModerationConfig()
+		config.TrustedReputationThreshold = 5
+		configStore := moderation.NewConfigStore(sqlDB)
+		if err := configStore.Create(ctx, site.ID, config); err != nil {
+			t.Fatalf("Failed to create moderation config: %v", err)
+		}
+		h.ModerationConfigStore = configStore
+
+		userStore := models.NewUserStore(sqlDB)
+		if err := userStore.CreateOrUpdate(ctx, &models.User{ID: "trusted", SiteID: site.ID, Name: "trusted"}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if err := userStore.UpdateReputationScore(ctx, site.ID, "trusted", 10); err != nil {
+			t.Fatalf("Failed to set reputation: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if rr := post("trusted"); rr.Code != http.StatusOK {
+				t.Errorf("Post %d: expected trusted author to skip the cooldown, got %d", i+1, rr.Code)
+			}
+		}
+	})
+}
+
+func TestPostComments_ReplyNotifications(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+	h.NotificationQueue = notifications.NewQueue(sqlDB, time.Minute, 10)
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	// Alice comments twice below, which would otherwise hit the cooldown
+	siteSettings := models.DefaultSiteSettings(site.ID)
+	siteSettings.CommentCooldownSeconds = 0
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, siteSettings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+	page, err := models.NewPageStore(sqlDB).Create(ctx, site.ID, "/post", "Post")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	err = notifications.NewStore(sqlDB).SaveSettings(&notifications.NotificationSettings{
+		SiteID: site.ID, Enabled: true, Provider: "smtp", NotifyReply: true, OwnerEmail: "owner@example.com",
+	})
+	if err != nil {
+		t.Fatalf("Failed to save notification settings: %v", err)
+	}
+
+	userStore := models.NewUserStore(sqlDB)
+	for _, u := range []*models.User{
+		{ID: "alice", SiteID: site.ID, Name: "Alice", Email: "alice@example.com"},
+		{ID: "carol", SiteID: site.ID, Name: "Carol King", Email: "carol@example.com"},
+		{ID: "dave", SiteID: site.ID, Name: "Dave", Email: "dave@example.com"},
+	} {
+		if err := userStore.CreateOrUpdate(ctx, u); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if err := notifications.NewUnsubscribeStore(sqlDB).Unsubscribe(site.ID, "dave@example.com"); err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+
+	err = store.AddPageComment(ctx, site.ID, page.ID, comments.Comment{
+		ID: "parent", AuthorID: "alice", Author: "Alice", AuthorEmail: "alice@example.com", Text: "First!", Status: "approved",
+	})
+	if err != nil {
+		t.Fatalf("Failed to add parent comment: %v", err)
+	}
+
+	post := func(user *models.KotomiUser, body string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/"+page.ID+"/comments", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": page.ID})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	recipients := func() []string {
+		t.Helper()
+		rows, err := sqlDB.Query(`SELECT recipient FROM notification_queue WHERE type = ? ORDER BY recipient`, notifications.NotificationCommentReply)
+		if err != nil {
+			t.Fatalf("Failed to query notifications: %v", err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var to string
+			if err := rows.Scan(&to); err != nil {
+				t.Fatalf("Failed to scan notification: %v", err)
+			}
+			got = append(got, to)
+		}
+		return got
+	}
+
+	// Mentioning the parent author as well must not notify them twice
+	post(&models.KotomiUser{ID: "bob", Name: "Bob", Email: "bob@example.com"}, `{"text":"@alice agreed","parent_id":"parent"}`)
+	if got := recipients(); len(got) != 1 || got[0] != "alice@example.com" {
+		t.Fatalf("Expected exactly one notification to the parent author, got %v", got)
+	}
+
+	// Mentions reach other users, skipping unsubscribed ones and the commenter
+	if _, err := sqlDB.Exec(`DELETE FROM notification_queue`); err != nil {
+		t.Fatalf("Failed to clear queue: %v", err)
+	}
+	post(&models.KotomiUser{ID: "alice", Name: "Alice", Email: "alice@example.com"}, `{"text":"cc @carolking @dave @alice @nobody"}`)
+	if got := recipients(); len(got) != 1 || got[0] != "carol@example.com" {
+		t.Errorf("Expected only carol to be notified, got %v", got)
+	}
+}
+
+func TestGetComments_ApprovedOnlyForPublic(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(store.GetDB()).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	for _, c := range []comments.Comment{
+		{ID: "approved", Status: "approved"},
+		{ID: "pending", Status: "pending"},
+		{ID: "rejected", Status: "rejected"},
+		{ID: "reply", ParentID: "approved", Status: "pending"},
+	} {
+		c.AuthorID, c.Author, c.Text = "author-1", "Alice", "Hello"
+		if err := store.AddPageComment(ctx, site.ID, "page-1", c); err != nil {
+			t.Fatalf("Failed to add comment %s: %v", c.ID, err)
+		}
+	}
+
+	get := func(req *http.Request) []string {
+		t.Helper()
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		rr := httptest.NewRecorder()
+		h.GetComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var body []CommentResponse
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		ids := make([]string, len(body))
+		for i, c := range body {
+			ids[i] = c.ID
+		}
+		return ids
+	}
+	path := "/api/v1/site/" + site.ID + "/page/page-1/comments"
+
+	if ids := get(httptest.NewRequest(http.MethodGet, path, nil)); strings.Join(ids, ",") != "approved" {
+		t.Errorf("Expected the public to see only the approved comment, got %v", ids)
+	}
+	// An admin who doesn't own the site is just another visitor
+	other := withAdminSession(t, httptest.NewRequest(http.MethodGet, path, nil), "someone-else")
+	if ids := get(other); len(ids) != 1 {
+		t.Errorf("Expected a non-owner to see 1 comment, got %v", ids)
+	}
+	ownerReq := withAdminSession(t, httptest.NewRequest(http.MethodGet, path, nil), owner.ID)
+	if ids := get(ownerReq); len(ids) != 4 {
+		t.Errorf("Expected the owner to see all 4 comments, got %v", ids)
+	}
+}
+
+func TestGetComments_ConditionalRequests(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	err = store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	get := func(ifNoneMatch, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/page/page-1/comments"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		h.GetComments(rr, req)
+		return rr
+	}
+
+	first := get("", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d (ETag %q)", first.Code, etag)
+	}
+	if first.Header().Get("Last-Modified") == "" || first.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected Last-Modified and Cache-Control no-cache, got %v", first.Header())
+	}
+
+	// Nothing changed: the client's copy is current
+	rr := get(etag, "")
+	if rr.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304 body, got %q", rr.Body.String())
+	}
+	if rr := get(`"other", W/`+etag, ""); rr.Code != http.StatusNotModified {
+		t.Errorf("Expected a weak match in a list to give 304, got %d", rr.Code)
+	}
+	if rr := get(etag, "?render=html"); rr.Code != http.StatusOK {
+		t.Errorf("Expected a different representation to give 200, got %d", rr.Code)
+	}
+
+	// A reaction changes the counts in the body
+	allowed, err := models.NewAllowedReactionStore(sqlDB).Create(ctx, site.ID, "like", "👍", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+	if _, err := models.NewReactionStore(sqlDB).AddReaction(ctx, "comment-1", allowed.ID, "user-1"); err != nil {
+		t.Fatalf("Failed to add reaction: %v", err)
+	}
+	rr = get(etag, "")
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") == etag {
+		t.Fatalf("Expected 200 with a new ETag after a reaction, got %d (ETag %q)", rr.Code, rr.Header().Get("ETag"))
+	}
+	etag = rr.Header().Get("ETag")
+
+	// So does a new comment
+	err = store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-2", AuthorID: "author-2", Author: "Bob", Text: "Hi", Status: "approved",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+	if rr := get(etag, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 after a new comment, got %d", rr.Code)
+	}
+}
+
+func TestGetComments_Order(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	for i, id := range []string{"first", "second"} {
+		err := store.AddPageComment(ctx, "site-1", "page-1", comments.Comment{
+			ID: id, AuthorID: "author-1", Author: "Alice", Text: id, Status: "approved",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute), UpdatedAt: base,
+		})
+		if err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/site-1/page/page-1/comments"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "pageId": "page-1"})
+		rr := httptest.NewRecorder()
+		h.GetComments(rr, req)
+		return rr
+	}
+
+	rr := get("?order=newest")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body []CommentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body) != 2 || body[0].ID != "second" {
+		t.Errorf("Expected newest first, got %+v", body)
+	}
+
+	if rr := get("?order=random"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown order, got %d", rr.Code)
+	}
+}
+
+func TestGetMyComments_OnlyOwnComments(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, c := range []struct{ id, site, author, status string }{
+		{"alice-1", "site-1", "alice", "approved"},
+		{"alice-2", "site-1", "alice", "pending"},
+		{"bob-1", "site-1", "bob", "approved"},
+		{"alice-other-site", "site-2", "alice", "approved"},
+	} {
+		err := store.AddPageComment(ctx, c.site, "page-1", comments.Comment{
+			ID: c.id, AuthorID: c.author, Author: c.author, Text: "Hello", Status: c.status,
+		})
+		if err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	get := func(user *models.KotomiUser, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/site-1/users/me/comments"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1"})
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+		}
+		rr := httptest.NewRecorder()
+		h.GetMyComments(rr, req)
+		return rr
+	}
+
+	rr := get(&models.KotomiUser{ID: "alice", Name: "Alice"}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body MyCommentsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Comments) != 2 {
+		t.Fatalf("Expected alice's 2 comments on site-1, got %+v", body.Comments)
+	}
+	for _, c := range body.Comments {
+		if c.AuthorID != "alice" || c.SiteID != "site-1" {
+			t.Errorf("Leaked comment %s by %s on %s", c.ID, c.AuthorID, c.SiteID)
+		}
+		if c.PageID != "page-1" {
+			t.Errorf("Expected page-1 on comment %s, got %q", c.ID, c.PageID)
+		}
+	}
+	if body.Limit != defaultMyCommentsPageSize || body.Offset != 0 {
+		t.Errorf("Expected default paging, got limit %d offset %d", body.Limit, body.Offset)
+	}
+
+	rr = get(&models.KotomiUser{ID: "bob", Name: "Bob"}, "")
+	body = MyCommentsResponse{}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Comments) != 1 || body.Comments[0].ID != "bob-1" {
+		t.Errorf("Expected only bob's comment, got %+v", body.Comments)
+	}
+
+	if rr := get(nil, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a user, got %d", rr.Code)
+	}
+	if rr := get(&models.KotomiUser{ID: "alice"}, "?limit=1000"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an oversized limit, got %d", rr.Code)
+	}
+}
+
+func TestGetComments_RenderHTML(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	err := store.AddPageComment(ctx, "site-1", "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "**hi** <script>alert(1)</script>", Status: "approved",
+	})
+	if err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	get := func(query string) []CommentResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/site-1/page/page-1/comments"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "pageId": "page-1"})
+		rr := httptest.NewRecorder()
+		h.GetComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response []CommentResponse
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil || len(response) != 1 {
+			t.Fatalf("Expected one comment, got %v (%v)", response, err)
+		}
+		return response
+	}
+
+	if plain := get(""); plain[0].RenderedHTML != "" {
+		t.Errorf("Expected no rendered_html without ?render=html, got %q", plain[0].RenderedHTML)
+	}
+
+	rendered := get("?render=html")
+	want := "<p><strong>hi</strong> &lt;script&gt;alert(1)&lt;/script&gt;</p>"
+	if rendered[0].RenderedHTML != want {
+		t.Errorf("Expected rendered_html %q, got %q", want, rendered[0].RenderedHTML)
+	}
+	if rendered[0].Text != "**hi** <script>alert(1)</script>" {
+		t.Errorf("Expected the stored text to be returned unchanged, got %q", rendered[0].Text)
+	}
+}
+
+func TestPostComments_ContentPolicy(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+
+	input := "**bold** <strong>html</strong><script>alert(1)</script>"
+	tests := []struct {
+		policy   string
+		stored   string
+		rendered string
+	}{
+		{comments.ContentPolicyPlain, input, "<p>**bold** &lt;strong&gt;html&lt;/strong&gt;&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{comments.ContentPolicyMarkdown, input, "<p><strong>bold</strong> &lt;strong&gt;html&lt;/strong&gt;&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{comments.ContentPolicyLimitedHTML, "**bold** <strong>html</strong>", "**bold** <strong>html</strong>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, tt.policy, tt.policy+".example.com", "")
+			if err != nil {
+				t.Fatalf("Failed to create site: %v", err)
+			}
+			settings := models.DefaultSiteSettings(site.ID)
+			settings.CommentCooldownSeconds = 0
+			settings.ContentPolicy = tt.policy
+			if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+				t.Fatalf("Failed to save site settings: %v", err)
+			}
+			user := &models.KotomiUser{ID: "author-1", Name: "Alice"}
+
+			body, _ := json.Marshal(map[string]string{"text": input})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(string(body)))
+			req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+			rr := httptest.NewRecorder()
+			h.PostComments(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var created comments.Comment
+			if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			stored, err := store.GetCommentByID(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Failed to load comment: %v", err)
+			}
+			if stored.Text != tt.stored {
+				t.Errorf("Expected stored text %q, got %q", tt.stored, stored.Text)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/comments/"+created.ID+"?render=html", nil)
+			req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "commentId": created.ID})
+			req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+			rr = httptest.NewRecorder()
+			h.GetComment(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var response CommentResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.RenderedHTML != tt.rendered {
+				t.Errorf("Expected rendered_html %q, got %q", tt.rendered, response.RenderedHTML)
+			}
+		})
+	}
+
+	t.Run("limited-html that sanitizes to nothing is rejected", func(t *testing.T) {
+		site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "empty", "empty.example.com", "")
+		if err != nil {
+			t.Fatalf("Failed to create site: %v", err)
+		}
+		settings := models.DefaultSiteSettings(site.ID)
+		settings.ContentPolicy = comments.ContentPolicyLimitedHTML
+		if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+			t.Fatalf("Failed to save site settings: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(`{"text":"<script>alert(1)</script>"}`))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "author-2", Name: "Bob"}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
+
+func TestPostComments_DetectsLanguage(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.CommentCooldownSeconds = 0
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+	user := &models.KotomiUser{ID: "author-1", Name: "Alice"}
+
+	tests := []struct {
+		text     string
+		language string
+	}{
+		{"Thanks for writing this, it was exactly what I needed.", "en"},
+		{"この記事はとても分かりやすかったです。", "ja"},
+		{"ok", ""},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(map[string]string{"text": tt.text})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(string(body)))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var created comments.Comment
+		if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		stored, err := store.GetCommentByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Failed to load comment: %v", err)
+		}
+		if stored.Language != tt.language {
+			t.Errorf("Expected language %q for %q, got %q", tt.language, tt.text, stored.Language)
+		}
+	}
+}
+
+func TestSearchComments(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	seed := []comments.Comment{
+		{ID: "c1", Author: "Alice", AuthorID: "alice", Text: "The section on channels was really helpful", Status: "approved", CreatedAt: now},
+		{ID: "c2", Author: "Bob", AuthorID: "bob", Text: "Spam about channels", Status: "pending", CreatedAt: now},
+		{ID: "c3", Author: "Carol", AuthorID: "carol", Text: "Nice post", Status: "approved", CreatedAt: now},
+	}
+	for _, c := range seed {
+		if err := store.AddPageComment(ctx, "site-1", "page-1", c); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	search := func(q string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/site-1/page/page-1/comments/search?q="+url.QueryEscape(q), nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "pageId": "page-1"})
+		rr := httptest.NewRecorder()
+		h.SearchComments(rr, req)
+		return rr
+	}
+
+	t.Run("matching", func(t *testing.T) {
+		rr := search("Channels")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp CommentSearchResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		// The pending comment also matches but isn't public
+		if len(resp.Results) != 1 || resp.Results[0].ID != "c1" {
+			t.Fatalf("Expected only the approved match, got %+v", resp.Results)
+		}
+		if !strings.Contains(resp.Results[0].Snippet, "<mark>channels</mark>") {
+			t.Errorf("Expected a highlighted snippet, got %q", resp.Results[0].Snippet)
+		}
+	})
+
+	t.Run("non-matching", func(t *testing.T) {
+		rr := search("goroutines")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp CommentSearchResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Results == nil || len(resp.Results) != 0 {
+			t.Errorf("Expected an empty result list, got %+v", resp.Results)
+		}
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		for _, q := range []string{"", "a", " ch "} {
+			if rr := search(q); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %q, got %d", q, rr.Code)
+			}
+		}
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		if rr := search(strings.Repeat("x", comments.MaxSearchQueryLength+1)); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", rr.Code)
+		}
+	})
+}
+
+func TestGetComments_GravatarFallback(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	if err := models.NewUserStore(sqlDB).CreateOrUpdate(ctx, &models.User{
+		ID: "alice", SiteID: site.ID, Name: "Alice", AvatarURL: "https://cdn.example.com/alice.png",
+	}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	now := time.Now()
+	for _, c := range []comments.Comment{
+		{ID: "c1", Author: "Alice", AuthorID: "alice", AuthorEmail: "alice@example.com", Text: "Has an avatar", Status: "approved", CreatedAt: now},
+		{ID: "c2", Author: "Bob", AuthorID: "bob", AuthorEmail: " Bob@Example.com ", Text: "Has only an email", Status: "approved", CreatedAt: now},
+		{ID: "c3", Author: "Carol", AuthorID: "carol", Text: "Has neither", Status: "approved", CreatedAt: now},
+	} {
+		if err := store.AddPageComment(ctx, site.ID, "page-1", c); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	list := func() ([]CommentResponse, string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/page/page-1/comments", nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		rr := httptest.NewRecorder()
+		h.GetComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp []CommentResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		avatars := map[string]string{}
+		for _, c := range resp {
+			avatars[c.ID] = c.Avatar
+		}
+		if avatars["c1"] != "https://cdn.example.com/alice.png" {
+			t.Errorf("Expected the author's own avatar, got %q", avatars["c1"])
+		}
+		if avatars["c3"] != "" {
+			t.Errorf("Expected no avatar without an email, got %q", avatars["c3"])
+		}
+		return resp, rr.Header().Get("ETag")
+	}
+
+	resp, enabledETag := list()
+	for _, c := range resp {
+		if c.ID == "c2" && c.Avatar != models.GravatarURL("bob@example.com", models.DefaultGravatarSize) {
+			t.Errorf("Expected a Gravatar fallback, got %q", c.Avatar)
+		}
+	}
+
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.GravatarEnabled = false
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	resp, disabledETag := list()
+	for _, c := range resp {
+		if c.ID == "c2" && c.Avatar != "" {
+			t.Errorf("Expected no Gravatar when the site disables it, got %q", c.Avatar)
+		}
+	}
+	if enabledETag == disabledETag {
+		t.Error("Expected the ETag to change with the Gravatar setting")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/comments/c2", nil)
+	req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "commentId": "c2"})
+	rr := httptest.NewRecorder()
+	h.GetComment(rr, req)
+	var single CommentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&single); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if single.Avatar != "" {
+		t.Errorf("Expected no Gravatar on a single comment when disabled, got %q", single.Avatar)
+	}
+}
+
+func TestGetComments_Since(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	for _, c := range []comments.Comment{
+		{ID: "old", Author: "Alice", AuthorID: "alice", Text: "Before the poll", Status: "approved", CreatedAt: since.Add(-time.Minute), UpdatedAt: since.Add(-time.Minute)},
+		{ID: "reply", Author: "Bob", AuthorID: "bob", Text: "Reply after", ParentID: "old", Status: "approved", CreatedAt: since.Add(time.Minute), UpdatedAt: since.Add(time.Minute)},
+		{ID: "pending", Author: "Carol", AuthorID: "carol", Text: "Awaiting review", Status: "pending", CreatedAt: since.Add(2 * time.Minute), UpdatedAt: since.Add(2 * time.Minute)},
+	} {
+		if err := store.AddPageComment(ctx, site.ID, "page-1", c); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	get := func(sinceParam string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/page/page-1/comments?since="+url.QueryEscape(sinceParam), nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		rr := httptest.NewRecorder()
+		h.GetComments(rr, req)
+		return rr
+	}
+
+	before := time.Now()
+	rr := get(since.Format(time.RFC3339Nano))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp CommentsSinceResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Comments) != 1 || resp.Comments[0].ID != "reply" {
+		t.Fatalf("Expected only the newer approved reply, got %+v", resp.Comments)
+	}
+	if resp.Comments[0].ParentID != "old" {
+		t.Errorf("Expected the reply to keep its approved parent from before since, got %q", resp.Comments[0].ParentID)
+	}
+	if resp.ServerTime.Before(before.Add(-time.Second)) || resp.ServerTime.After(time.Now()) {
+		t.Errorf("Expected a current server_time, got %v", resp.ServerTime)
+	}
+
+	// Polling again from server_time returns nothing new
+	rr = get(resp.ServerTime.Format(time.RFC3339Nano))
+	resp = CommentsSinceResponse{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Comments) != 0 {
+		t.Errorf("Expected no comments since server_time, got %+v", resp.Comments)
+	}
+
+	if rr := get("yesterday"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid since, got %d", rr.Code)
+	}
+}
+
+func TestUpdateComment_StaleVersionConflicts(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	loaded := time.Now().Add(-time.Minute)
+	if err := store.AddPageComment(ctx, "site-1", "page-1", comments.Comment{
+		ID: "c1", AuthorID: "alice", Author: "Alice", Text: "Original", Status: "approved", CreatedAt: loaded, UpdatedAt: loaded,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	update := func(text string, updatedAt time.Time) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{"text": text, "updated_at": updatedAt})
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/site/site-1/comments/c1", strings.NewReader(string(body)))
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "commentId": "c1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "alice", Name: "Alice"}))
+		rr := httptest.NewRecorder()
+		h.UpdateComment(rr, req)
+		return rr
+	}
+
+	rr := update("First edit", loaded)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an edit of the current version, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var edited comments.Comment
+	if err := json.NewDecoder(rr.Body).Decode(&edited); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// A second tab still holding the original version is rejected
+	if rr := update("Second edit", loaded); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale updated_at, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if current, _ := store.GetCommentByID(ctx, "c1"); current.Text != "First edit" {
+		t.Errorf("Expected the first edit to survive, got %q", current.Text)
+	}
+
+	// Retrying with the refetched version succeeds
+	if rr := update("Second edit", edited.UpdatedAt); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 after refetching, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUpdateAndDeleteComment_InvalidateAnalyticsCache(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	h.AnalyticsCache = analytics.NewCachedStore(store.GetDB(), time.Hour, true)
+
+	for _, c := range []comments.Comment{
+		{ID: "c1", AuthorID: "alice", Author: "Alice", Text: "Original", Status: "approved"},
+		{ID: "c2", AuthorID: "bob", Author: "Bob", Text: "Hello", Status: "approved"},
+	} {
+		if err := store.AddPageComment(ctx, "site-1", "page-1", c); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+	dateRange := analytics.DateRange{From: time.Now().AddDate(0, 0, -1), To: time.Now().AddDate(0, 0, 1)}
+	dashboard := func() *analytics.AnalyticsDashboard {
+		t.Helper()
+		d, err := h.AnalyticsCache.GetAnalyticsDashboard(ctx, "site-1", dateRange)
+		if err != nil {
+			t.Fatalf("Failed to get analytics dashboard: %v", err)
+		}
+		return d
+	}
+	request := func(method, body string) *http.Request {
+		req := httptest.NewRequest(method, "/api/v1/site/site-1/comments/c1", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "commentId": "c1"})
+		return req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "alice", Name: "Alice"}))
+	}
+
+	before := dashboard()
+	rr := httptest.NewRecorder()
+	h.UpdateComment(rr, request(http.MethodPut, `{"text":"Edited"}`))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if dashboard() == before {
+		t.Error("Expected an edit to invalidate the cached dashboard")
+	}
+
+	if got := dashboard().Comments.Total; got != 2 {
+		t.Fatalf("Expected 2 comments before deleting, got %d", got)
+	}
+	rr = httptest.NewRecorder()
+	h.DeleteComment(rr, request(http.MethodDelete, ""))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := dashboard().Comments.Total; got != 1 {
+		t.Errorf("Expected the deleted comment to leave the cached dashboard, got %d comments", got)
+	}
+}
+
+func TestGetCommentCounts(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		id, page, status string
+	}{
+		{"c1", "post-1", "approved"},
+		{"c2", "post-1", "approved"},
+		{"c3", "post-2", "approved"},
+		{"c4", "post-2", "pending"},
+	} {
+		if err := store.AddPageComment(ctx, "site-1", c.page, comments.Comment{ID: c.id, Author: "Alice", Text: "Hi", Status: c.status}); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+	}
+
+	get := func(pages string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/site-1/comment-counts?pages="+url.QueryEscape(pages), nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1"})
+		rr := httptest.NewRecorder()
+		h.GetCommentCounts(rr, req)
+		return rr
+	}
+
+	rr := get("post-1, post-2,post-3,post-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp CommentCountsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	want := map[string]int{"post-1": 2, "post-2": 1, "post-3": 0}
+	if len(resp.Counts) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, resp.Counts)
+	}
+	for page, n := range want {
+		if got, ok := resp.Counts[page]; !ok || got != n {
+			t.Errorf("Expected %d comments on %s, got %d (present: %v)", n, page, got, ok)
+		}
+	}
+
+	if rr := get(""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without pages, got %d", rr.Code)
+	}
+	tooMany := make([]string, maxCommentCountPages+1)
+	for i := range tooMany {
+		tooMany[i] = "p" + strconv.Itoa(i)
+	}
+	if rr := get(strings.Join(tooMany, ",")); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for too many pages, got %d", rr.Code)
+	}
+}
+
+func TestPostComments_Attachments(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+
+	post := func(attachments int) *httptest.ResponseRecorder {
+		items := make([]string, attachments)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"url":"https://images.example.com/%d.png","mime_type":"image/png"}`, i)
+		}
+		body := `{"text":"Look at this","attachments":[` + strings.Join(items, ",") + `]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/site-1/page/page-1/comments", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "user-1", Name: "Alice"}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		return rr
+	}
+
+	rr := post(comments.MaxAttachmentsPerComment)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 within the limit, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created comments.Comment
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(created.Attachments) != comments.MaxAttachmentsPerComment || created.Attachments[0].ID == "" {
+		t.Fatalf("Expected the attachments in the response, got %+v", created.Attachments)
+	}
+	stored, err := store.GetCommentByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("Failed to load comment: %v", err)
+	}
+	if len(stored.Attachments) != comments.MaxAttachmentsPerComment || stored.Attachments[0].ID != created.Attachments[0].ID {
+		t.Errorf("Expected the stored attachments to match the response, got %+v", stored.Attachments)
+	}
+
+	if rr := post(comments.MaxAttachmentsPerComment + 1); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 over the limit, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPostComments_DispatchesWebhook(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	sub, err := webhooks.NewSubscriptionStore(sqlDB).Create(ctx, site.ID, receiver.URL, []string{webhooks.EventCommentCreated})
+	if err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+	h.Events = webhooks.NewDispatcherWithRetry(sqlDB, 1, time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(`{"text":"Nice post"}`))
+	req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "alice", Name: "Alice"}))
+	rr := httptest.NewRecorder()
+	h.PostComments(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created comments.Comment
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	select {
+	case r := <-received:
+		body := <-bodies
+		if r.Header.Get(webhooks.SignatureHeader) != webhooks.Sign(sub.Secret, body) {
+			t.Error("Expected a valid signature")
+		}
+		var payload webhooks.Payload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("Failed to decode payload: %v", err)
+		}
+		var data webhooks.CommentData
+		json.Unmarshal(payload.Data, &data)
+		if payload.Event != webhooks.EventCommentCreated || data.ID != created.ID || data.PageID != "page-1" || data.Text != "Nice post" {
+			t.Errorf("Unexpected payload %+v with data %+v", payload, data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the webhook to be delivered")
+	}
+	h.Events.Wait(ctx)
+}
+
+func TestPostComments_DefaultCommentStatus(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	newSite := func(name, defaultStatus string) *models.Site {
+		t.Helper()
+		site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, name, name+".example.com", "")
+		if err != nil {
+			t.Fatalf("Failed to create site: %v", err)
+		}
+		settings := models.DefaultSiteSettings(site.ID)
+		settings.CommentCooldownSeconds = 0
+		settings.DefaultCommentStatus = defaultStatus
+		if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+			t.Fatalf("Failed to save site settings: %v", err)
+		}
+		return site
+	}
+	openSite := newSite("open", models.CommentStatusApproved)
+	strictSite := newSite("strict", models.CommentStatusPending)
+
+	post := func(siteID, text string) comments.Comment {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+siteID+"/page/page-1/comments", strings.NewReader(`{"text":"`+text+`"}`))
+		req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "alice", Name: "Alice"}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var comment comments.Comment
+		if err := json.NewDecoder(rr.Body).Decode(&comment); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return comment
+	}
+
+	if comment := post(openSite.ID, "Nice post"); comment.Status != "approved" {
+		t.Errorf("Expected a site defaulting to approved to publish the comment, got %s", comment.Status)
+	}
+	if comment := post(strictSite.ID, "Nice post"); comment.Status != "pending" {
+		t.Errorf("Expected a site defaulting to pending to hold the comment, got %s", comment.Status)
+	}
+
+	// Blocklist and AI decisions still win over the default
+	configStore := moderation.NewConfigStore(sqlDB)
+	config := moderation.DefaultModerationConfig()
+	config.Enabled = true
+	if err := configStore.Create(ctx, openSite.ID, config); err != nil {
+		t.Fatalf("Failed to create moderation config: %v", err)
+	}
+	if err := configStore.AddBlockedWord(ctx, openSite.ID, "casino"); err != nil {
+		t.Fatalf("Failed to add blocked word: %v", err)
+	}
+	h.Moderator = &countingModerator{}
+	h.ModerationConfigStore = configStore
+
+	if comment := post(openSite.ID, "Visit my casino"); comment.Status != "rejected" {
+		t.Errorf("Expected the blocklist to reject the comment, got %s", comment.Status)
+	}
+	if comment := post(openSite.ID, "Nice post"); comment.Status != "pending" {
+		t.Errorf("Expected AI moderation's flag to hold the comment, got %s", comment.Status)
+	}
+}
+
+func TestPostComments_IgnoresClientModerationFields(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.DefaultCommentStatus = models.CommentStatusPending
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	body := `{"text":"Trust me","status":"approved","moderated_by":"owner","moderated_at":"2026-01-01T00:00:00Z","sentiment":"positive"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "alice", Name: "Alice"}))
+	rr := httptest.NewRecorder()
+	h.PostComments(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created comments.Comment
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	stored, err := store.GetCommentByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Failed to load comment: %v", err)
+	}
+	if stored.Status != "pending" {
+		t.Errorf("Expected the posted status to be ignored and the comment held as pending, got %s", stored.Status)
+	}
+	if stored.ModeratedBy != "" || !stored.ModeratedAt.IsZero() || stored.Sentiment != "" {
+		t.Errorf("Expected client moderation fields to be ignored, got moderated_by=%q moderated_at=%v sentiment=%q",
+			stored.ModeratedBy, stored.ModeratedAt, stored.Sentiment)
+	}
+}
+
+func TestPostComments_RecordsCommenter(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+
+	owner, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.CommentCooldownSeconds = 0
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	post := func() {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/page/page-1/comments", strings.NewReader(`{"text":"Hello"}`))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": "page-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser,
+			&models.KotomiUser{ID: "alice", Name: "Alice", Email: "alice@example.com"}))
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	userStore := models.NewUserStore(sqlDB)
+	post()
+	first, err := userStore.GetBySiteAndID(ctx, site.ID, "alice")
+	if err != nil || first == nil {
+		t.Fatalf("Expected the author to be recorded, got %v, %v", first, err)
+	}
+	if first.Name != "Alice" || first.Email != "alice@example.com" {
+		t.Errorf("Expected name and email from the token, got %q %q", first.Name, first.Email)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	post()
+	second, _ := userStore.GetBySiteAndID(ctx, site.ID, "alice")
+	if !second.FirstSeen.Equal(first.FirstSeen) {
+		t.Errorf("Expected first_seen to stay %v, got %v", first.FirstSeen, second.FirstSeen)
+	}
+	if !second.LastSeen.After(first.LastSeen) {
+		t.Errorf("Expected the second comment to move last_seen past %v, got %v", first.LastSeen, second.LastSeen)
+	}
+}
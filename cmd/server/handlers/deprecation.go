@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+)
+
+// DeprecationConfig controls the headers and sunset behavior of deprecated routes
+type DeprecationConfig struct {
+	// Sunset is when the deprecated routes stop being supported
+	Sunset time.Time
+	// Warning is sent in the X-API-Warn header
+	Warning string
+	// SuccessorURL is advertised with a Link rel="successor-version" header
+	SuccessorURL string
+	// EnforceSunset answers 410 Gone once Sunset has passed instead of serving the route
+	EnforceSunset bool
+	// Now returns the current time; defaults to time.Now
+	Now func() time.Time
+}
+
+// DefaultDeprecationConfig returns the policy for the unversioned /api routes
+func DefaultDeprecationConfig() DeprecationConfig {
+	return DeprecationConfig{
+		Sunset:       time.Date(2027, time.December, 31, 0, 0, 0, 0, time.UTC),
+		Warning:      "Deprecated API endpoint. Please use /api/v1/ prefix instead.",
+		SuccessorURL: "/api/v1",
+	}
+}
+
+// DeprecationConfigFromEnv returns the default policy overridden by
+// DEPRECATION_SUNSET (YYYY-MM-DD or RFC 3339), DEPRECATION_WARNING,
+// DEPRECATION_SUCCESSOR_URL and DEPRECATION_ENFORCE_SUNSET. Invalid values
+// are logged and ignored.
+func DeprecationConfigFromEnv() DeprecationConfig {
+	cfg := DefaultDeprecationConfig()
+
+	if value := os.Getenv("DEPRECATION_SUNSET"); value != "" {
+		if sunset, err := parseSunset(value); err == nil {
+			cfg.Sunset = sunset
+		} else {
+			log.Printf("Ignoring invalid DEPRECATION_SUNSET %q: %v", value, err)
+		}
+	}
+	if value := os.Getenv("DEPRECATION_WARNING"); value != "" {
+		cfg.Warning = value
+	}
+	if value := os.Getenv("DEPRECATION_SUCCESSOR_URL"); value != "" {
+		cfg.SuccessorURL = value
+	}
+	if value := os.Getenv("DEPRECATION_ENFORCE_SUNSET"); value != "" {
+		if enforce, err := strconv.ParseBool(value); err == nil {
+			cfg.EnforceSunset = enforce
+		} else {
+			log.Printf("Ignoring invalid DEPRECATION_ENFORCE_SUNSET %q: %v", value, err)
+		}
+	}
+
+	return cfg
+}
+
+// parseSunset accepts a date or a full RFC 3339 timestamp
+func parseSunset(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// NewDeprecationMiddleware returns middleware marking routes as deprecated
+// (RFC 8594). With EnforceSunset set, requests after the sunset get 410 Gone.
+func NewDeprecationMiddleware(cfg DeprecationConfig) func(http.Handler) http.Handler {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if !cfg.Sunset.IsZero() {
+				w.Header().Set("Sunset", cfg.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if cfg.Warning != "" {
+				w.Header().Set("X-API-Warn", cfg.Warning)
+			}
+			if cfg.SuccessorURL != "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, cfg.SuccessorURL))
+			}
+
+			if cfg.EnforceSunset && !cfg.Sunset.IsZero() && now().After(cfg.Sunset) {
+				message := "This API endpoint has been retired"
+				if cfg.SuccessorURL != "" {
+					message += "; use " + cfg.SuccessorURL + " instead"
+				}
+				apierrors.WriteErrorWithRequestID(w, apierrors.Gone(message), middleware.GetRequestID(r))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+)
+
+func serveDeprecated(cfg DeprecationConfig) (*httptest.ResponseRecorder, bool) {
+	served := false
+	handler := NewDeprecationMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/site/s1/page/p1/comments", nil))
+	return rr, served
+}
+
+func TestDeprecationMiddleware_BeforeSunset(t *testing.T) {
+	sunset := time.Date(2030, time.June, 1, 0, 0, 0, 0, time.UTC)
+	rr, served := serveDeprecated(DeprecationConfig{
+		Sunset:        sunset,
+		Warning:       "Use v2",
+		SuccessorURL:  "/api/v2",
+		EnforceSunset: true,
+		Now:           func() time.Time { return sunset.Add(-time.Hour) },
+	})
+
+	if !served || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the route to be served before sunset, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Expected Deprecation: true, got %q", got)
+	}
+	if got := rr.Header().Get("Sunset"); got != "Sat, 01 Jun 2030 00:00:00 GMT" {
+		t.Errorf("Unexpected Sunset header %q", got)
+	}
+	if got := rr.Header().Get("X-API-Warn"); got != "Use v2" {
+		t.Errorf("Unexpected X-API-Warn header %q", got)
+	}
+	if got := rr.Header().Get("Link"); got != `</api/v2>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header %q", got)
+	}
+}
+
+func TestDeprecationMiddleware_AfterSunset(t *testing.T) {
+	sunset := time.Date(2030, time.June, 1, 0, 0, 0, 0, time.UTC)
+	afterSunset := func() time.Time { return sunset.Add(time.Hour) }
+
+	rr, served := serveDeprecated(DeprecationConfig{
+		Sunset:        sunset,
+		SuccessorURL:  "/api/v1",
+		EnforceSunset: true,
+		Now:           afterSunset,
+	})
+	if served {
+		t.Error("Expected the route not to be served after sunset")
+	}
+	if rr.Code != http.StatusGone {
+		t.Fatalf("Expected 410, got %d", rr.Code)
+	}
+	var apiErr apierrors.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&apiErr); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if apiErr.Error.Code != apierrors.ErrCodeGone {
+		t.Errorf("Expected GONE error code, got %q", apiErr.Error.Code)
+	}
+	if rr.Header().Get("Link") == "" || rr.Header().Get("Sunset") == "" {
+		t.Error("Expected deprecation headers on the 410 response")
+	}
+
+	// Without enforcement the route keeps working past the sunset
+	rr, served = serveDeprecated(DeprecationConfig{Sunset: sunset, Now: afterSunset})
+	if !served || rr.Code != http.StatusOK {
+		t.Errorf("Expected the route to be served when sunset isn't enforced, got %d", rr.Code)
+	}
+}
+
+func TestDeprecationConfigFromEnv(t *testing.T) {
+	t.Setenv("DEPRECATION_SUNSET", "2031-02-03")
+	t.Setenv("DEPRECATION_SUCCESSOR_URL", "/api/v2")
+	t.Setenv("DEPRECATION_ENFORCE_SUNSET", "true")
+
+	cfg := DeprecationConfigFromEnv()
+	if !cfg.Sunset.Equal(time.Date(2031, time.February, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected sunset %v", cfg.Sunset)
+	}
+	if cfg.SuccessorURL != "/api/v2" || !cfg.EnforceSunset {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if cfg.Warning != DefaultDeprecationConfig().Warning {
+		t.Errorf("Expected default warning, got %q", cfg.Warning)
+	}
+
+	t.Setenv("DEPRECATION_SUNSET", "soon")
+	if cfg := DeprecationConfigFromEnv(); !cfg.Sunset.Equal(DefaultDeprecationConfig().Sunset) {
+		t.Errorf("Expected invalid sunset to fall back to the default, got %v", cfg.Sunset)
+	}
+}
@@ -10,10 +10,13 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
 	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
 	"github.com/saasuke-labs/kotomi/pkg/moderation"
 	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // ServerHandlers wraps the server dependencies for handler methods
@@ -23,9 +26,11 @@ type ServerHandlers struct {
 	Templates             *template.Template
 	Auth0Config           *auth.Auth0Config
 	Moderator             moderation.Moderator
-	ModerationConfigStore *moderation.ConfigStore
+	ModerationConfigStore moderation.ConfigRepository
 	NotificationQueue     *notifications.Queue
 	Logger                *slog.Logger
+	AnalyticsCache        *analytics.CachedStore // Optional; invalidated when comments change
+	Events                *webhooks.Dispatcher   // Optional; receives comment and reaction events
 }
 
 // NewHandlers creates a new ServerHandlers instance
@@ -35,7 +40,7 @@ func NewHandlers(
 	templates *template.Template,
 	auth0Config *auth.Auth0Config,
 	moderator moderation.Moderator,
-	moderationConfigStore *moderation.ConfigStore,
+	moderationConfigStore moderation.ConfigRepository,
 	notificationQueue *notifications.Queue,
 	logger *slog.Logger,
 ) *ServerHandlers {
@@ -76,34 +81,51 @@ func GetUrlParams(r *http.Request) (map[string]string, error) {
 		return vars, nil
 	}
 	
-	// Fallback to manual parsing for legacy/test code
-	path := strings.Trim(r.URL.Path, "/")
-	parts := strings.Split(path, "/")
+	// Fallback to manual parsing for legacy/test code:
+	// /api[/{version}]/site/{siteId}/page/{pageId}/comments
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 0 || parts[0] != "api" {
+		return nil, fmt.Errorf("invalid path")
+	}
+	parts = parts[1:]
+
+	version := ""
+	if len(parts) > 0 && IsAPIVersion(parts[0]) {
+		version = parts[0]
+		parts = parts[1:]
+	}
 
-	// Expected: ["api", "site", "{siteId}", "page", "{pageId}", "comments"]
-	// Or: ["api", "v1", "site", "{siteId}", "page", "{pageId}", "comments"]
-	if len(parts) == 7 && parts[0] == "api" && parts[1] == "v1" && parts[2] == "site" && parts[4] == "page" && parts[6] == "comments" {
-		// Versioned path
-		return map[string]string{
-			"siteId": parts[3],
-			"pageId": parts[5],
-		}, nil
-	} else if len(parts) == 6 && parts[0] == "api" && parts[1] == "site" && parts[3] == "page" && parts[5] == "comments" {
-		// Legacy path
-		return map[string]string{
-			"siteId": parts[2],
-			"pageId": parts[4],
-		}, nil
+	if len(parts) != 5 || parts[0] != "site" || parts[2] != "page" || parts[4] != "comments" || parts[1] == "" || parts[3] == "" {
+		return nil, fmt.Errorf("invalid path")
+	}
+
+	vars = map[string]string{
+		"siteId": parts[1],
+		"pageId": parts[3],
+	}
+	if version != "" {
+		vars["version"] = version
 	}
+	return vars, nil
+}
+
+// APIVersions lists the versioned API prefixes served under /api, oldest first
+var APIVersions = []string{"v1", "v2"}
 
-	return nil, fmt.Errorf("invalid path")
+// IsAPIVersion reports whether segment names a served API version
+func IsAPIVersion(segment string) bool {
+	for _, v := range APIVersions {
+		if segment == v {
+			return true
+		}
+	}
+	return false
 }
 
 // GetUserIdentifier extracts a user identifier from the request
-// WARNING: This function reads client-provided headers which can be spoofed.
-// Only use when behind a trusted reverse proxy that sanitizes these headers.
+// WARNING: X-User-ID is a client-provided header which can be spoofed.
 // - X-User-ID: Should only be set by trusted middleware, not from client requests
-// - X-Forwarded-For/X-Real-IP: Only reliable when behind properly configured reverse proxy
+// - X-Forwarded-For/X-Real-IP: Only honored from proxies listed in TRUSTED_PROXIES
 func GetUserIdentifier(r *http.Request) string {
 	// Try to get user from Auth (preferred) - NOTE: This header can be spoofed if not validated
 	// TODO: This should only be read if set by internal middleware, not from client
@@ -111,14 +133,6 @@ func GetUserIdentifier(r *http.Request) string {
 		return userID
 	}
 	
-	// Fall back to IP address - only reliable behind trusted proxy
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = r.Header.Get("X-Real-IP")
-	}
-	if ip == "" {
-		ip = r.RemoteAddr
-	}
-	
-	return ip
+	// Fall back to the client IP, as reported by trusted proxies
+	return middleware.ClientIP(r)
 }
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUrlParams(t *testing.T) {
+	tests := []struct {
+		path    string
+		site    string
+		page    string
+		version string
+		wantErr bool
+	}{
+		{path: "/api/site/s1/page/p1/comments", site: "s1", page: "p1"},
+		{path: "/api/v1/site/s1/page/p1/comments", site: "s1", page: "p1", version: "v1"},
+		{path: "/api/v2/site/s1/page/p1/comments/", site: "s1", page: "p1", version: "v2"},
+		// A site called "v1" is still a site on the legacy path
+		{path: "/api/site/v1/page/p1/comments", site: "v1", page: "p1"},
+		{path: "/api/v3/site/s1/page/p1/comments", wantErr: true},
+		{path: "/api/v1/site/s1/page/p1/comments/extra", wantErr: true},
+		{path: "/api/v1/site/s1/pages/p1/comments", wantErr: true},
+		{path: "/site/s1/page/p1/comments", wantErr: true},
+		{path: "/api/site//page/p1/comments", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		vars, err := GetUrlParams(httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %v", tt.path, vars)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.path, err)
+			continue
+		}
+		if vars["siteId"] != tt.site || vars["pageId"] != tt.page || vars["version"] != tt.version {
+			t.Errorf("%s: got %v, want site=%s page=%s version=%q", tt.path, vars, tt.site, tt.page, tt.version)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+)
+
+// readinessTimeout bounds the database ping made by GetReadyz
+const readinessTimeout = 2 * time.Second
+
+// ReadinessResponse is the body returned by GetReadyz when the service is ready
+type ReadinessResponse struct {
+	Status    string  `json:"status"`
+	Database  string  `json:"database"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// GetReadyz is a readiness check handler that verifies database connectivity
+// @Summary Readiness check
+// @Description Check that the service can reach its database. Unlike /healthz this fails with 503 when the database is down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} apierrors.APIError
+// @Router /readyz [get]
+func (s *ServerHandlers) GetReadyz(w http.ResponseWriter, r *http.Request) {
+	db := s.DB
+	if s.CommentStore != nil && s.CommentStore.GetDB() != nil {
+		db = s.CommentStore.GetDB()
+	}
+
+	// Stores without a SQL connection (e.g. Firestore) have nothing to ping
+	if db == nil {
+		s.WriteJsonResponse(w, ReadinessResponse{Status: "ok", Database: "not applicable"})
+		return
+	}
+
+	latency, err := pingDatabase(r.Context(), db)
+	if err != nil {
+		s.Logger.ErrorContext(r.Context(), "readiness check failed", "error", err)
+		apierrors.WriteErrorWithRequestID(w, apierrors.ServiceUnavailable("Database unavailable").WithDetails(err.Error()), middleware.GetRequestID(r))
+		return
+	}
+
+	s.WriteJsonResponse(w, ReadinessResponse{
+		Status:    "ok",
+		Database:  "connected",
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	})
+}
+
+// pingDatabase pings db within readinessTimeout and returns the round-trip time
+func pingDatabase(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := db.PingContext(ctx)
+	return time.Since(start), err
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+)
+
+func newReadyzHandlers(t *testing.T) (*ServerHandlers, *db.SQLiteAdapter) {
+	t.Helper()
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "readyz.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewHandlers(store, store.GetDB(), nil, nil, nil, nil, nil, logger), store
+}
+
+func TestGetReadyz_Connected(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+
+	rr := httptest.NewRecorder()
+	h.GetReadyz(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body ReadinessResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Status != "ok" || body.Database != "connected" {
+		t.Errorf("Unexpected readiness response: %+v", body)
+	}
+	if body.LatencyMs < 0 {
+		t.Errorf("Expected non-negative latency, got %f", body.LatencyMs)
+	}
+}
+
+func TestGetReadyz_ClosedDatabase(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	store.Close()
+
+	rr := httptest.NewRecorder()
+	h.GetReadyz(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body apierrors.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if body.Error.Code != apierrors.ErrCodeServiceUnavailable {
+		t.Errorf("Expected code %s, got %s", apierrors.ErrCodeServiceUnavailable, body.Error.Code)
+	}
+
+	// Liveness stays cheap and unaffected by the database
+	rr = httptest.NewRecorder()
+	h.GetHealthz(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected /healthz to stay 200, got %d", rr.Code)
+	}
+}
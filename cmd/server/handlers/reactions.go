@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
 	"github.com/saasuke-labs/kotomi/pkg/logging"
 	"github.com/saasuke-labs/kotomi/pkg/middleware"
 	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // GetAllowedReactions retrieves allowed reactions for a site
@@ -82,23 +87,63 @@ func (s *ServerHandlers) AddReaction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	reactionStore := models.NewReactionStore(s.DB)
-	reaction, err := reactionStore.AddReaction(ctx, commentID, req.AllowedReactionID, user.ID)
+	toggle, err := reactionStore.AddReaction(ctx, commentID, req.AllowedReactionID, user.ID)
+	if errors.Is(err, models.ErrReactionLimitReached) {
+		apierrors.WriteError(w, apierrors.Conflict("Reaction limit reached for this target").WithDetails(err.Error()).WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+	if errors.Is(err, models.ErrReactionSiteMismatch) || errors.Is(err, models.ErrReactionTypeMismatch) {
+		apierrors.WriteError(w, apierrors.ValidationError("Reaction is not allowed on this target").WithDetails(err.Error()).WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "failed to add reaction", "error", err, "allowed_reaction_id", req.AllowedReactionID, "user_id", user.ID)
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to add reaction").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
 
-	// If reaction is nil, it means the user toggled off their reaction
-	if reaction == nil {
+	// The user toggled off their reaction
+	if toggle.Removed() {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	s.dispatchReactionAdded(ctx, toggle.Reaction)
 
-	s.WriteJsonResponse(w, reaction)
+	s.WriteJsonResponse(w, toggle.Reaction)
+}
+
+// defaultReactionsPageSize and maxReactionsPageSize bound the limit parameter
+// of GetReactionsByComment and GetReactionsByPage
+const (
+	defaultReactionsPageSize = 50
+	maxReactionsPageSize     = 200
+)
+
+// parseReactionsPage reads the limit and offset query parameters of the
+// reaction list endpoints, writing a validation error if either is invalid
+func parseReactionsPage(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit = defaultReactionsPageSize
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxReactionsPageSize {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError(fmt.Sprintf("limit must be between 1 and %d", maxReactionsPageSize)), middleware.GetRequestID(r))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			apierrors.WriteErrorWithRequestID(w, apierrors.ValidationError("offset must be a non-negative integer"), middleware.GetRequestID(r))
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
 }
 
-// GetReactionsByComment retrieves all reactions for a comment
+// GetReactionsByComment retrieves a page of reactions for a comment, oldest first
 func (s *ServerHandlers) GetReactionsByComment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	commentID := vars["commentId"]
@@ -107,8 +152,13 @@ func (s *ServerHandlers) GetReactionsByComment(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 	ctx = logging.WithCommentID(ctx, commentID)
 
+	limit, offset, ok := parseReactionsPage(w, r)
+	if !ok {
+		return
+	}
+
 	reactionStore := models.NewReactionStore(s.DB)
-	reactions, err := reactionStore.GetReactionsByComment(ctx, commentID)
+	reactions, err := reactionStore.GetReactionsByComment(ctx, commentID, limit, offset)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "failed to retrieve reactions", "error", err)
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to retrieve reactions").WithRequestID(middleware.GetRequestID(r)))
@@ -128,6 +178,19 @@ func (s *ServerHandlers) GetReactionCounts(w http.ResponseWriter, r *http.Reques
 	ctx = logging.WithCommentID(ctx, commentID)
 
 	reactionStore := models.NewReactionStore(s.DB)
+
+	// Viewers with a valid token also learn which reactions are theirs
+	if user := middleware.GetUserFromContext(ctx); user != nil {
+		counts, err := reactionStore.GetReactionCountsForUser(ctx, commentID, user.ID)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to retrieve reaction counts", "error", err, "user_id", user.ID)
+			apierrors.WriteError(w, apierrors.DatabaseError("Failed to retrieve reaction counts").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
+		s.WriteJsonResponse(w, counts)
+		return
+	}
+
 	counts, err := reactionStore.GetReactionCounts(ctx, commentID)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "failed to retrieve reaction counts", "error", err)
@@ -169,23 +232,32 @@ func (s *ServerHandlers) AddPageReaction(w http.ResponseWriter, r *http.Request)
 	}
 
 	reactionStore := models.NewReactionStore(s.DB)
-	reaction, err := reactionStore.AddPageReaction(ctx, pageID, req.AllowedReactionID, user.ID)
+	toggle, err := reactionStore.AddPageReaction(ctx, pageID, req.AllowedReactionID, user.ID)
+	if errors.Is(err, models.ErrReactionLimitReached) {
+		apierrors.WriteError(w, apierrors.Conflict("Reaction limit reached for this target").WithDetails(err.Error()).WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+	if errors.Is(err, models.ErrReactionSiteMismatch) || errors.Is(err, models.ErrReactionTypeMismatch) {
+		apierrors.WriteError(w, apierrors.ValidationError("Reaction is not allowed on this target").WithDetails(err.Error()).WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "failed to add page reaction", "error", err, "allowed_reaction_id", req.AllowedReactionID, "user_id", user.ID)
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to add reaction").WithRequestID(middleware.GetRequestID(r)))
 		return
 	}
 
-	// If reaction is nil, it means the user toggled off their reaction
-	if reaction == nil {
+	// The user toggled off their reaction
+	if toggle.Removed() {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	s.dispatchReactionAdded(ctx, toggle.Reaction)
 
-	s.WriteJsonResponse(w, reaction)
+	s.WriteJsonResponse(w, toggle.Reaction)
 }
 
-// GetReactionsByPage retrieves all reactions for a page
+// GetReactionsByPage retrieves a page of reactions for a page, oldest first
 func (s *ServerHandlers) GetReactionsByPage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	pageID := vars["pageId"]
@@ -194,8 +266,13 @@ func (s *ServerHandlers) GetReactionsByPage(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	ctx = logging.WithPageID(ctx, pageID)
 
+	limit, offset, ok := parseReactionsPage(w, r)
+	if !ok {
+		return
+	}
+
 	reactionStore := models.NewReactionStore(s.DB)
-	reactions, err := reactionStore.GetReactionsByPage(ctx, pageID)
+	reactions, err := reactionStore.GetReactionsByPage(ctx, pageID, limit, offset)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "failed to retrieve page reactions", "error", err)
 		apierrors.WriteError(w, apierrors.DatabaseError("Failed to retrieve reactions").WithRequestID(middleware.GetRequestID(r)))
@@ -215,6 +292,19 @@ func (s *ServerHandlers) GetPageReactionCounts(w http.ResponseWriter, r *http.Re
 	ctx = logging.WithPageID(ctx, pageID)
 
 	reactionStore := models.NewReactionStore(s.DB)
+
+	// Viewers with a valid token also learn which reactions are theirs
+	if user := middleware.GetUserFromContext(ctx); user != nil {
+		counts, err := reactionStore.GetPageReactionCountsForUser(ctx, pageID, user.ID)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "failed to retrieve page reaction counts", "error", err, "user_id", user.ID)
+			apierrors.WriteError(w, apierrors.DatabaseError("Failed to retrieve reaction counts").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
+		s.WriteJsonResponse(w, counts)
+		return
+	}
+
 	counts, err := reactionStore.GetPageReactionCounts(ctx, pageID)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "failed to retrieve page reaction counts", "error", err)
@@ -242,3 +332,17 @@ func (s *ServerHandlers) RemoveReaction(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// dispatchReactionAdded sends the reaction.added event to the site the
+// reaction belongs to
+func (s *ServerHandlers) dispatchReactionAdded(ctx context.Context, reaction *models.Reaction) {
+	if s.Events == nil {
+		return
+	}
+	allowed, err := models.NewAllowedReactionStore(s.DB).GetByID(ctx, reaction.AllowedReactionID)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to load allowed reaction for webhook", "error", err, "allowed_reaction_id", reaction.AllowedReactionID)
+		return
+	}
+	s.Events.Dispatch(allowed.SiteID, webhooks.EventReactionAdded, webhooks.NewReactionData(*reaction, *allowed))
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func TestGetReactionsByComment_Pagination(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	db := store.GetDB()
+
+	owner, _ := models.NewAdminUserStore(db).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	site, _ := models.NewSiteStore(db).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	now := time.Now()
+	if err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+	heart, err := models.NewAllowedReactionStore(db).Create(ctx, site.ID, "heart", "❤️", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+	reactionStore := models.NewReactionStore(db)
+	for i := 0; i < 3; i++ {
+		if _, err := reactionStore.AddReaction(ctx, "comment-1", heart.ID, fmt.Sprintf("user-%d", i)); err != nil {
+			t.Fatalf("Failed to add reaction: %v", err)
+		}
+	}
+
+	list := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/site/"+site.ID+"/comments/comment-1/reactions"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "commentId": "comment-1"})
+		rr := httptest.NewRecorder()
+		h.GetReactionsByComment(rr, req)
+		return rr
+	}
+	count := func(rr *httptest.ResponseRecorder) int {
+		t.Helper()
+		var reactions []models.ReactionWithDetails
+		if err := json.Unmarshal(rr.Body.Bytes(), &reactions); err != nil {
+			t.Fatalf("Failed to decode reactions: %s", rr.Body.String())
+		}
+		return len(reactions)
+	}
+
+	if rr := list(""); rr.Code != http.StatusOK || count(rr) != 3 {
+		t.Errorf("Expected all 3 reactions within the default page, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := list("?limit=2"); rr.Code != http.StatusOK || count(rr) != 2 {
+		t.Errorf("Expected 2 reactions, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := list("?limit=2&offset=2"); rr.Code != http.StatusOK || count(rr) != 1 {
+		t.Errorf("Expected the last reaction, got %d: %s", rr.Code, rr.Body.String())
+	}
+	for _, query := range []string{"?limit=0", "?limit=201", "?limit=x", "?offset=-1"} {
+		if rr := list(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestAddReaction_ConcurrentToggles(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	db := store.GetDB()
+
+	owner, _ := models.NewAdminUserStore(db).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	site, _ := models.NewSiteStore(db).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	now := time.Now()
+	if err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+	heart, err := models.NewAllowedReactionStore(db).Create(ctx, site.ID, "heart", "❤️", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+
+	tap := func() int {
+		body := strings.NewReader(`{"allowed_reaction_id": "` + heart.ID + `"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+site.ID+"/comments/comment-1/reactions", body)
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "commentId": "comment-1"})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "user-1"}))
+		rr := httptest.NewRecorder()
+		h.AddReaction(rr, req)
+		return rr.Code
+	}
+
+	// Run the taps on separate threads even on a single CPU, or they rarely
+	// interleave inside a transaction
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// Two simultaneous taps behave like two taps in a row: one adds the
+	// reaction and the other removes it
+	for round := 0; round < 50; round++ {
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		codes := make([]int, 2)
+		for i := range codes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				codes[i] = tap()
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		sort.Ints(codes)
+		if codes[0] != http.StatusOK || codes[1] != http.StatusNoContent {
+			t.Fatalf("Round %d: expected one 200 and one 204, got %v", round, codes)
+		}
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM reactions WHERE comment_id = 'comment-1' AND user_id = 'user-1'`).Scan(&n); err != nil {
+			t.Fatalf("Failed to count reactions: %v", err)
+		}
+		if n != 0 {
+			t.Fatalf("Round %d: expected the reaction to end up removed, found %d", round, n)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/moderation"
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+)
+
+// ReportComment flags a comment for owner review
+// @Summary Report a comment
+// @Description Report an abusive comment (requires JWT authentication). Each user can report a comment once; comments reaching the site's report threshold go back to pending review.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param commentId path string true "Comment ID"
+// @Param report body object{reason=string} false "Optional reason for the report"
+// @Success 200 {object} comments.Report
+// @Failure 400 {string} string "Invalid JSON or reason too long"
+// @Failure 401 {string} string "Authentication required"
+// @Failure 404 {string} string "Comment not found"
+// @Failure 409 {string} string "Comment already reported"
+// @Failure 500 {string} string "Failed to report comment"
+// @Security BearerAuth
+// @Router /site/{siteId}/comments/{commentId}/report [post]
+func (s *ServerHandlers) ReportComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["commentId"]
+	siteID := vars["siteId"]
+
+	// Enrich context with site_id and comment_id for automatic logging
+	ctx := r.Context()
+	ctx = logging.WithSiteID(ctx, siteID)
+	ctx = logging.WithCommentID(ctx, commentID)
+
+	user := middleware.GetUserFromContext(ctx)
+	if user == nil {
+		apierrors.WriteError(w, apierrors.Unauthorized("Authentication required").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	if s.DB == nil {
+		apierrors.WriteError(w, apierrors.ServiceUnavailable("Reporting requires a SQL database").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	// The body is optional; an empty one reports without a reason
+	var reportReq struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reportReq); err != nil && !errors.Is(err, io.EOF) {
+		apierrors.WriteError(w, apierrors.InvalidJSON("Invalid request body").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+	if len(reportReq.Reason) > comments.MaxReportReasonLength {
+		apierrors.WriteError(w, apierrors.ValidationError(fmt.Sprintf("Reason must be at most %d characters", comments.MaxReportReasonLength)).WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	comment, err := s.CommentStore.GetCommentByIDForSite(ctx, commentID, siteID)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+	if comment.Status == "pending" && !s.canViewPendingComment(r, comment) {
+		apierrors.WriteError(w, apierrors.NotFound("Comment not found").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	reports := comments.NewReportStore(s.DB)
+	report, err := reports.Create(ctx, commentID, user.ID, reportReq.Reason)
+	if err != nil {
+		if errors.Is(err, comments.ErrAlreadyReported) {
+			apierrors.WriteError(w, apierrors.Conflict("You have already reported this comment").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
+		s.Logger.ErrorContext(ctx, "failed to report comment", "error", err)
+		apierrors.WriteError(w, apierrors.DatabaseError("Failed to report comment").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	s.Logger.InfoContext(ctx, "comment reported", "reporter_id", user.ID)
+
+	if comment.Status == "approved" {
+		if err := s.hideReportedComment(ctx, reports, comment); err != nil {
+			s.Logger.WarnContext(ctx, "failed to apply report threshold", "error", err)
+		}
+	}
+
+	s.WriteJsonResponse(w, report)
+}
+
+// hideReportedComment sends an approved comment back to pending once its report
+// count reaches the site's threshold and notifies the site owner
+func (s *ServerHandlers) hideReportedComment(ctx context.Context, reports *comments.ReportStore, comment *comments.Comment) error {
+	settings, err := models.NewSiteSettingsStore(s.DB).GetBySiteID(ctx, comment.SiteID)
+	if err != nil {
+		return err
+	}
+	if settings.ReportThreshold <= 0 {
+		return nil
+	}
+
+	count, err := reports.CountReports(ctx, comment.ID)
+	if err != nil {
+		return err
+	}
+	if count < settings.ReportThreshold {
+		return nil
+	}
+
+	if err := s.CommentStore.UpdateCommentStatus(ctx, comment.ID, "pending", ""); err != nil {
+		return err
+	}
+	s.AnalyticsCache.InvalidateSite(comment.SiteID)
+	if err := models.NewReputationStore(s.DB).ApplyStatusChange(ctx, comment.SiteID, comment.AuthorID, comment.Status, "pending"); err != nil {
+		s.Logger.WarnContext(ctx, "failed to update reputation", "error", err)
+	}
+	s.Logger.InfoContext(ctx, "comment hidden after reports", "report_count", count, "threshold", settings.ReportThreshold)
+
+	if err := moderation.NewEventStore(s.DB).Record(ctx, moderation.Event{
+		CommentID: comment.ID,
+		SiteID:    comment.SiteID,
+		Decision:  "pending",
+		Source:    moderation.SourceReports,
+		Reason:    fmt.Sprintf("reported by %d users", count),
+	}); err != nil {
+		s.Logger.WarnContext(ctx, "failed to record moderation event", "error", err)
+	}
+
+	s.enqueueReportedNotification(ctx, comment, count)
+	return nil
+}
+
+// enqueueReportedNotification tells the site owner a comment was hidden by reports
+func (s *ServerHandlers) enqueueReportedNotification(ctx context.Context, comment *comments.Comment, reportCount int) {
+	if s.NotificationQueue == nil {
+		return
+	}
+
+	settings, err := notifications.NewStore(s.DB).GetSettings(comment.SiteID)
+	if err != nil || settings == nil || !settings.Enabled || !settings.NotifyModeration {
+		return
+	}
+
+	site, err := models.NewSiteStore(s.DB).GetByID(ctx, comment.SiteID)
+	if err != nil || site == nil {
+		return
+	}
+
+	var pageID string
+	if err := s.DB.QueryRowContext(ctx, "SELECT page_id FROM comments WHERE id = ?", comment.ID).Scan(&pageID); err != nil {
+		return
+	}
+	page, err := models.NewPageStore(s.DB).GetByID(ctx, pageID)
+	if err != nil || page == nil {
+		return
+	}
+
+	commentURL := fmt.Sprintf("%s?comment=%s", page.Path, comment.ID)
+	err = s.NotificationQueue.EnqueueCommentReported(
+		comment.SiteID,
+		site.Name,
+		page.Title,
+		commentURL,
+		comment.Author,
+		comment.Text,
+		reportCount,
+		settings.OwnerEmail,
+	)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "failed to enqueue report notification", "error", err)
+	} else {
+		s.Logger.InfoContext(ctx, "enqueued report notification")
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// reportCommentRequest builds a POST report request from user
+func reportCommentRequest(siteID, commentID, body string, user *models.KotomiUser) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+siteID+"/comments/"+commentID+"/report", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "commentId": commentID})
+	return req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+}
+
+func TestReportComment_DedupeAndThreshold(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(store.GetDB()).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.ReportThreshold = 2
+	if err := models.NewSiteSettingsStore(store.GetDB()).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	now := time.Now()
+	if err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Rude", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	report := func(userID string) int {
+		rr := httptest.NewRecorder()
+		h.ReportComment(rr, reportCommentRequest(site.ID, "comment-1", `{"reason":"abusive"}`, &models.KotomiUser{ID: userID}))
+		return rr.Code
+	}
+	status := func() string {
+		comment, err := store.GetCommentByID(ctx, "comment-1")
+		if err != nil {
+			t.Fatalf("Failed to get comment: %v", err)
+		}
+		return comment.Status
+	}
+
+	if code := report("user-1"); code != http.StatusOK {
+		t.Fatalf("Expected first report to succeed, got %d", code)
+	}
+	if code := report("user-1"); code != http.StatusConflict {
+		t.Errorf("Expected duplicate report to return 409, got %d", code)
+	}
+	if got := status(); got != "approved" {
+		t.Errorf("Expected comment to stay approved below the threshold, got %s", got)
+	}
+
+	if code := report("user-2"); code != http.StatusOK {
+		t.Fatalf("Expected second report to succeed, got %d", code)
+	}
+	if got := status(); got != "pending" {
+		t.Errorf("Expected comment to be pending once the threshold is reached, got %s", got)
+	}
+
+	var source string
+	if err := store.GetDB().QueryRow(`SELECT source FROM moderation_events WHERE comment_id = ? AND decision = 'pending'`, "comment-1").Scan(&source); err != nil {
+		t.Fatalf("Expected a moderation event for the hidden comment: %v", err)
+	}
+	if source != "reports" {
+		t.Errorf("Expected moderation event source reports, got %s", source)
+	}
+}
+
+func TestReportComment_ThresholdDisabled(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(store.GetDB()).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(store.GetDB()).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	settings := models.DefaultSiteSettings(site.ID)
+	settings.ReportThreshold = 0
+	if err := models.NewSiteSettingsStore(store.GetDB()).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	now := time.Now()
+	if err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Rude", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	for _, userID := range []string{"user-1", "user-2", "user-3", "user-4"} {
+		rr := httptest.NewRecorder()
+		h.ReportComment(rr, reportCommentRequest(site.ID, "comment-1", "", &models.KotomiUser{ID: userID}))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected report from %s to succeed, got %d: %s", userID, rr.Code, rr.Body.String())
+		}
+	}
+
+	comment, err := store.GetCommentByID(ctx, "comment-1")
+	if err != nil {
+		t.Fatalf("Failed to get comment: %v", err)
+	}
+	if comment.Status != "approved" {
+		t.Errorf("Expected comment to stay approved with the threshold disabled, got %s", comment.Status)
+	}
+}
+
+func TestReportComment_OtherSiteNotFound(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	if err := store.AddPageComment(ctx, "site-1", "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ReportComment(rr, reportCommentRequest("site-2", "comment-1", "", &models.KotomiUser{ID: "user-1"}))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a comment on another site, got %d", rr.Code)
+	}
+}
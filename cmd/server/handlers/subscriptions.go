@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+)
+
+// PageSubscriptionResponse reports whether the authenticated user follows a page
+type PageSubscriptionResponse struct {
+	SiteID     string `json:"site_id"`
+	PageID     string `json:"page_id"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+// GetPageSubscription reports whether the user follows new comments on a page
+// @Summary Get page subscription
+// @Description Report whether the authenticated user is notified of new comments on a page
+// @Tags subscriptions
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param pageId path string true "Page ID"
+// @Success 200 {object} PageSubscriptionResponse
+// @Failure 401 {string} string "Authentication required"
+// @Failure 404 {string} string "Page not found"
+// @Failure 500 {string} string "Failed to load subscription"
+// @Security BearerAuth
+// @Router /site/{siteId}/page/{pageId}/subscribe [get]
+func (s *ServerHandlers) GetPageSubscription(w http.ResponseWriter, r *http.Request) {
+	s.handlePageSubscription(w, r, nil)
+}
+
+// SubscribePage subscribes the user to new comments on a page
+// @Summary Subscribe to a page
+// @Description Notify the authenticated user by email of new comments on a page, including after an earlier unsubscribe
+// @Tags subscriptions
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param pageId path string true "Page ID"
+// @Success 200 {object} PageSubscriptionResponse
+// @Failure 401 {string} string "Authentication required"
+// @Failure 404 {string} string "Page not found"
+// @Failure 500 {string} string "Failed to subscribe"
+// @Security BearerAuth
+// @Router /site/{siteId}/page/{pageId}/subscribe [post]
+func (s *ServerHandlers) SubscribePage(w http.ResponseWriter, r *http.Request) {
+	s.handlePageSubscription(w, r, (*notifications.SubscriptionStore).Subscribe)
+}
+
+// UnsubscribePage stops new comment notifications on a page for the user
+// @Summary Unsubscribe from a page
+// @Description Stop notifying the authenticated user of new comments on a page. Commenting on the page again doesn't resubscribe them.
+// @Tags subscriptions
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Param pageId path string true "Page ID"
+// @Success 200 {object} PageSubscriptionResponse
+// @Failure 401 {string} string "Authentication required"
+// @Failure 404 {string} string "Page not found"
+// @Failure 500 {string} string "Failed to unsubscribe"
+// @Security BearerAuth
+// @Router /site/{siteId}/page/{pageId}/subscribe [delete]
+func (s *ServerHandlers) UnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	s.handlePageSubscription(w, r, (*notifications.SubscriptionStore).Unsubscribe)
+}
+
+// handlePageSubscription applies change, if any, to the user's subscription to
+// the page and responds with the resulting state
+func (s *ServerHandlers) handlePageSubscription(w http.ResponseWriter, r *http.Request, change func(store *notifications.SubscriptionStore, siteID, pageID, userID string) error) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	pageID := vars["pageId"]
+
+	// Enrich context with site_id and page_id for automatic logging
+	ctx := r.Context()
+	ctx = logging.WithSiteID(ctx, siteID)
+	ctx = logging.WithPageID(ctx, pageID)
+
+	user := middleware.GetUserFromContext(ctx)
+	if user == nil {
+		apierrors.WriteError(w, apierrors.Unauthorized("Authentication required").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	if s.DB == nil {
+		apierrors.WriteError(w, apierrors.ServiceUnavailable("Subscriptions require a SQL database").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	page, err := models.NewPageStore(s.DB).GetByID(ctx, pageID)
+	if err != nil || page.SiteID != siteID {
+		apierrors.WriteError(w, apierrors.NotFound("Page not found").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	store := notifications.NewSubscriptionStore(s.DB)
+	if change != nil {
+		if err := change(store, siteID, pageID, user.ID); err != nil {
+			s.Logger.ErrorContext(ctx, "failed to update page subscription", "error", err)
+			apierrors.WriteError(w, apierrors.DatabaseError("Failed to update subscription").WithRequestID(middleware.GetRequestID(r)))
+			return
+		}
+	}
+
+	subscribed, err := store.IsSubscribed(siteID, pageID, user.ID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to load page subscription", "error", err)
+		apierrors.WriteError(w, apierrors.DatabaseError("Failed to load subscription").WithRequestID(middleware.GetRequestID(r)))
+		return
+	}
+
+	s.WriteJsonResponse(w, PageSubscriptionResponse{SiteID: siteID, PageID: pageID, Subscribed: subscribed})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+)
+
+func TestPageSubscriptions_NotifySubscribersButNotAuthor(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+	ctx := context.Background()
+	sqlDB := store.GetDB()
+	h.NotificationQueue = notifications.NewQueue(sqlDB, time.Minute, 10)
+
+	owner, err := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	// Comments are published immediately so subscribers hear about them
+	siteSettings := models.DefaultSiteSettings(site.ID)
+	siteSettings.CommentCooldownSeconds = 0
+	siteSettings.DefaultCommentStatus = models.CommentStatusApproved
+	if err := models.NewSiteSettingsStore(sqlDB).Upsert(ctx, siteSettings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+	page, err := models.NewPageStore(sqlDB).Create(ctx, site.ID, "/post", "Post")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	err = notifications.NewStore(sqlDB).SaveSettings(&notifications.NotificationSettings{
+		SiteID: site.ID, Enabled: true, Provider: "smtp", NotifyReply: true, OwnerEmail: "owner@example.com",
+	})
+	if err != nil {
+		t.Fatalf("Failed to save notification settings: %v", err)
+	}
+
+	users := map[string]*models.KotomiUser{}
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		users[id] = &models.KotomiUser{ID: id, Name: id, Email: id + "@example.com"}
+		err := models.NewUserStore(sqlDB).CreateOrUpdate(ctx, &models.User{ID: id, SiteID: site.ID, Name: id, Email: users[id].Email})
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	request := func(method, path, body string, user *models.KotomiUser) *http.Request {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"siteId": site.ID, "pageId": page.ID})
+		return req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, user))
+	}
+	post := func(user string, body string) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.PostComments(rr, request(http.MethodPost, "/api/v1/site/"+site.ID+"/page/"+page.ID+"/comments", body, users[user]))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	subscription := func(handler http.HandlerFunc, method, user string) bool {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler(rr, request(method, "/api/v1/site/"+site.ID+"/page/"+page.ID+"/subscribe", "", users[user]))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp PageSubscriptionResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Subscribed
+	}
+	recipients := func() []string {
+		t.Helper()
+		rows, err := sqlDB.Query(`SELECT recipient FROM notification_queue WHERE type = ? ORDER BY recipient`, notifications.NotificationPageComment)
+		if err != nil {
+			t.Fatalf("Failed to query notifications: %v", err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var to string
+			if err := rows.Scan(&to); err != nil {
+				t.Fatalf("Failed to scan notification: %v", err)
+			}
+			got = append(got, to)
+		}
+		if _, err := sqlDB.Exec(`DELETE FROM notification_queue`); err != nil {
+			t.Fatalf("Failed to clear queue: %v", err)
+		}
+		return got
+	}
+
+	// Carol follows the page explicitly; Dave follows and then stops
+	if !subscription(h.SubscribePage, http.MethodPost, "carol") {
+		t.Error("Expected carol to be subscribed")
+	}
+	subscription(h.SubscribePage, http.MethodPost, "dave")
+	if subscription(h.UnsubscribePage, http.MethodDelete, "dave") {
+		t.Error("Expected dave to be unsubscribed")
+	}
+
+	// Commenting subscribes Alice; she isn't notified of her own comment
+	post("alice", `{"text":"First!"}`)
+	if got := recipients(); !reflect.DeepEqual(got, []string{"carol@example.com"}) {
+		t.Errorf("Expected only carol to be notified, got %v", got)
+	}
+	if !subscription(h.GetPageSubscription, http.MethodGet, "alice") {
+		t.Error("Expected commenting to subscribe alice")
+	}
+
+	// Commenting again doesn't resubscribe Dave
+	post("dave", `{"text":"Me too"}`)
+	if got := recipients(); !reflect.DeepEqual(got, []string{"alice@example.com", "carol@example.com"}) {
+		t.Errorf("Expected alice and carol to be notified, got %v", got)
+	}
+	post("bob", `{"text":"Hello all"}`)
+	if got := recipients(); !reflect.DeepEqual(got, []string{"alice@example.com", "carol@example.com"}) {
+		t.Errorf("Expected alice and carol to be notified, got %v", got)
+	}
+	post("carol", `{"text":"Welcome, Bob"}`)
+	if got := recipients(); !reflect.DeepEqual(got, []string{"alice@example.com", "bob@example.com"}) {
+		t.Errorf("Expected alice and bob to be notified, got %v", got)
+	}
+
+	// A reply notifies the parent author once, as a reply
+	var parentID string
+	if err := sqlDB.QueryRow(`SELECT id FROM comments WHERE author_id = 'bob'`).Scan(&parentID); err != nil {
+		t.Fatalf("Failed to find bob's comment: %v", err)
+	}
+	post("alice", `{"text":"Hi Bob","parent_id":"`+parentID+`"}`)
+	var replies int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM notification_queue WHERE type = ? AND recipient = 'bob@example.com'`, notifications.NotificationCommentReply).Scan(&replies); err != nil || replies != 1 {
+		t.Errorf("Expected one reply notification to bob, got %d (err %v)", replies, err)
+	}
+	if got := recipients(); !reflect.DeepEqual(got, []string{"carol@example.com"}) {
+		t.Errorf("Expected only carol to be notified of the new comment, got %v", got)
+	}
+}
+
+func TestPageSubscriptions_UnknownPage(t *testing.T) {
+	h, store := newReadyzHandlers(t)
+	defer store.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/site-1/page/missing/subscribe", nil)
+	req = mux.SetURLVars(req, map[string]string{"siteId": "site-1", "pageId": "missing"})
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, &models.KotomiUser{ID: "alice"}))
+	rr := httptest.NewRecorder()
+	h.SubscribePage(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+)
+
+// unsubscribePage is the confirmation page shown after following an unsubscribe link
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{ .Title }} - Kotomi</title>
+    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/@picocss/pico@2/css/pico.min.css">
+</head>
+<body>
+    <main class="container">
+        <article>
+            <h1>{{ .Title }}</h1>
+            <p>{{ .Message }}</p>
+        </article>
+    </main>
+</body>
+</html>
+`))
+
+// Unsubscribe opts the recipient of a signed unsubscribe link out of notifications
+// @Summary Unsubscribe from notifications
+// @Description Validate an unsubscribe token from a notification email and stop emails for that recipient and site, or with page only new comment emails for that page
+// @Tags notifications
+// @Produce html
+// @Param token query string true "Signed unsubscribe token"
+// @Param page query string false "Page ID to stop following instead of unsubscribing from the site"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Invalid unsubscribe link"
+// @Failure 500 {string} string "Failed to unsubscribe"
+// @Router /unsubscribe [get]
+func (s *ServerHandlers) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tokens := notifications.NewUnsubscribeTokens(notifications.UnsubscribeSecret())
+	siteID, email, err := tokens.Parse(r.URL.Query().Get("token"))
+	if err != nil {
+		s.renderUnsubscribePage(w, http.StatusBadRequest, "Invalid unsubscribe link",
+			"This unsubscribe link is invalid or has been modified.")
+		return
+	}
+
+	// Page links only stop new comment emails for that page
+	if pageID := r.URL.Query().Get("page"); pageID != "" {
+		if err := notifications.NewSubscriptionStore(s.DB).UnsubscribeEmail(siteID, pageID, email); err != nil {
+			s.Logger.ErrorContext(ctx, "failed to record page unsubscribe", "site_id", siteID, "page_id", pageID, "error", err)
+			s.renderUnsubscribePage(w, http.StatusInternalServerError, "Something went wrong",
+				"We couldn't process your request. Please try again later.")
+			return
+		}
+
+		s.Logger.InfoContext(ctx, "recipient unsubscribed from page", "site_id", siteID, "page_id", pageID)
+		s.renderUnsubscribePage(w, http.StatusOK, "You're no longer following this page",
+			"You will no longer receive emails about new comments on this page.")
+		return
+	}
+
+	if err := notifications.NewUnsubscribeStore(s.DB).Unsubscribe(siteID, email); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to record unsubscribe", "site_id", siteID, "error", err)
+		s.renderUnsubscribePage(w, http.StatusInternalServerError, "Something went wrong",
+			"We couldn't process your request. Please try again later.")
+		return
+	}
+
+	s.Logger.InfoContext(ctx, "recipient unsubscribed", "site_id", siteID)
+	s.renderUnsubscribePage(w, http.StatusOK, "You're unsubscribed",
+		"You will no longer receive notification emails from this site.")
+}
+
+// renderUnsubscribePage writes the unsubscribe confirmation page
+func (s *ServerHandlers) renderUnsubscribePage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	data := struct {
+		Title   string
+		Message string
+	}{title, message}
+	if err := unsubscribePage.Execute(w, data); err != nil {
+		s.Logger.Error("failed to render unsubscribe page", "error", err)
+	}
+}
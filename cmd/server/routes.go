@@ -2,14 +2,19 @@ package server
 
 import (
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/saasuke-labs/kotomi/cmd/server/handlers"
 	"github.com/saasuke-labs/kotomi/pkg/admin"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/avatars"
 	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/openapi"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
-	_ "github.com/saasuke-labs/kotomi/docs" // Import generated docs
 )
 
 // RegisterRoutes registers all HTTP routes for the server
@@ -24,73 +29,80 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		s.NotificationQueue,
 		s.Logger,
 	)
-	
-	logger := middleware.NewLogger()
 
-	// Apply global middleware (request ID and logging)
+	// Analytics dashboards are cached in-process (ANALYTICS_CACHE_TTL) and
+	// invalidated when comments change
+	analyticsCache := analytics.NewCachedStoreFromEnv(s.DB)
+	h.AnalyticsCache = analyticsCache
+	h.Events = s.Events
+	
+	// Apply global middleware (request ID, then request logging with panic recovery)
 	router.Use(middleware.RequestIDMiddleware)
-	router.Use(middleware.LoggingMiddleware(logger))
+	router.Use(middleware.RequestLogger(s.Logger))
 
-	// Create CORS middleware
-	corsMiddleware := middleware.NewCORSMiddleware()
+	// Create CORS middleware (per-site allowed origins, falling back to the CORS_* environment policy)
+	corsMiddleware := middleware.NewSiteCORSMiddleware(s.DB)
 
 	// Create rate limiter middleware
 	rateLimiter := middleware.NewRateLimiter()
 
-	// API v1 routes (with CORS and rate limiting enabled)
-	apiV1Router := router.PathPrefix("/api/v1").Subrouter()
-	apiV1Router.Use(corsMiddleware.Handler)
-	apiV1Router.Use(rateLimiter.Handler)
-	
+	// Per-user rate limiters for write-heavy routes (keyed by authenticated user, falling back to IP)
+	commentPostLimiter := middleware.NewKeyedRateLimiterFromEnv("RATE_LIMIT_COMMENT_POST", 10, time.Minute)
+	reactionPostLimiter := middleware.NewKeyedRateLimiterFromEnv("RATE_LIMIT_REACTION_POST", 60, time.Minute)
+
+	limits := siteAPILimiters{
+		comments:     commentPostLimiter,
+		reactions:    reactionPostLimiter,
+		commentBody:  middleware.NewBodyLimitFromEnv("MAX_COMMENT_BODY_BYTES", middleware.DefaultCommentBodyBytes),
+		reactionBody: middleware.NewBodyLimitFromEnv("MAX_REACTION_BODY_BYTES", middleware.DefaultReactionBodyBytes),
+	}
+
+	// Site auth configs are cached for the shared-secret auth path and
+	// invalidated when they are edited in the admin panel
+	var authConfigs *models.CachedSiteAuthConfigStore
+	if s.DB != nil {
+		authConfigs = models.NewCachedSiteAuthConfigStore(s.DB, models.DefaultAuthConfigCacheTTL)
+	}
+
+	// Versioned API routes (with CORS and rate limiting enabled). Every version
+	// is registered before the legacy /api prefix so it can't shadow them.
+	for _, version := range handlers.APIVersions {
+		apiRouter := router.PathPrefix("/api/" + version).Subrouter()
+		apiRouter.Use(corsMiddleware.Handler)
+		apiRouter.Use(rateLimiter.Handler)
+		if authConfigs != nil {
+			apiRouter.Use(middleware.SharedSecretAuth(s.DB, authConfigs))
+		}
+		s.registerSiteAPI(apiRouter, version, h, limits)
+	}
+
 	// Kotomi authentication routes (no JWT auth required for these endpoints)
 	// Use the same Auth0 config as admin panel for kotomi auth mode
 	authHandler := auth.NewAuthHandler(s.DB, s.Auth0Config)
 	authHandler.RegisterRoutes(router)
-	
-	// Read-only routes (no auth required for phase 1)
-	apiV1Router.HandleFunc("/site/{siteId}/page/{pageId}/comments", h.GetComments).Methods("GET")
-	apiV1Router.HandleFunc("/site/{siteId}/allowed-reactions", h.GetAllowedReactions).Methods("GET")
-	apiV1Router.HandleFunc("/site/{siteId}/comments/{commentId}/reactions", h.GetReactionsByComment).Methods("GET")
-	apiV1Router.HandleFunc("/site/{siteId}/comments/{commentId}/reactions/counts", h.GetReactionCounts).Methods("GET")
-	apiV1Router.HandleFunc("/site/{siteId}/pages/{pageId}/reactions", h.GetReactionsByPage).Methods("GET")
-	apiV1Router.HandleFunc("/site/{siteId}/pages/{pageId}/reactions/counts", h.GetPageReactionCounts).Methods("GET")
-	
-	// Protected routes requiring JWT authentication
-	apiV1AuthRouter := apiV1Router.PathPrefix("").Subrouter()
-	apiV1AuthRouter.Use(middleware.JWTAuthMiddleware(s.DB))
-	apiV1AuthRouter.HandleFunc("/site/{siteId}/page/{pageId}/comments", h.PostComments).Methods("POST")
-	apiV1AuthRouter.HandleFunc("/site/{siteId}/comments/{commentId}", h.UpdateComment).Methods("PUT")
-	apiV1AuthRouter.HandleFunc("/site/{siteId}/comments/{commentId}", h.DeleteComment).Methods("DELETE")
-	apiV1AuthRouter.HandleFunc("/site/{siteId}/comments/{commentId}/reactions", h.AddReaction).Methods("POST")
-	apiV1AuthRouter.HandleFunc("/site/{siteId}/pages/{pageId}/reactions", h.AddPageReaction).Methods("POST")
-	apiV1AuthRouter.HandleFunc("/site/{siteId}/reactions/{reactionId}", h.RemoveReaction).Methods("DELETE")
 
 	// Legacy API routes (backward compatibility with deprecation warning)
 	legacyAPIRouter := router.PathPrefix("/api").Subrouter()
 	legacyAPIRouter.Use(corsMiddleware.Handler)
 	legacyAPIRouter.Use(rateLimiter.Handler)
-	legacyAPIRouter.Use(handlers.DeprecationMiddleware)
-	
-	// Read-only routes
-	legacyAPIRouter.HandleFunc("/site/{siteId}/page/{pageId}/comments", h.GetComments).Methods("GET")
-	legacyAPIRouter.HandleFunc("/site/{siteId}/allowed-reactions", h.GetAllowedReactions).Methods("GET")
-	legacyAPIRouter.HandleFunc("/site/{siteId}/comments/{commentId}/reactions", h.GetReactionsByComment).Methods("GET")
-	legacyAPIRouter.HandleFunc("/site/{siteId}/comments/{commentId}/reactions/counts", h.GetReactionCounts).Methods("GET")
-	legacyAPIRouter.HandleFunc("/site/{siteId}/pages/{pageId}/reactions", h.GetReactionsByPage).Methods("GET")
-	legacyAPIRouter.HandleFunc("/site/{siteId}/pages/{pageId}/reactions/counts", h.GetPageReactionCounts).Methods("GET")
-	
-	// Protected write routes
-	legacyAuthRouter := legacyAPIRouter.PathPrefix("").Subrouter()
-	legacyAuthRouter.Use(middleware.JWTAuthMiddleware(s.DB))
-	legacyAuthRouter.HandleFunc("/site/{siteId}/page/{pageId}/comments", h.PostComments).Methods("POST")
-	legacyAuthRouter.HandleFunc("/site/{siteId}/comments/{commentId}", h.UpdateComment).Methods("PUT")
-	legacyAuthRouter.HandleFunc("/site/{siteId}/comments/{commentId}", h.DeleteComment).Methods("DELETE")
-	legacyAuthRouter.HandleFunc("/site/{siteId}/comments/{commentId}/reactions", h.AddReaction).Methods("POST")
-	legacyAuthRouter.HandleFunc("/site/{siteId}/pages/{pageId}/reactions", h.AddPageReaction).Methods("POST")
-	legacyAuthRouter.HandleFunc("/site/{siteId}/reactions/{reactionId}", h.RemoveReaction).Methods("DELETE")
+	legacyAPIRouter.Use(handlers.NewDeprecationMiddleware(handlers.DeprecationConfigFromEnv()))
+	if authConfigs != nil {
+		legacyAPIRouter.Use(middleware.SharedSecretAuth(s.DB, authConfigs))
+	}
+	s.registerSiteAPI(legacyAPIRouter, "legacy", h, limits)
 
 	// Health check endpoint (no CORS needed, but harmless if included)
 	router.HandleFunc("/healthz", h.GetHealthz).Methods("GET")
+	// Readiness check (fails with 503 when the database can't be reached)
+	router.HandleFunc("/readyz", h.GetReadyz).Methods("GET")
+
+	// Unsubscribe links from notification emails (requires SQL database)
+	if s.DB != nil {
+		router.HandleFunc("/unsubscribe", h.Unsubscribe).Methods("GET")
+	}
+
+	// Avatar proxy, so widgets can load avatars from this origin instead of third-party hosts
+	router.Handle("/avatar", rateLimiter.Handler(avatars.NewProxy(avatars.ConfigFromEnv()))).Methods("GET")
 
 	// Static files
 	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
@@ -111,6 +123,8 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		// Comments handlers
 		commentsHandler := admin.NewCommentsHandler(s.DB, s.CommentStore, s.Templates)
 		commentsHandler.SetNotificationQueue(s.NotificationQueue)
+		commentsHandler.SetAnalyticsCache(analyticsCache)
+		commentsHandler.SetEventDispatcher(s.Events)
 		// Sites handlers
 		sitesHandler := admin.NewSitesHandler(s.DB, s.Templates)
 		adminRouter.HandleFunc("/sites", sitesHandler.ListSites).Methods("GET")
@@ -133,6 +147,8 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 
 		// Comments handlers already added earlier
 		adminRouter.HandleFunc("/sites/{siteId}/comments", commentsHandler.ListComments).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/comments/export", commentsHandler.ExportComments).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/moderation/queue", commentsHandler.GetModerationQueue).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/pages/{pageId}/comments", commentsHandler.ListPageComments).Methods("GET")
 		adminRouter.HandleFunc("/comments/{commentId}/approve", commentsHandler.ApproveComment).Methods("POST")
 		adminRouter.HandleFunc("/comments/{commentId}/reject", commentsHandler.RejectComment).Methods("POST")
@@ -150,6 +166,7 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		adminRouter.HandleFunc("/sites/{siteId}/reactions", reactionsHandler.CreateAllowedReaction).Methods("POST")
 		adminRouter.HandleFunc("/sites/{siteId}/reactions/{reactionId}/edit", reactionsHandler.ShowReactionForm).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/reactions/{reactionId}", reactionsHandler.UpdateAllowedReaction).Methods("PUT")
+		adminRouter.HandleFunc("/sites/{siteId}/reactions/{reactionId}/type", reactionsHandler.ChangeAllowedReactionType).Methods("PUT")
 		adminRouter.HandleFunc("/sites/{siteId}/reactions/{reactionId}", reactionsHandler.DeleteAllowedReaction).Methods("DELETE")
 		adminRouter.HandleFunc("/sites/{siteId}/reactions/stats", reactionsHandler.GetReactionStats).Methods("GET")
 
@@ -163,20 +180,49 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		adminRouter.HandleFunc("/sites/{siteId}/notifications", notificationsHandler.HandleNotificationsForm).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/notifications", notificationsHandler.HandleNotificationsUpdate).Methods("POST")
 		adminRouter.HandleFunc("/sites/{siteId}/notifications/test", notificationsHandler.HandleTestEmail).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/notifications/templates", notificationsHandler.ListTemplates).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/notifications/templates/{type}", notificationsHandler.SaveTemplate).Methods("PUT")
+		adminRouter.HandleFunc("/sites/{siteId}/notifications/templates/{type}", notificationsHandler.DeleteTemplate).Methods("DELETE")
 
 		// Auth configuration handlers
 		authConfigHandler := admin.NewAuthConfigHandler(s.DB, s.Templates)
+		authConfigHandler.SetCache(authConfigs)
 		adminRouter.HandleFunc("/sites/{siteId}/auth", authConfigHandler.HandleAuthConfigForm).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/auth/config", authConfigHandler.GetAuthConfig).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/auth/config", authConfigHandler.CreateAuthConfig).Methods("POST")
 		adminRouter.HandleFunc("/sites/{siteId}/auth/config", authConfigHandler.UpdateAuthConfig).Methods("PUT")
 		adminRouter.HandleFunc("/sites/{siteId}/auth/config", authConfigHandler.DeleteAuthConfig).Methods("DELETE")
 
+		// Site API keys for server-to-server calls
+		apiKeysHandler := admin.NewAPIKeysHandler(s.DB)
+		adminRouter.HandleFunc("/sites/{siteId}/api-keys", apiKeysHandler.ListAPIKeys).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/api-keys", apiKeysHandler.CreateAPIKey).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/api-keys/{keyId}", apiKeysHandler.RevokeAPIKey).Methods("DELETE")
+
+		// Outbound webhook subscriptions
+		webhooksHandler := admin.NewWebhooksHandler(s.DB)
+		adminRouter.HandleFunc("/sites/{siteId}/webhooks", webhooksHandler.ListSubscriptions).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/webhooks", webhooksHandler.CreateSubscription).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/webhooks/{subscriptionId}", webhooksHandler.UpdateSubscription).Methods("PUT")
+		adminRouter.HandleFunc("/sites/{siteId}/webhooks/{subscriptionId}", webhooksHandler.DeleteSubscription).Methods("DELETE")
+		adminRouter.HandleFunc("/sites/{siteId}/webhooks/{subscriptionId}/deliveries", webhooksHandler.ListDeliveries).Methods("GET")
+
 		// User management handlers (Phase 2)
 		userMgmtHandler := admin.NewUserManagementHandler(s.DB, s.Templates)
+		userMgmtHandler.SetAnalyticsCache(analyticsCache)
 		adminRouter.HandleFunc("/sites/{siteId}/users", userMgmtHandler.ListUsersPage).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/users/{userId}", userMgmtHandler.GetUserDetailPage).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/users/{userId}", userMgmtHandler.DeleteUserHandler).Methods("DELETE")
+		adminRouter.HandleFunc("/sites/{siteId}/users/{userId}/verify", userMgmtHandler.VerifyUserHandler).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/users/{userId}/unverify", userMgmtHandler.UnverifyUserHandler).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/users/{userId}/export", userMgmtHandler.ExportUserDataHandler).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/users/{userId}/erase", userMgmtHandler.DeleteUserDataHandler).Methods("POST")
+
+		// Blocked author handlers
+		blockedAuthorsHandler := admin.NewBlockedAuthorsHandler(s.DB)
+		adminRouter.HandleFunc("/sites/{siteId}/blocked-authors", blockedAuthorsHandler.ListBlockedAuthors).Methods("GET")
+		adminRouter.HandleFunc("/sites/{siteId}/blocked-authors", blockedAuthorsHandler.BlockAuthor).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/blocked-authors/{authorId}", blockedAuthorsHandler.UnblockAuthor).Methods("DELETE")
 
 		// Export/Import handlers
 		exportImportHandler := admin.NewExportImportHandler(s.DB, s.Templates)
@@ -184,9 +230,15 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		adminRouter.HandleFunc("/sites/{siteId}/export", exportImportHandler.ExportData).Methods("POST")
 		adminRouter.HandleFunc("/sites/{siteId}/import", exportImportHandler.ShowImportForm).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/import", exportImportHandler.ImportData).Methods("POST")
+		adminRouter.HandleFunc("/sites/{siteId}/import/progress/{uploadId}", exportImportHandler.ImportProgressStream).Methods("GET")
+
+		// Audit log of owners' changes
+		auditHandler := admin.NewAuditHandler(s.DB)
+		adminRouter.HandleFunc("/sites/{siteId}/audit", auditHandler.GetAuditLog).Methods("GET")
 
 		// Analytics handlers
 		analyticsHandler := admin.NewAnalyticsHandler(s.DB, s.Templates)
+		analyticsHandler.SetCache(analyticsCache)
 		adminRouter.HandleFunc("/sites/{siteId}/analytics", analyticsHandler.ShowDashboard).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/analytics/data", analyticsHandler.GetAnalyticsData).Methods("GET")
 		adminRouter.HandleFunc("/sites/{siteId}/analytics/export", analyticsHandler.ExportCSV).Methods("GET")
@@ -202,9 +254,10 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		}).Methods("GET")
 	}
 
-	// Swagger documentation (only in development)
-	if s.Auth0Config != nil {
-		router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	// OpenAPI 3 spec and Swagger UI for the public API (not in production)
+	if os.Getenv("ENV") != "production" {
+		router.Handle("/swagger.json", openapi.Handler()).Methods("GET")
+		router.PathPrefix("/swagger/").Handler(httpSwagger.Handler(httpSwagger.URL("/swagger.json")))
 	}
 
 	// Root handler - show login or info page
@@ -212,3 +265,52 @@ func (s *Server) RegisterRoutes(router *mux.Router) {
 		http.ServeFile(w, r, "static/index.html")
 	}).Methods("GET")
 }
+
+// siteAPILimiters are the per-user rate limiters and body size limits shared by every API version
+type siteAPILimiters struct {
+	comments     *middleware.KeyedRateLimiter
+	reactions    *middleware.KeyedRateLimiter
+	commentBody  *middleware.BodyLimit
+	reactionBody *middleware.BodyLimit
+}
+
+// registerSiteAPI registers the public comment and reaction routes on api.
+// Routes are named "<version>:<Handler>" so each version's dispatch can be
+// checked with router.Get.
+func (s *Server) registerSiteAPI(api *mux.Router, version string, h *handlers.ServerHandlers, limits siteAPILimiters) {
+	name := func(handler string) string { return version + ":" + handler }
+
+	api.PathPrefix("/site/{siteId}/").Methods("OPTIONS").HandlerFunc(middleware.PreflightHandler)
+
+	// Read-only routes (no auth required)
+	getComments := h.GetComments
+	if version == "v2" {
+		// v2 wraps the comment list with the server time for ?since= polling
+		getComments = h.GetCommentsV2
+	}
+	api.HandleFunc("/site/{siteId}/page/{pageId}/comments", getComments).Methods("GET").Name(name("GetComments"))
+	api.HandleFunc("/site/{siteId}/page/{pageId}/comments/search", h.SearchComments).Methods("GET").Name(name("SearchComments"))
+	api.HandleFunc("/site/{siteId}/comment-counts", h.GetCommentCounts).Methods("GET").Name(name("GetCommentCounts"))
+	api.HandleFunc("/site/{siteId}/allowed-reactions", h.GetAllowedReactions).Methods("GET").Name(name("GetAllowedReactions"))
+	api.Handle("/site/{siteId}/comments/{commentId}", middleware.OptionalAuth(s.DB)(http.HandlerFunc(h.GetComment))).Methods("GET").Name(name("GetComment"))
+	api.HandleFunc("/site/{siteId}/comments/{commentId}/reactions", h.GetReactionsByComment).Methods("GET").Name(name("GetReactionsByComment"))
+	api.Handle("/site/{siteId}/comments/{commentId}/reactions/counts", middleware.OptionalAuth(s.DB)(http.HandlerFunc(h.GetReactionCounts))).Methods("GET").Name(name("GetReactionCounts"))
+	api.HandleFunc("/site/{siteId}/pages/{pageId}/reactions", h.GetReactionsByPage).Methods("GET").Name(name("GetReactionsByPage"))
+	api.Handle("/site/{siteId}/pages/{pageId}/reactions/counts", middleware.OptionalAuth(s.DB)(http.HandlerFunc(h.GetPageReactionCounts))).Methods("GET").Name(name("GetPageReactionCounts"))
+
+	// Protected routes requiring JWT authentication
+	authRouter := api.PathPrefix("").Subrouter()
+	authRouter.Use(middleware.APIKeyAuth(s.DB))
+	authRouter.Use(middleware.JWTAuthMiddleware(s.DB))
+	authRouter.Handle("/site/{siteId}/page/{pageId}/comments", limits.comments.Handler(limits.commentBody.Wrap(h.PostComments))).Methods("POST").Name(name("PostComments"))
+	authRouter.HandleFunc("/site/{siteId}/users/me/comments", h.GetMyComments).Methods("GET").Name(name("GetMyComments"))
+	authRouter.Handle("/site/{siteId}/comments/{commentId}", limits.commentBody.Wrap(h.UpdateComment)).Methods("PUT").Name(name("UpdateComment"))
+	authRouter.HandleFunc("/site/{siteId}/comments/{commentId}", h.DeleteComment).Methods("DELETE").Name(name("DeleteComment"))
+	authRouter.Handle("/site/{siteId}/comments/{commentId}/reactions", limits.reactions.Handler(limits.reactionBody.Wrap(h.AddReaction))).Methods("POST").Name(name("AddReaction"))
+	authRouter.Handle("/site/{siteId}/comments/{commentId}/report", limits.comments.Handler(limits.commentBody.Wrap(h.ReportComment))).Methods("POST").Name(name("ReportComment"))
+	authRouter.Handle("/site/{siteId}/pages/{pageId}/reactions", limits.reactions.Handler(limits.reactionBody.Wrap(h.AddPageReaction))).Methods("POST").Name(name("AddPageReaction"))
+	authRouter.HandleFunc("/site/{siteId}/reactions/{reactionId}", h.RemoveReaction).Methods("DELETE").Name(name("RemoveReaction"))
+	authRouter.HandleFunc("/site/{siteId}/page/{pageId}/subscribe", h.GetPageSubscription).Methods("GET").Name(name("GetPageSubscription"))
+	authRouter.HandleFunc("/site/{siteId}/page/{pageId}/subscribe", h.SubscribePage).Methods("POST").Name(name("SubscribePage"))
+	authRouter.HandleFunc("/site/{siteId}/page/{pageId}/subscribe", h.UnsubscribePage).Methods("DELETE").Name(name("UnsubscribePage"))
+}
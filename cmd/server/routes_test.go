@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/cmd/server/handlers"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/openapi"
+)
+
+// newTestRouter registers every route against a temporary SQLite database
+func newTestRouter(t *testing.T) (*mux.Router, *db.SQLiteAdapter) {
+	t.Helper()
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "routes.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	s, err := New(Config{
+		CommentStore: store,
+		DB:           store.GetDB(),
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	router := mux.NewRouter()
+	s.RegisterRoutes(router)
+	return router, store
+}
+
+func TestRegisterRoutes_VersionDispatch(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/v1/site/s1/page/p1/comments", "v1:GetComments"},
+		{"GET", "/api/v2/site/s1/page/p1/comments", "v2:GetComments"},
+		{"GET", "/api/site/s1/page/p1/comments", "legacy:GetComments"},
+		{"POST", "/api/v1/site/s1/page/p1/comments", "v1:PostComments"},
+		{"POST", "/api/v2/site/s1/page/p1/comments", "v2:PostComments"},
+		{"GET", "/api/v2/site/s1/comments/c1", "v2:GetComment"},
+		{"DELETE", "/api/v2/site/s1/comments/c1", "v2:DeleteComment"},
+		{"POST", "/api/v2/site/s1/pages/p1/reactions", "v2:AddPageReaction"},
+		{"GET", "/api/v1/site/s1/comments/c1/reactions/counts", "v1:GetReactionCounts"},
+		{"GET", "/api/v2/site/s1/users/me/comments", "v2:GetMyComments"},
+		{"GET", "/api/v1/site/s1/page/p1/comments/search", "v1:SearchComments"},
+		{"POST", "/api/v2/site/s1/page/p1/subscribe", "v2:SubscribePage"},
+		{"DELETE", "/api/v1/site/s1/page/p1/subscribe", "v1:UnsubscribePage"},
+	}
+
+	for _, tt := range tests {
+		var match mux.RouteMatch
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if !router.Match(req, &match) || match.Route == nil {
+			t.Errorf("%s %s: no route matched", tt.method, tt.path)
+			continue
+		}
+		if got := match.Route.GetName(); got != tt.want {
+			t.Errorf("%s %s: dispatched to %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	for _, version := range handlers.APIVersions {
+		if router.Get(version+":GetComments") == nil {
+			t.Errorf("Expected %s to register GetComments", version)
+		}
+	}
+}
+
+func TestRegisterRoutes_VersionResponses(t *testing.T) {
+	router, store := newTestRouter(t)
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := store.AddPageComment(context.Background(), "site-1", "page-1", comments.Comment{
+		ID: "comment-1", AuthorID: "author-1", Author: "Alice", Text: "Hello", Status: "approved", CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		return rr
+	}
+
+	v1 := get("/api/v1/site/site-1/page/page-1/comments")
+	v2 := get("/api/v2/site/site-1/page/page-1/comments")
+	legacy := get("/api/site/site-1/page/page-1/comments")
+
+	// v2 wraps the same comments with the server time
+	var list []json.RawMessage
+	if err := json.Unmarshal(v1.Body.Bytes(), &list); err != nil || len(list) != 1 {
+		t.Fatalf("Expected v1 to return a bare list of one comment, got %s", v1.Body.String())
+	}
+	var wrapped struct {
+		Comments   []json.RawMessage `json:"comments"`
+		ServerTime time.Time         `json:"server_time"`
+	}
+	if err := json.Unmarshal(v2.Body.Bytes(), &wrapped); err != nil {
+		t.Fatalf("Expected v2 to return an object, got %s", v2.Body.String())
+	}
+	if len(wrapped.Comments) != 1 || string(wrapped.Comments[0]) != string(list[0]) {
+		t.Errorf("Expected v2 to wrap the v1 comments:\nv1: %s\nv2: %s", v1.Body.String(), v2.Body.String())
+	}
+	if wrapped.ServerTime.IsZero() {
+		t.Error("Expected v2 to include server_time")
+	}
+	if v1.Header().Get("ETag") == v2.Header().Get("ETag") {
+		t.Error("Expected v1 and v2 lists to have different ETags")
+	}
+
+	if v1.Body.String() != legacy.Body.String() {
+		t.Errorf("Expected legacy body to match v1:\nv1: %s\nlegacy: %s", v1.Body.String(), legacy.Body.String())
+	}
+
+	if v1.Header().Get("Deprecation") != "" || v2.Header().Get("Deprecation") != "" {
+		t.Error("Expected versioned routes not to be marked deprecated")
+	}
+	if legacy.Header().Get("Deprecation") != "true" {
+		t.Error("Expected legacy route to be marked deprecated")
+	}
+}
+
+// TestRegisterRoutes_SpecCoversPublicRoutes walks the registered routes so a
+// public API route can't be added without an OpenAPI entry
+func TestRegisterRoutes_SpecCoversPublicRoutes(t *testing.T) {
+	router, _ := newTestRouter(t)
+	spec := openapi.Spec()
+	base := spec.Servers[0].URL
+
+	checked := 0
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, base+"/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		path = strings.TrimPrefix(path, base)
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			checked++
+			item, ok := spec.Paths[path]
+			if !ok {
+				t.Errorf("Missing path %s", path)
+				continue
+			}
+			if item.Operations()[strings.ToLower(method)] == nil {
+				t.Errorf("Missing %s %s", method, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk routes: %v", err)
+	}
+	if checked == 0 {
+		t.Fatalf("Expected routes under %s", base)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/saasuke-labs/kotomi/pkg/db"
 	"github.com/saasuke-labs/kotomi/pkg/moderation"
 	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // Server holds all dependencies for the application
@@ -20,8 +21,9 @@ type Server struct {
 	Templates             *template.Template
 	Auth0Config           *auth.Auth0Config
 	Moderator             moderation.Moderator
-	ModerationConfigStore *moderation.ConfigStore
+	ModerationConfigStore moderation.ConfigRepository
 	NotificationQueue     *notifications.Queue
+	Events                *webhooks.Dispatcher // Optional; delivers comment and reaction webhooks
 	Logger                *slog.Logger
 }
 
@@ -35,6 +37,7 @@ func New(cfg Config) (*Server, error) {
 		Moderator:             cfg.Moderator,
 		ModerationConfigStore: cfg.ModerationConfigStore,
 		NotificationQueue:     cfg.NotificationQueue,
+		Events:                cfg.Events,
 		Logger:                cfg.Logger,
 	}
 	
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long in-flight requests get to finish on shutdown
+const DefaultShutdownTimeout = 15 * time.Second
+
+// ShutdownTimeoutFromEnv reads the drain timeout from SHUTDOWN_TIMEOUT (a Go
+// duration such as "30s"), falling back to DefaultShutdownTimeout
+func ShutdownTimeoutFromEnv() time.Duration {
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return DefaultShutdownTimeout
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then stops accepting
+// new connections and waits up to drainTimeout for in-flight requests to
+// finish. It returns once httpServer has fully stopped, so callers can close
+// the database afterwards; the error is non-nil if serving failed or the
+// drain timed out.
+func Serve(ctx context.Context, httpServer *http.Server, ln net.Listener, drainTimeout time.Duration, logger *slog.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down: no longer accepting connections", "drain_timeout", drainTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("timed out draining connections", "error", err)
+		httpServer.Close()
+		return err
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logger.Info("all connections drained")
+	return nil
+}
+
+// ListenAndServe listens on httpServer.Addr and serves it like Serve
+func ListenAndServe(ctx context.Context, httpServer *http.Server, drainTimeout time.Duration, logger *slog.Logger) error {
+	addr := httpServer.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, httpServer, ln, drainTimeout, logger)
+}
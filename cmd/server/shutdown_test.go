@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.Write([]byte("done"))
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, httpServer, ln, 5*time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+	<-entered
+
+	cancel()
+
+	// Once the listener is closed, new connections are refused
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("Expected new connections to be refused during shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case err := <-served:
+		t.Fatalf("Expected Serve to wait for the in-flight request, returned %v", err)
+	default:
+	}
+
+	close(release)
+	if res := <-inFlight; res.err != nil || res.body != "done" {
+		t.Errorf("Expected the in-flight request to complete, got %q (%v)", res.body, res.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestServe_DrainTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, httpServer, ln, 50*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}()
+
+	go http.Get("http://" + ln.Addr().String() + "/")
+	<-entered
+	cancel()
+
+	select {
+	case err := <-served:
+		if err == nil {
+			t.Error("Expected an error when in-flight requests outlive the drain timeout")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Serve to give up after the drain timeout")
+	}
+}
@@ -2,13 +2,11 @@ package admin
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/saasuke-labs/kotomi/pkg/analytics"
@@ -20,6 +18,7 @@ import (
 type AnalyticsHandler struct {
 	db        *sql.DB
 	templates *template.Template
+	cache     *analytics.CachedStore
 }
 
 // NewAnalyticsHandler creates a new analytics handler
@@ -27,9 +26,24 @@ func NewAnalyticsHandler(db *sql.DB, templates *template.Template) *AnalyticsHan
 	return &AnalyticsHandler{
 		db:        db,
 		templates: templates,
+		cache:     analytics.NewCachedStore(db, 0, false),
 	}
 }
 
+// topReactorsLimit is how many users the optional top reactors section lists
+const topReactorsLimit = 10
+
+// dashboardData is the JSON dashboard with the optional top reactors section
+type dashboardData struct {
+	*analytics.AnalyticsDashboard
+	TopReactors []models.ReactorStat `json:"top_reactors,omitempty"`
+}
+
+// SetCache sets a shared analytics cache used to serve dashboard data
+func (h *AnalyticsHandler) SetCache(cache *analytics.CachedStore) {
+	h.cache = cache
+}
+
 // ShowDashboard displays the analytics dashboard for a site
 func (h *AnalyticsHandler) ShowDashboard(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -38,14 +52,14 @@ func (h *AnalyticsHandler) ShowDashboard(w http.ResponseWriter, r *http.Request)
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -60,24 +74,33 @@ func (h *AnalyticsHandler) ShowDashboard(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Get analytics data
-	store := analytics.NewStore(h.db)
-	dashboard, err := store.GetAnalyticsDashboard(siteID, dateRange)
+	dashboard, err := h.cache.GetAnalyticsDashboard(r.Context(), siteID, dateRange)
 	if err != nil {
 		log.Printf("Error fetching analytics: %v", err)
-		http.Error(w, "Failed to fetch analytics", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch analytics")
+		return
+	}
+
+	topReactors, err := h.topReactors(r, siteID, dateRange)
+	if err != nil {
+		log.Printf("Error fetching top reactors: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch analytics")
 		return
 	}
 
 	data := map[string]interface{}{
-		"Site":      site,
-		"Dashboard": dashboard,
-		"DateFrom":  dateRange.From.Format("2006-01-02"),
-		"DateTo":    dateRange.To.Format("2006-01-02"),
+		"Site":            site,
+		"Dashboard":       dashboard,
+		"DateFrom":        dateRange.From.Format("2006-01-02"),
+		"DateTo":          dateRange.To.Format("2006-01-02"),
+		"ShowTopReactors": topReactors != nil,
+		"TopReactors":     topReactors,
+		"IncludeOwner":    r.URL.Query().Get("include_owner") != "",
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "admin/analytics/dashboard.html", data); err != nil {
 		log.Printf("Template error: %v", err)
-		http.Error(w, "Template error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error")
 	}
 }
 
@@ -89,14 +112,14 @@ func (h *AnalyticsHandler) GetAnalyticsData(w http.ResponseWriter, r *http.Reque
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -111,19 +134,38 @@ func (h *AnalyticsHandler) GetAnalyticsData(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Get analytics data
-	store := analytics.NewStore(h.db)
-	dashboard, err := store.GetAnalyticsDashboard(siteID, dateRange)
+	dashboard, err := h.cache.GetAnalyticsDashboard(r.Context(), siteID, dateRange)
 	if err != nil {
 		log.Printf("Error fetching analytics: %v", err)
-		http.Error(w, "Failed to fetch analytics", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch analytics")
+		return
+	}
+
+	topReactors, err := h.topReactors(r, siteID, dateRange)
+	if err != nil {
+		log.Printf("Error fetching top reactors: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch analytics")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dashboard)
+	json.NewEncoder(w).Encode(dashboardData{AnalyticsDashboard: dashboard, TopReactors: topReactors})
+}
+
+// topReactors loads the optional top reactors section when the request asks
+// for it with top_reactors, leaving out the site owner unless include_owner is
+// set. It returns nil when the section wasn't requested.
+func (h *AnalyticsHandler) topReactors(r *http.Request, siteID string, dateRange analytics.DateRange) ([]models.ReactorStat, error) {
+	query := r.URL.Query()
+	if query.Get("top_reactors") == "" {
+		return nil, nil
+	}
+	excludeOwner := query.Get("include_owner") == ""
+	return models.NewReactionStore(h.db).GetTopReactors(r.Context(), siteID, dateRange, topReactorsLimit, excludeOwner)
 }
 
-// ExportCSV exports analytics data to CSV format
+// ExportCSV handles GET /admin/sites/{siteId}/analytics/export?format=csv,
+// downloading the dashboard for the from/to date range as CSV
 func (h *AnalyticsHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	siteID := vars["siteId"]
@@ -131,14 +173,19 @@ func (h *AnalyticsHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "csv" {
+		writeError(w, r, http.StatusBadRequest, "Invalid format. Use 'csv'")
 		return
 	}
 
@@ -153,94 +200,16 @@ func (h *AnalyticsHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get analytics data
-	store := analytics.NewStore(h.db)
-	dashboard, err := store.GetAnalyticsDashboard(siteID, dateRange)
+	dashboard, err := h.cache.GetAnalyticsDashboard(r.Context(), siteID, dateRange)
 	if err != nil {
 		log.Printf("Error fetching analytics: %v", err)
-		http.Error(w, "Failed to fetch analytics", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch analytics")
 		return
 	}
 
-	// Set CSV headers
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=analytics-%s.csv", siteID))
-
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-
-	// Write comment metrics
-	writer.Write([]string{"Comment Metrics"})
-	writer.Write([]string{"Metric", "Value"})
-	writer.Write([]string{"Total Comments", strconv.Itoa(dashboard.Comments.Total)})
-	writer.Write([]string{"Pending", strconv.Itoa(dashboard.Comments.Pending)})
-	writer.Write([]string{"Approved", strconv.Itoa(dashboard.Comments.Approved)})
-	writer.Write([]string{"Rejected", strconv.Itoa(dashboard.Comments.Rejected)})
-	writer.Write([]string{"Approval Rate", fmt.Sprintf("%.2f%%", dashboard.Comments.ApprovalRate)})
-	writer.Write([]string{"Today", strconv.Itoa(dashboard.Comments.TotalToday)})
-	writer.Write([]string{"This Week", strconv.Itoa(dashboard.Comments.TotalThisWeek)})
-	writer.Write([]string{"This Month", strconv.Itoa(dashboard.Comments.TotalThisMonth)})
-	writer.Write([]string{})
-
-	// Write user metrics
-	writer.Write([]string{"User Metrics"})
-	writer.Write([]string{"Metric", "Value"})
-	writer.Write([]string{"Total Users", strconv.Itoa(dashboard.Users.TotalUsers)})
-	writer.Write([]string{"Active Today", strconv.Itoa(dashboard.Users.ActiveUsersToday)})
-	writer.Write([]string{"Active This Week", strconv.Itoa(dashboard.Users.ActiveUsersWeek)})
-	writer.Write([]string{"Active This Month", strconv.Itoa(dashboard.Users.ActiveUsersMonth)})
-	writer.Write([]string{})
-
-	// Write top contributors
-	writer.Write([]string{"Top Contributors"})
-	writer.Write([]string{"Name", "Email", "Comments"})
-	for _, contributor := range dashboard.Users.TopContributors {
-		writer.Write([]string{contributor.Name, contributor.Email, strconv.Itoa(contributor.CommentCount)})
-	}
-	writer.Write([]string{})
-
-	// Write reaction metrics
-	writer.Write([]string{"Reaction Metrics"})
-	writer.Write([]string{"Metric", "Value"})
-	writer.Write([]string{"Total Reactions", strconv.Itoa(dashboard.Reactions.Total)})
-	writer.Write([]string{"Today", strconv.Itoa(dashboard.Reactions.TotalToday)})
-	writer.Write([]string{"This Week", strconv.Itoa(dashboard.Reactions.TotalThisWeek)})
-	writer.Write([]string{"This Month", strconv.Itoa(dashboard.Reactions.TotalThisMonth)})
-	writer.Write([]string{})
-
-	// Write reaction breakdown
-	writer.Write([]string{"Reactions by Type"})
-	writer.Write([]string{"Name", "Emoji", "Count"})
-	for _, reaction := range dashboard.Reactions.ByType {
-		writer.Write([]string{reaction.Name, reaction.Emoji, strconv.Itoa(reaction.Count)})
-	}
-	writer.Write([]string{})
-
-	// Write moderation metrics
-	writer.Write([]string{"Moderation Metrics"})
-	writer.Write([]string{"Metric", "Value"})
-	writer.Write([]string{"Total Moderated", strconv.Itoa(dashboard.Moderation.TotalModerated)})
-	writer.Write([]string{"Auto Rejected", strconv.Itoa(dashboard.Moderation.AutoRejected)})
-	writer.Write([]string{"Auto Approved", strconv.Itoa(dashboard.Moderation.AutoApproved)})
-	writer.Write([]string{"Manual Reviews", strconv.Itoa(dashboard.Moderation.ManualReviews)})
-	writer.Write([]string{"Avg Moderation Time (sec)", fmt.Sprintf("%.2f", dashboard.Moderation.AverageModerationSec)})
-	writer.Write([]string{"Spam Detection Rate", fmt.Sprintf("%.2f%%", dashboard.Moderation.SpamDetectionRate)})
-	writer.Write([]string{})
-
-	// Write time series data
-	writer.Write([]string{"Comments Trend"})
-	writer.Write([]string{"Date", "Count"})
-	for i, label := range dashboard.CommentsTrend.Labels {
-		if i < len(dashboard.CommentsTrend.Values) {
-			writer.Write([]string{label, strconv.Itoa(dashboard.CommentsTrend.Values[i])})
-		}
-	}
-	writer.Write([]string{})
-
-	writer.Write([]string{"Reactions Trend"})
-	writer.Write([]string{"Date", "Count"})
-	for i, label := range dashboard.ReactionsTrend.Labels {
-		if i < len(dashboard.ReactionsTrend.Values) {
-			writer.Write([]string{label, strconv.Itoa(dashboard.ReactionsTrend.Values[i])})
-		}
+	if err := dashboard.ToCSV(w); err != nil {
+		log.Printf("Error writing analytics CSV for site %s: %v", siteID, err)
 	}
 }
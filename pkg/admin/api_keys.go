@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// maxAPIKeyNameLength caps the label given to an API key
+const maxAPIKeyNameLength = 100
+
+// APIKeysHandler handles a site's API keys for server-to-server calls
+type APIKeysHandler struct {
+	db *sql.DB
+}
+
+// NewAPIKeysHandler creates a new API keys handler
+func NewAPIKeysHandler(db *sql.DB) *APIKeysHandler {
+	return &APIKeysHandler{db: db}
+}
+
+// CreatedAPIKey is a new key together with its plaintext, which is only
+// returned by the request that created it
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// verifySiteOwner writes an error and returns false unless the current user owns the site
+func (h *APIKeysHandler) verifySiteOwner(w http.ResponseWriter, r *http.Request, siteID string) bool {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil || site == nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return false
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return false
+	}
+
+	return true
+}
+
+// ListAPIKeys handles GET /admin/sites/{siteId}/api-keys
+func (h *APIKeysHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	keys, err := models.NewAPIKeyStore(h.db).ListBySite(r.Context(), siteID)
+	if err != nil {
+		log.Printf("Error fetching API keys: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey handles POST /admin/sites/{siteId}/api-keys. The response is
+// the only place the key's plaintext is ever shown.
+func (h *APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, r, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len([]rune(req.Name)) > maxAPIKeyNameLength {
+		writeError(w, r, http.StatusBadRequest, "name is too long")
+		return
+	}
+
+	key, plaintext, err := models.NewAPIKeyStore(h.db).Create(r.Context(), siteID, req.Name)
+	if err != nil {
+		log.Printf("Error creating API key on site %s: %v", siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedAPIKey{APIKey: *key, Key: plaintext})
+}
+
+// RevokeAPIKey handles DELETE /admin/sites/{siteId}/api-keys/{keyId}
+func (h *APIKeysHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	if err := models.NewAPIKeyStore(h.db).Revoke(r.Context(), siteID, vars["keyId"]); err != nil {
+		if errors.Is(err, models.ErrAPIKeyNotFound) {
+			writeError(w, r, http.StatusNotFound, "API key not found")
+			return
+		}
+		log.Printf("Error revoking API key %s on site %s: %v", vars["keyId"], siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
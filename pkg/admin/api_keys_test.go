@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func TestAPIKeysHandler(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminStore := models.NewAdminUserStore(sqlDB)
+	owner, _ := adminStore.Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	other, _ := adminStore.Create(ctx, "other@example.com", "Other", "auth0|other")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+
+	handler := NewAPIKeysHandler(sqlDB)
+	serve := func(handle http.HandlerFunc, method, body, userID string, vars map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/admin/sites/"+site.ID+"/api-keys", strings.NewReader(body))
+		req = mux.SetURLVars(req.WithContext(contextWithUser(userID)), vars)
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		return rr
+	}
+	siteVars := map[string]string{"siteId": site.ID}
+
+	if rr := serve(handler.CreateAPIKey, "POST", `{"name":"backend"}`, other.ID, siteVars); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 creating a key on someone else's site, got %d", rr.Code)
+	}
+	if rr := serve(handler.CreateAPIKey, "POST", `{"name":"  "}`, owner.ID, siteVars); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a name, got %d", rr.Code)
+	}
+
+	rr := serve(handler.CreateAPIKey, "POST", `{"name":"backend"}`, owner.ID, siteVars)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created CreatedAPIKey
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode created key: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || created.Name != "backend" {
+		t.Errorf("Unexpected created key: %+v", created)
+	}
+	if key, err := models.NewAPIKeyStore(sqlDB).Authenticate(ctx, created.Key); err != nil || key.ID != created.ID {
+		t.Errorf("Expected the returned plaintext to authenticate, got %+v (%v)", key, err)
+	}
+
+	// The plaintext is never listed
+	rr = serve(handler.ListAPIKeys, "GET", "", owner.ID, siteVars)
+	if strings.Contains(rr.Body.String(), created.Key) {
+		t.Error("Expected the key list not to contain the plaintext key")
+	}
+	var listed []models.APIKey
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID || listed[0].Revoked {
+		t.Errorf("Unexpected key list: %+v", listed)
+	}
+
+	keyVars := map[string]string{"siteId": site.ID, "keyId": created.ID}
+	if rr := serve(handler.RevokeAPIKey, "DELETE", "", other.ID, keyVars); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 revoking on someone else's site, got %d", rr.Code)
+	}
+	if rr := serve(handler.RevokeAPIKey, "DELETE", "", owner.ID, keyVars); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rr.Code)
+	}
+	if _, err := models.NewAPIKeyStore(sqlDB).Authenticate(ctx, created.Key); err != models.ErrAPIKeyRevoked {
+		t.Errorf("Expected a revoked key to be rejected, got %v", err)
+	}
+	if rr := serve(handler.RevokeAPIKey, "DELETE", "", owner.ID, map[string]string{"siteId": site.ID, "keyId": "missing"}); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown key, got %d", rr.Code)
+	}
+}
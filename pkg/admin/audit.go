@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/audit"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// defaultAuditPageSize is how many audit entries are returned per page
+const defaultAuditPageSize = 50
+
+// maxAuditPageSize caps the limit query parameter
+const maxAuditPageSize = 500
+
+// AuditHandler serves a site's audit log to its owner
+type AuditHandler struct {
+	db *sql.DB
+}
+
+// NewAuditHandler creates a new audit log handler
+func NewAuditHandler(db *sql.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// AuditLog is one page of a site's audit entries
+type AuditLog struct {
+	Total   int           `json:"total"`
+	Entries []audit.Entry `json:"entries"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+// GetAuditLog handles GET /admin/sites/{siteId}/audit. It returns the site's
+// audit entries newest first, optionally only those with the given action or
+// between the from and to dates.
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	siteID := mux.Vars(r)["siteId"]
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, total, err := audit.NewStore(h.db).List(r.Context(), siteID, filter)
+	if err != nil {
+		log.Printf("Error fetching audit log for site %s: %v", siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditLog{Total: total, Entries: entries, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+// parseAuditFilter reads the action, from, to, limit and offset query
+// parameters. Dates are YYYY-MM-DD or RFC 3339; a date-only "to" covers the
+// whole day.
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action: query.Get("action"),
+		Limit:  defaultAuditPageSize,
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditPageSize {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxAuditPageSize)
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("offset must be a non-negative integer")
+		}
+		filter.Offset = offset
+	}
+
+	var err error
+	if filter.From, err = parseCommentListDate(query.Get("from"), false); err != nil {
+		return filter, fmt.Errorf("invalid from date: %w", err)
+	}
+	if filter.To, err = parseCommentListDate(query.Get("to"), true); err != nil {
+		return filter, fmt.Errorf("invalid to date: %w", err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, fmt.Errorf("to date must not be before from date")
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/audit"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func TestAuditHandler_GetAuditLog(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	owner, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	other, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "other@example.com", "Other", "auth0|other")
+
+	// Creating and updating the site through the handler audits both
+	sites := NewSitesHandler(sqlDB, nil)
+	req := httptest.NewRequest("POST", "/admin/sites", strings.NewReader("name=Blog&domain=blog.example.com"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	sites.CreateSite(rr, req.WithContext(contextWithUser(owner.ID)))
+	var site models.Site
+	if err := json.Unmarshal(rr.Body.Bytes(), &site); err != nil || site.ID == "" {
+		t.Fatalf("CreateSite failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("PUT", "/admin/sites/"+site.ID, strings.NewReader("name=Renamed"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = mux.SetURLVars(req.WithContext(contextWithUser(owner.ID)), map[string]string{"siteId": site.ID})
+	rr = httptest.NewRecorder()
+	sites.UpdateSite(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("UpdateSite failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	handler := NewAuditHandler(sqlDB)
+	get := func(userID, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/admin/sites/"+site.ID+"/audit?"+query, nil)
+		req = mux.SetURLVars(req.WithContext(contextWithUser(userID)), map[string]string{"siteId": site.ID})
+		rr := httptest.NewRecorder()
+		handler.GetAuditLog(rr, req)
+		return rr
+	}
+
+	rr = get(owner.ID, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var auditLog AuditLog
+	if err := json.Unmarshal(rr.Body.Bytes(), &auditLog); err != nil {
+		t.Fatalf("Failed to decode audit log: %v", err)
+	}
+	if auditLog.Total != 2 || len(auditLog.Entries) != 2 || auditLog.Limit != defaultAuditPageSize {
+		t.Fatalf("Expected 2 entries, got %+v", auditLog)
+	}
+
+	rr = get(owner.ID, "action="+audit.ActionSiteUpdate)
+	auditLog = AuditLog{}
+	json.Unmarshal(rr.Body.Bytes(), &auditLog)
+	if auditLog.Total != 1 || auditLog.Entries[0].ActorID != owner.ID || auditLog.Entries[0].Metadata["name"] != "Renamed" {
+		t.Errorf("Expected the owner's update, got %+v", auditLog)
+	}
+
+	if rr := get(other.ID, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-owner, got %d", rr.Code)
+	}
+	if rr := get(owner.ID, "limit=0"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid limit, got %d", rr.Code)
+	}
+	if rr := get(owner.ID, "from=2026-05-02&to=2026-05-01"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for reversed dates, got %d", rr.Code)
+	}
+}
@@ -1,7 +1,6 @@
 package admin
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -18,6 +17,7 @@ import (
 type AuthConfigHandler struct {
 	db        *sql.DB
 	templates *template.Template
+	cache     *models.CachedSiteAuthConfigStore
 }
 
 // NewAuthConfigHandler creates a new auth config handler
@@ -28,11 +28,24 @@ func NewAuthConfigHandler(db *sql.DB, templates *template.Template) *AuthConfigH
 	}
 }
 
+// SetCache sets the auth config cache invalidated when a site's config changes
+func (h *AuthConfigHandler) SetCache(cache *models.CachedSiteAuthConfigStore) {
+	h.cache = cache
+}
+
+// invalidateCache drops a site's cached config so the change applies to the
+// next request
+func (h *AuthConfigHandler) invalidateCache(siteID string) {
+	if h.cache != nil {
+		h.cache.Invalidate(siteID)
+	}
+}
+
 // HandleAuthConfigForm displays the authentication configuration form
 func (h *AuthConfigHandler) HandleAuthConfigForm(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -40,7 +53,7 @@ func (h *AuthConfigHandler) HandleAuthConfigForm(w http.ResponseWriter, r *http.
 	siteID := vars["siteId"]
 
 	// Verify site ownership
-	if !h.verifySiteOwnership(r.Context(), siteID, userID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, userID) {
 		return
 	}
 
@@ -64,7 +77,7 @@ func (h *AuthConfigHandler) HandleAuthConfigForm(w http.ResponseWriter, r *http.
 
 	if err := h.templates.ExecuteTemplate(w, "auth/form.html", data); err != nil {
 		log.Printf("Error rendering auth config form: %v", err)
-		http.Error(w, "Failed to render form", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to render form")
 	}
 }
 
@@ -72,7 +85,7 @@ func (h *AuthConfigHandler) HandleAuthConfigForm(w http.ResponseWriter, r *http.
 func (h *AuthConfigHandler) GetAuthConfig(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -80,7 +93,7 @@ func (h *AuthConfigHandler) GetAuthConfig(w http.ResponseWriter, r *http.Request
 	siteID := vars["siteId"]
 
 	// Verify site ownership
-	if !h.verifySiteOwnership(r.Context(), siteID, userID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, userID) {
 		return
 	}
 
@@ -98,7 +111,7 @@ func (h *AuthConfigHandler) GetAuthConfig(w http.ResponseWriter, r *http.Request
 			})
 			return
 		}
-		http.Error(w, "Failed to fetch auth configuration", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch auth configuration")
 		return
 	}
 
@@ -113,7 +126,7 @@ func (h *AuthConfigHandler) GetAuthConfig(w http.ResponseWriter, r *http.Request
 func (h *AuthConfigHandler) CreateAuthConfig(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -121,14 +134,14 @@ func (h *AuthConfigHandler) CreateAuthConfig(w http.ResponseWriter, r *http.Requ
 	siteID := vars["siteId"]
 
 	// Verify site ownership
-	if !h.verifySiteOwnership(r.Context(), siteID, userID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, userID) {
 		return
 	}
 
 	// Parse request body
 	var config models.SiteAuthConfig
 	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -137,16 +150,17 @@ func (h *AuthConfigHandler) CreateAuthConfig(w http.ResponseWriter, r *http.Requ
 
 	// Validate configuration
 	if err := h.validateAuthConfig(&config); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Create configuration
 	authConfigStore := models.NewSiteAuthConfigStore(h.db)
 	if err := authConfigStore.Create(r.Context(), &config); err != nil {
-		http.Error(w, "Failed to create auth configuration", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create auth configuration")
 		return
 	}
+	h.invalidateCache(siteID)
 
 	// Don't expose secret in response
 	config.JWTSecret = ""
@@ -160,7 +174,7 @@ func (h *AuthConfigHandler) CreateAuthConfig(w http.ResponseWriter, r *http.Requ
 func (h *AuthConfigHandler) UpdateAuthConfig(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -168,7 +182,7 @@ func (h *AuthConfigHandler) UpdateAuthConfig(w http.ResponseWriter, r *http.Requ
 	siteID := vars["siteId"]
 
 	// Verify site ownership
-	if !h.verifySiteOwnership(r.Context(), siteID, userID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, userID) {
 		return
 	}
 
@@ -176,14 +190,14 @@ func (h *AuthConfigHandler) UpdateAuthConfig(w http.ResponseWriter, r *http.Requ
 	authConfigStore := models.NewSiteAuthConfigStore(h.db)
 	existingConfig, err := authConfigStore.GetBySiteID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Auth configuration not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Auth configuration not found")
 		return
 	}
 
 	// Parse request body
 	var updates models.SiteAuthConfig
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -204,18 +218,25 @@ func (h *AuthConfigHandler) UpdateAuthConfig(w http.ResponseWriter, r *http.Requ
 	if updates.TokenExpirationBuffer > 0 {
 		existingConfig.TokenExpirationBuffer = updates.TokenExpirationBuffer
 	}
+	if updates.AccessTokenLifetime > 0 {
+		existingConfig.AccessTokenLifetime = updates.AccessTokenLifetime
+	}
+	if updates.RefreshTokenLifetime > 0 {
+		existingConfig.RefreshTokenLifetime = updates.RefreshTokenLifetime
+	}
 
 	// Validate configuration
 	if err := h.validateAuthConfig(existingConfig); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Update configuration
 	if err := authConfigStore.Update(r.Context(), existingConfig); err != nil {
-		http.Error(w, "Failed to update auth configuration", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update auth configuration")
 		return
 	}
+	h.invalidateCache(siteID)
 
 	// Don't expose secret in response
 	existingConfig.JWTSecret = ""
@@ -228,7 +249,7 @@ func (h *AuthConfigHandler) UpdateAuthConfig(w http.ResponseWriter, r *http.Requ
 func (h *AuthConfigHandler) DeleteAuthConfig(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -236,7 +257,7 @@ func (h *AuthConfigHandler) DeleteAuthConfig(w http.ResponseWriter, r *http.Requ
 	siteID := vars["siteId"]
 
 	// Verify site ownership
-	if !h.verifySiteOwnership(r.Context(), siteID, userID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, userID) {
 		return
 	}
 
@@ -244,30 +265,31 @@ func (h *AuthConfigHandler) DeleteAuthConfig(w http.ResponseWriter, r *http.Requ
 	authConfigStore := models.NewSiteAuthConfigStore(h.db)
 	config, err := authConfigStore.GetBySiteID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Auth configuration not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Auth configuration not found")
 		return
 	}
 
 	// Delete configuration
 	if err := authConfigStore.Delete(r.Context(), config.ID); err != nil {
-		http.Error(w, "Failed to delete auth configuration", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete auth configuration")
 		return
 	}
+	h.invalidateCache(siteID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // verifySiteOwnership verifies that the user owns the specified site
-func (h *AuthConfigHandler) verifySiteOwnership(ctx context.Context, siteID, userID string, w http.ResponseWriter) bool {
+func (h *AuthConfigHandler) verifySiteOwnership(w http.ResponseWriter, r *http.Request, siteID, userID string) bool {
 	siteStore := models.NewSiteStore(h.db)
-	site, err := siteStore.GetByID(ctx, siteID)
+	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return false
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden: You do not own this site", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden: You do not own this site")
 		return false
 	}
 
@@ -285,6 +307,10 @@ func (h *AuthConfigHandler) validateAuthConfig(config *models.SiteAuthConfig) er
 		return fmt.Errorf("invalid auth_mode: must be either 'external' or 'kotomi'")
 	}
 
+	if err := config.ValidateTokenLifetimes(); err != nil {
+		return err
+	}
+
 	// For kotomi auth mode, no JWT validation settings are required (uses internal auth)
 	if config.AuthMode == "kotomi" {
 		return nil
@@ -292,18 +318,19 @@ func (h *AuthConfigHandler) validateAuthConfig(config *models.SiteAuthConfig) er
 
 	// For external auth mode, validate JWT validation type
 	validTypes := map[string]bool{
-		"hmac":  true,
-		"rsa":   true,
-		"ecdsa": true,
-		"jwks":  true,
+		"hmac":   true,
+		"secret": true,
+		"rsa":    true,
+		"ecdsa":  true,
+		"jwks":   true,
 	}
 	if !validTypes[config.JWTValidationType] {
-		return fmt.Errorf("invalid jwt_validation_type: must be one of hmac, rsa, ecdsa, or jwks")
+		return fmt.Errorf("invalid jwt_validation_type: must be one of hmac, secret, rsa, ecdsa, or jwks")
 	}
 
 	// Validate required fields based on validation type
 	switch config.JWTValidationType {
-	case "hmac":
+	case "hmac", "secret":
 		if config.JWTSecret == "" {
 			return http.ErrBodyNotAllowed
 		}
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// BlockedAuthorsHandler handles blocking authors from commenting on a site
+type BlockedAuthorsHandler struct {
+	db *sql.DB
+}
+
+// NewBlockedAuthorsHandler creates a new blocked authors handler
+func NewBlockedAuthorsHandler(db *sql.DB) *BlockedAuthorsHandler {
+	return &BlockedAuthorsHandler{db: db}
+}
+
+// verifySiteOwner writes an error and returns false unless the current user owns the site
+func (h *BlockedAuthorsHandler) verifySiteOwner(w http.ResponseWriter, r *http.Request, siteID string) (string, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return "", false
+	}
+
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil || site == nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return "", false
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return "", false
+	}
+
+	return userID, true
+}
+
+// ListBlockedAuthors handles GET /admin/sites/{siteId}/blocked-authors
+func (h *BlockedAuthorsHandler) ListBlockedAuthors(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	if _, ok := h.verifySiteOwner(w, r, siteID); !ok {
+		return
+	}
+
+	blocked, err := models.NewBlockedAuthorStore(h.db).ListBySite(r.Context(), siteID)
+	if err != nil {
+		log.Printf("Error fetching blocked authors: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch blocked authors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocked)
+}
+
+// BlockAuthor handles POST /admin/sites/{siteId}/blocked-authors
+func (h *BlockedAuthorsHandler) BlockAuthor(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	userID, ok := h.verifySiteOwner(w, r, siteID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AuthorID string `json:"author_id"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	req.AuthorID = strings.TrimSpace(req.AuthorID)
+	if req.AuthorID == "" {
+		writeError(w, r, http.StatusBadRequest, "author_id is required")
+		return
+	}
+
+	blocked, err := models.NewBlockedAuthorStore(h.db).Block(r.Context(), siteID, req.AuthorID, userID, strings.TrimSpace(req.Reason))
+	if err != nil {
+		log.Printf("Error blocking author %s on site %s: %v", req.AuthorID, siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to block author")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(blocked)
+}
+
+// UnblockAuthor handles DELETE /admin/sites/{siteId}/blocked-authors/{authorId}
+func (h *BlockedAuthorsHandler) UnblockAuthor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if _, ok := h.verifySiteOwner(w, r, siteID); !ok {
+		return
+	}
+
+	if err := models.NewBlockedAuthorStore(h.db).Unblock(r.Context(), siteID, vars["authorId"]); err != nil {
+		if err.Error() == "blocked author not found" {
+			writeError(w, r, http.StatusNotFound, "Blocked author not found")
+			return
+		}
+		log.Printf("Error unblocking author %s on site %s: %v", vars["authorId"], siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to unblock author")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func TestBlockedAuthorsHandler_OwnerOnly(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminStore := models.NewAdminUserStore(sqlDB)
+	owner, _ := adminStore.Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	other, _ := adminStore.Create(ctx, "other@example.com", "Other", "auth0|other")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+
+	handler := NewBlockedAuthorsHandler(sqlDB)
+	serve := func(handle http.HandlerFunc, method, body, userID string, vars map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/admin/sites/"+site.ID+"/blocked-authors", strings.NewReader(body))
+		req = mux.SetURLVars(req.WithContext(contextWithUser(userID)), vars)
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		return rr
+	}
+	siteVars := map[string]string{"siteId": site.ID}
+	authorVars := map[string]string{"siteId": site.ID, "authorId": "troll"}
+
+	if rr := serve(handler.BlockAuthor, "POST", `{"author_id":"troll"}`, other.ID, siteVars); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 blocking on someone else's site, got %d", rr.Code)
+	}
+	if rr := serve(handler.BlockAuthor, "POST", `{"author_id":""}`, owner.ID, siteVars); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without author_id, got %d", rr.Code)
+	}
+	if rr := serve(handler.BlockAuthor, "POST", `{"author_id":"troll","reason":"spam"}`, owner.ID, siteVars); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr := serve(handler.ListBlockedAuthors, "GET", "", owner.ID, siteVars)
+	var blocked []models.BlockedAuthor
+	if err := json.Unmarshal(rr.Body.Bytes(), &blocked); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(blocked) != 1 || blocked[0].AuthorID != "troll" || blocked[0].Reason != "spam" || blocked[0].BlockedBy != owner.ID {
+		t.Errorf("Unexpected blocked authors: %+v", blocked)
+	}
+
+	if rr := serve(handler.UnblockAuthor, "DELETE", "", other.ID, authorVars); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 unblocking on someone else's site, got %d", rr.Code)
+	}
+	if rr := serve(handler.UnblockAuthor, "DELETE", "", owner.ID, authorVars); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rr.Code)
+	}
+	if rr := serve(handler.UnblockAuthor, "DELETE", "", owner.ID, authorVars); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 unblocking twice, got %d", rr.Code)
+	}
+}
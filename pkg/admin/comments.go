@@ -6,16 +6,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+	"github.com/saasuke-labs/kotomi/pkg/audit"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
 	"github.com/saasuke-labs/kotomi/pkg/comments"
 	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/i18n"
 	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/moderation"
 	"github.com/saasuke-labs/kotomi/pkg/notifications"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
 )
 
 // CommentsHandler handles comment moderation requests
@@ -24,6 +32,9 @@ type CommentsHandler struct {
 	commentStore      db.Store
 	templates         *template.Template
 	notificationQueue *notifications.Queue
+	analyticsCache    *analytics.CachedStore
+	moderationEvents  *moderation.EventStore
+	events            *webhooks.Dispatcher
 }
 
 // NewCommentsHandler creates a new comments handler
@@ -33,6 +44,7 @@ func NewCommentsHandler(sqlDB *sql.DB, commentStore db.Store, templates *templat
 		commentStore:      commentStore,
 		templates:         templates,
 		notificationQueue: nil, // Will be set later if needed
+		moderationEvents:  moderation.NewEventStore(sqlDB),
 	}
 }
 
@@ -41,11 +53,110 @@ func (h *CommentsHandler) SetNotificationQueue(queue *notifications.Queue) {
 	h.notificationQueue = queue
 }
 
+// SetAnalyticsCache sets the analytics cache invalidated when comments are moderated
+func (h *CommentsHandler) SetAnalyticsCache(cache *analytics.CachedStore) {
+	h.analyticsCache = cache
+}
+
+// SetEventDispatcher sets the dispatcher that receives comment.approved and
+// comment.rejected webhooks
+func (h *CommentsHandler) SetEventDispatcher(events *webhooks.Dispatcher) {
+	h.events = events
+}
+
+// dispatchStatusEvent sends the webhook for a comment a moderator moved to status
+func (h *CommentsHandler) dispatchStatusEvent(r *http.Request, siteID string, comment *comments.Comment, status string) {
+	if h.events == nil {
+		return
+	}
+	event := webhooks.EventCommentApproved
+	if status == "rejected" {
+		event = webhooks.EventCommentRejected
+	}
+
+	var pageID string
+	if err := h.db.QueryRowContext(r.Context(), "SELECT page_id FROM comments WHERE id = ?", comment.ID).Scan(&pageID); err != nil {
+		log.Printf("Warning: Failed to load page for %s webhook: %v", event, err)
+	}
+	moderated := *comment
+	moderated.Status = status
+	h.events.Dispatch(siteID, event, webhooks.NewCommentData(moderated, pageID))
+}
+
+// notifyPageSubscribers tells the subscribers of the comment's page about a
+// comment that was just published. The comment's author isn't notified.
+func (h *CommentsHandler) notifyPageSubscribers(r *http.Request, siteID string, comment *comments.Comment) {
+	settings, err := notifications.NewStore(h.db).GetSettings(siteID)
+	if err != nil || settings == nil || !settings.Enabled || !settings.NotifyReply {
+		return
+	}
+
+	var pageID string
+	if err := h.db.QueryRowContext(r.Context(), "SELECT page_id FROM comments WHERE id = ?", comment.ID).Scan(&pageID); err != nil {
+		log.Printf("Warning: Failed to load page for subscriber notifications: %v", err)
+		return
+	}
+	page, err := models.NewPageStore(h.db).GetByID(r.Context(), pageID)
+	if err != nil {
+		log.Printf("Warning: Failed to load page for subscriber notifications: %v", err)
+		return
+	}
+
+	commentURL := fmt.Sprintf("%s?comment=%s", page.Path, comment.ID)
+	if err := h.notificationQueue.EnqueuePageSubscribers(siteID, pageID, page.Title, commentURL,
+		comment.AuthorID, comment.Author, comment.Text, nil); err != nil {
+		log.Printf("Warning: Failed to enqueue subscriber notifications: %v", err)
+	}
+}
+
+// recordManualDecision writes a moderator's status change to the moderation audit log
+func (h *CommentsHandler) recordManualDecision(r *http.Request, commentID, siteID, decision, moderatorID string) {
+	err := h.moderationEvents.Record(r.Context(), moderation.Event{
+		CommentID:   commentID,
+		SiteID:      siteID,
+		Decision:    decision,
+		Source:      moderation.SourceManual,
+		ModeratorID: moderatorID,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to record moderation event for comment %s: %v", commentID, err)
+	}
+}
+
+// statusAuditActions maps the statuses a moderator sets to their audit actions
+var statusAuditActions = map[string]string{
+	"approved": audit.ActionCommentApprove,
+	"rejected": audit.ActionCommentReject,
+}
+
+// auditComment records a moderator's action on a comment in the audit log
+func (h *CommentsHandler) auditComment(r *http.Request, siteID, userID, action string, comment *comments.Comment) {
+	audit.Record(r.Context(), h.db, audit.Entry{
+		SiteID:     siteID,
+		ActorID:    userID,
+		Action:     action,
+		TargetType: audit.TargetComment,
+		TargetID:   comment.ID,
+		Metadata:   map[string]interface{}{"author_id": comment.AuthorID, "previous_status": comment.Status},
+	})
+}
+
+// updateReputation adjusts a comment author's reputation for a status change
+func (h *CommentsHandler) updateReputation(r *http.Request, siteID string, comment *comments.Comment, newStatus string) {
+	if h.db == nil || comment == nil {
+		return
+	}
+	err := models.NewReputationStore(h.db).ApplyStatusChange(r.Context(), siteID, comment.AuthorID, comment.Status, newStatus)
+	if err != nil {
+		log.Printf("Warning: Failed to update reputation for comment %s: %v", comment.ID, err)
+	}
+}
+
 // ListComments handles GET /admin/sites/{siteId}/comments
 func (h *CommentsHandler) ListComments(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -56,42 +167,71 @@ func (h *CommentsHandler) ListComments(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	// Get filters from query params
 	status := r.URL.Query().Get("status")
 	search := r.URL.Query().Get("search")
+	filter, err := parseCommentListFilter(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Status = status
 
 	// Get comments with search
 	var commentsList []comments.Comment
+	total := 0
 	if search != "" {
 		commentsList, err = h.searchComments(r.Context(), siteID, status, search)
+		total = len(commentsList)
 	} else {
-		commentsList, err = h.commentStore.GetCommentsBySite(r.Context(), siteID, status)
+		var page *comments.SiteCommentPage
+		page, err = h.commentStore.GetCommentsBySiteFiltered(r.Context(), siteID, filter)
+		if err == nil {
+			commentsList, total = page.Comments, page.Total
+		}
 	}
 	if err != nil {
-		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		log.Printf("Error fetching comments for site %s: %v", siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch comments")
 		return
 	}
 
+	// Surface report counts so owners can prioritize flagged comments
+	if h.db != nil && len(commentsList) > 0 {
+		ids := make([]string, len(commentsList))
+		for i, c := range commentsList {
+			ids[i] = c.ID
+		}
+		reportCounts, err := comments.NewReportStore(h.db).CountReportsByComment(r.Context(), ids)
+		if err != nil {
+			log.Printf("Error counting reports for site %s: %v", siteID, err)
+		}
+		for i := range commentsList {
+			commentsList[i].ReportCount = reportCounts[commentsList[i].ID]
+		}
+	}
+
 	// Check if this is an HTMX request or regular page load
 	if r.Header.Get("HX-Request") == "true" || r.Header.Get("Accept") == "text/html" {
 		if h.templates != nil {
 			err = h.templates.ExecuteTemplate(w, "comments/list.html", map[string]interface{}{
-				"Comments": commentsList,
-				"SiteID":   siteID,
-				"Status":   status,
-				"Search":   search,
+				"Comments":   commentsList,
+				"SiteID":     siteID,
+				"Status":     status,
+				"Search":     search,
+				"Pagination": newCommentPagination(filter, total, search != ""),
 			})
 			if err != nil {
-				http.Error(w, "Template error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Template error")
 			}
 		}
 		return
@@ -99,14 +239,130 @@ func (h *CommentsHandler) ListComments(w http.ResponseWriter, r *http.Request) {
 
 	// Return JSON
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	json.NewEncoder(w).Encode(commentsList)
 }
 
+// defaultCommentPageSize is how many comments the admin list shows per page
+const defaultCommentPageSize = 50
+
+// maxCommentPageSize caps the limit query parameter
+const maxCommentPageSize = 500
+
+// parseCommentListFilter reads limit, offset, from, to, author_id, language and
+// moderated_by query parameters. Dates are YYYY-MM-DD or RFC 3339; a date-only
+// "to" covers the whole day. With moderated_by the dates bound the moderation time.
+func parseCommentListFilter(r *http.Request) (comments.SiteCommentFilter, error) {
+	query := r.URL.Query()
+	filter := comments.SiteCommentFilter{
+		AuthorID:    query.Get("author_id"),
+		Language:    strings.ToLower(strings.TrimSpace(query.Get("language"))),
+		ModeratedBy: strings.TrimSpace(query.Get("moderated_by")),
+		Limit:       defaultCommentPageSize,
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxCommentPageSize {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxCommentPageSize)
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("offset must be a non-negative integer")
+		}
+		filter.Offset = offset
+	}
+
+	var err error
+	if filter.From, err = parseCommentListDate(query.Get("from"), false); err != nil {
+		return filter, fmt.Errorf("invalid from date: %w", err)
+	}
+	if filter.To, err = parseCommentListDate(query.Get("to"), true); err != nil {
+		return filter, fmt.Errorf("invalid to date: %w", err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, fmt.Errorf("to date must not be before from date")
+	}
+
+	return filter, nil
+}
+
+// parseCommentListDate parses a YYYY-MM-DD or RFC 3339 date; empty values give the zero time
+func parseCommentListDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// commentPagination is the template data for the comment list's paging controls
+type commentPagination struct {
+	Total       int
+	Limit       int
+	Offset      int
+	From        int // 1-based index of the first comment shown
+	To          int // 1-based index of the last comment shown
+	PrevOffset  int
+	NextOffset  int
+	HasPrev     bool
+	HasNext     bool
+	AuthorID    string
+	Language    string
+	ModeratedBy string
+	DateFrom    string
+	DateTo      string
+}
+
+// newCommentPagination builds paging controls for a page of comments. Search
+// results aren't paged, so they get a single page.
+func newCommentPagination(filter comments.SiteCommentFilter, total int, search bool) commentPagination {
+	p := commentPagination{Total: total, Limit: filter.Limit, Offset: filter.Offset, AuthorID: filter.AuthorID, Language: filter.Language, ModeratedBy: filter.ModeratedBy}
+	if !filter.From.IsZero() {
+		p.DateFrom = filter.From.Format("2006-01-02")
+	}
+	if !filter.To.IsZero() {
+		p.DateTo = filter.To.Format("2006-01-02")
+	}
+	if search {
+		p.Limit, p.Offset = total, 0
+	}
+
+	if total > 0 && p.Offset < total {
+		p.From = p.Offset + 1
+		p.To = p.Offset + p.Limit
+		if p.To > total {
+			p.To = total
+		}
+	}
+	p.HasPrev = p.Offset > 0
+	p.PrevOffset = p.Offset - p.Limit
+	if p.PrevOffset < 0 {
+		p.PrevOffset = 0
+	}
+	p.NextOffset = p.Offset + p.Limit
+	p.HasNext = p.NextOffset < total
+
+	return p
+}
+
 // ListPageComments handles GET /admin/sites/{siteId}/pages/{pageId}/comments
 func (h *CommentsHandler) ListPageComments(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -118,13 +374,13 @@ func (h *CommentsHandler) ListPageComments(w http.ResponseWriter, r *http.Reques
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	comments, err := h.commentStore.GetPageComments(r.Context(), siteID, pageID)
 	if err != nil {
-		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch comments")
 		return
 	}
 
@@ -137,7 +393,7 @@ func (h *CommentsHandler) ListPageComments(w http.ResponseWriter, r *http.Reques
 func (h *CommentsHandler) ApproveComment(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -147,29 +403,34 @@ func (h *CommentsHandler) ApproveComment(w http.ResponseWriter, r *http.Request)
 	// Get comment to verify ownership of site
 	comment, err := h.commentStore.GetCommentByID(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Comment not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Comment not found")
 		return
 	}
 
 	// Get site ID for the comment and verify ownership
 	siteID, err := h.commentStore.GetCommentSiteID(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Failed to verify comment ownership", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to verify comment ownership")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	err = h.commentStore.UpdateCommentStatus(r.Context(), commentID, "approved", userID)
 	if err != nil {
-		http.Error(w, "Failed to approve comment", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to approve comment")
 		return
 	}
+	h.analyticsCache.InvalidateSite(siteID)
+	h.recordManualDecision(r, commentID, siteID, "approved", userID)
+	h.auditComment(r, siteID, userID, audit.ActionCommentApprove, comment)
+	h.updateReputation(r, siteID, comment, "approved")
+	h.dispatchStatusEvent(r, siteID, comment, "approved")
 
 	// Enqueue moderation update notification
 	if h.notificationQueue != nil && comment.AuthorEmail != "" {
@@ -185,17 +446,15 @@ func (h *CommentsHandler) ApproveComment(w http.ResponseWriter, r *http.Request)
 				page, err := pageStore.GetByID(r.Context(), pageID)
 				if err == nil && page != nil {
 					commentURL := fmt.Sprintf("%s?comment=%s", page.Path, comment.ID)
-					unsubscribeURL := fmt.Sprintf("/unsubscribe?site=%s", siteID)
 					
 					err = h.notificationQueue.EnqueueModerationUpdate(
 						siteID,
 						page.Title,
 						commentURL,
-						comment.Text,
+						comment.Excerpt(notifications.ExcerptLength),
 						"approved",
 						"", // No reason for approval
 						comment.AuthorEmail,
-						unsubscribeURL,
 					)
 					if err != nil {
 						log.Printf("Warning: Failed to enqueue moderation notification: %v", err)
@@ -205,6 +464,11 @@ func (h *CommentsHandler) ApproveComment(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// Page subscribers hear about comments once they are published
+	if h.notificationQueue != nil && comment.Status != "approved" {
+		h.notifyPageSubscribers(r, siteID, comment)
+	}
+
 	// For HTMX requests, return updated comment row
 	if r.Header.Get("HX-Request") == "true" {
 		comment.Status = "approved"
@@ -212,7 +476,7 @@ func (h *CommentsHandler) ApproveComment(w http.ResponseWriter, r *http.Request)
 		if h.templates != nil {
 			err = h.templates.ExecuteTemplate(w, "comments/row.html", comment)
 			if err != nil {
-				http.Error(w, "Template error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Template error")
 			}
 		}
 		return
@@ -225,7 +489,7 @@ func (h *CommentsHandler) ApproveComment(w http.ResponseWriter, r *http.Request)
 func (h *CommentsHandler) RejectComment(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -235,29 +499,34 @@ func (h *CommentsHandler) RejectComment(w http.ResponseWriter, r *http.Request)
 	// Get comment to verify ownership
 	comment, err := h.commentStore.GetCommentByID(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Comment not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Comment not found")
 		return
 	}
 
 	// Get site ID for the comment and verify ownership
 	siteID, err := h.commentStore.GetCommentSiteID(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Failed to verify comment ownership", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to verify comment ownership")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	err = h.commentStore.UpdateCommentStatus(r.Context(), commentID, "rejected", userID)
 	if err != nil {
-		http.Error(w, "Failed to reject comment", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to reject comment")
 		return
 	}
+	h.analyticsCache.InvalidateSite(siteID)
+	h.recordManualDecision(r, commentID, siteID, "rejected", userID)
+	h.auditComment(r, siteID, userID, audit.ActionCommentReject, comment)
+	h.updateReputation(r, siteID, comment, "rejected")
+	h.dispatchStatusEvent(r, siteID, comment, "rejected")
 
 	// Enqueue moderation update notification
 	if h.notificationQueue != nil && comment.AuthorEmail != "" {
@@ -273,17 +542,20 @@ func (h *CommentsHandler) RejectComment(w http.ResponseWriter, r *http.Request)
 				page, err := pageStore.GetByID(r.Context(), pageID)
 				if err == nil && page != nil {
 					commentURL := fmt.Sprintf("%s?comment=%s", page.Path, comment.ID)
-					unsubscribeURL := fmt.Sprintf("/unsubscribe?site=%s", siteID)
-					
+
+					locale := i18n.DefaultLocale
+					if siteSettings, err := models.NewSiteSettingsStore(h.db).GetBySiteID(r.Context(), siteID); err == nil {
+						locale = siteSettings.Locale
+					}
+
 					err = h.notificationQueue.EnqueueModerationUpdate(
 						siteID,
 						page.Title,
 						commentURL,
-						comment.Text,
+						comment.Excerpt(notifications.ExcerptLength),
 						"rejected",
-						"Content violated community guidelines", // Default reason
+						i18n.T(locale, i18n.ReasonCommunityGuidelines), // Default reason
 						comment.AuthorEmail,
-						unsubscribeURL,
 					)
 					if err != nil {
 						log.Printf("Warning: Failed to enqueue moderation notification: %v", err)
@@ -300,7 +572,7 @@ func (h *CommentsHandler) RejectComment(w http.ResponseWriter, r *http.Request)
 		if h.templates != nil {
 			err = h.templates.ExecuteTemplate(w, "comments/row.html", comment)
 			if err != nil {
-				http.Error(w, "Template error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Template error")
 			}
 		}
 		return
@@ -313,7 +585,7 @@ func (h *CommentsHandler) RejectComment(w http.ResponseWriter, r *http.Request)
 func (h *CommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -321,31 +593,33 @@ func (h *CommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Request)
 	commentID := vars["commentId"]
 
 	// Get comment to verify ownership
-	_, err := h.commentStore.GetCommentByID(r.Context(), commentID)
+	comment, err := h.commentStore.GetCommentByID(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Comment not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Comment not found")
 		return
 	}
 
 	// Get site ID for the comment and verify ownership
 	siteID, err := h.commentStore.GetCommentSiteID(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Failed to verify comment ownership", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to verify comment ownership")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	err = h.commentStore.DeleteComment(r.Context(), commentID)
 	if err != nil {
-		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete comment")
 		return
 	}
+	h.analyticsCache.InvalidateSite(siteID)
+	h.auditComment(r, siteID, userID, audit.ActionCommentDelete, comment)
 
 	// For HTMX requests, return empty response (row will be removed)
 	if r.Header.Get("HX-Request") == "true" {
@@ -374,12 +648,7 @@ func (h *CommentsHandler) searchComments(ctx context.Context, siteID, status, se
 	}
 
 	// Add search filter with escaped wildcards
-	// Escape special LIKE characters: %, _, and \
-	escapedSearch := search
-	escapedSearch = strings.ReplaceAll(escapedSearch, "\\", "\\\\")
-	escapedSearch = strings.ReplaceAll(escapedSearch, "%", "\\%")
-	escapedSearch = strings.ReplaceAll(escapedSearch, "_", "\\_")
-	searchPattern := "%" + escapedSearch + "%"
+	searchPattern := "%" + comments.EscapeLike(search) + "%"
 	query += " AND (c.text LIKE ? ESCAPE '\\' OR c.author LIKE ? ESCAPE '\\' OR c.author_email LIKE ? ESCAPE '\\' OR p.path LIKE ? ESCAPE '\\')"
 	args = append(args, searchPattern, searchPattern, searchPattern, searchPattern)
 
@@ -417,63 +686,72 @@ func (h *CommentsHandler) searchComments(ctx context.Context, siteID, status, se
 	return commentsList, nil
 }
 
-// BulkApprove handles POST /admin/comments/bulk/approve
-func (h *CommentsHandler) BulkApprove(w http.ResponseWriter, r *http.Request) {
-	userID := auth.GetUserIDFromContext(r.Context())
-	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
+// BulkResult reports how a bulk moderation request was applied. Comments that
+// don't exist or belong to a site the user doesn't own are skipped, not failed.
+type BulkResult struct {
+	Succeeded        int `json:"succeeded"`
+	SkippedNotFound  int `json:"skipped_not_found"`
+	SkippedForbidden int `json:"skipped_forbidden"`
+	Failed           int `json:"failed"`
+}
 
-	var req struct {
-		CommentIDs []string `json:"comment_ids"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
+// bulkResponse keeps the success/count fields the admin UI reads alongside the breakdown
+type bulkResponse struct {
+	Success bool `json:"success"`
+	Count   int  `json:"count"`
+	BulkResult
+}
 
-	successCount := 0
-	// Approve each comment with proper authorization check
-	for _, commentID := range req.CommentIDs {
-		// Get comment and verify ownership
-		_, err := h.commentStore.GetCommentByID(r.Context(), commentID)
-		if err != nil {
-			continue // Skip invalid comments
-		}
-		
-		// Verify site ownership
+// bulkTarget is a comment the requesting user may moderate
+type bulkTarget struct {
+	comment *comments.Comment
+	siteID  string
+}
+
+// authorizeBulk resolves each comment's site via GetCommentSiteID and keeps only
+// those on sites owned by userID, so IDs from other owners' sites are never
+// touched. Ownership is cached per site.
+func (h *CommentsHandler) authorizeBulk(r *http.Request, userID string, commentIDs []string) ([]bulkTarget, BulkResult) {
+	var result BulkResult
+	var targets []bulkTarget
+	siteStore := models.NewSiteStore(h.db)
+	owned := make(map[string]bool)
+
+	for _, commentID := range commentIDs {
 		siteID, err := h.commentStore.GetCommentSiteID(r.Context(), commentID)
 		if err != nil {
+			result.SkippedNotFound++
 			continue
 		}
-		
-		siteStore := models.NewSiteStore(h.db)
-		site, err := siteStore.GetByID(r.Context(), siteID)
-		if err != nil || site == nil || site.OwnerID != userID {
-			continue // Skip if not owner
+
+		isOwner, checked := owned[siteID]
+		if !checked {
+			site, err := siteStore.GetByID(r.Context(), siteID)
+			isOwner = err == nil && site != nil && site.OwnerID == userID
+			owned[siteID] = isOwner
+		}
+		if !isOwner {
+			result.SkippedForbidden++
+			continue
 		}
-		
-		err = h.commentStore.UpdateCommentStatus(r.Context(), commentID, "approved", userID)
+
+		comment, err := h.commentStore.GetCommentByIDForSite(r.Context(), commentID, siteID)
 		if err != nil {
-			log.Printf("Failed to approve comment %s: %v", commentID, err)
+			result.SkippedNotFound++
 			continue
 		}
-		successCount++
+		targets = append(targets, bulkTarget{comment: comment, siteID: siteID})
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"count":   successCount,
-	})
+	return targets, result
 }
 
-// BulkReject handles POST /admin/comments/bulk/reject
-func (h *CommentsHandler) BulkReject(w http.ResponseWriter, r *http.Request) {
+// serveBulk decodes a bulk moderation request, applies action to the comments
+// the user may moderate and writes the result
+func (h *CommentsHandler) serveBulk(w http.ResponseWriter, r *http.Request, action func(r *http.Request, userID string, targets []bulkTarget, result *BulkResult)) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -481,94 +759,143 @@ func (h *CommentsHandler) BulkReject(w http.ResponseWriter, r *http.Request) {
 		CommentIDs []string `json:"comment_ids"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
-	successCount := 0
-	// Reject each comment with proper authorization check
-	for _, commentID := range req.CommentIDs {
-		// Get comment and verify ownership
-		_, err := h.commentStore.GetCommentByID(r.Context(), commentID)
-		if err != nil {
-			continue // Skip invalid comments
+	targets, result := h.authorizeBulk(r, userID, req.CommentIDs)
+	if len(targets) > 0 {
+		action(r, userID, targets, &result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bulkResponse{
+		Success:    result.Failed == 0,
+		Count:      result.Succeeded,
+		BulkResult: result,
+	})
+}
+
+// setStatusBatch returns a bulk action that moves every target to status in a
+// single transaction, so a failure leaves none of them changed
+func (h *CommentsHandler) setStatusBatch(status string) func(r *http.Request, userID string, targets []bulkTarget, result *BulkResult) {
+	return func(r *http.Request, userID string, targets []bulkTarget, result *BulkResult) {
+		ids := make([]string, len(targets))
+		for i, target := range targets {
+			ids[i] = target.comment.ID
 		}
-		
-		// Verify site ownership
-		siteID, err := h.commentStore.GetCommentSiteID(r.Context(), commentID)
+
+		updated, err := h.commentStore.UpdateCommentStatusBatch(r.Context(), ids, status, userID)
 		if err != nil {
-			continue
+			log.Printf("Failed to set status %s on %d comments: %v", status, len(ids), err)
+			result.Failed += len(targets)
+			return
 		}
-		
-		siteStore := models.NewSiteStore(h.db)
-		site, err := siteStore.GetByID(r.Context(), siteID)
-		if err != nil || site == nil || site.OwnerID != userID {
-			continue // Skip if not owner
+		// Comments deleted since they were authorized aren't updated
+		result.Succeeded += len(updated)
+		result.SkippedNotFound += len(targets) - len(updated)
+
+		changed := make(map[string]bool, len(updated))
+		for _, id := range updated {
+			changed[id] = true
 		}
-		
-		err = h.commentStore.UpdateCommentStatus(r.Context(), commentID, "rejected", userID)
-		if err != nil {
-			log.Printf("Failed to reject comment %s: %v", commentID, err)
-			continue
+		for _, target := range targets {
+			if !changed[target.comment.ID] {
+				continue
+			}
+			h.analyticsCache.InvalidateSite(target.siteID)
+			h.recordManualDecision(r, target.comment.ID, target.siteID, status, userID)
+			h.auditComment(r, target.siteID, userID, statusAuditActions[status], target.comment)
+			h.updateReputation(r, target.siteID, target.comment, status)
+			h.dispatchStatusEvent(r, target.siteID, target.comment, status)
+
+			// Page subscribers hear about comments once they are published
+			if status == "approved" && h.notificationQueue != nil && target.comment.Status != "approved" {
+				h.notifyPageSubscribers(r, target.siteID, target.comment)
+			}
 		}
-		successCount++
 	}
+}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"count":   successCount,
-	})
+// BulkApprove handles POST /admin/comments/bulk/approve
+func (h *CommentsHandler) BulkApprove(w http.ResponseWriter, r *http.Request) {
+	h.serveBulk(w, r, h.setStatusBatch("approved"))
+}
+
+// BulkReject handles POST /admin/comments/bulk/reject
+func (h *CommentsHandler) BulkReject(w http.ResponseWriter, r *http.Request) {
+	h.serveBulk(w, r, h.setStatusBatch("rejected"))
 }
 
 // BulkDelete handles POST /admin/comments/bulk/delete
 func (h *CommentsHandler) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	h.serveBulk(w, r, func(r *http.Request, userID string, targets []bulkTarget, result *BulkResult) {
+		for _, target := range targets {
+			if err := h.commentStore.DeleteComment(r.Context(), target.comment.ID); err != nil {
+				log.Printf("Failed to delete comment %s: %v", target.comment.ID, err)
+				result.Failed++
+				continue
+			}
+			h.analyticsCache.InvalidateSite(target.siteID)
+			h.auditComment(r, target.siteID, userID, audit.ActionCommentDelete, target.comment)
+			result.Succeeded++
+		}
+	})
+}
+
+// commentStreamer is implemented by stores that can stream a site's comments without buffering them
+type commentStreamer interface {
+	StreamCommentsBySite(ctx context.Context, siteID string, w io.Writer, format string) error
+}
+
+// ExportComments handles GET /admin/sites/{siteId}/comments/export
+func (h *CommentsHandler) ExportComments(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	var req struct {
-		CommentIDs []string `json:"comment_ids"`
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+
+	// Verify ownership
+	siteStore := models.NewSiteStore(h.db)
+	site, err := siteStore.GetByID(r.Context(), siteID)
+	if err != nil || site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = comments.StreamFormatJSON
+	}
+
+	var contentType string
+	switch format {
+	case comments.StreamFormatJSON:
+		contentType = "application/json"
+	case comments.StreamFormatCSV:
+		contentType = "text/csv"
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid format. Use 'json' or 'csv'")
 		return
 	}
 
-	successCount := 0
-	// Delete each comment with proper authorization check
-	for _, commentID := range req.CommentIDs {
-		// Get comment and verify ownership
-		_, err := h.commentStore.GetCommentByID(r.Context(), commentID)
-		if err != nil {
-			continue // Skip invalid comments
-		}
-		
-		// Verify site ownership
-		siteID, err := h.commentStore.GetCommentSiteID(r.Context(), commentID)
-		if err != nil {
-			continue
-		}
-		
-		siteStore := models.NewSiteStore(h.db)
-		site, err := siteStore.GetByID(r.Context(), siteID)
-		if err != nil || site == nil || site.OwnerID != userID {
-			continue // Skip if not owner
-		}
-		
-		err = h.commentStore.DeleteComment(r.Context(), commentID)
-		if err != nil {
-			log.Printf("Failed to delete comment %s: %v", commentID, err)
-			continue
-		}
-		successCount++
+	streamer, ok := h.commentStore.(commentStreamer)
+	if !ok {
+		writeError(w, r, http.StatusNotImplemented, "Comment export is not supported by this database")
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"count":   successCount,
-	})
+	filename := fmt.Sprintf("comments_%s_%s.%s", siteID, time.Now().UTC().Format("20060102"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// Headers are already sent once streaming starts, so errors can only be logged
+	if err := streamer.StreamCommentsBySite(r.Context(), siteID, w, format); err != nil {
+		log.Printf("Failed to export comments for site %s: %v", siteID, err)
+	}
 }
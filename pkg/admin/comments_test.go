@@ -0,0 +1,372 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/audit"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+)
+
+func TestCommentsHandler_ModerationUpdatesReputation(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminUser, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+
+	userStore := models.NewUserStore(sqlDB)
+	if err := userStore.CreateOrUpdate(ctx, &models.User{ID: "author-1", SiteID: site.ID, Name: "Alice"}); err != nil {
+		t.Fatalf("CreateOrUpdate failed: %v", err)
+	}
+	for _, id := range []string{"comment-1", "comment-2"} {
+		err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+			ID: id, Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: "pending", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	handler := NewCommentsHandler(sqlDB, store, nil)
+	moderate := func(action string, handle http.HandlerFunc, commentID string) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/admin/comments/"+commentID+"/"+action, nil)
+		req = mux.SetURLVars(req.WithContext(contextWithUser(adminUser.ID)), map[string]string{"commentId": commentID})
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d: %s", action, commentID, rr.Code, rr.Body.String())
+		}
+	}
+	score := func() int {
+		t.Helper()
+		u, err := userStore.GetBySiteAndID(ctx, site.ID, "author-1")
+		if err != nil || u == nil {
+			t.Fatalf("GetBySiteAndID failed: %v", err)
+		}
+		return u.ReputationScore
+	}
+
+	moderate("approve", handler.ApproveComment, "comment-1")
+	if got := score(); got != models.ReputationApprovedDelta {
+		t.Errorf("Expected score %d after approval, got %d", models.ReputationApprovedDelta, got)
+	}
+
+	moderate("reject", handler.RejectComment, "comment-2")
+	if want := models.ReputationApprovedDelta + models.ReputationRejectedDelta; score() != want {
+		t.Errorf("Expected score %d after rejection, got %d", want, score())
+	}
+
+	// Approving an already approved comment doesn't count twice
+	moderate("approve", handler.ApproveComment, "comment-1")
+	if want := models.ReputationApprovedDelta + models.ReputationRejectedDelta; score() != want {
+		t.Errorf("Expected score %d after re-approval, got %d", want, score())
+	}
+}
+
+func TestCommentsHandler_BulkModerationSkipsUnownedComments(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminStore := models.NewAdminUserStore(sqlDB)
+	owner, _ := adminStore.Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	other, _ := adminStore.Create(ctx, "other@example.com", "Other", "auth0|other")
+	siteStore := models.NewSiteStore(sqlDB)
+	ownSite, _ := siteStore.Create(ctx, owner.ID, "Own Site", "own.example.com", "")
+	otherSite, _ := siteStore.Create(ctx, other.ID, "Other Site", "other.example.com", "")
+
+	addComment := func(siteID, id string) {
+		t.Helper()
+		err := store.AddPageComment(ctx, siteID, "page-1", comments.Comment{
+			ID: id, Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: "pending", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+	addComment(ownSite.ID, "own-1")
+	addComment(ownSite.ID, "own-2")
+	addComment(otherSite.ID, "other-1")
+
+	handler := NewCommentsHandler(sqlDB, store, nil)
+	body := `{"comment_ids": ["own-1", "other-1", "missing", "own-2"]}`
+
+	tests := []struct {
+		name   string
+		handle http.HandlerFunc
+		check  func(id string) bool
+	}{
+		{"approve", handler.BulkApprove, func(id string) bool {
+			c, err := store.GetCommentByID(ctx, id)
+			return err == nil && c.Status == "approved"
+		}},
+		{"reject", handler.BulkReject, func(id string) bool {
+			c, err := store.GetCommentByID(ctx, id)
+			return err == nil && c.Status == "rejected"
+		}},
+		{"delete", handler.BulkDelete, func(id string) bool {
+			_, err := store.GetCommentByID(ctx, id)
+			return err != nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/comments/bulk/"+tt.name, strings.NewReader(body))
+			req = req.WithContext(contextWithUser(owner.ID))
+			rr := httptest.NewRecorder()
+			tt.handle(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			var resp bulkResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			want := BulkResult{Succeeded: 2, SkippedNotFound: 1, SkippedForbidden: 1}
+			if resp.BulkResult != want || resp.Count != 2 || !resp.Success {
+				t.Errorf("Expected %+v with count 2, got %+v", want, resp)
+			}
+
+			for _, id := range []string{"own-1", "own-2"} {
+				if !tt.check(id) {
+					t.Errorf("Expected %s to apply to %s", tt.name, id)
+				}
+			}
+			other, err := store.GetCommentByID(ctx, "other-1")
+			if err != nil || other.Status != "pending" {
+				t.Errorf("Expected other owner's comment to stay pending, got %+v (%v)", other, err)
+			}
+		})
+	}
+}
+
+// vanishingStore deletes a comment just before a batch status update, as if it
+// was deleted after the bulk request authorized it
+type vanishingStore struct {
+	db.Store
+	vanish string
+}
+
+func (s *vanishingStore) UpdateCommentStatusBatch(ctx context.Context, commentIDs []string, status, moderatorID string) ([]string, error) {
+	if err := s.Store.DeleteComment(ctx, s.vanish); err != nil {
+		return nil, err
+	}
+	return s.Store.UpdateCommentStatusBatch(ctx, commentIDs, status, moderatorID)
+}
+
+func TestCommentsHandler_BulkModerationSkipsSideEffectsForUnchangedComments(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminUser, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	userStore := models.NewUserStore(sqlDB)
+	if err := userStore.CreateOrUpdate(ctx, &models.User{ID: "author-1", SiteID: site.ID, Name: "Alice"}); err != nil {
+		t.Fatalf("CreateOrUpdate failed: %v", err)
+	}
+	for _, id := range []string{"comment-1", "comment-2"} {
+		err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+			ID: id, Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: "pending", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	handler := NewCommentsHandler(sqlDB, &vanishingStore{Store: store, vanish: "comment-2"}, nil)
+	req := httptest.NewRequest("POST", "/admin/comments/bulk/approve", strings.NewReader(`{"comment_ids": ["comment-1", "comment-2"]}`))
+	req = req.WithContext(contextWithUser(adminUser.ID))
+	rr := httptest.NewRecorder()
+	handler.BulkApprove(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp bulkResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if want := (BulkResult{Succeeded: 1, SkippedNotFound: 1}); resp.BulkResult != want {
+		t.Errorf("Expected %+v, got %+v", want, resp.BulkResult)
+	}
+
+	// Only the comment that was approved is audited and counts towards reputation
+	entries, _, err := audit.NewStore(sqlDB).List(ctx, site.ID, audit.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TargetID != "comment-1" {
+		t.Errorf("Expected a single audit entry for comment-1, got %+v", entries)
+	}
+	u, err := userStore.GetBySiteAndID(ctx, site.ID, "author-1")
+	if err != nil || u == nil {
+		t.Fatalf("GetBySiteAndID failed: %v", err)
+	}
+	if u.ReputationScore != models.ReputationApprovedDelta {
+		t.Errorf("Expected score %d, got %d", models.ReputationApprovedDelta, u.ReputationScore)
+	}
+	var decisions int
+	if err := sqlDB.QueryRow("SELECT COUNT(*) FROM moderation_events WHERE comment_id = 'comment-2'").Scan(&decisions); err != nil || decisions != 0 {
+		t.Errorf("Expected no moderation decision for the deleted comment, got %d (%v)", decisions, err)
+	}
+}
+
+func TestCommentsHandler_BulkApproveNotifiesPageSubscribers(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminUser, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	page, err := models.NewPageStore(sqlDB).Create(ctx, site.ID, "/post", "Post")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	err = notifications.NewStore(sqlDB).SaveSettings(&notifications.NotificationSettings{
+		SiteID: site.ID, Enabled: true, Provider: "smtp", NotifyReply: true, OwnerEmail: "admin@example.com",
+	})
+	if err != nil {
+		t.Fatalf("Failed to save notification settings: %v", err)
+	}
+	subscriptions := notifications.NewSubscriptionStore(sqlDB)
+	for _, id := range []string{"alice", "bob"} {
+		if err := models.NewUserStore(sqlDB).CreateOrUpdate(ctx, &models.User{ID: id, SiteID: site.ID, Name: id, Email: id + "@example.com"}); err != nil {
+			t.Fatalf("CreateOrUpdate failed: %v", err)
+		}
+		if err := subscriptions.Subscribe(site.ID, page.ID, id); err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+	}
+	for _, c := range []struct{ id, status string }{{"pending-1", "pending"}, {"approved-1", "approved"}} {
+		err := store.AddPageComment(ctx, site.ID, page.ID, comments.Comment{
+			ID: c.id, Author: "alice", AuthorID: "alice", Text: "Hello", Status: c.status, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	handler := NewCommentsHandler(sqlDB, store, nil)
+	handler.SetNotificationQueue(notifications.NewQueue(sqlDB, time.Minute, 10))
+	req := httptest.NewRequest("POST", "/admin/comments/bulk/approve", strings.NewReader(`{"comment_ids": ["pending-1", "approved-1"]}`))
+	req = req.WithContext(contextWithUser(adminUser.ID))
+	rr := httptest.NewRecorder()
+	handler.BulkApprove(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Only the newly published comment notifies, and not its author
+	rows, err := sqlDB.Query("SELECT recipient, body FROM notification_queue WHERE type = ?", notifications.NotificationPageComment)
+	if err != nil {
+		t.Fatalf("Failed to query notifications: %v", err)
+	}
+	defer rows.Close()
+	var recipients []string
+	for rows.Next() {
+		var recipient, body string
+		if err := rows.Scan(&recipient, &body); err != nil {
+			t.Fatalf("Failed to scan notification: %v", err)
+		}
+		if !strings.Contains(body, "pending-1") {
+			t.Errorf("Expected the notification to link to pending-1")
+		}
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) != 1 || recipients[0] != "bob@example.com" {
+		t.Errorf("Expected only bob to be notified, got %v", recipients)
+	}
+}
+
+func TestCommentsHandler_ApproveCommentRecordsAudit(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminUser, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	for _, id := range []string{"comment-1", "comment-2"} {
+		err := store.AddPageComment(ctx, site.ID, "page-1", comments.Comment{
+			ID: id, Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: "pending", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	handler := NewCommentsHandler(sqlDB, store, nil)
+	approve := func(commentID string) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/admin/comments/"+commentID+"/approve", nil)
+		req = mux.SetURLVars(req.WithContext(contextWithUser(adminUser.ID)), map[string]string{"commentId": commentID})
+		rr := httptest.NewRecorder()
+		handler.ApproveComment(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("approve %s: expected 200, got %d: %s", commentID, rr.Code, rr.Body.String())
+		}
+	}
+
+	approve("comment-1")
+
+	entries, total, err := audit.NewStore(sqlDB).List(ctx, site.ID, audit.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 1 || len(entries) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d", total)
+	}
+	entry := entries[0]
+	if entry.ActorID != adminUser.ID || entry.Action != audit.ActionCommentApprove ||
+		entry.TargetType != audit.TargetComment || entry.TargetID != "comment-1" {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+	if entry.Metadata["previous_status"] != "pending" || entry.Metadata["author_id"] != "author-1" {
+		t.Errorf("Unexpected audit metadata: %v", entry.Metadata)
+	}
+
+	// A failed audit insert doesn't fail the approval
+	if _, err := sqlDB.Exec("DROP TABLE audit_log"); err != nil {
+		t.Fatalf("Failed to drop audit_log: %v", err)
+	}
+	approve("comment-2")
+	comment, err := store.GetCommentByID(ctx, "comment-2")
+	if err != nil || comment.Status != "approved" {
+		t.Errorf("Expected comment-2 to be approved, got %+v (%v)", comment, err)
+	}
+}
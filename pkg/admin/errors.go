@@ -0,0 +1,14 @@
+package admin
+
+import (
+	"net/http"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+)
+
+// writeError writes message in the standard JSON error envelope with the
+// request's ID, so admin clients parse errors the same way as public API ones
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	apierrors.WriteErrorWithRequestID(w, apierrors.FromStatus(statusCode, message), middleware.GetRequestID(r))
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/middleware"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func TestAdminErrorsUseEnvelope(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	owner, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+
+	handler := NewWebhooksHandler(sqlDB)
+	router := mux.NewRouter()
+	router.Use(middleware.RequestIDMiddleware)
+	router.HandleFunc("/admin/sites/{siteId}/webhooks", handler.CreateSubscription).Methods("POST")
+	router.HandleFunc("/admin/sites/{siteId}/webhooks/{subscriptionId}", handler.DeleteSubscription).Methods("DELETE")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   apierrors.ErrorCode
+	}{
+		{"not found", "DELETE", "/admin/sites/" + site.ID + "/webhooks/missing", "", http.StatusNotFound, apierrors.ErrCodeNotFound},
+		{"bad request", "POST", "/admin/sites/" + site.ID + "/webhooks", "{", http.StatusBadRequest, apierrors.ErrCodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(contextWithUser(owner.ID))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON error, got Content-Type %q", ct)
+			}
+
+			var envelope apierrors.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil || envelope.Error == nil {
+				t.Fatalf("Expected error envelope, got %s", rr.Body.String())
+			}
+			if envelope.Error.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, envelope.Error.Code)
+			}
+			if envelope.Error.Message == "" {
+				t.Error("Expected an error message")
+			}
+			if envelope.Error.RequestID == "" || envelope.Error.RequestID != rr.Header().Get("X-Request-ID") {
+				t.Errorf("Expected request ID %q in the envelope, got %q", rr.Header().Get("X-Request-ID"), envelope.Error.RequestID)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package admin
 import (
 	"bytes"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -19,6 +21,7 @@ import (
 type ExportImportHandler struct {
 	db        *sql.DB
 	templates *template.Template
+	progress  *importProgressTracker
 }
 
 // NewExportImportHandler creates a new export/import handler
@@ -26,6 +29,7 @@ func NewExportImportHandler(db *sql.DB, templates *template.Template) *ExportImp
 	return &ExportImportHandler{
 		db:        db,
 		templates: templates,
+		progress:  newImportProgressTracker(),
 	}
 }
 
@@ -41,12 +45,12 @@ func (h *ExportImportHandler) ShowExportForm(w http.ResponseWriter, r *http.Requ
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -55,7 +59,7 @@ func (h *ExportImportHandler) ShowExportForm(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "admin/export_import/export_form.html", data); err != nil {
-		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to render template")
 		return
 	}
 }
@@ -72,12 +76,12 @@ func (h *ExportImportHandler) ExportData(w http.ResponseWriter, r *http.Request)
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -93,7 +97,7 @@ func (h *ExportImportHandler) ExportData(w http.ResponseWriter, r *http.Request)
 	case "json":
 		exportData, err := exporter.ExportToJSON(r.Context(), siteID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Export failed: %v", err), http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Export failed: %v", err))
 			return
 		}
 
@@ -102,14 +106,14 @@ func (h *ExportImportHandler) ExportData(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
 		if err := exporter.WriteJSON(w, exportData); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to write export: %v", err), http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to write export: %v", err))
 			return
 		}
 
 	case "csv-comments":
 		var buf bytes.Buffer
 		if err := exporter.ExportToCSV(r.Context(), &buf, siteID); err != nil {
-			http.Error(w, fmt.Sprintf("Export failed: %v", err), http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Export failed: %v", err))
 			return
 		}
 
@@ -121,7 +125,7 @@ func (h *ExportImportHandler) ExportData(w http.ResponseWriter, r *http.Request)
 	case "csv-reactions":
 		var buf bytes.Buffer
 		if err := exporter.ExportReactionsToCSV(&buf, siteID); err != nil {
-			http.Error(w, fmt.Sprintf("Export failed: %v", err), http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Export failed: %v", err))
 			return
 		}
 
@@ -131,7 +135,7 @@ func (h *ExportImportHandler) ExportData(w http.ResponseWriter, r *http.Request)
 		w.Write(buf.Bytes())
 
 	default:
-		http.Error(w, "Invalid format", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid format")
 		return
 	}
 }
@@ -148,12 +152,12 @@ func (h *ExportImportHandler) ShowImportForm(w http.ResponseWriter, r *http.Requ
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -162,7 +166,7 @@ func (h *ExportImportHandler) ShowImportForm(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "admin/export_import/import_form.html", data); err != nil {
-		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to render template")
 		return
 	}
 }
@@ -179,25 +183,25 @@ func (h *ExportImportHandler) ImportData(w http.ResponseWriter, r *http.Request)
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	// Parse multipart form (limit to 10MB)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Failed to parse form")
 		return
 	}
 
 	// Get file from form
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "No file uploaded")
 		return
 	}
 	defer file.Close()
@@ -209,13 +213,22 @@ func (h *ExportImportHandler) ImportData(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Create importer
-	importer := importpkg.NewImporter(h.db, importpkg.DuplicateStrategy(strategy))
+	importer := importpkg.NewImporterWithOptions(h.db, importpkg.DuplicateStrategy(strategy), importpkg.ImportOptions{
+		DedupeByContent: r.FormValue("dedupe_by_content") == "true",
+	})
+
+	// Report progress to a subscriber of the upload's progress stream
+	if uploadID := r.FormValue("upload_id"); uploadID != "" {
+		onProgress, finish := h.progress.start(siteID, uploadID)
+		importer.OnProgress = onProgress
+		defer finish()
+	}
 
 	// Determine format from file extension
 	var result *importpkg.ImportResult
 	filename := header.Filename
 	if len(filename) < 4 {
-		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid file name")
 		return
 	}
 	
@@ -225,20 +238,24 @@ func (h *ExportImportHandler) ImportData(w http.ResponseWriter, r *http.Request)
 		ext = ".json"
 	} else if len(filename) >= 4 && filename[len(filename)-4:] == ".csv" {
 		ext = ".csv"
+	} else if len(filename) >= 4 && filename[len(filename)-4:] == ".xml" {
+		ext = ".xml"
 	}
 	
 	switch ext {
 	case ".json":
 		result, err = importer.ImportFromJSON(file, siteID)
 	case ".csv":
-		result, err = importer.ImportFromCSV(file, siteID)
+		result, err = importCSV(importer, file, siteID)
+	case ".xml":
+		result, err = importer.ImportFromXML(file, siteID)
 	default:
-		http.Error(w, "Unsupported file format (must be .json or .csv)", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Unsupported file format (must be .json, .csv or a WordPress/Disqus .xml export)")
 		return
 	}
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Import failed: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Import failed: %v", err))
 		return
 	}
 
@@ -252,6 +269,20 @@ func (h *ExportImportHandler) ImportData(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// importCSV imports a comments or reactions CSV export, told apart by its header row
+func importCSV(importer *importpkg.Importer, r io.Reader, siteID string) (*importpkg.ImportResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	header, _ := csv.NewReader(bytes.NewReader(data)).Read()
+	if importpkg.IsReactionsCSVHeader(header) {
+		return importer.ImportReactionsFromCSV(bytes.NewReader(data), siteID)
+	}
+	return importer.ImportFromCSV(bytes.NewReader(data), siteID)
+}
+
 // ExportDataAPI provides API endpoint for exports (returns JSON in response)
 func (h *ExportImportHandler) ExportDataAPI(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -264,19 +295,19 @@ func (h *ExportImportHandler) ExportDataAPI(w http.ResponseWriter, r *http.Reque
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	exporter := export.NewExporter(h.db)
 	exportData, err := exporter.ExportToJSON(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Export failed: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Export failed: %v", err))
 		return
 	}
 
@@ -296,12 +327,12 @@ func (h *ExportImportHandler) ImportDataAPI(w http.ResponseWriter, r *http.Reque
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -312,12 +343,14 @@ func (h *ExportImportHandler) ImportDataAPI(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Create importer
-	importer := importpkg.NewImporter(h.db, importpkg.DuplicateStrategy(strategy))
+	importer := importpkg.NewImporterWithOptions(h.db, importpkg.DuplicateStrategy(strategy), importpkg.ImportOptions{
+		DedupeByContent: r.URL.Query().Get("dedupe_by_content") == "true",
+	})
 
 	// Import from request body
 	result, err := importer.ImportFromJSON(r.Body, siteID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Import failed: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Import failed: %v", err))
 		return
 	}
 
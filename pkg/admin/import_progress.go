@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// importProgressRetention is how long a finished or abandoned upload's
+// progress is kept so a late subscriber still sees the final state
+const importProgressRetention = time.Minute
+
+// ImportProgress is a snapshot of a running import
+type ImportProgress struct {
+	Processed int  `json:"processed"`
+	Total     int  `json:"total"`
+	Done      bool `json:"done"`
+}
+
+// importProgressEntry holds the latest progress of one upload. updates has a
+// buffer of one and is only ever sent to without blocking, so a slow
+// subscriber just sees fewer, coalesced events.
+type importProgressEntry struct {
+	mu       sync.Mutex
+	siteID   string
+	progress ImportProgress
+	started  bool
+	updates  chan struct{}
+}
+
+func (e *importProgressEntry) snapshot() ImportProgress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.progress
+}
+
+func (e *importProgressEntry) notify() {
+	select {
+	case e.updates <- struct{}{}:
+	default:
+	}
+}
+
+// importProgressTracker keeps the progress of in-flight uploads keyed by an
+// upload ID chosen by the browser
+type importProgressTracker struct {
+	mu      sync.Mutex
+	entries map[string]*importProgressEntry
+}
+
+func newImportProgressTracker() *importProgressTracker {
+	return &importProgressTracker{entries: make(map[string]*importProgressEntry)}
+}
+
+// entry returns the upload's entry, creating it so the subscriber and the
+// upload can arrive in either order. An entry never moves between sites.
+func (t *importProgressTracker) entry(siteID, uploadID string) *importProgressEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[uploadID]
+	if !ok {
+		e = &importProgressEntry{siteID: siteID, updates: make(chan struct{}, 1)}
+		t.entries[uploadID] = e
+	}
+	if e.siteID != siteID {
+		return nil
+	}
+	return e
+}
+
+// removeLater forgets the upload after importProgressRetention
+func (t *importProgressTracker) removeLater(uploadID string, e *importProgressEntry) {
+	time.AfterFunc(importProgressRetention, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.entries[uploadID] == e {
+			delete(t.entries, uploadID)
+		}
+	})
+}
+
+// start marks the upload as running and returns the importer's progress
+// callback and a function to call once the import has finished
+func (t *importProgressTracker) start(siteID, uploadID string) (onProgress func(processed, total int), finish func()) {
+	e := t.entry(siteID, uploadID)
+	if e == nil {
+		return nil, func() {}
+	}
+	e.mu.Lock()
+	e.started = true
+	e.mu.Unlock()
+
+	onProgress = func(processed, total int) {
+		e.mu.Lock()
+		e.progress.Processed = processed
+		e.progress.Total = total
+		e.mu.Unlock()
+		e.notify()
+	}
+	finish = func() {
+		e.mu.Lock()
+		e.progress.Done = true
+		e.mu.Unlock()
+		e.notify()
+		t.removeLater(uploadID, e)
+	}
+	return onProgress, finish
+}
+
+// ImportProgressStream handles GET /admin/sites/{siteId}/import/progress/{uploadId}.
+// It streams the upload's progress as server-sent events until the import
+// finishes or the client disconnects.
+func (h *ExportImportHandler) ImportProgressStream(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	uploadID := vars["uploadId"]
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	e := h.progress.entry(siteID, uploadID)
+	if e == nil {
+		writeError(w, r, http.StatusNotFound, "Upload not found")
+		return
+	}
+	defer func() {
+		e.mu.Lock()
+		started := e.started
+		e.mu.Unlock()
+		if !started {
+			h.progress.removeLater(uploadID, e)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for {
+		progress := e.snapshot()
+		data, _ := json.Marshal(progress)
+		fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+		flusher.Flush()
+		if progress.Done {
+			return
+		}
+
+		select {
+		case <-e.updates:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
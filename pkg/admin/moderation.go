@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/saasuke-labs/kotomi/pkg/moderation"
@@ -41,14 +42,22 @@ func (h *ModerationHandler) HandleModerationForm(w http.ResponseWriter, r *http.
 		config = &defaultConfig
 	}
 
+	blockedWords, err := h.store.ListBlockedWords(r.Context(), siteID)
+	if err != nil {
+		log.Printf("Error listing blocked words: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to load blocked words")
+		return
+	}
+
 	data := map[string]interface{}{
-		"SiteID": siteID,
-		"Config": config,
+		"SiteID":       siteID,
+		"Config":       config,
+		"BlockedWords": strings.Join(blockedWords, "\n"),
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "moderation/form.html", data); err != nil {
 		log.Printf("Error rendering moderation form: %v", err)
-		http.Error(w, "Failed to render form", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to render form")
 	}
 }
 
@@ -58,7 +67,7 @@ func (h *ModerationHandler) HandleModerationUpdate(w http.ResponseWriter, r *htt
 	siteID := vars["siteId"]
 
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid form data")
 		return
 	}
 
@@ -69,8 +78,21 @@ func (h *ModerationHandler) HandleModerationUpdate(w http.ResponseWriter, r *htt
 		CheckOffensive:       r.FormValue("check_offensive") == "on",
 		CheckAggressive:      r.FormValue("check_aggressive") == "on",
 		CheckOffTopic:        r.FormValue("check_off_topic") == "on",
+		BlockedWordAction:    r.FormValue("blocked_word_action"),
+		LinkAction:           r.FormValue("link_action"),
 	}
 
+	// A blank key keeps the stored one, matching other secret fields in the admin UI
+	akismetAPIKey := strings.TrimSpace(r.FormValue("akismet_api_key"))
+	if r.FormValue("clear_akismet_api_key") == "on" {
+		akismetAPIKey = ""
+	} else if akismetAPIKey == "" {
+		if existing, err := h.store.GetBySiteID(r.Context(), siteID); err == nil {
+			akismetAPIKey = existing.AkismetAPIKey
+		}
+	}
+	config.AkismetAPIKey = akismetAPIKey
+
 	// Parse thresholds
 	autoRejectThreshold, err := strconv.ParseFloat(r.FormValue("auto_reject_threshold"), 64)
 	if err != nil {
@@ -84,26 +106,77 @@ func (h *ModerationHandler) HandleModerationUpdate(w http.ResponseWriter, r *htt
 	}
 	config.AutoApproveThreshold = autoApproveThreshold
 
+	trustedThreshold, err := strconv.Atoi(strings.TrimSpace(r.FormValue("trusted_reputation_threshold")))
+	if err != nil {
+		trustedThreshold = moderation.DefaultTrustedReputationThreshold
+	}
+	config.TrustedReputationThreshold = trustedThreshold
+
+	maxLinks, err := strconv.Atoi(strings.TrimSpace(r.FormValue("max_links")))
+	if err != nil || maxLinks < 0 {
+		maxLinks = moderation.DefaultMaxLinks
+	}
+	config.MaxLinks = maxLinks
+
 	// Check if config exists
 	_, err = h.store.GetBySiteID(r.Context(), siteID)
 	if err != nil {
 		// Config doesn't exist, create it
 		if err := h.store.Create(r.Context(), siteID, config); err != nil {
 			log.Printf("Error creating moderation config: %v", err)
-			http.Error(w, "Failed to create configuration", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to create configuration")
 			return
 		}
 	} else {
 		// Config exists, update it
 		if err := h.store.Update(r.Context(), siteID, config); err != nil {
 			log.Printf("Error updating moderation config: %v", err)
-			http.Error(w, "Failed to update configuration", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to update configuration")
 			return
 		}
 	}
 
+	if err := h.syncBlockedWords(r, siteID, r.FormValue("blocked_words")); err != nil {
+		log.Printf("Error updating blocked words: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update blocked words")
+		return
+	}
+
 	// Return success response for HTMX
 	w.Header().Set("HX-Trigger", "configUpdated")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, "Configuration updated successfully")
 }
+
+// syncBlockedWords makes the site's blocklist match the newline-separated words
+// submitted in the form
+func (h *ModerationHandler) syncBlockedWords(r *http.Request, siteID, submitted string) error {
+	existing, err := h.store.ListBlockedWords(r.Context(), siteID)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, line := range strings.Split(submitted, "\n") {
+		if word := strings.ToLower(strings.TrimSpace(line)); word != "" {
+			wanted[word] = true
+		}
+	}
+
+	for _, word := range existing {
+		if wanted[word] {
+			delete(wanted, word)
+			continue
+		}
+		if err := h.store.RemoveBlockedWord(r.Context(), siteID, word); err != nil {
+			return err
+		}
+	}
+	for word := range wanted {
+		if err := h.store.AddBlockedWord(r.Context(), siteID, word); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/moderation"
+)
+
+// queueSources are the moderation sources the queue can be filtered by
+var queueSources = map[string]bool{
+	moderation.SourceAI:        true,
+	moderation.SourceReports:   true,
+	moderation.SourceBlocklist: true,
+	moderation.SourceLinks:     true,
+}
+
+// QueueComment is a pending comment with the context an owner needs to review it
+type QueueComment struct {
+	comments.Comment
+	PageTitle string `json:"page_title,omitempty"`
+	// PendingSource is the source of the comment's latest moderation event
+	// (ai, reports, blocklist, links), or empty if none was recorded
+	PendingSource string `json:"pending_source,omitempty"`
+}
+
+// ModerationQueue is one page of a site's pending comments
+type ModerationQueue struct {
+	PendingCount int            `json:"pending_count"` // every pending comment, for the badge
+	Total        int            `json:"total"`         // pending comments matching the source filter
+	Comments     []QueueComment `json:"comments"`
+	Limit        int            `json:"limit"`
+	Offset       int            `json:"offset"`
+}
+
+// GetModerationQueue handles GET /admin/sites/{siteId}/moderation/queue. It
+// returns pending comments oldest first, optionally only those held by the
+// given source, with the site's total pending count.
+func (h *CommentsHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	siteID := mux.Vars(r)["siteId"]
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	filter, err := parseCommentListFilter(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	source := r.URL.Query().Get("source")
+	if source != "" && !queueSources[source] {
+		writeError(w, r, http.StatusBadRequest, "source must be one of ai, reports, blocklist or links")
+		return
+	}
+
+	pendingCount, err := h.commentStore.GetPendingCount(r.Context(), siteID)
+	if err != nil {
+		log.Printf("Error counting pending comments for site %s: %v", siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch moderation queue")
+		return
+	}
+
+	queue := &ModerationQueue{PendingCount: pendingCount, Comments: []QueueComment{}, Limit: filter.Limit, Offset: filter.Offset}
+	if pendingCount > 0 {
+		queue.Comments, queue.Total, err = h.queryModerationQueue(r.Context(), siteID, source, filter.Limit, filter.Offset)
+		if err != nil {
+			log.Printf("Error fetching moderation queue for site %s: %v", siteID, err)
+			writeError(w, r, http.StatusInternalServerError, "Failed to fetch moderation queue")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+// queryModerationQueue loads a page of pending comments with their page,
+// report count and latest moderation source, and the number matching source
+func (h *CommentsHandler) queryModerationQueue(ctx context.Context, siteID, source string, limit, offset int) ([]QueueComment, int, error) {
+	from := `
+		FROM (
+			SELECT c.*,
+			       (SELECT me.source FROM moderation_events me
+			        WHERE me.comment_id = c.id
+			        ORDER BY me.created_at DESC LIMIT 1) AS pending_source
+			FROM comments c
+			WHERE c.status = 'pending' AND c.site_id = ?
+		) q
+		LEFT JOIN pages p ON q.page_id = p.id
+	`
+	args := []interface{}{siteID}
+	if source != "" {
+		from += " WHERE q.pending_source = ?"
+		args = append(args, source)
+	}
+
+	var total int
+	if err := h.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT q.id, q.site_id, q.page_id, q.author, q.author_id, q.text, q.parent_id,
+		       q.status, q.created_at, q.updated_at, q.pending_source, p.path, p.title,
+		       (SELECT COUNT(*) FROM comment_reports cr WHERE cr.comment_id = q.id)
+	` + from + " ORDER BY q.created_at ASC"
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	queue := []QueueComment{}
+	for rows.Next() {
+		var c QueueComment
+		var parentID, pendingSource, pagePath, pageTitle sql.NullString
+		err := rows.Scan(
+			&c.ID, &c.SiteID, &c.PageID, &c.Author, &c.AuthorID, &c.Text, &parentID,
+			&c.Status, &c.CreatedAt, &c.UpdatedAt, &pendingSource, &pagePath, &pageTitle,
+			&c.ReportCount,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		c.ParentID = parentID.String
+		c.PendingSource = pendingSource.String
+		c.PagePath = pagePath.String
+		c.PageTitle = pageTitle.String
+		queue = append(queue, c)
+	}
+
+	return queue, total, rows.Err()
+}
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/moderation"
+)
+
+func TestCommentsHandler_GetModerationQueue(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminUser, _ := models.NewAdminUserStore(sqlDB).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	page, err := models.NewPageStore(sqlDB).Create(ctx, site.ID, "/post", "A Post")
+	if err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	seed := []struct {
+		id, status, source string
+	}{
+		{"held-by-ai", "pending", moderation.SourceAI},
+		{"approved", "approved", moderation.SourceAI},
+		{"held-by-reports", "pending", moderation.SourceReports},
+		{"rejected", "rejected", moderation.SourceBlocklist},
+		{"new", "pending", ""},
+	}
+	events := moderation.NewEventStore(sqlDB)
+	for i, s := range seed {
+		err := store.AddPageComment(ctx, site.ID, page.ID, comments.Comment{
+			ID: s.id, Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: s.status,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute), UpdatedAt: base,
+		})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+		if s.source != "" {
+			if err := events.Record(ctx, moderation.Event{CommentID: s.id, SiteID: site.ID, Decision: s.status, Source: s.source}); err != nil {
+				t.Fatalf("Record failed: %v", err)
+			}
+		}
+	}
+	if _, err := comments.NewReportStore(sqlDB).Create(ctx, "held-by-reports", "reader-1", "spam"); err != nil {
+		t.Fatalf("failed to report comment: %v", err)
+	}
+
+	handler := NewCommentsHandler(sqlDB, store, nil)
+	get := func(query string) ModerationQueue {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/admin/sites/"+site.ID+"/moderation/queue"+query, nil)
+		req = mux.SetURLVars(req.WithContext(contextWithUser(adminUser.ID)), map[string]string{"siteId": site.ID})
+		rr := httptest.NewRecorder()
+		handler.GetModerationQueue(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var queue ModerationQueue
+		if err := json.NewDecoder(rr.Body).Decode(&queue); err != nil {
+			t.Fatalf("failed to decode queue: %v", err)
+		}
+		return queue
+	}
+
+	queue := get("")
+	if queue.PendingCount != 3 || queue.Total != 3 {
+		t.Errorf("Expected 3 pending comments, got pending_count=%d total=%d", queue.PendingCount, queue.Total)
+	}
+	wantOrder := []string{"held-by-ai", "held-by-reports", "new"}
+	if len(queue.Comments) != len(wantOrder) {
+		t.Fatalf("Expected %d queued comments, got %d", len(wantOrder), len(queue.Comments))
+	}
+	for i, id := range wantOrder {
+		c := queue.Comments[i]
+		if c.ID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, c.ID)
+		}
+		if c.Status != "pending" {
+			t.Errorf("Comment %s: expected pending, got %s", c.ID, c.Status)
+		}
+		if c.PagePath != "/post" || c.PageTitle != "A Post" {
+			t.Errorf("Comment %s: expected page context, got %q %q", c.ID, c.PagePath, c.PageTitle)
+		}
+	}
+	if queue.Comments[1].ReportCount != 1 || queue.Comments[1].PendingSource != moderation.SourceReports {
+		t.Errorf("Expected reported comment to carry its report count and source, got %+v", queue.Comments[1])
+	}
+
+	queue = get("?source=ai")
+	if queue.PendingCount != 3 || queue.Total != 1 || len(queue.Comments) != 1 || queue.Comments[0].ID != "held-by-ai" {
+		t.Errorf("Expected only the AI-held comment, got %+v", queue)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/sites/"+site.ID+"/moderation/queue?source=bogus", nil)
+	req = mux.SetURLVars(req.WithContext(contextWithUser(adminUser.ID)), map[string]string{"siteId": site.ID})
+	rr := httptest.NewRecorder()
+	handler.GetModerationQueue(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown source, got %d", rr.Code)
+	}
+}
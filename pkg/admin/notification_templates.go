@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/notifications"
+)
+
+// notificationTemplateRequest is the body of a template save
+type notificationTemplateRequest struct {
+	SubjectTemplate string `json:"subject_template"`
+	BodyTemplate    string `json:"body_template"`
+}
+
+// verifySiteOwner writes an error and returns false unless the current user owns the site
+func (h *NotificationsHandler) verifySiteOwner(w http.ResponseWriter, r *http.Request, siteID string) bool {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil || site == nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return false
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return false
+	}
+
+	return true
+}
+
+// ListTemplates handles GET /admin/sites/{siteId}/notifications/templates
+func (h *NotificationsHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	templates, err := notifications.NewSiteTemplateStore(h.db).List(siteID)
+	if err != nil {
+		log.Printf("Error listing notification templates: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to list templates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// SaveTemplate handles PUT /admin/sites/{siteId}/notifications/templates/{type}.
+// The template is rendered against sample data first and rejected with 400 if
+// it fails to parse or uses a field the notification type doesn't provide.
+func (h *NotificationsHandler) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	var req notificationTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	store := notifications.NewSiteTemplateStore(h.db)
+	notificationType := notifications.NotificationType(vars["type"])
+	err := store.Save(&notifications.SiteTemplate{
+		SiteID:          siteID,
+		Type:            notificationType,
+		SubjectTemplate: req.SubjectTemplate,
+		BodyTemplate:    req.BodyTemplate,
+	})
+	if errors.Is(err, notifications.ErrUnsupportedTemplateType) {
+		writeError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	saved, err := store.Get(siteID, notificationType)
+	if err != nil {
+		log.Printf("Error reading saved notification template: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to save template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteTemplate handles DELETE /admin/sites/{siteId}/notifications/templates/{type},
+// restoring the default template
+func (h *NotificationsHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	err := notifications.NewSiteTemplateStore(h.db).Delete(siteID, notifications.NotificationType(vars["type"]))
+	if err != nil {
+		log.Printf("Error deleting notification template: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
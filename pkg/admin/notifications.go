@@ -36,23 +36,24 @@ func (h *NotificationsHandler) HandleNotificationsForm(w http.ResponseWriter, r
 	settings, err := h.store.GetSettings(siteID)
 	if err != nil {
 		log.Printf("Error getting notification settings: %v", err)
-		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get settings")
 		return
 	}
 
 	// If no settings exist, create default
 	if settings == nil {
 		settings = &notifications.NotificationSettings{
-			SiteID:           siteID,
-			Enabled:          false,
-			Provider:         "smtp",
-			FromEmail:        "",
-			FromName:         "",
-			SMTPEncryption:   "tls",
-			SMTPPort:         587,
-			NotifyNewComment: true,
-			NotifyReply:      true,
-			NotifyModeration: true,
+			SiteID:               siteID,
+			Enabled:              false,
+			Provider:             "smtp",
+			FromEmail:            "",
+			FromName:             "",
+			SMTPEncryption:       "tls",
+			SMTPPort:             587,
+			NotifyNewComment:     true,
+			NotifyNewCommentMode: notifications.NewCommentModeImmediate,
+			NotifyReply:          true,
+			NotifyModeration:     true,
 		}
 	}
 
@@ -63,7 +64,7 @@ func (h *NotificationsHandler) HandleNotificationsForm(w http.ResponseWriter, r
 
 	if err := h.templates.ExecuteTemplate(w, "admin/notifications/form.html", data); err != nil {
 		log.Printf("Error rendering notifications form: %v", err)
-		http.Error(w, "Template error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error")
 	}
 }
 
@@ -73,7 +74,7 @@ func (h *NotificationsHandler) HandleNotificationsUpdate(w http.ResponseWriter,
 	siteID := vars["siteId"]
 
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Failed to parse form")
 		return
 	}
 
@@ -96,7 +97,15 @@ func (h *NotificationsHandler) HandleNotificationsUpdate(w http.ResponseWriter,
 	sendGridAPIKey := r.FormValue("sendgrid_api_key")
 	
 	// Notification types
-	notifyNewComment := r.FormValue("notify_new_comment") == "on"
+	newCommentMode := r.FormValue("notify_new_comment_mode")
+	switch newCommentMode {
+	case notifications.NewCommentModeImmediate, notifications.NewCommentModeDaily, notifications.NewCommentModeOff:
+	case "":
+		newCommentMode = notifications.NewCommentModeImmediate
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid new comment notification mode")
+		return
+	}
 	notifyReply := r.FormValue("notify_reply") == "on"
 	notifyModeration := r.FormValue("notify_moderation") == "on"
 
@@ -106,7 +115,7 @@ func (h *NotificationsHandler) HandleNotificationsUpdate(w http.ResponseWriter,
 		var err error
 		smtpPort, err = strconv.Atoi(smtpPortStr)
 		if err != nil {
-			http.Error(w, "Invalid SMTP port", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "Invalid SMTP port")
 			return
 		}
 	}
@@ -115,7 +124,7 @@ func (h *NotificationsHandler) HandleNotificationsUpdate(w http.ResponseWriter,
 	settings, err := h.store.GetSettings(siteID)
 	if err != nil {
 		log.Printf("Error getting settings: %v", err)
-		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get settings")
 		return
 	}
 
@@ -142,14 +151,15 @@ func (h *NotificationsHandler) HandleNotificationsUpdate(w http.ResponseWriter,
 	if sendGridAPIKey != "" {
 		settings.SendGridAPIKey = sendGridAPIKey
 	}
-	settings.NotifyNewComment = notifyNewComment
+	settings.NotifyNewComment = newCommentMode != notifications.NewCommentModeOff
+	settings.NotifyNewCommentMode = newCommentMode
 	settings.NotifyReply = notifyReply
 	settings.NotifyModeration = notifyModeration
 
 	// Save settings
 	if err := h.store.SaveSettings(settings); err != nil {
 		log.Printf("Error saving notification settings: %v", err)
-		http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to save settings")
 		return
 	}
 
@@ -165,12 +175,12 @@ func (h *NotificationsHandler) HandleTestEmail(w http.ResponseWriter, r *http.Re
 	// Get notification settings
 	settings, err := h.store.GetSettings(siteID)
 	if err != nil || settings == nil {
-		http.Error(w, "Settings not configured", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Settings not configured")
 		return
 	}
 
 	if !settings.Enabled {
-		http.Error(w, "Notifications are not enabled", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Notifications are not enabled")
 		return
 	}
 
@@ -200,7 +210,7 @@ func (h *NotificationsHandler) HandleTestEmail(w http.ResponseWriter, r *http.Re
 			settings.FromName,
 		)
 	default:
-		http.Error(w, "Unknown provider", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Unknown provider")
 		return
 	}
 
@@ -220,7 +230,7 @@ func (h *NotificationsHandler) HandleTestEmail(w http.ResponseWriter, r *http.Re
 	err = sender.Send(r.Context(), testEmail, "Kotomi Test Email", testBody)
 	if err != nil {
 		log.Printf("Test email failed: %v", err)
-		http.Error(w, "Failed to send test email: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to send test email: "+err.Error())
 		return
 	}
 
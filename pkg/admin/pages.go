@@ -29,7 +29,7 @@ func NewPagesHandler(db *sql.DB, templates *template.Template) *PagesHandler {
 func (h *PagesHandler) ListPages(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -40,19 +40,19 @@ func (h *PagesHandler) ListPages(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	pageStore := models.NewPageStore(h.db)
 	pages, err := pageStore.GetBySite(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Failed to fetch pages", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch pages")
 		return
 	}
 
@@ -64,7 +64,7 @@ func (h *PagesHandler) ListPages(w http.ResponseWriter, r *http.Request) {
 				"SiteID": siteID,
 			})
 			if err != nil {
-				http.Error(w, "Template error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Template error")
 			}
 		}
 		return
@@ -79,7 +79,7 @@ func (h *PagesHandler) ListPages(w http.ResponseWriter, r *http.Request) {
 func (h *PagesHandler) GetPage(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -91,14 +91,14 @@ func (h *PagesHandler) GetPage(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	pageStore := models.NewPageStore(h.db)
 	page, err := pageStore.GetByID(r.Context(), pageID)
 	if err != nil || page.SiteID != siteID {
-		http.Error(w, "Page not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Page not found")
 		return
 	}
 
@@ -111,7 +111,7 @@ func (h *PagesHandler) GetPage(w http.ResponseWriter, r *http.Request) {
 func (h *PagesHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -122,13 +122,13 @@ func (h *PagesHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid form data")
 		return
 	}
 
@@ -136,14 +136,14 @@ func (h *PagesHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
 	title := r.FormValue("title")
 
 	if path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	pageStore := models.NewPageStore(h.db)
 	page, err := pageStore.Create(r.Context(), siteID, path, title)
 	if err != nil {
-		http.Error(w, "Failed to create page", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create page")
 		return
 	}
 
@@ -164,7 +164,7 @@ func (h *PagesHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
 func (h *PagesHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -176,7 +176,7 @@ func (h *PagesHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -184,13 +184,13 @@ func (h *PagesHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 	pageStore := models.NewPageStore(h.db)
 	page, err := pageStore.GetByID(r.Context(), pageID)
 	if err != nil || page.SiteID != siteID {
-		http.Error(w, "Page not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Page not found")
 		return
 	}
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid form data")
 		return
 	}
 
@@ -198,13 +198,13 @@ func (h *PagesHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 	title := r.FormValue("title")
 
 	if path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	err = pageStore.Update(r.Context(), pageID, path, title)
 	if err != nil {
-		http.Error(w, "Failed to update page", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update page")
 		return
 	}
 
@@ -222,7 +222,7 @@ func (h *PagesHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 func (h *PagesHandler) DeletePage(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -234,7 +234,7 @@ func (h *PagesHandler) DeletePage(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -242,13 +242,13 @@ func (h *PagesHandler) DeletePage(w http.ResponseWriter, r *http.Request) {
 	pageStore := models.NewPageStore(h.db)
 	page, err := pageStore.GetByID(r.Context(), pageID)
 	if err != nil || page.SiteID != siteID {
-		http.Error(w, "Page not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Page not found")
 		return
 	}
 
 	err = pageStore.Delete(r.Context(), pageID)
 	if err != nil {
-		http.Error(w, "Failed to delete page", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete page")
 		return
 	}
 
@@ -265,7 +265,7 @@ func (h *PagesHandler) DeletePage(w http.ResponseWriter, r *http.Request) {
 // ShowPageForm handles GET /admin/sites/{siteId}/pages/new and GET /admin/sites/{siteId}/pages/{pageId}/edit
 func (h *PagesHandler) ShowPageForm(w http.ResponseWriter, r *http.Request) {
 	if h.templates == nil {
-		http.Error(w, "Templates not available", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Templates not available")
 		return
 	}
 
@@ -285,14 +285,14 @@ func (h *PagesHandler) ShowPageForm(w http.ResponseWriter, r *http.Request) {
 		siteStore := models.NewSiteStore(h.db)
 		site, err := siteStore.GetByID(r.Context(), siteID)
 		if err != nil || site.OwnerID != userID {
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden, "Forbidden")
 			return
 		}
 
 		pageStore := models.NewPageStore(h.db)
 		page, err := pageStore.GetByID(r.Context(), pageID)
 		if err != nil || page.SiteID != siteID {
-			http.Error(w, "Page not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "Page not found")
 			return
 		}
 		data["Page"] = page
@@ -300,6 +300,6 @@ func (h *PagesHandler) ShowPageForm(w http.ResponseWriter, r *http.Request) {
 
 	err := h.templates.ExecuteTemplate(w, "pages/form.html", data)
 	if err != nil {
-		http.Error(w, "Template error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error")
 	}
 }
@@ -3,11 +3,13 @@ package admin
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/audit"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
 	"github.com/saasuke-labs/kotomi/pkg/models"
 )
@@ -34,14 +36,14 @@ func (h *ReactionsHandler) ListAllowedReactions(w http.ResponseWriter, r *http.R
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -50,7 +52,7 @@ func (h *ReactionsHandler) ListAllowedReactions(w http.ResponseWriter, r *http.R
 	reactions, err := allowedReactionStore.GetBySite(r.Context(), siteID)
 	if err != nil {
 		log.Printf("Error fetching allowed reactions: %v", err)
-		http.Error(w, "Failed to fetch reactions", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch reactions")
 		return
 	}
 
@@ -61,7 +63,7 @@ func (h *ReactionsHandler) ListAllowedReactions(w http.ResponseWriter, r *http.R
 
 	if err := h.templates.ExecuteTemplate(w, "admin/reactions/list.html", data); err != nil {
 		log.Printf("Template error: %v", err)
-		http.Error(w, "Template error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error")
 	}
 }
 
@@ -74,14 +76,14 @@ func (h *ReactionsHandler) ShowReactionForm(w http.ResponseWriter, r *http.Reque
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -91,11 +93,11 @@ func (h *ReactionsHandler) ShowReactionForm(w http.ResponseWriter, r *http.Reque
 		allowedReactionStore := models.NewAllowedReactionStore(h.db)
 		reaction, err = allowedReactionStore.GetByID(r.Context(), reactionID)
 		if err != nil {
-			http.Error(w, "Reaction not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "Reaction not found")
 			return
 		}
 		if reaction.SiteID != siteID {
-			http.Error(w, "Reaction not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "Reaction not found")
 			return
 		}
 	}
@@ -108,7 +110,7 @@ func (h *ReactionsHandler) ShowReactionForm(w http.ResponseWriter, r *http.Reque
 
 	if err := h.templates.ExecuteTemplate(w, "admin/reactions/form.html", data); err != nil {
 		log.Printf("Template error: %v", err)
-		http.Error(w, "Template error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error")
 	}
 }
 
@@ -120,14 +122,14 @@ func (h *ReactionsHandler) CreateAllowedReaction(w http.ResponseWriter, r *http.
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -137,7 +139,7 @@ func (h *ReactionsHandler) CreateAllowedReaction(w http.ResponseWriter, r *http.
 	reactionType := r.FormValue("reaction_type")
 
 	if name == "" || emoji == "" {
-		http.Error(w, "Name and emoji are required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Name and emoji are required")
 		return
 	}
 
@@ -148,17 +150,45 @@ func (h *ReactionsHandler) CreateAllowedReaction(w http.ResponseWriter, r *http.
 
 	// Create reaction
 	allowedReactionStore := models.NewAllowedReactionStore(h.db)
-	_, err = allowedReactionStore.Create(r.Context(), siteID, name, emoji, reactionType)
+	reaction, err := allowedReactionStore.Create(r.Context(), siteID, name, emoji, reactionType)
+	if isAllowedReactionValidationError(err) {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating allowed reaction: %v", err)
-		http.Error(w, "Failed to create reaction", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create reaction")
 		return
 	}
+	h.auditReaction(r, siteID, userID, audit.ActionReactionCreate, reaction.ID, map[string]interface{}{
+		"name": name, "emoji": emoji, "reaction_type": reactionType,
+	})
 
 	// Redirect back to list
 	http.Redirect(w, r, "/admin/sites/"+siteID+"/reactions", http.StatusSeeOther)
 }
 
+// auditReaction records an owner's change to an allowed reaction in the audit log
+func (h *ReactionsHandler) auditReaction(r *http.Request, siteID, userID, action, reactionID string, metadata map[string]interface{}) {
+	audit.Record(r.Context(), h.db, audit.Entry{
+		SiteID:     siteID,
+		ActorID:    userID,
+		Action:     action,
+		TargetType: audit.TargetAllowedReaction,
+		TargetID:   reactionID,
+		Metadata:   metadata,
+	})
+}
+
+// isAllowedReactionValidationError reports whether err is a rejected
+// allowed reaction that should be shown to the admin rather than logged
+func isAllowedReactionValidationError(err error) bool {
+	return errors.Is(err, models.ErrInvalidEmoji) ||
+		errors.Is(err, models.ErrAllowedReactionLimitReached) ||
+		errors.Is(err, models.ErrDuplicateAllowedReactionEmoji) ||
+		errors.Is(err, models.ErrInvalidReactionType)
+}
+
 // UpdateAllowedReaction updates an allowed reaction
 func (h *ReactionsHandler) UpdateAllowedReaction(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -168,14 +198,14 @@ func (h *ReactionsHandler) UpdateAllowedReaction(w http.ResponseWriter, r *http.
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -183,7 +213,7 @@ func (h *ReactionsHandler) UpdateAllowedReaction(w http.ResponseWriter, r *http.
 	allowedReactionStore := models.NewAllowedReactionStore(h.db)
 	reaction, err := allowedReactionStore.GetByID(r.Context(), reactionID)
 	if err != nil || reaction.SiteID != siteID {
-		http.Error(w, "Reaction not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Reaction not found")
 		return
 	}
 
@@ -193,7 +223,7 @@ func (h *ReactionsHandler) UpdateAllowedReaction(w http.ResponseWriter, r *http.
 	reactionType := r.FormValue("reaction_type")
 
 	if name == "" || emoji == "" {
-		http.Error(w, "Name and emoji are required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Name and emoji are required")
 		return
 	}
 
@@ -204,15 +234,75 @@ func (h *ReactionsHandler) UpdateAllowedReaction(w http.ResponseWriter, r *http.
 
 	// Update reaction
 	if err := allowedReactionStore.Update(r.Context(), reactionID, name, emoji, reactionType); err != nil {
+		if isAllowedReactionValidationError(err) {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
 		log.Printf("Error updating allowed reaction: %v", err)
-		http.Error(w, "Failed to update reaction", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update reaction")
 		return
 	}
+	h.auditReaction(r, siteID, userID, audit.ActionReactionUpdate, reactionID, map[string]interface{}{
+		"name": name, "emoji": emoji, "reaction_type": reactionType,
+		"previous_name": reaction.Name, "previous_emoji": reaction.Emoji, "previous_reaction_type": reaction.ReactionType,
+	})
 
 	// Redirect back to list
 	http.Redirect(w, r, "/admin/sites/"+siteID+"/reactions", http.StatusSeeOther)
 }
 
+// ChangeAllowedReactionType changes the targets an allowed reaction applies to,
+// deleting the reactions the new type no longer allows, and reports how many
+// were removed
+func (h *ReactionsHandler) ChangeAllowedReactionType(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	reactionID := vars["reactionId"]
+
+	// Verify user owns the site
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	siteStore := models.NewSiteStore(h.db)
+	site, err := siteStore.GetByID(r.Context(), siteID)
+	if err != nil || site.OwnerID != userID {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return
+	}
+
+	// Verify reaction belongs to site
+	allowedReactionStore := models.NewAllowedReactionStore(h.db)
+	reaction, err := allowedReactionStore.GetByID(r.Context(), reactionID)
+	if err != nil || reaction.SiteID != siteID {
+		writeError(w, r, http.StatusNotFound, "Reaction not found")
+		return
+	}
+
+	reactionType := r.FormValue("reaction_type")
+	removed, err := allowedReactionStore.ChangeType(r.Context(), reactionID, reactionType)
+	if isAllowedReactionValidationError(err) {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error changing allowed reaction type: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to change reaction type")
+		return
+	}
+	h.auditReaction(r, siteID, userID, audit.ActionReactionChangeType, reactionID, map[string]interface{}{
+		"reaction_type": reactionType, "previous_reaction_type": reaction.ReactionType, "removed_reactions": removed,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reaction_type":     reactionType,
+		"removed_reactions": removed,
+	})
+}
+
 // DeleteAllowedReaction deletes an allowed reaction
 func (h *ReactionsHandler) DeleteAllowedReaction(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -222,14 +312,14 @@ func (h *ReactionsHandler) DeleteAllowedReaction(w http.ResponseWriter, r *http.
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -237,16 +327,19 @@ func (h *ReactionsHandler) DeleteAllowedReaction(w http.ResponseWriter, r *http.
 	allowedReactionStore := models.NewAllowedReactionStore(h.db)
 	reaction, err := allowedReactionStore.GetByID(r.Context(), reactionID)
 	if err != nil || reaction.SiteID != siteID {
-		http.Error(w, "Reaction not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Reaction not found")
 		return
 	}
 
 	// Delete reaction
 	if err := allowedReactionStore.Delete(r.Context(), reactionID); err != nil {
 		log.Printf("Error deleting allowed reaction: %v", err)
-		http.Error(w, "Failed to delete reaction", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete reaction")
 		return
 	}
+	h.auditReaction(r, siteID, userID, audit.ActionReactionDelete, reactionID, map[string]interface{}{
+		"name": reaction.Name, "emoji": reaction.Emoji,
+	})
 
 	// Return success for HTMX
 	w.WriteHeader(http.StatusOK)
@@ -260,14 +353,14 @@ func (h *ReactionsHandler) GetReactionStats(w http.ResponseWriter, r *http.Reque
 	// Verify user owns the site
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site.OwnerID != userID {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
@@ -284,7 +377,7 @@ func (h *ReactionsHandler) GetReactionStats(w http.ResponseWriter, r *http.Reque
 	rows, err := h.db.QueryContext(r.Context(), query, siteID)
 	if err != nil {
 		log.Printf("Error querying reaction stats: %v", err)
-		http.Error(w, "Failed to get reaction stats", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get reaction stats")
 		return
 	}
 	defer rows.Close()
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/audit"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
 	"github.com/saasuke-labs/kotomi/pkg/models"
 )
@@ -29,14 +30,14 @@ func NewSitesHandler(db *sql.DB, templates *template.Template) *SitesHandler {
 func (h *SitesHandler) ListSites(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	sites, err := siteStore.GetByOwner(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to fetch sites", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch sites")
 		return
 	}
 
@@ -48,7 +49,7 @@ func (h *SitesHandler) ListSites(w http.ResponseWriter, r *http.Request) {
 				"Sites": sites,
 			})
 			if err != nil {
-				http.Error(w, "Template error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Template error")
 			}
 		}
 		return
@@ -63,7 +64,7 @@ func (h *SitesHandler) ListSites(w http.ResponseWriter, r *http.Request) {
 func (h *SitesHandler) GetSite(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -73,13 +74,13 @@ func (h *SitesHandler) GetSite(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	// Check ownership
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -87,7 +88,7 @@ func (h *SitesHandler) GetSite(w http.ResponseWriter, r *http.Request) {
 	pageStore := models.NewPageStore(h.db)
 	pages, err := pageStore.GetBySite(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Failed to fetch pages", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch pages")
 		return
 	}
 
@@ -99,7 +100,7 @@ func (h *SitesHandler) GetSite(w http.ResponseWriter, r *http.Request) {
 				"Pages": pages,
 			})
 			if err != nil {
-				http.Error(w, "Template error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Template error")
 			}
 		}
 		return
@@ -117,13 +118,13 @@ func (h *SitesHandler) GetSite(w http.ResponseWriter, r *http.Request) {
 func (h *SitesHandler) CreateSite(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid form data")
 		return
 	}
 
@@ -132,16 +133,17 @@ func (h *SitesHandler) CreateSite(w http.ResponseWriter, r *http.Request) {
 	description := r.FormValue("description")
 
 	if name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Name is required")
 		return
 	}
 
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.Create(r.Context(), userID, name, domain, description)
 	if err != nil {
-		http.Error(w, "Failed to create site", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create site")
 		return
 	}
+	h.auditSite(r, site.ID, userID, audit.ActionSiteCreate, name, domain)
 
 	// For HTMX requests, redirect to the site detail page
 	if r.Header.Get("HX-Request") == "true" {
@@ -160,7 +162,7 @@ func (h *SitesHandler) CreateSite(w http.ResponseWriter, r *http.Request) {
 func (h *SitesHandler) UpdateSite(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -171,18 +173,18 @@ func (h *SitesHandler) UpdateSite(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid form data")
 		return
 	}
 
@@ -191,15 +193,16 @@ func (h *SitesHandler) UpdateSite(w http.ResponseWriter, r *http.Request) {
 	description := r.FormValue("description")
 
 	if name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Name is required")
 		return
 	}
 
 	err = siteStore.Update(r.Context(), siteID, name, domain, description)
 	if err != nil {
-		http.Error(w, "Failed to update site", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update site")
 		return
 	}
+	h.auditSite(r, siteID, userID, audit.ActionSiteUpdate, name, domain)
 
 	// For HTMX requests
 	if r.Header.Get("HX-Request") == "true" {
@@ -215,7 +218,7 @@ func (h *SitesHandler) UpdateSite(w http.ResponseWriter, r *http.Request) {
 func (h *SitesHandler) DeleteSite(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -226,20 +229,21 @@ func (h *SitesHandler) DeleteSite(w http.ResponseWriter, r *http.Request) {
 	siteStore := models.NewSiteStore(h.db)
 	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 
 	if site.OwnerID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	err = siteStore.Delete(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Failed to delete site", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete site")
 		return
 	}
+	h.auditSite(r, siteID, userID, audit.ActionSiteDelete, site.Name, site.Domain)
 
 	// For HTMX requests
 	if r.Header.Get("HX-Request") == "true" {
@@ -251,10 +255,22 @@ func (h *SitesHandler) DeleteSite(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// auditSite records an owner's change to a site in the audit log
+func (h *SitesHandler) auditSite(r *http.Request, siteID, userID, action, name, domain string) {
+	audit.Record(r.Context(), h.db, audit.Entry{
+		SiteID:     siteID,
+		ActorID:    userID,
+		Action:     action,
+		TargetType: audit.TargetSite,
+		TargetID:   siteID,
+		Metadata:   map[string]interface{}{"name": name, "domain": domain},
+	})
+}
+
 // ShowSiteForm handles GET /admin/sites/new and GET /admin/sites/{siteId}/edit
 func (h *SitesHandler) ShowSiteForm(w http.ResponseWriter, r *http.Request) {
 	if h.templates == nil {
-		http.Error(w, "Templates not available", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Templates not available")
 		return
 	}
 
@@ -270,7 +286,7 @@ func (h *SitesHandler) ShowSiteForm(w http.ResponseWriter, r *http.Request) {
 		siteStore := models.NewSiteStore(h.db)
 		site, err := siteStore.GetByID(r.Context(), siteID)
 		if err != nil || site.OwnerID != userID {
-			http.Error(w, "Site not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "Site not found")
 			return
 		}
 		data["Site"] = site
@@ -278,6 +294,6 @@ func (h *SitesHandler) ShowSiteForm(w http.ResponseWriter, r *http.Request) {
 
 	err := h.templates.ExecuteTemplate(w, "sites/form.html", data)
 	if err != nil {
-		http.Error(w, "Template error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error")
 	}
 }
@@ -4,20 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/export"
 	"github.com/saasuke-labs/kotomi/pkg/models"
 )
 
 // UserManagementHandler handles admin user management endpoints
 type UserManagementHandler struct {
-	db        *sql.DB
-	templates *template.Template
+	db             *sql.DB
+	templates      *template.Template
+	analyticsCache *analytics.CachedStore
 }
 
 // NewUserManagementHandler creates a new user management handler
@@ -28,6 +34,11 @@ func NewUserManagementHandler(db *sql.DB, templates *template.Template) *UserMan
 	}
 }
 
+// SetAnalyticsCache sets the analytics cache invalidated when a user's data is erased
+func (h *UserManagementHandler) SetAnalyticsCache(cache *analytics.CachedStore) {
+	h.analyticsCache = cache
+}
+
 // UserStats represents statistics about users
 type UserStats struct {
 	TotalUsers        int
@@ -67,7 +78,7 @@ type CommentInfo struct {
 func (h *UserManagementHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 	adminUserID := auth.GetUserIDFromContext(r.Context())
 	if adminUserID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -75,7 +86,7 @@ func (h *UserManagementHandler) ListUsersHandler(w http.ResponseWriter, r *http.
 	siteID := vars["siteId"]
 
 	// Verify user owns the site
-	if !h.verifySiteOwnership(r.Context(), siteID, adminUserID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, adminUserID) {
 		return
 	}
 
@@ -83,7 +94,7 @@ func (h *UserManagementHandler) ListUsersHandler(w http.ResponseWriter, r *http.
 	userStore := models.NewUserStore(h.db)
 	users, err := userStore.ListBySite(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to retrieve users")
 		return
 	}
 
@@ -96,7 +107,7 @@ func (h *UserManagementHandler) ListUsersPage(w http.ResponseWriter, r *http.Req
 	ctx := r.Context()
 	adminUserID := auth.GetUserIDFromContext(ctx)
 	if adminUserID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -105,13 +116,13 @@ func (h *UserManagementHandler) ListUsersPage(w http.ResponseWriter, r *http.Req
 
 	// Verify user owns the site
 	siteStore := models.NewSiteStore(h.db)
-	site, err := siteStore.GetByID(ctx, siteID)
+	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site == nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 	if site.OwnerID != adminUserID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -123,7 +134,7 @@ func (h *UserManagementHandler) ListUsersPage(w http.ResponseWriter, r *http.Req
 	userStore := models.NewUserStore(h.db)
 	users, err := userStore.ListBySite(ctx, siteID)
 	if err != nil {
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to retrieve users")
 		return
 	}
 
@@ -170,7 +181,7 @@ func (h *UserManagementHandler) ListUsersPage(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "admin/users/list.html", data); err != nil {
-		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error: "+err.Error())
 	}
 }
 
@@ -178,7 +189,7 @@ func (h *UserManagementHandler) ListUsersPage(w http.ResponseWriter, r *http.Req
 func (h *UserManagementHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	adminUserID := auth.GetUserIDFromContext(r.Context())
 	if adminUserID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -187,7 +198,7 @@ func (h *UserManagementHandler) GetUserHandler(w http.ResponseWriter, r *http.Re
 	userID := vars["userId"]
 
 	// Verify user owns the site
-	if !h.verifySiteOwnership(r.Context(), siteID, adminUserID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, adminUserID) {
 		return
 	}
 
@@ -195,11 +206,11 @@ func (h *UserManagementHandler) GetUserHandler(w http.ResponseWriter, r *http.Re
 	userStore := models.NewUserStore(h.db)
 	user, err := userStore.GetBySiteAndID(r.Context(), siteID, userID)
 	if err != nil {
-		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to retrieve user")
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "User not found")
 		return
 	}
 
@@ -212,7 +223,7 @@ func (h *UserManagementHandler) GetUserDetailPage(w http.ResponseWriter, r *http
 	ctx := r.Context()
 	adminUserID := auth.GetUserIDFromContext(ctx)
 	if adminUserID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -222,13 +233,13 @@ func (h *UserManagementHandler) GetUserDetailPage(w http.ResponseWriter, r *http
 
 	// Get site
 	siteStore := models.NewSiteStore(h.db)
-	site, err := siteStore.GetByID(ctx, siteID)
+	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site == nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return
 	}
 	if site.OwnerID != adminUserID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
@@ -236,11 +247,11 @@ func (h *UserManagementHandler) GetUserDetailPage(w http.ResponseWriter, r *http
 	userStore := models.NewUserStore(h.db)
 	user, err := userStore.GetBySiteAndID(ctx, siteID, userID)
 	if err != nil {
-		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to retrieve user")
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "User not found")
 		return
 	}
 
@@ -258,7 +269,7 @@ func (h *UserManagementHandler) GetUserDetailPage(w http.ResponseWriter, r *http
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "admin/users/detail.html", data); err != nil {
-		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template error: "+err.Error())
 	}
 }
 
@@ -266,7 +277,7 @@ func (h *UserManagementHandler) GetUserDetailPage(w http.ResponseWriter, r *http
 func (h *UserManagementHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	adminUserID := auth.GetUserIDFromContext(r.Context())
 	if adminUserID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -275,32 +286,163 @@ func (h *UserManagementHandler) DeleteUserHandler(w http.ResponseWriter, r *http
 	userID := vars["userId"]
 
 	// Verify user owns the site
-	if !h.verifySiteOwnership(r.Context(), siteID, adminUserID, w) {
+	if !h.verifySiteOwnership(w, r, siteID, adminUserID) {
 		return
 	}
 
 	// Delete user (cascade deletes comments and reactions)
 	userStore := models.NewUserStore(h.db)
 	if err := userStore.Delete(r.Context(), siteID, userID); err != nil {
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete user")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// VerifyUserHandler handles POST /admin/sites/{siteId}/users/{userId}/verify,
+// giving the commenter the verified badge
+func (h *UserManagementHandler) VerifyUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUserVerified(w, r, true)
+}
+
+// UnverifyUserHandler handles POST /admin/sites/{siteId}/users/{userId}/unverify,
+// removing the commenter's verified badge
+func (h *UserManagementHandler) UnverifyUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUserVerified(w, r, false)
+}
+
+// setUserVerified sets a commenter's verification for the site owner and
+// responds with the updated user
+func (h *UserManagementHandler) setUserVerified(w http.ResponseWriter, r *http.Request, verified bool) {
+	adminUserID := auth.GetUserIDFromContext(r.Context())
+	if adminUserID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	userID := vars["userId"]
+
+	if !h.verifySiteOwnership(w, r, siteID, adminUserID) {
+		return
+	}
+
+	userStore := models.NewUserStore(h.db)
+	setVerified := userStore.UnverifyUser
+	if verified {
+		setVerified = userStore.VerifyUser
+	}
+	if err := setVerified(r.Context(), siteID, userID); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			writeError(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		log.Printf("Error updating verification of user %s on site %s: %v", userID, siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	user, err := userStore.GetBySiteAndID(r.Context(), siteID, userID)
+	if err != nil || user == nil {
+		writeError(w, r, http.StatusInternalServerError, "Failed to retrieve user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteUserDataHandler handles POST /admin/sites/{siteId}/users/{userId}/erase,
+// erasing a commenter's data to honor a deletion request. The mode query
+// parameter is "anonymize" (the default) or "purge"; the response counts the
+// rows changed.
+func (h *UserManagementHandler) DeleteUserDataHandler(w http.ResponseWriter, r *http.Request) {
+	adminUserID := auth.GetUserIDFromContext(r.Context())
+	if adminUserID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	userID := vars["userId"]
+
+	if !h.verifySiteOwnership(w, r, siteID, adminUserID) {
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = models.DeletionModeAnonymize
+	}
+	if !models.IsValidDeletionMode(mode) {
+		writeError(w, r, http.StatusBadRequest, "mode must be anonymize or purge")
+		return
+	}
+
+	result, err := models.NewUserStore(h.db).DeleteUserData(r.Context(), siteID, userID, mode)
+	if err != nil {
+		log.Printf("Error erasing data of user %s on site %s: %v", userID, siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete user data")
+		return
+	}
+	h.analyticsCache.InvalidateSite(siteID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ExportUserDataHandler handles GET /admin/sites/{siteId}/users/{userId}/export,
+// downloading everything the site holds about a commenter as JSON to answer an
+// access request
+func (h *UserManagementHandler) ExportUserDataHandler(w http.ResponseWriter, r *http.Request) {
+	adminUserID := auth.GetUserIDFromContext(r.Context())
+	if adminUserID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	userID := vars["userId"]
+
+	if !h.verifySiteOwnership(w, r, siteID, adminUserID) {
+		return
+	}
+
+	data, err := models.NewUserStore(h.db).ExportUserData(r.Context(), siteID, userID)
+	if err != nil {
+		log.Printf("Error exporting data of user %s on site %s: %v", userID, siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to export user data")
+		return
+	}
+	if data.Profile == nil && len(data.Comments) == 0 && len(data.Reactions) == 0 && len(data.Reports) == 0 {
+		writeError(w, r, http.StatusNotFound, "No data found for user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", export.GetExportFilename("user_data", "json")))
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(data)
+}
+
 // verifySiteOwnership checks if the authenticated admin user owns the specified site
-func (h *UserManagementHandler) verifySiteOwnership(ctx context.Context, siteID, adminUserID string, w http.ResponseWriter) bool {
+func (h *UserManagementHandler) verifySiteOwnership(w http.ResponseWriter, r *http.Request, siteID, adminUserID string) bool {
 	// Check if site exists and belongs to admin user
 	siteStore := models.NewSiteStore(h.db)
-	site, err := siteStore.GetByID(ctx, siteID)
+	site, err := siteStore.GetByID(r.Context(), siteID)
 	if err != nil || site == nil {
-		http.Error(w, "Site not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found")
 		return false
 	}
 
 	if site.OwnerID != adminUserID {
-		http.Error(w, "Forbidden: You do not own this site", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Forbidden: You do not own this site")
 		return false
 	}
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -250,3 +251,150 @@ func TestUserManagementHandler_ForbiddenAccess(t *testing.T) {
 		t.Errorf("Expected status 403, got %d", rr.Code)
 	}
 }
+
+func TestUserManagementHandler_DeleteUserDataHandler(t *testing.T) {
+	handler, store, siteID, adminUserID := setupUserManagementTest(t)
+	defer store.Close()
+
+	erase := func(mode, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/sites/"+siteID+"/users/user-1/erase?mode="+mode, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "userId": "user-1"})
+		req = req.WithContext(auth.SetUserIDInContext(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		handler.DeleteUserDataHandler(rr, req)
+		return rr
+	}
+
+	if rr := erase("purge", "someone-else"); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-owner, got %d", rr.Code)
+	}
+	if rr := erase("shred", adminUserID); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown mode, got %d", rr.Code)
+	}
+
+	rr := erase("purge", adminUserID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result models.UserDataDeletion
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.Mode != models.DeletionModePurge || result.Users != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	user, err := models.NewUserStore(store.GetDB()).GetBySiteAndID(context.Background(), siteID, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to look up user: %v", err)
+	}
+	if user != nil {
+		t.Error("Expected the user to be deleted")
+	}
+}
+
+func TestUserManagementHandler_ExportUserDataHandler(t *testing.T) {
+	handler, store, siteID, adminUserID := setupUserManagementTest(t)
+	defer store.Close()
+
+	export := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/sites/"+siteID+"/users/"+userID+"/export", nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "userId": userID})
+		req = req.WithContext(auth.SetUserIDInContext(req.Context(), adminUserID))
+		rr := httptest.NewRecorder()
+		handler.ExportUserDataHandler(rr, req)
+		return rr
+	}
+
+	rr := export("user-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if disposition := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment;") {
+		t.Errorf("Expected a download, got Content-Disposition %q", disposition)
+	}
+	var data models.UserDataExport
+	if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	if data.Profile == nil || data.Profile.Email != "user1@test.com" {
+		t.Errorf("Expected user-1's profile, got %+v", data.Profile)
+	}
+
+	if rr := export("nobody"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a user without data, got %d", rr.Code)
+	}
+}
+
+func TestUserManagementHandler_VerifyUser(t *testing.T) {
+	handler, store, siteID, adminUserID := setupUserManagementTest(t)
+	defer store.Close()
+
+	call := func(verified bool, adminID, userID string) *httptest.ResponseRecorder {
+		action, handle := "unverify", handler.UnverifyUserHandler
+		if verified {
+			action, handle = "verify", handler.VerifyUserHandler
+		}
+		req := httptest.NewRequest("POST", "/admin/sites/"+siteID+"/users/"+userID+"/"+action, nil)
+		req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "userId": userID})
+		req = req.WithContext(auth.SetUserIDInContext(req.Context(), adminID))
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		return rr
+	}
+	isVerified := func(userID string) bool {
+		t.Helper()
+		user, err := models.NewUserStore(store.GetDB()).GetBySiteAndID(context.Background(), siteID, userID)
+		if err != nil || user == nil {
+			t.Fatalf("Failed to look up user: %v", err)
+		}
+		return user.IsVerified
+	}
+
+	rr := call(true, adminUserID, "user-2")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var user models.User
+	if err := json.Unmarshal(rr.Body.Bytes(), &user); err != nil || !user.IsVerified {
+		t.Errorf("Expected the verified user in the response, got %s", rr.Body.String())
+	}
+	if !isVerified("user-2") {
+		t.Error("Expected user-2 to be verified")
+	}
+
+	if rr := call(false, adminUserID, "user-1"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if isVerified("user-1") {
+		t.Error("Expected user-1 to be unverified")
+	}
+
+	if rr := call(true, adminUserID, "nobody"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", rr.Code)
+	}
+}
+
+func TestUserManagementHandler_VerifyUser_Forbidden(t *testing.T) {
+	handler, store, siteID, _ := setupUserManagementTest(t)
+	defer store.Close()
+
+	otherAdmin, err := models.NewAdminUserStore(store.GetDB()).Create(context.Background(), "other@test.com", "Other Admin", "auth0|other")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/admin/sites/"+siteID+"/users/user-2/verify", nil)
+	req = mux.SetURLVars(req, map[string]string{"siteId": siteID, "userId": "user-2"})
+	req = req.WithContext(auth.SetUserIDInContext(req.Context(), otherAdmin.ID))
+	rr := httptest.NewRecorder()
+	handler.VerifyUserHandler(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-owner, got %d", rr.Code)
+	}
+	user, _ := models.NewUserStore(store.GetDB()).GetBySiteAndID(context.Background(), siteID, "user-2")
+	if user.IsVerified {
+		t.Error("Expected a non-owner not to verify the user")
+	}
+}
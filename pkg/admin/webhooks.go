@@ -0,0 +1,208 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
+)
+
+// webhookDeliveriesLimit is how many recent delivery attempts are listed
+const webhookDeliveriesLimit = 50
+
+// WebhooksHandler handles a site's outbound webhook subscriptions
+type WebhooksHandler struct {
+	db *sql.DB
+}
+
+// NewWebhooksHandler creates a new webhooks handler
+func NewWebhooksHandler(db *sql.DB) *WebhooksHandler {
+	return &WebhooksHandler{db: db}
+}
+
+// CreatedSubscription is a new subscription together with its signing
+// secret, which is only returned by the request that created it
+type CreatedSubscription struct {
+	webhooks.Subscription
+	Secret string `json:"secret"`
+}
+
+// subscriptionRequest is the body of a subscription create or update
+type subscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// decodeSubscriptionRequest reads and validates a subscription body, writing
+// a 400 and returning false if it is invalid
+func decodeSubscriptionRequest(w http.ResponseWriter, r *http.Request) (*subscriptionRequest, bool) {
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request")
+		return nil, false
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if _, err := webhooks.ValidateEvents(req.Events); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return &req, true
+}
+
+// verifySiteOwner writes an error and returns false unless the current user owns the site
+func (h *WebhooksHandler) verifySiteOwner(w http.ResponseWriter, r *http.Request, siteID string) bool {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+
+	site, err := models.NewSiteStore(h.db).GetByID(r.Context(), siteID)
+	if err != nil || site == nil {
+		writeError(w, r, http.StatusNotFound, "Site not found")
+		return false
+	}
+	if site.OwnerID != userID {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return false
+	}
+
+	return true
+}
+
+// ListSubscriptions handles GET /admin/sites/{siteId}/webhooks
+func (h *WebhooksHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	subs, err := webhooks.NewSubscriptionStore(h.db).ListBySite(r.Context(), siteID)
+	if err != nil {
+		log.Printf("Error fetching webhook subscriptions: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// CreateSubscription handles POST /admin/sites/{siteId}/webhooks. The
+// response is the only place the signing secret is ever shown.
+func (h *WebhooksHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	req, ok := decodeSubscriptionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := webhooks.NewSubscriptionStore(h.db).Create(r.Context(), siteID, req.URL, req.Events)
+	if err != nil {
+		log.Printf("Error creating webhook subscription on site %s: %v", siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedSubscription{Subscription: *sub, Secret: sub.Secret})
+}
+
+// UpdateSubscription handles PUT /admin/sites/{siteId}/webhooks/{subscriptionId}
+func (h *WebhooksHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	req, ok := decodeSubscriptionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := webhooks.NewSubscriptionStore(h.db).Update(r.Context(), siteID, vars["subscriptionId"], req.URL, req.Events)
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		writeError(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating webhook subscription %s on site %s: %v", vars["subscriptionId"], siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// DeleteSubscription handles DELETE /admin/sites/{siteId}/webhooks/{subscriptionId}
+func (h *WebhooksHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	err := webhooks.NewSubscriptionStore(h.db).Delete(r.Context(), siteID, vars["subscriptionId"])
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		writeError(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting webhook subscription %s on site %s: %v", vars["subscriptionId"], siteID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /admin/sites/{siteId}/webhooks/{subscriptionId}/deliveries,
+// returning the subscription's most recent delivery attempts
+func (h *WebhooksHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	siteID := vars["siteId"]
+	if !h.verifySiteOwner(w, r, siteID) {
+		return
+	}
+
+	store := webhooks.NewSubscriptionStore(h.db)
+	sub, err := store.Get(r.Context(), siteID, vars["subscriptionId"])
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		writeError(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching webhook subscription %s: %v", vars["subscriptionId"], err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch deliveries")
+		return
+	}
+
+	deliveries, err := store.ListDeliveries(r.Context(), sub.ID, webhookDeliveriesLimit)
+	if err != nil {
+		log.Printf("Error fetching webhook deliveries for %s: %v", sub.ID, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to fetch deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/db"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+	"github.com/saasuke-labs/kotomi/pkg/webhooks"
+)
+
+func TestWebhooksHandler(t *testing.T) {
+	store, err := db.NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+
+	sqlDB := store.GetDB()
+	ctx := context.Background()
+	adminStore := models.NewAdminUserStore(sqlDB)
+	owner, _ := adminStore.Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	other, _ := adminStore.Create(ctx, "other@example.com", "Other", "auth0|other")
+	site, _ := models.NewSiteStore(sqlDB).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+
+	handler := NewWebhooksHandler(sqlDB)
+	serve := func(handle http.HandlerFunc, method, body, userID string, vars map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/admin/sites/"+site.ID+"/webhooks", strings.NewReader(body))
+		req = mux.SetURLVars(req.WithContext(contextWithUser(userID)), vars)
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		return rr
+	}
+	siteVars := map[string]string{"siteId": site.ID}
+	body := `{"url":"https://hooks.example.com/kotomi","events":["comment.created","reaction.added"]}`
+
+	if rr := serve(handler.CreateSubscription, "POST", body, other.ID, siteVars); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 creating a webhook on someone else's site, got %d", rr.Code)
+	}
+	if rr := serve(handler.CreateSubscription, "POST", `{"url":"https://hooks.example.com","events":["comment.deleted"]}`, owner.ID, siteVars); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown event, got %d", rr.Code)
+	}
+	if rr := serve(handler.CreateSubscription, "POST", `{"url":"not a url","events":["comment.created"]}`, owner.ID, siteVars); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid URL, got %d", rr.Code)
+	}
+
+	rr := serve(handler.CreateSubscription, "POST", body, owner.ID, siteVars)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created CreatedSubscription
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode created webhook: %v", err)
+	}
+	if created.Secret == "" || len(created.Events) != 2 {
+		t.Errorf("Unexpected created webhook: %+v", created)
+	}
+
+	// The secret is never listed
+	rr = serve(handler.ListSubscriptions, "GET", "", owner.ID, siteVars)
+	if strings.Contains(rr.Body.String(), created.Secret) {
+		t.Error("Expected the webhook list not to contain the secret")
+	}
+
+	subVars := map[string]string{"siteId": site.ID, "subscriptionId": created.ID}
+	rr = serve(handler.UpdateSubscription, "PUT", `{"url":"https://hooks.example.com/v2","events":["comment.approved"]}`, owner.ID, subVars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 updating, got %d: %s", rr.Code, rr.Body.String())
+	}
+	updated, err := webhooks.NewSubscriptionStore(sqlDB).Get(ctx, site.ID, created.ID)
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	if updated.URL != "https://hooks.example.com/v2" || len(updated.Events) != 1 || updated.Secret != created.Secret {
+		t.Errorf("Expected URL and events updated with the secret kept, got %+v", updated)
+	}
+
+	if rr := serve(handler.ListDeliveries, "GET", "", owner.ID, subVars); rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("Expected an empty delivery log, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve(handler.DeleteSubscription, "DELETE", "", other.ID, subVars); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 deleting on someone else's site, got %d", rr.Code)
+	}
+	if rr := serve(handler.DeleteSubscription, "DELETE", "", owner.ID, subVars); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rr.Code)
+	}
+	if rr := serve(handler.DeleteSubscription, "DELETE", "", owner.ID, subVars); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting again, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,425 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dayFormat is how days are keyed in the daily aggregate tables
+const dayFormat = "2006-01-02"
+
+// dailyCommentStatsQuery counts a site's comments per day and status, in the
+// column order of daily_comment_stats. The verbs are the day expression and
+// the condition the comments must meet.
+const dailyCommentStatsQuery = `
+	SELECT %[1]s as day, COUNT(*),
+		SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END),
+		SUM(CASE WHEN status = 'approved' THEN 1 ELSE 0 END),
+		SUM(CASE WHEN status = 'rejected' THEN 1 ELSE 0 END)
+	FROM comments
+	WHERE site_id = ? AND %[2]s
+	GROUP BY %[1]s
+`
+
+// dailyReactionStatsQuery counts a site's reactions per day and allowed
+// reaction, in the column order of daily_reaction_stats
+const dailyReactionStatsQuery = `
+	SELECT %[1]s as day, r.allowed_reaction_id, COUNT(*)
+	FROM reactions r
+	INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+	WHERE ar.site_id = ? AND %[2]s
+	GROUP BY %[1]s, r.allowed_reaction_id
+`
+
+// RebuildAggregates recomputes a site's daily comment and reaction aggregates
+// for the UTC days from from to to, inclusive, and records them as covered so
+// metrics and trends read them instead of scanning every row. A zero from
+// rebuilds every day up to to and a zero to ends today. Days rebuilt through
+// today stay current as comments and reactions are written, so rebuilding
+// once per site is enough.
+func (s *Store) RebuildAggregates(ctx context.Context, siteID string, from, to time.Time) error {
+	if s.dialect.Name() != "sqlite" {
+		return fmt.Errorf("daily aggregates are not supported on %s", s.dialect.Name())
+	}
+
+	today := time.Now().UTC().Format(dayFormat)
+	toDay := today
+	if !to.IsZero() {
+		toDay = to.UTC().Format(dayFormat)
+	}
+	// An empty from day sorts before every day
+	fromDay := ""
+	if !from.IsZero() {
+		fromDay = from.UTC().Format(dayFormat)
+	}
+	if toDay < fromDay {
+		return fmt.Errorf("to date must not be before from date")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleting first takes the write lock, so no trigger can update these
+	// days between the delete and the recount
+	dayRange := s.dialect.DateBucket("created_at") + " BETWEEN ? AND ?"
+	reactionDayRange := s.dialect.DateBucket("r.created_at") + " BETWEEN ? AND ?"
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`DELETE FROM daily_comment_stats WHERE site_id = ? AND day BETWEEN ? AND ?`, []interface{}{siteID, fromDay, toDay}},
+		{`DELETE FROM daily_reaction_stats WHERE site_id = ? AND day BETWEEN ? AND ?`, []interface{}{siteID, fromDay, toDay}},
+		{`INSERT INTO daily_comment_stats (site_id, day, total, pending, approved, rejected)
+			SELECT ?, * FROM (` + fmt.Sprintf(dailyCommentStatsQuery, s.dialect.DateBucket("created_at"), dayRange) + `)`,
+			[]interface{}{siteID, siteID, fromDay, toDay}},
+		{`INSERT INTO daily_reaction_stats (site_id, day, allowed_reaction_id, total)
+			SELECT ?, * FROM (` + fmt.Sprintf(dailyReactionStatsQuery, s.dialect.DateBucket("r.created_at"), reactionDayRange) + `)`,
+			[]interface{}{siteID, siteID, fromDay, toDay}},
+	}
+	for _, st := range statements {
+		if _, err := tx.ExecContext(ctx, s.dialect.Rebind(st.query), st.args...); err != nil {
+			return fmt.Errorf("failed to rebuild daily aggregates: %w", err)
+		}
+	}
+
+	if err := recordCoverage(ctx, tx, siteID, fromDay, toDay, today); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit daily aggregates: %w", err)
+	}
+	return nil
+}
+
+// aggregateCoverage is the span of days a site's aggregates are complete for.
+// An empty fromDay means every day before toDay, and an empty toDay means they
+// are kept current through today.
+type aggregateCoverage struct {
+	fromDay string
+	toDay   string
+}
+
+// covers reports whether every day from firstDay to lastDay is complete
+func (c aggregateCoverage) covers(firstDay, lastDay string) bool {
+	return c.fromDay <= firstDay && (c.toDay == "" || lastDay <= c.toDay)
+}
+
+// recordCoverage merges the rebuilt days into the site's coverage. Coverage is
+// a single span, so a rebuild that neither overlaps nor touches it replaces it.
+func recordCoverage(ctx context.Context, tx *sql.Tx, siteID, fromDay, toDay, today string) error {
+	rebuilt := aggregateCoverage{fromDay: fromDay, toDay: toDay}
+	if toDay >= today {
+		rebuilt.toDay = ""
+	}
+
+	var existing aggregateCoverage
+	var existingTo sql.NullString
+	err := tx.QueryRowContext(ctx, `SELECT from_day, to_day FROM daily_stats_coverage WHERE site_id = ?`, siteID).
+		Scan(&existing.fromDay, &existingTo)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("failed to read aggregate coverage: %w", err)
+	default:
+		existing.toDay = existingTo.String
+		if spansTouch(existing, rebuilt) {
+			if existing.fromDay < rebuilt.fromDay {
+				rebuilt.fromDay = existing.fromDay
+			}
+			if existing.toDay == "" || (rebuilt.toDay != "" && existing.toDay > rebuilt.toDay) {
+				rebuilt.toDay = existing.toDay
+			}
+		}
+	}
+
+	toDayValue := sql.NullString{String: rebuilt.toDay, Valid: rebuilt.toDay != ""}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO daily_stats_coverage (site_id, from_day, to_day, rebuilt_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (site_id) DO UPDATE SET
+			from_day = excluded.from_day, to_day = excluded.to_day, rebuilt_at = excluded.rebuilt_at
+	`, siteID, rebuilt.fromDay, toDayValue, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record aggregate coverage: %w", err)
+	}
+	return nil
+}
+
+// spansTouch reports whether two coverage spans overlap or are adjacent
+func spansTouch(a, b aggregateCoverage) bool {
+	return reaches(a.toDay, b.fromDay) && reaches(b.toDay, a.fromDay)
+}
+
+// reaches reports whether a span ending on end reaches one starting on start,
+// that is start is no later than the day after end. An empty end is open-ended.
+func reaches(end, start string) bool {
+	if end == "" {
+		return true
+	}
+	endDay, err := time.Parse(dayFormat, end)
+	if err != nil {
+		return false
+	}
+	return endDay.AddDate(0, 0, 1).Format(dayFormat) >= start
+}
+
+// aggregateSpan splits a date range into the whole UTC days answered from the
+// daily aggregates and the partial days at either end, which are counted live
+type aggregateSpan struct {
+	firstDay string
+	lastDay  string
+	edges    []DateRange
+}
+
+// splitDateRange returns the aggregate span of dateRange, and false when the
+// range has no whole UTC day in it
+func splitDateRange(dateRange DateRange) (aggregateSpan, bool) {
+	var span aggregateSpan
+	from, to := dateRange.From.UTC(), dateRange.To.UTC()
+
+	first := from.Truncate(24 * time.Hour)
+	if first.Before(from) {
+		first = first.AddDate(0, 0, 1)
+	}
+	// The midnight after the last whole day
+	end := to.Add(time.Nanosecond).Truncate(24 * time.Hour)
+	if !end.After(first) {
+		return span, false
+	}
+
+	span.firstDay = first.Format(dayFormat)
+	span.lastDay = end.AddDate(0, 0, -1).Format(dayFormat)
+	// Edge bounds keep the range's location, as the live queries compare them
+	location := dateRange.From.Location()
+	if from.Before(first) {
+		span.edges = append(span.edges, DateRange{From: dateRange.From, To: first.Add(-time.Nanosecond).In(location)})
+	}
+	if !to.Before(end) {
+		span.edges = append(span.edges, DateRange{From: end.In(location), To: dateRange.To})
+	}
+	return span, true
+}
+
+// aggregateSpanFor returns how to answer dateRange for a site from its daily
+// aggregates, and false when they don't cover the range's whole days
+func (s *Store) aggregateSpanFor(ctx context.Context, siteID string, dateRange DateRange) (aggregateSpan, bool) {
+	if s.dialect.Name() != "sqlite" {
+		return aggregateSpan{}, false
+	}
+	span, ok := splitDateRange(dateRange)
+	if !ok {
+		return span, false
+	}
+
+	var coverage aggregateCoverage
+	var toDay sql.NullString
+	err := s.queryRow(ctx, `SELECT from_day, to_day FROM daily_stats_coverage WHERE site_id = ?`, siteID).
+		Scan(&coverage.fromDay, &toDay)
+	switch {
+	case err == sql.ErrNoRows:
+		return span, false
+	case err != nil:
+		s.warn(ctx, "Failed to read aggregate coverage", err)
+		return span, false
+	}
+	coverage.toDay = toDay.String
+	return span, coverage.covers(span.firstDay, span.lastDay)
+}
+
+// commentCounts are a day's comments by status
+type commentCounts struct {
+	total    int
+	pending  int
+	approved int
+	rejected int
+}
+
+// aggregatedCommentCounts returns a site's comments per UTC day in dateRange
+// from the daily aggregates, and false when they can't answer it
+func (s *Store) aggregatedCommentCounts(ctx context.Context, siteID string, dateRange DateRange) (map[string]commentCounts, bool) {
+	span, ok := s.aggregateSpanFor(ctx, siteID, dateRange)
+	if !ok {
+		return nil, false
+	}
+
+	days := make(map[string]commentCounts)
+	err := s.addCommentCounts(ctx, days, `
+		SELECT day, total, pending, approved, rejected FROM daily_comment_stats
+		WHERE site_id = ? AND day BETWEEN ? AND ?
+	`, siteID, span.firstDay, span.lastDay)
+	for _, edge := range span.edges {
+		if err != nil {
+			break
+		}
+		query := fmt.Sprintf(dailyCommentStatsQuery, s.dialect.DateBucket("created_at"), "created_at BETWEEN ? AND ?")
+		err = s.addCommentCounts(ctx, days, query, siteID, edge.From, edge.To)
+	}
+	if err != nil {
+		s.warn(ctx, "Failed to read daily comment aggregates", err)
+		return nil, false
+	}
+	return days, true
+}
+
+// addCommentCounts adds the per-day counts a query returns to days
+func (s *Store) addCommentCounts(ctx context.Context, days map[string]commentCounts, query string, args ...interface{}) error {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day string
+		var counts commentCounts
+		if err := rows.Scan(&day, &counts.total, &counts.pending, &counts.approved, &counts.rejected); err != nil {
+			return err
+		}
+		sum := days[day]
+		sum.total += counts.total
+		sum.pending += counts.pending
+		sum.approved += counts.approved
+		sum.rejected += counts.rejected
+		days[day] = sum
+	}
+	return rows.Err()
+}
+
+// reactionCount is how many reactions of one allowed reaction a day had
+type reactionCount struct {
+	day               string
+	allowedReactionID string
+	total             int
+}
+
+// aggregatedReactionCounts returns a site's reactions per UTC day and allowed
+// reaction in dateRange from the daily aggregates, and false when they can't
+// answer it
+func (s *Store) aggregatedReactionCounts(ctx context.Context, siteID string, dateRange DateRange) ([]reactionCount, bool) {
+	span, ok := s.aggregateSpanFor(ctx, siteID, dateRange)
+	if !ok {
+		return nil, false
+	}
+
+	counts, err := s.appendReactionCounts(ctx, nil, `
+		SELECT day, allowed_reaction_id, total FROM daily_reaction_stats
+		WHERE site_id = ? AND day BETWEEN ? AND ?
+	`, siteID, span.firstDay, span.lastDay)
+	for _, edge := range span.edges {
+		if err != nil {
+			break
+		}
+		query := fmt.Sprintf(dailyReactionStatsQuery, s.dialect.DateBucket("r.created_at"), "r.created_at BETWEEN ? AND ?")
+		counts, err = s.appendReactionCounts(ctx, counts, query, siteID, edge.From, edge.To)
+	}
+	if err != nil {
+		s.warn(ctx, "Failed to read daily reaction aggregates", err)
+		return nil, false
+	}
+	return counts, true
+}
+
+// appendReactionCounts appends the per-day counts a query returns to counts
+func (s *Store) appendReactionCounts(ctx context.Context, counts []reactionCount, query string, args ...interface{}) ([]reactionCount, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count reactionCount
+		if err := rows.Scan(&count.day, &count.allowedReactionID, &count.total); err != nil {
+			return counts, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// aggregatedDailyTotals returns a site's comment or reaction totals per UTC
+// day in dateRange from the daily aggregates, and false when they can't
+// answer it
+func (s *Store) aggregatedDailyTotals(ctx context.Context, siteID string, dateRange DateRange, dataType string) (map[string]int, bool) {
+	totals := make(map[string]int)
+	if dataType == "comments" {
+		days, ok := s.aggregatedCommentCounts(ctx, siteID, dateRange)
+		for day, counts := range days {
+			totals[day] = counts.total
+		}
+		return totals, ok
+	}
+
+	counts, ok := s.aggregatedReactionCounts(ctx, siteID, dateRange)
+	for _, count := range counts {
+		totals[count.day] += count.total
+	}
+	return totals, ok
+}
+
+// reactionBreakdown totals reaction counts by allowed reaction, most used
+// first, leaving out reactions with none
+func (s *Store) reactionBreakdown(ctx context.Context, siteID string, counts []reactionCount) ([]ReactionBreakdown, error) {
+	totals := make(map[string]int)
+	for _, count := range counts {
+		totals[count.allowedReactionID] += count.total
+	}
+
+	rows, err := s.query(ctx, `SELECT id, name, emoji FROM allowed_reactions WHERE site_id = ?`, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowed reactions: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := []ReactionBreakdown{}
+	for rows.Next() {
+		var id string
+		var item ReactionBreakdown
+		if err := rows.Scan(&id, &item.Name, &item.Emoji); err != nil {
+			return nil, fmt.Errorf("failed to scan allowed reaction: %w", err)
+		}
+		if item.Count = totals[id]; item.Count > 0 {
+			breakdown = append(breakdown, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating allowed reactions: %w", err)
+	}
+
+	sort.SliceStable(breakdown, func(i, j int) bool { return breakdown[i].Count > breakdown[j].Count })
+	return breakdown, nil
+}
+
+// weeklyTrendFromDays groups daily totals into ISO weeks labeled like
+// WeekBucket, in order, leaving out weeks without any
+func weeklyTrendFromDays(totals map[string]int) TimeSeriesData {
+	weeks := make(map[string]int)
+	for day, total := range totals {
+		t, err := time.Parse(dayFormat, day)
+		if err != nil || total == 0 {
+			continue
+		}
+		weeks[isoWeekLabel(t)] += total
+	}
+
+	keys := make([]string, 0, len(weeks))
+	for week := range weeks {
+		keys = append(keys, week)
+	}
+	sort.Strings(keys)
+
+	trend := TimeSeriesData{Labels: []string{}, Values: []int{}}
+	for _, week := range keys {
+		trend.Labels = append(trend.Labels, strings.Replace(week, "-W", " Week ", 1))
+		trend.Values = append(trend.Values, weeks[week])
+	}
+	return trend
+}
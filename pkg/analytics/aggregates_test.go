@@ -0,0 +1,228 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// aggregateRanges are date ranges exercising the daily, weekly and partial-day
+// paths of the aggregate-backed queries
+func aggregateRanges() map[string]DateRange {
+	now := time.Now()
+	midnight := now.UTC().Truncate(24 * time.Hour)
+	return map[string]DateRange{
+		"partial days": {From: now.AddDate(0, 0, -10), To: now.AddDate(0, 0, 1)},
+		"whole days":   {From: midnight.AddDate(0, 0, -10), To: midnight.Add(-time.Nanosecond)},
+		"weekly":       {From: now.AddDate(0, 0, -150), To: now},
+	}
+}
+
+// analyticsSnapshot is everything the aggregates can answer, per date range
+type analyticsSnapshot map[string][]interface{}
+
+func takeSnapshot(t *testing.T, store *Store) analyticsSnapshot {
+	t.Helper()
+	ctx := context.Background()
+	snapshot := analyticsSnapshot{}
+	for name, dateRange := range aggregateRanges() {
+		commentMetrics, err := store.GetCommentMetrics(ctx, "test-site-1", dateRange)
+		if err != nil {
+			t.Fatalf("GetCommentMetrics failed: %v", err)
+		}
+		reactionMetrics, err := store.GetReactionMetrics(ctx, "test-site-1", dateRange)
+		if err != nil {
+			t.Fatalf("GetReactionMetrics failed: %v", err)
+		}
+		commentsTrend, err := store.GetCommentsTrend(ctx, "test-site-1", dateRange)
+		if err != nil {
+			t.Fatalf("GetCommentsTrend failed: %v", err)
+		}
+		reactionsTrend, err := store.GetReactionsTrend(ctx, "test-site-1", dateRange)
+		if err != nil {
+			t.Fatalf("GetReactionsTrend failed: %v", err)
+		}
+		snapshot[name] = []interface{}{commentMetrics, reactionMetrics, commentsTrend, reactionsTrend}
+	}
+	return snapshot
+}
+
+// liveSnapshot takes a snapshot with the site's aggregate coverage removed, so
+// every query runs live, and restores the coverage afterwards
+func liveSnapshot(t *testing.T, db *sql.DB, store *Store) analyticsSnapshot {
+	t.Helper()
+	var fromDay string
+	var toDay sql.NullString
+	if err := db.QueryRow(`SELECT from_day, to_day FROM daily_stats_coverage WHERE site_id = 'test-site-1'`).Scan(&fromDay, &toDay); err != nil {
+		t.Fatalf("Failed to read coverage: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM daily_stats_coverage`); err != nil {
+		t.Fatalf("Failed to remove coverage: %v", err)
+	}
+	snapshot := takeSnapshot(t, store)
+	if _, err := db.Exec(`INSERT INTO daily_stats_coverage (site_id, from_day, to_day) VALUES ('test-site-1', ?, ?)`, fromDay, toDay); err != nil {
+		t.Fatalf("Failed to restore coverage: %v", err)
+	}
+	return snapshot
+}
+
+// seedAggregateData adds an older comment and a second reaction type to the
+// shared test data, so weekly trends and the reaction breakdown have more to sum
+func seedAggregateData(t *testing.T, db *sql.DB) {
+	t.Helper()
+	insertTestData(t, db)
+	now := time.Now()
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO comments (id, site_id, page_id, author, author_id, text, status, created_at) VALUES ('comment-old', 'test-site-1', 'test-page-1', 'Test User', 'test-user-1', 'Old', 'approved', ?)`,
+			[]interface{}{now.AddDate(0, 0, -100)}},
+		{`INSERT INTO allowed_reactions (id, site_id, name, emoji) VALUES ('reaction-2', 'test-site-1', 'heart', '❤️')`, nil},
+		{`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES ('react-4', 'comment-4', 'reaction-2', 'test-user-1', ?)`,
+			[]interface{}{now.AddDate(0, 0, -1)}},
+		{`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES ('react-5', 'comment-5', 'reaction-2', 'test-user-1', ?)`,
+			[]interface{}{now}},
+		{`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES ('react-6', 'comment-old', 'reaction-2', 'test-user-2', ?)`,
+			[]interface{}{now.AddDate(0, 0, -99)}},
+	}
+	for _, st := range statements {
+		if _, err := db.Exec(st.query, st.args...); err != nil {
+			t.Fatalf("Failed to seed data: %v", err)
+		}
+	}
+}
+
+func TestRebuildAggregates_MatchesLiveQueries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	seedAggregateData(t, db)
+
+	store := NewStore(db)
+	live := takeSnapshot(t, store)
+
+	if err := store.RebuildAggregates(context.Background(), "test-site-1", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("RebuildAggregates failed: %v", err)
+	}
+	if got := takeSnapshot(t, store); !reflect.DeepEqual(got, live) {
+		t.Errorf("Aggregate-backed results differ from live ones:\ngot  %+v\nwant %+v", got, live)
+	}
+
+	// The results really come from the aggregates
+	if _, err := db.Exec(`UPDATE daily_comment_stats SET total = total + 100`); err != nil {
+		t.Fatalf("Failed to alter aggregates: %v", err)
+	}
+	metrics, err := store.GetCommentMetrics(context.Background(), "test-site-1", aggregateRanges()["whole days"])
+	if err != nil {
+		t.Fatalf("GetCommentMetrics failed: %v", err)
+	}
+	if metrics.Total < 100 {
+		t.Errorf("Expected the altered aggregates to be read, got a total of %d", metrics.Total)
+	}
+	weekly, err := store.GetCommentsTrend(context.Background(), "test-site-1", aggregateRanges()["weekly"])
+	if err != nil {
+		t.Fatalf("GetCommentsTrend failed: %v", err)
+	}
+	if len(weekly.Values) == 0 || weekly.Values[0] < 100 {
+		t.Errorf("Expected the weekly trend to read the altered aggregates, got %v", weekly.Values)
+	}
+
+	// Rebuilding restores them, and writes keep them current from then on
+	if err := store.RebuildAggregates(context.Background(), "test-site-1", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("RebuildAggregates failed: %v", err)
+	}
+	now := time.Now()
+	writes := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO comments (id, site_id, page_id, author, author_id, text, status, created_at) VALUES ('comment-new', 'test-site-1', 'test-page-1', 'Test User', 'test-user-1', 'New', 'pending', ?)`,
+			[]interface{}{now.AddDate(0, 0, -6)}},
+		{`UPDATE comments SET status = 'approved' WHERE id = 'comment-2'`, nil},
+		{`UPDATE comments SET created_at = ? WHERE id = 'comment-4'`, []interface{}{now.AddDate(0, 0, -7)}},
+		{`DELETE FROM comments WHERE id = 'comment-3'`, nil},
+		{`DELETE FROM reactions WHERE id = 'react-1'`, nil},
+		{`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES ('react-7', 'comment-1', 'reaction-2', 'test-user-3', ?)`,
+			[]interface{}{now.AddDate(0, 0, -2)}},
+		{`UPDATE reactions SET allowed_reaction_id = 'reaction-2' WHERE id = 'react-2'`, nil},
+	}
+	for _, w := range writes {
+		if _, err := db.Exec(w.query, w.args...); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	live = liveSnapshot(t, db, store)
+	if got := takeSnapshot(t, store); !reflect.DeepEqual(got, live) {
+		t.Errorf("Aggregates drifted from live results after writes:\ngot  %+v\nwant %+v", got, live)
+	}
+}
+
+func TestRebuildAggregates_Coverage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	seedAggregateData(t, db)
+
+	store := NewStore(db)
+	ctx := context.Background()
+	coverage := func() (string, string) {
+		t.Helper()
+		var fromDay string
+		var toDay sql.NullString
+		if err := db.QueryRow(`SELECT from_day, to_day FROM daily_stats_coverage WHERE site_id = 'test-site-1'`).Scan(&fromDay, &toDay); err != nil {
+			t.Fatalf("Failed to read coverage: %v", err)
+		}
+		return fromDay, toDay.String
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse(dayFormat, s)
+		return d
+	}
+	if err := store.RebuildAggregates(ctx, "test-site-1", day("2026-01-10"), day("2026-01-20")); err != nil {
+		t.Fatalf("RebuildAggregates failed: %v", err)
+	}
+	if from, to := coverage(); from != "2026-01-10" || to != "2026-01-20" {
+		t.Errorf("Expected 2026-01-10 to 2026-01-20, got %s to %s", from, to)
+	}
+
+	// Adjacent days extend the span; disjoint ones replace it
+	if err := store.RebuildAggregates(ctx, "test-site-1", day("2026-01-21"), day("2026-01-25")); err != nil {
+		t.Fatalf("RebuildAggregates failed: %v", err)
+	}
+	if from, to := coverage(); from != "2026-01-10" || to != "2026-01-25" {
+		t.Errorf("Expected 2026-01-10 to 2026-01-25, got %s to %s", from, to)
+	}
+	if err := store.RebuildAggregates(ctx, "test-site-1", day("2026-03-01"), day("2026-03-05")); err != nil {
+		t.Fatalf("RebuildAggregates failed: %v", err)
+	}
+	if from, to := coverage(); from != "2026-03-01" || to != "2026-03-05" {
+		t.Errorf("Expected 2026-03-01 to 2026-03-05, got %s to %s", from, to)
+	}
+
+	// A range outside the coverage is answered live even if the aggregates are wrong
+	if _, err := db.Exec(`UPDATE daily_comment_stats SET total = total + 100`); err != nil {
+		t.Fatalf("Failed to alter aggregates: %v", err)
+	}
+	metrics, err := store.GetCommentMetrics(ctx, "test-site-1", aggregateRanges()["partial days"])
+	if err != nil {
+		t.Fatalf("GetCommentMetrics failed: %v", err)
+	}
+	if metrics.Total != 5 {
+		t.Errorf("Expected the 5 comments counted live, got %d", metrics.Total)
+	}
+
+	// Rebuilding everything through today covers all days, kept current from then on
+	if err := store.RebuildAggregates(ctx, "test-site-1", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("RebuildAggregates failed: %v", err)
+	}
+	if from, to := coverage(); from != "" || to != "" {
+		t.Errorf("Expected every day to be covered, got %q to %q", from, to)
+	}
+
+	if err := store.RebuildAggregates(ctx, "test-site-1", day("2026-02-01"), day("2026-01-01")); err == nil {
+		t.Error("Expected an error for reversed dates")
+	}
+}
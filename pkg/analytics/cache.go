@@ -0,0 +1,150 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultCacheTTL is how long dashboard results are reused when no TTL is configured
+const DefaultCacheTTL = 5 * time.Minute
+
+// cacheKeyResolution is the granularity date ranges are rounded to when building
+// cache keys, so default "last 30 days" ranges computed moments apart share an entry
+const cacheKeyResolution = time.Minute
+
+// cacheEntry is a memoized dashboard with its expiry
+type cacheEntry struct {
+	dashboard *AnalyticsDashboard
+	expiresAt time.Time
+}
+
+// CachedStore memoizes analytics dashboards per site and date range. Concurrent
+// misses for the same key share a single computation.
+type CachedStore struct {
+	store   *Store
+	ttl     time.Duration
+	enabled bool
+
+	mu          sync.Mutex
+	entries     map[string]cacheEntry
+	generations map[string]uint64 // bumped on invalidation to discard in-flight results
+	group       singleflight.Group
+}
+
+// NewCachedStore creates a caching analytics store. A ttl <= 0 uses DefaultCacheTTL;
+// with enabled set to false every call goes straight to the database.
+func NewCachedStore(db *sql.DB, ttl time.Duration, enabled bool) *CachedStore {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedStore{
+		store:       NewStore(db),
+		ttl:         ttl,
+		enabled:     enabled,
+		entries:     make(map[string]cacheEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+// NewCachedStoreFromEnv creates a caching analytics store whose TTL comes from
+// ANALYTICS_CACHE_TTL (a Go duration such as "30s"). "0" disables caching; invalid
+// values are logged and ignored.
+func NewCachedStoreFromEnv(db *sql.DB) *CachedStore {
+	value := os.Getenv("ANALYTICS_CACHE_TTL")
+	if value == "" {
+		return NewCachedStore(db, DefaultCacheTTL, true)
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		log.Printf("Ignoring invalid ANALYTICS_CACHE_TTL %q", value)
+		return NewCachedStore(db, DefaultCacheTTL, true)
+	}
+	return NewCachedStore(db, ttl, ttl > 0)
+}
+
+// GetAnalyticsDashboard returns the dashboard for a site, serving it from the cache
+// while the entry is fresh
+func (c *CachedStore) GetAnalyticsDashboard(ctx context.Context, siteID string, dateRange DateRange) (*AnalyticsDashboard, error) {
+	if !c.enabled {
+		return c.store.GetAnalyticsDashboard(ctx, siteID, dateRange)
+	}
+
+	key := dashboardCacheKey(siteID, dateRange)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generations[siteID]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.dashboard, nil
+	}
+
+	// The generation is part of the flight key so a request arriving after an
+	// invalidation never joins a computation that started before it
+	flightKey := fmt.Sprintf("%s|%d", key, generation)
+	value, err, _ := c.group.Do(flightKey, func() (interface{}, error) {
+		dashboard, err := c.store.GetAnalyticsDashboard(ctx, siteID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		c.mu.Lock()
+		c.evictExpired(now)
+		if c.generations[siteID] == generation {
+			c.entries[key] = cacheEntry{dashboard: dashboard, expiresAt: now.Add(c.ttl)}
+		}
+		c.mu.Unlock()
+
+		return dashboard, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*AnalyticsDashboard), nil
+}
+
+// InvalidateSite drops every cached dashboard for a site. It is safe to call on a
+// nil CachedStore so callers don't need to check whether caching is configured.
+func (c *CachedStore) InvalidateSite(siteID string) {
+	if c == nil {
+		return
+	}
+
+	prefix := siteID + "|"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[siteID]++
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// evictExpired removes stale entries; callers must hold c.mu
+func (c *CachedStore) evictExpired(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// dashboardCacheKey builds the cache key for a site and date range
+func dashboardCacheKey(siteID string, dateRange DateRange) string {
+	from := dateRange.From.Truncate(cacheKeyResolution).Unix()
+	to := dateRange.To.Truncate(cacheKeyResolution).Unix()
+	return fmt.Sprintf("%s|%d|%d", siteID, from, to)
+}
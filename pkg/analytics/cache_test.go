@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedStore_GetAnalyticsDashboard(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	cache := NewCachedStore(db, time.Minute, true)
+	dateRange := DateRange{
+		From: time.Now().AddDate(0, 0, -10),
+		To:   time.Now().AddDate(0, 0, 1),
+	}
+
+	first, err := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get analytics dashboard: %v", err)
+	}
+
+	// New data is not visible until the entry expires or is invalidated
+	_, err = db.Exec(`INSERT INTO comments (id, site_id, page_id, author, author_id, text, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		"comment-new", "test-site-1", "test-page-1", "Test User", "test-user-1", "New", "pending", time.Now())
+	if err != nil {
+		t.Fatalf("Failed to insert comment: %v", err)
+	}
+
+	second, err := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get analytics dashboard: %v", err)
+	}
+	if second != first {
+		t.Error("Expected cached dashboard to be reused")
+	}
+
+	cache.InvalidateSite("test-site-1")
+
+	third, err := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get analytics dashboard: %v", err)
+	}
+	if third.Comments.Total != first.Comments.Total+1 {
+		t.Errorf("Expected %d comments after invalidation, got %d", first.Comments.Total+1, third.Comments.Total)
+	}
+}
+
+func TestCachedStore_Expiry(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	cache := NewCachedStore(db, 10*time.Millisecond, true)
+	dateRange := GetDefaultDateRange()
+
+	first, err := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get analytics dashboard: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	second, err := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get analytics dashboard: %v", err)
+	}
+	if second == first {
+		t.Error("Expected expired entry to be recomputed")
+	}
+}
+
+func TestCachedStore_Disabled(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	cache := NewCachedStore(db, time.Minute, false)
+	dateRange := GetDefaultDateRange()
+
+	first, _ := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	second, _ := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
+	if first == nil || first == second {
+		t.Error("Expected a disabled cache to query the store on every call")
+	}
+}
+
+func TestCachedStore_ConcurrentRequests(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	cache := NewCachedStore(db, time.Minute, true)
+	dateRange := GetDefaultDateRange()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Concurrent dashboard request failed: %v", err)
+	}
+
+	cache.mu.Lock()
+	entries := len(cache.entries)
+	cache.mu.Unlock()
+	if entries != 1 {
+		t.Errorf("Expected a single cache entry, got %d", entries)
+	}
+}
+
+func TestCachedStore_InvalidateSiteNil(t *testing.T) {
+	var cache *CachedStore
+	cache.InvalidateSite("test-site-1") // must not panic
+}
+
+func TestNewCachedStoreFromEnv(t *testing.T) {
+	tests := []struct {
+		value   string
+		ttl     time.Duration
+		enabled bool
+	}{
+		{"", DefaultCacheTTL, true},
+		{"30s", 30 * time.Second, true},
+		{"0", DefaultCacheTTL, false},
+		{"soon", DefaultCacheTTL, true},
+		{"-1m", DefaultCacheTTL, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("ANALYTICS_CACHE_TTL", tt.value)
+		cache := NewCachedStoreFromEnv(nil)
+		if cache.ttl != tt.ttl || cache.enabled != tt.enabled {
+			t.Errorf("ANALYTICS_CACHE_TTL=%q: got ttl %v enabled %v, want %v %v", tt.value, cache.ttl, cache.enabled, tt.ttl, tt.enabled)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToCSV writes the dashboard as CSV. Each metric group is a section: a title
+// row, a header row, the data rows and a blank separator row. Dates are
+// RFC 3339; trend labels that aren't a single date (weekly buckets) are kept
+// as they are.
+func (d *AnalyticsDashboard) ToCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	var rows [][]string
+	section := func(title string, header []string, data ...[]string) {
+		rows = append(rows, []string{title}, header)
+		rows = append(rows, data...)
+		rows = append(rows, []string{})
+	}
+	itoa := strconv.Itoa
+	percent := func(v float64) string { return fmt.Sprintf("%.2f%%", v) }
+
+	section("Analytics Report", []string{"Field", "Value"},
+		[]string{"Site ID", csvText(d.SiteID)},
+		[]string{"From", d.DateFrom.Format(time.RFC3339)},
+		[]string{"To", d.DateTo.Format(time.RFC3339)},
+	)
+
+	section("Comment Metrics", []string{"Metric", "Value"},
+		[]string{"Total Comments", itoa(d.Comments.Total)},
+		[]string{"Pending", itoa(d.Comments.Pending)},
+		[]string{"Approved", itoa(d.Comments.Approved)},
+		[]string{"Rejected", itoa(d.Comments.Rejected)},
+		[]string{"Approval Rate", percent(d.Comments.ApprovalRate)},
+		[]string{"Rejection Rate", percent(d.Comments.RejectionRate)},
+		[]string{"Today", itoa(d.Comments.TotalToday)},
+		[]string{"This Week", itoa(d.Comments.TotalThisWeek)},
+		[]string{"This Month", itoa(d.Comments.TotalThisMonth)},
+	)
+
+	section("User Metrics", []string{"Metric", "Value"},
+		[]string{"Total Users", itoa(d.Users.TotalUsers)},
+		[]string{"Active Today", itoa(d.Users.ActiveUsersToday)},
+		[]string{"Active This Week", itoa(d.Users.ActiveUsersWeek)},
+		[]string{"Active This Month", itoa(d.Users.ActiveUsersMonth)},
+	)
+
+	var contributors [][]string
+	for _, c := range d.Users.TopContributors {
+		contributors = append(contributors, []string{csvText(c.Name), csvText(c.Email), itoa(c.CommentCount)})
+	}
+	section("Top Contributors", []string{"Name", "Email", "Comments"}, contributors...)
+
+	section("Reaction Metrics", []string{"Metric", "Value"},
+		[]string{"Total Reactions", itoa(d.Reactions.Total)},
+		[]string{"Today", itoa(d.Reactions.TotalToday)},
+		[]string{"This Week", itoa(d.Reactions.TotalThisWeek)},
+		[]string{"This Month", itoa(d.Reactions.TotalThisMonth)},
+	)
+
+	var byType [][]string
+	for _, r := range d.Reactions.ByType {
+		byType = append(byType, []string{csvText(r.Name), csvText(r.Emoji), itoa(r.Count)})
+	}
+	section("Reactions by Type", []string{"Name", "Emoji", "Count"}, byType...)
+
+	var buckets [][]string
+	for _, b := range d.ReactionDistribution.Buckets {
+		buckets = append(buckets, []string{b.Label, itoa(b.Comments)})
+	}
+	section("Reactions per Comment", []string{"Reactions", "Comments"}, buckets...)
+
+	var pages [][]string
+	for _, p := range d.TopPages {
+		pages = append(pages, []string{csvText(p.Path), csvText(p.Title), itoa(p.CommentCount),
+			itoa(p.ReactionCount), itoa(p.UniqueCommenters)})
+	}
+	section("Top Pages", []string{"Path", "Title", "Comments", "Reactions", "Unique Commenters"}, pages...)
+
+	section("Moderation Metrics", []string{"Metric", "Value"},
+		[]string{"Total Moderated", itoa(d.Moderation.TotalModerated)},
+		[]string{"Auto Rejected", itoa(d.Moderation.AutoRejected)},
+		[]string{"Auto Approved", itoa(d.Moderation.AutoApproved)},
+		[]string{"Manual Reviews", itoa(d.Moderation.ManualReviews)},
+		[]string{"Avg Moderation Time (sec)", fmt.Sprintf("%.2f", d.Moderation.AverageModerationSec)},
+		[]string{"Spam Detection Rate", percent(d.Moderation.SpamDetectionRate)},
+	)
+
+	section("Comments Trend", []string{"Date", "Count"}, d.trendRows(d.CommentsTrend)...)
+	section("Reactions Trend", []string{"Date", "Count"}, d.trendRows(d.ReactionsTrend)...)
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write analytics CSV: %w", err)
+	}
+	return nil
+}
+
+// trendRows turns a time series into date/count rows
+func (d *AnalyticsDashboard) trendRows(trend TimeSeriesData) [][]string {
+	var rows [][]string
+	for i, label := range trend.Labels {
+		if i >= len(trend.Values) {
+			break
+		}
+		rows = append(rows, []string{d.trendDate(label), strconv.Itoa(trend.Values[i])})
+	}
+	return rows
+}
+
+// trendDate converts a daily or hourly trend label to RFC 3339 in the
+// dashboard's time zone. Other labels are returned unchanged.
+func (d *AnalyticsDashboard) trendDate(label string) string {
+	location := d.DateFrom.Location()
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:00"} {
+		if t, err := time.ParseInLocation(layout, label, location); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return label
+}
+
+// csvText guards user-supplied text against being run as a formula when the
+// CSV is opened in a spreadsheet. Quoting of commas, quotes and newlines is
+// left to encoding/csv.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
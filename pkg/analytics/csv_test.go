@@ -0,0 +1,127 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// csvSections splits CSV records into sections keyed by their title row
+func csvSections(t *testing.T, data []byte) map[string][][]string {
+	t.Helper()
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	// Blank separator rows are skipped by the reader; title rows are the only
+	// single-field records
+	sections := map[string][][]string{}
+	title := ""
+	for _, record := range records {
+		if len(record) == 1 {
+			title = record[0]
+			sections[title] = nil
+			continue
+		}
+		sections[title] = append(sections[title], record)
+	}
+	return sections
+}
+
+func TestAnalyticsDashboard_ToCSV(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	now := time.Now()
+	dashboard, err := NewStore(db).GetAnalyticsDashboard(context.Background(), "test-site-1", DateRange{
+		From: now.AddDate(0, 0, -10),
+		To:   now.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		t.Fatalf("Failed to get dashboard: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := dashboard.ToCSV(&buf); err != nil {
+		t.Fatalf("ToCSV failed: %v", err)
+	}
+	sections := csvSections(t, buf.Bytes())
+
+	headers := map[string][]string{
+		"Analytics Report":   {"Field", "Value"},
+		"Comment Metrics":    {"Metric", "Value"},
+		"User Metrics":       {"Metric", "Value"},
+		"Reactions by Type":  {"Name", "Emoji", "Count"},
+		"Moderation Metrics": {"Metric", "Value"},
+		"Comments Trend":     {"Date", "Count"},
+		"Reactions Trend":    {"Date", "Count"},
+	}
+	for title, header := range headers {
+		rows, ok := sections[title]
+		if !ok || len(rows) == 0 {
+			t.Errorf("Missing section %q", title)
+			continue
+		}
+		if got := rows[0]; len(got) != len(header) || got[0] != header[0] || got[len(got)-1] != header[len(header)-1] {
+			t.Errorf("Section %q header = %v, want %v", title, got, header)
+		}
+	}
+
+	report := sections["Analytics Report"]
+	if len(report) < 3 || report[2][1] != dashboard.DateFrom.Format(time.RFC3339) {
+		t.Errorf("Expected RFC 3339 from date, got %v", report)
+	}
+
+	// Every trend row matches the dashboard's series, with RFC 3339 dates
+	trend := sections["Comments Trend"][1:]
+	if len(trend) != len(dashboard.CommentsTrend.Labels) {
+		t.Fatalf("Expected %d trend rows, got %d", len(dashboard.CommentsTrend.Labels), len(trend))
+	}
+	total := 0
+	for i, row := range trend {
+		if _, err := time.Parse(time.RFC3339, row[0]); err != nil {
+			t.Errorf("Trend date %q is not RFC 3339: %v", row[0], err)
+		}
+		if want := strconv.Itoa(dashboard.CommentsTrend.Values[i]); row[1] != want {
+			t.Errorf("Trend row %d = %s, want %s", i, row[1], want)
+		}
+		n, _ := strconv.Atoi(row[1])
+		total += n
+	}
+	if total != dashboard.Comments.Total {
+		t.Errorf("Expected trend to add up to %d comments, got %d", dashboard.Comments.Total, total)
+	}
+}
+
+func TestAnalyticsDashboard_ToCSV_EscapesFields(t *testing.T) {
+	dashboard := &AnalyticsDashboard{
+		SiteID: "site-1",
+		Users: UserMetrics{TopContributors: []TopContributor{
+			{Name: `Smith, "Jo"`, Email: "jo@example.com", CommentCount: 2},
+			{Name: "=HYPERLINK(\"http://evil\")", CommentCount: 1},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := dashboard.ToCSV(&buf); err != nil {
+		t.Fatalf("ToCSV failed: %v", err)
+	}
+	rows := csvSections(t, buf.Bytes())["Top Contributors"]
+	if len(rows) != 3 {
+		t.Fatalf("Expected header and 2 contributors, got %v", rows)
+	}
+	if rows[1][0] != `Smith, "Jo"` {
+		t.Errorf("Expected quoted name to round-trip, got %q", rows[1][0])
+	}
+	if rows[2][0] != "'=HYPERLINK(\"http://evil\")" {
+		t.Errorf("Expected formula to be neutralized, got %q", rows[2][0])
+	}
+}
@@ -0,0 +1,127 @@
+package analytics
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/db"
+)
+
+// Dialect renders the database-specific SQL fragments used by analytics queries
+type Dialect interface {
+	// Name returns the dialect identifier ("sqlite" or "postgres")
+	Name() string
+	// DateBucket returns an expression formatting column as YYYY-MM-DD
+	DateBucket(column string) string
+	// HourBucket returns an expression formatting column as "YYYY-MM-DD HH"
+	HourBucket(column string) string
+	// WeekBucket returns an expression formatting column as "YYYY-Wnn" using
+	// ISO 8601 weeks, the same label as isoWeekLabel
+	WeekBucket(column string) string
+	// SecondsBetween returns an expression for the seconds elapsed from start to end
+	SecondsBetween(start, end string) string
+	// Rebind rewrites ? placeholders into the dialect's bind variable syntax
+	Rebind(query string) string
+}
+
+// SQLiteDialect is the dialect of the default SQLite database
+type SQLiteDialect struct{}
+
+// Name returns the dialect identifier
+func (SQLiteDialect) Name() string { return "sqlite" }
+
+// DateBucket returns an expression formatting column as YYYY-MM-DD
+func (SQLiteDialect) DateBucket(column string) string {
+	return fmt.Sprintf("DATE(%s)", column)
+}
+
+// HourBucket returns an expression formatting column as "YYYY-MM-DD HH"
+func (SQLiteDialect) HourBucket(column string) string {
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H', %s)", column)
+}
+
+// WeekBucket returns an expression formatting column as "YYYY-Wnn" using ISO weeks
+func (SQLiteDialect) WeekBucket(column string) string {
+	return fmt.Sprintf("strftime('%%G-W%%V', %s)", column)
+}
+
+// SecondsBetween returns an expression for the seconds elapsed from start to end
+func (SQLiteDialect) SecondsBetween(start, end string) string {
+	return fmt.Sprintf("(julianday(%s) - julianday(%s)) * 86400", end, start)
+}
+
+// Rebind returns the query unchanged; SQLite accepts ? placeholders
+func (SQLiteDialect) Rebind(query string) string { return query }
+
+// PostgresDialect is the dialect of PostgreSQL databases
+type PostgresDialect struct{}
+
+// Name returns the dialect identifier
+func (PostgresDialect) Name() string { return "postgres" }
+
+// DateBucket returns an expression formatting column as YYYY-MM-DD
+func (PostgresDialect) DateBucket(column string) string {
+	return fmt.Sprintf("to_char(date_trunc('day', %s), 'YYYY-MM-DD')", column)
+}
+
+// HourBucket returns an expression formatting column as "YYYY-MM-DD HH"
+func (PostgresDialect) HourBucket(column string) string {
+	return fmt.Sprintf("to_char(date_trunc('hour', %s), 'YYYY-MM-DD HH24')", column)
+}
+
+// WeekBucket returns an expression formatting column as "YYYY-Wnn" using ISO weeks
+func (PostgresDialect) WeekBucket(column string) string {
+	return fmt.Sprintf(`to_char(%s, 'IYYY-"W"IW')`, column)
+}
+
+// SecondsBetween returns an expression for the seconds elapsed from start to end
+func (PostgresDialect) SecondsBetween(start, end string) string {
+	return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s))", end, start)
+}
+
+// Rebind rewrites ? placeholders into $1, $2, ...
+func (PostgresDialect) Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isoWeekLabel formats t's ISO 8601 week as "YYYY-Wnn", the label every
+// dialect's WeekBucket produces
+func isoWeekLabel(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// DialectForDriver returns the dialect for a database/sql driver name
+func DialectForDriver(driverName string) (Dialect, error) {
+	switch driverName {
+	case "sqlite3", "sqlite":
+		return SQLiteDialect{}, nil
+	case "postgres", "pgx":
+		return PostgresDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported analytics driver: %s", driverName)
+	}
+}
+
+// DetectDialect infers the dialect from the driver behind sqlDB, defaulting to SQLite
+func DetectDialect(sqlDB *sql.DB) Dialect {
+	if db.IsPostgres(sqlDB) {
+		return PostgresDialect{}
+	}
+	return SQLiteDialect{}
+}
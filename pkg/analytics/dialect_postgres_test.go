@@ -0,0 +1,35 @@
+//go:build postgres
+
+package analytics
+
+import (
+	"database/sql"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestPostgresDialect_WeekBucket runs against the database in
+// KOTOMI_TEST_POSTGRES_URL. The binary must link a Postgres driver registered as
+// KOTOMI_TEST_POSTGRES_DRIVER ("postgres" by default).
+func TestPostgresDialect_WeekBucket(t *testing.T) {
+	url := os.Getenv("KOTOMI_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("KOTOMI_TEST_POSTGRES_URL not set")
+	}
+	driver := os.Getenv("KOTOMI_TEST_POSTGRES_DRIVER")
+	if driver == "" {
+		driver = "postgres"
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		t.Skipf("no %q database driver registered", driver)
+	}
+
+	db, err := sql.Open(driver, url)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	checkWeekBucket(t, db, PostgresDialect{}, "CAST(? AS TIMESTAMP)")
+}
@@ -0,0 +1,152 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDialectForDriver(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"sqlite3", "sqlite"},
+		{"postgres", "postgres"},
+		{"pgx", "postgres"},
+	}
+
+	for _, tt := range tests {
+		dialect, err := DialectForDriver(tt.driver)
+		if err != nil {
+			t.Fatalf("DialectForDriver(%q) failed: %v", tt.driver, err)
+		}
+		if dialect.Name() != tt.want {
+			t.Errorf("DialectForDriver(%q) = %s, want %s", tt.driver, dialect.Name(), tt.want)
+		}
+	}
+
+	if _, err := DialectForDriver("mysql"); err == nil {
+		t.Error("Expected error for unsupported driver")
+	}
+}
+
+func TestDialect_Fragments(t *testing.T) {
+	sqlite := SQLiteDialect{}
+	if got := sqlite.SecondsBetween("created_at", "moderated_at"); got != "(julianday(moderated_at) - julianday(created_at)) * 86400" {
+		t.Errorf("Unexpected SQLite SecondsBetween: %s", got)
+	}
+	if got := sqlite.DateBucket("created_at"); got != "DATE(created_at)" {
+		t.Errorf("Unexpected SQLite DateBucket: %s", got)
+	}
+	if got := sqlite.WeekBucket("created_at"); got != "strftime('%G-W%V', created_at)" {
+		t.Errorf("Unexpected SQLite WeekBucket: %s", got)
+	}
+
+	postgres := PostgresDialect{}
+	if got := postgres.SecondsBetween("created_at", "moderated_at"); got != "EXTRACT(EPOCH FROM (moderated_at - created_at))" {
+		t.Errorf("Unexpected Postgres SecondsBetween: %s", got)
+	}
+	if got := postgres.WeekBucket("created_at"); got != `to_char(created_at, 'IYYY-"W"IW')` {
+		t.Errorf("Unexpected Postgres WeekBucket: %s", got)
+	}
+	if got := postgres.HourBucket("r.created_at"); got != "to_char(date_trunc('hour', r.created_at), 'YYYY-MM-DD HH24')" {
+		t.Errorf("Unexpected Postgres HourBucket: %s", got)
+	}
+
+	query := "SELECT 1 FROM comments WHERE site_id = ? AND created_at BETWEEN ? AND ?"
+	if got := sqlite.Rebind(query); got != query {
+		t.Errorf("Expected SQLite Rebind to be a no-op, got %s", got)
+	}
+	if got := postgres.Rebind(query); got != "SELECT 1 FROM comments WHERE site_id = $1 AND created_at BETWEEN $2 AND $3" {
+		t.Errorf("Unexpected Postgres Rebind: %s", got)
+	}
+}
+
+// weekBucketDates straddle year boundaries, where ISO weeks and calendar years differ
+var weekBucketDates = map[string]string{
+	"2021-01-01": "2020-W53",
+	"2023-01-01": "2022-W52",
+	"2023-01-02": "2023-W01",
+	"2024-06-15": "2024-W24",
+	"2024-12-30": "2025-W01",
+}
+
+// checkWeekBucket runs dialect's WeekBucket on db for weekBucketDates, binding
+// each date as a "YYYY-MM-DD HH:MM:SS" string through the timestamp expression
+func checkWeekBucket(t *testing.T, db *sql.DB, dialect Dialect, timestamp string) {
+	t.Helper()
+	for day, want := range weekBucketDates {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			t.Fatalf("Invalid date %s: %v", day, err)
+		}
+		date = date.Add(12 * time.Hour)
+		if got := isoWeekLabel(date); got != want {
+			t.Errorf("isoWeekLabel(%s) = %s, want %s", day, got, want)
+		}
+
+		var got string
+		query := dialect.Rebind(fmt.Sprintf("SELECT %s", dialect.WeekBucket(timestamp)))
+		if err := db.QueryRow(query, date.Format("2006-01-02 15:04:05")).Scan(&got); err != nil {
+			t.Fatalf("%s WeekBucket failed: %v", dialect.Name(), err)
+		}
+		if got != want {
+			t.Errorf("%s WeekBucket(%s) = %s, want %s", dialect.Name(), day, got, want)
+		}
+	}
+}
+
+// TestSQLiteDialect_WeekBucket checks SQLite labels weeks like Postgres' ISO
+// weeks, which isoWeekLabel and the daily aggregates also use
+func TestSQLiteDialect_WeekBucket(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	checkWeekBucket(t, db, SQLiteDialect{}, "?")
+}
+
+// TestGetModerationMetrics_SecondsBetween checks the dialect-driven moderation time
+// against durations computed in Go, which is the value both dialects must agree on
+func TestGetModerationMetrics_SecondsBetween(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", "site-1", "owner-1", "Site")
+	if err != nil {
+		t.Fatalf("Failed to insert site: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO pages (id, site_id, path) VALUES (?, ?, ?)", "page-1", "site-1", "/")
+	if err != nil {
+		t.Fatalf("Failed to insert page: %v", err)
+	}
+
+	created := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	delays := []time.Duration{30 * time.Second, 90 * time.Second}
+	for i, delay := range delays {
+		moderated := created.Add(delay)
+		_, err := db.Exec(`INSERT INTO comments (id, site_id, page_id, author, author_id, text, status, created_at, moderated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			string(rune('a'+i)), "site-1", "page-1", "A", "u", "t", "approved", created, moderated)
+		if err != nil {
+			t.Fatalf("Failed to insert comment: %v", err)
+		}
+	}
+
+	store := NewStoreWithDialect(db, SQLiteDialect{})
+	metrics, err := store.GetModerationMetrics(context.Background(), "site-1", DateRange{From: created.Add(-time.Minute), To: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to get moderation metrics: %v", err)
+	}
+
+	want := (delays[0] + delays[1]).Seconds() / 2
+	if math.Abs(metrics.AverageModerationSec-want) > 0.01 {
+		t.Errorf("Expected average moderation time %.2f, got %.2f", want, metrics.AverageModerationSec)
+	}
+	if metrics.ManualReviews != 2 {
+		t.Errorf("Expected 2 manual reviews, got %d", metrics.ManualReviews)
+	}
+}
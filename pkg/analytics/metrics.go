@@ -58,6 +58,37 @@ type MostReactedItem struct {
 	ReactionCount int    `json:"reaction_count"`
 }
 
+// ReactionDistribution shows how reactions are spread across a site's comments
+type ReactionDistribution struct {
+	Buckets     []ReactionBucket    `json:"buckets"`
+	MostReacted *MostReactedComment `json:"most_reacted,omitempty"` // nil when no comment has reactions
+}
+
+// ReactionBucket counts the comments whose reaction count falls in [Min, Max]
+type ReactionBucket struct {
+	Label    string `json:"label"`
+	Min      int    `json:"min"`
+	Max      int    `json:"max"` // -1 for no upper bound
+	Comments int    `json:"comments"`
+}
+
+// MostReactedComment is the single comment with the most reactions
+type MostReactedComment struct {
+	CommentID     string `json:"comment_id"`
+	Excerpt       string `json:"excerpt"`
+	ReactionCount int    `json:"reaction_count"`
+}
+
+// PageMetric represents engagement statistics for a single page
+type PageMetric struct {
+	PageID           string `json:"page_id"`
+	Path             string `json:"path"`
+	Title            string `json:"title"`
+	CommentCount     int    `json:"comment_count"`
+	ReactionCount    int    `json:"reaction_count"`
+	UniqueCommenters int    `json:"unique_commenters"`
+}
+
 // ModerationMetrics represents moderation-related statistics
 type ModerationMetrics struct {
 	TotalModerated       int     `json:"total_moderated"`
@@ -76,15 +107,17 @@ type TimeSeriesData struct {
 
 // AnalyticsDashboard represents complete analytics data for a site
 type AnalyticsDashboard struct {
-	SiteID            string            `json:"site_id"`
-	DateFrom          time.Time         `json:"date_from"`
-	DateTo            time.Time         `json:"date_to"`
-	Comments          CommentMetrics    `json:"comments"`
-	Users             UserMetrics       `json:"users"`
-	Reactions         ReactionMetrics   `json:"reactions"`
-	Moderation        ModerationMetrics `json:"moderation"`
-	CommentsTrend     TimeSeriesData    `json:"comments_trend"`
-	ReactionsTrend    TimeSeriesData    `json:"reactions_trend"`
+	SiteID               string               `json:"site_id"`
+	DateFrom             time.Time            `json:"date_from"`
+	DateTo               time.Time            `json:"date_to"`
+	Comments             CommentMetrics       `json:"comments"`
+	Users                UserMetrics          `json:"users"`
+	Reactions            ReactionMetrics      `json:"reactions"`
+	ReactionDistribution ReactionDistribution `json:"reaction_distribution"`
+	Moderation           ModerationMetrics    `json:"moderation"`
+	CommentsTrend        TimeSeriesData       `json:"comments_trend"`
+	ReactionsTrend       TimeSeriesData       `json:"reactions_trend"`
+	TopPages             []PageMetric         `json:"top_pages"`
 }
 
 // DateRange represents a date range for filtering
@@ -102,7 +135,8 @@ func GetDefaultDateRange() DateRange {
 	}
 }
 
-// ParseDateRange parses from and to strings into a DateRange
+// ParseDateRange parses from and to strings into a DateRange. Each is an
+// RFC 3339 time or a YYYY-MM-DD date; a date-only "to" covers the whole day.
 func ParseDateRange(from, to string) (DateRange, error) {
 	defaultRange := GetDefaultDateRange()
 	
@@ -110,7 +144,10 @@ func ParseDateRange(from, to string) (DateRange, error) {
 	var err error
 	
 	if from != "" {
-		dateFrom, err = time.Parse("2006-01-02", from)
+		dateFrom, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			dateFrom, err = time.Parse("2006-01-02", from)
+		}
 		if err != nil {
 			return defaultRange, err
 		}
@@ -119,12 +156,14 @@ func ParseDateRange(from, to string) (DateRange, error) {
 	}
 	
 	if to != "" {
-		dateTo, err = time.Parse("2006-01-02", to)
-		if err != nil {
-			return defaultRange, err
+		if dateTo, err = time.Parse(time.RFC3339, to); err != nil {
+			dateTo, err = time.Parse("2006-01-02", to)
+			if err != nil {
+				return defaultRange, err
+			}
+			// Set to end of day
+			dateTo = dateTo.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
 		}
-		// Set to end of day
-		dateTo = dateTo.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
 	} else {
 		dateTo = defaultRange.To
 	}
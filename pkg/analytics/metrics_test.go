@@ -1,11 +1,19 @@
 package analytics
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
 	"testing"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
 )
 
 func setupTestDB(t *testing.T) (*sql.DB, func()) {
@@ -18,68 +26,7 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	// Create test schema
-	schema := `
-	CREATE TABLE IF NOT EXISTS sites (
-		id TEXT PRIMARY KEY,
-		owner_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS pages (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		path TEXT NOT NULL,
-		title TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS users (
-		id TEXT NOT NULL,
-		site_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		email TEXT,
-		first_seen TIMESTAMP NOT NULL,
-		last_seen TIMESTAMP NOT NULL,
-		PRIMARY KEY (site_id, id)
-	);
-
-	CREATE TABLE IF NOT EXISTS comments (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		page_id TEXT NOT NULL,
-		author TEXT NOT NULL,
-		author_id TEXT NOT NULL,
-		author_email TEXT,
-		text TEXT NOT NULL,
-		status TEXT DEFAULT 'pending',
-		moderated_by TEXT,
-		moderated_at TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS allowed_reactions (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		emoji TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS reactions (
-		id TEXT PRIMARY KEY,
-		page_id TEXT,
-		comment_id TEXT,
-		allowed_reaction_id TEXT NOT NULL,
-		user_id TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-
-	_, err = db.Exec(schema)
-	if err != nil {
+	if err := comments.ApplySchema(db); err != nil {
 		t.Fatalf("Failed to create test schema: %v", err)
 	}
 
@@ -150,20 +97,21 @@ func insertTestData(t *testing.T, db *sql.DB) {
 		t.Fatalf("Failed to insert allowed reaction: %v", err)
 	}
 
-	// Insert reactions
+	// Insert reactions; a user can react with each reaction only once per comment
 	reactions := []struct {
 		id        string
 		commentID string
+		userID    string
 		createdAt time.Time
 	}{
-		{"react-1", "comment-1", now.AddDate(0, 0, -4)},
-		{"react-2", "comment-1", now.AddDate(0, 0, -3)},
-		{"react-3", "comment-4", now.AddDate(0, 0, -1)},
+		{"react-1", "comment-1", userID, now.AddDate(0, 0, -4)},
+		{"react-2", "comment-1", "test-user-2", now.AddDate(0, 0, -3)},
+		{"react-3", "comment-4", userID, now.AddDate(0, 0, -1)},
 	}
 
 	for _, r := range reactions {
 		_, err = db.Exec("INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES (?, ?, ?, ?, ?)",
-			r.id, r.commentID, reactionID, userID, r.createdAt)
+			r.id, r.commentID, reactionID, r.userID, r.createdAt)
 		if err != nil {
 			t.Fatalf("Failed to insert reaction: %v", err)
 		}
@@ -202,6 +150,7 @@ func TestParseDateRange(t *testing.T) {
 		{"Invalid to", "2024-01-01", "invalid", true},
 		{"Only from", "2024-01-01", "", false},
 		{"Only to", "", "2024-01-31", false},
+		{"RFC 3339 range", "2024-01-01T00:00:00Z", "2024-01-31T12:00:00Z", false},
 	}
 	
 	for _, tt := range tests {
@@ -235,7 +184,7 @@ func TestGetCommentMetrics(t *testing.T) {
 		To:   time.Now().AddDate(0, 0, 1),
 	}
 	
-	metrics, err := store.GetCommentMetrics("test-site-1", dateRange)
+	metrics, err := store.GetCommentMetrics(context.Background(), "test-site-1", dateRange)
 	if err != nil {
 		t.Fatalf("Failed to get comment metrics: %v", err)
 	}
@@ -274,7 +223,7 @@ func TestGetUserMetrics(t *testing.T) {
 		To:   time.Now().AddDate(0, 0, 1),
 	}
 	
-	metrics, err := store.GetUserMetrics("test-site-1", dateRange)
+	metrics, err := store.GetUserMetrics(context.Background(), "test-site-1", dateRange)
 	if err != nil {
 		t.Fatalf("Failed to get user metrics: %v", err)
 	}
@@ -309,7 +258,7 @@ func TestGetReactionMetrics(t *testing.T) {
 		To:   time.Now().AddDate(0, 0, 1),
 	}
 	
-	metrics, err := store.GetReactionMetrics("test-site-1", dateRange)
+	metrics, err := store.GetReactionMetrics(context.Background(), "test-site-1", dateRange)
 	if err != nil {
 		t.Fatalf("Failed to get reaction metrics: %v", err)
 	}
@@ -344,7 +293,7 @@ func TestGetModerationMetrics(t *testing.T) {
 		To:   time.Now().AddDate(0, 0, 1),
 	}
 	
-	metrics, err := store.GetModerationMetrics("test-site-1", dateRange)
+	metrics, err := store.GetModerationMetrics(context.Background(), "test-site-1", dateRange)
 	if err != nil {
 		t.Fatalf("Failed to get moderation metrics: %v", err)
 	}
@@ -372,7 +321,7 @@ func TestGetCommentsTrend(t *testing.T) {
 		To:   time.Now().AddDate(0, 0, 1),
 	}
 	
-	trend, err := store.GetCommentsTrend("test-site-1", dateRange)
+	trend, err := store.GetCommentsTrend(context.Background(), "test-site-1", dateRange)
 	if err != nil {
 		t.Fatalf("Failed to get comments trend: %v", err)
 	}
@@ -403,7 +352,7 @@ func TestGetAnalyticsDashboard(t *testing.T) {
 		To:   time.Now().AddDate(0, 0, 1),
 	}
 	
-	dashboard, err := store.GetAnalyticsDashboard("test-site-1", dateRange)
+	dashboard, err := store.GetAnalyticsDashboard(context.Background(), "test-site-1", dateRange)
 	if err != nil {
 		t.Fatalf("Failed to get analytics dashboard: %v", err)
 	}
@@ -428,3 +377,318 @@ func TestGetAnalyticsDashboard(t *testing.T) {
 		t.Error("Expected non-empty comments trend")
 	}
 }
+
+func TestGetPageMetrics(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	// A page without comments that only received page reactions
+	now := time.Now()
+	_, err := db.Exec("INSERT INTO pages (id, site_id, path, title) VALUES (?, ?, ?, ?)",
+		"test-page-2", "test-site-1", "/reacted", "Reacted Page")
+	if err != nil {
+		t.Fatalf("Failed to insert test page: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO pages (id, site_id, path, title) VALUES (?, ?, ?, ?)",
+		"test-page-3", "test-site-1", "/quiet", "Quiet Page")
+	if err != nil {
+		t.Fatalf("Failed to insert test page: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO reactions (id, page_id, allowed_reaction_id, user_id, created_at) VALUES (?, ?, ?, ?, ?)",
+		"react-page-1", "test-page-2", "reaction-1", "test-user-1", now)
+	if err != nil {
+		t.Fatalf("Failed to insert page reaction: %v", err)
+	}
+
+	store := NewStore(db)
+	dateRange := DateRange{
+		From: now.AddDate(0, 0, -10),
+		To:   now.AddDate(0, 0, 1),
+	}
+
+	metrics, err := store.GetPageMetrics(context.Background(), "test-site-1", dateRange, 10)
+	if err != nil {
+		t.Fatalf("Failed to get page metrics: %v", err)
+	}
+
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 pages with engagement, got %d", len(metrics))
+	}
+
+	top := metrics[0]
+	if top.Path != "/test" || top.CommentCount != 5 || top.ReactionCount != 3 || top.UniqueCommenters != 1 {
+		t.Errorf("Unexpected top page metric: %+v", top)
+	}
+
+	reacted := metrics[1]
+	if reacted.Path != "/reacted" || reacted.CommentCount != 0 || reacted.ReactionCount != 1 {
+		t.Errorf("Expected reaction-only page to be included, got %+v", reacted)
+	}
+
+	limited, err := store.GetPageMetrics(context.Background(), "test-site-1", dateRange, 1)
+	if err != nil {
+		t.Fatalf("Failed to get page metrics: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("Expected limit to be applied, got %d pages", len(limited))
+	}
+}
+
+func TestGetCommentsTrend_Hourly(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	store := NewStore(db)
+	from := time.Now().UTC().Truncate(time.Hour).Add(-35 * time.Hour)
+	dateRange := DateRange{
+		From: from,
+		To:   from.Add(36 * time.Hour),
+	}
+
+	trend, err := store.GetCommentsTrend(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get comments trend: %v", err)
+	}
+
+	if len(trend.Labels) != 36 || len(trend.Values) != 36 {
+		t.Fatalf("Expected 36 hourly buckets, got %d labels and %d values", len(trend.Labels), len(trend.Values))
+	}
+
+	if trend.Labels[0] != from.Format("2006-01-02 15:00") {
+		t.Errorf("Expected first label %q, got %q", from.Format("2006-01-02 15:00"), trend.Labels[0])
+	}
+
+	// comment-4 (one day ago) and comment-5 (now) fall inside the range
+	total := 0
+	for _, v := range trend.Values {
+		total += v
+	}
+	if total != 2 {
+		t.Errorf("Expected 2 comments across hourly buckets, got %d", total)
+	}
+}
+
+func TestGetModerationMetrics_ModerationEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", "site-1", "owner-1", "Site")
+	if err != nil {
+		t.Fatalf("Failed to insert site: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO pages (id, site_id, path) VALUES (?, ?, ?)", "page-1", "site-1", "/")
+	if err != nil {
+		t.Fatalf("Failed to insert page: %v", err)
+	}
+
+	created := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	insertComment := func(id, status string, moderatedAt time.Time) {
+		_, err := db.Exec(`INSERT INTO comments (id, site_id, page_id, author, author_id, text, status, created_at, moderated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, "site-1", "page-1", "A", "u", "t", status, created, moderatedAt)
+		if err != nil {
+			t.Fatalf("Failed to insert comment: %v", err)
+		}
+	}
+	insertEvent := func(id, commentID, decision, source string, at time.Time) {
+		_, err := db.Exec(`INSERT INTO moderation_events (id, comment_id, site_id, decision, source, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`, id, commentID, "site-1", decision, source, at)
+		if err != nil {
+			t.Fatalf("Failed to insert moderation event: %v", err)
+		}
+	}
+
+	// Auto-approved by AI, then rejected manually ten minutes later. The heuristic
+	// alone would count this as an automated rejection.
+	insertComment("c-audited", "rejected", created.Add(10*time.Minute))
+	insertEvent("e-1", "c-audited", "approved", "ai", created)
+	insertEvent("e-2", "c-audited", "rejected", "manual", created.Add(10*time.Minute))
+
+	// Flagged by the blocklist and still awaiting review; not moderated yet
+	insertComment("c-flagged", "pending", time.Time{})
+	insertEvent("e-3", "c-flagged", "pending", "blocklist", created)
+	if _, err := db.Exec("UPDATE comments SET moderated_at = NULL WHERE id = ?", "c-flagged"); err != nil {
+		t.Fatalf("Failed to clear moderated_at: %v", err)
+	}
+
+	// Legacy comment without events, reviewed after two minutes
+	insertComment("c-legacy", "approved", created.Add(2*time.Minute))
+
+	store := NewStore(db)
+	metrics, err := store.GetModerationMetrics(context.Background(), "site-1", DateRange{From: created.Add(-time.Minute), To: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to get moderation metrics: %v", err)
+	}
+
+	if metrics.TotalModerated != 2 {
+		t.Errorf("Expected 2 moderated comments, got %d", metrics.TotalModerated)
+	}
+	if metrics.AutoApproved != 0 {
+		t.Errorf("Expected 0 auto-approved comments, got %d", metrics.AutoApproved)
+	}
+	if metrics.AutoRejected != 0 {
+		t.Errorf("Expected 0 auto-rejected comments, got %d", metrics.AutoRejected)
+	}
+	if metrics.ManualReviews != 2 {
+		t.Errorf("Expected 2 manual reviews, got %d", metrics.ManualReviews)
+	}
+
+	want := (10*time.Minute + 2*time.Minute).Seconds() / 2
+	if math.Abs(metrics.AverageModerationSec-want) > 0.01 {
+		t.Errorf("Expected average moderation time %.2f, got %.2f", want, metrics.AverageModerationSec)
+	}
+}
+
+func TestGetModerationMetrics_ManualOverride(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	created := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	// Each comment's events, oldest first
+	history := map[string][]struct{ decision, source string }{
+		// Overridden by a moderator: manual reviews only
+		"c-unrejected": {{"rejected", "ai"}, {"approved", "manual"}},
+		"c-unapproved": {{"approved", "links"}, {"rejected", "manual"}},
+		// A later flag that isn't a decision keeps the automated rejection
+		"c-auto": {{"rejected", "ai"}, {"pending", "reports"}},
+	}
+	for commentID, events := range history {
+		_, err := db.Exec(`INSERT INTO comments (id, site_id, page_id, author, author_id, text, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, commentID, "site-1", "page-1", "A", "u", "t", "pending", created)
+		if err != nil {
+			t.Fatalf("Failed to insert comment: %v", err)
+		}
+		for i, event := range events {
+			_, err := db.Exec(`INSERT INTO moderation_events (id, comment_id, site_id, decision, source, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)`, fmt.Sprintf("%s-%d", commentID, i), commentID, "site-1",
+				event.decision, event.source, created.Add(time.Duration(i)*time.Minute))
+			if err != nil {
+				t.Fatalf("Failed to insert moderation event: %v", err)
+			}
+		}
+	}
+
+	metrics, err := NewStore(db).GetModerationMetrics(context.Background(), "site-1", DateRange{From: created.Add(-time.Minute), To: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to get moderation metrics: %v", err)
+	}
+
+	if metrics.TotalModerated != 3 {
+		t.Errorf("Expected 3 moderated comments, got %d", metrics.TotalModerated)
+	}
+	if metrics.ManualReviews != 2 {
+		t.Errorf("Expected 2 manual reviews, got %d", metrics.ManualReviews)
+	}
+	if metrics.AutoApproved != 0 {
+		t.Errorf("Expected 0 auto-approved comments, got %d", metrics.AutoApproved)
+	}
+	if metrics.AutoRejected != 1 {
+		t.Errorf("Expected 1 auto-rejected comment, got %d", metrics.AutoRejected)
+	}
+	if got := metrics.ManualReviews + metrics.AutoApproved + metrics.AutoRejected; got != metrics.TotalModerated {
+		t.Errorf("Expected each moderated comment counted once, got %d of %d", got, metrics.TotalModerated)
+	}
+}
+
+func TestGetReactionDistribution(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+
+	// Push comment-5 into the 6-20 bucket and comment-4 into the 21+ bucket;
+	// comment-1 keeps its 2 reactions and the rest have none
+	now := time.Now()
+	counts := map[string]int{"comment-5": 8, "comment-4": 24}
+	for commentID, n := range counts {
+		for i := 0; i < n; i++ {
+			_, err := db.Exec("INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES (?, ?, ?, ?, ?)",
+				fmt.Sprintf("%s-react-%d", commentID, i), commentID, "reaction-1", fmt.Sprintf("user-%d", i), now)
+			if err != nil {
+				t.Fatalf("Failed to insert reaction: %v", err)
+			}
+		}
+	}
+
+	store := NewStore(db)
+	dateRange := DateRange{
+		From: now.AddDate(0, 0, -10),
+		To:   now.AddDate(0, 0, 1),
+	}
+
+	distribution, err := store.GetReactionDistribution(context.Background(), "test-site-1", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get reaction distribution: %v", err)
+	}
+
+	// comment-4 already had one reaction from the seed data
+	want := map[string]int{"0": 2, "1-5": 1, "6-20": 1, "21+": 1}
+	if len(distribution.Buckets) != len(want) {
+		t.Fatalf("Expected %d buckets, got %+v", len(want), distribution.Buckets)
+	}
+	for _, bucket := range distribution.Buckets {
+		if bucket.Comments != want[bucket.Label] {
+			t.Errorf("Expected %d comments in bucket %s, got %d", want[bucket.Label], bucket.Label, bucket.Comments)
+		}
+	}
+
+	top := distribution.MostReacted
+	if top == nil {
+		t.Fatal("Expected a most reacted comment")
+	}
+	if top.CommentID != "comment-4" || top.ReactionCount != 25 || top.Excerpt != "Test comment 4" {
+		t.Errorf("Unexpected most reacted comment: %+v", top)
+	}
+
+	// A site without comments has empty buckets and no most reacted comment
+	empty, err := store.GetReactionDistribution(context.Background(), "other-site", dateRange)
+	if err != nil {
+		t.Fatalf("Failed to get reaction distribution: %v", err)
+	}
+	if empty.MostReacted != nil {
+		t.Errorf("Expected no most reacted comment, got %+v", empty.MostReacted)
+	}
+	for _, bucket := range empty.Buckets {
+		if bucket.Comments != 0 {
+			t.Errorf("Expected empty bucket %s, got %d", bucket.Label, bucket.Comments)
+		}
+	}
+}
+
+func TestStore_WarningsCarryRequestID(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	insertTestData(t, db)
+	// Total users still load, but every comment query after it fails
+	if _, err := db.Exec("DROP TABLE comments"); err != nil {
+		t.Fatalf("Failed to drop comments: %v", err)
+	}
+
+	var buf bytes.Buffer
+	store := NewStore(db)
+	store.SetLogger(slog.New(logging.NewContextHandler(slog.NewJSONHandler(&buf, nil))))
+
+	ctx := logging.WithRequestID(context.Background(), "req-analytics")
+	if _, err := store.GetUserMetrics(ctx, "test-site-1", GetDefaultDateRange()); err == nil {
+		t.Fatal("Expected missing comments table to fail the top contributors query")
+	}
+
+	var entry map[string]interface{}
+	if err := json.NewDecoder(&buf).Decode(&entry); err != nil {
+		t.Fatalf("Expected a warning to be logged: %v", err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "Failed to get active users today" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+	if entry["request_id"] != "req-analytics" {
+		t.Errorf("Expected request ID in the log entry, got %v", entry["request_id"])
+	}
+	if entry["error"] == nil {
+		t.Error("Expected the query error in the log entry")
+	}
+}
@@ -1,46 +1,95 @@
 package analytics
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
 
+// mostReactedExcerptLength is how many characters of a most reacted comment are shown
+const mostReactedExcerptLength = 50
+
 // Store provides database operations for analytics
 type Store struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect Dialect
+	logger  *slog.Logger
 }
 
-// NewStore creates a new analytics store
+// NewStore creates a new analytics store, detecting the SQL dialect from the driver
 func NewStore(db *sql.DB) *Store {
-	return &Store{db: db}
+	return NewStoreWithDialect(db, DetectDialect(db))
+}
+
+// NewStoreWithDialect creates a new analytics store using an explicit SQL dialect
+func NewStoreWithDialect(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, dialect: dialect}
+}
+
+// SetLogger sets the logger non-fatal query failures are reported to. Without
+// one the default slog logger is used.
+func (s *Store) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// warn logs a failed query that doesn't fail the whole request. Logging with
+// ctx lets a context-aware handler attach the request ID, so a slow or broken
+// dashboard load can be traced back to its request.
+func (s *Store) warn(ctx context.Context, msg string, err error) {
+	logger := s.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.WarnContext(ctx, msg, "error", err)
+}
+
+// query runs a query after rebinding its placeholders for the store's dialect
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.dialect.Rebind(query), args...)
+}
+
+// queryRow runs a single-row query after rebinding its placeholders for the store's dialect
+func (s *Store) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.dialect.Rebind(query), args...)
 }
 
 // GetCommentMetrics retrieves comment statistics for a site
-func (s *Store) GetCommentMetrics(siteID string, dateRange DateRange) (CommentMetrics, error) {
+func (s *Store) GetCommentMetrics(ctx context.Context, siteID string, dateRange DateRange) (CommentMetrics, error) {
 	var metrics CommentMetrics
 	
-	// Get total counts by status
-	query := `
-		SELECT 
-			COUNT(*) as total,
-			SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
-			SUM(CASE WHEN status = 'approved' THEN 1 ELSE 0 END) as approved,
-			SUM(CASE WHEN status = 'rejected' THEN 1 ELSE 0 END) as rejected
-		FROM comments
-		WHERE site_id = ? AND created_at BETWEEN ? AND ?
-	`
-	
-	err := s.db.QueryRow(query, siteID, dateRange.From, dateRange.To).Scan(
-		&metrics.Total,
-		&metrics.Pending,
-		&metrics.Approved,
-		&metrics.Rejected,
-	)
-	if err != nil {
-		return metrics, fmt.Errorf("failed to get comment counts: %w", err)
+	// Get total counts by status, from the daily aggregates when they cover the range
+	if days, ok := s.aggregatedCommentCounts(ctx, siteID, dateRange); ok {
+		for _, counts := range days {
+			metrics.Total += counts.total
+			metrics.Pending += counts.pending
+			metrics.Approved += counts.approved
+			metrics.Rejected += counts.rejected
+		}
+	} else {
+		query := `
+			SELECT 
+				COUNT(*) as total,
+				SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
+				SUM(CASE WHEN status = 'approved' THEN 1 ELSE 0 END) as approved,
+				SUM(CASE WHEN status = 'rejected' THEN 1 ELSE 0 END) as rejected
+			FROM comments
+			WHERE site_id = ? AND created_at BETWEEN ? AND ?
+		`
+		
+		err := s.queryRow(ctx, query, siteID, dateRange.From, dateRange.To).Scan(
+			&metrics.Total,
+			&metrics.Pending,
+			&metrics.Approved,
+			&metrics.Rejected,
+		)
+		if err != nil {
+			return metrics, fmt.Errorf("failed to get comment counts: %w", err)
+		}
 	}
 	
 	// Calculate rates
@@ -51,44 +100,44 @@ func (s *Store) GetCommentMetrics(siteID string, dateRange DateRange) (CommentMe
 	
 	// Get today's count
 	today := time.Now().Truncate(24 * time.Hour)
-	err = s.db.QueryRow(`
+	err := s.queryRow(ctx, `
 		SELECT COUNT(*) FROM comments 
 		WHERE site_id = ? AND created_at >= ?
 	`, siteID, today).Scan(&metrics.TotalToday)
 	if err != nil {
-		log.Printf("Failed to get today's comment count: %v", err)
+		s.warn(ctx, "Failed to get today's comment count", err)
 	}
 	
 	// Get this week's count
 	weekStart := time.Now().AddDate(0, 0, -int(time.Now().Weekday()))
 	weekStart = weekStart.Truncate(24 * time.Hour)
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(*) FROM comments 
 		WHERE site_id = ? AND created_at >= ?
 	`, siteID, weekStart).Scan(&metrics.TotalThisWeek)
 	if err != nil {
-		log.Printf("Failed to get this week's comment count: %v", err)
+		s.warn(ctx, "Failed to get this week's comment count", err)
 	}
 	
 	// Get this month's count
 	monthStart := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Now().Location())
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(*) FROM comments 
 		WHERE site_id = ? AND created_at >= ?
 	`, siteID, monthStart).Scan(&metrics.TotalThisMonth)
 	if err != nil {
-		log.Printf("Failed to get this month's comment count: %v", err)
+		s.warn(ctx, "Failed to get this month's comment count", err)
 	}
 	
 	return metrics, nil
 }
 
 // GetUserMetrics retrieves user statistics for a site
-func (s *Store) GetUserMetrics(siteID string, dateRange DateRange) (UserMetrics, error) {
+func (s *Store) GetUserMetrics(ctx context.Context, siteID string, dateRange DateRange) (UserMetrics, error) {
 	var metrics UserMetrics
 	
 	// Get total unique users
-	err := s.db.QueryRow(`
+	err := s.queryRow(ctx, `
 		SELECT COUNT(DISTINCT id) FROM users WHERE site_id = ?
 	`, siteID).Scan(&metrics.TotalUsers)
 	if err != nil {
@@ -97,33 +146,33 @@ func (s *Store) GetUserMetrics(siteID string, dateRange DateRange) (UserMetrics,
 	
 	// Get active users today
 	today := time.Now().Truncate(24 * time.Hour)
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(DISTINCT author_id) FROM comments 
 		WHERE site_id = ? AND created_at >= ?
 	`, siteID, today).Scan(&metrics.ActiveUsersToday)
 	if err != nil {
-		log.Printf("Failed to get active users today: %v", err)
+		s.warn(ctx, "Failed to get active users today", err)
 	}
 	
 	// Get active users this week
 	weekStart := time.Now().AddDate(0, 0, -int(time.Now().Weekday()))
 	weekStart = weekStart.Truncate(24 * time.Hour)
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(DISTINCT author_id) FROM comments 
 		WHERE site_id = ? AND created_at >= ?
 	`, siteID, weekStart).Scan(&metrics.ActiveUsersWeek)
 	if err != nil {
-		log.Printf("Failed to get active users this week: %v", err)
+		s.warn(ctx, "Failed to get active users this week", err)
 	}
 	
 	// Get active users this month
 	monthStart := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Now().Location())
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(DISTINCT author_id) FROM comments 
 		WHERE site_id = ? AND created_at >= ?
 	`, siteID, monthStart).Scan(&metrics.ActiveUsersMonth)
 	if err != nil {
-		log.Printf("Failed to get active users this month: %v", err)
+		s.warn(ctx, "Failed to get active users this month", err)
 	}
 	
 	// Get top contributors
@@ -136,7 +185,7 @@ func (s *Store) GetUserMetrics(siteID string, dateRange DateRange) (UserMetrics,
 		LIMIT 10
 	`
 	
-	rows, err := s.db.Query(query, siteID, dateRange.From, dateRange.To)
+	rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To)
 	if err != nil {
 		return metrics, fmt.Errorf("failed to get top contributors: %w", err)
 	}
@@ -147,7 +196,7 @@ func (s *Store) GetUserMetrics(siteID string, dateRange DateRange) (UserMetrics,
 		var contributor TopContributor
 		var email sql.NullString
 		if err := rows.Scan(&contributor.Name, &email, &contributor.CommentCount); err != nil {
-			log.Printf("Failed to scan top contributor: %v", err)
+			s.warn(ctx, "Failed to scan top contributor", err)
 			continue
 		}
 		if email.Valid {
@@ -160,77 +209,91 @@ func (s *Store) GetUserMetrics(siteID string, dateRange DateRange) (UserMetrics,
 }
 
 // GetReactionMetrics retrieves reaction statistics for a site
-func (s *Store) GetReactionMetrics(siteID string, dateRange DateRange) (ReactionMetrics, error) {
+func (s *Store) GetReactionMetrics(ctx context.Context, siteID string, dateRange DateRange) (ReactionMetrics, error) {
 	var metrics ReactionMetrics
 	
-	// Get total reactions
-	err := s.db.QueryRow(`
-		SELECT COUNT(*) FROM reactions r
-		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
-		WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
-	`, siteID, dateRange.From, dateRange.To).Scan(&metrics.Total)
-	if err != nil {
-		return metrics, fmt.Errorf("failed to get total reactions: %w", err)
+	// Get total reactions, from the daily aggregates when they cover the range
+	aggregated, useAggregates := s.aggregatedReactionCounts(ctx, siteID, dateRange)
+	if useAggregates {
+		for _, count := range aggregated {
+			metrics.Total += count.total
+		}
+	} else {
+		err := s.queryRow(ctx, `
+			SELECT COUNT(*) FROM reactions r
+			INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+			WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
+		`, siteID, dateRange.From, dateRange.To).Scan(&metrics.Total)
+		if err != nil {
+			return metrics, fmt.Errorf("failed to get total reactions: %w", err)
+		}
 	}
 	
 	// Get today's count
 	today := time.Now().Truncate(24 * time.Hour)
-	err = s.db.QueryRow(`
+	err := s.queryRow(ctx, `
 		SELECT COUNT(*) FROM reactions r
 		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 		WHERE ar.site_id = ? AND r.created_at >= ?
 	`, siteID, today).Scan(&metrics.TotalToday)
 	if err != nil {
-		log.Printf("Failed to get today's reaction count: %v", err)
+		s.warn(ctx, "Failed to get today's reaction count", err)
 	}
 	
 	// Get this week's count
 	weekStart := time.Now().AddDate(0, 0, -int(time.Now().Weekday()))
 	weekStart = weekStart.Truncate(24 * time.Hour)
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(*) FROM reactions r
 		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 		WHERE ar.site_id = ? AND r.created_at >= ?
 	`, siteID, weekStart).Scan(&metrics.TotalThisWeek)
 	if err != nil {
-		log.Printf("Failed to get this week's reaction count: %v", err)
+		s.warn(ctx, "Failed to get this week's reaction count", err)
 	}
 	
 	// Get this month's count
 	monthStart := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Now().Location())
-	err = s.db.QueryRow(`
+	err = s.queryRow(ctx, `
 		SELECT COUNT(*) FROM reactions r
 		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 		WHERE ar.site_id = ? AND r.created_at >= ?
 	`, siteID, monthStart).Scan(&metrics.TotalThisMonth)
 	if err != nil {
-		log.Printf("Failed to get this month's reaction count: %v", err)
+		s.warn(ctx, "Failed to get this month's reaction count", err)
 	}
 	
 	// Get reactions by type
-	query := `
-		SELECT ar.name, ar.emoji, COUNT(*) as count
-		FROM reactions r
-		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
-		WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
-		GROUP BY ar.id, ar.name, ar.emoji
-		ORDER BY count DESC
-	`
-	
-	rows, err := s.db.Query(query, siteID, dateRange.From, dateRange.To)
-	if err != nil {
-		return metrics, fmt.Errorf("failed to get reaction breakdown: %w", err)
-	}
-	defer rows.Close()
-	
-	metrics.ByType = []ReactionBreakdown{}
-	for rows.Next() {
-		var breakdown ReactionBreakdown
-		if err := rows.Scan(&breakdown.Name, &breakdown.Emoji, &breakdown.Count); err != nil {
-			log.Printf("Failed to scan reaction breakdown: %v", err)
-			continue
+	if useAggregates {
+		metrics.ByType, err = s.reactionBreakdown(ctx, siteID, aggregated)
+		if err != nil {
+			return metrics, fmt.Errorf("failed to get reaction breakdown: %w", err)
+		}
+	} else {
+		query := `
+			SELECT ar.name, ar.emoji, COUNT(*) as count
+			FROM reactions r
+			INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+			WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
+			GROUP BY ar.id, ar.name, ar.emoji
+			ORDER BY count DESC
+		`
+		
+		rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To)
+		if err != nil {
+			return metrics, fmt.Errorf("failed to get reaction breakdown: %w", err)
+		}
+		defer rows.Close()
+		
+		metrics.ByType = []ReactionBreakdown{}
+		for rows.Next() {
+			var breakdown ReactionBreakdown
+			if err := rows.Scan(&breakdown.Name, &breakdown.Emoji, &breakdown.Count); err != nil {
+				s.warn(ctx, "Failed to scan reaction breakdown", err)
+				continue
+			}
+			metrics.ByType = append(metrics.ByType, breakdown)
 		}
-		metrics.ByType = append(metrics.ByType, breakdown)
 	}
 	
 	// Get most reacted items (pages and comments combined)
@@ -247,13 +310,13 @@ func (s *Store) GetReactionMetrics(siteID string, dateRange DateRange) (Reaction
 		LIMIT 5
 	`
 	
-	pageRows, err := s.db.Query(pageQuery, siteID, dateRange.From, dateRange.To)
+	pageRows, err := s.query(ctx, pageQuery, siteID, dateRange.From, dateRange.To)
 	if err == nil {
 		defer pageRows.Close()
 		for pageRows.Next() {
 			var item MostReactedItem
 			if err := pageRows.Scan(&item.Type, &item.PagePath, &item.ReactionCount); err != nil {
-				log.Printf("Failed to scan most reacted page: %v", err)
+				s.warn(ctx, "Failed to scan most reacted page", err)
 				continue
 			}
 			metrics.MostReacted = append(metrics.MostReacted, item)
@@ -262,7 +325,7 @@ func (s *Store) GetReactionMetrics(siteID string, dateRange DateRange) (Reaction
 	
 	// Most reacted comments
 	commentQuery := `
-		SELECT 'comment' as type, SUBSTR(c.text, 1, 50) as comment_text, COUNT(*) as reaction_count
+		SELECT 'comment' as type, c.text as comment_text, COUNT(*) as reaction_count
 		FROM reactions r
 		INNER JOIN comments c ON r.comment_id = c.id
 		WHERE c.site_id = ? AND r.created_at BETWEEN ? AND ?
@@ -271,19 +334,16 @@ func (s *Store) GetReactionMetrics(siteID string, dateRange DateRange) (Reaction
 		LIMIT 5
 	`
 	
-	commentRows, err := s.db.Query(commentQuery, siteID, dateRange.From, dateRange.To)
+	commentRows, err := s.query(ctx, commentQuery, siteID, dateRange.From, dateRange.To)
 	if err == nil {
 		defer commentRows.Close()
 		for commentRows.Next() {
 			var item MostReactedItem
 			if err := commentRows.Scan(&item.Type, &item.CommentText, &item.ReactionCount); err != nil {
-				log.Printf("Failed to scan most reacted comment: %v", err)
+				s.warn(ctx, "Failed to scan most reacted comment", err)
 				continue
 			}
-			// Add ellipsis if text was truncated
-			if len(item.CommentText) == 50 {
-				item.CommentText += "..."
-			}
+			item.CommentText = comments.Excerpt(item.CommentText, mostReactedExcerptLength)
 			metrics.MostReacted = append(metrics.MostReacted, item)
 		}
 	}
@@ -291,93 +351,295 @@ func (s *Store) GetReactionMetrics(siteID string, dateRange DateRange) (Reaction
 	return metrics, nil
 }
 
-// GetModerationMetrics retrieves moderation statistics for a site
-func (s *Store) GetModerationMetrics(siteID string, dateRange DateRange) (ModerationMetrics, error) {
+// reactionBuckets are the reaction count ranges GetReactionDistribution groups
+// comments into, in order
+var reactionBuckets = []ReactionBucket{
+	{Label: "0", Min: 0, Max: 0},
+	{Label: "1-5", Min: 1, Max: 5},
+	{Label: "6-20", Min: 6, Max: 20},
+	{Label: "21+", Min: 21, Max: -1},
+}
+
+// GetReactionDistribution buckets the comments posted in the date range by how
+// many reactions each has received, and finds the most reacted of them
+func (s *Store) GetReactionDistribution(ctx context.Context, siteID string, dateRange DateRange) (ReactionDistribution, error) {
+	distribution := ReactionDistribution{Buckets: make([]ReactionBucket, len(reactionBuckets))}
+	copy(distribution.Buckets, reactionBuckets)
+
+	// Label each comment with the index of its bucket, then count per index
+	var bucketCase strings.Builder
+	bucketCase.WriteString("CASE")
+	for i, b := range reactionBuckets {
+		if b.Max < 0 {
+			fmt.Fprintf(&bucketCase, " ELSE %d", i)
+			break
+		}
+		fmt.Fprintf(&bucketCase, " WHEN reaction_count <= %d THEN %d", b.Max, i)
+	}
+	bucketCase.WriteString(" END")
+
+	rows, err := s.query(ctx, `
+		SELECT `+bucketCase.String()+` as bucket, COUNT(*)
+		FROM (
+			SELECT c.id, COUNT(r.id) as reaction_count
+			FROM comments c
+			LEFT JOIN reactions r ON r.comment_id = c.id
+			WHERE c.site_id = ? AND c.created_at BETWEEN ? AND ?
+			GROUP BY c.id
+		) per_comment
+		GROUP BY bucket
+	`, siteID, dateRange.From, dateRange.To)
+	if err != nil {
+		return distribution, fmt.Errorf("failed to get reaction distribution: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return distribution, fmt.Errorf("failed to scan reaction bucket: %w", err)
+		}
+		if bucket >= 0 && bucket < len(distribution.Buckets) {
+			distribution.Buckets[bucket].Comments = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return distribution, fmt.Errorf("error iterating reaction buckets: %w", err)
+	}
+
+	var top MostReactedComment
+	err = s.queryRow(ctx, `
+		SELECT c.id, c.text, COUNT(*) as reaction_count
+		FROM comments c
+		INNER JOIN reactions r ON r.comment_id = c.id
+		WHERE c.site_id = ? AND c.created_at BETWEEN ? AND ?
+		GROUP BY c.id, c.text, c.created_at
+		ORDER BY reaction_count DESC, c.created_at ASC
+		LIMIT 1
+	`, siteID, dateRange.From, dateRange.To).Scan(&top.CommentID, &top.Excerpt, &top.ReactionCount)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return distribution, fmt.Errorf("failed to get most reacted comment: %w", err)
+	default:
+		top.Excerpt = comments.Excerpt(top.Excerpt, mostReactedExcerptLength)
+		distribution.MostReacted = &top
+	}
+
+	return distribution, nil
+}
+
+// GetModerationMetrics retrieves moderation statistics for a site. Comments with
+// entries in moderation_events are counted from that audit log; older comments
+// without events fall back to inferring automated decisions from how quickly
+// they were moderated.
+func (s *Store) GetModerationMetrics(ctx context.Context, siteID string, dateRange DateRange) (ModerationMetrics, error) {
 	var metrics ModerationMetrics
 	
-	// Get total moderated comments
-	err := s.db.QueryRow(`
-		SELECT COUNT(*) FROM comments
-		WHERE site_id = ? AND moderated_at IS NOT NULL AND created_at BETWEEN ? AND ?
-	`, siteID, dateRange.From, dateRange.To).Scan(&metrics.TotalModerated)
+	legacy, err := s.getLegacyModerationCounts(ctx, siteID, dateRange)
 	if err != nil {
-		return metrics, fmt.Errorf("failed to get total moderated: %w", err)
+		return metrics, err
 	}
 	
-	// Get auto-rejected and auto-approved counts (moderated within 1 second indicates automated)
-	query := `
+	audited, err := s.getAuditedModerationCounts(ctx, siteID, dateRange)
+	if err != nil {
+		return metrics, err
+	}
+	
+	metrics.TotalModerated = legacy.moderated + audited.moderated
+	metrics.AutoRejected = legacy.autoRejected + audited.autoRejected
+	metrics.AutoApproved = legacy.autoApproved + audited.autoApproved
+	metrics.ManualReviews = legacy.manualReviews + audited.manualReviews
+	if metrics.TotalModerated > 0 {
+		metrics.AverageModerationSec = (legacy.decisionSeconds + audited.decisionSeconds) / float64(metrics.TotalModerated)
+	}
+	
+	// Calculate spam detection rate (rejected / total moderated)
+	if metrics.TotalModerated > 0 {
+		totalRejected := 0
+		s.queryRow(ctx, `
+			SELECT COUNT(*) FROM comments
+			WHERE site_id = ? AND status = 'rejected' AND created_at BETWEEN ? AND ?
+		`, siteID, dateRange.From, dateRange.To).Scan(&totalRejected)
+		metrics.SpamDetectionRate = float64(totalRejected) / float64(metrics.TotalModerated) * 100
+	}
+	
+	return metrics, nil
+}
+
+// moderationCounts are the per-source building blocks of ModerationMetrics
+type moderationCounts struct {
+	moderated       int
+	autoRejected    int
+	autoApproved    int
+	manualReviews   int
+	decisionSeconds float64 // summed over moderated comments
+}
+
+// getLegacyModerationCounts counts comments that have no moderation events,
+// treating decisions made within a second of posting as automated
+func (s *Store) getLegacyModerationCounts(ctx context.Context, siteID string, dateRange DateRange) (moderationCounts, error) {
+	var counts moderationCounts
+	
+	moderationSeconds := s.dialect.SecondsBetween("created_at", "moderated_at")
+	query := fmt.Sprintf(`
 		SELECT 
+			SUM(CASE WHEN moderated_at IS NOT NULL THEN 1 ELSE 0 END) as moderated,
 			SUM(CASE WHEN status = 'rejected' AND 
-				(julianday(moderated_at) - julianday(created_at)) * 86400 < 1 THEN 1 ELSE 0 END) as auto_rejected,
+				%[1]s < 1 THEN 1 ELSE 0 END) as auto_rejected,
 			SUM(CASE WHEN status = 'approved' AND 
-				(julianday(moderated_at) - julianday(created_at)) * 86400 < 1 THEN 1 ELSE 0 END) as auto_approved,
+				%[1]s < 1 THEN 1 ELSE 0 END) as auto_approved,
 			SUM(CASE WHEN moderated_at IS NOT NULL AND 
-				(julianday(moderated_at) - julianday(created_at)) * 86400 >= 1 THEN 1 ELSE 0 END) as manual_reviews
+				%[1]s >= 1 THEN 1 ELSE 0 END) as manual_reviews,
+			SUM(CASE WHEN moderated_at IS NOT NULL THEN %[1]s END) as decision_seconds
 		FROM comments
 		WHERE site_id = ? AND created_at BETWEEN ? AND ?
-	`
+			AND NOT EXISTS (SELECT 1 FROM moderation_events e WHERE e.comment_id = comments.id)
+	`, moderationSeconds)
 	
-	var autoRejected, autoApproved, manualReviews sql.NullInt64
-	err = s.db.QueryRow(query, siteID, dateRange.From, dateRange.To).Scan(&autoRejected, &autoApproved, &manualReviews)
+	var moderated, autoRejected, autoApproved, manualReviews sql.NullInt64
+	var decisionSeconds sql.NullFloat64
+	err := s.queryRow(ctx, query, siteID, dateRange.From, dateRange.To).Scan(
+		&moderated, &autoRejected, &autoApproved, &manualReviews, &decisionSeconds)
 	if err != nil {
-		return metrics, fmt.Errorf("failed to get moderation breakdown: %w", err)
+		return counts, fmt.Errorf("failed to get moderation breakdown: %w", err)
 	}
 	
-	if autoRejected.Valid {
-		metrics.AutoRejected = int(autoRejected.Int64)
+	counts.moderated = int(moderated.Int64)
+	counts.autoRejected = int(autoRejected.Int64)
+	counts.autoApproved = int(autoApproved.Int64)
+	counts.manualReviews = int(manualReviews.Int64)
+	counts.decisionSeconds = decisionSeconds.Float64
+	
+	return counts, nil
+}
+
+// getAuditedModerationCounts counts comments from their moderation events. A
+// comment is moderated once it has an approve or reject event and is counted
+// once, by its latest such event, so a manual override of an automated decision
+// is a manual review only. Its decision time runs until that event.
+func (s *Store) getAuditedModerationCounts(ctx context.Context, siteID string, dateRange DateRange) (moderationCounts, error) {
+	var counts moderationCounts
+	
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*),
+			SUM(CASE WHEN e.source <> 'manual' AND e.decision = 'rejected' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN e.source <> 'manual' AND e.decision = 'approved' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN e.source = 'manual' THEN 1 ELSE 0 END),
+			SUM(%s)
+		FROM comments c
+		JOIN moderation_events e ON e.id = (
+			SELECT me.id FROM moderation_events me
+			WHERE me.comment_id = c.id AND me.decision IN ('approved', 'rejected')
+			ORDER BY me.created_at DESC, me.id DESC
+			LIMIT 1
+		)
+		WHERE c.site_id = ? AND c.created_at BETWEEN ? AND ?
+	`, s.dialect.SecondsBetween("c.created_at", "e.created_at"))
+	
+	var moderated, autoRejected, autoApproved, manualReviews sql.NullInt64
+	var decisionSeconds sql.NullFloat64
+	err := s.queryRow(ctx, query, siteID, dateRange.From, dateRange.To).Scan(
+		&moderated, &autoRejected, &autoApproved, &manualReviews, &decisionSeconds)
+	if err != nil {
+		return counts, fmt.Errorf("failed to get moderation events breakdown: %w", err)
 	}
-	if autoApproved.Valid {
-		metrics.AutoApproved = int(autoApproved.Int64)
+	
+	counts.moderated = int(moderated.Int64)
+	counts.autoRejected = int(autoRejected.Int64)
+	counts.autoApproved = int(autoApproved.Int64)
+	counts.manualReviews = int(manualReviews.Int64)
+	counts.decisionSeconds = decisionSeconds.Float64
+	
+	return counts, nil
+}
+
+// defaultPageMetricsLimit is the number of pages returned when no limit is given
+const defaultPageMetricsLimit = 10
+
+// GetPageMetrics retrieves per-page engagement for a site, ordered by comments plus
+// reactions descending. Reactions count both those on the page itself and those on
+// its comments; pages without comments still appear when they have reactions.
+func (s *Store) GetPageMetrics(ctx context.Context, siteID string, dateRange DateRange, limit int) ([]PageMetric, error) {
+	if limit <= 0 {
+		limit = defaultPageMetricsLimit
 	}
-	if manualReviews.Valid {
-		metrics.ManualReviews = int(manualReviews.Int64)
+
+	query := `
+		SELECT p.id, p.path, COALESCE(p.title, ''),
+			COALESCE(c.comment_count, 0), COALESCE(r.reaction_count, 0), COALESCE(c.unique_commenters, 0)
+		FROM pages p
+		LEFT JOIN (
+			SELECT page_id, COUNT(*) as comment_count, COUNT(DISTINCT author_id) as unique_commenters
+			FROM comments
+			WHERE site_id = ? AND created_at BETWEEN ? AND ?
+			GROUP BY page_id
+		) c ON c.page_id = p.id
+		LEFT JOIN (
+			SELECT COALESCE(r.page_id, rc.page_id) as page_id, COUNT(*) as reaction_count
+			FROM reactions r
+			LEFT JOIN comments rc ON r.comment_id = rc.id
+			WHERE r.created_at BETWEEN ? AND ?
+			GROUP BY COALESCE(r.page_id, rc.page_id)
+		) r ON r.page_id = p.id
+		WHERE p.site_id = ? AND (c.comment_count > 0 OR r.reaction_count > 0)
+		ORDER BY COALESCE(c.comment_count, 0) + COALESCE(r.reaction_count, 0) DESC, p.path ASC
+		LIMIT ?
+	`
+
+	rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To,
+		dateRange.From, dateRange.To, siteID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page metrics: %w", err)
 	}
-	
-	// Calculate average moderation time
-	var avgSeconds sql.NullFloat64
-	err = s.db.QueryRow(`
-		SELECT AVG((julianday(moderated_at) - julianday(created_at)) * 86400)
-		FROM comments
-		WHERE site_id = ? AND moderated_at IS NOT NULL AND created_at BETWEEN ? AND ?
-	`, siteID, dateRange.From, dateRange.To).Scan(&avgSeconds)
-	if err == nil && avgSeconds.Valid {
-		metrics.AverageModerationSec = avgSeconds.Float64
+	defer rows.Close()
+
+	metrics := []PageMetric{}
+	for rows.Next() {
+		var metric PageMetric
+		if err := rows.Scan(&metric.PageID, &metric.Path, &metric.Title,
+			&metric.CommentCount, &metric.ReactionCount, &metric.UniqueCommenters); err != nil {
+			return nil, fmt.Errorf("failed to scan page metric: %w", err)
+		}
+		metrics = append(metrics, metric)
 	}
-	
-	// Calculate spam detection rate (rejected / total moderated)
-	if metrics.TotalModerated > 0 {
-		totalRejected := 0
-		s.db.QueryRow(`
-			SELECT COUNT(*) FROM comments
-			WHERE site_id = ? AND status = 'rejected' AND created_at BETWEEN ? AND ?
-		`, siteID, dateRange.From, dateRange.To).Scan(&totalRejected)
-		metrics.SpamDetectionRate = float64(totalRejected) / float64(metrics.TotalModerated) * 100
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating page metrics: %w", err)
 	}
-	
+
 	return metrics, nil
 }
 
 // GetCommentsTrend retrieves time series data for comments
-func (s *Store) GetCommentsTrend(siteID string, dateRange DateRange) (TimeSeriesData, error) {
+func (s *Store) GetCommentsTrend(ctx context.Context, siteID string, dateRange DateRange) (TimeSeriesData, error) {
 	var trend TimeSeriesData
 	
 	// Generate daily buckets
 	daysDiff := int(dateRange.To.Sub(dateRange.From).Hours() / 24)
 	if daysDiff > 90 {
 		// For more than 90 days, group by week
-		return s.getWeeklyTrend(siteID, dateRange, "comments")
+		return s.getWeeklyTrend(ctx, siteID, dateRange, "comments")
+	}
+	if dateRange.To.Sub(dateRange.From) < hourlyTrendThreshold {
+		// For short ranges daily buckets carry no signal, group by hour
+		return s.getHourlyTrend(ctx, siteID, dateRange, "comments")
+	}
+	if dateMap, ok := s.aggregatedDailyTotals(ctx, siteID, dateRange, "comments"); ok {
+		return dailyTrend(dateRange, dateMap), nil
 	}
 	
 	// Daily trend
-	query := `
-		SELECT DATE(created_at) as date, COUNT(*) as count
+	query := fmt.Sprintf(`
+		SELECT %[1]s as date, COUNT(*) as count
 		FROM comments
 		WHERE site_id = ? AND created_at BETWEEN ? AND ?
-		GROUP BY DATE(created_at)
+		GROUP BY %[1]s
 		ORDER BY date ASC
-	`
+	`, s.dialect.DateBucket("created_at"))
 	
-	rows, err := s.db.Query(query, siteID, dateRange.From, dateRange.To)
+	rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To)
 	if err != nil {
 		return trend, fmt.Errorf("failed to get comments trend: %w", err)
 	}
@@ -393,40 +655,38 @@ func (s *Store) GetCommentsTrend(siteID string, dateRange DateRange) (TimeSeries
 		dateMap[date] = count
 	}
 	
-	// Fill in all dates in range
-	trend.Labels = []string{}
-	trend.Values = []int{}
-	for d := dateRange.From; !d.After(dateRange.To); d = d.AddDate(0, 0, 1) {
-		dateStr := d.Format("2006-01-02")
-		trend.Labels = append(trend.Labels, dateStr)
-		trend.Values = append(trend.Values, dateMap[dateStr])
-	}
-	
-	return trend, nil
+	return dailyTrend(dateRange, dateMap), nil
 }
 
 // GetReactionsTrend retrieves time series data for reactions
-func (s *Store) GetReactionsTrend(siteID string, dateRange DateRange) (TimeSeriesData, error) {
+func (s *Store) GetReactionsTrend(ctx context.Context, siteID string, dateRange DateRange) (TimeSeriesData, error) {
 	var trend TimeSeriesData
 	
 	// Generate daily buckets
 	daysDiff := int(dateRange.To.Sub(dateRange.From).Hours() / 24)
 	if daysDiff > 90 {
 		// For more than 90 days, group by week
-		return s.getWeeklyTrend(siteID, dateRange, "reactions")
+		return s.getWeeklyTrend(ctx, siteID, dateRange, "reactions")
+	}
+	if dateRange.To.Sub(dateRange.From) < hourlyTrendThreshold {
+		// For short ranges daily buckets carry no signal, group by hour
+		return s.getHourlyTrend(ctx, siteID, dateRange, "reactions")
+	}
+	if dateMap, ok := s.aggregatedDailyTotals(ctx, siteID, dateRange, "reactions"); ok {
+		return dailyTrend(dateRange, dateMap), nil
 	}
 	
 	// Daily trend
-	query := `
-		SELECT DATE(r.created_at) as date, COUNT(*) as count
+	query := fmt.Sprintf(`
+		SELECT %[1]s as date, COUNT(*) as count
 		FROM reactions r
 		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 		WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
-		GROUP BY DATE(r.created_at)
+		GROUP BY %[1]s
 		ORDER BY date ASC
-	`
+	`, s.dialect.DateBucket("r.created_at"))
 	
-	rows, err := s.db.Query(query, siteID, dateRange.From, dateRange.To)
+	rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To)
 	if err != nil {
 		return trend, fmt.Errorf("failed to get reactions trend: %w", err)
 	}
@@ -442,43 +702,105 @@ func (s *Store) GetReactionsTrend(siteID string, dateRange DateRange) (TimeSerie
 		dateMap[date] = count
 	}
 	
-	// Fill in all dates in range
+	return dailyTrend(dateRange, dateMap), nil
+}
+
+// hourlyTrendThreshold is the range length below which trends are bucketed hourly
+const hourlyTrendThreshold = 3 * 24 * time.Hour
+
+// getHourlyTrend is a helper to get hourly aggregated data, filling in empty hours.
+// SQLite's strftime normalizes timestamps to UTC, so buckets are labelled in UTC.
+func (s *Store) getHourlyTrend(ctx context.Context, siteID string, dateRange DateRange, dataType string) (TimeSeriesData, error) {
+	var trend TimeSeriesData
+	var query string
+	
+	if dataType == "comments" {
+		query = fmt.Sprintf(`
+			SELECT %s as hour, COUNT(*) as count
+			FROM comments
+			WHERE site_id = ? AND created_at BETWEEN ? AND ?
+			GROUP BY hour
+			ORDER BY hour ASC
+		`, s.dialect.HourBucket("created_at"))
+	} else {
+		query = fmt.Sprintf(`
+			SELECT %s as hour, COUNT(*) as count
+			FROM reactions r
+			INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+			WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
+			GROUP BY hour
+			ORDER BY hour ASC
+		`, s.dialect.HourBucket("r.created_at"))
+	}
+	
+	rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To)
+	if err != nil {
+		return trend, fmt.Errorf("failed to get hourly trend: %w", err)
+	}
+	defer rows.Close()
+	
+	hourMap := make(map[string]int)
+	for rows.Next() {
+		var hour string
+		var count int
+		if err := rows.Scan(&hour, &count); err != nil {
+			continue
+		}
+		hourMap[hour] = count
+	}
+	
+	// Fill in all hours in range
 	trend.Labels = []string{}
 	trend.Values = []int{}
+	to := dateRange.To.UTC()
+	for h := dateRange.From.UTC().Truncate(time.Hour); h.Before(to); h = h.Add(time.Hour) {
+		trend.Labels = append(trend.Labels, h.Format("2006-01-02 15:00"))
+		trend.Values = append(trend.Values, hourMap[h.Format("2006-01-02 15")])
+	}
+	
+	return trend, nil
+}
+
+// dailyTrend fills in every date in the range from per-date counts
+func dailyTrend(dateRange DateRange, dateMap map[string]int) TimeSeriesData {
+	trend := TimeSeriesData{Labels: []string{}, Values: []int{}}
 	for d := dateRange.From; !d.After(dateRange.To); d = d.AddDate(0, 0, 1) {
 		dateStr := d.Format("2006-01-02")
 		trend.Labels = append(trend.Labels, dateStr)
 		trend.Values = append(trend.Values, dateMap[dateStr])
 	}
-	
-	return trend, nil
+	return trend
 }
 
 // getWeeklyTrend is a helper to get weekly aggregated data
-func (s *Store) getWeeklyTrend(siteID string, dateRange DateRange, dataType string) (TimeSeriesData, error) {
+func (s *Store) getWeeklyTrend(ctx context.Context, siteID string, dateRange DateRange, dataType string) (TimeSeriesData, error) {
+	if dateMap, ok := s.aggregatedDailyTotals(ctx, siteID, dateRange, dataType); ok {
+		return weeklyTrendFromDays(dateMap), nil
+	}
+	
 	var trend TimeSeriesData
 	var query string
 	
 	if dataType == "comments" {
-		query = `
-			SELECT strftime('%Y-W%W', created_at) as week, COUNT(*) as count
+		query = fmt.Sprintf(`
+			SELECT %s as week, COUNT(*) as count
 			FROM comments
 			WHERE site_id = ? AND created_at BETWEEN ? AND ?
 			GROUP BY week
 			ORDER BY week ASC
-		`
+		`, s.dialect.WeekBucket("created_at"))
 	} else {
-		query = `
-			SELECT strftime('%Y-W%W', r.created_at) as week, COUNT(*) as count
+		query = fmt.Sprintf(`
+			SELECT %s as week, COUNT(*) as count
 			FROM reactions r
 			INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 			WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?
 			GROUP BY week
 			ORDER BY week ASC
-		`
+		`, s.dialect.WeekBucket("r.created_at"))
 	}
 	
-	rows, err := s.db.Query(query, siteID, dateRange.From, dateRange.To)
+	rows, err := s.query(ctx, query, siteID, dateRange.From, dateRange.To)
 	if err != nil {
 		return trend, fmt.Errorf("failed to get weekly trend: %w", err)
 	}
@@ -502,7 +824,7 @@ func (s *Store) getWeeklyTrend(siteID string, dateRange DateRange, dataType stri
 }
 
 // GetAnalyticsDashboard retrieves complete analytics data for a site
-func (s *Store) GetAnalyticsDashboard(siteID string, dateRange DateRange) (*AnalyticsDashboard, error) {
+func (s *Store) GetAnalyticsDashboard(ctx context.Context, siteID string, dateRange DateRange) (*AnalyticsDashboard, error) {
 	dashboard := &AnalyticsDashboard{
 		SiteID:   siteID,
 		DateFrom: dateRange.From,
@@ -512,40 +834,52 @@ func (s *Store) GetAnalyticsDashboard(siteID string, dateRange DateRange) (*Anal
 	var err error
 	
 	// Get comment metrics
-	dashboard.Comments, err = s.GetCommentMetrics(siteID, dateRange)
+	dashboard.Comments, err = s.GetCommentMetrics(ctx, siteID, dateRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comment metrics: %w", err)
 	}
 	
 	// Get user metrics
-	dashboard.Users, err = s.GetUserMetrics(siteID, dateRange)
+	dashboard.Users, err = s.GetUserMetrics(ctx, siteID, dateRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user metrics: %w", err)
 	}
 	
 	// Get reaction metrics
-	dashboard.Reactions, err = s.GetReactionMetrics(siteID, dateRange)
+	dashboard.Reactions, err = s.GetReactionMetrics(ctx, siteID, dateRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reaction metrics: %w", err)
 	}
 	
+	// Get how reactions spread across comments
+	dashboard.ReactionDistribution, err = s.GetReactionDistribution(ctx, siteID, dateRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reaction distribution: %w", err)
+	}
+	
 	// Get moderation metrics
-	dashboard.Moderation, err = s.GetModerationMetrics(siteID, dateRange)
+	dashboard.Moderation, err = s.GetModerationMetrics(ctx, siteID, dateRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get moderation metrics: %w", err)
 	}
 	
 	// Get comments trend
-	dashboard.CommentsTrend, err = s.GetCommentsTrend(siteID, dateRange)
+	dashboard.CommentsTrend, err = s.GetCommentsTrend(ctx, siteID, dateRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comments trend: %w", err)
 	}
 	
 	// Get reactions trend
-	dashboard.ReactionsTrend, err = s.GetReactionsTrend(siteID, dateRange)
+	dashboard.ReactionsTrend, err = s.GetReactionsTrend(ctx, siteID, dateRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reactions trend: %w", err)
 	}
 	
+	// Get top pages by engagement
+	dashboard.TopPages, err = s.GetPageMetrics(ctx, siteID, dateRange, defaultPageMetricsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page metrics: %w", err)
+	}
+	
 	return dashboard, nil
 }
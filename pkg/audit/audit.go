@@ -0,0 +1,184 @@
+// Package audit records who changed what on a site, for owners and compliance
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionSiteCreate = "site.create"
+	ActionSiteUpdate = "site.update"
+	ActionSiteDelete = "site.delete"
+
+	ActionCommentApprove = "comment.approve"
+	ActionCommentReject  = "comment.reject"
+	ActionCommentDelete  = "comment.delete"
+
+	ActionReactionCreate     = "reaction.create"
+	ActionReactionUpdate     = "reaction.update"
+	ActionReactionChangeType = "reaction.change_type"
+	ActionReactionDelete     = "reaction.delete"
+)
+
+// Types of the object an action was performed on
+const (
+	TargetSite            = "site"
+	TargetComment         = "comment"
+	TargetAllowedReaction = "allowed_reaction"
+)
+
+// Entry is one recorded admin action
+type Entry struct {
+	ID         string                 `json:"id"`
+	SiteID     string                 `json:"site_id"`
+	ActorID    string                 `json:"actor_id"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// Filter narrows the entries List returns. Zero values match everything; a
+// Limit of 0 returns all matching entries.
+type Filter struct {
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// Store handles the audit_log table
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a new audit log store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Record saves an entry in the audit log. Auditing must never fail the action
+// being audited, so an error is logged rather than returned.
+func Record(ctx context.Context, db *sql.DB, entry Entry) {
+	if err := NewStore(db).Insert(ctx, entry); err != nil {
+		log.Printf("Warning: Failed to record audit entry %s for %s %s: %v", entry.Action, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+// Insert saves an entry. It is safe to call on a nil Store so callers without
+// a SQL database don't need to check.
+func (s *Store) Insert(ctx context.Context, entry Entry) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	if entry.ID == "" {
+		entry.ID = uuid.NewString()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	var metadata sql.NullString
+	if len(entry.Metadata) > 0 {
+		encoded, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+		metadata = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	query := `
+		INSERT INTO audit_log (id, site_id, actor_id, action, target_type, target_id, metadata, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	// Stored in UTC so date filters compare consistently
+	_, err := s.db.ExecContext(ctx, query, entry.ID, entry.SiteID, entry.ActorID, entry.Action,
+		entry.TargetType, entry.TargetID, metadata, entry.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// List returns a site's entries matching filter, newest first, and the number
+// of entries matching it in total
+func (s *Store) List(ctx context.Context, siteID string, filter Filter) ([]Entry, int, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, 0, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	conditions := []string{"site_id = ?"}
+	args := []interface{}{siteID}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.To.UTC())
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := `
+		SELECT id, site_id, actor_id, action, target_type, target_id, metadata, created_at
+		FROM audit_log
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id
+	`
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var metadata sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.SiteID, &entry.ActorID, &entry.Action,
+			&entry.TargetType, &entry.TargetID, &metadata, &entry.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if metadata.Valid && metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &entry.Metadata); err != nil {
+				return nil, 0, fmt.Errorf("failed to decode audit metadata: %w", err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit entries: %w", err)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+)
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := comments.ApplySchema(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	ctx := context.Background()
+	store := NewStore(db)
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{SiteID: "site1", ActorID: "admin-1", Action: ActionSiteCreate, TargetType: TargetSite, TargetID: "site1", CreatedAt: day},
+		{SiteID: "site1", ActorID: "admin-1", Action: ActionCommentApprove, TargetType: TargetComment, TargetID: "c1",
+			Metadata: map[string]interface{}{"previous_status": "pending"}, CreatedAt: day.Add(24 * time.Hour)},
+		{SiteID: "site1", ActorID: "admin-1", Action: ActionCommentApprove, TargetType: TargetComment, TargetID: "c2", CreatedAt: day.Add(48 * time.Hour)},
+		{SiteID: "site2", ActorID: "admin-2", Action: ActionCommentApprove, TargetType: TargetComment, TargetID: "c3", CreatedAt: day},
+	}
+	for _, entry := range entries {
+		if err := store.Insert(ctx, entry); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	all, total, err := store.List(ctx, "site1", Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 3 || len(all) != 3 {
+		t.Fatalf("Expected 3 entries for site1, got %d", total)
+	}
+	if all[0].TargetID != "c2" || all[2].Action != ActionSiteCreate {
+		t.Errorf("Expected newest first, got %s then %s", all[0].TargetID, all[2].TargetID)
+	}
+	if all[0].ID == "" || !all[2].CreatedAt.Equal(day) {
+		t.Errorf("Expected an ID and the recorded time, got %+v", all[2])
+	}
+	if all[1].Metadata["previous_status"] != "pending" || all[0].Metadata != nil {
+		t.Errorf("Unexpected metadata: %v, %v", all[1].Metadata, all[0].Metadata)
+	}
+
+	approvals, total, err := store.List(ctx, "site1", Filter{Action: ActionCommentApprove, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 2 || len(approvals) != 1 || approvals[0].TargetID != "c1" {
+		t.Errorf("Expected the second of 2 approvals, got %d: %+v", total, approvals)
+	}
+
+	dated, total, err := store.List(ctx, "site1", Filter{From: day.Add(time.Hour), To: day.Add(25 * time.Hour)})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 1 || len(dated) != 1 || dated[0].TargetID != "c1" {
+		t.Errorf("Expected only c1 between the dates, got %+v", dated)
+	}
+
+	if _, _, err := store.List(ctx, "site1", Filter{Limit: -1}); err == nil {
+		t.Error("Expected an error for a negative limit")
+	}
+}
+
+func TestRecord_WithoutDatabase(t *testing.T) {
+	// Neither call may panic or fail the caller
+	Record(context.Background(), nil, Entry{Action: ActionSiteDelete})
+	var store *Store
+	if err := store.Insert(context.Background(), Entry{}); err != nil {
+		t.Errorf("Expected nil Store to be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"net/http"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+)
+
+// ErrorResponse is the standard JSON error envelope auth endpoints respond with
+type ErrorResponse = apierrors.ErrorResponse
+
+// writeError writes message in the standard JSON error envelope with the
+// request's ID, so auth errors parse the same way as the rest of the API
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	apierrors.WriteErrorWithRequestID(w, apierrors.FromStatus(statusCode, message), logging.GetRequestID(r.Context()))
+}
@@ -3,28 +3,46 @@ package auth
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
 	"github.com/saasuke-labs/kotomi/pkg/models"
 )
 
+// DefaultRefreshRotationWindow is how close to expiry a refresh token must be before
+// a refresh also rotates it
+const DefaultRefreshRotationWindow = 7 * 24 * time.Hour
+
 // AuthHandler handles kotomi authentication API endpoints using Auth0
 type AuthHandler struct {
 	authStore   *KotomiAuthStore
 	db          *sql.DB
 	auth0Config *Auth0Config  // Shared Auth0 config for kotomi auth
+	// refreshRotationWindow is read from KOTOMI_AUTH_REFRESH_ROTATION_WINDOW (a Go duration)
+	refreshRotationWindow time.Duration
 }
 
 // NewAuthHandler creates a new auth handler
 func NewAuthHandler(db *sql.DB, auth0Config *Auth0Config) *AuthHandler {
+	rotationWindow := DefaultRefreshRotationWindow
+	if value := os.Getenv("KOTOMI_AUTH_REFRESH_ROTATION_WINDOW"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			rotationWindow = parsed
+		}
+	}
+
 	return &AuthHandler{
-		authStore:   NewKotomiAuthStore(db),
-		db:          db,
-		auth0Config: auth0Config,
+		authStore:             NewKotomiAuthStore(db),
+		db:                    db,
+		auth0Config:           auth0Config,
+		refreshRotationWindow: rotationWindow,
 	}
 }
 
@@ -36,9 +54,12 @@ type AuthResponse struct {
 	ExpiresAt    time.Time       `json:"expires_at"`
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error string `json:"error"`
+// RefreshResponse represents the tokens issued by a refresh
+type RefreshResponse struct {
+	Token            string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 // GetJWTSecret retrieves the JWT secret for a site (internal key for kotomi mode)
@@ -62,14 +83,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Get site ID from query parameter
 	siteID := r.URL.Query().Get("siteId")
 	if siteID == "" {
-		http.Error(w, `{"error": "siteId is required"}`, http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "siteId is required")
 		return
 	}
 
 	// Store site ID in session state for callback
 	state, err := GenerateRandomState()
 	if err != nil {
-		http.Error(w, `{"error": "Failed to generate state"}`, http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to generate state")
 		return
 	}
 	state = fmt.Sprintf("%s:%s", siteID, state)
@@ -97,14 +118,14 @@ func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
 	state := r.URL.Query().Get("state")
 	
 	if code == "" || state == "" {
-		http.Error(w, `{"error": "Missing code or state"}`, http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Missing code or state")
 		return
 	}
 	
 	// Extract site ID from state
 	parts := strings.SplitN(state, ":", 2)
 	if len(parts) != 2 {
-		http.Error(w, `{"error": "Invalid state parameter"}`, http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid state parameter")
 		return
 	}
 	siteID := parts[0]
@@ -112,35 +133,39 @@ func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
 	// Exchange code for token
 	token, err := h.auth0Config.ExchangeCode(r.Context(), code)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to exchange code: %v"}`, err), http.StatusInternalServerError)
+		log.Printf("Failed to exchange code: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to exchange code")
 		return
 	}
 	
 	// Get user info from Auth0
 	userInfo, err := h.auth0Config.GetUserInfo(r.Context(), token)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to get user info: %v"}`, err), http.StatusInternalServerError)
+		log.Printf("Failed to get user info: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get user info")
 		return
 	}
 	
 	// Create or update user in database
 	user, err := h.authStore.CreateOrUpdateUserFromAuth0(siteID, userInfo)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to create user: %v"}`, err), http.StatusInternalServerError)
+		log.Printf("Failed to create user: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
 	
 	// Get JWT secret for this site
 	jwtSecret, err := h.GetJWTSecret(siteID)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 	
 	// Create session with our own JWT token
 	session, err := h.authStore.CreateSession(user, jwtSecret)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to create session: %v"}`, err), http.StatusInternalServerError)
+		log.Printf("Failed to create session: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create session")
 		return
 	}
 	
@@ -181,7 +206,7 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Get token from Authorization header or cookie
 	token := h.extractToken(r)
 	if token == "" {
-		http.Error(w, `{"error": "No token provided"}`, http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "No token provided")
 		return
 	}
 
@@ -195,7 +220,8 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 			MaxAge:   -1,
 			HttpOnly: true,
 		})
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to logout: %v"}`, err), http.StatusInternalServerError)
+		log.Printf("Failed to logout: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
 
@@ -212,6 +238,129 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
 }
 
+// Refresh exchanges a refresh token for a new access token
+// @Summary Refresh access token
+// @Description Issue a new access token for a session; the refresh token is rotated when close to expiry
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Success 200 {object} RefreshResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/{siteId}/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, r, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	session, err := h.authStore.GetSessionByRefreshToken(req.RefreshToken)
+	if err != nil || session.SiteID != siteID {
+		writeError(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	if time.Now().After(session.RefreshExpiresAt) {
+		writeError(w, r, http.StatusUnauthorized, "Refresh token expired")
+		return
+	}
+
+	jwtSecret, err := h.GetJWTSecret(siteID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	refreshed, err := h.authStore.RefreshSession(session, jwtSecret, h.refreshRotationWindow)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			// The refresh token was redeemed concurrently or the session was removed
+			writeError(w, r, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+		log.Printf("Failed to refresh session: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to refresh session")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "kotomi_auth_token",
+		Value:    refreshed.Token,
+		Path:     "/",
+		Expires:  refreshed.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil, // Only secure in HTTPS
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RefreshResponse{
+		Token:            refreshed.Token,
+		RefreshToken:     refreshed.RefreshToken,
+		ExpiresAt:        refreshed.ExpiresAt,
+		RefreshExpiresAt: refreshed.RefreshExpiresAt,
+	})
+}
+
+// LogoutAll invalidates every session the current user holds on a site
+// @Summary Logout everywhere
+// @Description Invalidate all sessions of the current user on a site
+// @Tags auth
+// @Produce json
+// @Param siteId path string true "Site ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /auth/{siteId}/logout-all [post]
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	siteID := mux.Vars(r)["siteId"]
+
+	token := h.extractToken(r)
+	if token == "" {
+		writeError(w, r, http.StatusUnauthorized, "No token provided")
+		return
+	}
+
+	// Only a currently valid session for this site may revoke the user's sessions
+	session, err := h.authStore.GetSessionByToken(token)
+	if err != nil || session.SiteID != siteID {
+		writeError(w, r, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		writeError(w, r, http.StatusUnauthorized, "Token expired")
+		return
+	}
+
+	removed, err := h.authStore.DeleteAllSessionsForUser(siteID, session.UserID)
+	if err != nil {
+		log.Printf("Failed to logout: %v", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	// Clear cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     "kotomi_auth_token",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":          "Logged out of all sessions",
+		"sessions_removed": removed,
+	})
+}
+
 // GetCurrentUser returns the current authenticated user
 // @Summary Get current user
 // @Description Get current user profile
@@ -225,27 +374,27 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// Get token from Authorization header or cookie
 	token := h.extractToken(r)
 	if token == "" {
-		http.Error(w, `{"error": "No token provided"}`, http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "No token provided")
 		return
 	}
 
 	// Get session
 	session, err := h.authStore.GetSessionByToken(token)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Invalid or expired token")
 		return
 	}
 
 	// Check if session is expired
 	if time.Now().After(session.ExpiresAt) {
-		http.Error(w, `{"error": "Token expired"}`, http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Token expired")
 		return
 	}
 
 	// Get user
 	user, err := h.authStore.GetUserByID(session.UserID)
 	if err != nil {
-		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "User not found")
 		return
 	}
 
@@ -255,7 +404,7 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 
 // GetAuthConfig returns the auth configuration for a site
 // @Summary Get auth config
-// @Description Get authentication configuration for a site (helps clients know which auth flow to use)
+// @Description Get authentication configuration for a site (helps clients know which auth flow to use), its comment content policy and default comment status
 // @Tags auth
 // @Produce json
 // @Param siteId query string true "Site ID"
@@ -266,7 +415,7 @@ func (h *AuthHandler) GetAuthConfig(w http.ResponseWriter, r *http.Request) {
 	// Get site ID from query parameter
 	siteID := r.URL.Query().Get("siteId")
 	if siteID == "" {
-		http.Error(w, `{"error": "siteId is required"}`, http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "siteId is required")
 		return
 	}
 	
@@ -274,14 +423,25 @@ func (h *AuthHandler) GetAuthConfig(w http.ResponseWriter, r *http.Request) {
 	authConfigStore := models.NewSiteAuthConfigStore(h.db)
 	authConfig, err := authConfigStore.GetBySiteID(r.Context(), siteID)
 	if err != nil {
-		http.Error(w, `{"error": "Site not found or auth not configured"}`, http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Site not found or auth not configured")
 		return
 	}
 	
+	// The content policy tells the widget which editor to offer, and the
+	// default status whether to expect new comments to await review
+	contentPolicy := comments.DefaultContentPolicy
+	defaultStatus := models.CommentStatusPending
+	if settings, err := models.NewSiteSettingsStore(h.db).GetBySiteID(r.Context(), siteID); err == nil {
+		contentPolicy = settings.ContentPolicy
+		defaultStatus = settings.DefaultCommentStatus
+	}
+
 	// Return public auth config info
 	response := map[string]interface{}{
-		"site_id":   siteID,
-		"auth_mode": authConfig.AuthMode,
+		"site_id":                siteID,
+		"auth_mode":              authConfig.AuthMode,
+		"content_policy":         contentPolicy,
+		"default_comment_status": defaultStatus,
 	}
 	
 	// Add Auth0 domain if kotomi mode
@@ -324,4 +484,6 @@ func (h *AuthHandler) RegisterRoutes(router *mux.Router) {
 	authRouter.HandleFunc("/logout", h.Logout).Methods("POST")
 	authRouter.HandleFunc("/user", h.GetCurrentUser).Methods("GET")
 	authRouter.HandleFunc("/config", h.GetAuthConfig).Methods("GET")
+	authRouter.HandleFunc("/{siteId}/refresh", h.Refresh).Methods("POST")
+	authRouter.HandleFunc("/{siteId}/logout-all", h.LogoutAll).Methods("POST")
 }
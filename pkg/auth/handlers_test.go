@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+)
+
+func TestAuthHandler_ErrorEnvelope(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	NewAuthHandler(db, nil).RegisterRoutes(router)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"login without site", "GET", "/api/v1/auth/login", "", "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"callback without code", "GET", "/api/v1/auth/callback", "", "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"callback with invalid state", "GET", "/api/v1/auth/callback?code=c&state=bogus", "", "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"logout without token", "POST", "/api/v1/auth/logout", "", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"user without token", "GET", "/api/v1/auth/user", "", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"user with unknown token", "GET", "/api/v1/auth/user", "", "bogus", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"config without site", "GET", "/api/v1/auth/config", "", "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"config for unknown site", "GET", "/api/v1/auth/config?siteId=missing", "", "", http.StatusNotFound, "NOT_FOUND"},
+		{"refresh without token", "POST", "/api/v1/auth/test-site/refresh", "{}", "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"refresh with unknown token", "POST", "/api/v1/auth/test-site/refresh", `{"refresh_token":"bogus"}`, "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"logout-all with unknown token", "POST", "/api/v1/auth/test-site/logout-all", "", "bogus", http.StatusUnauthorized, "UNAUTHORIZED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(logging.WithRequestID(req.Context(), "req-123"))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected Content-Type application/json, got %s", ct)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode error envelope: %v", err)
+			}
+			if resp.Error == nil {
+				t.Fatal("Expected an error object in the envelope")
+			}
+			if string(resp.Error.Code) != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, resp.Error.Code)
+			}
+			if resp.Error.Message == "" {
+				t.Error("Expected a message")
+			}
+			if resp.Error.RequestID != "req-123" {
+				t.Errorf("Expected request ID req-123, got %q", resp.Error.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultJWKSRefreshInterval is how long fetched keys are used before the key
+// set is fetched again
+const DefaultJWKSRefreshInterval = time.Hour
+
+// jwksMinRefetchInterval limits how often a token with an unknown kid can
+// trigger a fetch, so forged kids can't flood the identity provider
+const jwksMinRefetchInterval = 30 * time.Second
+
+// maxJWKSBytes caps the size of a JWKS response
+const maxJWKSBytes = 1 << 20
+
+// ErrUnknownKey is returned when no key in the set matches a token's kid
+var ErrUnknownKey = errors.New("no matching key found in JWKS")
+
+// JWKSValidator verifies RS256 tokens against the keys published at a JWKS
+// endpoint. Keys are cached by kid and refetched once the refresh interval has
+// passed, or early when a token names a kid the cache doesn't know, which is
+// how key rotation is picked up.
+type JWKSValidator struct {
+	endpoint        string
+	client          *http.Client
+	refreshInterval time.Duration
+	minRefetch      time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	group     singleflight.Group
+}
+
+// NewJWKSValidator creates a validator for the key set at endpoint. A zero
+// refreshInterval uses DefaultJWKSRefreshInterval.
+func NewJWKSValidator(endpoint string, refreshInterval time.Duration) *JWKSValidator {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultJWKSRefreshInterval
+	}
+	return &JWKSValidator{
+		endpoint:        endpoint,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: refreshInterval,
+		minRefetch:      jwksMinRefetchInterval,
+	}
+}
+
+// jwksValidators shares one validator, and so one key cache, per endpoint
+// across requests
+var jwksValidators = struct {
+	sync.Mutex
+	byEndpoint map[string]*JWKSValidator
+}{byEndpoint: make(map[string]*JWKSValidator)}
+
+// jwksValidatorFor returns the shared validator for endpoint
+func jwksValidatorFor(endpoint string) *JWKSValidator {
+	jwksValidators.Lock()
+	defer jwksValidators.Unlock()
+
+	v, ok := jwksValidators.byEndpoint[endpoint]
+	if !ok {
+		v = NewJWKSValidator(endpoint, 0)
+		jwksValidators.byEndpoint[endpoint] = v
+	}
+	return v
+}
+
+// Parse parses and verifies a token signed with one of the set's keys. The
+// exp claim is checked with leeway, allowing the given grace period; the
+// remaining claims are left to the caller.
+func (v *JWKSValidator) Parse(tokenString string, leeway time.Duration) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, v.Keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithLeeway(leeway),
+	)
+}
+
+// Keyfunc returns the public key named by the token's kid header, for use with
+// jwt.Parse
+func (v *JWKSValidator) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, fmt.Errorf("token missing kid header")
+	}
+	return v.key(kid)
+}
+
+// key looks kid up in the cache, refreshing the set when it is stale or
+// doesn't have the key
+func (v *JWKSValidator) key(kid string) (*rsa.PublicKey, error) {
+	v.mu.RLock()
+	key, found := v.keys[kid]
+	age := time.Since(v.fetchedAt)
+	fetched := !v.fetchedAt.IsZero()
+	v.mu.RUnlock()
+
+	switch {
+	case fetched && found && age < v.refreshInterval:
+		return key, nil
+	case fetched && !found && age < v.minRefetch:
+		// Fetched moments ago; a rotation wouldn't have shown up yet
+		return nil, ErrUnknownKey
+	}
+
+	if err := v.refresh(); err != nil {
+		if found {
+			// Keep serving a known key while the endpoint is unreachable
+			log.Printf("Warning: failed to refresh JWKS from %s, using cached keys: %v", v.endpoint, err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// refresh fetches the key set and replaces the cache. Concurrent refreshes
+// share one request.
+func (v *JWKSValidator) refresh() error {
+	_, err, _ := v.group.Do("refresh", func() (interface{}, error) {
+		keys, err := v.fetch()
+		if err != nil {
+			return nil, err
+		}
+		v.mu.Lock()
+		v.keys = keys
+		v.fetchedAt = time.Now()
+		v.mu.Unlock()
+		return nil, nil
+	})
+	return err
+}
+
+// jsonWebKey is the subset of a JWK used to build RSA signing keys
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetch downloads the key set, keeping the RSA signing keys that parse
+func (v *JWKSValidator) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := v.client.Get(v.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS response: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Kid == "" || jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") ||
+			(jwk.Alg != "" && jwk.Alg != jwt.SigningMethodRS256.Alg()) {
+			continue
+		}
+		key, err := rsaPublicKey(jwk)
+		if err != nil {
+			log.Printf("Warning: skipping JWKS key %q from %s: %v", jwk.Kid, v.endpoint, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// rsaPublicKey decodes a JWK's base64url modulus and exponent
+func rsaPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil || len(n) == 0 {
+		return nil, fmt.Errorf("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	exponent := 0
+	for _, b := range e {
+		exponent = exponent<<8 | int(b)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// jwksServer serves a mutable key set and counts how often it is fetched
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		var keys []map[string]string
+		for kid, key := range s.keys {
+			keys = append(keys, map[string]string{
+				"kid": kid, "kty": "RSA", "use": "sig", "alg": "RS256",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotate replaces the published keys with a new key under kid
+func (s *jwksServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	s.mu.Lock()
+	s.keys = map[string]*rsa.PrivateKey{kid: key}
+	s.mu.Unlock()
+	return key
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// signJWKSToken creates an RS256 token for user-123 signed with key under kid
+func signJWKSToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	base := jwt.MapClaims{
+		"iss":         "https://idp.example.com/",
+		"sub":         "user-123",
+		"aud":         []string{"kotomi", "https://idp.example.com/userinfo"},
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"kotomi_user": map[string]interface{}{"name": "Jane Doe", "email": "jane@example.com"},
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestJWTValidator_ValidateJWKS(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+
+	config := &models.SiteAuthConfig{
+		AuthMode:              "external",
+		JWTValidationType:     "jwks",
+		JWKSEndpoint:          server.URL,
+		JWTIssuer:             "https://idp.example.com/",
+		JWTAudience:           "kotomi",
+		TokenExpirationBuffer: 60,
+	}
+	validator := NewJWTValidator(config)
+
+	user, err := validator.ValidateToken(signJWKSToken(t, key, "key-1", nil))
+	if err != nil {
+		t.Fatalf("Token validation failed: %v", err)
+	}
+	if user.ID != "user-123" || user.Name != "Jane Doe" || user.Email != "jane@example.com" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+
+	// Within the expiration buffer
+	if _, err := validator.ValidateToken(signJWKSToken(t, key, "key-1", jwt.MapClaims{"exp": time.Now().Add(-30 * time.Second).Unix()})); err != nil {
+		t.Errorf("Expected a token expired within the buffer to be accepted: %v", err)
+	}
+
+	rejected := map[string]string{
+		"expired":        signJWKSToken(t, key, "key-1", jwt.MapClaims{"exp": time.Now().Add(-2 * time.Minute).Unix()}),
+		"wrong issuer":   signJWKSToken(t, key, "key-1", jwt.MapClaims{"iss": "https://evil.example.com/"}),
+		"wrong audience": signJWKSToken(t, key, "key-1", jwt.MapClaims{"aud": "someone-else"}),
+		"unknown kid":    signJWKSToken(t, key, "key-9", nil),
+	}
+	for name, token := range rejected {
+		if _, err := validator.ValidateToken(token); err == nil {
+			t.Errorf("Expected %s token to be rejected", name)
+		}
+	}
+
+	// An HMAC token naming a known kid must not be accepted
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-123", "exp": time.Now().Add(time.Hour).Unix()})
+	hmac.Header["kid"] = "key-1"
+	hmacString, _ := hmac.SignedString([]byte("secret"))
+	if _, err := validator.ValidateToken(hmacString); err == nil {
+		t.Error("Expected an HS256 token to be rejected")
+	}
+}
+
+func TestJWKSValidator_KeyRotation(t *testing.T) {
+	server := newJWKSServer(t)
+	oldKey := server.rotate(t, "key-1")
+
+	v := NewJWKSValidator(server.URL, time.Hour)
+	v.minRefetch = 0
+
+	if _, err := v.Parse(signJWKSToken(t, oldKey, "key-1", nil), 0); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := v.Parse(signJWKSToken(t, oldKey, "key-1", nil), 0); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := server.fetchCount(); got != 1 {
+		t.Errorf("Expected cached keys to be reused, got %d fetches", got)
+	}
+
+	// A token with the new kid triggers a refetch
+	newKey := server.rotate(t, "key-2")
+	if _, err := v.Parse(signJWKSToken(t, newKey, "key-2", nil), 0); err != nil {
+		t.Fatalf("Expected the rotated key to be picked up: %v", err)
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Errorf("Expected one refetch for the new kid, got %d fetches", got)
+	}
+
+	// The old key was dropped from the set
+	if _, err := v.Parse(signJWKSToken(t, oldKey, "key-1", nil), 0); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected the rotated-out key to be unknown, got %v", err)
+	}
+}
+
+func TestJWKSValidator_UnknownKidRateLimited(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+
+	v := NewJWKSValidator(server.URL, time.Hour)
+	if _, err := v.Parse(signJWKSToken(t, key, "key-1", nil), 0); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := v.Parse(signJWKSToken(t, key, "forged", nil), 0); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Expected ErrUnknownKey, got %v", err)
+		}
+	}
+	if got := server.fetchCount(); got != 1 {
+		t.Errorf("Expected unknown kids not to refetch right after a fetch, got %d fetches", got)
+	}
+}
+
+func TestJWKSValidator_RefreshInterval(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+
+	v := NewJWKSValidator(server.URL, time.Millisecond)
+	if _, err := v.Parse(signJWKSToken(t, key, "key-1", nil), 0); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := v.Parse(signJWKSToken(t, key, "key-1", nil), 0); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Errorf("Expected stale keys to be refetched, got %d fetches", got)
+	}
+
+	// Cached keys keep working while the endpoint is down
+	server.Close()
+	time.Sleep(5 * time.Millisecond)
+	if _, err := v.Parse(signJWKSToken(t, key, "key-1", nil), 0); err != nil {
+		t.Errorf("Expected the cached key to be used when the refresh fails: %v", err)
+	}
+}
@@ -3,11 +3,9 @@ package auth
 import (
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/json"
 	"encoding/pem"
 	"fmt"
-	"io"
-	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -44,6 +42,8 @@ func (v *JWTValidator) ValidateToken(tokenString string) (*models.KotomiUser, er
 		switch v.config.JWTValidationType {
 		case "hmac":
 			token, err = v.validateHMAC(tokenString)
+		case "secret":
+			token, err = v.validateSecret(tokenString)
 		case "rsa":
 			token, err = v.validateRSA(tokenString)
 		case "ecdsa":
@@ -105,6 +105,16 @@ func (v *JWTValidator) validateHMAC(tokenString string) (*jwt.Token, error) {
 	})
 }
 
+// validateSecret validates an HS256 token signed with the site's shared secret
+func (v *JWTValidator) validateSecret(tokenString string) (*jwt.Token, error) {
+	if v.config.JWTSecret == "" {
+		return nil, fmt.Errorf("JWT secret not configured")
+	}
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(v.config.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+}
+
 // validateRSA validates token using RSA public key
 func (v *JWTValidator) validateRSA(tokenString string) (*jwt.Token, error) {
 	// Parse public key
@@ -154,56 +164,14 @@ func (v *JWTValidator) validateECDSA(tokenString string) (*jwt.Token, error) {
 	})
 }
 
-// validateJWKS validates token using JWKS endpoint
-// Note: This is a placeholder implementation for Phase 1.
-// Full JWKS support with proper key caching and rotation will be added in future phases.
+// validateJWKS validates an RS256 token against the site's JWKS endpoint. The
+// keys are cached per endpoint across requests.
 func (v *JWTValidator) validateJWKS(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Get key ID from token header
-		kid, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, fmt.Errorf("token missing kid header")
-		}
-
-		// Fetch JWKS
-		resp, err := http.Get(v.config.JWKSEndpoint)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read JWKS response: %w", err)
-		}
-
-		// Parse JWKS
-		var jwks struct {
-			Keys []map[string]interface{} `json:"keys"`
-		}
-		if err := json.Unmarshal(body, &jwks); err != nil {
-			return nil, fmt.Errorf("failed to parse JWKS: %w", err)
-		}
-
-		// Find matching key
-		for _, key := range jwks.Keys {
-			if keyID, ok := key["kid"].(string); ok && keyID == kid {
-				// For simplicity, we'll handle RSA keys for now
-				// In production, you'd want to use a proper JWKS library
-				if kty, ok := key["kty"].(string); ok && kty == "RSA" {
-					// This is a simplified version - production should use a proper JWKS library
-					// like github.com/MicahParks/keyfunc for full JWKS support with caching
-					return nil, fmt.Errorf("JWKS validation requires a proper JWKS library - not fully implemented in Phase 1")
-				}
-			}
-		}
-
-		return nil, fmt.Errorf("no matching key found in JWKS")
-	})
+	if v.config.JWKSEndpoint == "" {
+		return nil, fmt.Errorf("JWKS endpoint not configured")
+	}
+	leeway := time.Duration(v.config.TokenExpirationBuffer) * time.Second
+	return jwksValidatorFor(v.config.JWKSEndpoint).Parse(tokenString, leeway)
 }
 
 // validateStandardClaims validates issuer, audience, and expiration
@@ -216,11 +184,11 @@ func (v *JWTValidator) validateStandardClaims(claims jwt.MapClaims) error {
 		}
 	}
 
-	// Validate audience if configured
+	// Validate audience if configured; identity providers may send a list
 	if v.config.JWTAudience != "" {
-		aud, ok := claims["aud"].(string)
-		if !ok || aud != v.config.JWTAudience {
-			return fmt.Errorf("invalid audience: expected %s, got %s", v.config.JWTAudience, aud)
+		aud, err := claims.GetAudience()
+		if err != nil || !slices.Contains(aud, v.config.JWTAudience) {
+			return fmt.Errorf("invalid audience: expected %s, got %v", v.config.JWTAudience, aud)
 		}
 	}
 
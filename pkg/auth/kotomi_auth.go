@@ -3,12 +3,15 @@ package auth
 import (
 	"crypto/rand"
 	"database/sql"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/saasuke-labs/kotomi/pkg/models"
 )
 
 // KotomiAuthUser represents a user authenticated through Kotomi's Auth0 integration
@@ -24,6 +27,10 @@ type KotomiAuthUser struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+// ErrSessionNotFound is returned when no session matches a token, including
+// when a refresh token was already redeemed
+var ErrSessionNotFound = errors.New("session not found")
+
 // KotomiAuthSession represents a user session with JWT tokens
 type KotomiAuthSession struct {
 	ID                string    `json:"id"`
@@ -36,6 +43,15 @@ type KotomiAuthSession struct {
 	CreatedAt         time.Time `json:"created_at"`
 }
 
+const (
+	// AccessTokenTTL is how long a session's access token is valid unless the
+	// site configures access_token_lifetime
+	AccessTokenTTL = models.DefaultAccessTokenLifetime * time.Second
+	// RefreshTokenTTL is how long a session's refresh token is valid unless the
+	// site configures refresh_token_lifetime
+	RefreshTokenTTL = models.DefaultRefreshTokenLifetime * time.Second
+)
+
 // KotomiAuthStore handles database operations for kotomi authentication
 type KotomiAuthStore struct {
 	db *sql.DB
@@ -195,6 +211,7 @@ func GenerateJWTToken(user *KotomiAuthUser, siteID string, secret string, expira
 		"aud": "kotomi",
 		"exp": time.Now().Add(time.Duration(expirationMinutes) * time.Minute).Unix(),
 		"iat": time.Now().Unix(),
+		"jti": uuid.NewString(), // Keeps tokens issued in the same second unique per session
 		"kotomi_user": map[string]interface{}{
 			"id":       user.ID,
 			"name":     user.Name,
@@ -215,29 +232,50 @@ func GenerateJWTToken(user *KotomiAuthUser, siteID string, secret string, expira
 	return tokenString, nil
 }
 
-// CreateSession creates a new session with JWT tokens
+// tokenLifetimes returns how long a site's access and refresh tokens are
+// valid, falling back to AccessTokenTTL and RefreshTokenTTL for sites without
+// an auth config. The access lifetime is whole minutes, as JWTs are issued.
+func (s *KotomiAuthStore) tokenLifetimes(siteID string) (access, refresh time.Duration, err error) {
+	config, err := models.NewSiteAuthConfigStore(s.db).GetBySiteID(context.Background(), siteID)
+	if errors.Is(err, models.ErrSiteAuthConfigNotFound) {
+		return AccessTokenTTL, RefreshTokenTTL, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	access, refresh = config.TokenLifetimes()
+	return access.Truncate(time.Minute), refresh, nil
+}
+
+// CreateSession creates a new session with JWT tokens that expire after the
+// site's configured lifetimes
 func (s *KotomiAuthStore) CreateSession(user *KotomiAuthUser, jwtSecret string) (*KotomiAuthSession, error) {
-	// Generate access token (expires in 1 hour)
-	accessToken, err := GenerateJWTToken(user, user.SiteID, jwtSecret, 60)
+	accessTTL, refreshTTL, err := s.tokenLifetimes(user.SiteID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate refresh token (random string, expires in 30 days)
+	accessToken, err := GenerateJWTToken(user, user.SiteID, jwtSecret, int(accessTTL/time.Minute))
+	if err != nil {
+		return nil, err
+	}
+
+	// The refresh token is a random string rather than a JWT
 	refreshToken, err := GenerateRandomToken()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	session := &KotomiAuthSession{
 		ID:                uuid.NewString(),
 		UserID:            user.ID,
 		SiteID:            user.SiteID,
 		Token:             accessToken,
 		RefreshToken:      refreshToken,
-		ExpiresAt:         time.Now().Add(1 * time.Hour),
-		RefreshExpiresAt:  time.Now().Add(30 * 24 * time.Hour),
-		CreatedAt:         time.Now(),
+		ExpiresAt:         now.Add(accessTTL),
+		RefreshExpiresAt:  now.Add(refreshTTL),
+		CreatedAt:         now,
 	}
 
 	query := `
@@ -269,7 +307,7 @@ func (s *KotomiAuthStore) GetSessionByToken(token string) (*KotomiAuthSession, e
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("session not found")
+			return nil, ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to query session: %w", err)
 	}
@@ -292,7 +330,7 @@ func (s *KotomiAuthStore) GetSessionByRefreshToken(refreshToken string) (*Kotomi
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("session not found")
+			return nil, ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to query session: %w", err)
 	}
@@ -300,6 +338,63 @@ func (s *KotomiAuthStore) GetSessionByRefreshToken(refreshToken string) (*Kotomi
 	return &session, nil
 }
 
+// RefreshSession issues a new access token for a session. When the refresh token
+// expires within rotationWindow it is rotated as well. The update is conditional on
+// the refresh token still matching, so a refresh token can only be redeemed once
+// per rotation even under concurrent requests.
+func (s *KotomiAuthStore) RefreshSession(session *KotomiAuthSession, jwtSecret string, rotationWindow time.Duration) (*KotomiAuthSession, error) {
+	user, err := s.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessTTL, refreshTTL, err := s.tokenLifetimes(session.SiteID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := GenerateJWTToken(user, session.SiteID, jwtSecret, int(accessTTL/time.Minute))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshed := *session
+	refreshed.Token = accessToken
+	refreshed.ExpiresAt = now.Add(accessTTL)
+
+	if session.RefreshExpiresAt.Sub(now) < rotationWindow {
+		refreshToken, err := GenerateRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		refreshed.RefreshToken = refreshToken
+		refreshed.RefreshExpiresAt = now.Add(refreshTTL)
+	}
+
+	query := `
+		UPDATE kotomi_auth_sessions
+		SET token = ?, expires_at = ?, refresh_token = ?, refresh_expires_at = ?
+		WHERE id = ? AND refresh_token = ?
+	`
+
+	result, err := s.db.Exec(query, refreshed.Token, refreshed.ExpiresAt, refreshed.RefreshToken,
+		refreshed.RefreshExpiresAt, session.ID, session.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	return &refreshed, nil
+}
+
 // DeleteSession deletes a session (logout)
 func (s *KotomiAuthStore) DeleteSession(sessionID string) error {
 	query := `DELETE FROM kotomi_auth_sessions WHERE id = ?`
@@ -320,6 +415,41 @@ func (s *KotomiAuthStore) DeleteSessionByToken(token string) error {
 	return nil
 }
 
+// DeleteAllSessionsForUser deletes every session a user holds on a site and returns
+// how many were removed. The site scope guarantees sessions on other sites are never
+// touched, even if user IDs collide.
+func (s *KotomiAuthStore) DeleteAllSessionsForUser(siteID, userID string) (int, error) {
+	query := `DELETE FROM kotomi_auth_sessions WHERE site_id = ? AND user_id = ?`
+	result, err := s.db.Exec(query, siteID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// PurgeExpiredSessions deletes sessions whose refresh token has expired, since they
+// can no longer be used, and returns how many were removed
+func (s *KotomiAuthStore) PurgeExpiredSessions() (int, error) {
+	query := `DELETE FROM kotomi_auth_sessions WHERE refresh_expires_at < ?`
+	result, err := s.db.Exec(query, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired sessions: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
 // UpdateUser updates user information
 func (s *KotomiAuthStore) UpdateUser(user *KotomiAuthUser) error {
 	user.UpdatedAt = time.Now()
@@ -1,12 +1,17 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/models"
 )
 
 func setupTestDB(t *testing.T) (*sql.DB, func()) {
@@ -24,49 +29,18 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		t.Fatalf("Failed to enable foreign keys: %v", err)
 	}
 
-	// Create schema - updated for Auth0
-	schema := `
-	CREATE TABLE IF NOT EXISTS sites (
-		id TEXT PRIMARY KEY,
-		owner_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS kotomi_auth_users (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		email TEXT NOT NULL,
-		auth0_sub TEXT NOT NULL,
-		name TEXT,
-		avatar_url TEXT,
-		is_verified INTEGER DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
-		UNIQUE(site_id, auth0_sub)
-	);
-
-	CREATE TABLE IF NOT EXISTS kotomi_auth_sessions (
-		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL,
-		site_id TEXT NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		refresh_token TEXT NOT NULL UNIQUE,
-		expires_at TIMESTAMP NOT NULL,
-		refresh_expires_at TIMESTAMP NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES kotomi_auth_users(id) ON DELETE CASCADE,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-	`
-
-	if _, err := db.Exec(schema); err != nil {
+	if err := comments.ApplySchema(db); err != nil {
 		db.Close()
 		t.Fatalf("Failed to create schema: %v", err)
 	}
 
+	// Insert the test site's owner
+	_, err = db.Exec(`INSERT INTO admin_users (id, email, name, auth0_sub) VALUES (?, ?, ?, ?)`, "test-owner", "owner@example.com", "Owner", "auth0|test-owner")
+	if err != nil {
+		db.Close()
+		t.Fatalf("Failed to insert test owner: %v", err)
+	}
+
 	// Insert test site
 	_, err = db.Exec(`INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)`, "test-site", "test-owner", "Test Site")
 	if err != nil {
@@ -512,3 +486,181 @@ func TestUpdateUser(t *testing.T) {
 		t.Error("Expected IsVerified to be true")
 	}
 }
+
+func TestRefreshSession(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewKotomiAuthStore(db)
+
+	userInfo := &UserInfo{
+		Sub:           "auth0|12345",
+		Email:         "test@example.com",
+		Name:          "Test User",
+		EmailVerified: true,
+	}
+	user, err := store.CreateOrUpdateUserFromAuth0("test-site", userInfo)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUserFromAuth0 failed: %v", err)
+	}
+
+	created, err := store.CreateSession(user, "test-secret")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	// Far from expiry: only the access token changes
+	refreshed, err := store.RefreshSession(created, "test-secret", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+	if refreshed.RefreshToken != created.RefreshToken {
+		t.Error("Expected refresh token to be kept outside the rotation window")
+	}
+	if refreshed.ExpiresAt.Before(created.ExpiresAt) {
+		t.Error("Expected refreshed access token to expire no earlier than the original")
+	}
+
+	stored, err := store.GetSessionByToken(refreshed.Token)
+	if err != nil {
+		t.Fatalf("Expected session to be found by new token: %v", err)
+	}
+	if stored.ID != created.ID {
+		t.Errorf("Expected session ID %s, got %s", created.ID, stored.ID)
+	}
+
+	// Within the rotation window the refresh token is rotated too
+	rotated, err := store.RefreshSession(refreshed, "test-secret", RefreshTokenTTL+time.Hour)
+	if err != nil {
+		t.Fatalf("RefreshSession with rotation failed: %v", err)
+	}
+	if rotated.RefreshToken == refreshed.RefreshToken {
+		t.Error("Expected refresh token to be rotated inside the rotation window")
+	}
+	if _, err := store.GetSessionByRefreshToken(refreshed.RefreshToken); err == nil {
+		t.Error("Expected old refresh token to be invalid after rotation")
+	}
+
+	// Redeeming the superseded refresh token again must fail
+	if _, err := store.RefreshSession(refreshed, "test-secret", RefreshTokenTTL+time.Hour); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for a superseded refresh token, got %v", err)
+	}
+}
+
+func TestCreateSession_SiteTokenLifetimes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewKotomiAuthStore(db)
+	user, err := store.CreateOrUpdateUserFromAuth0("test-site", &UserInfo{Sub: "auth0|12345", Email: "test@example.com", Name: "Test User"})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUserFromAuth0 failed: %v", err)
+	}
+
+	// Without an auth config the defaults apply
+	before := time.Now()
+	session, err := store.CreateSession(user, "test-secret")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	assertExpiresAfter(t, "default access token", session.ExpiresAt, before, AccessTokenTTL)
+	assertExpiresAfter(t, "default refresh token", session.RefreshExpiresAt, before, RefreshTokenTTL)
+
+	if err := models.NewSiteAuthConfigStore(db).Create(context.Background(), &models.SiteAuthConfig{
+		SiteID:               "test-site",
+		AuthMode:             "kotomi",
+		AccessTokenLifetime:  15 * 60,
+		RefreshTokenLifetime: 7 * 24 * 60 * 60,
+	}); err != nil {
+		t.Fatalf("Failed to create auth config: %v", err)
+	}
+
+	before = time.Now()
+	session, err = store.CreateSession(user, "test-secret")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	assertExpiresAfter(t, "access token", session.ExpiresAt, before, 15*time.Minute)
+	assertExpiresAfter(t, "refresh token", session.RefreshExpiresAt, before, 7*24*time.Hour)
+
+	// The JWT itself carries the same expiry
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(session.Token, claims); err != nil {
+		t.Fatalf("Failed to parse access token: %v", err)
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp.Unix() != session.ExpiresAt.Unix() {
+		t.Errorf("Expected token exp %v, got %v (%v)", session.ExpiresAt.Unix(), exp, err)
+	}
+
+	// Refreshing keeps using the site's lifetime
+	before = time.Now()
+	refreshed, err := store.RefreshSession(session, "test-secret", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+	assertExpiresAfter(t, "refreshed access token", refreshed.ExpiresAt, before, 15*time.Minute)
+}
+
+// assertExpiresAfter checks that expiresAt is ttl after a moment between start
+// and now
+func assertExpiresAfter(t *testing.T, name string, expiresAt, start time.Time, ttl time.Duration) {
+	t.Helper()
+	if expiresAt.Before(start.Add(ttl).Truncate(time.Second)) || expiresAt.After(time.Now().Add(ttl)) {
+		t.Errorf("Expected %s to expire %v after creation, got %v", name, ttl, expiresAt.Sub(start))
+	}
+}
+
+func TestDeleteAllSessionsForUser(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewKotomiAuthStore(db)
+
+	user, err := store.CreateOrUpdateUserFromAuth0("test-site", &UserInfo{
+		Sub:   "auth0|12345",
+		Email: "test@example.com",
+		Name:  "Test User",
+	})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUserFromAuth0 failed: %v", err)
+	}
+
+	var sessions []*KotomiAuthSession
+	for i := 0; i < 3; i++ {
+		session, err := store.CreateSession(user, "test-secret")
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	// A session with the same user ID on another site must survive
+	if _, err := db.Exec(`INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)`, "other-site", "test-owner", "Other"); err != nil {
+		t.Fatalf("Failed to insert site: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO kotomi_auth_sessions (id, user_id, site_id, token, refresh_token, expires_at, refresh_expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		"other-session", user.ID, "other-site", "other-token", "other-refresh", time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to insert other-site session: %v", err)
+	}
+
+	removed, err := store.DeleteAllSessionsForUser("test-site", user.ID)
+	if err != nil {
+		t.Fatalf("DeleteAllSessionsForUser failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("Expected 3 sessions removed, got %d", removed)
+	}
+
+	for _, session := range sessions {
+		if _, err := store.GetSessionByToken(session.Token); err == nil {
+			t.Errorf("Expected session %s to be deleted", session.ID)
+		}
+	}
+
+	if _, err := store.GetSessionByToken("other-token"); err != nil {
+		t.Errorf("Expected session on another site to remain: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/gorilla/sessions"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
 )
 
 const (
@@ -80,6 +81,7 @@ func RequireAuth(next http.Handler) http.Handler {
 
 		// Add user ID to request context
 		ctx := context.WithValue(r.Context(), SessionKeyUserID, userID)
+		logging.SetAuthenticatedUser(ctx, userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
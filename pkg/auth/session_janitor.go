@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartSessionJanitor periodically purges expired kotomi-auth sessions until ctx is
+// cancelled. It blocks, so run it in its own goroutine.
+func (s *KotomiAuthStore) StartSessionJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Println("Session janitor started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Session janitor stopping...")
+			return
+		case <-ticker.C:
+			removed, err := s.PurgeExpiredSessions()
+			if err != nil {
+				log.Printf("Error purging expired sessions: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("Purged %d expired sessions", removed)
+			}
+		}
+	}
+}
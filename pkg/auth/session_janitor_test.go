@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func insertTestSession(t *testing.T, store *KotomiAuthStore, id, userID string, refreshExpiresAt time.Time) {
+	t.Helper()
+	_, err := store.db.Exec(`INSERT INTO kotomi_auth_sessions (id, user_id, site_id, token, refresh_token, expires_at, refresh_expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, userID, "test-site", id+"-token", id+"-refresh", refreshExpiresAt, refreshExpiresAt)
+	if err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+}
+
+func TestPurgeExpiredSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewKotomiAuthStore(db)
+	user, err := store.CreateOrUpdateUserFromAuth0("test-site", &UserInfo{Sub: "auth0|1", Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUserFromAuth0 failed: %v", err)
+	}
+
+	insertTestSession(t, store, "expired", user.ID, time.Now().Add(-time.Hour))
+	insertTestSession(t, store, "valid", user.ID, time.Now().Add(time.Hour))
+
+	removed, err := store.PurgeExpiredSessions()
+	if err != nil {
+		t.Fatalf("PurgeExpiredSessions failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 session purged, got %d", removed)
+	}
+
+	if _, err := store.GetSessionByToken("expired-token"); err == nil {
+		t.Error("Expected expired session to be purged")
+	}
+	if _, err := store.GetSessionByToken("valid-token"); err != nil {
+		t.Errorf("Expected valid session to remain: %v", err)
+	}
+}
+
+func TestStartSessionJanitor(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := NewKotomiAuthStore(db)
+	user, err := store.CreateOrUpdateUserFromAuth0("test-site", &UserInfo{Sub: "auth0|1", Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUserFromAuth0 failed: %v", err)
+	}
+	insertTestSession(t, store, "expired", user.ID, time.Now().Add(-time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.StartSessionJanitor(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := store.GetSessionByToken("expired-token"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected janitor to purge the expired session")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected janitor to exit after context cancellation")
+	}
+}
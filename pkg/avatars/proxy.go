@@ -0,0 +1,290 @@
+// Package avatars serves third-party avatar images through the Kotomi origin,
+// so readers' browsers never contact the avatar hosts directly.
+package avatars
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultMaxBytes is the largest avatar image the proxy will serve
+const DefaultMaxBytes = 1 << 20
+
+// DefaultCacheTTL is how long a fetched avatar is reused
+const DefaultCacheTTL = 24 * time.Hour
+
+// DefaultMaxEntries caps the number of avatars held in memory
+const DefaultMaxEntries = 1000
+
+// defaultFetchTimeout bounds each upstream request
+const defaultFetchTimeout = 10 * time.Second
+
+// DefaultAllowedHosts are the avatar hosts used by common identity providers.
+// An entry starting with "*." also matches every subdomain.
+var DefaultAllowedHosts = []string{
+	"gravatar.com",
+	"*.gravatar.com",
+	"avatars.githubusercontent.com",
+	"*.googleusercontent.com",
+	"pbs.twimg.com",
+	"cdn.auth0.com",
+}
+
+// Config controls which avatars the proxy fetches and how long it keeps them
+type Config struct {
+	// AllowedHosts lists the hosts avatars may be fetched from
+	AllowedHosts []string
+	// MaxBytes is the largest image served; larger responses are rejected
+	MaxBytes int64
+	// CacheTTL is how long a fetched image is served from memory
+	CacheTTL time.Duration
+	// MaxEntries caps the number of cached images
+	MaxEntries int
+	// Client fetches images; defaults to a client with a 10 second timeout
+	Client *http.Client
+}
+
+// DefaultConfig returns the proxy settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		AllowedHosts: DefaultAllowedHosts,
+		MaxBytes:     DefaultMaxBytes,
+		CacheTTL:     DefaultCacheTTL,
+		MaxEntries:   DefaultMaxEntries,
+	}
+}
+
+// ConfigFromEnv returns the default settings overridden by
+// AVATAR_PROXY_ALLOWED_HOSTS (comma-separated), AVATAR_PROXY_MAX_BYTES and
+// AVATAR_PROXY_CACHE_TTL (a Go duration). Invalid values are logged and ignored.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	if value := os.Getenv("AVATAR_PROXY_ALLOWED_HOSTS"); value != "" {
+		var hosts []string
+		for _, host := range strings.Split(value, ",") {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+		cfg.AllowedHosts = hosts
+	}
+	if value := os.Getenv("AVATAR_PROXY_MAX_BYTES"); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			cfg.MaxBytes = n
+		} else {
+			log.Printf("Ignoring invalid AVATAR_PROXY_MAX_BYTES %q", value)
+		}
+	}
+	if value := os.Getenv("AVATAR_PROXY_CACHE_TTL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			cfg.CacheTTL = d
+		} else {
+			log.Printf("Ignoring invalid AVATAR_PROXY_CACHE_TTL %q", value)
+		}
+	}
+
+	return cfg
+}
+
+// Errors returned when an avatar can't be served
+var (
+	ErrInvalidURL     = errors.New("invalid avatar URL")
+	ErrHostNotAllowed = errors.New("avatar host not allowed")
+	ErrNotImage       = errors.New("avatar is not an image")
+	ErrTooLarge       = errors.New("avatar is too large")
+)
+
+// image is a cached avatar
+type image struct {
+	body        []byte
+	contentType string
+	expiresAt   time.Time
+}
+
+// Proxy is an http.Handler serving GET /avatar?u=<url>. It fetches images
+// from allowed hosts only, checks their type and size, and caches them in
+// memory. Concurrent misses for the same URL share one fetch.
+type Proxy struct {
+	config Config
+	client *http.Client
+
+	mu      sync.Mutex
+	entries map[string]image
+	group   singleflight.Group
+}
+
+// NewProxy creates an avatar proxy. Zero values in cfg fall back to the defaults.
+func NewProxy(cfg Config) *Proxy {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+
+	p := &Proxy{config: cfg, entries: make(map[string]image)}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultFetchTimeout}
+	}
+	// Copy the client so redirects are checked against the allowlist too
+	checked := *client
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !p.allowed(req.URL) {
+			return ErrHostNotAllowed
+		}
+		return nil
+	}
+	p.client = &checked
+
+	return p
+}
+
+// ServeHTTP serves the avatar named by the u query parameter
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target, err := url.Parse(r.URL.Query().Get("u"))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || target.User != nil {
+		http.Error(w, ErrInvalidURL.Error(), http.StatusBadRequest)
+		return
+	}
+	if !p.allowed(target) {
+		http.Error(w, ErrHostNotAllowed.Error(), http.StatusForbidden)
+		return
+	}
+
+	img, err := p.get(target.String())
+	if err != nil {
+		log.Printf("Avatar proxy failed for %s: %v", target.Host, err)
+		http.Error(w, "Failed to fetch avatar", http.StatusBadGateway)
+		return
+	}
+
+	maxAge := int(time.Until(img.expiresAt).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.body)))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.WriteHeader(http.StatusOK)
+	w.Write(img.body)
+}
+
+// allowed reports whether u's host is on the allowlist
+func (p *Proxy) allowed(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range p.config.AllowedHosts {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the avatar at rawURL from the cache, fetching it when missing or stale
+func (p *Proxy) get(rawURL string) (image, error) {
+	p.mu.Lock()
+	img, ok := p.entries[rawURL]
+	p.mu.Unlock()
+	if ok && time.Now().Before(img.expiresAt) {
+		return img, nil
+	}
+
+	value, err, _ := p.group.Do(rawURL, func() (interface{}, error) {
+		img, err := p.fetch(rawURL)
+		if err != nil {
+			return image{}, err
+		}
+
+		p.mu.Lock()
+		p.store(rawURL, img)
+		p.mu.Unlock()
+		return img, nil
+	})
+	if err != nil {
+		return image{}, err
+	}
+	return value.(image), nil
+}
+
+// fetch downloads an avatar, rejecting non-images and oversized bodies
+func (p *Proxy) fetch(rawURL string) (image, error) {
+	resp, err := p.client.Get(rawURL)
+	if err != nil {
+		return image{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return image{}, fmt.Errorf("upstream returned %d", resp.StatusCode)
+	}
+	if resp.ContentLength > p.config.MaxBytes {
+		return image{}, ErrTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.config.MaxBytes+1))
+	if err != nil {
+		return image{}, fmt.Errorf("failed to read avatar: %w", err)
+	}
+	if int64(len(body)) > p.config.MaxBytes {
+		return image{}, ErrTooLarge
+	}
+
+	// Both the declared and the sniffed type must be a raster image; SVG can
+	// carry scripts and is never served
+	declared, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	sniffed := http.DetectContentType(body)
+	if !strings.HasPrefix(declared, "image/") || declared == "image/svg+xml" || !strings.HasPrefix(sniffed, "image/") {
+		return image{}, ErrNotImage
+	}
+
+	return image{body: body, contentType: sniffed, expiresAt: time.Now().Add(p.config.CacheTTL)}, nil
+}
+
+// store caches an image, making room by dropping expired entries and then the
+// one closest to expiry; callers must hold p.mu
+func (p *Proxy) store(key string, img image) {
+	now := time.Now()
+	for k, entry := range p.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(p.entries, k)
+		}
+	}
+	if _, ok := p.entries[key]; !ok && len(p.entries) >= p.config.MaxEntries {
+		oldest := ""
+		for k, entry := range p.entries {
+			if oldest == "" || entry.expiresAt.Before(p.entries[oldest].expiresAt) {
+				oldest = k
+			}
+		}
+		delete(p.entries, oldest)
+	}
+	p.entries[key] = img
+}
@@ -0,0 +1,156 @@
+package avatars
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// newUpstream serves body with contentType and counts the requests it gets
+func newUpstream(t *testing.T, contentType string, body []byte) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func proxyRequest(p *Proxy, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/avatar?u="+url.QueryEscape(target), nil)
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestProxy_AllowedHost(t *testing.T) {
+	body := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 64)...)
+	upstream, hits := newUpstream(t, "image/png", body)
+	p := NewProxy(Config{AllowedHosts: []string{"127.0.0.1"}})
+
+	rr := proxyRequest(p, upstream.URL+"/a.png")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Expected image/png, got %q", got)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Cache-Control"), "public, max-age=") {
+		t.Errorf("Expected public cache headers, got %q", rr.Header().Get("Cache-Control"))
+	}
+	if !bytes.Equal(rr.Body.Bytes(), body) {
+		t.Error("Expected the upstream image to be served unchanged")
+	}
+
+	// The second request is served from the cache
+	if rr := proxyRequest(p, upstream.URL+"/a.png"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from cache, got %d", rr.Code)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", n)
+	}
+}
+
+func TestProxy_BlockedHost(t *testing.T) {
+	upstream, hits := newUpstream(t, "image/png", pngHeader)
+	p := NewProxy(Config{AllowedHosts: []string{"avatars.example.com"}})
+
+	if rr := proxyRequest(p, upstream.URL+"/a.png"); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", rr.Code)
+	}
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("Expected no upstream request, got %d", n)
+	}
+
+	for _, target := range []string{"", "ftp://avatars.example.com/a.png", "/relative.png", "https://user:pw@avatars.example.com/a.png"} {
+		if rr := proxyRequest(p, target); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", target, rr.Code)
+		}
+	}
+}
+
+func TestProxy_RedirectToBlockedHost(t *testing.T) {
+	blocked, hits := newUpstream(t, "image/png", pngHeader)
+	redirector := httptest.NewServer(http.RedirectHandler(strings.Replace(blocked.URL, "127.0.0.1", "localhost", 1)+"/a.png", http.StatusFound))
+	defer redirector.Close()
+	p := NewProxy(Config{AllowedHosts: []string{"127.0.0.1"}})
+
+	if rr := proxyRequest(p, redirector.URL); rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502, got %d", rr.Code)
+	}
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("Expected the redirect not to be followed, got %d requests", n)
+	}
+}
+
+func TestProxy_OversizedResponse(t *testing.T) {
+	body := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 2048)...)
+	upstream, _ := newUpstream(t, "image/png", body)
+	p := NewProxy(Config{AllowedHosts: []string{"127.0.0.1"}, MaxBytes: 1024})
+
+	if rr := proxyRequest(p, upstream.URL+"/big.png"); rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502, got %d", rr.Code)
+	}
+
+	// Without a Content-Length the body is still cut off at the limit
+	chunked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(body[:512])
+		w.(http.Flusher).Flush()
+		w.Write(body[512:])
+	}))
+	defer chunked.Close()
+	if rr := proxyRequest(p, chunked.URL+"/big.png"); rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 for a chunked response, got %d", rr.Code)
+	}
+}
+
+func TestProxy_RejectsNonImages(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"html", "text/html", []byte("<html><body>hotlink blocked</body></html>")},
+		{"svg", "image/svg+xml", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)},
+		{"mislabelled html", "image/png", []byte("<html><body>not a png</body></html>")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, _ := newUpstream(t, tt.contentType, tt.body)
+			p := NewProxy(Config{AllowedHosts: []string{"127.0.0.1"}})
+			if rr := proxyRequest(p, upstream.URL); rr.Code != http.StatusBadGateway {
+				t.Errorf("Expected 502, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestProxy_AllowedHostPatterns(t *testing.T) {
+	p := NewProxy(Config{AllowedHosts: []string{"gravatar.com", "*.googleusercontent.com"}})
+	tests := map[string]bool{
+		"https://gravatar.com/avatar/x":              true,
+		"https://GRAVATAR.com/avatar/x":              true,
+		"https://www.gravatar.com/avatar/x":          false,
+		"https://lh3.googleusercontent.com/a":        true,
+		"https://googleusercontent.com/a":            false,
+		"https://evilgoogleusercontent.com/a":        false,
+		"https://gravatar.com.evil.example/avatar/x": false,
+	}
+	for raw, want := range tests {
+		u, _ := url.Parse(raw)
+		if got := p.allowed(u); got != want {
+			t.Errorf("allowed(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxAttachmentsPerComment is how many images a single comment can carry
+const MaxAttachmentsPerComment = 4
+
+// maxAttachmentURLLength caps the length of an attachment URL, in bytes
+const maxAttachmentURLLength = 2048
+
+// maxAttachmentDimension caps the width and height reported for an attachment
+const maxAttachmentDimension = 20000
+
+// AllowedAttachmentMimeTypes are the image types comments can attach
+var AllowedAttachmentMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ErrInvalidAttachment is returned when a comment's attachments fail validation
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// Attachment is an image attached to a comment. Kotomi only stores the URL;
+// the image itself is hosted elsewhere.
+type Attachment struct {
+	ID        string    `json:"id"`
+	CommentID string    `json:"comment_id,omitempty"`
+	URL       string    `json:"url"`
+	MimeType  string    `json:"mime_type"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidateAttachments checks a comment's attachments against the per-comment
+// limit, the allowed image types and URL rules. Errors wrap ErrInvalidAttachment.
+func ValidateAttachments(attachments []Attachment) error {
+	if len(attachments) > MaxAttachmentsPerComment {
+		return fmt.Errorf("%w: a comment can have at most %d attachments", ErrInvalidAttachment, MaxAttachmentsPerComment)
+	}
+
+	for i, a := range attachments {
+		if !AllowedAttachmentMimeTypes[strings.ToLower(a.MimeType)] {
+			return fmt.Errorf("%w: attachment %d has unsupported mime_type %q", ErrInvalidAttachment, i+1, a.MimeType)
+		}
+		if len(a.URL) > maxAttachmentURLLength {
+			return fmt.Errorf("%w: attachment %d url is too long", ErrInvalidAttachment, i+1)
+		}
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%w: attachment %d url must be an absolute http(s) URL", ErrInvalidAttachment, i+1)
+		}
+		if a.Width < 0 || a.Height < 0 || a.Width > maxAttachmentDimension || a.Height > maxAttachmentDimension {
+			return fmt.Errorf("%w: attachment %d has invalid dimensions", ErrInvalidAttachment, i+1)
+		}
+	}
+
+	return nil
+}
+
+// insertAttachments stores a new comment's attachments within tx
+func insertAttachments(ctx context.Context, tx *sql.Tx, commentID string, attachments []Attachment, createdAt time.Time) error {
+	for _, a := range attachments {
+		id := a.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO attachments (id, comment_id, url, mime_type, width, height, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, commentID, a.URL, strings.ToLower(a.MimeType), a.Width, a.Height, createdAt); err != nil {
+			return fmt.Errorf("failed to insert attachment: %w", err)
+		}
+	}
+	return nil
+}
+
+// attachmentBatchSize caps the comment IDs bound into a single loadAttachments
+// query, keeping well under SQLite's variable limit
+const attachmentBatchSize = 500
+
+// loadAttachments fills in the attachments of every comment in comments,
+// fetching them in batches rather than once per comment
+func (s *SQLiteStore) loadAttachments(ctx context.Context, comments []Comment) error {
+	index := make(map[string]int, len(comments))
+	ids := make([]string, len(comments))
+	for i, c := range comments {
+		index[c.ID] = i
+		ids[i] = c.ID
+	}
+
+	for start := 0; start < len(ids); start += attachmentBatchSize {
+		end := start + attachmentBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+
+		query := fmt.Sprintf(`
+			SELECT id, comment_id, url, mime_type, width, height, created_at
+			FROM attachments
+			WHERE comment_id IN (%s)
+			ORDER BY created_at ASC, rowid ASC
+		`, strings.Join(placeholders, ", "))
+
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query attachments: %w", err)
+		}
+		for rows.Next() {
+			var a Attachment
+			if err := rows.Scan(&a.ID, &a.CommentID, &a.URL, &a.MimeType, &a.Width, &a.Height, &a.CreatedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan attachment: %w", err)
+			}
+			i := index[a.CommentID]
+			comments[i].Attachments = append(comments[i].Attachments, a)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("error iterating attachments: %w", err)
+		}
+	}
+
+	return nil
+}
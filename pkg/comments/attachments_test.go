@@ -0,0 +1,107 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// imageAttachments returns n valid PNG attachments
+func imageAttachments(n int) []Attachment {
+	attachments := make([]Attachment, n)
+	for i := range attachments {
+		attachments[i] = Attachment{URL: fmt.Sprintf("https://images.example.com/%d.png", i), MimeType: "image/png", Width: 640, Height: 480}
+	}
+	return attachments
+}
+
+func TestValidateAttachments(t *testing.T) {
+	tests := []struct {
+		name        string
+		attachments []Attachment
+		wantErr     bool
+	}{
+		{"none", nil, false},
+		{"at the limit", imageAttachments(MaxAttachmentsPerComment), false},
+		{"over the limit", imageAttachments(MaxAttachmentsPerComment + 1), true},
+		{"uppercase mime type", []Attachment{{URL: "https://images.example.com/a.jpg", MimeType: "IMAGE/JPEG"}}, false},
+		{"unsupported mime type", []Attachment{{URL: "https://images.example.com/a.svg", MimeType: "image/svg+xml"}}, true},
+		{"relative url", []Attachment{{URL: "/a.png", MimeType: "image/png"}}, true},
+		{"javascript url", []Attachment{{URL: "javascript:alert(1)", MimeType: "image/png"}}, true},
+		{"negative width", []Attachment{{URL: "https://images.example.com/a.png", MimeType: "image/png", Width: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAttachments(tt.attachments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAttachments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAttachment) {
+				t.Errorf("Expected ErrInvalidAttachment, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStore_Attachments(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	for i, n := range []int{2, 0, 1} {
+		c := Comment{ID: fmt.Sprintf("c%d", i), Author: "A", Text: "Look", Status: "approved", Attachments: imageAttachments(n)}
+		if err := store.AddPageComment(ctx, "site1", "page1", c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	got, err := store.GetPageComments(ctx, "site1", "page1")
+	if err != nil {
+		t.Fatalf("GetPageComments failed: %v", err)
+	}
+	counts := map[string]int{}
+	for _, c := range got {
+		counts[c.ID] = len(c.Attachments)
+		for _, a := range c.Attachments {
+			if a.ID == "" || a.CommentID != c.ID || a.MimeType != "image/png" || a.Width != 640 {
+				t.Errorf("Unexpected attachment on %s: %+v", c.ID, a)
+			}
+		}
+	}
+	if counts["c0"] != 2 || counts["c1"] != 0 || counts["c2"] != 1 {
+		t.Errorf("Expected 2, 0 and 1 attachments, got %v", counts)
+	}
+
+	single, err := store.GetCommentByID(ctx, "c0")
+	if err != nil {
+		t.Fatalf("GetCommentByID failed: %v", err)
+	}
+	if len(single.Attachments) != 2 || single.Attachments[0].URL != "https://images.example.com/0.png" {
+		t.Errorf("Expected the comment's attachments in order, got %+v", single.Attachments)
+	}
+
+	t.Run("over the limit", func(t *testing.T) {
+		c := Comment{ID: "too-many", Author: "A", Text: "Gallery", Attachments: imageAttachments(MaxAttachmentsPerComment + 1)}
+		if err := store.AddPageComment(ctx, "site1", "page1", c); !errors.Is(err, ErrInvalidAttachment) {
+			t.Fatalf("Expected ErrInvalidAttachment, got %v", err)
+		}
+		if _, err := store.GetCommentByID(ctx, "too-many"); err == nil {
+			t.Error("Expected the comment not to be stored")
+		}
+	})
+
+	t.Run("deleted with the comment", func(t *testing.T) {
+		if err := store.DeleteComment(ctx, "c0"); err != nil {
+			t.Fatalf("DeleteComment failed: %v", err)
+		}
+		var remaining int
+		if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM attachments WHERE comment_id = 'c0'`).Scan(&remaining); err != nil {
+			t.Fatalf("Failed to count attachments: %v", err)
+		}
+		if remaining != 0 {
+			t.Errorf("Expected the comment's attachments to be deleted, %d remain", remaining)
+		}
+	})
+}
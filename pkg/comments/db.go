@@ -18,13 +18,20 @@ type Comment struct {
 	Author             string    `json:"author"`
 	AuthorID           string    `json:"author_id"`
 	AuthorEmail        string    `json:"author_email,omitempty"`
+	AuthorAvatarURL    string    `json:"author_avatar_url,omitempty"`    // The author's own avatar, when they have one
 	AuthorVerified     bool      `json:"author_verified,omitempty"`      // Phase 3: Show user verification status
 	AuthorReputation   int       `json:"author_reputation,omitempty"`    // Phase 3: Show user reputation
+	ReportCount        int       `json:"report_count,omitempty"`         // Set by the admin list so owners can prioritize
+	PageID             string    `json:"page_id,omitempty"`              // Set by GetCommentsByAuthor, where comments span pages
+	PagePath           string    `json:"page_path,omitempty"`            // Set by GetCommentsByAuthor
 	Text               string    `json:"text"`
 	ParentID           string    `json:"parent_id,omitempty"`
 	Status             string    `json:"status"`
 	ModeratedBy        string    `json:"moderated_by,omitempty"`
 	ModeratedAt        time.Time `json:"moderated_at,omitempty"`
+	Language           string    `json:"language,omitempty"`            // ISO 639-1 code detected when posted; empty if unknown
+	Sentiment          string    `json:"sentiment,omitempty"`           // positive, neutral or negative when AI moderation reported one
+	Attachments        []Attachment `json:"attachments,omitempty"`
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
@@ -0,0 +1,108 @@
+package comments
+
+import (
+	"strings"
+	"unicode"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Excerpt returns a plain-text excerpt of the comment of at most maxRunes
+// characters; see the package-level Excerpt
+func (c Comment) Excerpt(maxRunes int) string {
+	return Excerpt(c.Text, maxRunes)
+}
+
+// Excerpt strips markdown and HTML from text, collapses whitespace and
+// shortens it to at most maxRunes characters. A cut that would land inside a
+// word backs up to the previous word boundary, and truncated text ends with
+// an ellipsis. Lengths are counted in runes, so multibyte text is never split
+// mid-character.
+func Excerpt(text string, maxRunes int) string {
+	if maxRunes < 1 {
+		return ""
+	}
+
+	runes := []rune(strings.Join(strings.Fields(PlainText(text)), " "))
+	if len(runes) <= maxRunes {
+		return string(runes)
+	}
+
+	cut := runes[:maxRunes]
+	if runes[maxRunes] != ' ' {
+		// Text without spaces (e.g. Japanese) is cut at the limit instead
+		for i := len(cut) - 1; i > 0; i-- {
+			if cut[i] == ' ' {
+				cut = cut[:i]
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(cut), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// PlainText returns comment text with its markdown and HTML markup removed and
+// entities decoded. Block boundaries become spaces; scripts and other
+// elements SanitizeHTML drops are removed with their contents.
+func PlainText(text string) string {
+	// Raw HTML is removed first so the markdown renderer doesn't keep it as
+	// text, then the renderer's own tags are removed
+	return htmlText(RenderMarkdown(htmlText(text)))
+}
+
+// inlineHTMLTags don't separate words, so PlainText removes them without
+// leaving a space
+var inlineHTMLTags = map[string]bool{
+	"a": true, "strong": true, "b": true, "em": true, "i": true, "code": true,
+	"span": true, "u": true, "s": true, "small": true, "mark": true,
+}
+
+// htmlText returns the text content of an HTML fragment
+func htmlText(text string) string {
+	var out strings.Builder
+	skipping := ""
+	skipDepth := 0
+
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(text))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		if skipping != "" {
+			switch {
+			case tokenType == xhtml.StartTagToken && token.Data == skipping:
+				skipDepth++
+			case tokenType == xhtml.EndTagToken && token.Data == skipping:
+				skipDepth--
+				if skipDepth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+
+		switch tokenType {
+		case xhtml.TextToken:
+			out.WriteString(token.Data)
+		case xhtml.StartTagToken:
+			if droppedContentTags[token.Data] {
+				skipping = token.Data
+				skipDepth = 1
+			}
+			if !inlineHTMLTags[token.Data] {
+				out.WriteString(" ")
+			}
+		case xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			if !inlineHTMLTags[token.Data] {
+				out.WriteString(" ")
+			}
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,39 @@
+package comments
+
+import "testing"
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     string
+	}{
+		{"short text unchanged", "Great post!", 50, "Great post!"},
+		{"exact length unchanged", "Great post!", 11, "Great post!"},
+		{"cuts on a word boundary", "The quick brown fox jumps over the lazy dog", 18, "The quick brown…"},
+		{"cut at a space keeps the whole word", "The quick brown fox", 15, "The quick brown…"},
+		{"trailing punctuation dropped", "Hello, world and everyone", 7, "Hello…"},
+		{"collapses whitespace", "  Hello\n\n   there\tfriend  ", 50, "Hello there friend"},
+		{"strips markdown", "**Bold** and *italic* with [a link](https://example.com) and `code`", 100, "Bold and italic with a link and code"},
+		{"strips lists", "- one\n- two", 50, "one two"},
+		{"strips html", `<p>Hello <b>there</b></p><script>alert(1)</script><p>friend &amp; co</p>`, 50, "Hello there friend & co"},
+		{"counts runes", "日本語のコメントです", 5, "日本語のコ…"},
+		{"multibyte word boundary", "café crème brûlée", 12, "café crème…"},
+		{"non-positive limit", "Hello", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.text, tt.maxRunes); got != tt.want {
+				t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComment_Excerpt(t *testing.T) {
+	c := Comment{Text: "Thanks for the *detailed* write-up, it helped a lot"}
+	if got := c.Excerpt(24); got != "Thanks for the detailed…" {
+		t.Errorf("Excerpt() = %q", got)
+	}
+}
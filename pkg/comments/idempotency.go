@@ -0,0 +1,99 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long an Idempotency-Key is remembered after the
+// comment it created
+const DefaultIdempotencyTTL = time.Hour
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key header accepted
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyStore maps (site, user, Idempotency-Key) to the comment created for
+// it so retried POSTs don't create duplicates
+type IdempotencyStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewIdempotencyStore creates an idempotency key store. A ttl <= 0 uses
+// DefaultIdempotencyTTL.
+func NewIdempotencyStore(db *sql.DB, ttl time.Duration) *IdempotencyStore {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyStore{db: db, ttl: ttl}
+}
+
+// ValidateIdempotencyKey checks an Idempotency-Key header value
+func ValidateIdempotencyKey(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("idempotency key must not be empty")
+	}
+	if len(key) > MaxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key must be at most %d characters", MaxIdempotencyKeyLength)
+	}
+	return nil
+}
+
+// Reserve claims key for commentID. If the key was already used by the same user
+// on the site within the TTL, it returns the comment ID stored for it and false;
+// expired keys are reclaimed.
+func (s *IdempotencyStore) Reserve(ctx context.Context, siteID, userID, key, commentID string) (string, bool, error) {
+	now := time.Now()
+	cutoff := now.Add(-s.ttl)
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM comment_idempotency_keys WHERE created_at < ?`, cutoff); err != nil {
+		return "", false, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO comment_idempotency_keys (site_id, user_id, idempotency_key, comment_id, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(site_id, user_id, idempotency_key) DO UPDATE SET
+			comment_id = excluded.comment_id,
+			created_at = excluded.created_at
+		WHERE comment_idempotency_keys.created_at < ?
+	`, siteID, userID, key, commentID, now, cutoff)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if rows > 0 {
+		return commentID, true, nil
+	}
+
+	var existingID string
+	err = s.db.QueryRowContext(ctx, `
+		SELECT comment_id FROM comment_idempotency_keys
+		WHERE site_id = ? AND user_id = ? AND idempotency_key = ?
+	`, siteID, userID, key).Scan(&existingID)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	return existingID, false, nil
+}
+
+// Release forgets a reserved key, used when creating its comment failed so the
+// client can retry with the same key
+func (s *IdempotencyStore) Release(ctx context.Context, siteID, userID, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM comment_idempotency_keys
+		WHERE site_id = ? AND user_id = ? AND idempotency_key = ?
+	`, siteID, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
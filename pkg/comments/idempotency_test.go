@@ -0,0 +1,113 @@
+package comments
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore_ReplayReturnsSameComment(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	idempotency := NewIdempotencyStore(store.GetDB(), time.Hour)
+
+	id, reserved, err := idempotency.Reserve(ctx, "site-1", "user-1", "key-1", "comment-1")
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if !reserved || id != "comment-1" {
+		t.Fatalf("Expected first use of key to reserve comment-1, got %q reserved=%v", id, reserved)
+	}
+
+	// A retry carries a fresh comment ID but must resolve to the original one
+	id, reserved, err = idempotency.Reserve(ctx, "site-1", "user-1", "key-1", "comment-2")
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if reserved || id != "comment-1" {
+		t.Errorf("Expected replay to return comment-1, got %q reserved=%v", id, reserved)
+	}
+}
+
+func TestIdempotencyStore_NewKeyCreatesNewComment(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	idempotency := NewIdempotencyStore(store.GetDB(), time.Hour)
+
+	if _, _, err := idempotency.Reserve(ctx, "site-1", "user-1", "key-1", "comment-1"); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		site, user, key string
+	}{
+		{"different key", "site-1", "user-1", "key-2"},
+		{"same key from another user", "site-1", "user-2", "key-1"},
+		{"same key on another site", "site-2", "user-1", "key-1"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commentID := "comment-new-" + string(rune('a'+i))
+			id, reserved, err := idempotency.Reserve(ctx, tt.site, tt.user, tt.key, commentID)
+			if err != nil {
+				t.Fatalf("Reserve failed: %v", err)
+			}
+			if !reserved || id != commentID {
+				t.Errorf("Expected a new reservation for %s, got %q reserved=%v", commentID, id, reserved)
+			}
+		})
+	}
+}
+
+func TestIdempotencyStore_ExpiryAndRelease(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	idempotency := NewIdempotencyStore(store.GetDB(), time.Hour)
+
+	// Keys older than the TTL are reclaimed
+	_, err := store.GetDB().Exec(`INSERT INTO comment_idempotency_keys (site_id, user_id, idempotency_key, comment_id, created_at)
+		VALUES (?, ?, ?, ?, ?)`, "site-1", "user-1", "old-key", "comment-old", time.Now().Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to insert expired key: %v", err)
+	}
+	id, reserved, err := idempotency.Reserve(ctx, "site-1", "user-1", "old-key", "comment-1")
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if !reserved || id != "comment-1" {
+		t.Errorf("Expected expired key to be reclaimed, got %q reserved=%v", id, reserved)
+	}
+
+	// Released keys can be reserved again
+	if err := idempotency.Release(ctx, "site-1", "user-1", "old-key"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	id, reserved, err = idempotency.Reserve(ctx, "site-1", "user-1", "old-key", "comment-2")
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if !reserved || id != "comment-2" {
+		t.Errorf("Expected released key to be reserved again, got %q reserved=%v", id, reserved)
+	}
+}
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	if err := ValidateIdempotencyKey("8f14e45f-ceea-467f-a0e6-7d2f6d3c9a1b"); err != nil {
+		t.Errorf("Expected valid key, got %v", err)
+	}
+	if err := ValidateIdempotencyKey("   "); err == nil {
+		t.Error("Expected error for blank key")
+	}
+	if err := ValidateIdempotencyKey(strings.Repeat("k", MaxIdempotencyKeyLength+1)); err == nil {
+		t.Error("Expected error for overlong key")
+	}
+}
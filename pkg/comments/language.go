@@ -0,0 +1,127 @@
+package comments
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Language detection is a cheap best-effort guess. Text in a non-Latin script
+// is labelled by its script; Latin-script text is scored against short lists
+// of each language's most frequent words. Results are ISO 639-1 codes, or ""
+// when the text is too short or too ambiguous to call.
+
+// scriptLanguages maps non-Latin scripts to the language they most likely mean
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// commonWords lists frequent function words per Latin-script language
+var commonWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "this", "that", "with", "for", "you", "have", "not", "it", "of", "to", "in", "on", "my", "but", "what"},
+	"es": {"el", "la", "los", "las", "es", "y", "de", "que", "en", "un", "una", "por", "con", "para", "muy", "pero", "como", "mi", "del", "gracias"},
+	"fr": {"le", "la", "les", "est", "et", "de", "que", "un", "une", "des", "pour", "avec", "pas", "très", "mais", "je", "vous", "ce", "du", "merci"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "ein", "eine", "mit", "für", "auf", "sehr", "aber", "es", "zu", "den", "dem", "sie", "danke"},
+	"pt": {"o", "os", "as", "é", "e", "de", "que", "um", "uma", "não", "para", "com", "muito", "mas", "em", "do", "da", "isso", "você", "obrigado"},
+	"it": {"il", "lo", "gli", "è", "e", "di", "che", "un", "una", "non", "per", "con", "molto", "ma", "sono", "questo", "della", "del", "mi", "grazie"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "ik", "van", "dat", "met", "voor", "op", "zijn", "maar", "ook", "je", "dit", "heel", "wat", "bedankt"},
+}
+
+// commonWordIndex maps each common word to the languages that use it
+var commonWordIndex = func() map[string][]string {
+	index := map[string][]string{}
+	for language, words := range commonWords {
+		for _, word := range words {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// minLanguageLetters is the fewest letters worth guessing a language from
+const minLanguageLetters = 8
+
+// DetectLanguage guesses the language of text, returning an ISO 639-1 code or
+// "" when it can't tell
+func DetectLanguage(text string) string {
+	letters := 0
+	scripts := map[string]int{}
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				scripts[s.language]++
+				break
+			}
+		}
+	}
+
+	if letters == 0 {
+		return ""
+	}
+
+	// Kana only appear in Japanese, which also uses Han characters
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > letters/2 {
+		return "ja"
+	}
+	best, bestCount := "", 0
+	for language, count := range scripts {
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	// A few non-Latin letters can identify a language without reaching the Latin minimum
+	if bestCount > letters/2 {
+		if best == "ru" && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return "uk"
+		}
+		return best
+	}
+
+	if letters < minLanguageLetters {
+		return ""
+	}
+	return detectLatinLanguage(text)
+}
+
+// detectLatinLanguage scores text by how many of its words are common in each
+// language. The top language needs at least two hits and a clear lead.
+func detectLatinLanguage(text string) string {
+	scores := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, language := range commonWordIndex[word] {
+			scores[language]++
+		}
+	}
+
+	best, bestScore, runnerUp := "", 0, 0
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUp = language, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if bestScore < 2 || bestScore == runnerUp {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,40 @@
+package comments
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "This is a great post and I think the examples are really clear.", "en"},
+		{"spanish", "Muy buen artículo, gracias por compartir la información con todos.", "es"},
+		{"german", "Das ist ein sehr guter Artikel und ich danke dir für die Mühe.", "de"},
+		{"japanese", "この記事はとても分かりやすかったです。ありがとう！", "ja"},
+		{"chinese", "这篇文章写得很好，谢谢分享。", "zh"},
+		{"russian", "Отличная статья, спасибо за подробное объяснение.", "ru"},
+		{"ukrainian", "Дякую, це дуже цікава стаття про їжу.", "uk"},
+		{"korean", "정말 좋은 글이네요. 감사합니다.", "ko"},
+		{"empty", "", ""},
+		{"too short", "ok thx", ""},
+		{"no common words", "Lorem ipsum dolor sit amet consectetur", ""},
+		{"only links and numbers", "https://example.com 12345", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage_DistinguishesScripts(t *testing.T) {
+	english := DetectLanguage("Thanks for writing this, it was exactly what I needed.")
+	russian := DetectLanguage("Спасибо, это именно то, что мне было нужно.")
+	if english == "" || russian == "" || english == russian {
+		t.Errorf("Expected distinct language codes, got %q and %q", english, russian)
+	}
+}
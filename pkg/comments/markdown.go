@@ -0,0 +1,153 @@
+package comments
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Markdown rendering supports a deliberately small subset: paragraphs, line
+// breaks, **bold**, *italic*, `code`, fenced code blocks, [links](https://...)
+// and bulleted or numbered lists. All input is HTML-escaped before any tag is
+// emitted, so the only elements in the output are the ones the renderer writes
+// itself; raw HTML, scripts, iframes and event handlers come out as text.
+
+var (
+	fencePattern       = regexp.MustCompile("^\\s*```")
+	bulletPattern      = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	codeSpanPattern    = regexp.MustCompile("`([^`]+)`")
+	linkPattern        = regexp.MustCompile(`\[([^\[\]]+)\]\(([^()\s]+)\)`)
+	boldStarPattern    = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	boldUnderPattern   = regexp.MustCompile(`\b__(\S(?:.*?\S)?)__\b`)
+	italicStarPattern  = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`)
+	italicUnderPattern = regexp.MustCompile(`\b_(\S(?:.*?\S)?)_\b`)
+)
+
+// allowedLinkSchemes lists the URL schemes links may use
+var allowedLinkSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// RenderMarkdown converts comment text to sanitized HTML
+func RenderMarkdown(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+
+	flushParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		rendered := make([]string, len(paragraph))
+		for i, line := range paragraph {
+			rendered[i] = renderInline(strings.TrimSpace(line))
+		}
+		out.WriteString("<p>" + strings.Join(rendered, "<br>\n") + "</p>\n")
+		paragraph = nil
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if fencePattern.MatchString(line) {
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !fencePattern.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+
+		tag, item := "", ""
+		if m := bulletPattern.FindStringSubmatch(line); m != nil {
+			tag, item = "ul", m[1]
+		} else if m := orderedPattern.FindStringSubmatch(line); m != nil {
+			tag, item = "ol", m[1]
+		}
+		if tag != "" {
+			flushParagraph()
+			if listTag != tag {
+				closeList()
+				out.WriteString("<" + tag + ">\n")
+				listTag = tag
+			}
+			out.WriteString("<li>" + renderInline(item) + "</li>\n")
+			continue
+		}
+
+		closeList()
+		paragraph = append(paragraph, line)
+	}
+	flushParagraph()
+	closeList()
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// renderInline renders code spans, links and emphasis within a single line
+func renderInline(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range codeSpanPattern.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(renderLinks(text[last:m[0]]))
+		out.WriteString("<code>" + html.EscapeString(text[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	out.WriteString(renderLinks(text[last:]))
+	return out.String()
+}
+
+// renderLinks renders [text](url) links whose URL uses an allowed scheme. Links
+// with any other URL are reduced to their text.
+func renderLinks(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(renderEmphasis(html.EscapeString(text[last:m[0]])))
+		label := renderEmphasis(html.EscapeString(text[m[2]:m[3]]))
+		if href, ok := safeLinkURL(text[m[4]:m[5]]); ok {
+			out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener" target="_blank">` + label + "</a>")
+		} else {
+			out.WriteString(label)
+		}
+		last = m[1]
+	}
+	out.WriteString(renderEmphasis(html.EscapeString(text[last:])))
+	return out.String()
+}
+
+// renderEmphasis applies bold and italic markers to already escaped text
+func renderEmphasis(escaped string) string {
+	escaped = boldStarPattern.ReplaceAllString(escaped, "<strong>$1</strong>")
+	escaped = boldUnderPattern.ReplaceAllString(escaped, "<strong>$1</strong>")
+	escaped = italicStarPattern.ReplaceAllString(escaped, "<em>$1</em>")
+	return italicUnderPattern.ReplaceAllString(escaped, "<em>$1</em>")
+}
+
+// safeLinkURL returns the normalized URL if it is absolute and uses an allowed scheme
+func safeLinkURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || !allowedLinkSchemes[u.Scheme] {
+		return "", false
+	}
+	if u.Scheme != "mailto" && u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
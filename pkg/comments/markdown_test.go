@@ -0,0 +1,77 @@
+package comments
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown_Formatting(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "Hello world", "<p>Hello world</p>"},
+		{"bold and italic", "**bold** and *italic* and _also_", "<p><strong>bold</strong> and <em>italic</em> and <em>also</em></p>"},
+		{"inline code", "use `a < b` here", "<p>use <code>a &lt; b</code> here</p>"},
+		{"code keeps markers", "`**not bold**`", "<p><code>**not bold**</code></p>"},
+		{"link", "[Kotomi](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener" target="_blank">Kotomi</a></p>`},
+		{"line breaks and paragraphs", "one\ntwo\n\nthree", "<p>one<br>\ntwo</p>\n<p>three</p>"},
+		{"bullet list", "- a\n- **b**", "<ul>\n<li>a</li>\n<li><strong>b</strong></li>\n</ul>"},
+		{"numbered list", "1. first\n2. second", "<ol>\n<li>first</li>\n<li>second</li>\n</ol>"},
+		{"fenced code", "```\n<b>x</b>\n```", "<pre><code>&lt;b&gt;x&lt;/b&gt;</code></pre>"},
+		{"snake case untouched", "my_var_name", "<p>my_var_name</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.input); got != tt.want {
+				t.Errorf("RenderMarkdown(%q)\ngot:  %q\nwant: %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown_NeutralizesMaliciousInput(t *testing.T) {
+	inputs := []string{
+		`<script>alert(1)</script>`,
+		`<iframe src="https://evil.example"></iframe>`,
+		`<img src=x onerror="alert(1)">`,
+		`<a href="javascript:alert(1)">click</a>`,
+		`[click](javascript:alert(1))`,
+		`[click](JaVaScRiPt:alert(1))`,
+		`[click](data:text/html;base64,PHNjcmlwdD4=)`,
+		`[click](vbscript:msgbox)`,
+		`[x](https://example.com/"onmouseover="alert(1))`,
+		`**<script>alert(1)</script>**`,
+		"- <svg onload=alert(1)>",
+		"```\n</code></pre><script>alert(1)</script>\n```",
+		"`</code><script>alert(1)</script>`",
+		`[<img src=x onerror=alert(1)>](https://example.com)`,
+	}
+
+	for _, input := range inputs {
+		got := RenderMarkdown(input)
+		// Only the renderer's own tags may appear, and only links carry attributes
+		for _, tag := range tagPattern.FindAllString(got, -1) {
+			if !allowedTagPattern.MatchString(tag) && !safeLinkTagPattern.MatchString(tag) {
+				t.Errorf("RenderMarkdown(%q) emitted unexpected tag %s: %s", input, tag, got)
+			}
+		}
+	}
+}
+
+func TestRenderMarkdown_LinkAttributes(t *testing.T) {
+	got := RenderMarkdown("see [docs](http://example.com) and [mail](mailto:a@example.com)")
+	if strings.Count(got, `rel="nofollow noopener" target="_blank"`) != 2 {
+		t.Errorf("Expected every link to get rel and target attributes, got %s", got)
+	}
+}
+
+// Patterns describing the only markup RenderMarkdown may produce
+var (
+	tagPattern         = regexp.MustCompile(`<[^>]*>`)
+	allowedTagPattern  = regexp.MustCompile(`^</?(p|br|strong|em|code|pre|ul|ol|li|a)>$`)
+	safeLinkTagPattern = regexp.MustCompile(`^<a href="(https?://|mailto:)[^"<>]*" rel="nofollow noopener" target="_blank">$`)
+)
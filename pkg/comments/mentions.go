@@ -0,0 +1,31 @@
+package comments
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @name tokens that aren't part of a word or an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w][\w.-]*)`)
+
+// ParseMentions returns the distinct names mentioned with @name in text,
+// lowercased and in the order they first appear
+func ParseMentions(text string) []string {
+	var mentions []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		mentions = append(mentions, name)
+	}
+	return mentions
+}
+
+// MentionHandle returns the form of a display name that @mentions match
+// against: lowercased with whitespace removed, so "Jane Doe" is @janedoe
+func MentionHandle(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ""))
+}
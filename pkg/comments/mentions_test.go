@@ -0,0 +1,35 @@
+package comments
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"none", "no mentions here", nil},
+		{"single", "@alice thanks!", []string{"alice"}},
+		{"several", "cc @Bob and @carol.", []string{"bob", "carol"}},
+		{"deduplicated", "@alice @ALICE @alice", []string{"alice"}},
+		{"email ignored", "mail me at alice@example.com", nil},
+		{"punctuation", "(@dave), @erin-", []string{"dave", "erin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionHandle(t *testing.T) {
+	if got := MentionHandle("  Jane   Doe "); got != "janedoe" {
+		t.Errorf("Expected janedoe, got %q", got)
+	}
+}
@@ -2,6 +2,10 @@ package comments
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -96,6 +100,225 @@ func TestSQLiteStore_GetCommentsBySite(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_GetCommentsBySiteFiltered_Paging(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		c := Comment{
+			ID:        fmt.Sprintf("c%d", i),
+			Author:    "John",
+			AuthorID:  "user1",
+			Text:      "Comment",
+			Status:    "pending",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := store.AddPageComment(context.Background(), "site1", "page1", c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   []string
+	}{
+		{"first page", 2, 0, []string{"c4", "c3"}},
+		{"middle page", 2, 2, []string{"c2", "c1"}},
+		{"partial last page", 2, 4, []string{"c0"}},
+		{"offset at total", 2, 5, []string{}},
+		{"offset past total", 2, 10, []string{}},
+		{"no limit", 0, 3, []string{"c1", "c0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.GetCommentsBySiteFiltered(context.Background(), "site1", SiteCommentFilter{Limit: tt.limit, Offset: tt.offset})
+			if err != nil {
+				t.Fatalf("GetCommentsBySiteFiltered failed: %v", err)
+			}
+			if page.Total != 5 {
+				t.Errorf("Expected total 5, got %d", page.Total)
+			}
+			var got []string
+			for _, c := range page.Comments {
+				got = append(got, c.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := store.GetCommentsBySiteFiltered(context.Background(), "site1", SiteCommentFilter{Limit: -1}); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+}
+
+func TestSQLiteStore_GetCommentsBySiteFiltered_CombinedFilters(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+	comments := []Comment{
+		{ID: "1", Author: "John", AuthorID: "john", Text: "a", Status: "pending", CreatedAt: day1},
+		{ID: "2", Author: "John", AuthorID: "john", Text: "b", Status: "approved", CreatedAt: day2},
+		{ID: "3", Author: "John", AuthorID: "john", Text: "c", Status: "pending", CreatedAt: day2},
+		{ID: "4", Author: "Jane", AuthorID: "jane", Text: "d", Status: "pending", CreatedAt: day2},
+		{ID: "5", Author: "John", AuthorID: "john", Text: "e", Status: "pending", CreatedAt: day3},
+	}
+	for _, c := range comments {
+		if err := store.AddPageComment(context.Background(), "site1", "page1", c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+	// Another site's comments never leak into the results
+	other := Comment{ID: "6", Author: "John", AuthorID: "john", Text: "f", Status: "pending", CreatedAt: day2}
+	if err := store.AddPageComment(context.Background(), "site2", "page1", other); err != nil {
+		t.Fatalf("AddPageComment failed: %v", err)
+	}
+
+	page, err := store.GetCommentsBySiteFiltered(context.Background(), "site1", SiteCommentFilter{
+		Status:   "pending",
+		AuthorID: "john",
+		From:     day2.Add(-time.Hour),
+		To:       day3.Add(-time.Hour),
+		Limit:    10,
+	})
+	if err != nil {
+		t.Fatalf("GetCommentsBySiteFiltered failed: %v", err)
+	}
+	if page.Total != 1 || len(page.Comments) != 1 || page.Comments[0].ID != "3" {
+		t.Errorf("Expected only comment 3, got total %d: %+v", page.Total, page.Comments)
+	}
+
+	// The date range bounds are inclusive and the total ignores paging
+	page, err = store.GetCommentsBySiteFiltered(context.Background(), "site1", SiteCommentFilter{
+		AuthorID: "john",
+		From:     day1,
+		To:       day2,
+		Limit:    1,
+	})
+	if err != nil {
+		t.Fatalf("GetCommentsBySiteFiltered failed: %v", err)
+	}
+	if page.Total != 3 {
+		t.Errorf("Expected total 3, got %d", page.Total)
+	}
+	if len(page.Comments) != 1 {
+		t.Errorf("Expected 1 comment on the page, got %d", len(page.Comments))
+	}
+}
+
+func TestSQLiteStore_GetCommentsBySiteFiltered_Language(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	now := time.Now()
+	comments := []Comment{
+		{ID: "1", Author: "John", Text: "Thanks for the post", Status: "approved", Language: "en", Sentiment: "positive", CreatedAt: now},
+		{ID: "2", Author: "Taro", Text: "ありがとう", Status: "approved", Language: "ja", CreatedAt: now},
+		{ID: "3", Author: "Jane", Text: "ok", Status: "approved", CreatedAt: now},
+	}
+	for _, c := range comments {
+		if err := store.AddPageComment(context.Background(), "site1", "page1", c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	page, err := store.GetCommentsBySiteFiltered(context.Background(), "site1", SiteCommentFilter{Language: "en", Limit: 10})
+	if err != nil {
+		t.Fatalf("GetCommentsBySiteFiltered failed: %v", err)
+	}
+	if page.Total != 1 || len(page.Comments) != 1 || page.Comments[0].ID != "1" {
+		t.Fatalf("Expected only comment 1, got total %d: %+v", page.Total, page.Comments)
+	}
+	if got := page.Comments[0]; got.Language != "en" || got.Sentiment != "positive" {
+		t.Errorf("Expected language en and sentiment positive, got %q and %q", got.Language, got.Sentiment)
+	}
+
+	// Comments stored without tags read back with empty values
+	untagged, err := store.GetCommentByID(context.Background(), "3")
+	if err != nil {
+		t.Fatalf("GetCommentByID failed: %v", err)
+	}
+	if untagged.Language != "" || untagged.Sentiment != "" {
+		t.Errorf("Expected no tags, got %q and %q", untagged.Language, untagged.Sentiment)
+	}
+}
+
+func TestSQLiteStore_GetCommentsBySiteFiltered_ModeratedBy(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	base := time.Now().Add(-24 * time.Hour)
+	seed := []struct {
+		id, moderator string
+		moderatedAt   time.Time
+	}{
+		{"alice-old", "alice", base},
+		{"alice-new", "alice", base.Add(2 * time.Hour)},
+		{"bob-1", "bob", base.Add(time.Hour)},
+		{"unmoderated", "", time.Time{}},
+	}
+	for i, c := range seed {
+		// Created in the reverse order of moderation, so ordering by
+		// created_at would give a different result
+		comment := Comment{ID: c.id, Author: "John", Text: "Hi", Status: "pending", CreatedAt: base.Add(-time.Duration(i) * time.Minute)}
+		if err := store.AddPageComment(ctx, "site1", "page1", comment); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+		if c.moderator == "" {
+			continue
+		}
+		if err := store.UpdateCommentStatus(ctx, c.id, "approved", c.moderator); err != nil {
+			t.Fatalf("UpdateCommentStatus failed: %v", err)
+		}
+		if _, err := store.db.Exec(`UPDATE comments SET moderated_at = ? WHERE id = ?`, c.moderatedAt, c.id); err != nil {
+			t.Fatalf("Failed to set moderated_at: %v", err)
+		}
+	}
+
+	ids := func(filter SiteCommentFilter) []string {
+		t.Helper()
+		page, err := store.GetCommentsBySiteFiltered(ctx, "site1", filter)
+		if err != nil {
+			t.Fatalf("GetCommentsBySiteFiltered failed: %v", err)
+		}
+		var got []string
+		for _, c := range page.Comments {
+			got = append(got, c.ID)
+		}
+		if page.Total != len(got) {
+			t.Errorf("Expected total %d to match the %d comments returned", page.Total, len(got))
+		}
+		return got
+	}
+
+	if got := strings.Join(ids(SiteCommentFilter{ModeratedBy: "alice"}), ","); got != "alice-new,alice-old" {
+		t.Errorf("Expected alice's decisions, most recent first, got %s", got)
+	}
+	if got := strings.Join(ids(SiteCommentFilter{ModeratedBy: "bob"}), ","); got != "bob-1" {
+		t.Errorf("Expected only bob's decision, got %s", got)
+	}
+
+	// The date range bounds when the decision was made
+	window := SiteCommentFilter{ModeratedBy: "alice", From: base.Add(time.Hour), To: base.Add(3 * time.Hour)}
+	if got := strings.Join(ids(window), ","); got != "alice-new" {
+		t.Errorf("Expected only alice's decision inside the window, got %s", got)
+	}
+
+	// Without a moderator every comment is listed, newest first
+	if got := strings.Join(ids(SiteCommentFilter{}), ","); got != "alice-old,alice-new,bob-1,unmoderated" {
+		t.Errorf("Expected all comments by creation time, got %s", got)
+	}
+}
+
 func TestSQLiteStore_DeleteComment(t *testing.T) {
 	store, _ := createTestDB(t)
 	defer store.Close()
@@ -176,6 +399,279 @@ func TestSQLiteStore_GetCommentByID(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_GetCommentByIDForSite(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	comment := Comment{
+		ID:        "1",
+		Author:    "John",
+		Text:      "Test comment",
+		Status:    "approved",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := store.AddPageComment(context.Background(), "site1", "page1", comment); err != nil {
+		t.Fatalf("AddPageComment failed: %v", err)
+	}
+
+	retrieved, err := store.GetCommentByIDForSite(context.Background(), "1", "site1")
+	if err != nil {
+		t.Fatalf("GetCommentByIDForSite failed: %v", err)
+	}
+	if retrieved.ID != "1" || retrieved.SiteID != "site1" {
+		t.Errorf("Expected comment 1 on site1, got %s on %s", retrieved.ID, retrieved.SiteID)
+	}
+
+	// A comment from another site must look like it doesn't exist
+	_, err = store.GetCommentByIDForSite(context.Background(), "1", "site2")
+	if err == nil || err.Error() != "comment not found" {
+		t.Errorf("Expected 'comment not found' for wrong site, got %v", err)
+	}
+}
+
+func TestSQLiteStore_GetApprovedPageComments(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	// a (approved)
+	// ├── b (pending)
+	// │   └── c (approved)
+	// └── d (approved)
+	// e (rejected)
+	// └── f (approved)
+	base := time.Now()
+	for i, c := range []Comment{
+		{ID: "a", Status: "approved"},
+		{ID: "b", ParentID: "a", Status: "pending"},
+		{ID: "c", ParentID: "b", Status: "approved"},
+		{ID: "d", ParentID: "a", Status: "approved"},
+		{ID: "e", Status: "rejected"},
+		{ID: "f", ParentID: "e", Status: "approved"},
+	} {
+		c.Author, c.Text = "John", "Comment "+c.ID
+		c.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		c.UpdatedAt = c.CreatedAt
+		if err := store.AddPageComment(ctx, "site1", "page1", c); err != nil {
+			t.Fatalf("AddPageComment %s failed: %v", c.ID, err)
+		}
+	}
+
+	approved, err := store.GetApprovedPageComments(ctx, "site1", "page1")
+	if err != nil {
+		t.Fatalf("GetApprovedPageComments failed: %v", err)
+	}
+
+	want := []struct{ id, parent string }{{"a", ""}, {"c", "a"}, {"d", "a"}, {"f", ""}}
+	if len(approved) != len(want) {
+		t.Fatalf("Expected %d approved comments, got %d", len(want), len(approved))
+	}
+	for i, w := range want {
+		if approved[i].ID != w.id || approved[i].ParentID != w.parent {
+			t.Errorf("Comment %d: expected %s under %q, got %s under %q", i, w.id, w.parent, approved[i].ID, approved[i].ParentID)
+		}
+	}
+
+	// The unfiltered path still returns everything
+	all, err := store.GetPageComments(ctx, "site1", "page1")
+	if err != nil || len(all) != 6 {
+		t.Errorf("Expected 6 comments from GetPageComments, got %d (%v)", len(all), err)
+	}
+}
+
+func TestSQLiteStore_GetPageCommentsStamp(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	stamp, err := store.GetPageCommentsStamp(ctx, "site1", "page1")
+	if err != nil {
+		t.Fatalf("GetPageCommentsStamp failed: %v", err)
+	}
+	if stamp.Count != 0 || !stamp.LastUpdated.IsZero() {
+		t.Errorf("Expected an empty stamp for an empty page, got %+v", stamp)
+	}
+
+	latest := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	for i, updated := range []time.Time{latest.Add(-time.Hour), latest, latest.Add(-2 * time.Hour)} {
+		c := Comment{ID: fmt.Sprintf("c%d", i), Author: "John", Text: "Hi", Status: "approved", CreatedAt: updated, UpdatedAt: updated}
+		if err := store.AddPageComment(ctx, "site1", "page1", c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+	if err := store.AddPageComment(ctx, "site1", "page2", Comment{ID: "other", Author: "John", Text: "Hi", CreatedAt: latest.Add(time.Hour), UpdatedAt: latest.Add(time.Hour)}); err != nil {
+		t.Fatalf("AddPageComment failed: %v", err)
+	}
+
+	stamp, err = store.GetPageCommentsStamp(ctx, "site1", "page1")
+	if err != nil {
+		t.Fatalf("GetPageCommentsStamp failed: %v", err)
+	}
+	if stamp.Count != 3 || !stamp.LastUpdated.Equal(latest) {
+		t.Errorf("Expected 3 comments last updated %v, got %+v", latest, stamp)
+	}
+}
+
+func TestSQLiteStore_GetPageCommentsSince(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	since := time.Now().Add(-time.Hour)
+	seed := []struct {
+		page string
+		c    Comment
+	}{
+		{"page1", Comment{ID: "old", Author: "A", Text: "Before", Status: "approved", CreatedAt: since.Add(-time.Minute), UpdatedAt: since.Add(-time.Minute)}},
+		{"page1", Comment{ID: "edited", Author: "B", Text: "Edited after", Status: "approved", CreatedAt: since.Add(-time.Minute), UpdatedAt: since.Add(2 * time.Minute)}},
+		{"page1", Comment{ID: "new", Author: "C", Text: "After", Status: "approved", CreatedAt: since.Add(time.Minute), UpdatedAt: since.Add(time.Minute)}},
+		{"page1", Comment{ID: "exact", Author: "D", Text: "At since", Status: "approved", CreatedAt: since, UpdatedAt: since}},
+		{"page2", Comment{ID: "other", Author: "E", Text: "Other page", Status: "approved", CreatedAt: since.Add(time.Minute), UpdatedAt: since.Add(time.Minute)}},
+	}
+	for _, s := range seed {
+		if err := store.AddPageComment(ctx, "site1", s.page, s.c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	got, err := store.GetPageCommentsSince(ctx, "site1", "page1", since)
+	if err != nil {
+		t.Fatalf("GetPageCommentsSince failed: %v", err)
+	}
+	var ids []string
+	for _, c := range got {
+		ids = append(ids, c.ID)
+	}
+	if strings.Join(ids, ",") != "edited,new" {
+		t.Errorf("Expected the edited and new comments oldest first, got %v", ids)
+	}
+
+	got, err = store.GetPageCommentsSince(ctx, "site1", "page1", time.Now())
+	if err != nil {
+		t.Fatalf("GetPageCommentsSince failed: %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty list when nothing changed, got %v", got)
+	}
+}
+
+func TestSQLiteStore_GetCommentsByAuthor(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	add := func(id, site, page, author, status string, minute int) {
+		t.Helper()
+		at := base.Add(time.Duration(minute) * time.Minute)
+		c := Comment{ID: id, Author: author, AuthorID: author, Text: "Comment " + id, Status: status, CreatedAt: at, UpdatedAt: at}
+		if err := store.AddPageComment(ctx, site, page, c); err != nil {
+			t.Fatalf("AddPageComment %s failed: %v", id, err)
+		}
+	}
+	add("a1", "site1", "page1", "alice", "approved", 0)
+	add("b1", "site1", "page1", "bob", "approved", 1)
+	add("a2", "site1", "page2", "alice", "pending", 2)
+	add("a3", "site1", "page1", "alice", "rejected", 3)
+	add("a4", "site2", "page1", "alice", "approved", 4)
+
+	got, err := store.GetCommentsByAuthor(ctx, "site1", "alice", 0, 0)
+	if err != nil {
+		t.Fatalf("GetCommentsByAuthor failed: %v", err)
+	}
+	var ids []string
+	for _, c := range got {
+		ids = append(ids, c.ID)
+		if c.AuthorID != "alice" || c.SiteID != "site1" {
+			t.Errorf("Comment %s: expected alice on site1, got %s on %s", c.ID, c.AuthorID, c.SiteID)
+		}
+	}
+	if fmt.Sprint(ids) != "[a3 a2 a1]" {
+		t.Errorf("Expected alice's site1 comments newest first in every status, got %v", ids)
+	}
+	if got[1].PageID != "page2" || got[1].PagePath == "" {
+		t.Errorf("Expected a2 to carry its page, got id %q path %q", got[1].PageID, got[1].PagePath)
+	}
+
+	paged, err := store.GetCommentsByAuthor(ctx, "site1", "alice", 1, 1)
+	if err != nil || len(paged) != 1 || paged[0].ID != "a2" {
+		t.Errorf("Expected the second comment with limit 1 offset 1, got %v (%v)", paged, err)
+	}
+}
+
+func TestSQLiteStore_UpdateCommentStatusBatch(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2"} {
+		err := store.AddPageComment(ctx, "site1", "page1", Comment{ID: id, Author: "John", Text: "Test", Status: "pending"})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	// Unknown IDs are harmless: they're skipped and the rest are updated
+	updated, err := store.UpdateCommentStatusBatch(ctx, []string{"1", "missing", "2"}, "approved", "mod1")
+	if err != nil {
+		t.Fatalf("UpdateCommentStatusBatch failed: %v", err)
+	}
+	sort.Strings(updated)
+	if !reflect.DeepEqual(updated, []string{"1", "2"}) {
+		t.Errorf("Expected comments 1 and 2 updated, got %v", updated)
+	}
+	for _, id := range []string{"1", "2"} {
+		c, _ := store.GetCommentByID(ctx, id)
+		if c.Status != "approved" || c.ModeratedBy != "mod1" {
+			t.Errorf("Expected comment %s approved by mod1, got %s by %s", id, c.Status, c.ModeratedBy)
+		}
+	}
+}
+
+func TestSQLiteStore_UpdateCommentStatusBatch_RollsBackOnError(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2"} {
+		err := store.AddPageComment(ctx, "site1", "page1", Comment{ID: id, Author: "John", Text: "Test", Status: "pending"})
+		if err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	// Fail updates to comment 2 and push it into a later statement than comment 1,
+	// so only the transaction can undo the first statement's update
+	_, err := store.db.Exec(`
+		CREATE TRIGGER fail_comment_2 BEFORE UPDATE ON comments
+		WHEN NEW.id = '2'
+		BEGIN SELECT RAISE(ABORT, 'simulated failure'); END
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+	ids := []string{"1"}
+	for i := 0; i < statusBatchSize; i++ {
+		ids = append(ids, fmt.Sprintf("missing-%d", i))
+	}
+	ids = append(ids, "2")
+
+	updated, err := store.UpdateCommentStatusBatch(ctx, ids, "rejected", "mod1")
+	if err == nil {
+		t.Fatal("Expected an error from the failing update")
+	}
+	if len(updated) != 0 {
+		t.Errorf("Expected nothing updated on error, got %v", updated)
+	}
+	for _, id := range []string{"1", "2"} {
+		c, _ := store.GetCommentByID(ctx, id)
+		if c.Status != "pending" {
+			t.Errorf("Expected comment %s to stay pending after rollback, got %s", id, c.Status)
+		}
+	}
+}
+
 func TestSQLiteStore_CommentDefaultStatus(t *testing.T) {
 	store, _ := createTestDB(t)
 	defer store.Close()
@@ -0,0 +1,69 @@
+package comments
+
+import (
+	"html"
+	"strings"
+)
+
+// Content policies a site can choose for comment text
+const (
+	// ContentPolicyPlain shows comments as plain text; nothing is interpreted
+	ContentPolicyPlain = "plain"
+	// ContentPolicyMarkdown renders the Markdown subset supported by RenderMarkdown
+	ContentPolicyMarkdown = "markdown"
+	// ContentPolicyLimitedHTML accepts the HTML allowlist of SanitizeHTML
+	ContentPolicyLimitedHTML = "limited-html"
+)
+
+// DefaultContentPolicy is the policy of sites that haven't chosen one
+const DefaultContentPolicy = ContentPolicyMarkdown
+
+// IsValidContentPolicy reports whether policy is one of the supported content policies
+func IsValidContentPolicy(policy string) bool {
+	switch policy {
+	case ContentPolicyPlain, ContentPolicyMarkdown, ContentPolicyLimitedHTML:
+		return true
+	}
+	return false
+}
+
+// PrepareText applies a site's content policy to comment text before it is
+// stored. Limited HTML is sanitized so disallowed tags never reach the
+// database; other policies store the text as written.
+func PrepareText(text, policy string) string {
+	if policy == ContentPolicyLimitedHTML {
+		return strings.TrimSpace(SanitizeHTML(text))
+	}
+	return text
+}
+
+// RenderHTML renders comment text to HTML under a site's content policy
+func RenderHTML(text, policy string) string {
+	switch policy {
+	case ContentPolicyPlain:
+		return RenderPlain(text)
+	case ContentPolicyLimitedHTML:
+		// Stored text is already sanitized; sanitizing again covers comments
+		// written before the site switched policy
+		return SanitizeHTML(text)
+	default:
+		return RenderMarkdown(text)
+	}
+}
+
+// RenderPlain escapes comment text, keeping only its paragraphs and line breaks
+func RenderPlain(text string) string {
+	var paragraphs []string
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(strings.TrimSpace(line))
+		}
+		paragraphs = append(paragraphs, "<p>"+strings.Join(lines, "<br>\n")+"</p>")
+	}
+	return strings.Join(paragraphs, "\n")
+}
@@ -0,0 +1,123 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxReportReasonLength is the longest reason accepted with a report, in bytes
+const MaxReportReasonLength = 1000
+
+// ErrAlreadyReported is returned when a user reports the same comment twice
+var ErrAlreadyReported = errors.New("comment already reported by this user")
+
+// Report is an end user's flag on a comment for owner review
+type Report struct {
+	ID             string    `json:"id"`
+	CommentID      string    `json:"comment_id"`
+	ReporterUserID string    `json:"reporter_user_id"`
+	Reason         string    `json:"reason,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReportStore handles comment_reports database operations
+type ReportStore struct {
+	db *sql.DB
+}
+
+// NewReportStore creates a new comment report store
+func NewReportStore(db *sql.DB) *ReportStore {
+	return &ReportStore{db: db}
+}
+
+// Create records a report on a comment. It returns ErrAlreadyReported if the
+// user has already reported it.
+func (s *ReportStore) Create(ctx context.Context, commentID, reporterUserID, reason string) (*Report, error) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > MaxReportReasonLength {
+		return nil, fmt.Errorf("reason must be at most %d characters", MaxReportReasonLength)
+	}
+
+	report := &Report{
+		ID:             uuid.NewString(),
+		CommentID:      commentID,
+		ReporterUserID: reporterUserID,
+		Reason:         reason,
+		CreatedAt:      time.Now(),
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO comment_reports (id, comment_id, reporter_user_id, reason, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(comment_id, reporter_user_id) DO NOTHING
+	`, report.ID, report.CommentID, report.ReporterUserID, report.Reason, report.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrAlreadyReported
+	}
+
+	return report, nil
+}
+
+// CountReports returns how many users have reported a comment
+func (s *ReportStore) CountReports(ctx context.Context, commentID string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comment_reports WHERE comment_id = ?`, commentID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+	return count, nil
+}
+
+// CountReportsByComment returns report counts for the given comments, keyed by
+// comment ID. Comments without reports are omitted.
+func (s *ReportStore) CountReportsByComment(ctx context.Context, commentIDs []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(commentIDs) == 0 {
+		return counts, nil
+	}
+
+	placeholders := make([]string, len(commentIDs))
+	args := make([]interface{}, len(commentIDs))
+	for i, id := range commentIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT comment_id, COUNT(*)
+		FROM comment_reports
+		WHERE comment_id IN (%s)
+		GROUP BY comment_id
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reports: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID string
+		var count int
+		if err := rows.Scan(&commentID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan report count: %w", err)
+		}
+		counts[commentID] = count
+	}
+
+	return counts, rows.Err()
+}
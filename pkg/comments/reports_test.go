@@ -0,0 +1,79 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportStore_PreventsDuplicateReports(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	comment := Comment{ID: "comment-1", Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: "approved", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.AddPageComment(ctx, "site-1", "page-1", comment); err != nil {
+		t.Fatalf("AddPageComment failed: %v", err)
+	}
+
+	reports := NewReportStore(store.GetDB())
+	report, err := reports.Create(ctx, "comment-1", "user-1", "  spam  ")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if report.ID == "" || report.Reason != "spam" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	if _, err := reports.Create(ctx, "comment-1", "user-1", "still spam"); !errors.Is(err, ErrAlreadyReported) {
+		t.Fatalf("Expected ErrAlreadyReported for a second report, got %v", err)
+	}
+
+	if _, err := reports.Create(ctx, "comment-1", "user-2", ""); err != nil {
+		t.Fatalf("Create for another user failed: %v", err)
+	}
+
+	count, err := reports.CountReports(ctx, "comment-1")
+	if err != nil {
+		t.Fatalf("CountReports failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 reports, got %d", count)
+	}
+}
+
+func TestReportStore_CountReportsByComment(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	for _, id := range []string{"comment-1", "comment-2", "comment-3"} {
+		comment := Comment{ID: id, Author: "Alice", AuthorID: "author-1", Text: "Hello", Status: "approved", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := store.AddPageComment(ctx, "site-1", "page-1", comment); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	reports := NewReportStore(store.GetDB())
+	for _, r := range []struct{ comment, user string }{
+		{"comment-1", "user-1"}, {"comment-1", "user-2"}, {"comment-2", "user-1"},
+	} {
+		if _, err := reports.Create(ctx, r.comment, r.user, ""); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	counts, err := reports.CountReportsByComment(ctx, []string{"comment-1", "comment-2", "comment-3"})
+	if err != nil {
+		t.Fatalf("CountReportsByComment failed: %v", err)
+	}
+	if counts["comment-1"] != 2 || counts["comment-2"] != 1 || counts["comment-3"] != 0 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	if _, err := reports.Create(ctx, "comment-3", "user-1", strings.Repeat("x", MaxReportReasonLength+1)); err == nil {
+		t.Error("Expected error for an overlong reason")
+	}
+}
@@ -0,0 +1,147 @@
+package comments
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Limited HTML keeps a small allowlist of formatting tags, links and images.
+// Every other tag is dropped but its text is kept, except for the elements in
+// droppedContentTags whose contents are removed along with them. Attributes
+// other than a link's href and an image's src and alt are removed.
+
+// allowedHTMLTags lists the tags kept by SanitizeHTML
+var allowedHTMLTags = map[string]bool{
+	"p": true, "br": true, "strong": true, "b": true, "em": true, "i": true,
+	"code": true, "pre": true, "blockquote": true, "ul": true, "ol": true, "li": true,
+	"a": true, "img": true,
+}
+
+// voidHTMLTags are allowed tags that never have a closing tag
+var voidHTMLTags = map[string]bool{"br": true, "img": true}
+
+// droppedContentTags are removed together with everything inside them
+var droppedContentTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "textarea": true, "select": true, "svg": true, "math": true,
+}
+
+// allowedImageSchemes lists the URL schemes images may be loaded from
+var allowedImageSchemes = map[string]bool{"http": true, "https": true}
+
+// SanitizeHTML reduces text to the limited HTML allowlist. Text outside tags is
+// re-escaped, unclosed tags are closed and stray closing tags are dropped, so
+// the result is well formed.
+func SanitizeHTML(text string) string {
+	var out strings.Builder
+	var open []string
+	skipping := "" // dropped element whose contents are being skipped
+	skipDepth := 0
+
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(text))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			// io.EOF at the end of the input; reading a string can't fail otherwise
+			break
+		}
+		token := tokenizer.Token()
+
+		if skipping != "" {
+			switch {
+			case tokenType == xhtml.StartTagToken && token.Data == skipping:
+				skipDepth++
+			case tokenType == xhtml.EndTagToken && token.Data == skipping:
+				skipDepth--
+				if skipDepth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+
+		switch tokenType {
+		case xhtml.TextToken:
+			out.WriteString(html.EscapeString(token.Data))
+
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if droppedContentTags[token.Data] {
+				if tokenType == xhtml.StartTagToken {
+					skipping, skipDepth = token.Data, 1
+				}
+				continue
+			}
+			tag, ok := sanitizeTag(token)
+			if !ok {
+				continue
+			}
+			out.WriteString(tag)
+			if !voidHTMLTags[token.Data] {
+				open = append(open, token.Data)
+			}
+
+		case xhtml.EndTagToken:
+			// Close back to the matching open tag, if there is one
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == token.Data {
+					for j := len(open) - 1; j >= i; j-- {
+						out.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
+
+// sanitizeTag renders an allowed start tag with only its safe attributes
+func sanitizeTag(token xhtml.Token) (string, bool) {
+	if !allowedHTMLTags[token.Data] {
+		return "", false
+	}
+
+	switch token.Data {
+	case "a":
+		href, ok := safeLinkURL(attribute(token, "href"))
+		if !ok {
+			return "<a>", true
+		}
+		return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener" target="_blank">`, true
+	case "img":
+		src, ok := safeImageURL(attribute(token, "src"))
+		if !ok {
+			return "", false
+		}
+		return `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(attribute(token, "alt")) + `">`, true
+	default:
+		return "<" + token.Data + ">", true
+	}
+}
+
+// attribute returns the value of a token's attribute, or "" if it has none
+func attribute(token xhtml.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return strings.TrimSpace(attr.Val)
+		}
+	}
+	return ""
+}
+
+// safeImageURL returns the normalized URL if it is an absolute http(s) URL
+func safeImageURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || !allowedImageSchemes[u.Scheme] || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
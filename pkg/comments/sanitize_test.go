@@ -0,0 +1,96 @@
+package comments
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML_KeepsAllowedMarkup(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"formatting", "<p>Hi <strong>there</strong> and <em>you</em></p>", "<p>Hi <strong>there</strong> and <em>you</em></p>"},
+		{"code block", "<pre><code>if a &lt; b {}</code></pre>", "<pre><code>if a &lt; b {}</code></pre>"},
+		{"image", `<img src="https://example.com/a.png" alt="A chart" width="10">`, `<img src="https://example.com/a.png" alt="A chart">`},
+		{"link", `<a href="https://example.com" title="x">docs</a>`, `<a href="https://example.com" rel="nofollow noopener" target="_blank">docs</a>`},
+		{"unknown tag keeps text", "<span class=\"x\">hello</span>", "hello"},
+		{"unclosed tags are closed", "<p><strong>bold", "<p><strong>bold</strong></p>"},
+		{"stray closing tag dropped", "text</em> more", "text more"},
+		{"plain text is escaped", "a < b & c > d", "a &lt; b &amp; c &gt; d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.input); got != tt.want {
+				t.Errorf("SanitizeHTML(%q)\ngot:  %q\nwant: %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTML_NeutralizesMaliciousInput(t *testing.T) {
+	inputs := []string{
+		`<script>alert(1)</script>`,
+		`<iframe src="https://evil.example"></iframe>`,
+		`<img src=x onerror="alert(1)">`,
+		`<img src="javascript:alert(1)">`,
+		`<a href="javascript:alert(1)">click</a>`,
+		`<a href="https://example.com" onclick="alert(1)">click</a>`,
+		`<p style="background:url(javascript:alert(1))">x</p>`,
+		`<svg><script>alert(1)</script></svg>`,
+		`<scr<script>ipt>alert(1)</script>`,
+		`<style>body{display:none}</style>`,
+		`<!-- <script>alert(1)</script> -->`,
+		`<object data="evil.swf"></object>`,
+	}
+
+	for _, input := range inputs {
+		got := SanitizeHTML(input)
+		lower := strings.ToLower(got)
+		for _, bad := range []string{"<script", "<iframe", "<svg", "<style", "<object", "onerror", "onclick", "javascript:", "style="} {
+			if strings.Contains(lower, bad) {
+				t.Errorf("SanitizeHTML(%q) kept %q: %s", input, bad, got)
+			}
+		}
+		for _, tag := range tagPattern.FindAllString(got, -1) {
+			if !strings.HasPrefix(tag, "<img ") && !allowedTagPattern.MatchString(tag) && !safeLinkTagPattern.MatchString(tag) {
+				t.Errorf("SanitizeHTML(%q) emitted unexpected tag %s: %s", input, tag, got)
+			}
+		}
+	}
+}
+
+func TestRenderHTML_Policies(t *testing.T) {
+	text := "**bold** <em>html</em>"
+
+	if got := RenderHTML(text, ContentPolicyPlain); got != "<p>**bold** &lt;em&gt;html&lt;/em&gt;</p>" {
+		t.Errorf("plain: got %q", got)
+	}
+	if got := RenderHTML(text, ContentPolicyMarkdown); got != "<p><strong>bold</strong> &lt;em&gt;html&lt;/em&gt;</p>" {
+		t.Errorf("markdown: got %q", got)
+	}
+	if got := RenderHTML(text, ContentPolicyLimitedHTML); got != "**bold** <em>html</em>" {
+		t.Errorf("limited-html: got %q", got)
+	}
+}
+
+func TestPrepareText(t *testing.T) {
+	input := `<p>Hi</p><script>alert(1)</script>`
+	if got := PrepareText(input, ContentPolicyLimitedHTML); got != "<p>Hi</p>" {
+		t.Errorf("Expected limited-html to be sanitized, got %q", got)
+	}
+	for _, policy := range []string{ContentPolicyPlain, ContentPolicyMarkdown} {
+		if got := PrepareText(input, policy); got != input {
+			t.Errorf("Expected %s text to be stored as written, got %q", policy, got)
+		}
+	}
+}
+
+func TestRenderPlain_Paragraphs(t *testing.T) {
+	got := RenderPlain("one\ntwo\n\n<b>three</b>")
+	if want := "<p>one<br>\ntwo</p>\n<p>&lt;b&gt;three&lt;/b&gt;</p>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package comments
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MinSearchQueryLength is the fewest characters a public comment search needs,
+// so single letters don't match nearly every comment
+const MinSearchQueryLength = 3
+
+// MaxSearchQueryLength caps the length of a comment search query
+const MaxSearchQueryLength = 100
+
+// snippetContext is how many characters SearchSnippet keeps on each side of
+// the first match
+const snippetContext = 60
+
+// EscapeLike escapes LIKE wildcards so s matches literally with ESCAPE '\'
+func EscapeLike(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "%", "\\%")
+	return strings.ReplaceAll(s, "_", "\\_")
+}
+
+// MatchesSearch reports whether text contains query, ignoring case
+func MatchesSearch(text, query string) bool {
+	return len(searchMatches([]rune(text), foldRunes(query))) > 0
+}
+
+// SearchSnippet returns an HTML excerpt of text around the first match of
+// query, with every match in it wrapped in <mark>. Whitespace is collapsed and
+// the excerpt starts at the beginning of text when there is no match.
+func SearchSnippet(text, query string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	needle := foldRunes(query)
+	matches := searchMatches(runes, needle)
+
+	start := 0
+	if len(matches) > 0 && matches[0] > snippetContext {
+		start = matches[0] - snippetContext
+	}
+	end := start + 2*snippetContext + len(needle)
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var out strings.Builder
+	if start > 0 {
+		out.WriteString("…")
+	}
+	next := 0 // index into matches of the next match to highlight
+	for i := start; i < end; {
+		for next < len(matches) && matches[next] < i {
+			next++
+		}
+		if next < len(matches) && matches[next] == i && i+len(needle) <= end {
+			out.WriteString("<mark>" + html.EscapeString(string(runes[i:i+len(needle)])) + "</mark>")
+			i += len(needle)
+			continue
+		}
+		out.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+	if end < len(runes) {
+		out.WriteString("…")
+	}
+	return out.String()
+}
+
+// foldRunes lower-cases s rune by rune, so match lengths line up with the text
+func foldRunes(s string) []rune {
+	runes := make([]rune, 0, utf8.RuneCountInString(s))
+	for _, r := range strings.TrimSpace(s) {
+		runes = append(runes, unicode.ToLower(r))
+	}
+	return runes
+}
+
+// searchMatches returns the start of every non-overlapping case-insensitive
+// occurrence of needle in runes
+func searchMatches(runes, needle []rune) []int {
+	if len(needle) == 0 {
+		return nil
+	}
+	var matches []int
+	for i := 0; i+len(needle) <= len(runes); i++ {
+		j := 0
+		for j < len(needle) && unicode.ToLower(runes[i+j]) == needle[j] {
+			j++
+		}
+		if j == len(needle) {
+			matches = append(matches, i)
+			i += len(needle) - 1
+		}
+	}
+	return matches
+}
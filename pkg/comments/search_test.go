@@ -0,0 +1,113 @@
+package comments
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"highlights every match", "Go is fun. I like go.", "go", "<mark>Go</mark> is fun. I like <mark>go</mark>."},
+		{"escapes html", "use <b>bold</b> tags", "bold", "use &lt;b&gt;<mark>bold</mark>&lt;/b&gt; tags"},
+		{"collapses whitespace", "line one\n\nline   two", "line two", "line one <mark>line two</mark>"},
+		{"no match starts at the beginning", "nothing here", "xyz", "nothing here"},
+		{"non-latin text", "この記事はとても良い", "記事", "この<mark>記事</mark>はとても良い"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SearchSnippet(tt.text, tt.query); got != tt.want {
+				t.Errorf("SearchSnippet(%q, %q)\ngot:  %q\nwant: %q", tt.text, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "plain",
+		"100%":       `100\%`,
+		"snake_case": `snake\_case`,
+		`C:\path`:    `C:\\path`,
+	}
+	for in, want := range tests {
+		if got := EscapeLike(in); got != want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchSnippet_TrimsLongText(t *testing.T) {
+	text := strings.Repeat("a", 200) + " needle " + strings.Repeat("b", 200)
+	got := SearchSnippet(text, "needle")
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Expected ellipses on both ends, got %q", got)
+	}
+	if !strings.Contains(got, "<mark>needle</mark>") {
+		t.Errorf("Expected the match to be highlighted, got %q", got)
+	}
+	if n := len([]rune(got)); n > 2*snippetContext+len("needle")+len("<mark></mark>")+2 {
+		t.Errorf("Expected a short snippet, got %d characters", n)
+	}
+}
+
+func TestSQLiteStore_SearchApprovedPageComments(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	now := time.Now()
+	seed := []struct {
+		page string
+		c    Comment
+	}{
+		{"page1", Comment{ID: "1", Author: "A", Text: "Great explanation of goroutines", Status: "approved", CreatedAt: now.Add(-time.Hour)}},
+		{"page1", Comment{ID: "2", Author: "B", Text: "Goroutines are cheap", Status: "approved", CreatedAt: now}},
+		{"page1", Comment{ID: "3", Author: "C", Text: "Pending goroutines question", Status: "pending", CreatedAt: now}},
+		{"page2", Comment{ID: "4", Author: "D", Text: "Goroutines on another page", Status: "approved", CreatedAt: now}},
+		{"page1", Comment{ID: "5", Author: "E", Text: "100% sure", Status: "approved", CreatedAt: now}},
+	}
+	for _, s := range seed {
+		if err := store.AddPageComment(context.Background(), "site1", s.page, s.c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	results, err := store.SearchApprovedPageComments(context.Background(), "site1", "page1", "GOROUTINES", 10)
+	if err != nil {
+		t.Fatalf("SearchApprovedPageComments failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "2" || results[1].ID != "1" {
+		t.Errorf("Expected approved page1 matches 2 then 1, got %+v", results)
+	}
+
+	// LIKE wildcards in the query match literally
+	results, err = store.SearchApprovedPageComments(context.Background(), "site1", "page1", "0%", 10)
+	if err != nil {
+		t.Fatalf("SearchApprovedPageComments failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "5" {
+		t.Errorf("Expected only comment 5, got %+v", results)
+	}
+
+	results, err = store.SearchApprovedPageComments(context.Background(), "site1", "page1", "goroutines", 1)
+	if err != nil {
+		t.Fatalf("SearchApprovedPageComments failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected the limit to apply, got %d results", len(results))
+	}
+
+	results, err = store.SearchApprovedPageComments(context.Background(), "site1", "page1", "channels", 10)
+	if err != nil {
+		t.Fatalf("SearchApprovedPageComments failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no results, got %+v", results)
+	}
+}
@@ -3,32 +3,133 @@ package comments
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/migrate"
 )
 
+// ErrInvalidParent is returned when a reply's parent comment does not exist on the same site and page
+var ErrInvalidParent = errors.New("invalid parent comment")
+
+// ErrInvalidOrder is returned when a comment ordering isn't one of the supported values
+var ErrInvalidOrder = errors.New("invalid comment order")
+
+// ErrCommentNotFound is returned when a comment to update doesn't exist
+var ErrCommentNotFound = errors.New("comment not found")
+
+// ErrConcurrentModification is returned when a comment changed after the
+// version an update was based on
+var ErrConcurrentModification = errors.New("comment was modified concurrently")
+
+// Orderings accepted by GetPageCommentsOrdered
+const (
+	OrderOldest      = "oldest"
+	OrderNewest      = "newest"
+	OrderMostReacted = "most_reacted"
+)
+
+// pageCommentOrders maps each allowed ordering to its ORDER BY clause. Only
+// these fixed strings are ever interpolated into the query.
+var pageCommentOrders = map[string]string{
+	OrderOldest:      "c.created_at ASC",
+	OrderNewest:      "c.created_at DESC",
+	OrderMostReacted: "COALESCE(rc.reaction_count, 0) DESC, c.created_at ASC",
+}
+
+// IsValidOrder reports whether order is one of the supported comment orderings
+func IsValidOrder(order string) bool {
+	_, ok := pageCommentOrders[order]
+	return ok
+}
+
 // SQLiteStore provides SQLite-based persistent storage for comments
 type SQLiteStore struct {
 	db *sql.DB
 }
 
+// StoreConfig tunes the SQLite connection pool and pragmas. Zero fields keep
+// the defaults from DefaultStoreConfig, which suit a small-to-medium host.
+type StoreConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// CacheSizeKB is the page cache size in KiB (PRAGMA cache_size = -CacheSizeKB)
+	CacheSizeKB int
+	// BusyTimeout is how long a writer waits on a locked database before failing
+	BusyTimeout time.Duration
+	// MmapSize is the memory-mapped I/O size in bytes
+	MmapSize int64
+}
+
+// DefaultStoreConfig returns the pool and pragma settings used by NewSQLiteStore
+func DefaultStoreConfig() StoreConfig {
+	return StoreConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 5 * time.Minute,
+		ConnMaxIdleTime: time.Minute,
+		CacheSizeKB:     64000,
+		BusyTimeout:     5 * time.Second,
+		MmapSize:        256 << 20,
+	}
+}
+
+// withDefaults fills the zero fields of cfg from DefaultStoreConfig
+func (cfg StoreConfig) withDefaults() StoreConfig {
+	def := DefaultStoreConfig()
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = def.MaxOpenConns
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = def.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = def.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime == 0 {
+		cfg.ConnMaxIdleTime = def.ConnMaxIdleTime
+	}
+	if cfg.CacheSizeKB == 0 {
+		cfg.CacheSizeKB = def.CacheSizeKB
+	}
+	if cfg.BusyTimeout == 0 {
+		cfg.BusyTimeout = def.BusyTimeout
+	}
+	if cfg.MmapSize == 0 {
+		cfg.MmapSize = def.MmapSize
+	}
+	return cfg
+}
+
 // NewSQLiteStore creates a new SQLite-based comment store
 func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	return NewSQLiteStoreWithConfig(dbPath, DefaultStoreConfig())
+}
+
+// NewSQLiteStoreWithConfig creates a new SQLite-based comment store with custom
+// pool limits and pragmas
+func NewSQLiteStoreWithConfig(dbPath string, cfg StoreConfig) (*SQLiteStore, error) {
+	cfg = cfg.withDefaults()
+	busyTimeoutMS := cfg.BusyTimeout.Milliseconds()
+
 	// Configure SQLite with WAL mode for better concurrency and busy timeout
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_busy_timeout=5000")
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=%d", dbPath, busyTimeoutMS))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Configure connection pool for production
-	db.SetMaxOpenConns(25)                      // Limit concurrent connections
-	db.SetMaxIdleConns(5)                       // Keep some connections warm
-	db.SetConnMaxLifetime(5 * time.Minute)      // Recycle old connections
-	db.SetConnMaxIdleTime(time.Minute)          // Close idle connections
+	db.SetMaxOpenConns(cfg.MaxOpenConns)       // Limit concurrent connections
+	db.SetMaxIdleConns(cfg.MaxIdleConns)       // Keep some connections warm
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime) // Recycle old connections
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime) // Close idle connections
 
 	// Verify connection
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -55,8 +156,8 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 	}
 
 	// Set busy timeout to handle lock contention gracefully
-	// Instead of failing immediately when database is locked, retry for up to 5 seconds
-	_, err = db.Exec("PRAGMA busy_timeout = 5000")
+	// Instead of failing immediately when database is locked, retry for up to BusyTimeout
+	_, err = db.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", busyTimeoutMS))
 	if err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
@@ -70,9 +171,9 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to set synchronous mode: %w", err)
 	}
 
-	// Increase cache size for better query performance (64MB)
+	// Increase cache size for better query performance (64MB by default)
 	// Larger cache reduces disk I/O for frequently accessed data
-	_, err = db.Exec("PRAGMA cache_size = -64000")
+	_, err = db.Exec(fmt.Sprintf("PRAGMA cache_size = -%d", cfg.CacheSizeKB))
 	if err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to set cache size: %w", err)
@@ -86,284 +187,35 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to set temp store: %w", err)
 	}
 
-	// Enable memory-mapped I/O for better read performance (256MB)
+	// Enable memory-mapped I/O for better read performance (256MB by default)
 	// This is optional and will be ignored on systems that don't support it
-	_, err = db.Exec("PRAGMA mmap_size = 268435456")
+	_, err = db.Exec(fmt.Sprintf("PRAGMA mmap_size = %d", cfg.MmapSize))
 	if err != nil {
 		// Log warning but don't fail - mmap not supported on all systems
 		log.Printf("Warning: Could not enable mmap: %v", err)
 	}
 
 	// Log the configuration for debugging and verification
-	log.Printf("SQLite database initialized with optimizations: WAL mode, 64MB cache, 25 max connections")
-
-	// Create table and indexes if they don't exist
-	schema := `
-	CREATE TABLE IF NOT EXISTS admin_users (
-		id TEXT PRIMARY KEY,
-		email TEXT UNIQUE NOT NULL,
-		name TEXT,
-		auth0_sub TEXT UNIQUE NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS sites (
-		id TEXT PRIMARY KEY,
-		owner_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		domain TEXT,
-		description TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (owner_id) REFERENCES admin_users(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_sites_owner ON sites(owner_id);
-
-	CREATE TABLE IF NOT EXISTS users (
-		id TEXT NOT NULL,
-		site_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		email TEXT,
-		avatar_url TEXT,
-		profile_url TEXT,
-		is_verified INTEGER DEFAULT 0,
-		roles TEXT,
-		reputation_score INTEGER DEFAULT 0,
-		first_seen TIMESTAMP NOT NULL,
-		last_seen TIMESTAMP NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
-		PRIMARY KEY (site_id, id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_users_site_id ON users(site_id);
-	CREATE INDEX IF NOT EXISTS idx_users_email ON users(site_id, email);
-
-	CREATE TABLE IF NOT EXISTS pages (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		path TEXT NOT NULL,
-		title TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
-		UNIQUE(site_id, path)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_pages_site ON pages(site_id);
-
-	CREATE TABLE IF NOT EXISTS comments (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		page_id TEXT NOT NULL,
-		author TEXT NOT NULL,
-		author_id TEXT NOT NULL,
-		author_email TEXT,
-		text TEXT NOT NULL,
-		parent_id TEXT,
-		status TEXT DEFAULT 'pending' CHECK(status IN ('pending', 'approved', 'rejected')),
-		moderated_by TEXT,
-		moderated_at TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_site_page ON comments(site_id, page_id);
-	CREATE INDEX IF NOT EXISTS idx_parent ON comments(parent_id);
-	CREATE INDEX IF NOT EXISTS idx_comments_status ON comments(status);
-	CREATE INDEX IF NOT EXISTS idx_comments_author ON comments(author_id);
-
-	CREATE TABLE IF NOT EXISTS allowed_reactions (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		emoji TEXT NOT NULL,
-		reaction_type TEXT NOT NULL DEFAULT 'comment' CHECK(reaction_type IN ('page', 'comment', 'both')),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
-		UNIQUE(site_id, name, reaction_type)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_allowed_reactions_site ON allowed_reactions(site_id);
-	CREATE INDEX IF NOT EXISTS idx_allowed_reactions_type ON allowed_reactions(reaction_type);
-
-	CREATE TABLE IF NOT EXISTS reactions (
-		id TEXT PRIMARY KEY,
-		page_id TEXT,
-		comment_id TEXT,
-		allowed_reaction_id TEXT NOT NULL,
-		user_id TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE,
-		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
-		FOREIGN KEY (allowed_reaction_id) REFERENCES allowed_reactions(id) ON DELETE CASCADE,
-		CHECK ((page_id IS NOT NULL AND comment_id IS NULL) OR (page_id IS NULL AND comment_id IS NOT NULL)),
-		UNIQUE(page_id, comment_id, allowed_reaction_id, user_id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_reactions_page ON reactions(page_id);
-	CREATE INDEX IF NOT EXISTS idx_reactions_comment ON reactions(comment_id);
-	CREATE INDEX IF NOT EXISTS idx_reactions_allowed ON reactions(allowed_reaction_id);
-	CREATE INDEX IF NOT EXISTS idx_reactions_user ON reactions(user_id);
-
-	CREATE TABLE IF NOT EXISTS moderation_config (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL UNIQUE,
-		enabled INTEGER DEFAULT 0,
-		auto_reject_threshold REAL DEFAULT 0.85,
-		auto_approve_threshold REAL DEFAULT 0.30,
-		check_spam INTEGER DEFAULT 1,
-		check_offensive INTEGER DEFAULT 1,
-		check_aggressive INTEGER DEFAULT 1,
-		check_off_topic INTEGER DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_moderation_config_site ON moderation_config(site_id);
-
-	CREATE TABLE IF NOT EXISTS site_auth_configs (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL UNIQUE,
-		auth_mode TEXT NOT NULL DEFAULT 'external',
-		jwt_validation_type TEXT,
-		jwt_secret TEXT,
-		jwt_public_key TEXT,
-		jwks_endpoint TEXT,
-		jwt_issuer TEXT,
-		jwt_audience TEXT,
-		token_expiration_buffer INTEGER DEFAULT 60,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_site_auth_configs_site ON site_auth_configs(site_id);
-
-	CREATE TABLE IF NOT EXISTS kotomi_auth_users (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		email TEXT NOT NULL,
-		auth0_sub TEXT NOT NULL,
-		name TEXT,
-		avatar_url TEXT,
-		is_verified INTEGER DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
-		UNIQUE(site_id, auth0_sub)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_users_site ON kotomi_auth_users(site_id);
-	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_users_email ON kotomi_auth_users(site_id, email);
-	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_users_auth0 ON kotomi_auth_users(auth0_sub);
-
-	CREATE TABLE IF NOT EXISTS kotomi_auth_sessions (
-		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL,
-		site_id TEXT NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		refresh_token TEXT NOT NULL UNIQUE,
-		expires_at TIMESTAMP NOT NULL,
-		refresh_expires_at TIMESTAMP NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES kotomi_auth_users(id) ON DELETE CASCADE,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_sessions_user ON kotomi_auth_sessions(user_id);
-	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_sessions_token ON kotomi_auth_sessions(token);
-	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_sessions_refresh ON kotomi_auth_sessions(refresh_token);
-
-	CREATE TABLE IF NOT EXISTS notification_settings (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL UNIQUE,
-		enabled INTEGER DEFAULT 0,
-		provider TEXT DEFAULT 'smtp',
-		from_email TEXT NOT NULL,
-		from_name TEXT NOT NULL,
-		reply_to TEXT,
-		smtp_host TEXT,
-		smtp_port INTEGER,
-		smtp_user TEXT,
-		smtp_password TEXT,
-		smtp_encryption TEXT,
-		sendgrid_api_key TEXT,
-		notify_new_comment INTEGER DEFAULT 1,
-		notify_reply INTEGER DEFAULT 1,
-		notify_moderation INTEGER DEFAULT 1,
-		owner_email TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_notification_settings_site ON notification_settings(site_id);
-
-	CREATE TABLE IF NOT EXISTS notification_queue (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		type TEXT NOT NULL,
-		recipient TEXT NOT NULL,
-		subject TEXT NOT NULL,
-		body TEXT NOT NULL,
-		data TEXT,
-		status TEXT DEFAULT 'pending',
-		attempts INTEGER DEFAULT 0,
-		error TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		sent_at TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_notification_queue_site ON notification_queue(site_id);
-	CREATE INDEX IF NOT EXISTS idx_notification_queue_status ON notification_queue(status);
-	CREATE INDEX IF NOT EXISTS idx_notification_queue_created ON notification_queue(created_at);
-
-	CREATE TABLE IF NOT EXISTS notification_log (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		type TEXT NOT NULL,
-		recipient TEXT NOT NULL,
-		subject TEXT NOT NULL,
-		status TEXT NOT NULL,
-		error TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		sent_at TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_notification_log_site ON notification_log(site_id);
-	CREATE INDEX IF NOT EXISTS idx_notification_log_created ON notification_log(created_at);
-	`
+	log.Printf("SQLite database initialized with optimizations: WAL mode, %dMB cache, %d max connections",
+		cfg.CacheSizeKB/1000, cfg.MaxOpenConns)
 
-	if _, err := db.Exec(schema); err != nil {
+	// Create or upgrade the schema
+	if err := ApplySchema(db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to create schema: %w", err)
+		return nil, err
 	}
 
-	// Run migrations for existing databases
-	migrations := []string{
-		// Phase 3: Add reputation_score to users table if it doesn't exist
-		`ALTER TABLE users ADD COLUMN reputation_score INTEGER DEFAULT 0`,
-	}
+	return &SQLiteStore{db: db}, nil
+}
 
-	for _, migration := range migrations {
-		// Try to run migration, ignore only if column already exists
-		_, err := db.Exec(migration)
-		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
-			// Log unexpected errors but don't fail - allows database to work
-			log.Printf("Warning: Migration error (continuing anyway): %v", err)
-		}
+// ApplySchema creates or upgrades the canonical SQLite schema on db. Tests that
+// open their own database use it rather than hand-written tables, so they run
+// against the columns and constraints production has.
+func ApplySchema(db *sql.DB) error {
+	if err := migrate.Migrate(db, "sqlite3"); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
 	}
-
-	return &SQLiteStore{db: db}, nil
+	return nil
 }
 
 // AddPageComment adds a comment to a specific page on a site
@@ -379,6 +231,9 @@ func (s *SQLiteStore) AddPageComment(ctx context.Context, site, page string, com
 	if comment.Status == "" {
 		comment.Status = "pending"
 	}
+	if err := ValidateAttachments(comment.Attachments); err != nil {
+		return err
+	}
 
 	// Auto-create site and page if they don't exist (for testing and standalone use without admin)
 	// This allows the comment system to work without pre-creating sites/pages
@@ -427,9 +282,12 @@ func (s *SQLiteStore) AddPageComment(ctx context.Context, site, page string, com
 		}
 	}
 
+	// Replies must point at an existing comment on the same site and page. The check is part
+	// of the INSERT so it runs atomically with it and can't race a concurrent delete.
 	query := `
-		INSERT INTO comments (id, site_id, page_id, author, author_id, author_email, text, parent_id, status, moderated_by, moderated_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO comments (id, site_id, page_id, author, author_id, author_email, text, parent_id, status, moderated_by, moderated_at, created_at, updated_at, language, sentiment)
+		SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15
+		WHERE ?8 IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = ?8 AND site_id = ?2 AND page_id = ?3)
 	`
 
 	// Convert empty ParentID to NULL
@@ -460,7 +318,14 @@ func (s *SQLiteStore) AddPageComment(ctx context.Context, site, page string, com
 		authorEmail.Valid = true
 	}
 
-	_, err = s.db.ExecContext(ctx, query,
+	// The comment and its attachments are stored together
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query,
 		comment.ID,
 		site,
 		page,
@@ -474,34 +339,122 @@ func (s *SQLiteStore) AddPageComment(ctx context.Context, site, page string, com
 		moderatedAt,
 		comment.CreatedAt,
 		comment.UpdatedAt,
+		sql.NullString{String: comment.Language, Valid: comment.Language != ""},
+		sql.NullString{String: comment.Sentiment, Valid: comment.Sentiment != ""},
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert comment: %w", err)
 	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: parent comment %s does not exist on page %s", ErrInvalidParent, comment.ParentID, page)
+	}
+
+	if err := insertAttachments(ctx, tx, comment.ID, comment.Attachments, comment.CreatedAt); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-// GetPageComments retrieves all comments for a specific page on a site
+// GetPageComments retrieves all comments for a specific page on a site, oldest first
 func (s *SQLiteStore) GetPageComments(ctx context.Context, site, page string) ([]Comment, error) {
-	query := `
+	return s.GetPageCommentsOrdered(ctx, site, page, OrderOldest)
+}
+
+// GetPageCommentsOrdered retrieves all comments for a page in the given order:
+// OrderOldest, OrderNewest or OrderMostReacted (ties oldest first). Any other
+// value returns ErrInvalidOrder.
+func (s *SQLiteStore) GetPageCommentsOrdered(ctx context.Context, site, page, order string) ([]Comment, error) {
+	orderBy, ok := pageCommentOrders[order]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOrder, order)
+	}
+
+	reactionJoin := ""
+	var args []interface{}
+	if order == OrderMostReacted {
+		// Count only the reactions on this page's comments
+		reactionJoin = `
+		LEFT JOIN (
+			SELECT r.comment_id, COUNT(*) AS reaction_count
+			FROM reactions r
+			JOIN comments rc_c ON rc_c.id = r.comment_id
+			WHERE rc_c.site_id = ? AND rc_c.page_id = ?
+			GROUP BY r.comment_id
+		) rc ON rc.comment_id = c.id`
+		args = append(args, site, page)
+	}
+	args = append(args, site, page)
+
+	query := pageCommentColumns + reactionJoin + `
+		WHERE c.site_id = ? AND c.page_id = ?
+		ORDER BY ` + orderBy
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanPageCommentsWithAttachments(ctx, rows)
+}
+
+// pageCommentColumns are the columns read by scanPageComments, selected from
+// comments c joined with the author's users row u
+const pageCommentColumns = `
 		SELECT c.id, c.author, c.author_id, c.author_email, c.text, c.parent_id, c.status, 
 		       c.moderated_by, c.moderated_at, c.created_at, c.updated_at,
 		       COALESCE(u.is_verified, 0) as author_verified,
-		       COALESCE(u.reputation_score, 0) as author_reputation
+		       COALESCE(u.reputation_score, 0) as author_reputation,
+		       COALESCE(c.language, ''), COALESCE(c.sentiment, ''),
+		       COALESCE(u.avatar_url, '') as author_avatar_url
 		FROM comments c
-		LEFT JOIN users u ON c.site_id = u.site_id AND c.author_id = u.id
-		WHERE c.site_id = ? AND c.page_id = ?
-		ORDER BY c.created_at ASC
-	`
-
-	rows, err := s.db.QueryContext(ctx, query, site, page)
+		LEFT JOIN users u ON c.site_id = u.site_id AND c.author_id = u.id`
+
+// GetPageCommentsSince retrieves a page's comments created or updated after
+// since, oldest first, so polling clients only fetch what changed
+func (s *SQLiteStore) GetPageCommentsSince(ctx context.Context, site, page string, since time.Time) ([]Comment, error) {
+	query := pageCommentColumns + `
+		WHERE c.site_id = ? AND c.page_id = ? AND (c.created_at > ? OR c.updated_at > ?)
+		ORDER BY c.created_at ASC, c.id ASC`
+
+	// Timestamps are stored in local time and compared as text, so bind since
+	// in the same zone
+	since = since.Local()
+	rows, err := s.db.QueryContext(ctx, query, site, page, since, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query comments: %w", err)
 	}
 	defer rows.Close()
 
+	return s.scanPageCommentsWithAttachments(ctx, rows)
+}
+
+// scanPageCommentsWithAttachments reads comments selected with
+// pageCommentColumns along with their attachments
+func (s *SQLiteStore) scanPageCommentsWithAttachments(ctx context.Context, rows *sql.Rows) ([]Comment, error) {
+	comments, err := scanPageComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := s.loadAttachments(ctx, comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// scanPageComments reads comments selected with pageCommentColumns
+func scanPageComments(rows *sql.Rows) ([]Comment, error) {
 	var comments []Comment
 	for rows.Next() {
 		var c Comment
@@ -511,7 +464,8 @@ func (s *SQLiteStore) GetPageComments(ctx context.Context, site, page string) ([
 		var authorEmail sql.NullString
 
 		err := rows.Scan(&c.ID, &c.Author, &c.AuthorID, &authorEmail, &c.Text, &parentID, &c.Status, 
-			&moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt, &c.AuthorVerified, &c.AuthorReputation)
+			&moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt, &c.AuthorVerified, &c.AuthorReputation,
+			&c.Language, &c.Sentiment, &c.AuthorAvatarURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan comment: %w", err)
 		}
@@ -544,6 +498,115 @@ func (s *SQLiteStore) GetPageComments(ctx context.Context, site, page string) ([
 	return comments, nil
 }
 
+// PageCommentsStamp summarizes a page's comments so responses can be
+// validated without loading them
+type PageCommentsStamp struct {
+	Count       int
+	LastUpdated time.Time // zero when the page has no comments
+}
+
+// GetPageCommentsStamp returns how many comments a page has and when the most
+// recent change to one of them happened
+func (s *SQLiteStore) GetPageCommentsStamp(ctx context.Context, site, page string) (PageCommentsStamp, error) {
+	// Selecting the column itself (rather than MAX) keeps its TIMESTAMP type
+	// so the driver parses it
+	query := `
+		SELECT updated_at, COUNT(*) OVER ()
+		FROM comments
+		WHERE site_id = ? AND page_id = ?
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var stamp PageCommentsStamp
+	err := s.db.QueryRowContext(ctx, query, site, page).Scan(&stamp.LastUpdated, &stamp.Count)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return PageCommentsStamp{}, nil
+		}
+		return PageCommentsStamp{}, fmt.Errorf("failed to query comments stamp: %w", err)
+	}
+
+	return stamp, nil
+}
+
+// commentCountBatchSize caps the page IDs bound into a single GetCommentCounts
+// query, keeping well under SQLite's variable limit
+const commentCountBatchSize = 500
+
+// GetCommentCounts returns the number of approved comments on each of a site's
+// pages, keyed by page ID. Every requested page has an entry, 0 when it has no
+// approved comments.
+func (s *SQLiteStore) GetCommentCounts(ctx context.Context, siteID string, pageIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(pageIDs))
+	for _, id := range pageIDs {
+		counts[id] = 0
+	}
+
+	for start := 0; start < len(pageIDs); start += commentCountBatchSize {
+		end := start + commentCountBatchSize
+		if end > len(pageIDs) {
+			end = len(pageIDs)
+		}
+		batch := pageIDs[start:end]
+
+		placeholders := make([]string, len(batch))
+		args := []interface{}{siteID}
+		for i, id := range batch {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+
+		query := fmt.Sprintf(`
+			SELECT page_id, COUNT(*)
+			FROM comments
+			WHERE site_id = ? AND status = 'approved' AND page_id IN (%s)
+			GROUP BY page_id
+		`, strings.Join(placeholders, ", "))
+
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query comment counts: %w", err)
+		}
+		for rows.Next() {
+			var pageID string
+			var count int
+			if err := rows.Scan(&pageID, &count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan comment count: %w", err)
+			}
+			counts[pageID] = count
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating comment counts: %w", err)
+		}
+	}
+
+	return counts, nil
+}
+
+// GetPendingCount returns how many of a site's comments await moderation
+func (s *SQLiteStore) GetPendingCount(ctx context.Context, siteID string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE status = 'pending' AND site_id = ?`, siteID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending comments: %w", err)
+	}
+	return count, nil
+}
+
+// GetApprovedPageComments retrieves only the approved comments for a page, as
+// shown publicly. See ApprovedThread for how replies are threaded.
+func (s *SQLiteStore) GetApprovedPageComments(ctx context.Context, site, page string) ([]Comment, error) {
+	all, err := s.GetPageComments(ctx, site, page)
+	if err != nil {
+		return nil, err
+	}
+	return ApprovedThread(all), nil
+}
+
 // Close closes the database connection
 func (s *SQLiteStore) Close() error {
 	if s.db != nil {
@@ -557,26 +620,100 @@ func (s *SQLiteStore) GetDB() *sql.DB {
 	return s.db
 }
 
+// SiteCommentFilter narrows and pages the comments listed for a site
+type SiteCommentFilter struct {
+	Status   string    // optional: pending, approved or rejected
+	AuthorID string    // optional
+	Language string    // optional: ISO 639-1 code as detected when the comment was posted
+	From     time.Time // optional: only comments created at or after From
+	To       time.Time // optional: only comments created at or before To
+	// ModeratedBy optionally keeps only the comments this moderator last acted
+	// on. From and To then bound moderated_at instead of created_at, and
+	// comments are ordered by moderated_at, most recent decision first.
+	ModeratedBy string
+	Limit       int // page size; 0 returns every matching comment
+	Offset      int
+}
+
+// SiteCommentPage is one page of a site's comments with the total number of
+// comments matching the filter
+type SiteCommentPage struct {
+	Comments []Comment `json:"comments"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
+
 // GetCommentsBySite retrieves all comments for a specific site
 func (s *SQLiteStore) GetCommentsBySite(ctx context.Context, siteID string, status string) ([]Comment, error) {
+	page, err := s.GetCommentsBySiteFiltered(ctx, siteID, SiteCommentFilter{Status: status})
+	if err != nil {
+		return nil, err
+	}
+	return page.Comments, nil
+}
+
+// GetCommentsBySiteFiltered retrieves a page of a site's comments, newest first,
+// along with the total count of comments matching the filter
+func (s *SQLiteStore) GetCommentsBySiteFiltered(ctx context.Context, siteID string, filter SiteCommentFilter) (*SiteCommentPage, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	where := " WHERE c.site_id = ?"
+	args := []interface{}{siteID}
+
+	if filter.Status != "" {
+		where += " AND c.status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.AuthorID != "" {
+		where += " AND c.author_id = ?"
+		args = append(args, filter.AuthorID)
+	}
+	if filter.Language != "" {
+		where += " AND c.language = ?"
+		args = append(args, filter.Language)
+	}
+	dateColumn, orderBy := "c.created_at", "c.created_at DESC, c.id"
+	if filter.ModeratedBy != "" {
+		where += " AND c.moderated_by = ?"
+		args = append(args, filter.ModeratedBy)
+		dateColumn, orderBy = "c.moderated_at", "c.moderated_at DESC, c.id"
+	}
+	if !filter.From.IsZero() {
+		where += " AND " + dateColumn + " >= ?"
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		where += " AND " + dateColumn + " <= ?"
+		args = append(args, filter.To)
+	}
+
+	page := &SiteCommentPage{Limit: filter.Limit, Offset: filter.Offset}
+
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments c"+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count comments: %w", err)
+	}
+
 	query := `
 		SELECT c.id, c.site_id, c.page_id, c.author, c.author_id, c.author_email, c.text, c.parent_id, 
 		       c.status, c.moderated_by, c.moderated_at, c.created_at, c.updated_at,
 		       COALESCE(u.is_verified, 0) as author_verified,
-		       COALESCE(u.reputation_score, 0) as author_reputation
+		       COALESCE(u.reputation_score, 0) as author_reputation,
+		       COALESCE(c.language, ''), COALESCE(c.sentiment, '')
 		FROM comments c
 		LEFT JOIN users u ON c.site_id = u.site_id AND c.author_id = u.id
-		WHERE c.site_id = ?
-	`
-	args := []interface{}{siteID}
-
-	if status != "" {
-		query += " AND c.status = ?"
-		args = append(args, status)
+	` + where + " ORDER BY " + orderBy
+
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, filter.Offset)
 	}
 
-	query += " ORDER BY c.created_at DESC"
-
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query comments: %w", err)
@@ -593,7 +730,8 @@ func (s *SQLiteStore) GetCommentsBySite(ctx context.Context, siteID string, stat
 		var authorEmail sql.NullString
 
 		err := rows.Scan(&c.ID, &c.SiteID, &pageID, &c.Author, &c.AuthorID, &authorEmail, &c.Text, &parentID, 
-			&c.Status, &moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt, &c.AuthorVerified, &c.AuthorReputation)
+			&c.Status, &moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt, &c.AuthorVerified, &c.AuthorReputation,
+			&c.Language, &c.Sentiment)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan comment: %w", err)
 		}
@@ -621,17 +759,141 @@ func (s *SQLiteStore) GetCommentsBySite(ctx context.Context, siteID string, stat
 	if comments == nil {
 		comments = []Comment{}
 	}
+	page.Comments = comments
+
+	return page, nil
+}
+
+// GetCommentsByAuthor retrieves a page of the comments authorID wrote on a
+// site, newest first and in every status, each with its page's ID and path
+func (s *SQLiteStore) GetCommentsByAuthor(ctx context.Context, siteID, authorID string, limit, offset int) ([]Comment, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+	if limit == 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	query := `
+		SELECT c.id, c.site_id, c.page_id, COALESCE(p.path, ''), c.author, c.author_id, c.author_email,
+		       c.text, c.parent_id, c.status, c.moderated_by, c.moderated_at, c.created_at, c.updated_at,
+		       COALESCE(c.language, ''), COALESCE(c.sentiment, '')
+		FROM comments c
+		LEFT JOIN pages p ON p.id = c.page_id
+		WHERE c.author_id = ? AND c.site_id = ?
+		ORDER BY c.created_at DESC, c.id
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, authorID, siteID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		var parentID, moderatedBy, authorEmail sql.NullString
+		var moderatedAt sql.NullTime
+
+		err := rows.Scan(&c.ID, &c.SiteID, &c.PageID, &c.PagePath, &c.Author, &c.AuthorID, &authorEmail,
+			&c.Text, &parentID, &c.Status, &moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt,
+			&c.Language, &c.Sentiment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		c.ParentID = parentID.String
+		c.ModeratedBy = moderatedBy.String
+		c.AuthorEmail = authorEmail.String
+		if moderatedAt.Valid {
+			c.ModeratedAt = moderatedAt.Time
+		}
+
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
 
 	return comments, nil
 }
 
-// GetCommentByID retrieves a comment by its ID
+// SearchApprovedPageComments returns up to limit approved comments on a page
+// whose text contains query, newest first. Matching is a case-insensitive
+// substring match (for ASCII letters, as with SQLite's LIKE).
+func (s *SQLiteStore) SearchApprovedPageComments(ctx context.Context, site, page, query string, limit int) ([]Comment, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, site_id, page_id, author, author_id, author_email, text, parent_id, status,
+		       moderated_by, moderated_at, created_at, updated_at,
+		       COALESCE(language, ''), COALESCE(sentiment, '')
+		FROM comments
+		WHERE site_id = ? AND page_id = ? AND status = 'approved' AND text LIKE ? ESCAPE '\'
+		ORDER BY created_at DESC, id
+		LIMIT ?
+	`, site, page, "%"+EscapeLike(query)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		var parentID, moderatedBy, authorEmail sql.NullString
+		var moderatedAt sql.NullTime
+
+		err := rows.Scan(&c.ID, &c.SiteID, &c.PageID, &c.Author, &c.AuthorID, &authorEmail,
+			&c.Text, &parentID, &c.Status, &moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt,
+			&c.Language, &c.Sentiment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		c.ParentID = parentID.String
+		c.ModeratedBy = moderatedBy.String
+		c.AuthorEmail = authorEmail.String
+		if moderatedAt.Valid {
+			c.ModeratedAt = moderatedAt.Time
+		}
+
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+
+	return comments, nil
+}
+
+// GetCommentByID retrieves a comment by its ID regardless of site. Callers
+// serving a specific site must check the returned SiteID themselves, or use
+// GetCommentByIDForSite, or risk leaking comments across tenants.
 func (s *SQLiteStore) GetCommentByID(ctx context.Context, commentID string) (*Comment, error) {
+	return s.getComment(ctx, `WHERE id = ?`, commentID)
+}
+
+// GetCommentByIDForSite retrieves a comment by its ID only if it belongs to
+// siteID, returning "comment not found" otherwise
+func (s *SQLiteStore) GetCommentByIDForSite(ctx context.Context, commentID, siteID string) (*Comment, error) {
+	return s.getComment(ctx, `WHERE id = ? AND site_id = ?`, commentID, siteID)
+}
+
+// getComment retrieves the single comment matched by the where clause
+func (s *SQLiteStore) getComment(ctx context.Context, where string, args ...interface{}) (*Comment, error) {
 	query := `
-		SELECT id, site_id, page_id, author, author_id, author_email, text, parent_id, status, moderated_by, moderated_at, created_at, updated_at
+		SELECT id, site_id, page_id, author, author_id, author_email, text, parent_id, status, moderated_by, moderated_at, created_at, updated_at,
+		       COALESCE(language, ''), COALESCE(sentiment, ''),
+		       COALESCE((SELECT u.avatar_url FROM users u WHERE u.site_id = comments.site_id AND u.id = comments.author_id), '')
 		FROM comments
-		WHERE id = ?
-	`
+	` + where
 
 	var c Comment
 	var pageID string // Scanned but not included in returned Comment struct
@@ -640,8 +902,9 @@ func (s *SQLiteStore) GetCommentByID(ctx context.Context, commentID string) (*Co
 	var moderatedBy sql.NullString
 	var moderatedAt sql.NullTime
 
-	err := s.db.QueryRowContext(ctx, query, commentID).Scan(
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
 		&c.ID, &c.SiteID, &pageID, &c.Author, &c.AuthorID, &authorEmail, &c.Text, &parentID, &c.Status, &moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt,
+		&c.Language, &c.Sentiment, &c.AuthorAvatarURL,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -663,7 +926,12 @@ func (s *SQLiteStore) GetCommentByID(ctx context.Context, commentID string) (*Co
 		c.ModeratedAt = moderatedAt.Time
 	}
 
-	return &c, nil
+	loaded := []Comment{c}
+	if err := s.loadAttachments(ctx, loaded); err != nil {
+		return nil, err
+	}
+
+	return &loaded[0], nil
 }
 
 // UpdateCommentStatus updates the status of a comment
@@ -683,16 +951,87 @@ func (s *SQLiteStore) UpdateCommentStatus(ctx context.Context, commentID, status
 	return nil
 }
 
-// UpdateCommentText updates the text content of a comment
-func (s *SQLiteStore) UpdateCommentText(ctx context.Context, commentID, text string) error {
+// statusBatchSize caps the comment IDs bound into a single
+// UpdateCommentStatusBatch statement, keeping well under SQLite's variable limit
+const statusBatchSize = 500
+
+// UpdateCommentStatusBatch sets status on every comment in commentIDs inside a
+// single transaction and returns the IDs of the comments it updated. IDs that
+// don't exist are ignored; any database error rolls back the whole batch.
+func (s *SQLiteStore) UpdateCommentStatusBatch(ctx context.Context, commentIDs []string, status, moderatorID string) ([]string, error) {
+	if len(commentIDs) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var updated []string
+	for start := 0; start < len(commentIDs); start += statusBatchSize {
+		end := start + statusBatchSize
+		if end > len(commentIDs) {
+			end = len(commentIDs)
+		}
+		batch := commentIDs[start:end]
+
+		placeholders := make([]string, len(batch))
+		args := []interface{}{status, moderatorID, now, now}
+		for i, id := range batch {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+
+		query := fmt.Sprintf(`
+			UPDATE comments
+			SET status = ?, moderated_by = ?, moderated_at = ?, updated_at = ?
+			WHERE id IN (%s)
+			RETURNING id
+		`, strings.Join(placeholders, ", "))
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update comment status: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan updated comment: %w", err)
+			}
+			updated = append(updated, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to update comment status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return updated, nil
+}
+
+// UpdateCommentText updates the text content of a comment, as long as it is
+// still at the version last updated at expectedUpdatedAt. A comment changed
+// since returns ErrConcurrentModification and a missing one ErrCommentNotFound.
+func (s *SQLiteStore) UpdateCommentText(ctx context.Context, commentID, text string, expectedUpdatedAt time.Time) error {
 	query := `
 		UPDATE comments
 		SET text = ?, updated_at = ?
-		WHERE id = ?
+		WHERE id = ? AND julianday(updated_at) = julianday(?)
 	`
 
+	// Compare as instants rather than text: imported rows may be stored in
+	// another zone than the one this process writes in
 	now := time.Now()
-	result, err := s.db.ExecContext(ctx, query, text, now, commentID)
+	result, err := s.db.ExecContext(ctx, query, text, now, commentID, expectedUpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to update comment text: %w", err)
 	}
@@ -703,7 +1042,15 @@ func (s *SQLiteStore) UpdateCommentText(ctx context.Context, commentID, text str
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("comment not found")
+		var exists int
+		err := s.db.QueryRowContext(ctx, `SELECT 1 FROM comments WHERE id = ?`, commentID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return ErrCommentNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check comment: %w", err)
+		}
+		return ErrConcurrentModification
 	}
 
 	return nil
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -247,3 +248,46 @@ func TestDatabasePingHealthCheck(t *testing.T) {
 
 	t.Log("✓ Database health check works")
 }
+
+// TestNewSQLiteStoreWithConfig verifies that custom pool limits and pragmas are applied
+func TestNewSQLiteStoreWithConfig(t *testing.T) {
+	dbPath := t.TempDir() + "/custom.db"
+
+	store, err := NewSQLiteStoreWithConfig(dbPath, StoreConfig{
+		MaxOpenConns: 3,
+		MaxIdleConns: 2,
+		CacheSizeKB:  8000,
+		BusyTimeout:  2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	db := store.GetDB()
+	if stats := db.Stats(); stats.MaxOpenConnections != 3 {
+		t.Errorf("MaxOpenConnections: expected 3, got %d", stats.MaxOpenConnections)
+	}
+
+	for pragma, expected := range map[string]string{
+		"PRAGMA busy_timeout": "2000",
+		"PRAGMA cache_size":   "-8000",
+	} {
+		var value string
+		if err := db.QueryRow(pragma).Scan(&value); err != nil {
+			t.Fatalf("Failed to query %s: %v", pragma, err)
+		}
+		if value != expected {
+			t.Errorf("%s: expected %s, got %s", pragma, expected, value)
+		}
+	}
+
+	// Fields left zero keep their defaults (mmap reads 0 where it is unsupported)
+	var mmapSize string
+	if err := db.QueryRow("PRAGMA mmap_size").Scan(&mmapSize); err != nil {
+		t.Fatalf("Failed to query mmap_size: %v", err)
+	}
+	if mmapSize != "0" && mmapSize != "268435456" {
+		t.Errorf("mmap_size: expected default 268435456, got %s", mmapSize)
+	}
+}
@@ -0,0 +1,19 @@
+package comments
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// IsWriteConflict reports whether err comes from a write that lost a race with
+// a concurrent one: the unique index rejected a duplicate, or SQLite refused the
+// write because another transaction held the lock or committed first
+func IsWriteConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
@@ -0,0 +1,34 @@
+package comments
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestIsWriteConflict(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE t (id TEXT NOT NULL UNIQUE, v TEXT NOT NULL)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO t (id, v) VALUES ('a', 'x')`); err != nil {
+		t.Fatalf("Failed to insert row: %v", err)
+	}
+
+	_, duplicate := db.Exec(`INSERT INTO t (id, v) VALUES ('a', 'y')`)
+	if !IsWriteConflict(duplicate) {
+		t.Errorf("Expected a duplicate key to be a write conflict, got %v", duplicate)
+	}
+	_, notNull := db.Exec(`INSERT INTO t (id) VALUES ('b')`)
+	if notNull == nil || IsWriteConflict(notNull) {
+		t.Errorf("Expected a NOT NULL violation not to be a write conflict, got %v", notNull)
+	}
+	if IsWriteConflict(errors.New("boom")) || IsWriteConflict(nil) {
+		t.Error("Expected non-SQLite errors not to be write conflicts")
+	}
+}
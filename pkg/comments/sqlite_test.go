@@ -2,8 +2,11 @@ package comments
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -51,6 +54,81 @@ func TestNewSQLiteStore_InvalidPath(t *testing.T) {
 	}
 }
 
+// TestApplySchema_ProductionColumns writes a comment through every column of
+// the shared schema's comments table, so tests built on ApplySchema fail when
+// a column they rely on is dropped or a new one isn't covered here
+func TestApplySchema_ProductionColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "schema.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Applying it again is a no-op, as when a test reuses a database
+	for i := 0; i < 2; i++ {
+		if err := ApplySchema(db); err != nil {
+			t.Fatalf("ApplySchema failed: %v", err)
+		}
+	}
+
+	values := map[string]interface{}{
+		"id":           "comment-1",
+		"site_id":      "site-1",
+		"page_id":      "page-1",
+		"author":       "Alice",
+		"author_id":    "alice",
+		"author_email": "alice@example.com",
+		"text":         "Hello",
+		"parent_id":    "comment-0",
+		"status":       "approved",
+		"moderated_by": "admin-1",
+		"moderated_at": time.Now(),
+		"language":     "en",
+		"sentiment":    "positive",
+		"created_at":   time.Now(),
+		"updated_at":   time.Now(),
+	}
+
+	rows, err := db.Query("SELECT name FROM pragma_table_info('comments')")
+	if err != nil {
+		t.Fatalf("failed to read comments columns: %v", err)
+	}
+	var columns, placeholders []string
+	var args []interface{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("failed to scan column: %v", err)
+		}
+		value, ok := values[name]
+		if !ok {
+			t.Errorf("comments.%s has no test value", name)
+			continue
+		}
+		columns = append(columns, name)
+		placeholders = append(placeholders, "?")
+		args = append(args, value)
+	}
+	rows.Close()
+	if len(columns) != len(values) {
+		t.Fatalf("expected the schema to have the %d columns under test, got %v", len(values), columns)
+	}
+
+	query := fmt.Sprintf("INSERT INTO comments (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("failed to insert comment: %v", err)
+	}
+
+	comment, err := (&SQLiteStore{db: db}).GetCommentByID(context.Background(), "comment-1")
+	if err != nil {
+		t.Fatalf("GetCommentByID failed: %v", err)
+	}
+	if comment.AuthorID != "alice" || comment.AuthorEmail != "alice@example.com" || comment.ModeratedBy != "admin-1" ||
+		comment.Language != "en" || comment.Sentiment != "positive" || comment.ParentID != "comment-0" {
+		t.Errorf("expected every column to round-trip, got %+v", comment)
+	}
+}
+
 func TestSQLiteStore_AddPageComment_Success(t *testing.T) {
 	store, _ := createTestDB(t)
 	defer store.Close()
@@ -168,6 +246,24 @@ func TestSQLiteStore_GetPageComments_Empty(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_GetPageComments_CancelledContext(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	comment := Comment{ID: "comment-1", Author: "Alice", AuthorID: "alice", Text: "Hello", Status: "approved"}
+	if err := store.AddPageComment(context.Background(), "site1", "page1", comment); err != nil {
+		t.Fatalf("AddPageComment failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := store.GetPageComments(ctx, "site1", "page1")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v (%d comments)", err, len(got))
+	}
+}
+
 func TestSQLiteStore_GetPageComments_MultipleComments(t *testing.T) {
 	store, _ := createTestDB(t)
 	defer store.Close()
@@ -256,6 +352,67 @@ func TestSQLiteStore_GetPageComments_WithParentID(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_GetPageCommentsOrdered(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c", "d"} {
+		c := Comment{ID: id, Author: "John", Text: "Comment " + id, CreatedAt: base.Add(time.Duration(i) * time.Minute), UpdatedAt: base}
+		if err := store.AddPageComment(ctx, "site1", "page1", c); err != nil {
+			t.Fatalf("failed to add comment %s: %v", id, err)
+		}
+	}
+	// A busy comment on another page must not affect this page's ordering
+	if err := store.AddPageComment(ctx, "site1", "page2", Comment{ID: "other", Author: "John", Text: "Elsewhere"}); err != nil {
+		t.Fatalf("failed to add comment: %v", err)
+	}
+
+	// b gets two reactions, c and other one each, a and d none
+	setup := []string{
+		`INSERT INTO allowed_reactions (id, site_id, name, emoji) VALUES ('like', 'site1', 'like', '👍')`,
+		`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES
+			('r1', 'b', 'like', 'u1'), ('r2', 'b', 'like', 'u2'), ('r3', 'c', 'like', 'u1'),
+			('r4', 'other', 'like', 'u1')`,
+	}
+	for _, stmt := range setup {
+		if _, err := store.GetDB().Exec(stmt); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{OrderOldest, []string{"a", "b", "c", "d"}},
+		{OrderNewest, []string{"d", "c", "b", "a"}},
+		{OrderMostReacted, []string{"b", "c", "a", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			got, err := store.GetPageCommentsOrdered(ctx, "site1", "page1", tt.order)
+			if err != nil {
+				t.Fatalf("GetPageCommentsOrdered failed: %v", err)
+			}
+			ids := make([]string, len(got))
+			for i, c := range got {
+				ids[i] = c.ID
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+
+	for _, order := range []string{"", "created_at; DROP TABLE comments", "OLDEST"} {
+		if _, err := store.GetPageCommentsOrdered(ctx, "site1", "page1", order); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("order %q: expected ErrInvalidOrder, got %v", order, err)
+		}
+	}
+}
+
 func TestSQLiteStore_Persistence(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -495,7 +652,7 @@ func TestSQLiteStore_UpdateCommentText(t *testing.T) {
 
 	// Update the comment text
 	newText := "Updated text content"
-	err = store.UpdateCommentText(context.Background(), comment.ID, newText)
+	err = store.UpdateCommentText(context.Background(), comment.ID, newText, comment.UpdatedAt)
 	if err != nil {
 		t.Fatalf("UpdateCommentText failed: %v", err)
 	}
@@ -527,7 +684,7 @@ func TestSQLiteStore_UpdateCommentText_NotFound(t *testing.T) {
 	store, _ := createTestDB(t)
 	defer store.Close()
 
-	err := store.UpdateCommentText(context.Background(), "nonexistent-id", "Some text")
+	err := store.UpdateCommentText(context.Background(), "nonexistent-id", "Some text", time.Now())
 	if err == nil {
 		t.Error("expected error for non-existent comment, got nil")
 	}
@@ -536,3 +693,181 @@ func TestSQLiteStore_UpdateCommentText_NotFound(t *testing.T) {
 	}
 }
 
+// TestSQLiteStore_UpdateCommentText_StaleVersion tests that an edit based on an
+// outdated updated_at doesn't overwrite a newer one
+func TestSQLiteStore_UpdateCommentText_StaleVersion(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	loaded := time.Now().Add(-time.Minute)
+	comment := Comment{ID: "c1", Author: "John", Text: "Original", Status: "approved", CreatedAt: loaded, UpdatedAt: loaded}
+	if err := store.AddPageComment(ctx, "site1", "page1", comment); err != nil {
+		t.Fatalf("failed to add comment: %v", err)
+	}
+
+	// The first editor saves against the version they loaded
+	if err := store.UpdateCommentText(ctx, "c1", "First edit", loaded); err != nil {
+		t.Fatalf("UpdateCommentText failed: %v", err)
+	}
+
+	// The second editor loaded the same version, which is now stale
+	err := store.UpdateCommentText(ctx, "c1", "Second edit", loaded)
+	if !errors.Is(err, ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+
+	current, err := store.GetCommentByID(ctx, "c1")
+	if err != nil {
+		t.Fatalf("failed to get comment: %v", err)
+	}
+	if current.Text != "First edit" {
+		t.Errorf("expected the first edit to be kept, got %q", current.Text)
+	}
+
+	// Retrying against the current version succeeds
+	if err := store.UpdateCommentText(ctx, "c1", "Second edit", current.UpdatedAt); err != nil {
+		t.Errorf("expected the refetched version to be accepted, got %v", err)
+	}
+}
+
+// TestSQLiteStore_UpdateCommentText_OtherZone tests that a comment stored with
+// a timestamp in another zone, as imports do, can still be edited
+func TestSQLiteStore_UpdateCommentText_OtherZone(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	stored := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("UTC+5:45", 5*3600+45*60))
+	comment := Comment{ID: "c1", Author: "John", Text: "Imported", Status: "approved", CreatedAt: stored, UpdatedAt: stored}
+	if err := store.AddPageComment(ctx, "site1", "page1", comment); err != nil {
+		t.Fatalf("failed to add comment: %v", err)
+	}
+
+	// The client echoes the instant back in UTC, as JSON clients do
+	if err := store.UpdateCommentText(ctx, "c1", "Edited", stored.UTC()); err != nil {
+		t.Fatalf("expected the edit to be accepted, got %v", err)
+	}
+
+	// A different instant is still a conflict
+	if err := store.UpdateCommentText(ctx, "c1", "Again", stored.UTC()); !errors.Is(err, ErrConcurrentModification) {
+		t.Errorf("expected ErrConcurrentModification, got %v", err)
+	}
+}
+
+func TestSQLiteStore_AddPageComment_ParentValidation(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	parent := Comment{ID: "parent", Author: "John", Text: "Parent"}
+	if err := store.AddPageComment(ctx, "site1", "page1", parent); err != nil {
+		t.Fatalf("failed to add parent: %v", err)
+	}
+
+	// Valid parent on the same page
+	reply := Comment{ID: "reply", Author: "Jane", Text: "Reply", ParentID: "parent"}
+	if err := store.AddPageComment(ctx, "site1", "page1", reply); err != nil {
+		t.Fatalf("expected reply to valid parent to succeed, got %v", err)
+	}
+
+	// Missing parent
+	orphan := Comment{ID: "orphan", Author: "Jane", Text: "Orphan", ParentID: "does-not-exist"}
+	err := store.AddPageComment(ctx, "site1", "page1", orphan)
+	if !errors.Is(err, ErrInvalidParent) {
+		t.Errorf("expected ErrInvalidParent for missing parent, got %v", err)
+	}
+
+	// Parent on a different page
+	crossPage := Comment{ID: "cross", Author: "Jane", Text: "Cross page", ParentID: "parent"}
+	err = store.AddPageComment(ctx, "site1", "page2", crossPage)
+	if !errors.Is(err, ErrInvalidParent) {
+		t.Errorf("expected ErrInvalidParent for parent on another page, got %v", err)
+	}
+
+	// Rejected replies must not be stored
+	if _, err := store.GetCommentByID(ctx, "orphan"); err == nil {
+		t.Error("expected orphan comment not to be stored")
+	}
+	if _, err := store.GetCommentByID(ctx, "cross"); err == nil {
+		t.Error("expected cross-page comment not to be stored")
+	}
+}
+
+func TestSQLiteStore_GetCommentCounts(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	seed := []struct {
+		site, page, status string
+	}{
+		{"site1", "busy", "approved"},
+		{"site1", "busy", "approved"},
+		{"site1", "busy", "pending"},
+		{"site1", "quiet", "approved"},
+		{"site1", "hidden", "rejected"},
+		{"site2", "busy", "approved"},
+	}
+	for i, s := range seed {
+		c := Comment{ID: fmt.Sprintf("c%d", i), Author: "A", Text: "Hello", Status: s.status}
+		if err := store.AddPageComment(ctx, s.site, s.page, c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	counts, err := store.GetCommentCounts(ctx, "site1", []string{"busy", "quiet", "hidden", "empty"})
+	if err != nil {
+		t.Fatalf("GetCommentCounts failed: %v", err)
+	}
+	want := map[string]int{"busy": 2, "quiet": 1, "hidden": 0, "empty": 0}
+	if len(counts) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, counts)
+	}
+	for page, n := range want {
+		if got, ok := counts[page]; !ok || got != n {
+			t.Errorf("Expected %d comments on %s, got %d (present: %v)", n, page, got, ok)
+		}
+	}
+
+	// More pages than fit in one query are split across batches
+	pageIDs := make([]string, commentCountBatchSize*2+1)
+	for i := range pageIDs {
+		pageIDs[i] = fmt.Sprintf("page-%d", i)
+	}
+	pageIDs[len(pageIDs)-1] = "busy"
+	counts, err = store.GetCommentCounts(ctx, "site1", pageIDs)
+	if err != nil {
+		t.Fatalf("GetCommentCounts failed for a large batch: %v", err)
+	}
+	if len(counts) != len(pageIDs) || counts["busy"] != 2 || counts["page-0"] != 0 {
+		t.Errorf("Unexpected counts for a large batch: %d entries, busy=%d", len(counts), counts["busy"])
+	}
+}
+
+func TestSQLiteStore_GetPendingCount(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	for i, s := range []struct{ site, status string }{
+		{"site1", "pending"},
+		{"site1", "pending"},
+		{"site1", "approved"},
+		{"site1", "rejected"},
+		{"site2", "pending"},
+	} {
+		c := Comment{ID: fmt.Sprintf("c%d", i), Author: "A", Text: "Hello", Status: s.status}
+		if err := store.AddPageComment(ctx, s.site, "page", c); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+
+	count, err := store.GetPendingCount(ctx, "site1")
+	if err != nil {
+		t.Fatalf("GetPendingCount failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 pending comments, got %d", count)
+	}
+}
@@ -0,0 +1,158 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Supported formats for StreamCommentsBySite
+const (
+	StreamFormatJSON = "json"
+	StreamFormatCSV  = "csv"
+)
+
+// streamFlushInterval is the number of rows written between flushes of the output
+const streamFlushInterval = 100
+
+// StreamedComment is a comment as written by StreamCommentsBySite, including its page
+type StreamedComment struct {
+	Comment
+	PageID string `json:"page_id"`
+}
+
+// streamCSVHeader is the header row for CSV comment streams
+var streamCSVHeader = []string{
+	"id", "site_id", "page_id", "author", "author_id", "author_email", "text",
+	"parent_id", "status", "moderated_by", "moderated_at", "created_at", "updated_at",
+}
+
+// flusher is implemented by writers that buffer output, such as http.ResponseWriter
+type flusher interface {
+	Flush()
+}
+
+// StreamCommentsBySite writes all comments for a site to w as a JSON array or CSV.
+// Rows are written as they are read so the full result set is never held in memory.
+func (s *SQLiteStore) StreamCommentsBySite(ctx context.Context, siteID string, w io.Writer, format string) error {
+	if format == "" {
+		format = StreamFormatJSON
+	}
+	if format != StreamFormatJSON && format != StreamFormatCSV {
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	query := `
+		SELECT id, site_id, page_id, author, author_id, author_email, text, parent_id,
+		       status, moderated_by, moderated_at, created_at, updated_at
+		FROM comments
+		WHERE site_id = ?
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, siteID)
+	if err != nil {
+		return fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	var csvWriter *csv.Writer
+	if format == StreamFormatCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(streamCSVHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	} else if _, err := io.WriteString(w, "["); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	flush := func() error {
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return fmt.Errorf("failed to write CSV: %w", err)
+			}
+		}
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	count := 0
+	for rows.Next() {
+		var c StreamedComment
+		var parentID, moderatedBy, authorEmail sql.NullString
+		var moderatedAt sql.NullTime
+
+		err := rows.Scan(&c.ID, &c.SiteID, &c.PageID, &c.Author, &c.AuthorID, &authorEmail, &c.Text, &parentID,
+			&c.Status, &moderatedBy, &moderatedAt, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		if parentID.Valid {
+			c.ParentID = parentID.String
+		}
+		if moderatedBy.Valid {
+			c.ModeratedBy = moderatedBy.String
+		}
+		if moderatedAt.Valid {
+			c.ModeratedAt = moderatedAt.Time
+		}
+		if authorEmail.Valid {
+			c.AuthorEmail = authorEmail.String
+		}
+
+		if csvWriter != nil {
+			moderatedAtStr := ""
+			if !c.ModeratedAt.IsZero() {
+				moderatedAtStr = c.ModeratedAt.Format(time.RFC3339)
+			}
+			record := []string{
+				c.ID, c.SiteID, c.PageID, c.Author, c.AuthorID, c.AuthorEmail, c.Text,
+				c.ParentID, c.Status, c.ModeratedBy, moderatedAtStr,
+				c.CreatedAt.Format(time.RFC3339), c.UpdatedAt.Format(time.RFC3339),
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV: %w", err)
+			}
+		} else {
+			if count > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return fmt.Errorf("failed to write JSON: %w", err)
+				}
+			}
+			data, err := json.Marshal(c)
+			if err != nil {
+				return fmt.Errorf("failed to encode comment: %w", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				return fmt.Errorf("failed to write JSON: %w", err)
+			}
+		}
+
+		count++
+		if count%streamFlushInterval == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating comments: %w", err)
+	}
+
+	if csvWriter == nil {
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return fmt.Errorf("failed to write JSON: %w", err)
+		}
+	}
+
+	return flush()
+}
@@ -0,0 +1,102 @@
+package comments
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func seedStreamComments(t *testing.T, store *SQLiteStore, n int) {
+	t.Helper()
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		comment := Comment{
+			ID:        fmt.Sprintf("c%03d", i),
+			Author:    "Alice",
+			AuthorID:  "alice",
+			Text:      fmt.Sprintf("comment, \"%d\"", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		}
+		if err := store.AddPageComment(context.Background(), "site1", "page1", comment); err != nil {
+			t.Fatalf("AddPageComment failed: %v", err)
+		}
+	}
+	// Comment on another site should not be exported
+	if err := store.AddPageComment(context.Background(), "site2", "page1", Comment{ID: "other", Author: "Bob", Text: "hi"}); err != nil {
+		t.Fatalf("AddPageComment failed: %v", err)
+	}
+}
+
+func TestSQLiteStore_StreamCommentsBySite_JSON(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	seedStreamComments(t, store, 150)
+
+	var buf bytes.Buffer
+	if err := store.StreamCommentsBySite(context.Background(), "site1", &buf, StreamFormatJSON); err != nil {
+		t.Fatalf("StreamCommentsBySite failed: %v", err)
+	}
+
+	var result []StreamedComment
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode JSON output: %v", err)
+	}
+	if len(result) != 150 {
+		t.Fatalf("expected 150 comments, got %d", len(result))
+	}
+	if result[0].ID != "c000" || result[0].PageID != "page1" {
+		t.Errorf("unexpected first comment: %+v", result[0])
+	}
+}
+
+func TestSQLiteStore_StreamCommentsBySite_JSONEmpty(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	var buf bytes.Buffer
+	if err := store.StreamCommentsBySite(context.Background(), "missing", &buf, StreamFormatJSON); err != nil {
+		t.Fatalf("StreamCommentsBySite failed: %v", err)
+	}
+	if buf.String() != "[]" {
+		t.Errorf("expected empty JSON array, got %q", buf.String())
+	}
+}
+
+func TestSQLiteStore_StreamCommentsBySite_CSV(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+	seedStreamComments(t, store, 3)
+
+	var buf bytes.Buffer
+	if err := store.StreamCommentsBySite(context.Background(), "site1", &buf, StreamFormatCSV); err != nil {
+		t.Fatalf("StreamCommentsBySite failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV output: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected header plus 3 rows, got %d", len(records))
+	}
+	if records[0][0] != "id" {
+		t.Errorf("expected header row, got %v", records[0])
+	}
+	if records[1][6] != `comment, "0"` {
+		t.Errorf("expected text to round-trip, got %q", records[1][6])
+	}
+}
+
+func TestSQLiteStore_StreamCommentsBySite_InvalidFormat(t *testing.T) {
+	store, _ := createTestDB(t)
+	defer store.Close()
+
+	var buf bytes.Buffer
+	if err := store.StreamCommentsBySite(context.Background(), "site1", &buf, "xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
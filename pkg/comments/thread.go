@@ -0,0 +1,40 @@
+package comments
+
+// ApprovedThread returns the approved comments from a page's comments, in
+// their original order. An approved reply whose parent isn't approved is
+// attached to its nearest approved ancestor instead, or becomes top-level if
+// there is none, so hiding a comment never orphans the replies below it.
+func ApprovedThread(all []Comment) []Comment {
+	byID := make(map[string]Comment, len(all))
+	for _, c := range all {
+		byID[c.ID] = c
+	}
+
+	approved := []Comment{}
+	for _, c := range all {
+		if c.Status != "approved" {
+			continue
+		}
+		c.ParentID = nearestApprovedAncestor(byID, c)
+		approved = append(approved, c)
+	}
+	return approved
+}
+
+// nearestApprovedAncestor walks up from c's parent until it finds an approved
+// comment, returning "" when the chain ends first
+func nearestApprovedAncestor(byID map[string]Comment, c Comment) string {
+	seen := map[string]bool{c.ID: true}
+	for id := c.ParentID; id != "" && !seen[id]; {
+		parent, ok := byID[id]
+		if !ok {
+			return ""
+		}
+		if parent.Status == "approved" {
+			return parent.ID
+		}
+		seen[id] = true
+		id = parent.ParentID
+	}
+	return ""
+}
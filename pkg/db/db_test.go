@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -183,3 +184,19 @@ func TestNewStore(t *testing.T) {
 		t.Error("Expected error for missing Firestore project ID")
 	}
 }
+
+func TestIsPostgres(t *testing.T) {
+	if IsPostgres(nil) {
+		t.Error("Expected a nil database not to be PostgreSQL")
+	}
+
+	adapter, err := NewSQLiteAdapter(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	if IsPostgres(adapter.GetDB()) {
+		t.Error("Expected a SQLite database not to be PostgreSQL")
+	}
+}
@@ -0,0 +1,16 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// IsPostgres reports whether db is backed by a PostgreSQL driver (lib/pq or pgx)
+func IsPostgres(db *sql.DB) bool {
+	if db == nil {
+		return false
+	}
+	driverType := fmt.Sprintf("%T", db.Driver())
+	return strings.Contains(driverType, "pq.") || strings.Contains(driverType, "pgx")
+}
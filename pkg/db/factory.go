@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
 
 // Config holds database configuration
@@ -12,6 +16,8 @@ type Config struct {
 	Provider  Provider
 	SQLitePath string
 	FirestoreProjectID string
+	// SQLite overrides the SQLite pool and pragma defaults; zero fields keep them
+	SQLite comments.StoreConfig
 }
 
 // NewStore creates a new database store based on the provider configuration
@@ -21,7 +27,7 @@ func NewStore(ctx context.Context, cfg Config) (Store, error) {
 		if cfg.SQLitePath == "" {
 			return nil, fmt.Errorf("SQLite path is required")
 		}
-		return NewSQLiteAdapter(cfg.SQLitePath)
+		return NewSQLiteAdapterWithConfig(cfg.SQLitePath, cfg.SQLite)
 	case ProviderFirestore:
 		if cfg.FirestoreProjectID == "" {
 			return nil, fmt.Errorf("Firestore project ID is required")
@@ -49,6 +55,15 @@ func ConfigFromEnv() Config {
 		if cfg.SQLitePath == "" {
 			cfg.SQLitePath = "./kotomi.db"
 		}
+		cfg.SQLite = comments.StoreConfig{
+			MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS"),
+			MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS"),
+			ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME"),
+			ConnMaxIdleTime: envDuration("DB_CONN_MAX_IDLE_TIME"),
+			CacheSizeKB:     envInt("SQLITE_CACHE_SIZE_KB"),
+			BusyTimeout:     envDuration("SQLITE_BUSY_TIMEOUT"),
+			MmapSize:        int64(envInt("SQLITE_MMAP_SIZE")),
+		}
 	case ProviderFirestore:
 		cfg.FirestoreProjectID = os.Getenv("FIRESTORE_PROJECT_ID")
 		// Also check GCP_PROJECT for convenience
@@ -59,3 +74,22 @@ func ConfigFromEnv() Config {
 
 	return cfg
 }
+
+// envInt reads a positive integer from the environment, or 0 if unset or invalid
+func envInt(name string) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// envDuration reads a Go duration such as "5m" from the environment, or 0 if
+// unset or invalid
+func envDuration(name string) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
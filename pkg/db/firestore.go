@@ -3,11 +3,17 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/google/uuid"
 	"github.com/saasuke-labs/kotomi/pkg/comments"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
@@ -58,29 +64,56 @@ func (s *FirestoreStore) AddPageComment(ctx context.Context, site, page string,
 	if comment.Status == "" {
 		comment.Status = "pending"
 	}
+	if err := comments.ValidateAttachments(comment.Attachments); err != nil {
+		return err
+	}
 
 	// Store comment in Firestore with optimized structure
 	// Collection: comments/{commentID}
 	// This allows direct access by ID and efficient queries
-	_, err := s.client.Collection("comments").Doc(comment.ID).Set(ctx, map[string]interface{}{
-		"id":                comment.ID,
-		"site_id":           site,
-		"page_id":           page,
-		"author":            comment.Author,
-		"author_id":         comment.AuthorID,
-		"author_email":      comment.AuthorEmail,
-		"author_verified":   comment.AuthorVerified,
-		"author_reputation": comment.AuthorReputation,
-		"text":              comment.Text,
-		"parent_id":         comment.ParentID,
-		"status":            comment.Status,
-		"moderated_by":      comment.ModeratedBy,
-		"moderated_at":      comment.ModeratedAt,
-		"created_at":        comment.CreatedAt,
-		"updated_at":        comment.UpdatedAt,
+	// The parent check and write share a transaction so the parent can't vanish in between
+	commentRef := s.client.Collection("comments").Doc(comment.ID)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if comment.ParentID != "" {
+			parentDoc, err := tx.Get(s.client.Collection("comments").Doc(comment.ParentID))
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					return fmt.Errorf("%w: parent comment %s does not exist on page %s", comments.ErrInvalidParent, comment.ParentID, page)
+				}
+				return fmt.Errorf("failed to check parent comment: %w", err)
+			}
+			parentData := parentDoc.Data()
+			if parentData["site_id"] != site || parentData["page_id"] != page {
+				return fmt.Errorf("%w: parent comment %s does not exist on page %s", comments.ErrInvalidParent, comment.ParentID, page)
+			}
+		}
+
+		return tx.Set(commentRef, map[string]interface{}{
+			"id":                comment.ID,
+			"site_id":           site,
+			"page_id":           page,
+			"author":            comment.Author,
+			"author_id":         comment.AuthorID,
+			"author_email":      comment.AuthorEmail,
+			"author_verified":   comment.AuthorVerified,
+			"author_reputation": comment.AuthorReputation,
+			"text":              comment.Text,
+			"parent_id":         comment.ParentID,
+			"status":            comment.Status,
+			"moderated_by":      comment.ModeratedBy,
+			"moderated_at":      comment.ModeratedAt,
+			"language":          comment.Language,
+			"sentiment":         comment.Sentiment,
+			"attachments":       attachmentsToData(comment.Attachments, comment.CreatedAt),
+			"created_at":        comment.CreatedAt,
+			"updated_at":        comment.UpdatedAt,
+		})
 	})
 
 	if err != nil {
+		if errors.Is(err, comments.ErrInvalidParent) {
+			return err
+		}
 		return fmt.Errorf("failed to add comment: %w", err)
 	}
 
@@ -99,11 +132,11 @@ func (s *FirestoreStore) AddPageComment(ctx context.Context, site, page string,
 				"updated_at": time.Now(),
 			})
 			if err != nil {
-				log.Printf("Warning: Could not auto-create site: %v", err)
+				slog.WarnContext(ctx, "Could not auto-create site", "site", site, "error", err)
 			}
 		} else {
 			// Other errors (permission, network, etc.)
-			log.Printf("Warning: Error checking site existence: %v", err)
+			slog.WarnContext(ctx, "Error checking site existence", "site", site, "error", err)
 		}
 	}
 
@@ -122,11 +155,11 @@ func (s *FirestoreStore) AddPageComment(ctx context.Context, site, page string,
 				"updated_at": time.Now(),
 			})
 			if err != nil {
-				log.Printf("Warning: Could not auto-create page: %v", err)
+				slog.WarnContext(ctx, "Could not auto-create page", "page", page, "error", err)
 			}
 		} else {
 			// Other errors (permission, network, etc.)
-			log.Printf("Warning: Error checking page existence: %v", err)
+			slog.WarnContext(ctx, "Error checking page existence", "page", page, "error", err)
 		}
 	}
 
@@ -135,11 +168,28 @@ func (s *FirestoreStore) AddPageComment(ctx context.Context, site, page string,
 
 // GetPageComments retrieves all comments for a specific page
 func (s *FirestoreStore) GetPageComments(ctx context.Context, site, page string) ([]comments.Comment, error) {
+	return s.GetPageCommentsOrdered(ctx, site, page, comments.OrderOldest)
+}
+
+// GetPageCommentsOrdered retrieves all comments for a page oldest or newest
+// first. Reactions aren't stored in Firestore, so comments.OrderMostReacted
+// is rejected with comments.ErrInvalidOrder like any unknown ordering.
+func (s *FirestoreStore) GetPageCommentsOrdered(ctx context.Context, site, page, order string) ([]comments.Comment, error) {
+	var direction firestore.Direction
+	switch order {
+	case comments.OrderOldest:
+		direction = firestore.Asc
+	case comments.OrderNewest:
+		direction = firestore.Desc
+	default:
+		return nil, fmt.Errorf("%w: %q is not supported by the Firestore store", comments.ErrInvalidOrder, order)
+	}
+
 	// Query with composite index: site_id + page_id + created_at
 	query := s.client.Collection("comments").
 		Where("site_id", "==", site).
 		Where("page_id", "==", page).
-		OrderBy("created_at", firestore.Asc)
+		OrderBy("created_at", direction)
 
 	iter := query.Documents(ctx)
 	defer iter.Stop()
@@ -161,6 +211,166 @@ func (s *FirestoreStore) GetPageComments(ctx context.Context, site, page string)
 	return result, nil
 }
 
+// GetPageCommentsSince retrieves a page's comments updated after since,
+// oldest first. Every write sets updated_at, so it also covers comments
+// created after since.
+func (s *FirestoreStore) GetPageCommentsSince(ctx context.Context, site, page string, since time.Time) ([]comments.Comment, error) {
+	// Query with composite index: site_id + page_id + updated_at
+	iter := s.client.Collection("comments").
+		Where("site_id", "==", site).
+		Where("page_id", "==", page).
+		Where("updated_at", ">", since).
+		Documents(ctx)
+	defer iter.Stop()
+
+	result := []comments.Comment{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate comments: %w", err)
+		}
+		result = append(result, s.docToComment(doc))
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// GetPageCommentsStamp returns a page's comment count, using an aggregation
+// query, and the update time of its most recently changed comment
+func (s *FirestoreStore) GetPageCommentsStamp(ctx context.Context, site, page string) (comments.PageCommentsStamp, error) {
+	query := s.client.Collection("comments").
+		Where("site_id", "==", site).
+		Where("page_id", "==", page)
+
+	results, err := query.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return comments.PageCommentsStamp{}, fmt.Errorf("failed to count comments: %w", err)
+	}
+	var stamp comments.PageCommentsStamp
+	if count, ok := results["count"].(*firestorepb.Value); ok {
+		stamp.Count = int(count.GetIntegerValue())
+	}
+	if stamp.Count == 0 {
+		return stamp, nil
+	}
+
+	docs, err := query.OrderBy("updated_at", firestore.Desc).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return comments.PageCommentsStamp{}, fmt.Errorf("failed to query latest comment: %w", err)
+	}
+	if len(docs) > 0 {
+		stamp.LastUpdated = s.docToComment(docs[0]).UpdatedAt
+	}
+
+	return stamp, nil
+}
+
+// firestoreInQueryLimit is the most values Firestore accepts in an "in" filter
+const firestoreInQueryLimit = 30
+
+// GetCommentCounts returns the number of approved comments on each page.
+// Firestore aggregations can't group, so page IDs are queried in chunks with
+// only page_id selected and the matches are counted in memory.
+func (s *FirestoreStore) GetCommentCounts(ctx context.Context, siteID string, pageIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(pageIDs))
+	for _, id := range pageIDs {
+		counts[id] = 0
+	}
+
+	for start := 0; start < len(pageIDs); start += firestoreInQueryLimit {
+		end := start + firestoreInQueryLimit
+		if end > len(pageIDs) {
+			end = len(pageIDs)
+		}
+
+		docs, err := s.client.Collection("comments").
+			Where("site_id", "==", siteID).
+			Where("status", "==", "approved").
+			Where("page_id", "in", pageIDs[start:end]).
+			Select("page_id").
+			Documents(ctx).GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query comment counts: %w", err)
+		}
+		for _, doc := range docs {
+			if pageID, ok := doc.Data()["page_id"].(string); ok {
+				counts[pageID]++
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+// GetPendingCount returns how many of a site's comments await moderation, using
+// an aggregation query
+func (s *FirestoreStore) GetPendingCount(ctx context.Context, siteID string) (int, error) {
+	query := s.client.Collection("comments").
+		Where("site_id", "==", siteID).
+		Where("status", "==", "pending")
+
+	results, err := query.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending comments: %w", err)
+	}
+	if count, ok := results["count"].(*firestorepb.Value); ok {
+		return int(count.GetIntegerValue()), nil
+	}
+	return 0, nil
+}
+
+// GetApprovedPageComments retrieves only the approved comments for a page.
+// Unapproved comments are still read so approved replies beneath them can be
+// re-threaded (see comments.ApprovedThread).
+func (s *FirestoreStore) GetApprovedPageComments(ctx context.Context, site, page string) ([]comments.Comment, error) {
+	all, err := s.GetPageComments(ctx, site, page)
+	if err != nil {
+		return nil, err
+	}
+	return comments.ApprovedThread(all), nil
+}
+
+// SearchApprovedPageComments searches the approved comments on a page by text.
+// Firestore has no substring queries, so the page's approved comments are
+// loaded and filtered in memory.
+func (s *FirestoreStore) SearchApprovedPageComments(ctx context.Context, site, page, query string, limit int) ([]comments.Comment, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	iter := s.client.Collection("comments").
+		Where("site_id", "==", site).
+		Where("page_id", "==", page).
+		Where("status", "==", "approved").
+		OrderBy("created_at", firestore.Desc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	result := []comments.Comment{}
+	for len(result) < limit {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate comments: %w", err)
+		}
+
+		comment := s.docToComment(doc)
+		if comments.MatchesSearch(comment.Text, query) {
+			result = append(result, comment)
+		}
+	}
+
+	return result, nil
+}
+
 // GetCommentsBySite retrieves comments for a site with optional status filter
 func (s *FirestoreStore) GetCommentsBySite(ctx context.Context, siteID string, status string) ([]comments.Comment, error) {
 	query := s.client.Collection("comments").Where("site_id", "==", siteID)
@@ -191,6 +401,108 @@ func (s *FirestoreStore) GetCommentsBySite(ctx context.Context, siteID string, s
 	return result, nil
 }
 
+// GetCommentsBySiteFiltered retrieves a page of a site's comments with the total
+// matching count. Firestore has no cheap offset, so matching comments are fetched
+// and paged in memory.
+func (s *FirestoreStore) GetCommentsBySiteFiltered(ctx context.Context, siteID string, filter comments.SiteCommentFilter) (*comments.SiteCommentPage, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	query := s.client.Collection("comments").Where("site_id", "==", siteID)
+
+	if filter.Status != "" {
+		query = query.Where("status", "==", filter.Status)
+	}
+	if filter.AuthorID != "" {
+		query = query.Where("author_id", "==", filter.AuthorID)
+	}
+	if filter.Language != "" {
+		query = query.Where("language", "==", filter.Language)
+	}
+	dateField := "created_at"
+	if filter.ModeratedBy != "" {
+		query = query.Where("moderated_by", "==", filter.ModeratedBy)
+		dateField = "moderated_at"
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(dateField, ">=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(dateField, "<=", filter.To)
+	}
+
+	query = query.OrderBy(dateField, firestore.Desc)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	result := []comments.Comment{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate comments: %w", err)
+		}
+
+		result = append(result, s.docToComment(doc))
+	}
+
+	page := &comments.SiteCommentPage{Total: len(result), Limit: filter.Limit, Offset: filter.Offset}
+	if filter.Offset >= len(result) {
+		page.Comments = []comments.Comment{}
+		return page, nil
+	}
+	result = result[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(result) {
+		result = result[:filter.Limit]
+	}
+	page.Comments = result
+
+	return page, nil
+}
+
+// GetCommentsByAuthor retrieves a page of an author's comments on a site,
+// newest first. Pages aren't stored in Firestore, so only PageID is set.
+func (s *FirestoreStore) GetCommentsByAuthor(ctx context.Context, siteID, authorID string, limit, offset int) ([]comments.Comment, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	query := s.client.Collection("comments").
+		Where("site_id", "==", siteID).
+		Where("author_id", "==", authorID).
+		OrderBy("created_at", firestore.Desc).
+		Offset(offset)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	result := []comments.Comment{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate comments: %w", err)
+		}
+
+		comment := s.docToComment(doc)
+		if pageID, ok := doc.Data()["page_id"].(string); ok {
+			comment.PageID = pageID
+		}
+		result = append(result, comment)
+	}
+
+	return result, nil
+}
+
 // GetCommentByID retrieves a specific comment by ID
 func (s *FirestoreStore) GetCommentByID(ctx context.Context, commentID string) (*comments.Comment, error) {
 	doc, err := s.client.Collection("comments").Doc(commentID).Get(ctx)
@@ -202,6 +514,18 @@ func (s *FirestoreStore) GetCommentByID(ctx context.Context, commentID string) (
 	return &comment, nil
 }
 
+// GetCommentByIDForSite retrieves a comment by ID only if it belongs to siteID
+func (s *FirestoreStore) GetCommentByIDForSite(ctx context.Context, commentID, siteID string) (*comments.Comment, error) {
+	comment, err := s.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.SiteID != siteID {
+		return nil, fmt.Errorf("comment not found")
+	}
+	return comment, nil
+}
+
 // UpdateCommentStatus updates a comment's status
 func (s *FirestoreStore) UpdateCommentStatus(ctx context.Context, commentID, status, moderatorID string) error {
 	_, err := s.client.Collection("comments").Doc(commentID).Update(ctx, []firestore.Update{
@@ -218,13 +542,75 @@ func (s *FirestoreStore) UpdateCommentStatus(ctx context.Context, commentID, sta
 	return nil
 }
 
-// UpdateCommentText updates a comment's text content
-func (s *FirestoreStore) UpdateCommentText(ctx context.Context, commentID, text string) error {
-	_, err := s.client.Collection("comments").Doc(commentID).Update(ctx, []firestore.Update{
-		{Path: "text", Value: text},
-		{Path: "updated_at", Value: time.Now()},
+// UpdateCommentStatusBatch sets status on every comment in commentIDs inside a
+// single transaction and returns the IDs of the comments it updated. IDs that
+// don't exist are ignored. Firestore caps a transaction at 500 writes.
+func (s *FirestoreStore) UpdateCommentStatusBatch(ctx context.Context, commentIDs []string, status, moderatorID string) ([]string, error) {
+	if len(commentIDs) == 0 {
+		return nil, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, len(commentIDs))
+	for i, id := range commentIDs {
+		refs[i] = s.client.Collection("comments").Doc(id)
+	}
+
+	var updated []string
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		updated = nil
+		docs, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, doc := range docs {
+			if !doc.Exists() {
+				continue
+			}
+			err := tx.Update(doc.Ref, []firestore.Update{
+				{Path: "status", Value: status},
+				{Path: "moderated_by", Value: moderatorID},
+				{Path: "moderated_at", Value: now},
+				{Path: "updated_at", Value: now},
+			})
+			if err != nil {
+				return err
+			}
+			updated = append(updated, doc.Ref.ID)
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment status: %w", err)
+	}
+
+	return updated, nil
+}
 
+// UpdateCommentText updates a comment's text content inside a transaction,
+// as long as its updated_at still matches expectedUpdatedAt
+func (s *FirestoreStore) UpdateCommentText(ctx context.Context, commentID, text string, expectedUpdatedAt time.Time) error {
+	ref := s.client.Collection("comments").Doc(commentID)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return comments.ErrCommentNotFound
+			}
+			return err
+		}
+		if !getTime(doc.Data(), "updated_at").Equal(expectedUpdatedAt) {
+			return comments.ErrConcurrentModification
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "text", Value: text},
+			{Path: "updated_at", Value: time.Now()},
+		})
+	})
+	if errors.Is(err, comments.ErrCommentNotFound) || errors.Is(err, comments.ErrConcurrentModification) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update comment text: %w", err)
 	}
@@ -279,6 +665,8 @@ func (s *FirestoreStore) docToComment(doc *firestore.DocumentSnapshot) comments.
 		Text:       getString(data, "text"),
 		ParentID:   getString(data, "parent_id"),
 		Status:     getString(data, "status"),
+		Language:   getString(data, "language"),
+		Sentiment:  getString(data, "sentiment"),
 		CreatedAt:  getTime(data, "created_at"),
 		UpdatedAt:  getTime(data, "updated_at"),
 	}
@@ -299,10 +687,62 @@ func (s *FirestoreStore) docToComment(doc *firestore.DocumentSnapshot) comments.
 	if moderatedAt := getTime(data, "moderated_at"); !moderatedAt.IsZero() {
 		comment.ModeratedAt = moderatedAt
 	}
+	comment.Attachments = dataToAttachments(comment.ID, data["attachments"])
 
 	return comment
 }
 
+// attachmentsToData converts a comment's attachments for storage. Firestore
+// keeps them inside the comment document, so they are deleted along with it.
+func attachmentsToData(attachments []comments.Attachment, createdAt time.Time) []map[string]interface{} {
+	data := make([]map[string]interface{}, len(attachments))
+	for i, a := range attachments {
+		id := a.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+		data[i] = map[string]interface{}{
+			"id":         id,
+			"url":        a.URL,
+			"mime_type":  strings.ToLower(a.MimeType),
+			"width":      a.Width,
+			"height":     a.Height,
+			"created_at": createdAt,
+		}
+	}
+	return data
+}
+
+// dataToAttachments reads the attachments stored in a comment document
+func dataToAttachments(commentID string, value interface{}) []comments.Attachment {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var attachments []comments.Attachment
+	for _, item := range items {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		a := comments.Attachment{
+			ID:        getString(data, "id"),
+			CommentID: commentID,
+			URL:       getString(data, "url"),
+			MimeType:  getString(data, "mime_type"),
+			CreatedAt: getTime(data, "created_at"),
+		}
+		if width, ok := data["width"].(int64); ok {
+			a.Width = int(width)
+		}
+		if height, ok := data["height"].(int64); ok {
+			a.Height = int(height)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments
+}
+
 // Helper functions for type conversion
 func getString(data map[string]interface{}, key string) string {
 	if val, ok := data[key].(string); ok {
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
@@ -25,14 +26,46 @@ type Store interface {
 	AddPageComment(ctx context.Context, site, page string, comment comments.Comment) error
 	// GetPageComments retrieves all comments for a specific page
 	GetPageComments(ctx context.Context, site, page string) ([]comments.Comment, error)
+	// GetPageCommentsOrdered retrieves all comments for a page ordered by
+	// comments.OrderOldest, OrderNewest or OrderMostReacted
+	GetPageCommentsOrdered(ctx context.Context, site, page, order string) ([]comments.Comment, error)
+	// GetPageCommentsSince retrieves a page's comments created or updated
+	// after since, oldest first
+	GetPageCommentsSince(ctx context.Context, site, page string, since time.Time) ([]comments.Comment, error)
+	// GetPageCommentsStamp returns a page's comment count and latest update
+	// time without loading the comments
+	GetPageCommentsStamp(ctx context.Context, site, page string) (comments.PageCommentsStamp, error)
+	// GetCommentCounts returns the number of approved comments on each of a
+	// site's pages, with 0 for pages that have none
+	GetCommentCounts(ctx context.Context, siteID string, pageIDs []string) (map[string]int, error)
+	// GetPendingCount returns how many of a site's comments await moderation
+	GetPendingCount(ctx context.Context, siteID string) (int, error)
+	// GetApprovedPageComments retrieves only the approved comments for a page,
+	// for public display
+	GetApprovedPageComments(ctx context.Context, site, page string) ([]comments.Comment, error)
+	// SearchApprovedPageComments returns up to limit approved comments on a page
+	// whose text contains query (case-insensitive), newest first
+	SearchApprovedPageComments(ctx context.Context, site, page, query string, limit int) ([]comments.Comment, error)
 	// GetCommentsBySite retrieves comments for a site with optional status filter
 	GetCommentsBySite(ctx context.Context, siteID string, status string) ([]comments.Comment, error)
-	// GetCommentByID retrieves a specific comment by ID
+	// GetCommentsBySiteFiltered retrieves a page of a site's comments with the total matching count
+	GetCommentsBySiteFiltered(ctx context.Context, siteID string, filter comments.SiteCommentFilter) (*comments.SiteCommentPage, error)
+	// GetCommentsByAuthor retrieves a page of an author's comments on a site,
+	// newest first and in every status, with their page ID and path
+	GetCommentsByAuthor(ctx context.Context, siteID, authorID string, limit, offset int) ([]comments.Comment, error)
+	// GetCommentByID retrieves a specific comment by ID from any site. Handlers
+	// serving a site should prefer GetCommentByIDForSite so a forgotten SiteID
+	// check can't leak comments across tenants.
 	GetCommentByID(ctx context.Context, commentID string) (*comments.Comment, error)
+	// GetCommentByIDForSite retrieves a comment by ID only if it belongs to siteID
+	GetCommentByIDForSite(ctx context.Context, commentID, siteID string) (*comments.Comment, error)
 	// UpdateCommentStatus updates a comment's status (pending, approved, rejected)
 	UpdateCommentStatus(ctx context.Context, commentID, status, moderatorID string) error
-	// UpdateCommentText updates a comment's text content
-	UpdateCommentText(ctx context.Context, commentID, text string) error
+	// UpdateCommentStatusBatch updates many comments' status all-or-nothing and returns the IDs it updated
+	UpdateCommentStatusBatch(ctx context.Context, commentIDs []string, status, moderatorID string) ([]string, error)
+	// UpdateCommentText updates a comment's text content if it was last updated
+	// at expectedUpdatedAt, returning comments.ErrConcurrentModification otherwise
+	UpdateCommentText(ctx context.Context, commentID, text string, expectedUpdatedAt time.Time) error
 	// DeleteComment deletes a comment by ID
 	DeleteComment(ctx context.Context, commentID string) error
 	// GetCommentSiteID retrieves the site ID for a comment
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"io"
+	"time"
 
 	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
@@ -14,7 +16,12 @@ type SQLiteAdapter struct {
 
 // NewSQLiteAdapter creates a new SQLite adapter
 func NewSQLiteAdapter(dbPath string) (*SQLiteAdapter, error) {
-	store, err := comments.NewSQLiteStore(dbPath)
+	return NewSQLiteAdapterWithConfig(dbPath, comments.DefaultStoreConfig())
+}
+
+// NewSQLiteAdapterWithConfig creates a new SQLite adapter with custom pool limits and pragmas
+func NewSQLiteAdapterWithConfig(dbPath string, cfg comments.StoreConfig) (*SQLiteAdapter, error) {
+	store, err := comments.NewSQLiteStoreWithConfig(dbPath, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -31,24 +38,79 @@ func (a *SQLiteAdapter) GetPageComments(ctx context.Context, site, page string)
 	return a.store.GetPageComments(ctx, site, page)
 }
 
+// GetCommentsByAuthor retrieves a page of an author's comments on a site
+func (a *SQLiteAdapter) GetCommentsByAuthor(ctx context.Context, siteID, authorID string, limit, offset int) ([]comments.Comment, error) {
+	return a.store.GetCommentsByAuthor(ctx, siteID, authorID, limit, offset)
+}
+
+// GetPageCommentsOrdered retrieves all comments for a page in the given order
+func (a *SQLiteAdapter) GetPageCommentsOrdered(ctx context.Context, site, page, order string) ([]comments.Comment, error) {
+	return a.store.GetPageCommentsOrdered(ctx, site, page, order)
+}
+
+// GetPageCommentsSince retrieves a page's comments created or updated after since
+func (a *SQLiteAdapter) GetPageCommentsSince(ctx context.Context, site, page string, since time.Time) ([]comments.Comment, error) {
+	return a.store.GetPageCommentsSince(ctx, site, page, since)
+}
+
+// GetPageCommentsStamp returns a page's comment count and latest update time
+func (a *SQLiteAdapter) GetPageCommentsStamp(ctx context.Context, site, page string) (comments.PageCommentsStamp, error) {
+	return a.store.GetPageCommentsStamp(ctx, site, page)
+}
+
+// GetCommentCounts returns the number of approved comments on each page
+func (a *SQLiteAdapter) GetCommentCounts(ctx context.Context, siteID string, pageIDs []string) (map[string]int, error) {
+	return a.store.GetCommentCounts(ctx, siteID, pageIDs)
+}
+
+// GetPendingCount returns how many of a site's comments await moderation
+func (a *SQLiteAdapter) GetPendingCount(ctx context.Context, siteID string) (int, error) {
+	return a.store.GetPendingCount(ctx, siteID)
+}
+
+// SearchApprovedPageComments searches the approved comments on a page by text
+func (a *SQLiteAdapter) SearchApprovedPageComments(ctx context.Context, site, page, query string, limit int) ([]comments.Comment, error) {
+	return a.store.SearchApprovedPageComments(ctx, site, page, query, limit)
+}
+
+// GetApprovedPageComments retrieves only the approved comments for a page
+func (a *SQLiteAdapter) GetApprovedPageComments(ctx context.Context, site, page string) ([]comments.Comment, error) {
+	return a.store.GetApprovedPageComments(ctx, site, page)
+}
+
 // GetCommentsBySite retrieves comments for a site with optional status filter
 func (a *SQLiteAdapter) GetCommentsBySite(ctx context.Context, siteID string, status string) ([]comments.Comment, error) {
 	return a.store.GetCommentsBySite(ctx, siteID, status)
 }
 
+// GetCommentsBySiteFiltered retrieves a page of a site's comments with the total matching count
+func (a *SQLiteAdapter) GetCommentsBySiteFiltered(ctx context.Context, siteID string, filter comments.SiteCommentFilter) (*comments.SiteCommentPage, error) {
+	return a.store.GetCommentsBySiteFiltered(ctx, siteID, filter)
+}
+
 // GetCommentByID retrieves a specific comment by ID
 func (a *SQLiteAdapter) GetCommentByID(ctx context.Context, commentID string) (*comments.Comment, error) {
 	return a.store.GetCommentByID(ctx, commentID)
 }
 
+// GetCommentByIDForSite retrieves a comment by ID only if it belongs to siteID
+func (a *SQLiteAdapter) GetCommentByIDForSite(ctx context.Context, commentID, siteID string) (*comments.Comment, error) {
+	return a.store.GetCommentByIDForSite(ctx, commentID, siteID)
+}
+
 // UpdateCommentStatus updates a comment's status
 func (a *SQLiteAdapter) UpdateCommentStatus(ctx context.Context, commentID, status, moderatorID string) error {
 	return a.store.UpdateCommentStatus(ctx, commentID, status, moderatorID)
 }
 
-// UpdateCommentText updates a comment's text content
-func (a *SQLiteAdapter) UpdateCommentText(ctx context.Context, commentID, text string) error {
-	return a.store.UpdateCommentText(ctx, commentID, text)
+// UpdateCommentStatusBatch updates the status of many comments in one transaction
+func (a *SQLiteAdapter) UpdateCommentStatusBatch(ctx context.Context, commentIDs []string, status, moderatorID string) ([]string, error) {
+	return a.store.UpdateCommentStatusBatch(ctx, commentIDs, status, moderatorID)
+}
+
+// UpdateCommentText updates a comment's text content if it is unchanged since expectedUpdatedAt
+func (a *SQLiteAdapter) UpdateCommentText(ctx context.Context, commentID, text string, expectedUpdatedAt time.Time) error {
+	return a.store.UpdateCommentText(ctx, commentID, text, expectedUpdatedAt)
 }
 
 // DeleteComment deletes a comment by ID
@@ -61,6 +123,11 @@ func (a *SQLiteAdapter) GetCommentSiteID(ctx context.Context, commentID string)
 	return a.store.GetCommentSiteID(ctx, commentID)
 }
 
+// StreamCommentsBySite writes all comments for a site to w without buffering them in memory
+func (a *SQLiteAdapter) StreamCommentsBySite(ctx context.Context, siteID string, w io.Writer, format string) error {
+	return a.store.StreamCommentsBySite(ctx, siteID, w, format)
+}
+
 // GetDB returns the underlying database connection
 func (a *SQLiteAdapter) GetDB() *sql.DB {
 	return a.store.GetDB()
@@ -20,11 +20,14 @@ const (
 	ErrCodeRateLimitExceeded   ErrorCode = "RATE_LIMIT_EXCEEDED"
 	ErrCodeInvalidJSON         ErrorCode = "INVALID_JSON"
 	ErrCodeMissingField        ErrorCode = "MISSING_FIELD"
+	ErrCodeGone                ErrorCode = "GONE"
+	ErrCodePayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"
 	
 	// Server errors (5xx)
 	ErrCodeInternalServer      ErrorCode = "INTERNAL_SERVER_ERROR"
 	ErrCodeDatabaseError       ErrorCode = "DATABASE_ERROR"
 	ErrCodeExternalService     ErrorCode = "EXTERNAL_SERVICE_ERROR"
+	ErrCodeServiceUnavailable  ErrorCode = "SERVICE_UNAVAILABLE"
 )
 
 // APIError represents a structured error response for the API
@@ -86,6 +89,10 @@ func Conflict(message string) *APIError {
 	return NewAPIError(ErrCodeConflict, message, http.StatusConflict)
 }
 
+func Gone(message string) *APIError {
+	return NewAPIError(ErrCodeGone, message, http.StatusGone)
+}
+
 func ValidationError(message string) *APIError {
 	return NewAPIError(ErrCodeValidation, message, http.StatusBadRequest)
 }
@@ -98,6 +105,10 @@ func InvalidJSON(message string) *APIError {
 	return NewAPIError(ErrCodeInvalidJSON, message, http.StatusBadRequest)
 }
 
+func PayloadTooLarge(message string) *APIError {
+	return NewAPIError(ErrCodePayloadTooLarge, message, http.StatusRequestEntityTooLarge)
+}
+
 func InternalServerError(message string) *APIError {
 	return NewAPIError(ErrCodeInternalServer, message, http.StatusInternalServerError)
 }
@@ -110,11 +121,52 @@ func ExternalServiceError(message string) *APIError {
 	return NewAPIError(ErrCodeExternalService, message, http.StatusInternalServerError)
 }
 
+func ServiceUnavailable(message string) *APIError {
+	return NewAPIError(ErrCodeServiceUnavailable, message, http.StatusServiceUnavailable)
+}
+
+// FromStatus creates an APIError for an HTTP status code, using the standard
+// error code for that status
+func FromStatus(statusCode int, message string) *APIError {
+	code := ErrCodeInternalServer
+	switch statusCode {
+	case http.StatusBadRequest:
+		code = ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		code = ErrCodeUnauthorized
+	case http.StatusForbidden:
+		code = ErrCodeForbidden
+	case http.StatusNotFound:
+		code = ErrCodeNotFound
+	case http.StatusConflict:
+		code = ErrCodeConflict
+	case http.StatusGone:
+		code = ErrCodeGone
+	case http.StatusRequestEntityTooLarge:
+		code = ErrCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		code = ErrCodeRateLimitExceeded
+	case http.StatusServiceUnavailable:
+		code = ErrCodeServiceUnavailable
+	default:
+		if statusCode >= 400 && statusCode < 500 {
+			code = ErrCodeBadRequest
+		}
+	}
+	return NewAPIError(code, message, statusCode)
+}
+
+// ErrorResponse is the JSON envelope every error response is wrapped in:
+// {"error": {"code", "message", "details", "request_id"}}
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
 // WriteError writes an APIError as a JSON response
 func WriteError(w http.ResponseWriter, err *APIError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(err.StatusCode)
-	json.NewEncoder(w).Encode(err)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: err})
 }
 
 // WriteErrorWithRequestID writes an APIError with a request ID as a JSON response
@@ -83,6 +83,7 @@ func TestCommonErrorConstructors(t *testing.T) {
 		{"InternalServerError", InternalServerError, ErrCodeInternalServer, http.StatusInternalServerError},
 		{"DatabaseError", DatabaseError, ErrCodeDatabaseError, http.StatusInternalServerError},
 		{"ExternalServiceError", ExternalServiceError, ErrCodeExternalService, http.StatusInternalServerError},
+		{"ServiceUnavailable", ServiceUnavailable, ErrCodeServiceUnavailable, http.StatusServiceUnavailable},
 	}
 
 	for _, tt := range tests {
@@ -120,10 +121,14 @@ func TestWriteError(t *testing.T) {
 	}
 	
 	// Check JSON response
-	var response APIError
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+	var envelope ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
 		t.Fatalf("Failed to unmarshal response: %v", err)
 	}
+	response := envelope.Error
+	if response == nil {
+		t.Fatalf("Expected error envelope, got %s", w.Body.String())
+	}
 	
 	if response.Code != ErrCodeBadRequest {
 		t.Errorf("Expected code %v, got %v", ErrCodeBadRequest, response.Code)
@@ -141,10 +146,14 @@ func TestWriteErrorWithRequestID(t *testing.T) {
 	WriteErrorWithRequestID(w, err, requestID)
 	
 	// Check JSON response includes request ID
-	var response APIError
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+	var envelope ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
 		t.Fatalf("Failed to unmarshal response: %v", err)
 	}
+	response := envelope.Error
+	if response == nil {
+		t.Fatalf("Expected error envelope, got %s", w.Body.String())
+	}
 	
 	if response.RequestID != requestID {
 		t.Errorf("Expected request ID %v, got %v", requestID, response.RequestID)
@@ -160,12 +169,40 @@ func TestWriteError_WithDetails(t *testing.T) {
 	
 	WriteError(w, err)
 	
-	var response APIError
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+	var envelope ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
 		t.Fatalf("Failed to unmarshal response: %v", err)
 	}
+	response := envelope.Error
+	if response == nil {
+		t.Fatalf("Expected error envelope, got %s", w.Body.String())
+	}
 	
 	if response.Details != "Field 'name' is required" {
 		t.Errorf("Expected details 'Field 'name' is required', got %v", response.Details)
 	}
 }
+
+func TestFromStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		code   ErrorCode
+	}{
+		{http.StatusBadRequest, ErrCodeBadRequest},
+		{http.StatusUnauthorized, ErrCodeUnauthorized},
+		{http.StatusForbidden, ErrCodeForbidden},
+		{http.StatusNotFound, ErrCodeNotFound},
+		{http.StatusConflict, ErrCodeConflict},
+		{http.StatusTooManyRequests, ErrCodeRateLimitExceeded},
+		{http.StatusMethodNotAllowed, ErrCodeBadRequest},
+		{http.StatusInternalServerError, ErrCodeInternalServer},
+		{http.StatusServiceUnavailable, ErrCodeServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		err := FromStatus(tt.status, "message")
+		if err.Code != tt.code || err.StatusCode != tt.status {
+			t.Errorf("FromStatus(%d) = %s/%d, want %s/%d", tt.status, err.Code, err.StatusCode, tt.code, tt.status)
+		}
+	}
+}
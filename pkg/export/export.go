@@ -152,13 +152,14 @@ func (e *Exporter) getCommentsForPage(siteID, pageID string) ([]models.CommentEx
 	return comments, rows.Err()
 }
 
-// getCommentReactions retrieves all reactions for a comment
+// getCommentReactions retrieves all reactions for a comment. The reacting user's
+// ID is exported as the UserIdentifier whether or not they have a users row, so
+// the importer can match it.
 func (e *Exporter) getCommentReactions(commentID string) ([]models.ReactionExport, error) {
 	query := `
-		SELECT r.allowed_reaction_id, ar.name, ar.emoji, u.id as user_identifier, r.created_at
+		SELECT r.allowed_reaction_id, ar.name, ar.emoji, r.user_id, r.created_at
 		FROM reactions r
 		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
-		LEFT JOIN users u ON r.user_id = u.id
 		WHERE r.comment_id = ?
 		ORDER BY r.created_at ASC
 	`
@@ -193,10 +194,9 @@ func (e *Exporter) getCommentReactions(commentID string) ([]models.ReactionExpor
 // getPageReactions retrieves all reactions for a page
 func (e *Exporter) getPageReactions(pageID string) ([]models.ReactionExport, error) {
 	query := `
-		SELECT r.allowed_reaction_id, ar.name, ar.emoji, u.id as user_identifier, r.created_at
+		SELECT r.allowed_reaction_id, ar.name, ar.emoji, r.user_id, r.created_at
 		FROM reactions r
 		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
-		LEFT JOIN users u ON r.user_id = u.id
 		WHERE r.page_id = ?
 		ORDER BY r.created_at ASC
 	`
@@ -307,10 +307,9 @@ func (e *Exporter) ExportReactionsToCSV(w io.Writer, siteID string) error {
 		SELECT r.id, 
 		       CASE WHEN r.page_id IS NOT NULL THEN 'page' ELSE 'comment' END as target_type,
 		       COALESCE(r.page_id, r.comment_id) as target_id,
-		       ar.name, ar.emoji, u.id as user_identifier, r.created_at
+		       ar.name, ar.emoji, r.user_id, r.created_at
 		FROM reactions r
 		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
-		LEFT JOIN users u ON r.user_id = u.id
 		WHERE ar.site_id = ?
 		ORDER BY r.created_at ASC
 	`
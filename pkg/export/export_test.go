@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/saasuke-labs/kotomi/pkg/comments"
+	importpkg "github.com/saasuke-labs/kotomi/pkg/import"
 	"github.com/saasuke-labs/kotomi/pkg/models"
 )
 
@@ -313,3 +314,103 @@ func TestExporter_ExportToJSON_InvalidSite(t *testing.T) {
 		t.Error("Expected error for non-existent site, got nil")
 	}
 }
+
+func TestExporter_RoundTripThroughImporter(t *testing.T) {
+	source := createTestDB(t)
+	defer source.Close()
+
+	siteID, pageID, commentID := createTestData(t, source)
+	ctx := context.Background()
+	db := source.GetDB()
+
+	// A second page with a reply, plus reactions from a user without a users row
+	page2, err := models.NewPageStore(db).Create(ctx, siteID, "/second", "Second Page")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	now := time.Now().UTC()
+	_, err = db.Exec(`
+		INSERT INTO comments (id, site_id, page_id, author, author_id, text, parent_id, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		"comment-2", siteID, pageID, "Anon", "jwt-user", "A reply", commentID, "pending", now.Add(time.Second), now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to create reply: %v", err)
+	}
+	heart, err := models.NewAllowedReactionStore(db).Create(ctx, siteID, "heart", "❤️", "page")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+	var thumbsUpID string
+	if err := db.QueryRow(`SELECT id FROM allowed_reactions WHERE name = 'thumbs_up'`).Scan(&thumbsUpID); err != nil {
+		t.Fatalf("Failed to find allowed reaction: %v", err)
+	}
+	reactions := []struct{ id, target, column, allowedID, userID string }{
+		{"reaction-2", "comment-2", "comment_id", thumbsUpID, "jwt-user"},
+		{"reaction-3", page2.ID, "page_id", heart.ID, "jwt-user"},
+		{"reaction-4", pageID, "page_id", heart.ID, "user-1"},
+	}
+	for _, r := range reactions {
+		_, err := db.Exec(`INSERT INTO reactions (id, `+r.column+`, allowed_reaction_id, user_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+			r.id, r.target, r.allowedID, r.userID, now)
+		if err != nil {
+			t.Fatalf("Failed to create reaction %s: %v", r.id, err)
+		}
+	}
+
+	exporter := NewExporter(db)
+	exported, err := exporter.ExportToJSON(ctx, siteID)
+	if err != nil {
+		t.Fatalf("ExportToJSON failed: %v", err)
+	}
+	for _, page := range exported.Pages {
+		for _, reaction := range page.PageReactions {
+			if reaction.UserIdentifier == "" {
+				t.Errorf("Expected page reaction on %s to carry a user identifier", page.Page.Path)
+			}
+		}
+		for _, comment := range page.Comments {
+			for _, reaction := range comment.Reactions {
+				if reaction.UserIdentifier == "" {
+					t.Errorf("Expected reaction on %s to carry a user identifier", comment.ID)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := exporter.WriteJSON(&buf, exported); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	// Import into a fresh database that only knows the site
+	target := createTestDB(t)
+	defer target.Close()
+	targetDB := target.GetDB()
+	if _, err := targetDB.Exec(`INSERT INTO admin_users (id, email, name, auth0_sub) VALUES (?, ?, ?, ?)`,
+		"admin-1", "admin@example.com", "Admin User", "auth0|123"); err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	if _, err := targetDB.Exec(`INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)`, siteID, "admin-1", "Test Site"); err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	result, err := importpkg.NewImporter(targetDB, importpkg.StrategySkip).ImportFromJSON(&buf, siteID)
+	if err != nil {
+		t.Fatalf("ImportFromJSON failed: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("Expected a clean import, got errors: %v", result.Errors)
+	}
+
+	reimported, err := NewExporter(targetDB).ExportToJSON(ctx, siteID)
+	if err != nil {
+		t.Fatalf("ExportToJSON after import failed: %v", err)
+	}
+	want, got := exported.Metadata, reimported.Metadata
+	if got.TotalPages != want.TotalPages || got.TotalComments != want.TotalComments || got.TotalReactions != want.TotalReactions {
+		t.Errorf("Expected %d pages, %d comments, %d reactions after round trip, got %d, %d, %d",
+			want.TotalPages, want.TotalComments, want.TotalReactions, got.TotalPages, got.TotalComments, got.TotalReactions)
+	}
+	if want.TotalComments != 2 || want.TotalReactions != 4 {
+		t.Errorf("Expected the seeded site to export 2 comments and 4 reactions, got %d and %d", want.TotalComments, want.TotalReactions)
+	}
+}
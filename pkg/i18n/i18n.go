@@ -0,0 +1,113 @@
+// Package i18n holds the translated strings Kotomi sends to people outside the
+// admin panel: notification subjects and standard moderation reasons.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLocale is used for sites without a locale and for keys missing from
+// a site's locale
+const DefaultLocale = "en"
+
+// Message keys
+const (
+	SubjectNewComment       = "subject.new_comment"       // args: site name
+	SubjectCommentReply     = "subject.comment_reply"     // no args
+	SubjectModerationUpdate = "subject.moderation_update" // args: localized status
+	SubjectCommentReported  = "subject.comment_reported"  // args: site name
+	SubjectDailyDigest      = "subject.daily_digest"      // args: comment count, site name
+	SubjectPageComment      = "subject.page_comment"      // args: page title
+
+	StatusApproved = "status.approved"
+	StatusRejected = "status.rejected"
+	StatusPending  = "status.pending"
+
+	ReasonCommunityGuidelines = "reason.community_guidelines"
+)
+
+// catalog maps each supported locale to its messages. Messages are fmt
+// format strings taking the arguments noted on their key.
+var catalog = map[string]map[string]string{
+	"en": {
+		SubjectNewComment:         "New comment on %s",
+		SubjectCommentReply:       "Someone replied to your comment",
+		SubjectModerationUpdate:   "Your comment was %s",
+		SubjectCommentReported:    "A comment on %s was reported",
+		SubjectDailyDigest:        "%d new comments on %s",
+		SubjectPageComment:        "New comment on \"%s\"",
+		StatusApproved:            "approved",
+		StatusRejected:            "rejected",
+		StatusPending:             "held for review",
+		ReasonCommunityGuidelines: "Content violated community guidelines",
+	},
+	"es": {
+		SubjectNewComment:         "Nuevo comentario en %s",
+		SubjectCommentReply:       "Alguien respondió a tu comentario",
+		SubjectModerationUpdate:   "Tu comentario fue %s",
+		SubjectCommentReported:    "Se denunció un comentario en %s",
+		SubjectDailyDigest:        "%d comentarios nuevos en %s",
+		SubjectPageComment:        "Nuevo comentario en «%s»",
+		StatusApproved:            "aprobado",
+		StatusRejected:            "rechazado",
+		StatusPending:             "retenido para revisión",
+		ReasonCommunityGuidelines: "El contenido infringe las normas de la comunidad",
+	},
+	"ja": {
+		SubjectNewComment:         "%sに新しいコメントがあります",
+		SubjectCommentReply:       "あなたのコメントに返信がありました",
+		SubjectModerationUpdate:   "あなたのコメントは%sされました",
+		SubjectCommentReported:    "%sのコメントが報告されました",
+		SubjectDailyDigest:        "%[2]sに%[1]d件の新しいコメント",
+		SubjectPageComment:        "「%s」に新しいコメントがあります",
+		StatusApproved:            "承認",
+		StatusRejected:            "却下",
+		StatusPending:             "保留",
+		ReasonCommunityGuidelines: "コミュニティガイドラインに違反する内容です",
+	},
+}
+
+// Normalize returns the supported locale for a language tag such as "ja" or
+// "es-MX", or "" if the language isn't supported
+func Normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := catalog[locale]; ok {
+		return locale
+	}
+	return ""
+}
+
+// IsSupported reports whether a language tag maps to a supported locale
+func IsSupported(locale string) bool {
+	return Normalize(locale) != ""
+}
+
+// T returns the message for key in locale, formatted with args. Unsupported
+// locales and keys missing from a locale fall back to English.
+func T(locale, key string, args ...interface{}) string {
+	message, ok := catalog[Normalize(locale)][key]
+	if !ok {
+		message, ok = catalog[DefaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Status returns the localized word for a comment status, or the status
+// itself if it has no translation
+func Status(locale, status string) string {
+	key := "status." + status
+	if message := T(locale, key); message != key {
+		return message
+	}
+	return status
+}
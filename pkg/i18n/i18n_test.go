@@ -0,0 +1,54 @@
+package i18n
+
+import "testing"
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		locale string
+		key    string
+		args   []interface{}
+		want   string
+	}{
+		{"en", SubjectNewComment, []interface{}{"My Blog"}, "New comment on My Blog"},
+		{"es", SubjectNewComment, []interface{}{"My Blog"}, "Nuevo comentario en My Blog"},
+		{"ja", SubjectNewComment, []interface{}{"My Blog"}, "My Blogに新しいコメントがあります"},
+		{"ja", SubjectDailyDigest, []interface{}{3, "My Blog"}, "My Blogに3件の新しいコメント"},
+		{"ja-JP", SubjectCommentReply, nil, "あなたのコメントに返信がありました"},
+		{"fr", SubjectCommentReply, nil, "Someone replied to your comment"},
+		{"", ReasonCommunityGuidelines, nil, "Content violated community guidelines"},
+		{"ja", "subject.unknown", nil, "subject.unknown"},
+	}
+	for _, tt := range tests {
+		if got := T(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestTFallsBackToEnglishForMissingKey(t *testing.T) {
+	original := catalog["ja"][ReasonCommunityGuidelines]
+	delete(catalog["ja"], ReasonCommunityGuidelines)
+	defer func() { catalog["ja"][ReasonCommunityGuidelines] = original }()
+
+	if got := T("ja", ReasonCommunityGuidelines); got != "Content violated community guidelines" {
+		t.Errorf("expected English fallback, got %q", got)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if got := Status("ja", "rejected"); got != "却下" {
+		t.Errorf("expected Japanese status, got %q", got)
+	}
+	if got := Status("es", "spam"); got != "spam" {
+		t.Errorf("expected untranslated status unchanged, got %q", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{"en": "en", "ES": "es", "ja_JP": "ja", "es-MX": "es", "fr": "", "": ""}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package importpkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// disqusDocument is the subset of a Disqus XML export we read.
+// Attributes such as dsq:id are matched by local name.
+type disqusDocument struct {
+	Threads []disqusThread `xml:"thread"`
+	Posts   []disqusPost   `xml:"post"`
+}
+
+// disqusThread is a discussion thread, which maps to a Kotomi page
+type disqusThread struct {
+	DsqID string `xml:"id,attr"`
+	Link  string `xml:"link"`
+	Title string `xml:"title"`
+}
+
+// disqusRef is an element referring to another object by dsq:id
+type disqusRef struct {
+	DsqID string `xml:"id,attr"`
+}
+
+// disqusPost is a single comment in a Disqus export
+type disqusPost struct {
+	DsqID     string     `xml:"id,attr"`
+	Message   string     `xml:"message"`
+	CreatedAt string     `xml:"createdAt"`
+	IsDeleted bool       `xml:"isDeleted"`
+	IsSpam    bool       `xml:"isSpam"`
+	Thread    disqusRef  `xml:"thread"`
+	Parent    *disqusRef `xml:"parent"`
+	Author    struct {
+		Name     string `xml:"name"`
+		Email    string `xml:"email"`
+		Username string `xml:"username"`
+	} `xml:"author"`
+}
+
+var (
+	// htmlBreakPattern matches tags that should become line breaks in plain text
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	// htmlTagPattern matches any remaining HTML tag
+	htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ImportFromDisqus imports comments from a Disqus XML export.
+// Threads become pages keyed by the path of their link and reply hierarchies
+// are preserved through the posts' parent references.
+func (i *Importer) ImportFromDisqus(r io.Reader, siteID string) (*ImportResult, error) {
+	var doc disqusDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode Disqus export: %w", err)
+	}
+
+	result := &ImportResult{
+		Errors: make([]string, 0),
+	}
+
+	threads := make(map[string]disqusThread, len(doc.Threads))
+	for _, thread := range doc.Threads {
+		threads[thread.DsqID] = thread
+	}
+
+	// Start a transaction
+	tx, err := i.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Pages are created lazily so threads without posts don't produce empty pages
+	pageIDs := make(map[string]string)
+
+	for _, post := range doc.Posts {
+		if post.IsDeleted {
+			result.CommentsSkipped++
+			continue
+		}
+
+		thread, ok := threads[post.Thread.DsqID]
+		if !ok {
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("Failed to import post %s: unknown thread %s", post.DsqID, post.Thread.DsqID))
+			continue
+		}
+
+		pageID, ok := pageIDs[thread.DsqID]
+		if !ok {
+			page := &models.Page{
+				ID:    uuid.NewString(),
+				Path:  pagePathFromLink(thread.Link),
+				Title: strings.TrimSpace(thread.Title),
+			}
+			var created bool
+			pageID, created, err = i.importPage(tx, siteID, page)
+			if err != nil {
+				result.Errors = append(result.Errors,
+					fmt.Sprintf("Failed to import page %s: %v", page.Path, err))
+				continue
+			}
+			if created {
+				result.PagesCreated++
+			} else {
+				result.PagesSkipped++
+			}
+			pageIDs[thread.DsqID] = pageID
+		}
+
+		comment, err := disqusToCommentExport(siteID, post)
+		if err != nil {
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("Failed to parse post %s: %v", post.DsqID, err))
+			continue
+		}
+
+		imported, skipped, updated, err := i.importComment(tx, siteID, pageID, comment)
+		if err != nil {
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("Failed to import comment %s: %v", comment.ID, err))
+			continue
+		}
+
+		result.CommentsImported += imported
+		result.CommentsSkipped += skipped
+		result.CommentsUpdated += updated
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// disqusToCommentExport maps a Disqus post to a CommentExport
+func disqusToCommentExport(siteID string, post disqusPost) (*models.CommentExport, error) {
+	id := strings.TrimSpace(post.DsqID)
+	if id == "" {
+		return nil, fmt.Errorf("missing dsq:id")
+	}
+
+	text := htmlToText(post.Message)
+	if text == "" {
+		return nil, fmt.Errorf("empty message")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, strings.TrimSpace(post.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("invalid createdAt: %w", err)
+	}
+
+	author := strings.TrimSpace(post.Author.Name)
+	if author == "" {
+		author = "Anonymous"
+	}
+	email := strings.TrimSpace(post.Author.Email)
+
+	authorID := "disqus-guest-" + id
+	if username := strings.TrimSpace(post.Author.Username); username != "" {
+		authorID = "disqus-user-" + username
+	} else if email != "" {
+		authorID = "disqus-email-" + strings.ToLower(email)
+	}
+
+	parentID := ""
+	if post.Parent != nil && strings.TrimSpace(post.Parent.DsqID) != "" {
+		parentID = disqusCommentID(siteID, post.Parent.DsqID)
+	}
+
+	status := "approved"
+	if post.IsSpam {
+		status = "rejected"
+	}
+
+	return &models.CommentExport{
+		ID:          disqusCommentID(siteID, id),
+		Author:      author,
+		AuthorID:    authorID,
+		AuthorEmail: email,
+		Text:        text,
+		ParentID:    parentID,
+		Status:      status,
+		CreatedAt:   createdAt.UTC(),
+		UpdatedAt:   createdAt.UTC(),
+	}, nil
+}
+
+// disqusCommentID builds a site-scoped Kotomi comment ID from a Disqus post ID
+func disqusCommentID(siteID, dsqID string) string {
+	return "disqus-" + siteID + "-" + strings.TrimSpace(dsqID)
+}
+
+// htmlToText converts a Disqus HTML message to plain text
+func htmlToText(message string) string {
+	text := htmlBreakPattern.ReplaceAllString(message, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(text)
+}
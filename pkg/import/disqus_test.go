@@ -0,0 +1,139 @@
+package importpkg
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDisqusXML = `<?xml version="1.0" encoding="utf-8"?>
+<disqus xmlns="http://disqus.com"
+	xmlns:dsq="http://disqus.com/disqus-internals">
+	<thread dsq:id="100">
+		<id>thread-a</id>
+		<link>https://blog.example.com/posts/first/</link>
+		<title>First Post</title>
+		<createdAt>2023-05-01T08:00:00Z</createdAt>
+	</thread>
+	<thread dsq:id="200">
+		<link>https://blog.example.com/posts/empty/</link>
+		<title>Empty</title>
+	</thread>
+	<post dsq:id="1">
+		<message><![CDATA[<p>Hello &amp; welcome</p><p>Second line</p>]]></message>
+		<createdAt>2023-05-01T09:00:00Z</createdAt>
+		<isDeleted>false</isDeleted>
+		<isSpam>false</isSpam>
+		<author>
+			<email>carol@example.com</email>
+			<name>Carol</name>
+			<username>carol</username>
+		</author>
+		<thread dsq:id="100"/>
+	</post>
+	<post dsq:id="2">
+		<message><![CDATA[Reply<br/>here]]></message>
+		<createdAt>2023-05-01T10:00:00Z</createdAt>
+		<isDeleted>false</isDeleted>
+		<isSpam>true</isSpam>
+		<author>
+			<name>Dave</name>
+		</author>
+		<thread dsq:id="100"/>
+		<parent dsq:id="1"/>
+	</post>
+	<post dsq:id="3">
+		<message><![CDATA[gone]]></message>
+		<createdAt>2023-05-01T11:00:00Z</createdAt>
+		<isDeleted>true</isDeleted>
+		<thread dsq:id="100"/>
+	</post>
+	<post dsq:id="4">
+		<message><![CDATA[orphan]]></message>
+		<createdAt>2023-05-01T11:00:00Z</createdAt>
+		<thread dsq:id="999"/>
+	</post>
+</disqus>`
+
+func TestImporter_ImportFromDisqus(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, _ := createTestSite(t, store)
+	db := store.GetDB()
+
+	importer := NewImporter(db, StrategySkip)
+	result, err := importer.ImportFromDisqus(strings.NewReader(testDisqusXML), siteID)
+	if err != nil {
+		t.Fatalf("ImportFromDisqus failed: %v", err)
+	}
+
+	if result.PagesCreated != 1 {
+		t.Errorf("Expected 1 page created, got %d", result.PagesCreated)
+	}
+	if result.CommentsImported != 2 {
+		t.Errorf("Expected 2 comments imported, got %d", result.CommentsImported)
+	}
+	if result.CommentsSkipped != 1 {
+		t.Errorf("Expected 1 deleted post skipped, got %d", result.CommentsSkipped)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("Expected 1 error for the orphan post, got %v", result.Errors)
+	}
+
+	var text, authorID string
+	err = db.QueryRow(`SELECT text, author_id FROM comments WHERE id = ?`, "disqus-"+siteID+"-1").Scan(&text, &authorID)
+	if err != nil {
+		t.Fatalf("Failed to find imported comment: %v", err)
+	}
+	if text != "Hello & welcome\nSecond line" {
+		t.Errorf("Expected HTML stripped to text, got %q", text)
+	}
+	if authorID != "disqus-user-carol" {
+		t.Errorf("Expected author ID 'disqus-user-carol', got '%s'", authorID)
+	}
+
+	var parentID, status, pagePath string
+	err = db.QueryRow(`
+		SELECT c.parent_id, c.status, p.path FROM comments c JOIN pages p ON c.page_id = p.id
+		WHERE c.id = ?`, "disqus-"+siteID+"-2").Scan(&parentID, &status, &pagePath)
+	if err != nil {
+		t.Fatalf("Failed to find imported reply: %v", err)
+	}
+	if parentID != "disqus-"+siteID+"-1" {
+		t.Errorf("Expected parent 'disqus-%s-1', got '%s'", siteID, parentID)
+	}
+	if status != "rejected" {
+		t.Errorf("Expected spam post to be rejected, got '%s'", status)
+	}
+	if pagePath != "/posts/first/" {
+		t.Errorf("Expected page path '/posts/first/', got '%s'", pagePath)
+	}
+}
+
+func TestImporter_ImportFromXML_DetectsFormat(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, _ := createTestSite(t, store)
+	importer := NewImporter(store.GetDB(), StrategySkip)
+
+	result, err := importer.ImportFromXML(strings.NewReader(testDisqusXML), siteID)
+	if err != nil {
+		t.Fatalf("ImportFromXML (Disqus) failed: %v", err)
+	}
+	if result.CommentsImported != 2 {
+		t.Errorf("Expected 2 Disqus comments imported, got %d", result.CommentsImported)
+	}
+
+	result, err = importer.ImportFromXML(strings.NewReader(testWXR), siteID)
+	if err != nil {
+		t.Fatalf("ImportFromXML (WXR) failed: %v", err)
+	}
+	if result.CommentsImported != 2 {
+		t.Errorf("Expected 2 WordPress comments imported, got %d", result.CommentsImported)
+	}
+
+	if _, err := importer.ImportFromXML(strings.NewReader("<feed></feed>"), siteID); err == nil {
+		t.Error("Expected error for unsupported XML format")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -423,3 +424,263 @@ func TestImporter_ImportFromJSON_EmptyData(t *testing.T) {
 		t.Errorf("Expected 0 pages created, got %d", result.PagesCreated)
 	}
 }
+
+func TestImporter_OnProgress(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, pageID := createTestSite(t, store)
+
+	const total = 120
+	now := time.Now().UTC()
+
+	assertProgress := func(t *testing.T, calls [][2]int) {
+		t.Helper()
+		if len(calls) < 2 {
+			t.Fatalf("Expected several progress reports, got %d", len(calls))
+		}
+		for i, call := range calls {
+			if call[1] != total {
+				t.Errorf("Report %d: expected total %d, got %d", i, total, call[1])
+			}
+			if i > 0 && call[0] <= calls[i-1][0] {
+				t.Errorf("Report %d: processed %d did not increase from %d", i, call[0], calls[i-1][0])
+			}
+		}
+		if last := calls[len(calls)-1]; last[0] != total {
+			t.Errorf("Expected final report of %d processed, got %d", total, last[0])
+		}
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		exportData := createTestExportData(siteID, pageID)
+		exportData.Pages[0].Comments = nil
+		for n := 0; n < total; n++ {
+			exportData.Pages[0].Comments = append(exportData.Pages[0].Comments, models.CommentExport{
+				ID:        fmt.Sprintf("json-comment-%d", n),
+				Author:    "Test User",
+				AuthorID:  "user-1",
+				Text:      "Progress comment",
+				Status:    "approved",
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+		}
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(exportData); err != nil {
+			t.Fatalf("Failed to encode export data: %v", err)
+		}
+
+		var calls [][2]int
+		importer := NewImporter(store.GetDB(), StrategySkip)
+		importer.OnProgress = func(processed, total int) {
+			calls = append(calls, [2]int{processed, total})
+		}
+		if _, err := importer.ImportFromJSON(&buf, siteID); err != nil {
+			t.Fatalf("ImportFromJSON failed: %v", err)
+		}
+		assertProgress(t, calls)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		var sb strings.Builder
+		sb.WriteString("Comment ID,Page ID,Page Title,Author,Author ID,Author Email,Text,Parent ID,Status,Created At,Updated At,Reaction Count\n")
+		for n := 0; n < total; n++ {
+			fmt.Fprintf(&sb, "csv-comment-%d,%s,Test Page,CSV User,user-1,,CSV text,,approved,%s,%s,0\n",
+				n, pageID, now.Format(time.RFC3339), now.Format(time.RFC3339))
+		}
+
+		var calls [][2]int
+		importer := NewImporter(store.GetDB(), StrategySkip)
+		importer.OnProgress = func(processed, total int) {
+			calls = append(calls, [2]int{processed, total})
+		}
+		if _, err := importer.ImportFromCSV(strings.NewReader(sb.String()), siteID); err != nil {
+			t.Fatalf("ImportFromCSV failed: %v", err)
+		}
+		assertProgress(t, calls)
+	})
+}
+
+func TestImporter_DedupeByContent(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 30, 10, 0, time.UTC)
+	sameContent := func(siteID, pageID string, secondCreatedAt time.Time) *bytes.Buffer {
+		exportData := createTestExportData(siteID, pageID)
+		first := exportData.Pages[0].Comments[0]
+		first.CreatedAt, first.UpdatedAt = createdAt, createdAt
+		second := first
+		second.ID = "comment-regenerated"
+		second.CreatedAt, second.UpdatedAt = secondCreatedAt, secondCreatedAt
+		exportData.Pages[0].Comments = []models.CommentExport{first, second}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(exportData); err != nil {
+			t.Fatalf("Failed to encode export data: %v", err)
+		}
+		return &buf
+	}
+
+	tests := []struct {
+		name            string
+		strategy        DuplicateStrategy
+		options         ImportOptions
+		secondCreatedAt time.Time
+		imported        int
+		skipped         int
+		updated         int
+	}{
+		{"disabled by default", StrategySkip, ImportOptions{}, createdAt.Add(20 * time.Second), 2, 0, 0},
+		{"skip", StrategySkip, ImportOptions{DedupeByContent: true}, createdAt.Add(20 * time.Second), 1, 1, 0},
+		{"update", StrategyUpdate, ImportOptions{DedupeByContent: true}, createdAt.Add(20 * time.Second), 1, 0, 1},
+		{"different minute", StrategySkip, ImportOptions{DedupeByContent: true}, createdAt.Add(time.Minute), 2, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := createTestDB(t)
+			defer store.Close()
+			siteID, pageID := createTestSite(t, store)
+
+			importer := NewImporterWithOptions(store.GetDB(), tt.strategy, tt.options)
+			result, err := importer.ImportFromJSON(sameContent(siteID, pageID, tt.secondCreatedAt), siteID)
+			if err != nil {
+				t.Fatalf("ImportFromJSON failed: %v", err)
+			}
+
+			if result.CommentsImported != tt.imported || result.CommentsSkipped != tt.skipped || result.CommentsUpdated != tt.updated {
+				t.Errorf("Expected imported/skipped/updated %d/%d/%d, got %d/%d/%d",
+					tt.imported, tt.skipped, tt.updated,
+					result.CommentsImported, result.CommentsSkipped, result.CommentsUpdated)
+			}
+
+			var count int
+			if err := store.GetDB().QueryRow(`SELECT COUNT(*) FROM comments WHERE page_id = ?`, pageID).Scan(&count); err != nil {
+				t.Fatalf("Failed to count comments: %v", err)
+			}
+			if count != tt.imported {
+				t.Errorf("Expected %d stored comments, got %d", tt.imported, count)
+			}
+		})
+	}
+}
+
+func TestImporter_ImportReactionsFromCSV(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, pageID := createTestSite(t, store)
+	db := store.GetDB()
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(createTestExportData(siteID, pageID)); err != nil {
+		t.Fatalf("Failed to encode export data: %v", err)
+	}
+	if _, err := NewImporter(db, StrategySkip).ImportFromJSON(&buf, siteID); err != nil {
+		t.Fatalf("ImportFromJSON failed: %v", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	csvData := "Reaction ID,Target Type,Target ID,Reaction Name,Reaction Emoji,User Identifier,Created At\n" +
+		"r1,comment,comment-1,thumbs_up,👍,user-1," + now + "\n" +
+		"r2,page," + pageID + ",heart,❤️,user-2," + now + "\n" +
+		"r3,comment,missing-comment,thumbs_up,👍,user-1," + now + "\n"
+
+	importer := NewImporter(db, StrategySkip)
+	result, err := importer.ImportReactionsFromCSV(strings.NewReader(csvData), siteID)
+	if err != nil {
+		t.Fatalf("ImportReactionsFromCSV failed: %v", err)
+	}
+
+	if result.ReactionsImported != 2 {
+		t.Errorf("Expected 2 reactions imported, got %d", result.ReactionsImported)
+	}
+	if result.AllowedReactionsImported != 1 {
+		t.Errorf("Expected 1 allowed reaction created, got %d", result.AllowedReactionsImported)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("Expected 1 error for the missing comment, got %v", result.Errors)
+	}
+
+	// The existing thumbs_up reaction is reused rather than duplicated
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM allowed_reactions WHERE site_id = ? AND name = 'thumbs_up'`, siteID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count allowed reactions: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected thumbs_up to be reused, found %d definitions", count)
+	}
+
+	var heartType string
+	if err := db.QueryRow(`SELECT reaction_type FROM allowed_reactions WHERE site_id = ? AND name = 'heart'`, siteID).Scan(&heartType); err != nil {
+		t.Fatalf("Expected heart reaction to be created: %v", err)
+	}
+	if heartType != "page" {
+		t.Errorf("Expected created heart reaction to be a page reaction, got %q", heartType)
+	}
+
+	// Importing the same file again skips every reaction
+	result, err = importer.ImportReactionsFromCSV(strings.NewReader(csvData), siteID)
+	if err != nil {
+		t.Fatalf("Second ImportReactionsFromCSV failed: %v", err)
+	}
+	if result.ReactionsImported != 0 || result.ReactionsSkipped != 2 {
+		t.Errorf("Expected 0 imported and 2 skipped on re-import, got %d and %d",
+			result.ReactionsImported, result.ReactionsSkipped)
+	}
+	if result.AllowedReactionsImported != 0 {
+		t.Errorf("Expected no allowed reactions created on re-import, got %d", result.AllowedReactionsImported)
+	}
+}
+
+func TestImporter_ImportReactionsFromCSV_AllowedReactionRules(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, pageID := createTestSite(t, store)
+	db := store.GetDB()
+
+	// createTestSite already offers thumbs_up, leaving room for two more
+	settings := models.DefaultSiteSettings(siteID)
+	settings.MaxAllowedReactions = 3
+	if err := models.NewSiteSettingsStore(db).Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	csvData := "Reaction ID,Target Type,Target ID,Reaction Name,Reaction Emoji,User Identifier,Created At\n" +
+		"r1,page," + pageID + ",heart,❤️,user-1," + now + "\n" +
+		"r2,page," + pageID + ",wave,hi,user-1," + now + "\n" +
+		"r3,page," + pageID + ",love,❤️,user-1," + now + "\n" +
+		"r4,page," + pageID + ",star,⭐,user-1," + now + "\n" +
+		"r5,page," + pageID + ",fire,🔥,user-1," + now + "\n"
+
+	result, err := NewImporter(db, StrategySkip).ImportReactionsFromCSV(strings.NewReader(csvData), siteID)
+	if err != nil {
+		t.Fatalf("ImportReactionsFromCSV failed: %v", err)
+	}
+
+	if result.AllowedReactionsImported != 2 || result.ReactionsImported != 2 {
+		t.Errorf("Expected heart and star to be created and imported, got %+v", result)
+	}
+	if len(result.Errors) != 3 {
+		t.Fatalf("Expected errors for the invalid emoji, duplicate emoji and cap, got %v", result.Errors)
+	}
+	for i, want := range []string{"Line 2:", "Line 3:", "Line 5:"} {
+		if !strings.HasPrefix(result.Errors[i], want) {
+			t.Errorf("Expected error %d to start with %q, got %q", i, want, result.Errors[i])
+		}
+	}
+	for i, want := range []string{"single emoji", "already", "at most 3"} {
+		if !strings.Contains(result.Errors[i], want) {
+			t.Errorf("Expected error %d to mention %q, got %q", i, want, result.Errors[i])
+		}
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM allowed_reactions WHERE site_id = ?`, siteID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count allowed reactions: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected the site to stay at its cap of 3 allowed reactions, got %d", count)
+	}
+}
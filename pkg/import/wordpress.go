@@ -0,0 +1,188 @@
+package importpkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// wxrDateFormat is the layout WordPress uses for comment dates
+const wxrDateFormat = "2006-01-02 15:04:05"
+
+// wxrDocument is the subset of a WordPress eXtended RSS (WXR) export we read.
+// Elements are matched by local name so any wp: namespace version is accepted.
+type wxrDocument struct {
+	Channel struct {
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// wxrItem is a post or page in a WXR export
+type wxrItem struct {
+	Title    string       `xml:"title"`
+	Link     string       `xml:"link"`
+	PostID   string       `xml:"post_id"`
+	Comments []wxrComment `xml:"comment"`
+}
+
+// wxrComment is a wp:comment element
+type wxrComment struct {
+	ID          string `xml:"comment_id"`
+	Author      string `xml:"comment_author"`
+	AuthorEmail string `xml:"comment_author_email"`
+	DateGMT     string `xml:"comment_date_gmt"`
+	Content     string `xml:"comment_content"`
+	Approved    string `xml:"comment_approved"`
+	Type        string `xml:"comment_type"`
+	Parent      string `xml:"comment_parent"`
+	UserID      string `xml:"comment_user_id"`
+}
+
+// ImportFromWordPressWXR imports comments from a WordPress WXR export.
+// Each post becomes a page keyed by the path of its link; comments that
+// cannot be parsed are reported in ImportResult.Errors rather than aborting.
+func (i *Importer) ImportFromWordPressWXR(r io.Reader, siteID string) (*ImportResult, error) {
+	var doc wxrDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode WXR: %w", err)
+	}
+
+	result := &ImportResult{
+		Errors: make([]string, 0),
+	}
+
+	// Start a transaction
+	tx, err := i.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range doc.Channel.Items {
+		if len(item.Comments) == 0 {
+			continue
+		}
+
+		page := &models.Page{
+			ID:    uuid.NewString(),
+			Path:  pagePathFromLink(item.Link),
+			Title: item.Title,
+		}
+		pageID, created, err := i.importPage(tx, siteID, page)
+		if err != nil {
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("Failed to import page %s: %v", page.Path, err))
+			continue
+		}
+
+		if created {
+			result.PagesCreated++
+		} else {
+			result.PagesSkipped++
+		}
+
+		for _, wc := range item.Comments {
+			// Pingbacks and trackbacks are not user comments
+			if wc.Type != "" && wc.Type != "comment" {
+				result.CommentsSkipped++
+				continue
+			}
+
+			comment, err := wxrToCommentExport(siteID, item.PostID, wc)
+			if err != nil {
+				result.Errors = append(result.Errors,
+					fmt.Sprintf("Failed to parse comment %s on %s: %v", wc.ID, page.Path, err))
+				continue
+			}
+
+			imported, skipped, updated, err := i.importComment(tx, siteID, pageID, comment)
+			if err != nil {
+				result.Errors = append(result.Errors,
+					fmt.Sprintf("Failed to import comment %s: %v", comment.ID, err))
+				continue
+			}
+
+			result.CommentsImported += imported
+			result.CommentsSkipped += skipped
+			result.CommentsUpdated += updated
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// wxrToCommentExport maps a WXR comment to a CommentExport. IDs are derived from
+// the site and the WordPress post and comment IDs so re-importing the same file
+// is idempotent and importing it into another site does not collide.
+func wxrToCommentExport(siteID, postID string, wc wxrComment) (*models.CommentExport, error) {
+	id := strings.TrimSpace(wc.ID)
+	if id == "" {
+		return nil, fmt.Errorf("missing comment_id")
+	}
+
+	text := strings.TrimSpace(wc.Content)
+	if text == "" {
+		return nil, fmt.Errorf("empty comment_content")
+	}
+
+	createdAt, err := time.ParseInLocation(wxrDateFormat, strings.TrimSpace(wc.DateGMT), time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment_date_gmt: %w", err)
+	}
+
+	author := strings.TrimSpace(wc.Author)
+	if author == "" {
+		author = "Anonymous"
+	}
+
+	// Registered WordPress users keep a stable ID; guests are identified by email when available
+	authorID := "wp-guest-" + id
+	if userID := strings.TrimSpace(wc.UserID); userID != "" && userID != "0" {
+		authorID = "wp-user-" + userID
+	} else if email := strings.TrimSpace(wc.AuthorEmail); email != "" {
+		authorID = "wp-email-" + strings.ToLower(email)
+	}
+
+	parentID := ""
+	if parent := strings.TrimSpace(wc.Parent); parent != "" && parent != "0" {
+		parentID = wxrCommentID(siteID, postID, parent)
+	}
+
+	return &models.CommentExport{
+		ID:          wxrCommentID(siteID, postID, id),
+		Author:      author,
+		AuthorID:    authorID,
+		AuthorEmail: strings.TrimSpace(wc.AuthorEmail),
+		Text:        text,
+		ParentID:    parentID,
+		Status:      wxrStatus(wc.Approved),
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}, nil
+}
+
+// wxrCommentID builds a site-scoped Kotomi comment ID from WordPress post and comment IDs
+func wxrCommentID(siteID, postID, commentID string) string {
+	return fmt.Sprintf("wp-%s-%s-%s", siteID, strings.TrimSpace(postID), commentID)
+}
+
+// wxrStatus maps comment_approved to a Kotomi comment status
+func wxrStatus(approved string) string {
+	switch strings.TrimSpace(approved) {
+	case "1", "approve":
+		return "approved"
+	case "spam", "trash":
+		return "rejected"
+	default:
+		return "pending"
+	}
+}
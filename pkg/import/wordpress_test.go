@@ -0,0 +1,185 @@
+package importpkg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+const testWXR = `<?xml version="1.0" encoding="UTF-8" ?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<title>My Blog</title>
+	<item>
+		<title>Hello World</title>
+		<link>https://blog.example.com/2024/01/hello-world/</link>
+		<wp:post_id>42</wp:post_id>
+		<wp:comment>
+			<wp:comment_id>1</wp:comment_id>
+			<wp:comment_author><![CDATA[Alice]]></wp:comment_author>
+			<wp:comment_author_email><![CDATA[alice@example.com]]></wp:comment_author_email>
+			<wp:comment_date_gmt><![CDATA[2024-01-02 10:00:00]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[Great post!]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[1]]></wp:comment_approved>
+			<wp:comment_type><![CDATA[comment]]></wp:comment_type>
+			<wp:comment_parent>0</wp:comment_parent>
+			<wp:comment_user_id>0</wp:comment_user_id>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>2</wp:comment_id>
+			<wp:comment_author><![CDATA[Bob]]></wp:comment_author>
+			<wp:comment_date_gmt><![CDATA[2024-01-02 11:00:00]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[Thanks <b>Alice</b>]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[0]]></wp:comment_approved>
+			<wp:comment_type><![CDATA[]]></wp:comment_type>
+			<wp:comment_parent>1</wp:comment_parent>
+			<wp:comment_user_id>7</wp:comment_user_id>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>3</wp:comment_id>
+			<wp:comment_author><![CDATA[Broken]]></wp:comment_author>
+			<wp:comment_date_gmt><![CDATA[not a date]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[Bad date]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[1]]></wp:comment_approved>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>4</wp:comment_id>
+			<wp:comment_author><![CDATA[Other Blog]]></wp:comment_author>
+			<wp:comment_date_gmt><![CDATA[2024-01-03 09:00:00]]></wp:comment_date_gmt>
+			<wp:comment_content><![CDATA[Linked to you]]></wp:comment_content>
+			<wp:comment_approved><![CDATA[1]]></wp:comment_approved>
+			<wp:comment_type><![CDATA[pingback]]></wp:comment_type>
+		</wp:comment>
+	</item>
+	<item>
+		<title>No Comments</title>
+		<link>https://blog.example.com/about/</link>
+		<wp:post_id>43</wp:post_id>
+	</item>
+</channel>
+</rss>`
+
+func TestImporter_ImportFromWordPressWXR(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, _ := createTestSite(t, store)
+	db := store.GetDB()
+
+	importer := NewImporter(db, StrategySkip)
+	result, err := importer.ImportFromWordPressWXR(strings.NewReader(testWXR), siteID)
+	if err != nil {
+		t.Fatalf("ImportFromWordPressWXR failed: %v", err)
+	}
+
+	if result.PagesCreated != 1 {
+		t.Errorf("Expected 1 page created, got %d", result.PagesCreated)
+	}
+	if result.CommentsImported != 2 {
+		t.Errorf("Expected 2 comments imported, got %d", result.CommentsImported)
+	}
+	if result.CommentsSkipped != 1 {
+		t.Errorf("Expected 1 comment skipped (pingback), got %d", result.CommentsSkipped)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("Expected 1 error for the unparseable comment, got %v", result.Errors)
+	}
+
+	// Verify the page was keyed by the link path
+	var pageTitle string
+	err = db.QueryRow(`SELECT title FROM pages WHERE site_id = ? AND path = ?`,
+		siteID, "/2024/01/hello-world/").Scan(&pageTitle)
+	if err != nil {
+		t.Fatalf("Failed to find imported page: %v", err)
+	}
+	if pageTitle != "Hello World" {
+		t.Errorf("Expected page title 'Hello World', got '%s'", pageTitle)
+	}
+
+	// Verify the reply mapping and status
+	var parentID, status, authorID string
+	err = db.QueryRow(`SELECT parent_id, status, author_id FROM comments WHERE id = ?`, "wp-"+siteID+"-42-2").
+		Scan(&parentID, &status, &authorID)
+	if err != nil {
+		t.Fatalf("Failed to find imported reply: %v", err)
+	}
+	if parentID != "wp-"+siteID+"-42-1" {
+		t.Errorf("Expected parent 'wp-%s-42-1', got '%s'", siteID, parentID)
+	}
+	if status != "pending" {
+		t.Errorf("Expected status 'pending', got '%s'", status)
+	}
+	if authorID != "wp-user-7" {
+		t.Errorf("Expected author ID 'wp-user-7', got '%s'", authorID)
+	}
+
+	// Re-importing the same file skips existing comments
+	result, err = importer.ImportFromWordPressWXR(strings.NewReader(testWXR), siteID)
+	if err != nil {
+		t.Fatalf("Second ImportFromWordPressWXR failed: %v", err)
+	}
+	if result.CommentsImported != 0 || result.PagesSkipped != 1 {
+		t.Errorf("Expected re-import to skip everything, got %+v", result)
+	}
+}
+
+func TestImporter_ImportFromWordPressWXR_TwoSites(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteA, _ := createTestSite(t, store)
+	db := store.GetDB()
+
+	siteB, err := models.NewSiteStore(db).Create(context.Background(), "admin-1", "Other Site", "other.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create second site: %v", err)
+	}
+
+	importer := NewImporter(db, StrategyUpdate)
+	for _, siteID := range []string{siteA, siteB.ID} {
+		result, err := importer.ImportFromWordPressWXR(strings.NewReader(testWXR), siteID)
+		if err != nil {
+			t.Fatalf("ImportFromWordPressWXR into %s failed: %v", siteID, err)
+		}
+		if result.CommentsImported != 2 || result.CommentsUpdated != 0 {
+			t.Errorf("Expected 2 new comments for site %s, got %+v", siteID, result)
+		}
+	}
+
+	// Each site owns its own copy, and the reply points at its own site's parent
+	for _, siteID := range []string{siteA, siteB.ID} {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM comments WHERE site_id = ?`, siteID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count comments: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 comments for site %s, got %d", siteID, count)
+		}
+
+		var parentID string
+		err := db.QueryRow(`SELECT parent_id FROM comments WHERE id = ? AND site_id = ?`,
+			"wp-"+siteID+"-42-2", siteID).Scan(&parentID)
+		if err != nil {
+			t.Fatalf("Failed to find reply for site %s: %v", siteID, err)
+		}
+		if parentID != "wp-"+siteID+"-42-1" {
+			t.Errorf("Expected reply to stay within site %s, got parent '%s'", siteID, parentID)
+		}
+	}
+}
+
+func TestImporter_ImportFromWordPressWXR_InvalidXML(t *testing.T) {
+	store := createTestDB(t)
+	defer store.Close()
+
+	siteID, _ := createTestSite(t, store)
+	importer := NewImporter(store.GetDB(), StrategySkip)
+
+	if _, err := importer.ImportFromWordPressWXR(strings.NewReader("<rss><channel>"), siteID); err == nil {
+		t.Error("Expected error for malformed XML")
+	}
+}
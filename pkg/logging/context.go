@@ -19,6 +19,8 @@ const (
 	ContextKeyUserID ContextKey = "user_id"
 	// ContextKeyCommentID is the context key for comment ID
 	ContextKeyCommentID ContextKey = "comment_id"
+	// ContextKeyUserSlot is the context key for the request's authenticated user slot
+	ContextKeyUserSlot ContextKey = "user_slot"
 )
 
 // WithRequestID adds a request ID to the context
@@ -86,6 +88,29 @@ func GetCommentID(ctx context.Context) string {
 	return ""
 }
 
+// WithUserSlot adds a slot that authentication middleware fills in with
+// SetAuthenticatedUser. Request logging wraps the whole handler chain and can't
+// see context values added further down, so it reads the user from this slot.
+func WithUserSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ContextKeyUserSlot, new(string))
+}
+
+// SetAuthenticatedUser records the authenticated user in the context's user slot,
+// if there is one
+func SetAuthenticatedUser(ctx context.Context, userID string) {
+	if slot, ok := ctx.Value(ContextKeyUserSlot).(*string); ok {
+		*slot = userID
+	}
+}
+
+// GetAuthenticatedUser returns the user recorded in the context's user slot
+func GetAuthenticatedUser(ctx context.Context) string {
+	if slot, ok := ctx.Value(ContextKeyUserSlot).(*string); ok {
+		return *slot
+	}
+	return ""
+}
+
 // ContextHandler is a slog.Handler that automatically includes contextual fields from context
 type ContextHandler struct {
 	handler slog.Handler
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// ContextKeyAPIKey is the context key for the API key a request was made with
+const ContextKeyAPIKey ContextKey = "api_key"
+
+// maxAPIKeyBodyBytes caps the body read to find the author of an API-key request
+const maxAPIKeyBodyBytes = 1 << 20
+
+// APIKeyAuth authenticates server-to-server requests sent with
+// "Authorization: ApiKey <key>". The key must belong to the site in the path.
+// Since there is no user token, the body must name the author in author_id,
+// who must already be a user of the site; they are put in the context under
+// ContextKeyUser so handlers treat the request as theirs. Requests without an
+// API key are passed on untouched.
+func APIKeyAuth(db *sql.DB) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, plaintext, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, "ApiKey") || db == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			apiKey, err := models.NewAPIKeyStore(db).Authenticate(r.Context(), strings.TrimSpace(plaintext))
+			if errors.Is(err, models.ErrAPIKeyNotFound) || errors.Is(err, models.ErrAPIKeyRevoked) {
+				apierrors.WriteErrorWithRequestID(w, apierrors.Unauthorized("Invalid API key"), GetRequestID(r))
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to authenticate API key: %v", err)
+				apierrors.WriteErrorWithRequestID(w, apierrors.InternalServerError("Failed to authenticate API key"), GetRequestID(r))
+				return
+			}
+			siteID := mux.Vars(r)["siteId"]
+			if apiKey.SiteID != siteID {
+				apierrors.WriteErrorWithRequestID(w, apierrors.Forbidden("API key is not valid for this site"), GetRequestID(r))
+				return
+			}
+
+			authorID, err := peekAuthorID(r)
+			if err != nil {
+				apierrors.WriteErrorWithRequestID(w, apierrors.BadRequest("Invalid request body"), GetRequestID(r))
+				return
+			}
+			if authorID == "" {
+				apierrors.WriteErrorWithRequestID(w, apierrors.BadRequest("author_id is required for API key requests"), GetRequestID(r))
+				return
+			}
+			author, err := models.NewUserStore(db).GetBySiteAndID(r.Context(), siteID, authorID)
+			if err != nil {
+				log.Printf("Failed to look up API key author %s on site %s: %v", authorID, siteID, err)
+				apierrors.WriteErrorWithRequestID(w, apierrors.InternalServerError("Failed to authenticate API key"), GetRequestID(r))
+				return
+			}
+			if author == nil {
+				apierrors.WriteErrorWithRequestID(w, apierrors.BadRequest("author_id is not a user of this site"), GetRequestID(r))
+				return
+			}
+
+			kotomiUser := &models.KotomiUser{
+				ID:         author.ID,
+				Name:       author.Name,
+				Email:      author.Email,
+				AvatarURL:  author.AvatarURL,
+				ProfileURL: author.ProfileURL,
+				Verified:   author.IsVerified,
+				Roles:      author.Roles,
+			}
+			ctx := context.WithValue(r.Context(), ContextKeyUser, kotomiUser)
+			ctx = context.WithValue(ctx, ContextKeyAPIKey, apiKey)
+			logging.SetAuthenticatedUser(ctx, kotomiUser.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetAPIKeyFromContext returns the API key a request was authenticated with,
+// or nil for requests made by a user
+func GetAPIKeyFromContext(ctx context.Context) *models.APIKey {
+	apiKey, _ := ctx.Value(ContextKeyAPIKey).(*models.APIKey)
+	return apiKey
+}
+
+// peekAuthorID reads author_id from a JSON body, leaving the body in place
+// for the handler. An empty body has no author.
+func peekAuthorID(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAPIKeyBodyBytes))
+	r.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "", nil
+	}
+
+	var payload struct {
+		AuthorID string `json:"author_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(payload.AuthorID), nil
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	store, err := comments.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer store.Close()
+	db := store.GetDB()
+	ctx := context.Background()
+
+	owner, _ := models.NewAdminUserStore(db).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	siteStore := models.NewSiteStore(db)
+	site, _ := siteStore.Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	otherSite, _ := siteStore.Create(ctx, owner.ID, "Other", "other.example.com", "")
+	if err := models.NewUserStore(db).CreateOrUpdate(ctx, &models.User{ID: "alice", SiteID: site.ID, Name: "Alice"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	keys := models.NewAPIKeyStore(db)
+	_, validKey, err := keys.Create(ctx, site.ID, "backend")
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	revoked, revokedKey, _ := keys.Create(ctx, site.ID, "old backend")
+	if err := keys.Revoke(ctx, site.ID, revoked.ID); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	_, otherSiteKey, _ := keys.Create(ctx, otherSite.ID, "other backend")
+
+	var gotUser *models.KotomiUser
+	var gotBody string
+	router := mux.NewRouter()
+	router.Use(APIKeyAuth(db))
+	router.Use(JWTAuthMiddleware(db))
+	router.HandleFunc("/site/{siteId}/page/{pageId}/comments", func(w http.ResponseWriter, r *http.Request) {
+		gotUser = GetUserFromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusCreated)
+	}).Methods("POST")
+
+	post := func(authorization, body string) int {
+		gotUser, gotBody = nil, ""
+		req := httptest.NewRequest(http.MethodPost, "/site/"+site.ID+"/page/page-1/comments", strings.NewReader(body))
+		req.Header.Set("Authorization", authorization)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	body := `{"author_id":"alice","text":"Posted from the backend"}`
+	if code := post("ApiKey "+validKey, body); code != http.StatusCreated {
+		t.Fatalf("Expected 201 for a valid key, got %d", code)
+	}
+	if gotUser == nil || gotUser.ID != "alice" || gotUser.Name != "Alice" {
+		t.Errorf("Expected alice in the context, got %+v", gotUser)
+	}
+	if gotBody != body {
+		t.Errorf("Expected the body to reach the handler unchanged, got %q", gotBody)
+	}
+	listed, _ := keys.ListBySite(ctx, site.ID)
+	for _, k := range listed {
+		if k.Name == "backend" && k.LastUsedAt == nil {
+			t.Error("Expected last_used_at to be recorded")
+		}
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		body          string
+		want          int
+	}{
+		{"revoked key", "ApiKey " + revokedKey, body, http.StatusUnauthorized},
+		{"unknown key", "ApiKey kotomi_not-a-real-key", body, http.StatusUnauthorized},
+		{"other site's key", "ApiKey " + otherSiteKey, body, http.StatusForbidden},
+		{"missing author", "ApiKey " + validKey, `{"text":"Who am I?"}`, http.StatusBadRequest},
+		{"unknown author", "ApiKey " + validKey, `{"author_id":"mallory","text":"Hi"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := post(tt.authorization, tt.body); code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, code)
+			}
+			if gotUser != nil {
+				t.Errorf("Expected the handler not to run, got user %+v", gotUser)
+			}
+		})
+	}
+}
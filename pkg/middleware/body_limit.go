@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+)
+
+// Default body limits for the write routes
+const (
+	DefaultCommentBodyBytes  = 64 << 10
+	DefaultReactionBodyBytes = 1 << 10
+)
+
+// BodyLimit rejects requests whose body is larger than a fixed size
+type BodyLimit struct {
+	maxBytes int64
+}
+
+// NewBodyLimit creates a limit of maxBytes per request body
+func NewBodyLimit(maxBytes int64) *BodyLimit {
+	if maxBytes <= 0 {
+		maxBytes = DefaultCommentBodyBytes
+	}
+	return &BodyLimit{maxBytes: maxBytes}
+}
+
+// NewBodyLimitFromEnv creates a limit from the byte count in the key
+// environment variable, falling back to defaultBytes
+func NewBodyLimitFromEnv(key string, defaultBytes int64) *BodyLimit {
+	return NewBodyLimit(int64(getEnvInt(key, int(defaultBytes))))
+}
+
+// Handler returns middleware that enforces the limit. The body is read
+// through http.MaxBytesReader before next runs, so an oversized body gets 413
+// instead of surfacing as a JSON decoding error (400) in the handler.
+func (bl *BodyLimit) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > bl.maxBytes {
+			bl.writeTooLarge(w, r)
+			return
+		}
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, bl.maxBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				bl.writeTooLarge(w, r)
+				return
+			}
+			apierrors.WriteErrorWithRequestID(w, apierrors.BadRequest("Failed to read request body"), GetRequestID(r))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wrap applies the limit to a single handler function
+func (bl *BodyLimit) Wrap(next http.HandlerFunc) http.Handler {
+	return bl.Handler(next)
+}
+
+func (bl *BodyLimit) writeTooLarge(w http.ResponseWriter, r *http.Request) {
+	apierrors.WriteErrorWithRequestID(w, apierrors.PayloadTooLarge(
+		fmt.Sprintf("Request body must not exceed %d bytes", bl.maxBytes)), GetRequestID(r))
+}
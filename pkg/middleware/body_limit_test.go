@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+)
+
+func TestBodyLimit(t *testing.T) {
+	const limit = 64
+	handler := NewBodyLimit(limit).Wrap(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			apierrors.WriteError(w, apierrors.InvalidJSON("Invalid request body"))
+			return
+		}
+		w.Write([]byte(payload.Text))
+	})
+
+	// body returns a JSON payload exactly size bytes long
+	body := func(size int) string {
+		return `{"text":"` + strings.Repeat("a", size-len(`{"text":""}`)) + `"}`
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64 // -1 for a body of unknown length
+		wantStatus    int
+		wantCode      apierrors.ErrorCode
+	}{
+		{"just under the limit", body(limit), int64(limit), http.StatusOK, ""},
+		{"just over the limit", body(limit + 1), int64(limit + 1), http.StatusRequestEntityTooLarge, apierrors.ErrCodePayloadTooLarge},
+		{"over the limit without a content length", body(limit + 1), -1, http.StatusRequestEntityTooLarge, apierrors.ErrCodePayloadTooLarge},
+		{"malformed JSON under the limit", `{"text":`, 8, http.StatusBadRequest, apierrors.ErrCodeInvalidJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode == "" {
+				if rr.Body.Len() != limit-len(`{"text":""}`) {
+					t.Errorf("Expected the handler to read the whole body, got %q", rr.Body.String())
+				}
+				return
+			}
+			var apiErr apierrors.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &apiErr); err != nil {
+				t.Fatalf("Failed to decode error: %v", err)
+			}
+			if apiErr.Error.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, apiErr.Error.Code)
+			}
+		})
+	}
+}
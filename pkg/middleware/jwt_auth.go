@@ -3,12 +3,14 @@ package middleware
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/saasuke-labs/kotomi/pkg/auth"
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
 	"github.com/saasuke-labs/kotomi/pkg/models"
 )
 
@@ -24,6 +26,12 @@ const (
 func JWTAuthMiddleware(db *sql.DB) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Already authenticated by SharedSecretAuth
+			if GetUserFromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Extract site ID from request path
 			vars := mux.Vars(r)
 			siteID := vars["siteId"]
@@ -33,7 +41,7 @@ func JWTAuthMiddleware(db *sql.DB) mux.MiddlewareFunc {
 			}
 
 			if siteID == "" {
-				writeJSONError(w, "Site ID not found in request", http.StatusBadRequest)
+				apierrors.WriteErrorWithRequestID(w, apierrors.BadRequest("Site ID not found in request"), GetRequestID(r))
 				return
 			}
 
@@ -41,7 +49,7 @@ func JWTAuthMiddleware(db *sql.DB) mux.MiddlewareFunc {
 			authConfigStore := models.NewSiteAuthConfigStore(db)
 			authConfig, err := authConfigStore.GetBySiteID(r.Context(), siteID)
 			if err != nil {
-				writeJSONError(w, "Authentication not configured for this site", http.StatusUnauthorized)
+				apierrors.WriteErrorWithRequestID(w, apierrors.Unauthorized("Authentication not configured for this site"), GetRequestID(r))
 				return
 			}
 
@@ -58,7 +66,7 @@ func JWTAuthMiddleware(db *sql.DB) mux.MiddlewareFunc {
 			}
 			
 			if token == "" {
-				writeJSONError(w, "Authorization token required", http.StatusUnauthorized)
+				apierrors.WriteErrorWithRequestID(w, apierrors.Unauthorized("Authorization token required"), GetRequestID(r))
 				return
 			}
 
@@ -66,31 +74,17 @@ func JWTAuthMiddleware(db *sql.DB) mux.MiddlewareFunc {
 			validator := auth.NewJWTValidator(authConfig)
 			kotomiUser, err := validator.ValidateToken(token)
 			if err != nil {
-				writeJSONError(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
+				log.Printf("Invalid token for site %s: %v", siteID, err)
+				apierrors.WriteErrorWithRequestID(w, apierrors.Unauthorized("Invalid token"), GetRequestID(r))
 				return
 			}
 
 			// Persist/update user in database (Phase 2)
-			userStore := models.NewUserStore(db)
-			user := &models.User{
-				ID:         kotomiUser.ID,
-				SiteID:     siteID,
-				Name:       kotomiUser.Name,
-				Email:      kotomiUser.Email,
-				AvatarURL:  kotomiUser.AvatarURL,
-				ProfileURL: kotomiUser.ProfileURL,
-				IsVerified: kotomiUser.Verified,
-				Roles:      kotomiUser.Roles,
-			}
-			
-			if err := userStore.CreateOrUpdate(r.Context(), user); err != nil {
-				// Log error but don't fail the request
-				// User data will still be available from JWT
-				fmt.Printf("Warning: failed to persist user: %v\n", err)
-			}
+			persistUser(r.Context(), db, siteID, kotomiUser)
 
 			// Add kotomi user to request context
 			ctx := context.WithValue(r.Context(), ContextKeyUser, kotomiUser)
+			logging.SetAuthenticatedUser(ctx, kotomiUser.ID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -111,7 +105,7 @@ func RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := GetUserFromContext(r.Context())
 		if user == nil {
-			writeJSONError(w, "Authentication required", http.StatusUnauthorized)
+			apierrors.WriteErrorWithRequestID(w, apierrors.Unauthorized("Authentication required"), GetRequestID(r))
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -129,9 +123,10 @@ func OptionalAuth(db *sql.DB) mux.MiddlewareFunc {
 				siteID = vars["site_id"]
 			}
 
-			// If no site ID, or no auth header, just continue without user
+			// If no site ID, no auth header or a user already authenticated by
+			// SharedSecretAuth, just continue
 			authHeader := r.Header.Get("Authorization")
-			if siteID == "" || authHeader == "" {
+			if siteID == "" || authHeader == "" || GetUserFromContext(r.Context()) != nil {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -151,25 +146,11 @@ func OptionalAuth(db *sql.DB) mux.MiddlewareFunc {
 				kotomiUser, err := validator.ValidateToken(token)
 				if err == nil && kotomiUser != nil {
 					// Persist/update user in database (Phase 2)
-					userStore := models.NewUserStore(db)
-					user := &models.User{
-						ID:         kotomiUser.ID,
-						SiteID:     siteID,
-						Name:       kotomiUser.Name,
-						Email:      kotomiUser.Email,
-						AvatarURL:  kotomiUser.AvatarURL,
-						ProfileURL: kotomiUser.ProfileURL,
-						IsVerified: kotomiUser.Verified,
-						Roles:      kotomiUser.Roles,
-					}
-					
-					if err := userStore.CreateOrUpdate(r.Context(), user); err != nil {
-						// Log error but don't fail the request
-						fmt.Printf("Warning: failed to persist user: %v\n", err)
-					}
+					persistUser(r.Context(), db, siteID, kotomiUser)
 
 					// Add user to context if validation succeeded
 					ctx := context.WithValue(r.Context(), ContextKeyUser, kotomiUser)
+					logging.SetAuthenticatedUser(ctx, kotomiUser.ID)
 					r = r.WithContext(ctx)
 				}
 			}
@@ -179,11 +160,20 @@ func OptionalAuth(db *sql.DB) mux.MiddlewareFunc {
 	}
 }
 
-// writeJSONError writes a JSON error response
-func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+// persistUser records the token's user in the site's users table. Failures
+// are logged but don't fail the request; the user is still known from the JWT.
+func persistUser(ctx context.Context, db *sql.DB, siteID string, kotomiUser *models.KotomiUser) {
+	user := &models.User{
+		ID:         kotomiUser.ID,
+		SiteID:     siteID,
+		Name:       kotomiUser.Name,
+		Email:      kotomiUser.Email,
+		AvatarURL:  kotomiUser.AvatarURL,
+		ProfileURL: kotomiUser.ProfileURL,
+		IsVerified: kotomiUser.Verified,
+		Roles:      kotomiUser.Roles,
+	}
+	if err := models.NewUserStore(db).CreateOrUpdate(ctx, user); err != nil {
+		fmt.Printf("Warning: failed to persist user: %v\n", err)
+	}
 }
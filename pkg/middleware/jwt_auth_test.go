@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestJWTAuthMiddleware_ErrorEnvelope(t *testing.T) {
+	db, siteID := newSharedSecretSite(t)
+
+	router := mux.NewRouter()
+	router.Use(RequestIDMiddleware)
+	sub := router.PathPrefix("/api/v1/site/{siteId}").Subrouter()
+	sub.Use(JWTAuthMiddleware(db))
+	sub.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name        string
+		siteID      string
+		token       string
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"missing token", siteID, "", http.StatusUnauthorized, "UNAUTHORIZED", "Authorization token required"},
+		{"token signed with another secret", siteID, signSharedSecretToken(t, "another-secret-key-min-32-characters-long", "kotomi"),
+			http.StatusUnauthorized, "UNAUTHORIZED", "Invalid token"},
+		{"site without auth config", "unknown-site", "", http.StatusUnauthorized, "UNAUTHORIZED", "Authentication not configured for this site"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/site/"+tt.siteID+"/comments", nil)
+			req.Header.Set("X-Request-ID", "req-auth")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", ct)
+			}
+
+			var body map[string]map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Expected an error envelope, got %s", rr.Body.String())
+			}
+			apiErr, ok := body["error"]
+			if !ok || len(body) != 1 {
+				t.Fatalf("Expected only an error object, got %s", rr.Body.String())
+			}
+			if apiErr["code"] != tt.wantCode {
+				t.Errorf("Expected code %s, got %v", tt.wantCode, apiErr["code"])
+			}
+			if apiErr["message"] != tt.wantMessage {
+				t.Errorf("Expected message %q, got %v", tt.wantMessage, apiErr["message"])
+			}
+			if apiErr["request_id"] != "req-auth" {
+				t.Errorf("Expected request ID req-auth, got %v", apiErr["request_id"])
+			}
+			if details, ok := apiErr["details"]; ok {
+				t.Errorf("Expected token error details to stay out of the response, got %v", details)
+			}
+		})
+	}
+}
+
+func TestRequireAuth_ErrorEnvelope(t *testing.T) {
+	handler := RequestIDMiddleware(RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/s1/comments", nil)
+	req.Header.Set("X-Request-ID", "req-require")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", rr.Code)
+	}
+	var body struct {
+		Error struct {
+			Code      string `json:"code"`
+			Message   string `json:"message"`
+			RequestID string `json:"request_id"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode error: %v", err)
+	}
+	if body.Error.Code != "UNAUTHORIZED" || body.Error.Message != "Authentication required" || body.Error.RequestID != "req-require" {
+		t.Errorf("Unexpected error envelope: %s", rr.Body.String())
+	}
+}
@@ -3,7 +3,6 @@ package middleware
 import (
 	"encoding/json"
 	"log"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -94,96 +93,6 @@ func (l *Logger) Error(message string, requestID string, err error) {
 	l.Log(LogLevelError, message, requestID, extra)
 }
 
-// responseWriter wraps http.ResponseWriter to capture the status code
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-	written    bool
-}
-
-func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{
-		ResponseWriter: w,
-		statusCode:     http.StatusOK, // Default status code
-	}
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	if !rw.written {
-		rw.statusCode = code
-		rw.written = true
-		rw.ResponseWriter.WriteHeader(code)
-	}
-}
-
-func (rw *responseWriter) Write(b []byte) (int, error) {
-	if !rw.written {
-		rw.WriteHeader(http.StatusOK)
-	}
-	return rw.ResponseWriter.Write(b)
-}
-
-// LoggingMiddleware logs all HTTP requests and responses
-func LoggingMiddleware(logger *Logger) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-			
-			// Get request ID from context
-			requestID := GetRequestID(r)
-			
-			// Create response writer wrapper to capture status code
-			rw := newResponseWriter(w)
-			
-			// Get remote address (prefer X-Forwarded-For or X-Real-IP)
-			remoteAddr := r.Header.Get("X-Forwarded-For")
-			if remoteAddr == "" {
-				remoteAddr = r.Header.Get("X-Real-IP")
-			}
-			if remoteAddr == "" {
-				remoteAddr = r.RemoteAddr
-			}
-			
-			// Remove sensitive headers from logging
-			userAgent := r.UserAgent()
-			
-			// Call the next handler
-			next.ServeHTTP(rw, r)
-			
-			// Calculate duration
-			duration := time.Since(start)
-			
-			// Determine log level based on status code
-			var level LogLevel
-			statusCode := rw.statusCode
-			switch {
-			case statusCode >= 500:
-				level = LogLevelError
-			case statusCode >= 400:
-				level = LogLevelWarn
-			default:
-				level = LogLevelInfo
-			}
-			
-			// Log the request/response
-			entry := LogEntry{
-				Timestamp:  time.Now().UTC().Format(time.RFC3339),
-				Level:      level,
-				Message:    "HTTP Request",
-				RequestID:  requestID,
-				Method:     r.Method,
-				Path:       sanitizePath(r.URL.Path),
-				StatusCode: statusCode,
-				Duration:   duration.String(),
-				RemoteAddr: sanitizeIP(remoteAddr),
-				UserAgent:  userAgent,
-			}
-			
-			logger.writeLog(entry)
-		})
-	}
-}
-
 // sanitizePath removes sensitive information from the path
 func sanitizePath(path string) string {
 	// Don't log query parameters that might contain sensitive data
@@ -192,26 +101,3 @@ func sanitizePath(path string) string {
 	}
 	return path
 }
-
-// sanitizeIP removes port from IP address for cleaner logs
-func sanitizeIP(addr string) string {
-	// Handle IPv6 addresses with port notation: [address]:port
-	if strings.HasPrefix(addr, "[") {
-		if idx := strings.Index(addr, "]:"); idx != -1 {
-			// Extract just the address part without brackets
-			return addr[1:idx]
-		}
-	}
-	
-	// Check if it's a plain IPv6 address (multiple colons, no brackets)
-	if strings.Count(addr, ":") > 1 {
-		return addr
-	}
-	
-	// Handle IPv4 with port: address:port
-	if idx := strings.LastIndex(addr, ":"); idx != -1 {
-		return addr[:idx]
-	}
-	
-	return addr
-}
@@ -4,9 +4,10 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
 )
 
 // RateLimiter manages rate limiting for API endpoints
@@ -56,24 +57,8 @@ func NewRateLimiter() *RateLimiter {
 // Handler returns middleware that enforces rate limits
 func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get client IP (check X-Forwarded-For for proxies, fallback to RemoteAddr)
-		ip := r.Header.Get("X-Forwarded-For")
-		if ip != "" {
-			// X-Forwarded-For can contain multiple IPs: "client, proxy1, proxy2"
-			// Use only the first (client) IP
-			if commaIdx := strings.Index(ip, ","); commaIdx != -1 {
-				ip = strings.TrimSpace(ip[:commaIdx])
-			}
-		}
-		if ip == "" {
-			ip = r.Header.Get("X-Real-IP")
-		}
-		if ip == "" {
-			ip = r.RemoteAddr
-		}
-
 		// Get or create visitor
-		v := rl.getVisitor(ip)
+		v := rl.getVisitor(ClientIP(r))
 
 		// Check rate limit based on method
 		var allowed bool
@@ -113,7 +98,7 @@ func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
 			}
 			
 			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
-			http.Error(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
+			apierrors.WriteErrorWithRequestID(w, apierrors.RateLimitExceeded("Rate limit exceeded. Please try again later."), GetRequestID(r))
 			return
 		}
 
@@ -247,6 +247,7 @@ func TestRateLimiter_XForwardedFor_MultipleIPs(t *testing.T) {
 	// First request with X-Forwarded-For containing multiple IPs
 	req := httptest.NewRequest("GET", "/api/test", nil)
 	req.Header.Set("X-Forwarded-For", "10.0.0.2, 192.168.1.1, 172.16.0.1")
+	req.RemoteAddr = "10.0.0.254:1234" // Trusted proxy
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
 
@@ -257,6 +258,7 @@ func TestRateLimiter_XForwardedFor_MultipleIPs(t *testing.T) {
 	// Second request with same client IP (first in list) should be rate limited
 	req = httptest.NewRequest("GET", "/api/test", nil)
 	req.Header.Set("X-Forwarded-For", "10.0.0.2, 192.168.99.99")
+	req.RemoteAddr = "10.0.0.254:1234" // Trusted proxy
 	w = httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
 
@@ -267,6 +269,7 @@ func TestRateLimiter_XForwardedFor_MultipleIPs(t *testing.T) {
 	// Request with different client IP should be allowed
 	req = httptest.NewRequest("GET", "/api/test", nil)
 	req.Header.Set("X-Forwarded-For", "10.0.0.3, 192.168.1.1")
+	req.RemoteAddr = "10.0.0.254:1234" // Trusted proxy
 	w = httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
 
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+)
+
+// statusRecorder wraps http.ResponseWriter to capture the status code and the
+// number of body bytes written
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rw *statusRecorder) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.status = code
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *statusRecorder) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (rw *statusRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if !rw.wroteHeader {
+			rw.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *statusRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// RequestLogger logs every completed request through logger with its method,
+// path, status, bytes written, duration, client and authenticated user. Request
+// IDs are added by the logger's logging.ContextHandler. Panics are recovered,
+// logged with a stack trace and answered with a 500 carrying the request ID.
+func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := logging.WithUserSlot(r.Context())
+			r = r.WithContext(ctx)
+			rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					if recovered == http.ErrAbortHandler {
+						panic(recovered)
+					}
+
+					logger.ErrorContext(ctx, "panic serving request",
+						"method", r.Method,
+						"path", sanitizePath(r.URL.Path),
+						"panic", fmt.Sprint(recovered),
+						"stack", string(debug.Stack()))
+
+					if !rw.wroteHeader {
+						apierrors.WriteErrorWithRequestID(rw, apierrors.InternalServerError("Internal server error"), GetRequestID(r))
+					}
+					rw.status = http.StatusInternalServerError
+				}
+
+				level := slog.LevelInfo
+				switch {
+				case rw.status >= 500:
+					level = slog.LevelError
+				case rw.status >= 400:
+					level = slog.LevelWarn
+				}
+
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", sanitizePath(r.URL.Path)),
+					slog.Int("status", rw.status),
+					slog.Int("bytes", rw.bytes),
+					slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+					slog.String("remote_addr", ClientIP(r)),
+					slog.String("user_agent", r.UserAgent()),
+				}
+				if userID := logging.GetAuthenticatedUser(ctx); userID != "" {
+					attrs = append(attrs, slog.String("user_id", userID))
+				}
+
+				logger.LogAttrs(ctx, level, "http request", attrs...)
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
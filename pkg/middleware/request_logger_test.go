@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+)
+
+// newTestRequestLogger returns a logger configured like the server's, writing JSON lines to buf
+func newTestRequestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(logging.NewContextHandler(slog.NewJSONHandler(buf, nil)))
+}
+
+// decodeLogLines parses every JSON log line in buf
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRequestLogger_LogsCompletedRequest(t *testing.T) {
+	var buf bytes.Buffer
+	handler := RequestIDMiddleware(RequestLogger(newTestRequestLogger(&buf))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.SetAuthenticatedUser(r.Context(), "user-42")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("hello"))
+		})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/s1/page/p1/comments", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	entries := decodeLogLines(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry["method"] != "POST" || entry["path"] != "/api/v1/site/s1/page/p1/comments" {
+		t.Errorf("Unexpected method/path: %v %v", entry["method"], entry["path"])
+	}
+	if entry["status"] != float64(http.StatusCreated) {
+		t.Errorf("Expected status 201, got %v", entry["status"])
+	}
+	if entry["bytes"] != float64(5) {
+		t.Errorf("Expected 5 bytes, got %v", entry["bytes"])
+	}
+	if entry["request_id"] != "req-123" {
+		t.Errorf("Expected request ID req-123, got %v", entry["request_id"])
+	}
+	if entry["user_id"] != "user-42" {
+		t.Errorf("Expected user ID user-42, got %v", entry["user_id"])
+	}
+	if _, ok := entry["duration_ms"].(float64); !ok {
+		t.Errorf("Expected numeric duration_ms, got %v", entry["duration_ms"])
+	}
+	if entry["level"] != "INFO" {
+		t.Errorf("Expected INFO level, got %v", entry["level"])
+	}
+}
+
+func TestRequestLogger_IgnoresSpoofedClientIP(t *testing.T) {
+	var buf bytes.Buffer
+	handler := RequestLogger(newTestRequestLogger(&buf))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/s1/page/p1/comments", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeLogLines(t, &buf)
+	if len(entries) != 1 || entries[0]["remote_addr"] != "203.0.113.7" {
+		t.Errorf("Expected the untrusted peer's address to be logged, got %v", entries)
+	}
+}
+
+func TestRequestLogger_RecoversPanic(t *testing.T) {
+	var buf bytes.Buffer
+	handler := RequestIDMiddleware(RequestLogger(newTestRequestLogger(&buf))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/s1/page/p1/comments", nil)
+	req.Header.Set("X-Request-ID", "req-panic")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", rr.Code)
+	}
+	var apiErr apierrors.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&apiErr); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if apiErr.Error.RequestID != "req-panic" {
+		t.Errorf("Expected request ID in error response, got %q", apiErr.Error.RequestID)
+	}
+
+	entries := decodeLogLines(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected panic and request log entries, got %d", len(entries))
+	}
+	panicEntry, requestEntry := entries[0], entries[1]
+	if panicEntry["panic"] != "boom" {
+		t.Errorf("Expected panic value to be logged, got %v", panicEntry["panic"])
+	}
+	if stack, _ := panicEntry["stack"].(string); !strings.Contains(stack, "request_logger_test.go") {
+		t.Error("Expected stack trace pointing at the panicking handler")
+	}
+	if requestEntry["status"] != float64(http.StatusInternalServerError) || requestEntry["level"] != "ERROR" {
+		t.Errorf("Expected request logged as 500 at ERROR, got %v at %v", requestEntry["status"], requestEntry["level"])
+	}
+	if requestEntry["request_id"] != "req-panic" {
+		t.Errorf("Expected request ID req-panic, got %v", requestEntry["request_id"])
+	}
+}
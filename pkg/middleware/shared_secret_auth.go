@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/auth"
+	"github.com/saasuke-labs/kotomi/pkg/logging"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// SharedSecretAuth authenticates requests to sites using external auth with a
+// shared HS256 secret (jwt_validation_type "secret"). A valid bearer token,
+// signed with the site's jwt_secret and matching its issuer and audience, puts
+// the user in the context under ContextKeyUser. Requests without a valid token
+// continue unauthenticated, so public routes still work and JWTAuthMiddleware
+// rejects them on protected ones. Site configs are read through configs, so
+// authenticating a request doesn't cost a config query.
+func SharedSecretAuth(db *sql.DB, configs *models.CachedSiteAuthConfigStore) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			siteID := mux.Vars(r)["siteId"]
+			token := auth.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+			if siteID == "" || token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			config, err := configs.GetBySiteID(r.Context(), siteID)
+			if err != nil {
+				log.Printf("Failed to load auth config for site %s: %v", siteID, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if config == nil || config.AuthMode != "external" || config.JWTValidationType != "secret" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			kotomiUser, err := auth.NewJWTValidator(config).ValidateToken(token)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			persistUser(r.Context(), db, siteID, kotomiUser)
+
+			ctx := context.WithValue(r.Context(), ContextKeyUser, kotomiUser)
+			logging.SetAuthenticatedUser(ctx, kotomiUser.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
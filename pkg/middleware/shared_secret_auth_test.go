@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+const sharedSecret = "shared-secret-key-min-32-characters-long"
+
+// newSharedSecretSite creates a site configured for external HS256 auth
+func newSharedSecretSite(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	store, err := comments.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	db := store.GetDB()
+	ctx := context.Background()
+
+	owner, err := models.NewAdminUserStore(db).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := models.NewSiteStore(db).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	if err := models.NewSiteAuthConfigStore(db).Create(ctx, &models.SiteAuthConfig{
+		SiteID:            site.ID,
+		AuthMode:          "external",
+		JWTValidationType: "secret",
+		JWTSecret:         sharedSecret,
+		JWTIssuer:         "https://blog.example.com",
+		JWTAudience:       "kotomi",
+	}); err != nil {
+		t.Fatalf("Failed to create auth config: %v", err)
+	}
+	return db, site.ID
+}
+
+// signSharedSecretToken signs an HS256 token for user-1 with the given secret and audience
+func signSharedSecretToken(t *testing.T, secret, audience string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":         "https://blog.example.com",
+		"sub":         "user-1",
+		"aud":         audience,
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"kotomi_user": map[string]interface{}{"name": "Alice", "email": "alice@example.com"},
+	})
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestSharedSecretAuth(t *testing.T) {
+	db, siteID := newSharedSecretSite(t)
+	configs := models.NewCachedSiteAuthConfigStore(db, time.Minute)
+
+	var gotUser *models.KotomiUser
+	router := mux.NewRouter()
+	router.Use(SharedSecretAuth(db, configs))
+	router.HandleFunc("/site/{siteId}/comments", func(w http.ResponseWriter, r *http.Request) {
+		gotUser = GetUserFromContext(r.Context())
+	})
+
+	request := func(token string) *models.KotomiUser {
+		gotUser = nil
+		req := httptest.NewRequest(http.MethodGet, "/site/"+siteID+"/comments", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return gotUser
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		user := request(signSharedSecretToken(t, sharedSecret, "kotomi"))
+		if user == nil || user.ID != "user-1" || user.Name != "Alice" {
+			t.Fatalf("Expected user-1 in the context, got %+v", user)
+		}
+		stored, err := models.NewUserStore(db).GetBySiteAndID(context.Background(), siteID, "user-1")
+		if err != nil || stored.Email != "alice@example.com" {
+			t.Errorf("Expected the user to be persisted, got %+v (%v)", stored, err)
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		if user := request(signSharedSecretToken(t, "another-secret-key-min-32-characters", "kotomi")); user != nil {
+			t.Errorf("Expected no user for a token signed with another secret, got %+v", user)
+		}
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		if user := request(signSharedSecretToken(t, sharedSecret, "someone-else")); user != nil {
+			t.Errorf("Expected no user for a token for another audience, got %+v", user)
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		if user := request(""); user != nil {
+			t.Errorf("Expected no user without a token, got %+v", user)
+		}
+	})
+}
+
+func TestSharedSecretAuth_ProtectedRouteRejectsInvalidToken(t *testing.T) {
+	db, siteID := newSharedSecretSite(t)
+	configs := models.NewCachedSiteAuthConfigStore(db, time.Minute)
+
+	router := mux.NewRouter()
+	router.Use(SharedSecretAuth(db, configs))
+	protected := router.PathPrefix("").Subrouter()
+	protected.Use(JWTAuthMiddleware(db))
+	protected.HandleFunc("/site/{siteId}/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for token, want := range map[string]int{
+		signSharedSecretToken(t, sharedSecret, "kotomi"):       http.StatusNoContent,
+		signSharedSecretToken(t, sharedSecret, "someone-else"): http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/site/"+siteID+"/comments", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("Expected %d, got %d: %s", want, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// corsMaxAge is how long browsers may cache a preflight response (12 hours)
+const corsMaxAge = 43200
+
+// CORSPolicy is the cross-origin policy of a single site
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or false
+// when the origin is not allowed. A wildcard only matches without credentials.
+func (p *CORSPolicy) allowOrigin(origin string) (string, bool) {
+	normalized, err := models.NormalizeOrigin(origin)
+	if err != nil || normalized == "*" {
+		return "", false
+	}
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" && !p.AllowCredentials {
+			return "*", true
+		}
+		if allowed == normalized {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// CORSPolicyLookup returns a site's CORS policy. A nil policy means the site has
+// none configured and the server-wide policy applies.
+type CORSPolicyLookup func(ctx context.Context, siteID string) (*CORSPolicy, error)
+
+// SiteCORS applies per-site CORS policies to routes with a {siteId} variable,
+// falling back to a server-wide policy for sites without allowed origins
+type SiteCORS struct {
+	lookup   CORSPolicyLookup
+	fallback *cors.Cors
+	methods  []string
+	headers  []string
+}
+
+// NewSiteCORS creates a per-site CORS middleware. Allowed methods and headers are
+// read from CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS like NewCORSMiddleware.
+func NewSiteCORS(lookup CORSPolicyLookup, fallback *cors.Cors) *SiteCORS {
+	return &SiteCORS{
+		lookup:   lookup,
+		fallback: fallback,
+		methods:  corsListFromEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS"),
+		headers:  corsListFromEnv("CORS_ALLOWED_HEADERS", "Content-Type,Authorization"),
+	}
+}
+
+// NewSiteCORSMiddleware creates a per-site CORS middleware reading policies from
+// site settings, with the environment-configured policy as fallback
+func NewSiteCORSMiddleware(db *sql.DB) *SiteCORS {
+	fallback := NewCORSMiddleware()
+	if db == nil {
+		return NewSiteCORS(nil, fallback)
+	}
+
+	store := models.NewSiteSettingsStore(db)
+	return NewSiteCORS(func(ctx context.Context, siteID string) (*CORSPolicy, error) {
+		settings, err := store.GetBySiteID(ctx, siteID)
+		if err != nil {
+			return nil, err
+		}
+		if len(settings.CORSAllowedOrigins) == 0 {
+			return nil, nil
+		}
+		return &CORSPolicy{
+			AllowedOrigins:   settings.CORSAllowedOrigins,
+			AllowCredentials: settings.CORSAllowCredentials,
+		}, nil
+	}, fallback)
+}
+
+// Handler wraps next with CORS handling. Preflight requests are answered here and
+// never reach next.
+func (c *SiteCORS) Handler(next http.Handler) http.Handler {
+	fallback := next
+	if c.fallback != nil {
+		fallback = c.fallback.Handler(next)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siteID := mux.Vars(r)["siteId"]
+		if c.lookup == nil || siteID == "" {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		policy, err := c.lookup(r.Context(), siteID)
+		if err != nil {
+			// Fail closed: without the site's policy no origin is allowed
+			log.Printf("Warning: Failed to load CORS policy for site %s: %v", siteID, err)
+			policy = &CORSPolicy{}
+		}
+		if policy == nil {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		c.serve(w, r, policy, next)
+	})
+}
+
+// serve applies a site policy to the request
+func (c *SiteCORS) serve(w http.ResponseWriter, r *http.Request, policy *CORSPolicy, next http.Handler) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	origin := r.Header.Get("Origin")
+	allowOrigin, allowed := "", false
+	if origin != "" {
+		allowOrigin, allowed = policy.allowOrigin(origin)
+	}
+
+	if !preflight {
+		if allowed {
+			header.Set("Access-Control-Allow-Origin", allowOrigin)
+			if policy.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// Disallowed preflights get no CORS headers, which makes the browser block the request
+	if allowed && c.allowsMethod(r.Header.Get("Access-Control-Request-Method")) {
+		header.Set("Access-Control-Allow-Origin", allowOrigin)
+		header.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+		header.Set("Access-Control-Allow-Headers", strings.Join(c.headers, ", "))
+		header.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+		if policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowsMethod reports whether method is in the allowed methods list
+func (c *SiteCORS) allowsMethod(method string) bool {
+	for _, allowed := range c.methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreflightHandler answers OPTIONS requests on routes that only register other
+// methods; the CORS middleware in front of it writes the actual response
+func PreflightHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// corsListFromEnv parses a comma-separated environment variable, using def when unset
+func corsListFromEnv(key, def string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		value = def
+	}
+
+	items := strings.Split(value, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
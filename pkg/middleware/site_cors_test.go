@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+)
+
+// newSiteCORSRouter mirrors the API routes: a GET/POST route plus an OPTIONS
+// catch-all so preflights reach the CORS middleware
+func newSiteCORSRouter(policies map[string]*CORSPolicy) *mux.Router {
+	lookup := func(ctx context.Context, siteID string) (*CORSPolicy, error) {
+		if siteID == "broken" {
+			return nil, errors.New("database unavailable")
+		}
+		return policies[siteID], nil
+	}
+	c := NewSiteCORS(lookup, cors.New(cors.Options{
+		AllowedOrigins: []string{"https://fallback.example"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type"},
+	}))
+
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(c.Handler)
+	api.PathPrefix("/site/{siteId}/").Methods("OPTIONS").HandlerFunc(PreflightHandler)
+	api.HandleFunc("/site/{siteId}/page/{pageId}/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET", "POST")
+	return router
+}
+
+func corsRequest(router http.Handler, method, siteID, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1/site/"+siteID+"/page/p1/comments", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "content-type") // browsers send lowercase names
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSiteCORS_AllowedOrigin(t *testing.T) {
+	router := newSiteCORSRouter(map[string]*CORSPolicy{
+		"site1": {AllowedOrigins: []string{"https://blog.example.com"}, AllowCredentials: true},
+	})
+
+	rr := corsRequest(router, http.MethodGet, "site1", "https://Blog.example.com")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://Blog.example.com" {
+		t.Errorf("Expected origin to be echoed back, got %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected credentials header, got %q", got)
+	}
+	if got := rr.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %q", got)
+	}
+}
+
+func TestSiteCORS_DisallowedOrigin(t *testing.T) {
+	router := newSiteCORSRouter(map[string]*CORSPolicy{
+		"site1": {AllowedOrigins: []string{"https://blog.example.com"}},
+	})
+
+	for _, origin := range []string{"https://evil.example.com", "https://blog.example.com.evil.com", "null"} {
+		rr := corsRequest(router, http.MethodGet, "site1", origin)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected request from %s to still be served, got %d", origin, rr.Code)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no Allow-Origin for %s, got %q", origin, got)
+		}
+	}
+
+	// A site whose policy can't be loaded allows no origins
+	rr := corsRequest(router, http.MethodGet, "broken", "https://blog.example.com")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin when the policy lookup fails, got %q", got)
+	}
+}
+
+func TestSiteCORS_Preflight(t *testing.T) {
+	router := newSiteCORSRouter(map[string]*CORSPolicy{
+		"site1": {AllowedOrigins: []string{"https://blog.example.com"}, AllowCredentials: true},
+	})
+
+	rr := corsRequest(router, http.MethodOptions, "site1", "https://blog.example.com")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 for preflight, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://blog.example.com" {
+		t.Errorf("Expected allowed origin, got %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, DELETE, OPTIONS" {
+		t.Errorf("Unexpected Allow-Methods: %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+		t.Errorf("Unexpected Allow-Headers: %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected credentials header, got %q", got)
+	}
+
+	rr = corsRequest(router, http.MethodOptions, "site1", "https://evil.example.com")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 for disallowed preflight, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin for disallowed preflight, got %q", got)
+	}
+}
+
+func TestSiteCORS_Wildcard(t *testing.T) {
+	router := newSiteCORSRouter(map[string]*CORSPolicy{
+		"open":        {AllowedOrigins: []string{"*"}},
+		"credentials": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+	})
+
+	rr := corsRequest(router, http.MethodGet, "open", "https://anywhere.example")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected wildcard origin, got %q", got)
+	}
+
+	// Wildcards never apply to credentialed policies
+	rr = corsRequest(router, http.MethodGet, "credentials", "https://anywhere.example")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin for wildcard with credentials, got %q", got)
+	}
+}
+
+func TestSiteCORS_FallbackPolicy(t *testing.T) {
+	router := newSiteCORSRouter(map[string]*CORSPolicy{})
+
+	rr := corsRequest(router, http.MethodGet, "unconfigured", "https://fallback.example")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://fallback.example" {
+		t.Errorf("Expected fallback policy to allow origin, got %q", got)
+	}
+
+	rr = corsRequest(router, http.MethodOptions, "unconfigured", "https://fallback.example")
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected fallback preflight to succeed, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://fallback.example" {
+		t.Errorf("Expected fallback preflight Allow-Origin, got %q", got)
+	}
+}
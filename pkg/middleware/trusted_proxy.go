@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultTrustedProxies are the ranges whose forwarding headers are honored
+// when TRUSTED_PROXIES is unset: loopback, link-local and private networks,
+// where reverse proxies and load balancers normally connect from
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1/128",
+	"169.254.0.0/16",
+	"fe80::/10",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+}
+
+// TrustedProxies decides which peers may report the client address through
+// X-Forwarded-For and X-Real-IP
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseTrustedProxies parses CIDR ranges; a bare IP trusts that single address.
+// Invalid entries are skipped and reported together in the returned error.
+func ParseTrustedProxies(cidrs []string) (*TrustedProxies, error) {
+	tp := &TrustedProxies{}
+	var errs []error
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		prefix, err := parseTrustedProxy(cidr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err))
+			continue
+		}
+		tp.prefixes = append(tp.prefixes, prefix)
+	}
+	return tp, errors.Join(errs...)
+}
+
+// parseTrustedProxy parses one CIDR range or bare IP
+func parseTrustedProxy(cidr string) (netip.Prefix, error) {
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+// TrustedProxiesFromEnv returns the ranges in TRUSTED_PROXIES (comma-separated
+// CIDRs or IPs), or DefaultTrustedProxies when it is unset. Setting it to an
+// empty value trusts no proxy. Invalid entries are logged and ignored.
+func TrustedProxiesFromEnv() *TrustedProxies {
+	value, ok := os.LookupEnv("TRUSTED_PROXIES")
+	if !ok {
+		tp, _ := ParseTrustedProxies(DefaultTrustedProxies)
+		return tp
+	}
+
+	tp, err := ParseTrustedProxies(strings.Split(value, ","))
+	if err != nil {
+		log.Printf("Ignoring invalid TRUSTED_PROXIES entries: %v", err)
+	}
+	return tp
+}
+
+// Trusts reports whether addr is inside a trusted range
+func (tp *TrustedProxies) Trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range tp.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client behind r. Forwarding headers are
+// only read when the direct peer is a trusted proxy; X-Forwarded-For is then
+// walked from the right, skipping trusted hops, so the first untrusted address
+// is the client. Entries left of it were supplied by the client and ignored.
+func (tp *TrustedProxies) ClientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !tp.Trusts(addr) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		client := addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// A trusted proxy never writes this, so the client did
+				break
+			}
+			client = hop.Unmap()
+			if !tp.Trusts(client) {
+				break
+			}
+		}
+		return client.String()
+	}
+
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+	return addr.Unmap().String()
+}
+
+// trustedProxies is the process-wide configuration, read from the environment once
+var trustedProxies = sync.OnceValue(TrustedProxiesFromEnv)
+
+// ClientIP returns the address of the client behind r, honoring forwarding
+// headers only from the proxies configured in TRUSTED_PROXIES
+func ClientIP(r *http.Request) string {
+	return trustedProxies().ClientIP(r)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func mustTrustedProxies(t *testing.T, cidrs ...string) *TrustedProxies {
+	t.Helper()
+	tp, err := ParseTrustedProxies(cidrs)
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+	return tp
+}
+
+func TestTrustedProxies_IgnoresSpoofedHeadersFromUntrustedPeer(t *testing.T) {
+	tp := mustTrustedProxies(t, "10.0.0.0/8")
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("X-Real-IP", "198.51.100.2")
+
+	if got := tp.ClientIP(req); got != "203.0.113.7" {
+		t.Errorf("Expected the peer address 203.0.113.7, got %s", got)
+	}
+}
+
+func TestTrustedProxies_HonorsTrustedProxy(t *testing.T) {
+	tp := mustTrustedProxies(t, "10.0.0.0/8", "192.168.1.10")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"single hop", "10.0.0.5:443", "198.51.100.1", "", "198.51.100.1"},
+		{"right-most untrusted hop", "10.0.0.5:443", "203.0.113.9, 198.51.100.1, 192.168.1.10", "", "198.51.100.1"},
+		{"all hops trusted", "10.0.0.5:443", "10.1.1.1, 10.2.2.2", "", "10.1.1.1"},
+		{"garbage left by client", "10.0.0.5:443", "not-an-ip, 10.2.2.2", "", "10.2.2.2"},
+		{"X-Real-IP", "192.168.1.10:443", "", "198.51.100.3", "198.51.100.3"},
+		{"no headers", "10.0.0.5:443", "", "", "10.0.0.5"},
+		{"untrusted IPv6 peer", "[::1]:443", "198.51.100.4", "", "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := tp.ClientIP(req); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTrustedProxiesFromEnv(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	req.RemoteAddr = "127.0.0.1:443"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	if got := TrustedProxiesFromEnv().ClientIP(req); got != "198.51.100.1" {
+		t.Errorf("Expected loopback to be trusted by default, got %s", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "")
+	if got := TrustedProxiesFromEnv().ClientIP(req); got != "127.0.0.1" {
+		t.Errorf("Expected no proxy to be trusted, got %s", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "bogus, 127.0.0.1/32")
+	if got := TrustedProxiesFromEnv().ClientIP(req); got != "198.51.100.1" {
+		t.Errorf("Expected the valid entry to be kept, got %s", got)
+	}
+
+	tp, err := ParseTrustedProxies([]string{"10.0.0.0/33", "127.0.0.1"})
+	if err == nil {
+		t.Error("Expected an error for an invalid CIDR")
+	}
+	if got := tp.ClientIP(req); got != "198.51.100.1" {
+		t.Errorf("Expected the valid entry to be parsed despite the error, got %s", got)
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	apierrors "github.com/saasuke-labs/kotomi/pkg/errors"
+)
+
+// KeyedRateLimiter limits requests per authenticated user, falling back to client IP.
+// Unlike RateLimiter it is applied to individual routes, each with its own limit and window.
+type KeyedRateLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*keyedBucket
+	limit       int           // requests allowed per window
+	window      time.Duration // window over which limit tokens are refilled
+	idleTimeout time.Duration // buckets unused for this long are evicted
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// keyedBucket pairs a token bucket with the last time it was used
+type keyedBucket struct {
+	bucket   *tokenBucket
+	lastSeen time.Time
+}
+
+// NewKeyedRateLimiter creates a limiter allowing limit requests per window for each key
+func NewKeyedRateLimiter(limit int, window time.Duration) *KeyedRateLimiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	rl := &KeyedRateLimiter{
+		buckets:     make(map[string]*keyedBucket),
+		limit:       limit,
+		window:      window,
+		idleTimeout: 2 * window,
+		stop:        make(chan struct{}),
+	}
+
+	// Start sweeper to evict stale buckets
+	go rl.sweep(window)
+
+	return rl
+}
+
+// NewKeyedRateLimiterFromEnv creates a limiter configured from <prefix>_LIMIT and
+// <prefix>_WINDOW (a Go duration such as "1m"), falling back to the given defaults
+func NewKeyedRateLimiterFromEnv(prefix string, defaultLimit int, defaultWindow time.Duration) *KeyedRateLimiter {
+	limit := getEnvInt(prefix+"_LIMIT", defaultLimit)
+	window := defaultWindow
+	if value := os.Getenv(prefix + "_WINDOW"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			window = d
+		}
+	}
+	return NewKeyedRateLimiter(limit, window)
+}
+
+// Handler returns middleware that enforces the limit
+func (rl *KeyedRateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket := rl.getBucket(rateLimitKey(r))
+
+		allowed := bucket.allow()
+		remaining := int(bucket.getTokens())
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if !allowed {
+			retryAfter := bucket.getRetryAfter()
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			apierrors.WriteErrorWithRequestID(w, apierrors.RateLimitExceeded(
+				fmt.Sprintf("Rate limit exceeded. Please try again in %d seconds.", retryAfter)), GetRequestID(r))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wrap applies the limiter to a single handler function
+func (rl *KeyedRateLimiter) Wrap(next http.HandlerFunc) http.Handler {
+	return rl.Handler(next)
+}
+
+// Stop terminates the background sweeper
+func (rl *KeyedRateLimiter) Stop() {
+	rl.stopOnce.Do(func() {
+		close(rl.stop)
+	})
+}
+
+// rateLimitKey identifies the caller by authenticated user ID, or by client IP otherwise
+func rateLimitKey(r *http.Request) string {
+	if user := GetUserFromContext(r.Context()); user != nil && user.ID != "" {
+		return "user:" + user.ID
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// getBucket returns an existing bucket for key or creates a new one
+func (rl *KeyedRateLimiter) getBucket(key string) *tokenBucket {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, exists := rl.buckets[key]
+	if !exists {
+		b = &keyedBucket{
+			bucket: newTokenBucket(float64(rl.limit), float64(rl.limit)/rl.window.Seconds()),
+		}
+		rl.buckets[key] = b
+	}
+	b.lastSeen = time.Now()
+
+	return b.bucket
+}
+
+// sweep periodically removes buckets that have not been used recently
+func (rl *KeyedRateLimiter) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+			rl.evictStale(time.Now())
+		}
+	}
+}
+
+// evictStale removes buckets idle since before now minus the idle timeout
+func (rl *KeyedRateLimiter) evictStale(now time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.lastSeen) > rl.idleTimeout {
+			delete(rl.buckets, key)
+		}
+	}
+}
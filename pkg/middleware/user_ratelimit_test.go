@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+func newKeyedTestHandler(rl *KeyedRateLimiter) http.Handler {
+	return rl.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func withUser(r *http.Request, userID string) *http.Request {
+	ctx := context.WithValue(r.Context(), ContextKeyUser, &models.KotomiUser{ID: userID})
+	return r.WithContext(ctx)
+}
+
+func TestKeyedRateLimiter_LimitsByUser(t *testing.T) {
+	rl := NewKeyedRateLimiter(2, time.Minute)
+	defer rl.Stop()
+	handler := newKeyedTestHandler(rl)
+
+	for i := 0; i < 2; i++ {
+		req := withUser(httptest.NewRequest("POST", "/comments", nil), "user-1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d", i+1, w.Code)
+		}
+	}
+
+	// Same user from a different IP is still limited
+	req := withUser(httptest.NewRequest("POST", "/comments", nil), "user-1")
+	req.RemoteAddr = "10.0.0.99:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+
+	// A different user has their own bucket
+	req = withUser(httptest.NewRequest("POST", "/comments", nil), "user-2")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for different user, got %d", w.Code)
+	}
+}
+
+func TestKeyedRateLimiter_FallsBackToIP(t *testing.T) {
+	rl := NewKeyedRateLimiter(1, time.Minute)
+	defer rl.Stop()
+	handler := newKeyedTestHandler(rl)
+
+	req := httptest.NewRequest("POST", "/comments", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.5, 10.0.0.1")
+	req.RemoteAddr = "10.0.0.1:1234" // Trusted proxy
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/comments", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	req.RemoteAddr = "10.0.0.1:1234" // Trusted proxy
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for same IP, got %d", w.Code)
+	}
+}
+
+func TestKeyedRateLimiter_EvictsStaleBuckets(t *testing.T) {
+	rl := NewKeyedRateLimiter(1, time.Minute)
+	defer rl.Stop()
+
+	rl.getBucket("user:stale")
+	rl.getBucket("user:fresh")
+	rl.buckets["user:stale"].lastSeen = time.Now().Add(-time.Hour)
+
+	rl.evictStale(time.Now())
+
+	if _, ok := rl.buckets["user:stale"]; ok {
+		t.Error("Expected stale bucket to be evicted")
+	}
+	if _, ok := rl.buckets["user:fresh"]; !ok {
+		t.Error("Expected fresh bucket to be kept")
+	}
+}
+
+func TestNewKeyedRateLimiterFromEnv(t *testing.T) {
+	t.Setenv("TEST_LIMITER_LIMIT", "7")
+	t.Setenv("TEST_LIMITER_WINDOW", "30s")
+
+	rl := NewKeyedRateLimiterFromEnv("TEST_LIMITER", 1, time.Minute)
+	defer rl.Stop()
+
+	if rl.limit != 7 {
+		t.Errorf("Expected limit 7, got %d", rl.limit)
+	}
+	if rl.window != 30*time.Second {
+		t.Errorf("Expected window 30s, got %v", rl.window)
+	}
+}
@@ -0,0 +1,175 @@
+// Package migrate applies numbered schema migrations and records them in a
+// schema_migrations table, so each step runs once per database.
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version     int
+	Description string
+	// Up applies the change inside a transaction. It must succeed against a
+	// database that already has the change, since databases created before the
+	// runner existed have no record of what they contain.
+	Up func(tx *sql.Tx) error
+}
+
+// driverMigrations holds what the runner needs to know about one driver
+type driverMigrations struct {
+	migrations []Migration
+	// placeholder returns the n-th (1-based) bind parameter
+	placeholder func(n int) string
+}
+
+func questionMark(int) string { return "?" }
+
+// drivers maps database/sql driver names to their migrations
+var drivers = map[string]driverMigrations{
+	"sqlite3": {migrations: sqliteMigrations, placeholder: questionMark},
+	"sqlite":  {migrations: sqliteMigrations, placeholder: questionMark},
+}
+
+// Migrate applies every migration for driver that db hasn't recorded yet, in
+// version order. Each migration runs and is recorded in its own transaction.
+func Migrate(db *sql.DB, driver string) error {
+	d, ok := drivers[driver]
+	if !ok {
+		return fmt.Errorf("no migrations defined for driver %q", driver)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	migrations := append([]Migration(nil), d.migrations...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	record := fmt.Sprintf("INSERT INTO schema_migrations (version, description) VALUES (%s, %s)", d.placeholder(1), d.placeholder(2))
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := apply(db, m, record); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+	}
+	return nil
+}
+
+// apply runs one migration and records it
+func apply(db *sql.DB, m Migration, record string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, m.Version, m.Description); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// appliedVersions returns the versions recorded in schema_migrations
+func appliedVersions(db *sql.DB) (map[int]bool, error) {
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// Version returns the highest migration version applied to db, or 0 if none
+func Version(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// Latest returns the newest migration version defined for driver
+func Latest(driver string) int {
+	latest := 0
+	for _, m := range drivers[driver].migrations {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// Exec returns a migration step that runs the given statements
+func Exec(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// AddColumn returns a migration step that adds a column unless the table
+// already has it. The table's columns are read from an empty SELECT, which
+// works the same on every driver.
+func AddColumn(table, column, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT * FROM " + table + " LIMIT 0")
+		if err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		columns, err := rows.Columns()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		for _, existing := range columns {
+			if strings.EqualFold(existing, column) {
+				return nil
+			}
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+		return err
+	}
+}
+
+// Steps returns a migration step that runs each step in order
+func Steps(steps ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, step := range steps {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,205 @@
+package migrate
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// schemaSnapshot returns the SQL of every table and index, keyed by name
+func schemaSnapshot(t *testing.T, db *sql.DB) map[string]string {
+	t.Helper()
+	rows, err := db.Query("SELECT name, COALESCE(sql, '') FROM sqlite_master")
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	defer rows.Close()
+	snapshot := map[string]string{}
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			t.Fatalf("Failed to scan schema: %v", err)
+		}
+		snapshot[name] = definition
+	}
+	return snapshot
+}
+
+func countMigrations(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&n); err != nil {
+		t.Fatalf("Failed to count migrations: %v", err)
+	}
+	return n
+}
+
+func TestMigrate_FreshDatabaseReachesLatest(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := Migrate(db, "sqlite3"); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	version, err := Version(db)
+	if err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	if latest := Latest("sqlite3"); version != latest || latest == 0 {
+		t.Errorf("Expected version %d, got %d", latest, version)
+	}
+	if n := countMigrations(t, db); n != len(sqliteMigrations) {
+		t.Errorf("Expected %d recorded migrations, got %d", len(sqliteMigrations), n)
+	}
+
+	var score int
+	if err := db.QueryRow("SELECT COALESCE(MAX(reputation_score), 0) FROM users").Scan(&score); err != nil {
+		t.Errorf("Expected users.reputation_score to exist: %v", err)
+	}
+}
+
+func TestMigrate_SecondRunIsNoOp(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := Migrate(db, "sqlite3"); err != nil {
+		t.Fatalf("First Migrate failed: %v", err)
+	}
+	before := schemaSnapshot(t, db)
+	recorded := countMigrations(t, db)
+
+	if err := Migrate(db, "sqlite3"); err != nil {
+		t.Fatalf("Second Migrate failed: %v", err)
+	}
+
+	if n := countMigrations(t, db); n != recorded {
+		t.Errorf("Expected %d recorded migrations after second run, got %d", recorded, n)
+	}
+	after := schemaSnapshot(t, db)
+	if len(after) != len(before) {
+		t.Errorf("Expected %d schema objects, got %d", len(before), len(after))
+	}
+	for name, definition := range before {
+		if after[name] != definition {
+			t.Errorf("Schema of %s changed on second run:\nbefore: %s\nafter:  %s", name, definition, after[name])
+		}
+	}
+}
+
+func TestMigrate_UpgradesUntrackedDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	// A database created before reputation existed and before the runner tracked anything
+	if _, err := db.Exec(`CREATE TABLE users (
+		id TEXT NOT NULL,
+		site_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		email TEXT,
+		avatar_url TEXT,
+		profile_url TEXT,
+		is_verified INTEGER DEFAULT 0,
+		roles TEXT,
+		first_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (site_id, id)
+	)`); err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, site_id, name) VALUES ('u1', 's1', 'Alice')`); err != nil {
+		t.Fatalf("Failed to insert legacy user: %v", err)
+	}
+
+	if err := Migrate(db, "sqlite3"); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	var score int
+	if err := db.QueryRow("SELECT reputation_score FROM users WHERE id = 'u1'").Scan(&score); err != nil {
+		t.Fatalf("Expected reputation_score to be added: %v", err)
+	}
+	if score != 0 {
+		t.Errorf("Expected default reputation 0, got %d", score)
+	}
+}
+
+func TestMigrate_UnknownDriver(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrate(db, "mysql"); err == nil {
+		t.Error("Expected an error for a driver without migrations")
+	}
+}
+
+func TestMigrate_DeduplicatesReactions(t *testing.T) {
+	db := openTestDB(t)
+
+	// A database migrated up to just before the unique reaction indexes
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Failed to create schema_migrations: %v", err)
+	}
+	for _, m := range sqliteMigrations {
+		if m.Version >= 24 {
+			continue
+		}
+		if err := apply(db, m, "INSERT INTO schema_migrations (version, description) VALUES (?, ?)"); err != nil {
+			t.Fatalf("Migration %d failed: %v", m.Version, err)
+		}
+	}
+
+	// Duplicates left by concurrent toggles, plus a distinct reaction
+	for _, stmt := range []string{
+		`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES ('r1', 'c1', 'heart', 'u1')`,
+		`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES ('r2', 'c1', 'heart', 'u1')`,
+		`INSERT INTO reactions (id, page_id, allowed_reaction_id, user_id) VALUES ('r3', 'p1', 'heart', 'u1')`,
+		`INSERT INTO reactions (id, page_id, allowed_reaction_id, user_id) VALUES ('r4', 'p1', 'heart', 'u1')`,
+		`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES ('r5', 'c1', 'heart', 'u2')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to insert reaction: %v", err)
+		}
+	}
+
+	if err := Migrate(db, "sqlite3"); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	rows, err := db.Query("SELECT id FROM reactions ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query reactions: %v", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Failed to scan reaction: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 3 || ids[0] != "r1" || ids[1] != "r3" || ids[2] != "r5" {
+		t.Errorf("Expected the first of each duplicate to be kept, got %v", ids)
+	}
+
+	if _, err := db.Exec(`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES ('r6', 'c1', 'heart', 'u1')`); err == nil {
+		t.Error("Expected a duplicate comment reaction to be rejected")
+	}
+	if _, err := db.Exec(`INSERT INTO reactions (id, page_id, allowed_reaction_id, user_id) VALUES ('r7', 'p1', 'heart', 'u1')`); err == nil {
+		t.Error("Expected a duplicate page reaction to be rejected")
+	}
+}
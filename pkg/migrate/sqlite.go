@@ -0,0 +1,706 @@
+package migrate
+
+// sqliteMigrations are the schema steps for SQLite databases. The initial
+// schema creates every table with its current columns, so on a fresh database
+// the column steps that follow find nothing to do; they exist for databases
+// created before each column was added.
+var sqliteMigrations = []Migration{
+	{Version: 1, Description: "initial schema", Up: Exec(sqliteInitialSchema)},
+	// Phase 3: user reputation
+	{Version: 2, Description: "add users.reputation_score", Up: AddColumn("users", "reputation_score", "INTEGER DEFAULT 0")},
+	// Per-site cap on reactions a user may leave on a single comment or page (0 = unlimited)
+	{Version: 3, Description: "add site_settings.max_reactions_per_target", Up: AddColumn("site_settings", "max_reactions_per_target", "INTEGER NOT NULL DEFAULT 0")},
+	// New comment notifications can be sent immediately or batched into a daily digest
+	{Version: 4, Description: "add notification digest columns", Up: Steps(
+		AddColumn("notification_settings", "notify_new_comment_mode", "TEXT DEFAULT 'immediate'"),
+		AddColumn("notification_settings", "last_digest_sent_at", "TIMESTAMP"),
+	)},
+	// Status given to comments that contain a blocked word
+	{Version: 5, Description: "add moderation_config.blocked_word_action", Up: AddColumn("moderation_config", "blocked_word_action", "TEXT DEFAULT 'rejected'")},
+	// Per-site Akismet API key for spam checks
+	{Version: 6, Description: "add moderation_config.akismet_api_key", Up: AddColumn("moderation_config", "akismet_api_key", "TEXT")},
+	// Authors above this reputation score skip AI moderation (default effectively off)
+	{Version: 7, Description: "add moderation_config.trusted_reputation_threshold", Up: AddColumn("moderation_config", "trusted_reputation_threshold", "INTEGER DEFAULT 1000000")},
+	// Per-site CORS policy for the embeddable widget
+	{Version: 8, Description: "add site_settings CORS columns", Up: Steps(
+		AddColumn("site_settings", "cors_allowed_origins", "TEXT NOT NULL DEFAULT ''"),
+		AddColumn("site_settings", "cors_allow_credentials", "INTEGER NOT NULL DEFAULT 0"),
+	)},
+	// Number of user reports that sends a comment back to the moderation queue (0 = never)
+	{Version: 9, Description: "add site_settings.report_threshold", Up: AddColumn("site_settings", "report_threshold", "INTEGER NOT NULL DEFAULT 3")},
+	// Minimum seconds between two comments by the same author on a site
+	{Version: 10, Description: "add site_settings.comment_cooldown_seconds", Up: AddColumn("site_settings", "comment_cooldown_seconds", "INTEGER NOT NULL DEFAULT 15")},
+	// Cap on the reaction types a site offers per target kind (0 = unlimited)
+	{Version: 11, Description: "add site_settings.max_allowed_reactions_per_site", Up: AddColumn("site_settings", "max_allowed_reactions_per_site", "INTEGER NOT NULL DEFAULT 0")},
+	// Link-count spam rule checked before AI moderation
+	{Version: 12, Description: "add moderation_config link rule columns", Up: Steps(
+		AddColumn("moderation_config", "max_links", "INTEGER DEFAULT 3"),
+		AddColumn("moderation_config", "link_action", "TEXT DEFAULT 'pending'"),
+	)},
+	// How comment text is interpreted: plain, markdown or limited-html
+	{Version: 13, Description: "add site_settings.content_policy", Up: AddColumn("site_settings", "content_policy", "TEXT NOT NULL DEFAULT 'markdown'")},
+	// Detected language and AI sentiment bucket of each comment
+	{Version: 14, Description: "add comments language and sentiment", Up: Steps(
+		AddColumn("comments", "language", "TEXT"),
+		AddColumn("comments", "sentiment", "TEXT"),
+		Exec(`CREATE INDEX IF NOT EXISTS idx_comments_site_language ON comments(site_id, language)`),
+	)},
+	// Gravatar fallback for authors without an avatar
+	{Version: 15, Description: "add site_settings gravatar columns", Up: Steps(
+		AddColumn("site_settings", "gravatar_enabled", "INTEGER NOT NULL DEFAULT 1"),
+		AddColumn("site_settings", "gravatar_style", "TEXT NOT NULL DEFAULT 'identicon'"),
+	)},
+	// Site-scoped keys for server-to-server API calls
+	{Version: 16, Description: "add api_keys", Up: Exec(`
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		prefix TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_site ON api_keys(site_id);
+	`)},
+	// Images attached to comments
+	{Version: 17, Description: "add attachments", Up: Exec(`
+	CREATE TABLE IF NOT EXISTS attachments (
+		id TEXT PRIMARY KEY,
+		comment_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_attachments_comment ON attachments(comment_id);
+	`)},
+	// Per-site overrides of notification email subjects and bodies
+	{Version: 18, Description: "add notification_templates", Up: Exec(`
+	CREATE TABLE IF NOT EXISTS notification_templates (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		subject_template TEXT NOT NULL,
+		body_template TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(site_id, type),
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+	`)},
+	// Language of notification subjects and moderation reasons
+	{Version: 19, Description: "add site_settings.locale", Up: AddColumn("site_settings", "locale", "TEXT NOT NULL DEFAULT 'en'")},
+	// Outbound webhooks and a log of their delivery attempts
+	{Version: 20, Description: "add event_subscriptions and event_deliveries", Up: Exec(`
+	CREATE TABLE IF NOT EXISTS event_subscriptions (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_event_subscriptions_site ON event_subscriptions(site_id);
+
+	CREATE TABLE IF NOT EXISTS event_deliveries (
+		id TEXT PRIMARY KEY,
+		subscription_id TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		attempt INTEGER NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (subscription_id) REFERENCES event_subscriptions(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_event_deliveries_subscription ON event_deliveries(subscription_id, created_at);
+	`)},
+	// Status given to new comments that moderation leaves undecided
+	{Version: 21, Description: "add site_settings.default_comment_status", Up: AddColumn("site_settings", "default_comment_status", "TEXT NOT NULL DEFAULT 'pending'")},
+	// Per-site lifetimes of Kotomi auth session tokens, in seconds
+	{Version: 22, Description: "add site_auth_configs token lifetime columns", Up: Steps(
+		AddColumn("site_auth_configs", "access_token_lifetime", "INTEGER NOT NULL DEFAULT 3600"),
+		AddColumn("site_auth_configs", "refresh_token_lifetime", "INTEGER NOT NULL DEFAULT 2592000"),
+	)},
+	// Admin actions on sites, comments and allowed reactions. There is no
+	// foreign key to sites so the trail outlives a deleted site.
+	{Version: 23, Description: "add audit_log", Up: Exec(`
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		actor_id TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		metadata TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_site ON audit_log(site_id, created_at);
+	`)},
+	// The reactions UNIQUE constraint never matches because one of page_id and
+	// comment_id is always NULL, so concurrent toggles could both insert. Keep
+	// the first of any duplicates and enforce uniqueness per target kind. The
+	// indexes aren't in the initial schema, which also runs on untracked
+	// databases that may hold duplicates.
+	{Version: 24, Description: "add unique reaction indexes", Up: Exec(`
+	DELETE FROM reactions WHERE comment_id IS NOT NULL AND rowid NOT IN (
+		SELECT MIN(rowid) FROM reactions WHERE comment_id IS NOT NULL GROUP BY comment_id, allowed_reaction_id, user_id
+	);
+	DELETE FROM reactions WHERE page_id IS NOT NULL AND rowid NOT IN (
+		SELECT MIN(rowid) FROM reactions WHERE page_id IS NOT NULL GROUP BY page_id, allowed_reaction_id, user_id
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_unique_comment ON reactions(comment_id, allowed_reaction_id, user_id) WHERE comment_id IS NOT NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_unique_page ON reactions(page_id, allowed_reaction_id, user_id) WHERE page_id IS NOT NULL;
+	`)},
+	// Per-site daily comment and reaction counts for analytics. Triggers keep
+	// them current on every write; days before a site's first rebuild stay
+	// partial until analytics.Store.RebuildAggregates backfills them and
+	// records the rebuilt days in daily_stats_coverage. Partial days are
+	// counted live by site and time; page listings get the creation time in
+	// their index too, or the planner would list a page's comments by scanning
+	// the whole site in time order.
+	{Version: 25, Description: "add daily analytics aggregates", Up: Exec(sqliteDailyStatsSchema)},
+	// Users following new comments on a page. Unsubscribing keeps the row with
+	// unsubscribed_at set, so commenting again doesn't resubscribe implicitly.
+	{Version: 26, Description: "add page_subscriptions", Up: Steps(
+		Exec(`
+	CREATE TABLE IF NOT EXISTS page_subscriptions (
+		site_id TEXT NOT NULL,
+		page_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		unsubscribed_at TIMESTAMP,
+		PRIMARY KEY (site_id, page_id, user_id),
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+		FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE
+	);
+	`),
+		AddColumn("site_settings", "auto_subscribe", "INTEGER NOT NULL DEFAULT 1"),
+	)},
+}
+
+// sqliteDailyStatsSchema creates the daily aggregate tables and the triggers
+// that maintain them. Days are UTC, as SQLite's DATE() returns them.
+const sqliteDailyStatsSchema = `
+	CREATE TABLE IF NOT EXISTS daily_comment_stats (
+		site_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		total INTEGER NOT NULL DEFAULT 0,
+		pending INTEGER NOT NULL DEFAULT 0,
+		approved INTEGER NOT NULL DEFAULT 0,
+		rejected INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS daily_reaction_stats (
+		site_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		allowed_reaction_id TEXT NOT NULL,
+		total INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (site_id, day, allowed_reaction_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_daily_reaction_stats_allowed ON daily_reaction_stats(allowed_reaction_id, day);
+
+	CREATE TABLE IF NOT EXISTS daily_stats_coverage (
+		site_id TEXT PRIMARY KEY,
+		from_day TEXT NOT NULL,
+		to_day TEXT,
+		rebuilt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_comments_site_created ON comments(site_id, created_at);
+	DROP INDEX IF EXISTS idx_site_page;
+	CREATE INDEX IF NOT EXISTS idx_site_page ON comments(site_id, page_id, created_at);
+	DROP INDEX IF EXISTS idx_reactions_allowed;
+	CREATE INDEX IF NOT EXISTS idx_reactions_allowed ON reactions(allowed_reaction_id, created_at);
+
+	CREATE TRIGGER IF NOT EXISTS daily_comment_stats_insert AFTER INSERT ON comments
+	BEGIN
+		INSERT INTO daily_comment_stats (site_id, day, total, pending, approved, rejected)
+		SELECT NEW.site_id, DATE(NEW.created_at), 1,
+			NEW.status IS 'pending', NEW.status IS 'approved', NEW.status IS 'rejected'
+		WHERE DATE(NEW.created_at) IS NOT NULL
+		ON CONFLICT (site_id, day) DO UPDATE SET
+			total = total + 1,
+			pending = pending + excluded.pending,
+			approved = approved + excluded.approved,
+			rejected = rejected + excluded.rejected;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_comment_stats_delete AFTER DELETE ON comments
+	BEGIN
+		UPDATE daily_comment_stats SET
+			total = total - 1,
+			pending = pending - (OLD.status IS 'pending'),
+			approved = approved - (OLD.status IS 'approved'),
+			rejected = rejected - (OLD.status IS 'rejected')
+		WHERE site_id = OLD.site_id AND day = DATE(OLD.created_at);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_comment_stats_update AFTER UPDATE OF site_id, status, created_at ON comments
+	WHEN OLD.site_id IS NOT NEW.site_id OR OLD.status IS NOT NEW.status OR DATE(OLD.created_at) IS NOT DATE(NEW.created_at)
+	BEGIN
+		UPDATE daily_comment_stats SET
+			total = total - 1,
+			pending = pending - (OLD.status IS 'pending'),
+			approved = approved - (OLD.status IS 'approved'),
+			rejected = rejected - (OLD.status IS 'rejected')
+		WHERE site_id = OLD.site_id AND day = DATE(OLD.created_at);
+
+		INSERT INTO daily_comment_stats (site_id, day, total, pending, approved, rejected)
+		SELECT NEW.site_id, DATE(NEW.created_at), 1,
+			NEW.status IS 'pending', NEW.status IS 'approved', NEW.status IS 'rejected'
+		WHERE DATE(NEW.created_at) IS NOT NULL
+		ON CONFLICT (site_id, day) DO UPDATE SET
+			total = total + 1,
+			pending = pending + excluded.pending,
+			approved = approved + excluded.approved,
+			rejected = rejected + excluded.rejected;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_reaction_stats_insert AFTER INSERT ON reactions
+	BEGIN
+		INSERT INTO daily_reaction_stats (site_id, day, allowed_reaction_id, total)
+		SELECT site_id, DATE(NEW.created_at), id, 1 FROM allowed_reactions
+		WHERE id = NEW.allowed_reaction_id AND DATE(NEW.created_at) IS NOT NULL
+		ON CONFLICT (site_id, day, allowed_reaction_id) DO UPDATE SET total = total + 1;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_reaction_stats_delete AFTER DELETE ON reactions
+	BEGIN
+		UPDATE daily_reaction_stats SET total = total - 1
+		WHERE allowed_reaction_id = OLD.allowed_reaction_id AND day = DATE(OLD.created_at);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_reaction_stats_update AFTER UPDATE OF allowed_reaction_id, created_at ON reactions
+	WHEN OLD.allowed_reaction_id IS NOT NEW.allowed_reaction_id OR DATE(OLD.created_at) IS NOT DATE(NEW.created_at)
+	BEGIN
+		UPDATE daily_reaction_stats SET total = total - 1
+		WHERE allowed_reaction_id = OLD.allowed_reaction_id AND day = DATE(OLD.created_at);
+
+		INSERT INTO daily_reaction_stats (site_id, day, allowed_reaction_id, total)
+		SELECT site_id, DATE(NEW.created_at), id, 1 FROM allowed_reactions
+		WHERE id = NEW.allowed_reaction_id AND DATE(NEW.created_at) IS NOT NULL
+		ON CONFLICT (site_id, day, allowed_reaction_id) DO UPDATE SET total = total + 1;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_reaction_stats_allowed_delete AFTER DELETE ON allowed_reactions
+	BEGIN
+		DELETE FROM daily_reaction_stats WHERE allowed_reaction_id = OLD.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS daily_stats_site_delete AFTER DELETE ON sites
+	BEGIN
+		DELETE FROM daily_comment_stats WHERE site_id = OLD.id;
+		DELETE FROM daily_reaction_stats WHERE site_id = OLD.id;
+		DELETE FROM daily_stats_coverage WHERE site_id = OLD.id;
+	END;
+	`
+
+// sqliteInitialSchema creates every table and index if it doesn't exist
+const sqliteInitialSchema = `
+	CREATE TABLE IF NOT EXISTS admin_users (
+		id TEXT PRIMARY KEY,
+		email TEXT UNIQUE NOT NULL,
+		name TEXT,
+		auth0_sub TEXT UNIQUE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS sites (
+		id TEXT PRIMARY KEY,
+		owner_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		domain TEXT,
+		description TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (owner_id) REFERENCES admin_users(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sites_owner ON sites(owner_id);
+
+	CREATE TABLE IF NOT EXISTS users (
+		id TEXT NOT NULL,
+		site_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		email TEXT,
+		avatar_url TEXT,
+		profile_url TEXT,
+		is_verified INTEGER DEFAULT 0,
+		roles TEXT,
+		reputation_score INTEGER DEFAULT 0,
+		first_seen TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+		PRIMARY KEY (site_id, id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_users_site_id ON users(site_id);
+	CREATE INDEX IF NOT EXISTS idx_users_email ON users(site_id, email);
+
+	CREATE TABLE IF NOT EXISTS pages (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		path TEXT NOT NULL,
+		title TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+		UNIQUE(site_id, path)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_pages_site ON pages(site_id);
+
+	CREATE TABLE IF NOT EXISTS comments (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		page_id TEXT NOT NULL,
+		author TEXT NOT NULL,
+		author_id TEXT NOT NULL,
+		author_email TEXT,
+		text TEXT NOT NULL,
+		parent_id TEXT,
+		status TEXT DEFAULT 'pending' CHECK(status IN ('pending', 'approved', 'rejected')),
+		moderated_by TEXT,
+		moderated_at TIMESTAMP,
+		language TEXT,
+		sentiment TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_site_page ON comments(site_id, page_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_parent ON comments(parent_id);
+	CREATE INDEX IF NOT EXISTS idx_comments_status ON comments(status);
+	CREATE INDEX IF NOT EXISTS idx_comments_author ON comments(author_id);
+
+	CREATE TABLE IF NOT EXISTS comment_idempotency_keys (
+		site_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		idempotency_key TEXT NOT NULL,
+		comment_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (site_id, user_id, idempotency_key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_comment_idempotency_created ON comment_idempotency_keys(created_at);
+
+	CREATE TABLE IF NOT EXISTS comment_reports (
+		id TEXT PRIMARY KEY,
+		comment_id TEXT NOT NULL,
+		reporter_user_id TEXT NOT NULL,
+		reason TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+		UNIQUE(comment_id, reporter_user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_comment_reports_comment ON comment_reports(comment_id);
+
+	CREATE TABLE IF NOT EXISTS blocked_authors (
+		site_id TEXT NOT NULL,
+		author_id TEXT NOT NULL,
+		blocked_by TEXT NOT NULL,
+		reason TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (site_id, author_id),
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		prefix TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_site ON api_keys(site_id);
+
+	CREATE TABLE IF NOT EXISTS allowed_reactions (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		emoji TEXT NOT NULL,
+		reaction_type TEXT NOT NULL DEFAULT 'comment' CHECK(reaction_type IN ('page', 'comment', 'both')),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+		UNIQUE(site_id, name, reaction_type)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_allowed_reactions_site ON allowed_reactions(site_id);
+	CREATE INDEX IF NOT EXISTS idx_allowed_reactions_type ON allowed_reactions(reaction_type);
+
+	CREATE TABLE IF NOT EXISTS reactions (
+		id TEXT PRIMARY KEY,
+		page_id TEXT,
+		comment_id TEXT,
+		allowed_reaction_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+		FOREIGN KEY (allowed_reaction_id) REFERENCES allowed_reactions(id) ON DELETE CASCADE,
+		CHECK ((page_id IS NOT NULL AND comment_id IS NULL) OR (page_id IS NULL AND comment_id IS NOT NULL)),
+		UNIQUE(page_id, comment_id, allowed_reaction_id, user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_reactions_page ON reactions(page_id);
+	CREATE INDEX IF NOT EXISTS idx_reactions_comment ON reactions(comment_id);
+	CREATE INDEX IF NOT EXISTS idx_reactions_allowed ON reactions(allowed_reaction_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_reactions_user ON reactions(user_id);
+
+	CREATE TABLE IF NOT EXISTS attachments (
+		id TEXT PRIMARY KEY,
+		comment_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_attachments_comment ON attachments(comment_id);
+
+	CREATE TABLE IF NOT EXISTS moderation_config (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL UNIQUE,
+		enabled INTEGER DEFAULT 0,
+		auto_reject_threshold REAL DEFAULT 0.85,
+		auto_approve_threshold REAL DEFAULT 0.30,
+		check_spam INTEGER DEFAULT 1,
+		check_offensive INTEGER DEFAULT 1,
+		check_aggressive INTEGER DEFAULT 1,
+		check_off_topic INTEGER DEFAULT 0,
+		blocked_word_action TEXT DEFAULT 'rejected',
+		akismet_api_key TEXT,
+		trusted_reputation_threshold INTEGER DEFAULT 1000000,
+		max_links INTEGER DEFAULT 3,
+		link_action TEXT DEFAULT 'pending',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_moderation_config_site ON moderation_config(site_id);
+
+	CREATE TABLE IF NOT EXISTS moderation_events (
+		id TEXT PRIMARY KEY,
+		comment_id TEXT NOT NULL,
+		site_id TEXT NOT NULL,
+		decision TEXT NOT NULL,
+		source TEXT NOT NULL,
+		confidence REAL,
+		reason TEXT,
+		moderator_id TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_moderation_events_comment ON moderation_events(comment_id);
+	CREATE INDEX IF NOT EXISTS idx_moderation_events_site ON moderation_events(site_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS blocked_words (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		word TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(site_id, word),
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS site_auth_configs (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL UNIQUE,
+		auth_mode TEXT NOT NULL DEFAULT 'external',
+		jwt_validation_type TEXT,
+		jwt_secret TEXT,
+		jwt_public_key TEXT,
+		jwks_endpoint TEXT,
+		jwt_issuer TEXT,
+		jwt_audience TEXT,
+		token_expiration_buffer INTEGER DEFAULT 60,
+		access_token_lifetime INTEGER NOT NULL DEFAULT 3600,
+		refresh_token_lifetime INTEGER NOT NULL DEFAULT 2592000,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_site_auth_configs_site ON site_auth_configs(site_id);
+
+	CREATE TABLE IF NOT EXISTS kotomi_auth_users (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		email TEXT NOT NULL,
+		auth0_sub TEXT NOT NULL,
+		name TEXT,
+		avatar_url TEXT,
+		is_verified INTEGER DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+		UNIQUE(site_id, auth0_sub)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_users_site ON kotomi_auth_users(site_id);
+	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_users_email ON kotomi_auth_users(site_id, email);
+	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_users_auth0 ON kotomi_auth_users(auth0_sub);
+
+	CREATE TABLE IF NOT EXISTS kotomi_auth_sessions (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		site_id TEXT NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		refresh_token TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		refresh_expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES kotomi_auth_users(id) ON DELETE CASCADE,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_sessions_user ON kotomi_auth_sessions(user_id);
+	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_sessions_token ON kotomi_auth_sessions(token);
+	CREATE INDEX IF NOT EXISTS idx_kotomi_auth_sessions_refresh ON kotomi_auth_sessions(refresh_token);
+
+	CREATE TABLE IF NOT EXISTS notification_settings (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL UNIQUE,
+		enabled INTEGER DEFAULT 0,
+		provider TEXT DEFAULT 'smtp',
+		from_email TEXT NOT NULL,
+		from_name TEXT NOT NULL,
+		reply_to TEXT,
+		smtp_host TEXT,
+		smtp_port INTEGER,
+		smtp_user TEXT,
+		smtp_password TEXT,
+		smtp_encryption TEXT,
+		sendgrid_api_key TEXT,
+		notify_new_comment INTEGER DEFAULT 1,
+		notify_new_comment_mode TEXT DEFAULT 'immediate',
+		last_digest_sent_at TIMESTAMP,
+		notify_reply INTEGER DEFAULT 1,
+		notify_moderation INTEGER DEFAULT 1,
+		owner_email TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_settings_site ON notification_settings(site_id);
+
+	CREATE TABLE IF NOT EXISTS notification_queue (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		data TEXT,
+		status TEXT DEFAULT 'pending',
+		attempts INTEGER DEFAULT 0,
+		error TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_queue_site ON notification_queue(site_id);
+	CREATE INDEX IF NOT EXISTS idx_notification_queue_status ON notification_queue(status);
+	CREATE INDEX IF NOT EXISTS idx_notification_queue_created ON notification_queue(created_at);
+
+	CREATE TABLE IF NOT EXISTS notification_unsubscribes (
+		site_id TEXT NOT NULL,
+		email TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (site_id, email),
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_log (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		status TEXT NOT NULL,
+		error TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_log_site ON notification_log(site_id);
+	CREATE INDEX IF NOT EXISTS idx_notification_log_created ON notification_log(created_at);
+
+	CREATE TABLE IF NOT EXISTS site_settings (
+		site_id TEXT PRIMARY KEY,
+		max_comment_length INTEGER NOT NULL DEFAULT 10000,
+		max_reactions_per_target INTEGER NOT NULL DEFAULT 0,
+		cors_allowed_origins TEXT NOT NULL DEFAULT '',
+		cors_allow_credentials INTEGER NOT NULL DEFAULT 0,
+		report_threshold INTEGER NOT NULL DEFAULT 3,
+		comment_cooldown_seconds INTEGER NOT NULL DEFAULT 15,
+		max_allowed_reactions_per_site INTEGER NOT NULL DEFAULT 0,
+		content_policy TEXT NOT NULL DEFAULT 'markdown',
+		gravatar_enabled INTEGER NOT NULL DEFAULT 1,
+		gravatar_style TEXT NOT NULL DEFAULT 'identicon',
+		locale TEXT NOT NULL DEFAULT 'en',
+		default_comment_status TEXT NOT NULL DEFAULT 'pending',
+		auto_subscribe INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL,
+		actor_id TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		metadata TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_site ON audit_log(site_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS page_subscriptions (
+		site_id TEXT NOT NULL,
+		page_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		unsubscribed_at TIMESTAMP,
+		PRIMARY KEY (site_id, page_id, user_id),
+		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+		FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE
+	);
+	`
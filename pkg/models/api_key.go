@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix starts every generated API key so leaked keys are easy to spot
+const apiKeyPrefix = "kotomi_"
+
+// apiKeyDisplayLength is how much of a key is kept in plaintext to tell keys apart
+const apiKeyDisplayLength = 12
+
+// Errors returned when an API key can't be used
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyRevoked  = errors.New("api key revoked")
+)
+
+// APIKey lets a site's backend call the API without a user JWT. Only a hash of
+// the key is stored; the plaintext is shown once, when the key is created.
+type APIKey struct {
+	ID         string     `json:"id"`
+	SiteID     string     `json:"site_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the key, to identify it
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Revoked    bool       `json:"revoked"`
+}
+
+// HashAPIKey returns the stored form of an API key. Keys are random, so a
+// plain SHA-256 is enough to make a leaked table useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore handles API key database operations
+type APIKeyStore struct {
+	db *sql.DB
+}
+
+// NewAPIKeyStore creates a new API key store
+func NewAPIKeyStore(db *sql.DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+// Create generates a new key for the site, returning it with its plaintext
+func (s *APIKeyStore) Create(ctx context.Context, siteID, name string) (*APIKey, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	key := &APIKey{
+		ID:        uuid.NewString(),
+		SiteID:    siteID,
+		Name:      name,
+		Prefix:    plaintext[:apiKeyDisplayLength],
+		CreatedAt: time.Now(),
+	}
+	query := `
+		INSERT INTO api_keys (id, site_id, name, key_hash, prefix, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err := s.db.ExecContext(ctx, query, key.ID, key.SiteID, key.Name, HashAPIKey(plaintext), key.Prefix, key.CreatedAt); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return key, plaintext, nil
+}
+
+// ListBySite returns the site's keys, newest first
+func (s *APIKeyStore) ListBySite(ctx context.Context, siteID string) ([]APIKey, error) {
+	query := `
+		SELECT id, site_id, name, prefix, created_at, last_used_at, revoked
+		FROM api_keys
+		WHERE site_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke permanently disables one of the site's keys
+func (s *APIKeyStore) Revoke(ctx context.Context, siteID, id string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE api_keys SET revoked = 1 WHERE id = ? AND site_id = ?`, id, siteID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the key matching plaintext and records its use. It
+// returns ErrAPIKeyNotFound for unknown keys and ErrAPIKeyRevoked for revoked ones.
+func (s *APIKeyStore) Authenticate(ctx context.Context, plaintext string) (*APIKey, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	query := `
+		SELECT id, site_id, name, prefix, created_at, last_used_at, revoked
+		FROM api_keys
+		WHERE key_hash = ?
+	`
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, query, HashAPIKey(plaintext)))
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now, key.ID); err != nil {
+		return nil, fmt.Errorf("failed to record api key use: %w", err)
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
+
+// scanAPIKey reads a key from a row of id, site_id, name, prefix, created_at,
+// last_used_at and revoked
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	var key APIKey
+	var lastUsed sql.NullTime
+	if err := row.Scan(&key.ID, &key.SiteID, &key.Name, &key.Prefix, &key.CreatedAt, &lastUsed, &key.Revoked); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to scan api key: %w", err)
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	return &key, nil
+}
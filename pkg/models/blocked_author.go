@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BlockedAuthor is a user barred from posting new comments on a site. Their
+// existing comments are left in place.
+type BlockedAuthor struct {
+	SiteID    string    `json:"site_id"`
+	AuthorID  string    `json:"author_id"`
+	BlockedBy string    `json:"blocked_by"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlockedAuthorStore handles blocked author database operations
+type BlockedAuthorStore struct {
+	db *sql.DB
+}
+
+// NewBlockedAuthorStore creates a new blocked author store
+func NewBlockedAuthorStore(db *sql.DB) *BlockedAuthorStore {
+	return &BlockedAuthorStore{db: db}
+}
+
+// Block bars authorID from commenting on siteID. Blocking an already blocked
+// author updates the reason and who blocked them.
+func (s *BlockedAuthorStore) Block(ctx context.Context, siteID, authorID, blockedBy, reason string) (*BlockedAuthor, error) {
+	now := time.Now()
+	query := `
+		INSERT INTO blocked_authors (site_id, author_id, blocked_by, reason, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(site_id, author_id) DO UPDATE SET
+			blocked_by = excluded.blocked_by,
+			reason = excluded.reason
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, siteID, authorID, blockedBy, reason, now); err != nil {
+		return nil, fmt.Errorf("failed to block author: %w", err)
+	}
+
+	return s.Get(ctx, siteID, authorID)
+}
+
+// Unblock lets authorID comment on siteID again
+func (s *BlockedAuthorStore) Unblock(ctx context.Context, siteID, authorID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM blocked_authors WHERE site_id = ? AND author_id = ?`, siteID, authorID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock author: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("blocked author not found")
+	}
+
+	return nil
+}
+
+// Get retrieves the block for authorID on siteID
+func (s *BlockedAuthorStore) Get(ctx context.Context, siteID, authorID string) (*BlockedAuthor, error) {
+	query := `
+		SELECT site_id, author_id, blocked_by, reason, created_at
+		FROM blocked_authors
+		WHERE site_id = ? AND author_id = ?
+	`
+
+	var b BlockedAuthor
+	var reason sql.NullString
+	err := s.db.QueryRowContext(ctx, query, siteID, authorID).Scan(&b.SiteID, &b.AuthorID, &b.BlockedBy, &reason, &b.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("blocked author not found")
+		}
+		return nil, fmt.Errorf("failed to query blocked author: %w", err)
+	}
+	b.Reason = reason.String
+
+	return &b, nil
+}
+
+// IsBlocked reports whether authorID may not comment on siteID
+func (s *BlockedAuthorStore) IsBlocked(ctx context.Context, siteID, authorID string) (bool, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM blocked_authors WHERE site_id = ? AND author_id = ?`, siteID, authorID).Scan(&exists)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check blocked author: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListBySite retrieves the authors blocked on a site, most recent first
+func (s *BlockedAuthorStore) ListBySite(ctx context.Context, siteID string) ([]BlockedAuthor, error) {
+	query := `
+		SELECT site_id, author_id, blocked_by, reason, created_at
+		FROM blocked_authors
+		WHERE site_id = ?
+		ORDER BY created_at DESC, author_id ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked authors: %w", err)
+	}
+	defer rows.Close()
+
+	blocked := []BlockedAuthor{}
+	for rows.Next() {
+		var b BlockedAuthor
+		var reason sql.NullString
+		if err := rows.Scan(&b.SiteID, &b.AuthorID, &b.BlockedBy, &reason, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked author: %w", err)
+		}
+		b.Reason = reason.String
+		blocked = append(blocked, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocked authors: %w", err)
+	}
+
+	return blocked, nil
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEmoji is returned when an allowed reaction's emoji isn't exactly
+// one emoji
+var ErrInvalidEmoji = errors.New("invalid emoji")
+
+const (
+	zeroWidthJoiner    = 0x200D
+	variationSelector  = 0xFE0F
+	combiningKeycap    = 0x20E3
+	blackFlag          = 0x1F3F4
+	cancelTag          = 0xE007F
+	regionalIndicatorA = 0x1F1E6
+	regionalIndicatorZ = 0x1F1FF
+)
+
+// emojiRanges lists the code points that can start an emoji, following the
+// Emoji property of Unicode's emoji-data.txt coarsely enough to stay valid as
+// new emoji are added to the supplementary planes
+var emojiRanges = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x231A, 0x231B}, {0x2328, 0x2328}, {0x23CF, 0x23CF}, {0x23E9, 0x23F3},
+	{0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB}, {0x25B6, 0x25B6},
+	{0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x27BF}, {0x2934, 0x2935},
+	{0x2B05, 0x2B07}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55},
+	{0x3030, 0x3030}, {0x303D, 0x303D}, {0x3297, 0x3297}, {0x3299, 0x3299},
+	{0x1F000, 0x1F1E5}, {0x1F200, 0x1F3FA}, {0x1F400, 0x1FAFF},
+}
+
+func isEmojiBase(r rune) bool {
+	for _, rg := range emojiRanges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isSkinTone(r rune) bool { return r >= 0x1F3FB && r <= 0x1F3FF }
+
+func isRegionalIndicator(r rune) bool { return r >= regionalIndicatorA && r <= regionalIndicatorZ }
+
+func isTag(r rune) bool { return r >= 0xE0020 && r <= 0xE007E }
+
+// ValidateEmoji checks that value is a single emoji: one pictograph with an
+// optional variation selector and skin tone, a ZWJ sequence of those (👨‍👩‍👧),
+// a flag, a subdivision flag or a keycap. Text, several emoji and empty
+// values are rejected.
+func ValidateEmoji(value string) error {
+	if !isSingleEmoji([]rune(value)) {
+		return fmt.Errorf("%w: %q must be a single emoji", ErrInvalidEmoji, value)
+	}
+	return nil
+}
+
+func isSingleEmoji(runes []rune) bool {
+	if len(runes) == 0 {
+		return false
+	}
+
+	// Country flags are exactly two regional indicators
+	if isRegionalIndicator(runes[0]) {
+		return len(runes) == 2 && isRegionalIndicator(runes[1])
+	}
+
+	// Keycaps: digit, # or *, an optional variation selector, then U+20E3
+	if r := runes[0]; (r >= '0' && r <= '9') || r == '#' || r == '*' {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == variationSelector {
+			rest = rest[1:]
+		}
+		return len(rest) == 1 && rest[0] == combiningKeycap
+	}
+
+	// Subdivision flags: black flag, tag characters, cancel tag
+	if runes[0] == blackFlag && len(runes) > 2 && isTag(runes[1]) {
+		i := 1
+		for i < len(runes) && isTag(runes[i]) {
+			i++
+		}
+		return i == len(runes)-1 && runes[i] == cancelTag
+	}
+
+	// One or more pictographs joined by zero width joiners
+	i := 0
+	for {
+		if i >= len(runes) || !isEmojiBase(runes[i]) {
+			return false
+		}
+		i++
+		if i < len(runes) && runes[i] == variationSelector {
+			i++
+		}
+		if i < len(runes) && isSkinTone(runes[i]) {
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != zeroWidthJoiner {
+			return false
+		}
+		i++
+	}
+}
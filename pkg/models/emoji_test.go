@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateEmoji(t *testing.T) {
+	valid := []string{
+		"👍",     // single pictograph
+		"❤️",    // with variation selector
+		"👍🏽",    // with skin tone
+		"👨‍👩‍👧", // ZWJ family
+		"🏳️‍🌈",  // ZWJ with variation selector
+		"🇯🇵",    // country flag
+		"🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", // England
+		"1️⃣", // keycap
+		"⭐",
+	}
+	for _, emoji := range valid {
+		if err := ValidateEmoji(emoji); err != nil {
+			t.Errorf("ValidateEmoji(%q) = %v, want nil", emoji, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"thumbsup",
+		"👍👍",
+		"👍 ",
+		"a👍",
+		"🇯",    // half a flag
+		"🇯🇵🇺🇸", // two flags
+		"1",
+		"👨‍", // dangling joiner
+		"🏽",  // lone skin tone
+	}
+	for _, emoji := range invalid {
+		if err := ValidateEmoji(emoji); !errors.Is(err, ErrInvalidEmoji) {
+			t.Errorf("ValidateEmoji(%q) = %v, want ErrInvalidEmoji", emoji, err)
+		}
+	}
+}
+
+func TestAllowedReactionStore_RejectsInvalidEmoji(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", "site-1", "user-1", "Test Site"); err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+
+	store := NewAllowedReactionStore(db)
+	for _, emoji := range []string{"thumbsup", "👍❤️", ""} {
+		if _, err := store.Create(ctx, "site-1", "bad", emoji, "comment"); !errors.Is(err, ErrInvalidEmoji) {
+			t.Errorf("Create with %q: expected ErrInvalidEmoji, got %v", emoji, err)
+		}
+	}
+
+	reaction, err := store.Create(ctx, "site-1", "thumbs_up", "👍", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create reaction: %v", err)
+	}
+	if err := store.Update(ctx, reaction.ID, "thumbs_up", "thumbsup", "comment"); !errors.Is(err, ErrInvalidEmoji) {
+		t.Errorf("Update: expected ErrInvalidEmoji, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Gravatar default image styles, shown for emails without a Gravatar account
+const (
+	GravatarIdenticon     = "identicon"
+	GravatarRetro         = "retro"
+	GravatarMonsterID     = "monsterid"
+	GravatarWavatar       = "wavatar"
+	GravatarRobohash      = "robohash"
+	GravatarMysteryPerson = "mp"
+)
+
+// DefaultGravatarStyle is the default image style used when a site hasn't chosen one
+const DefaultGravatarStyle = GravatarIdenticon
+
+// DefaultGravatarSize is the avatar size in pixels put in comment responses
+const DefaultGravatarSize = 80
+
+// IsValidGravatarStyle reports whether style is a Gravatar default image style
+func IsValidGravatarStyle(style string) bool {
+	switch style {
+	case GravatarIdenticon, GravatarRetro, GravatarMonsterID, GravatarWavatar, GravatarRobohash, GravatarMysteryPerson:
+		return true
+	}
+	return false
+}
+
+// GravatarURL returns the Gravatar URL for email at size pixels, falling back
+// to the default identicon style
+func GravatarURL(email string, size int) string {
+	return GravatarURLWithStyle(email, size, DefaultGravatarStyle)
+}
+
+// GravatarURLWithStyle returns the Gravatar URL for email at size pixels with
+// the given default image style. The email is trimmed and lower-cased before
+// hashing, as Gravatar requires.
+func GravatarURLWithStyle(email string, size int, style string) string {
+	if !IsValidGravatarStyle(style) {
+		style = DefaultGravatarStyle
+	}
+	if size <= 0 {
+		size = DefaultGravatarSize
+	}
+	sum := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return fmt.Sprintf("https://www.gravatar.com/avatar/%s?s=%d&d=%s", hex.EncodeToString(sum[:]), size, style)
+}
+
+// AvatarFor returns the avatar to show for a comment author: their own avatar
+// URL when they have one, otherwise a Gravatar for their email if the site
+// allows it. It returns "" when neither applies.
+func (s *SiteSettings) AvatarFor(avatarURL, email string) string {
+	if avatarURL != "" {
+		return avatarURL
+	}
+	if !s.GravatarEnabled || strings.TrimSpace(email) == "" {
+		return ""
+	}
+	return GravatarURLWithStyle(email, DefaultGravatarSize, s.GravatarStyle)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGravatarURL(t *testing.T) {
+	// Example from Gravatar's documentation: the hash is of the trimmed,
+	// lower-cased address
+	want := "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?s=80&d=identicon"
+	for _, email := range []string{"myemailaddress@example.com", " MyEmailAddress@example.com ", "MYEMAILADDRESS@EXAMPLE.COM\n"} {
+		if got := GravatarURL(email, 80); got != want {
+			t.Errorf("GravatarURL(%q) = %q, want %q", email, got, want)
+		}
+	}
+
+	if got := GravatarURLWithStyle("a@example.com", 40, GravatarRetro); !strings.HasSuffix(got, "?s=40&d=retro") {
+		t.Errorf("Expected size and retro style in %q", got)
+	}
+	if got := GravatarURLWithStyle("a@example.com", 0, "<script>"); !strings.HasSuffix(got, "?s=80&d=identicon") {
+		t.Errorf("Expected default size and style for invalid input, got %q", got)
+	}
+}
+
+func TestSiteSettings_AvatarFor(t *testing.T) {
+	settings := DefaultSiteSettings("site-1")
+	settings.GravatarStyle = GravatarRetro
+
+	if got := settings.AvatarFor("https://cdn.example.com/me.png", "a@example.com"); got != "https://cdn.example.com/me.png" {
+		t.Errorf("Expected the author's own avatar, got %q", got)
+	}
+	if got := settings.AvatarFor("", "a@example.com"); got != GravatarURLWithStyle("a@example.com", DefaultGravatarSize, GravatarRetro) {
+		t.Errorf("Expected a retro Gravatar fallback, got %q", got)
+	}
+	if got := settings.AvatarFor("", " "); got != "" {
+		t.Errorf("Expected no avatar without an email, got %q", got)
+	}
+
+	settings.GravatarEnabled = false
+	if got := settings.AvatarFor("", "a@example.com"); got != "" {
+		t.Errorf("Expected no Gravatar when disabled, got %q", got)
+	}
+	if got := settings.AvatarFor("https://cdn.example.com/me.png", "a@example.com"); got != "https://cdn.example.com/me.png" {
+		t.Errorf("Expected the author's own avatar when Gravatar is disabled, got %q", got)
+	}
+}
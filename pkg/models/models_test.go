@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"testing"
@@ -461,3 +462,137 @@ func TestUserStore_CalculateReputationScore(t *testing.T) {
 	}
 }
 
+
+func TestUserStore_RecordCommenter(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	userStore := NewUserStore(db)
+
+	// First comment creates the user
+	if err := userStore.RecordCommenter(ctx, site.ID, "alice", "Alice", "alice@example.com"); err != nil {
+		t.Fatalf("RecordCommenter failed: %v", err)
+	}
+	first, err := userStore.GetBySiteAndID(ctx, site.ID, "alice")
+	if err != nil || first == nil {
+		t.Fatalf("Expected alice to be created, got %v, %v", first, err)
+	}
+	if first.Name != "Alice" || first.Email != "alice@example.com" {
+		t.Errorf("Expected name and email from the token, got %q %q", first.Name, first.Email)
+	}
+	if first.FirstSeen.IsZero() || !first.FirstSeen.Equal(first.LastSeen) {
+		t.Errorf("Expected first_seen = last_seen on the first comment, got %v and %v", first.FirstSeen, first.LastSeen)
+	}
+
+	// A later comment moves last_seen and refreshes the name, keeping the
+	// known email when the token has none
+	time.Sleep(10 * time.Millisecond)
+	if err := userStore.RecordCommenter(ctx, site.ID, "alice", "Alice B.", ""); err != nil {
+		t.Fatalf("RecordCommenter failed: %v", err)
+	}
+	second, _ := userStore.GetBySiteAndID(ctx, site.ID, "alice")
+	if !second.FirstSeen.Equal(first.FirstSeen) {
+		t.Errorf("Expected first_seen to stay %v, got %v", first.FirstSeen, second.FirstSeen)
+	}
+	if !second.LastSeen.After(first.LastSeen) {
+		t.Errorf("Expected last_seen to move past %v, got %v", first.LastSeen, second.LastSeen)
+	}
+	if second.Name != "Alice B." || second.Email != "alice@example.com" {
+		t.Errorf("Unexpected name or email after update: %q %q", second.Name, second.Email)
+	}
+
+	// The same ID on another site is a different commenter
+	otherSite, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Other Site", "other.example.com", "")
+	if err := userStore.RecordCommenter(ctx, otherSite.ID, "alice", "Alice", ""); err != nil {
+		t.Fatalf("RecordCommenter failed: %v", err)
+	}
+	if users, _ := userStore.ListBySite(ctx, site.ID); len(users) != 1 {
+		t.Errorf("Expected 1 user on the first site, got %d", len(users))
+	}
+}
+
+func TestUserStore_VerifyUser(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	userStore := NewUserStore(db)
+	if err := userStore.CreateOrUpdate(ctx, &User{ID: "alice", SiteID: site.ID, Name: "Alice"}); err != nil {
+		t.Fatalf("CreateOrUpdate failed: %v", err)
+	}
+
+	if err := userStore.VerifyUser(ctx, site.ID, "alice"); err != nil {
+		t.Fatalf("VerifyUser failed: %v", err)
+	}
+
+	// The next login with an unverified token keeps the owner's verification
+	if err := userStore.CreateOrUpdate(ctx, &User{ID: "alice", SiteID: site.ID, Name: "Alice"}); err != nil {
+		t.Fatalf("CreateOrUpdate failed: %v", err)
+	}
+	if user, _ := userStore.GetBySiteAndID(ctx, site.ID, "alice"); !user.IsVerified {
+		t.Error("Expected alice to stay verified after logging in again")
+	}
+
+	if err := userStore.UnverifyUser(ctx, site.ID, "alice"); err != nil {
+		t.Fatalf("UnverifyUser failed: %v", err)
+	}
+	if user, _ := userStore.GetBySiteAndID(ctx, site.ID, "alice"); user.IsVerified {
+		t.Error("Expected alice to be unverified")
+	}
+
+	if err := userStore.VerifyUser(ctx, site.ID, "nobody"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestSiteAuthConfigStore_TokenLifetimes(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	store := NewSiteAuthConfigStore(db)
+
+	// An access token outliving its refresh token is rejected
+	invalid := &SiteAuthConfig{SiteID: site.ID, AuthMode: "kotomi", AccessTokenLifetime: 7200, RefreshTokenLifetime: 3600}
+	if err := store.Create(ctx, invalid); !errors.Is(err, ErrInvalidTokenLifetimes) {
+		t.Errorf("Expected ErrInvalidTokenLifetimes, got %v", err)
+	}
+
+	// Unset lifetimes are stored as the defaults
+	config := &SiteAuthConfig{SiteID: site.ID, AuthMode: "kotomi"}
+	if err := store.Create(ctx, config); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	stored, err := store.GetBySiteID(ctx, site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if stored.AccessTokenLifetime != DefaultAccessTokenLifetime || stored.RefreshTokenLifetime != DefaultRefreshTokenLifetime {
+		t.Errorf("Expected default lifetimes, got %d and %d", stored.AccessTokenLifetime, stored.RefreshTokenLifetime)
+	}
+
+	// A refresh lifetime shorter than the default access lifetime is rejected too
+	stored.RefreshTokenLifetime = 1800
+	if err := store.Update(ctx, stored); !errors.Is(err, ErrInvalidTokenLifetimes) {
+		t.Errorf("Expected ErrInvalidTokenLifetimes, got %v", err)
+	}
+
+	stored.AccessTokenLifetime = 15 * 60
+	if err := store.Update(ctx, stored); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	updated, _ := store.GetBySiteID(ctx, site.ID)
+	if access, refresh := updated.TokenLifetimes(); access != 15*time.Minute || refresh != 30*time.Minute {
+		t.Errorf("Expected 15m and 30m lifetimes, got %v and %v", access, refresh)
+	}
+}
@@ -3,10 +3,14 @@ package models
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
 
 // AllowedReaction represents a reaction type that is allowed on a site
@@ -159,13 +163,87 @@ func (s *AllowedReactionStore) GetByID(ctx context.Context, id string) (*Allowed
 	return &reaction, nil
 }
 
-// Create creates a new allowed reaction for a site
+// ErrAllowedReactionLimitReached is returned when a site already offers as many
+// reaction types as its max_allowed_reactions_per_site setting permits
+var ErrAllowedReactionLimitReached = errors.New("allowed reaction limit reached")
+
+// ErrDuplicateAllowedReactionEmoji is returned when another of the site's
+// reactions for the same target kind already uses the emoji
+var ErrDuplicateAllowedReactionEmoji = errors.New("emoji already used by another reaction")
+
+// ErrInvalidReactionType is returned for a reaction type other than page,
+// comment or both
+var ErrInvalidReactionType = errors.New("invalid reaction type")
+
+// reactionTargetKinds returns the targets a reaction type applies to
+func reactionTargetKinds(reactionType string) []string {
+	switch reactionType {
+	case "both":
+		return []string{"comment", "page"}
+	case "comment", "page":
+		return []string{reactionType}
+	}
+	return nil
+}
+
+// RowQuerier is satisfied by both *sql.DB and *sql.Tx
+type RowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// checkAllowedReaction runs CheckAllowedReaction against the store's database
+func (s *AllowedReactionStore) checkAllowedReaction(ctx context.Context, siteID, emoji, reactionType, excludeID string) error {
+	return CheckAllowedReaction(ctx, s.db, siteID, emoji, reactionType, excludeID)
+}
+
+// CheckAllowedReaction enforces the site's reaction cap and emoji uniqueness
+// for a reaction of reactionType, reading through q so callers inside a
+// transaction see their own writes. A "both" reaction competes with comment
+// and page reactions alike. excludeID skips the reaction being updated.
+func CheckAllowedReaction(ctx context.Context, q RowQuerier, siteID, emoji, reactionType, excludeID string) error {
+	settings, err := getSiteSettings(ctx, q, siteID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(emoji = ?), 0)
+		FROM allowed_reactions
+		WHERE site_id = ? AND id != ? AND reaction_type IN (?, 'both')
+	`
+	for _, kind := range reactionTargetKinds(reactionType) {
+		var count, sameEmoji int
+		if err := q.QueryRowContext(ctx, query, emoji, siteID, excludeID, kind).Scan(&count, &sameEmoji); err != nil {
+			return fmt.Errorf("failed to count allowed reactions: %w", err)
+		}
+		if sameEmoji > 0 {
+			return fmt.Errorf("%w: %s is already a %s reaction", ErrDuplicateAllowedReactionEmoji, emoji, kind)
+		}
+		if settings.MaxAllowedReactions > 0 && count >= settings.MaxAllowedReactions {
+			return fmt.Errorf("%w: the site allows at most %d %s reactions", ErrAllowedReactionLimitReached, settings.MaxAllowedReactions, kind)
+		}
+	}
+
+	return nil
+}
+
+// Create creates a new allowed reaction for a site. It fails with
+// ErrInvalidEmoji unless emoji is a single emoji, with
+// ErrAllowedReactionLimitReached when the site's cap is reached and with
+// ErrDuplicateAllowedReactionEmoji when the emoji is already offered.
 func (s *AllowedReactionStore) Create(ctx context.Context, siteID, name, emoji, reactionType string) (*AllowedReaction, error) {
 	// Default to 'comment' if not specified
 	if reactionType == "" {
 		reactionType = "comment"
 	}
 
+	if err := ValidateEmoji(emoji); err != nil {
+		return nil, err
+	}
+	if err := s.checkAllowedReaction(ctx, siteID, emoji, reactionType, ""); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	reaction := &AllowedReaction{
 		ID:           uuid.NewString(),
@@ -191,22 +269,118 @@ func (s *AllowedReactionStore) Create(ctx context.Context, siteID, name, emoji,
 	return reaction, nil
 }
 
-// Update updates an allowed reaction
+// Update updates an allowed reaction, applying the same cap and emoji checks as
+// Create. Narrowing the type deletes the reactions it no longer allows, as
+// ChangeType does.
 func (s *AllowedReactionStore) Update(ctx context.Context, id, name, emoji, reactionType string) error {
+	if reactionTargetKinds(reactionType) == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidReactionType, reactionType)
+	}
+	if err := ValidateEmoji(emoji); err != nil {
+		return err
+	}
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkAllowedReaction(ctx, existing.SiteID, emoji, reactionType, id); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := removeIncompatibleReactions(ctx, tx, id, reactionType); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE allowed_reactions
 		SET name = ?, emoji = ?, reaction_type = ?, updated_at = ?
 		WHERE id = ?
 	`
 
-	_, err := s.db.ExecContext(ctx, query, name, emoji, reactionType, time.Now(), id)
+	_, err = tx.ExecContext(ctx, query, name, emoji, reactionType, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to update allowed reaction: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+// ChangeType changes the targets an allowed reaction applies to and returns
+// how many reactions were removed. Widening (e.g. comment to both) keeps every
+// reaction; narrowing deletes the ones left on targets the new type excludes,
+// such as comment reactions when going from both to page.
+func (s *AllowedReactionStore) ChangeType(ctx context.Context, id, newType string) (int64, error) {
+	if reactionTargetKinds(newType) == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidReactionType, newType)
+	}
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.checkAllowedReaction(ctx, existing.SiteID, existing.Emoji, newType, id); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed, err := removeIncompatibleReactions(ctx, tx, id, newType)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE allowed_reactions SET reaction_type = ?, updated_at = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, newType, time.Now(), id); err != nil {
+		return 0, fmt.Errorf("failed to change allowed reaction type: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return removed, nil
+}
+
+// removeIncompatibleReactions deletes the reactions using an allowed reaction
+// that reactionType no longer permits and returns how many were deleted
+func removeIncompatibleReactions(ctx context.Context, tx *sql.Tx, allowedReactionID, reactionType string) (int64, error) {
+	var removed int64
+	for _, target := range []struct{ kind, column string }{{"comment", "comment_id"}, {"page", "page_id"}} {
+		allowed := false
+		for _, kind := range reactionTargetKinds(reactionType) {
+			allowed = allowed || kind == target.kind
+		}
+		if allowed {
+			continue
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM reactions WHERE allowed_reaction_id = ? AND `+target.column+` IS NOT NULL`, allowedReactionID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to remove %s reactions: %w", target.kind, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check rows affected: %w", err)
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
 // Delete deletes an allowed reaction
 func (s *AllowedReactionStore) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM allowed_reactions WHERE id = ?`
@@ -219,6 +393,14 @@ func (s *AllowedReactionStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// ReactionCountWithMine is a reaction count that also reports whether the
+// requesting user left that reaction
+type ReactionCountWithMine struct {
+	AllowedReactionID string `json:"allowed_reaction_id"`
+	ReactionCount
+	Mine bool `json:"mine"`
+}
+
 // ReactionStore handles reactions database operations
 type ReactionStore struct {
 	db *sql.DB
@@ -229,76 +411,219 @@ func NewReactionStore(db *sql.DB) *ReactionStore {
 	return &ReactionStore{db: db}
 }
 
-// AddReaction adds a reaction to a comment (or toggles it off if already exists)
-func (s *ReactionStore) AddReaction(ctx context.Context, commentID, allowedReactionID, userID string) (*Reaction, error) {
-	// Check if user already reacted with this reaction type
-	existing, err := s.GetUserCommentReaction(ctx, commentID, allowedReactionID, userID)
-	if err == nil && existing != nil {
-		// User already reacted with this type - toggle it off (remove it)
-		if err := s.RemoveReaction(ctx, existing.ID); err != nil {
-			return nil, fmt.Errorf("failed to remove existing reaction: %w", err)
-		}
-		return nil, nil // Return nil to indicate removal
-	}
+// ErrReactionLimitReached is returned when a user already has the maximum number
+// of reactions allowed by the site on a comment or page
+var ErrReactionLimitReached = errors.New("reaction limit reached")
 
-	// Add new reaction
-	now := time.Now()
+// ErrReactionSiteMismatch is returned when an allowed reaction belongs to a
+// different site than the comment or page it is applied to
+var ErrReactionSiteMismatch = errors.New("allowed reaction belongs to another site")
+
+// ErrReactionTypeMismatch is returned when an allowed reaction's type doesn't
+// permit the target kind, e.g. a page-only reaction applied to a comment
+var ErrReactionTypeMismatch = errors.New("allowed reaction not permitted on this target")
+
+// Outcomes of toggling a reaction
+const (
+	ReactionAdded   = "added"
+	ReactionRemoved = "removed"
+)
+
+// ReactionToggle is what AddReaction or AddPageReaction did
+type ReactionToggle struct {
+	Result   string    // ReactionAdded or ReactionRemoved
+	Reaction *Reaction // The added reaction; nil when removed
+}
+
+// Removed reports whether the toggle removed the user's existing reaction
+func (t *ReactionToggle) Removed() bool {
+	return t.Result == ReactionRemoved
+}
+
+// maxReactionToggleAttempts bounds how often a toggle that lost a race with a
+// concurrent one for the same user and target is retried
+const maxReactionToggleAttempts = 5
+
+// reactionRetryBackoff is the base wait before retrying a toggle; each retry
+// waits a random time up to attempt times this, so racing taps spread out
+const reactionRetryBackoff = 10 * time.Millisecond
+
+// AddReaction adds a reaction to a comment (or toggles it off if already exists).
+// When the site allows a single reaction per target, any other reaction the user
+// left on the comment is replaced.
+func (s *ReactionStore) AddReaction(ctx context.Context, commentID, allowedReactionID, userID string) (*ReactionToggle, error) {
 	reaction := &Reaction{
 		ID:                uuid.NewString(),
 		CommentID:         commentID,
 		AllowedReactionID: allowedReactionID,
 		UserID:            userID,
-		CreatedAt:         now,
+		CreatedAt:         time.Now(),
 	}
 
-	query := `
-		INSERT INTO reactions (id, page_id, comment_id, allowed_reaction_id, user_id, created_at)
-		VALUES (?, NULL, ?, ?, ?, ?)
-	`
+	return s.addTargetReaction(ctx, "comment_id", commentID, reaction)
+}
 
-	_, err = s.db.ExecContext(ctx, query, reaction.ID, reaction.CommentID, reaction.AllowedReactionID,
-		reaction.UserID, reaction.CreatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to add reaction: %w", err)
+// AddPageReaction adds a reaction to a page (or toggles it off if already exists).
+// When the site allows a single reaction per target, any other reaction the user
+// left on the page is replaced.
+func (s *ReactionStore) AddPageReaction(ctx context.Context, pageID, allowedReactionID, userID string) (*ReactionToggle, error) {
+	reaction := &Reaction{
+		ID:                uuid.NewString(),
+		PageID:            pageID,
+		AllowedReactionID: allowedReactionID,
+		UserID:            userID,
+		CreatedAt:         time.Now(),
 	}
 
-	return reaction, nil
+	return s.addTargetReaction(ctx, "page_id", pageID, reaction)
 }
 
-// AddPageReaction adds a reaction to a page (or toggles it off if already exists)
-func (s *ReactionStore) AddPageReaction(ctx context.Context, pageID, allowedReactionID, userID string) (*Reaction, error) {
-	// Check if user already reacted with this reaction type
-	existing, err := s.GetUserPageReaction(ctx, pageID, allowedReactionID, userID)
-	if err == nil && existing != nil {
-		// User already reacted with this type - toggle it off (remove it)
-		if err := s.RemoveReaction(ctx, existing.ID); err != nil {
-			return nil, fmt.Errorf("failed to remove existing reaction: %w", err)
+// addTargetReaction toggles the reaction on the target identified by column
+// ("comment_id" or "page_id"). Concurrent toggles for the same user and target
+// normally queue on the write lock. One that still loses the race, to the
+// unique index or to SQLite reporting the database busy, is retried, finds the
+// winner's reaction and removes it, so the result is the same as if the taps
+// had run one after the other.
+func (s *ReactionStore) addTargetReaction(ctx context.Context, column, targetID string, reaction *Reaction) (*ReactionToggle, error) {
+	for attempt := 1; ; attempt++ {
+		toggle, err := s.toggleTargetReaction(ctx, column, targetID, reaction)
+		if err == nil || !comments.IsWriteConflict(err) || attempt == maxReactionToggleAttempts {
+			return toggle, err
+		}
+
+		timer := time.NewTimer(rand.N(time.Duration(attempt) * reactionRetryBackoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
 		}
-		return nil, nil // Return nil to indicate removal
 	}
+}
 
-	// Add new reaction
-	now := time.Now()
-	reaction := &Reaction{
-		ID:                uuid.NewString(),
-		PageID:            pageID,
-		AllowedReactionID: allowedReactionID,
-		UserID:            userID,
-		CreatedAt:         now,
+// toggleTargetReaction toggles, swaps or adds a reaction inside a single
+// transaction, honouring the site's max_reactions_per_target setting
+func (s *ReactionStore) toggleTargetReaction(ctx context.Context, column, targetID string, reaction *Reaction) (*ReactionToggle, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Toggle off the user's existing reaction of this type. Starting with a
+	// write takes SQLite's write lock before anything is read, so a concurrent
+	// toggle waits for this one to commit rather than acting on a stale read.
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM reactions WHERE `+column+` = ? AND allowed_reaction_id = ? AND user_id = ?`,
+		targetID, reaction.AllowedReactionID, reaction.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove existing reaction: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count removed reactions: %w", err)
+	}
+
+	if err := validateAllowedReaction(ctx, tx, column, targetID, reaction.AllowedReactionID); err != nil {
+		return nil, err
+	}
+
+	if removed > 0 {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return &ReactionToggle{Result: ReactionRemoved}, nil
+	}
+
+	// Look up the limit of the site owning the allowed reaction
+	var limit int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(ss.max_reactions_per_target, 0)
+		FROM allowed_reactions ar
+		LEFT JOIN site_settings ss ON ss.site_id = ar.site_id
+		WHERE ar.id = ?
+	`, reaction.AllowedReactionID).Scan(&limit)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query reaction limit: %w", err)
+	}
+
+	switch {
+	case limit == 1:
+		// Single-choice semantics: replace whatever the user picked before
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM reactions WHERE `+column+` = ? AND user_id = ?`,
+			targetID, reaction.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to replace existing reaction: %w", err)
+		}
+	case limit > 1:
+		var count int
+		err = tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM reactions WHERE `+column+` = ? AND user_id = ?`,
+			targetID, reaction.UserID).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count reactions: %w", err)
+		}
+		if count >= limit {
+			return nil, fmt.Errorf("%w: at most %d reactions per target", ErrReactionLimitReached, limit)
+		}
+	}
+
+	var pageID, commentID sql.NullString
+	if reaction.PageID != "" {
+		pageID = sql.NullString{String: reaction.PageID, Valid: true}
+	}
+	if reaction.CommentID != "" {
+		commentID = sql.NullString{String: reaction.CommentID, Valid: true}
 	}
 
 	query := `
 		INSERT INTO reactions (id, page_id, comment_id, allowed_reaction_id, user_id, created_at)
-		VALUES (?, ?, NULL, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = s.db.ExecContext(ctx, query, reaction.ID, reaction.PageID, reaction.AllowedReactionID,
+	_, err = tx.ExecContext(ctx, query, reaction.ID, pageID, commentID, reaction.AllowedReactionID,
 		reaction.UserID, reaction.CreatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add page reaction: %w", err)
+		return nil, fmt.Errorf("failed to add reaction: %w", err)
 	}
 
-	return reaction, nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &ReactionToggle{Result: ReactionAdded, Reaction: reaction}, nil
+}
+
+// validateAllowedReaction checks that the allowed reaction belongs to the same
+// site as the target and that its reaction_type permits the target kind
+func validateAllowedReaction(ctx context.Context, tx *sql.Tx, column, targetID, allowedReactionID string) error {
+	table, kind := "comments", "comment"
+	if column == "page_id" {
+		table, kind = "pages", "page"
+	}
+
+	var reactionSiteID, reactionType, targetSiteID string
+	err := tx.QueryRowContext(ctx, `
+		SELECT ar.site_id, ar.reaction_type, t.site_id
+		FROM allowed_reactions ar, `+table+` t
+		WHERE ar.id = ? AND t.id = ?
+	`, allowedReactionID, targetID).Scan(&reactionSiteID, &reactionType, &targetSiteID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("allowed reaction or %s not found", kind)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to validate allowed reaction: %w", err)
+	}
+
+	if reactionSiteID != targetSiteID {
+		return ErrReactionSiteMismatch
+	}
+	if reactionType != kind && reactionType != "both" {
+		return fmt.Errorf("%w: %s reaction on a %s", ErrReactionTypeMismatch, reactionType, kind)
+	}
+
+	return nil
 }
 
 // GetUserCommentReaction checks if a user has already reacted to a comment with a specific reaction type
@@ -383,17 +708,27 @@ func (s *ReactionStore) RemoveReaction(ctx context.Context, reactionID string) e
 	return nil
 }
 
-// GetReactionsByComment retrieves all reactions for a comment with details
-func (s *ReactionStore) GetReactionsByComment(ctx context.Context, commentID string) ([]ReactionWithDetails, error) {
+// GetReactionsByComment retrieves a page of a comment's reactions with details,
+// oldest first. A limit of 0 returns every reaction; GetReactionCounts is the
+// cheaper way to show totals.
+func (s *ReactionStore) GetReactionsByComment(ctx context.Context, commentID string, limit, offset int) ([]ReactionWithDetails, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+	if limit == 0 {
+		limit = -1 // SQLite: no limit
+	}
+
 	query := `
 		SELECT r.id, r.page_id, r.comment_id, ar.name, ar.emoji, r.user_id, r.created_at
 		FROM reactions r
 		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 		WHERE r.comment_id = ?
-		ORDER BY r.created_at ASC
+		ORDER BY r.created_at ASC, r.id
+		LIMIT ? OFFSET ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, commentID)
+	rows, err := s.db.QueryContext(ctx, query, commentID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query reactions: %w", err)
 	}
@@ -427,17 +762,27 @@ func (s *ReactionStore) GetReactionsByComment(ctx context.Context, commentID str
 	return reactions, nil
 }
 
-// GetReactionsByPage retrieves all reactions for a page with details
-func (s *ReactionStore) GetReactionsByPage(ctx context.Context, pageID string) ([]ReactionWithDetails, error) {
+// GetReactionsByPage retrieves a page of a page's reactions with details,
+// oldest first. A limit of 0 returns every reaction; GetReactionCounts is the
+// cheaper way to show totals.
+func (s *ReactionStore) GetReactionsByPage(ctx context.Context, pageID string, limit, offset int) ([]ReactionWithDetails, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+	if limit == 0 {
+		limit = -1 // SQLite: no limit
+	}
+
 	query := `
 		SELECT r.id, r.page_id, r.comment_id, ar.name, ar.emoji, r.user_id, r.created_at
 		FROM reactions r
 		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
 		WHERE r.page_id = ?
-		ORDER BY r.created_at ASC
+		ORDER BY r.created_at ASC, r.id
+		LIMIT ? OFFSET ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, pageID)
+	rows, err := s.db.QueryContext(ctx, query, pageID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query reactions: %w", err)
 	}
@@ -471,6 +816,55 @@ func (s *ReactionStore) GetReactionsByPage(ctx context.Context, pageID string) (
 	return reactions, nil
 }
 
+// GetReactionsByUser retrieves all page and comment reactions a user left on a site,
+// newest first. Reactions carry no site_id, so the site scope comes from the
+// allowed reaction they reference.
+func (s *ReactionStore) GetReactionsByUser(ctx context.Context, siteID, userID string) ([]ReactionWithDetails, error) {
+	query := `
+		SELECT r.id, r.page_id, r.comment_id, ar.name, ar.emoji, r.user_id, r.created_at
+		FROM reactions r
+		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+		WHERE ar.site_id = ? AND r.user_id = ?
+		ORDER BY r.created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, siteID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions: %w", err)
+	}
+	defer rows.Close()
+
+	var reactions []ReactionWithDetails
+	for rows.Next() {
+		var reaction ReactionWithDetails
+		var pageID, commentID sql.NullString
+		err := rows.Scan(
+			&reaction.ID, &pageID, &commentID, &reaction.Name, &reaction.Emoji,
+			&reaction.UserID, &reaction.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		if pageID.Valid {
+			reaction.PageID = pageID.String
+		}
+		if commentID.Valid {
+			reaction.CommentID = commentID.String
+		}
+		reactions = append(reactions, reaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reactions: %w", err)
+	}
+
+	if reactions == nil {
+		reactions = []ReactionWithDetails{}
+	}
+
+	return reactions, nil
+}
+
 // GetReactionCounts retrieves aggregated reaction counts for a comment
 func (s *ReactionStore) GetReactionCounts(ctx context.Context, commentID string) ([]ReactionCount, error) {
 	query := `
@@ -509,6 +903,97 @@ func (s *ReactionStore) GetReactionCounts(ctx context.Context, commentID string)
 	return counts, nil
 }
 
+// GetPageCommentReactionsStamp returns how many reactions the comments on a
+// page have and when the newest was added, so cached counts can be validated
+func (s *ReactionStore) GetPageCommentReactionsStamp(ctx context.Context, siteID, pageID string) (int, time.Time, error) {
+	query := `
+		SELECT r.created_at, COUNT(*) OVER ()
+		FROM reactions r
+		JOIN comments c ON r.comment_id = c.id
+		WHERE c.site_id = ? AND c.page_id = ?
+		ORDER BY r.created_at DESC
+		LIMIT 1
+	`
+
+	var latest time.Time
+	var count int
+	err := s.db.QueryRowContext(ctx, query, siteID, pageID).Scan(&latest, &count)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, fmt.Errorf("failed to query reactions stamp: %w", err)
+	}
+
+	return count, latest, nil
+}
+
+// reactionCountBatchSize caps the comment IDs bound into a single
+// GetReactionCountsForComments query, keeping well under SQLite's variable limit
+const reactionCountBatchSize = 500
+
+// GetReactionCountsForComments retrieves aggregated reaction counts for many
+// comments at once, keyed by comment ID. Every requested comment has an entry,
+// empty when it has no reactions.
+func (s *ReactionStore) GetReactionCountsForComments(ctx context.Context, commentIDs []string) (map[string][]ReactionCount, error) {
+	counts := make(map[string][]ReactionCount, len(commentIDs))
+	for _, id := range commentIDs {
+		counts[id] = []ReactionCount{}
+	}
+
+	for start := 0; start < len(commentIDs); start += reactionCountBatchSize {
+		end := start + reactionCountBatchSize
+		if end > len(commentIDs) {
+			end = len(commentIDs)
+		}
+		if err := s.loadReactionCountsForComments(ctx, commentIDs[start:end], counts); err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
+// loadReactionCountsForComments adds the counts for one batch of comment IDs to counts
+func (s *ReactionStore) loadReactionCountsForComments(ctx context.Context, commentIDs []string, counts map[string][]ReactionCount) error {
+	placeholders := make([]string, len(commentIDs))
+	args := make([]interface{}, len(commentIDs))
+	for i, id := range commentIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT r.comment_id, ar.name, ar.emoji, COUNT(*) as count
+		FROM reactions r
+		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+		WHERE r.comment_id IN (%s)
+		GROUP BY r.comment_id, ar.name, ar.emoji
+		ORDER BY r.comment_id, count DESC, ar.name ASC
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query reaction counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID string
+		var count ReactionCount
+		if err := rows.Scan(&commentID, &count.Name, &count.Emoji, &count.Count); err != nil {
+			return fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		counts[commentID] = append(counts[commentID], count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating reaction counts: %w", err)
+	}
+
+	return nil
+}
+
 // GetPageReactionCounts retrieves aggregated reaction counts for a page
 func (s *ReactionStore) GetPageReactionCounts(ctx context.Context, pageID string) ([]ReactionCount, error) {
 	query := `
@@ -546,3 +1031,55 @@ func (s *ReactionStore) GetPageReactionCounts(ctx context.Context, pageID string
 
 	return counts, nil
 }
+
+// GetReactionCountsForUser retrieves aggregated reaction counts for a comment,
+// flagging the reactions left by userID
+func (s *ReactionStore) GetReactionCountsForUser(ctx context.Context, commentID, userID string) ([]ReactionCountWithMine, error) {
+	return s.getReactionCountsForUser(ctx, "comment_id", commentID, userID)
+}
+
+// GetPageReactionCountsForUser retrieves aggregated reaction counts for a page,
+// flagging the reactions left by userID
+func (s *ReactionStore) GetPageReactionCountsForUser(ctx context.Context, pageID, userID string) ([]ReactionCountWithMine, error) {
+	return s.getReactionCountsForUser(ctx, "page_id", pageID, userID)
+}
+
+// getReactionCountsForUser aggregates reactions on the target identified by
+// column ("comment_id" or "page_id") in a single query
+func (s *ReactionStore) getReactionCountsForUser(ctx context.Context, column, targetID, userID string) ([]ReactionCountWithMine, error) {
+	query := `
+		SELECT ar.id, ar.name, ar.emoji, COUNT(*) as count,
+			MAX(CASE WHEN r.user_id = ? THEN 1 ELSE 0 END) as mine
+		FROM reactions r
+		JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+		WHERE r.` + column + ` = ?
+		GROUP BY ar.id, ar.name, ar.emoji
+		ORDER BY count DESC, ar.name ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reaction counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []ReactionCountWithMine
+	for rows.Next() {
+		var count ReactionCountWithMine
+		err := rows.Scan(&count.AllowedReactionID, &count.Name, &count.Emoji, &count.Count, &count.Mine)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reaction counts: %w", err)
+	}
+
+	if counts == nil {
+		counts = []ReactionCountWithMine{}
+	}
+
+	return counts, nil
+}
@@ -3,9 +3,14 @@ package models
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
 
 func setupTestDB(t *testing.T) *sql.DB {
@@ -13,6 +18,8 @@ func setupTestDB(t *testing.T) *sql.DB {
 	if err != nil {
 		t.Fatalf("Failed to open database: %v", err)
 	}
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
 
 	// Enable foreign key constraints
 	_, err = db.Exec("PRAGMA foreign_keys = ON")
@@ -20,73 +27,15 @@ func setupTestDB(t *testing.T) *sql.DB {
 		t.Fatalf("Failed to enable foreign keys: %v", err)
 	}
 
-	// Create schema
-	schema := `
-	CREATE TABLE sites (
-		id TEXT PRIMARY KEY,
-		owner_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		domain TEXT,
-		description TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE pages (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		path TEXT NOT NULL,
-		title TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE comments (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		page_id TEXT NOT NULL,
-		author TEXT NOT NULL,
-		text TEXT NOT NULL,
-		parent_id TEXT,
-		status TEXT DEFAULT 'pending',
-		moderated_by TEXT,
-		moderated_at TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE allowed_reactions (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		emoji TEXT NOT NULL,
-		reaction_type TEXT NOT NULL DEFAULT 'comment' CHECK(reaction_type IN ('page', 'comment', 'both')),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
-		UNIQUE(site_id, name, reaction_type)
-	);
-
-	CREATE TABLE reactions (
-		id TEXT PRIMARY KEY,
-		page_id TEXT,
-		comment_id TEXT,
-		allowed_reaction_id TEXT NOT NULL,
-		user_id TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE,
-		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
-		FOREIGN KEY (allowed_reaction_id) REFERENCES allowed_reactions(id) ON DELETE CASCADE,
-		CHECK ((page_id IS NOT NULL AND comment_id IS NULL) OR (page_id IS NULL AND comment_id IS NOT NULL)),
-		UNIQUE(page_id, comment_id, allowed_reaction_id, user_id)
-	);
-	`
-
-	if _, err := db.Exec(schema); err != nil {
+	if err := comments.ApplySchema(db); err != nil {
 		t.Fatalf("Failed to create schema: %v", err)
 	}
 
+	// The owner of the tests' sites
+	if _, err := db.Exec("INSERT INTO admin_users (id, email, name, auth0_sub) VALUES ('user-1', 'user-1@example.com', 'User', 'auth0|user-1')"); err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+
 	return db
 }
 
@@ -123,6 +72,96 @@ func TestAllowedReactionStore_Create(t *testing.T) {
 	}
 }
 
+func TestAllowedReactionStore_Create_Cap(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)",
+		"site-1", "user-1", "Test Site")
+	if err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+	settings := DefaultSiteSettings("site-1")
+	settings.MaxAllowedReactions = 2
+	if err := NewSiteSettingsStore(db).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+
+	store := NewAllowedReactionStore(db)
+	if _, err := store.Create(ctx, "site-1", "thumbs_up", "👍", "comment"); err != nil {
+		t.Fatalf("Failed to create reaction: %v", err)
+	}
+	if _, err := store.Create(ctx, "site-1", "heart", "❤️", "both"); err != nil {
+		t.Fatalf("Failed to create reaction: %v", err)
+	}
+
+	// The "both" reaction fills the comment slots
+	_, err = store.Create(ctx, "site-1", "laugh", "😂", "comment")
+	if !errors.Is(err, ErrAllowedReactionLimitReached) {
+		t.Errorf("Expected ErrAllowedReactionLimitReached, got %v", err)
+	}
+	// ...but pages still have room for one more
+	if _, err := store.Create(ctx, "site-1", "clap", "👏", "page"); err != nil {
+		t.Errorf("Expected a page reaction to fit under the cap, got %v", err)
+	}
+	_, err = store.Create(ctx, "site-1", "fire", "🔥", "page")
+	if !errors.Is(err, ErrAllowedReactionLimitReached) {
+		t.Errorf("Expected ErrAllowedReactionLimitReached for pages, got %v", err)
+	}
+
+	reactions, err := store.GetBySite(ctx, "site-1")
+	if err != nil || len(reactions) != 3 {
+		t.Errorf("Expected 3 reactions to be stored, got %d (%v)", len(reactions), err)
+	}
+}
+
+func TestAllowedReactionStore_DuplicateEmoji(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, site := range []string{"site-1", "site-2"} {
+		if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", site, "user-1", "Test Site"); err != nil {
+			t.Fatalf("Failed to create test site: %v", err)
+		}
+	}
+
+	store := NewAllowedReactionStore(db)
+	if _, err := store.Create(ctx, "site-1", "thumbs_up", "👍", "comment"); err != nil {
+		t.Fatalf("Failed to create reaction: %v", err)
+	}
+
+	_, err := store.Create(ctx, "site-1", "like", "👍", "comment")
+	if !errors.Is(err, ErrDuplicateAllowedReactionEmoji) {
+		t.Errorf("Expected ErrDuplicateAllowedReactionEmoji, got %v", err)
+	}
+	_, err = store.Create(ctx, "site-1", "like", "👍", "both")
+	if !errors.Is(err, ErrDuplicateAllowedReactionEmoji) {
+		t.Errorf("Expected a both reaction to clash with the comment reaction, got %v", err)
+	}
+
+	// The same emoji is fine for pages and on other sites
+	if _, err := store.Create(ctx, "site-1", "page_like", "👍", "page"); err != nil {
+		t.Errorf("Expected the emoji to be allowed for pages, got %v", err)
+	}
+	if _, err := store.Create(ctx, "site-2", "thumbs_up", "👍", "comment"); err != nil {
+		t.Errorf("Expected the emoji to be allowed on another site, got %v", err)
+	}
+
+	// Updates can't introduce a duplicate either, but may keep their own emoji
+	heart, err := store.Create(ctx, "site-1", "heart", "❤️", "comment")
+	if err != nil {
+		t.Fatalf("Failed to create reaction: %v", err)
+	}
+	if err := store.Update(ctx, heart.ID, "heart", "👍", "comment"); !errors.Is(err, ErrDuplicateAllowedReactionEmoji) {
+		t.Errorf("Expected ErrDuplicateAllowedReactionEmoji on update, got %v", err)
+	}
+	if err := store.Update(ctx, heart.ID, "love", "❤️", "comment"); err != nil {
+		t.Errorf("Expected renaming to keep its emoji, got %v", err)
+	}
+}
+
 func TestAllowedReactionStore_GetBySite(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -223,8 +262,8 @@ func TestReactionStore_AddReaction(t *testing.T) {
 		t.Fatalf("Failed to create test site: %v", err)
 	}
 
-	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, text) VALUES (?, ?, ?, ?, ?)",
-		"comment-1", "site-1", "page-1", "John", "Test comment")
+	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment")
 	if err != nil {
 		t.Fatalf("Failed to create test comment: %v", err)
 	}
@@ -235,10 +274,14 @@ func TestReactionStore_AddReaction(t *testing.T) {
 	reactionStore := NewReactionStore(db)
 
 	// Add a reaction
-	reaction, err := reactionStore.AddReaction(context.Background(), "comment-1", allowed.ID, "user-123")
+	toggle, err := reactionStore.AddReaction(context.Background(), "comment-1", allowed.ID, "user-123")
 	if err != nil {
 		t.Fatalf("Failed to add reaction: %v", err)
 	}
+	if toggle.Result != ReactionAdded {
+		t.Errorf("Expected the reaction to be added, got %q", toggle.Result)
+	}
+	reaction := toggle.Reaction
 
 	if reaction == nil {
 		t.Fatal("Expected reaction to be created")
@@ -262,8 +305,8 @@ func TestReactionStore_AddReaction_Toggle(t *testing.T) {
 		t.Fatalf("Failed to create test site: %v", err)
 	}
 
-	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, text) VALUES (?, ?, ?, ?, ?)",
-		"comment-1", "site-1", "page-1", "John", "Test comment")
+	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment")
 	if err != nil {
 		t.Fatalf("Failed to create test comment: %v", err)
 	}
@@ -278,7 +321,7 @@ func TestReactionStore_AddReaction_Toggle(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to add reaction: %v", err)
 	}
-	if reaction1 == nil {
+	if reaction1.Reaction == nil {
 		t.Fatal("Expected reaction to be created")
 	}
 
@@ -287,12 +330,12 @@ func TestReactionStore_AddReaction_Toggle(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to toggle reaction: %v", err)
 	}
-	if reaction2 != nil {
-		t.Error("Expected reaction to be nil (toggled off)")
+	if !reaction2.Removed() || reaction2.Reaction != nil {
+		t.Error("Expected reaction to be removed (toggled off)")
 	}
 
 	// Verify reaction was removed
-	reactions, _ := reactionStore.GetReactionsByComment(context.Background(), "comment-1")
+	reactions, _ := reactionStore.GetReactionsByComment(context.Background(), "comment-1", 0, 0)
 	if len(reactions) != 0 {
 		t.Errorf("Expected 0 reactions after toggle, got %d", len(reactions))
 	}
@@ -309,8 +352,8 @@ func TestReactionStore_GetReactionCounts(t *testing.T) {
 		t.Fatalf("Failed to create test site: %v", err)
 	}
 
-	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, text) VALUES (?, ?, ?, ?, ?)",
-		"comment-1", "site-1", "page-1", "John", "Test comment")
+	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment")
 	if err != nil {
 		t.Fatalf("Failed to create test comment: %v", err)
 	}
@@ -354,6 +397,79 @@ func TestReactionStore_GetReactionCounts(t *testing.T) {
 	}
 }
 
+func TestReactionStore_GetReactionCountsForComments(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", "site-1", "user-1", "Test Site"); err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+	for _, id := range []string{"comment-1", "comment-2", "comment-3"} {
+		if _, err := db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+			id, "site-1", "page-1", "John", "john", "Test comment"); err != nil {
+			t.Fatalf("Failed to create test comment: %v", err)
+		}
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	thumbsUp, _ := allowedStore.Create(ctx, "site-1", "thumbs_up", "👍", "comment")
+	heart, _ := allowedStore.Create(ctx, "site-1", "heart", "❤️", "comment")
+	laugh, _ := allowedStore.Create(ctx, "site-1", "laugh", "😂", "comment")
+
+	reactionStore := NewReactionStore(db)
+
+	// comment-1: 2 thumbs_up, 1 heart
+	reactionStore.AddReaction(ctx, "comment-1", thumbsUp.ID, "user-1")
+	reactionStore.AddReaction(ctx, "comment-1", thumbsUp.ID, "user-2")
+	reactionStore.AddReaction(ctx, "comment-1", heart.ID, "user-3")
+	// comment-2: 3 laugh, 1 thumbs_up
+	reactionStore.AddReaction(ctx, "comment-2", laugh.ID, "user-1")
+	reactionStore.AddReaction(ctx, "comment-2", laugh.ID, "user-2")
+	reactionStore.AddReaction(ctx, "comment-2", laugh.ID, "user-3")
+	reactionStore.AddReaction(ctx, "comment-2", thumbsUp.ID, "user-4")
+
+	counts, err := reactionStore.GetReactionCountsForComments(ctx, []string{"comment-1", "comment-2", "comment-3"})
+	if err != nil {
+		t.Fatalf("GetReactionCountsForComments failed: %v", err)
+	}
+
+	want := map[string][]ReactionCount{
+		"comment-1": {{Name: "thumbs_up", Emoji: "👍", Count: 2}, {Name: "heart", Emoji: "❤️", Count: 1}},
+		"comment-2": {{Name: "laugh", Emoji: "😂", Count: 3}, {Name: "thumbs_up", Emoji: "👍", Count: 1}},
+		"comment-3": {},
+	}
+	if len(counts) != len(want) {
+		t.Fatalf("Expected counts for %d comments, got %d", len(want), len(counts))
+	}
+	for commentID, expected := range want {
+		got, ok := counts[commentID]
+		if !ok {
+			t.Errorf("Missing counts for %s", commentID)
+			continue
+		}
+		if len(got) != len(expected) {
+			t.Errorf("%s: expected %d reaction types, got %d", commentID, len(expected), len(got))
+			continue
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("%s[%d]: expected %+v, got %+v", commentID, i, expected[i], got[i])
+			}
+		}
+	}
+
+	t.Run("empty slice", func(t *testing.T) {
+		counts, err := reactionStore.GetReactionCountsForComments(ctx, []string{})
+		if err != nil {
+			t.Fatalf("GetReactionCountsForComments failed: %v", err)
+		}
+		if len(counts) != 0 {
+			t.Errorf("Expected no counts, got %d", len(counts))
+		}
+	})
+}
+
 func TestReactionStore_CascadeDelete(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -365,8 +481,8 @@ func TestReactionStore_CascadeDelete(t *testing.T) {
 		t.Fatalf("Failed to create test site: %v", err)
 	}
 
-	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, text) VALUES (?, ?, ?, ?, ?)",
-		"comment-1", "site-1", "page-1", "John", "Test comment")
+	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment")
 	if err != nil {
 		t.Fatalf("Failed to create test comment: %v", err)
 	}
@@ -384,7 +500,7 @@ func TestReactionStore_CascadeDelete(t *testing.T) {
 	}
 
 	// Verify reactions were deleted
-	reactions, _ := reactionStore.GetReactionsByComment(context.Background(), "comment-1")
+	reactions, _ := reactionStore.GetReactionsByComment(context.Background(), "comment-1", 0, 0)
 	if len(reactions) != 0 {
 		t.Errorf("Expected reactions to be cascade deleted, found %d", len(reactions))
 	}
@@ -413,10 +529,11 @@ allowed, _ := allowedStore.Create(context.Background(), "site-1", "thumbs_up", "
 reactionStore := NewReactionStore(db)
 
 // Add a page reaction
-reaction, err := reactionStore.AddPageReaction(context.Background(), "page-1", allowed.ID, "user-123")
+toggle, err := reactionStore.AddPageReaction(context.Background(), "page-1", allowed.ID, "user-123")
 if err != nil {
 t.Fatalf("Failed to add page reaction: %v", err)
 }
+reaction := toggle.Reaction
 
 if reaction == nil {
 t.Fatal("Expected reaction to be created")
@@ -497,7 +614,7 @@ reaction1, err := reactionStore.AddPageReaction(context.Background(), "page-1",
 if err != nil {
 t.Fatalf("Failed to add page reaction: %v", err)
 }
-if reaction1 == nil {
+if reaction1.Reaction == nil {
 t.Fatal("Expected reaction to be created")
 }
 
@@ -506,12 +623,12 @@ reaction2, err := reactionStore.AddPageReaction(context.Background(), "page-1",
 if err != nil {
 t.Fatalf("Failed to toggle page reaction: %v", err)
 }
-if reaction2 != nil {
-t.Error("Expected reaction to be nil (toggled off)")
+if !reaction2.Removed() || reaction2.Reaction != nil {
+t.Error("Expected reaction to be removed (toggled off)")
 }
 
 // Verify reaction was removed
-reactions, _ := reactionStore.GetReactionsByPage(context.Background(), "page-1")
+reactions, _ := reactionStore.GetReactionsByPage(context.Background(), "page-1", 0, 0)
 if len(reactions) != 0 {
 t.Errorf("Expected 0 reactions after toggle, got %d", len(reactions))
 }
@@ -572,3 +689,485 @@ if counts[1].Count != 1 {
 t.Errorf("Expected heart count to be 1, got %d", counts[1].Count)
 }
 }
+
+func TestReactionStore_AddReaction_SingleChoiceSwap(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)",
+		"site-1", "user-1", "Test Site")
+	if err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+
+	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment")
+	if err != nil {
+		t.Fatalf("Failed to create test comment: %v", err)
+	}
+
+	settings := DefaultSiteSettings("site-1")
+	settings.MaxReactionsPerTarget = 1
+	if err := NewSiteSettingsStore(db).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	up, _ := allowedStore.Create(ctx, "site-1", "thumbs_up", "👍", "comment")
+	down, _ := allowedStore.Create(ctx, "site-1", "thumbs_down", "👎", "comment")
+
+	reactionStore := NewReactionStore(db)
+
+	if _, err := reactionStore.AddReaction(ctx, "comment-1", up.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add reaction: %v", err)
+	}
+
+	// Another user's reaction must not be affected by the swap
+	if _, err := reactionStore.AddReaction(ctx, "comment-1", up.ID, "user-456"); err != nil {
+		t.Fatalf("Failed to add reaction for second user: %v", err)
+	}
+
+	// Picking a different reaction replaces the first one
+	swapped, err := reactionStore.AddReaction(ctx, "comment-1", down.ID, "user-123")
+	if err != nil {
+		t.Fatalf("Failed to swap reaction: %v", err)
+	}
+	if swapped.Reaction == nil || swapped.Reaction.AllowedReactionID != down.ID {
+		t.Fatalf("Expected swapped reaction with %s, got %+v", down.ID, swapped)
+	}
+
+	if _, err := reactionStore.GetUserCommentReaction(ctx, "comment-1", up.ID, "user-123"); err == nil {
+		t.Error("Expected previous reaction to be removed after swap")
+	}
+	if _, err := reactionStore.GetUserCommentReaction(ctx, "comment-1", up.ID, "user-456"); err != nil {
+		t.Errorf("Expected other user's reaction to remain: %v", err)
+	}
+
+	reactions, _ := reactionStore.GetReactionsByComment(ctx, "comment-1", 0, 0)
+	if len(reactions) != 2 {
+		t.Errorf("Expected 2 reactions after swap, got %d", len(reactions))
+	}
+
+	// Re-selecting the current reaction still toggles it off
+	toggled, err := reactionStore.AddReaction(ctx, "comment-1", down.ID, "user-123")
+	if err != nil {
+		t.Fatalf("Failed to toggle reaction: %v", err)
+	}
+	if !toggled.Removed() {
+		t.Error("Expected reaction to be removed (toggled off)")
+	}
+}
+
+func TestReactionStore_AddPageReaction_Limit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)",
+		"site-1", "user-1", "Test Site")
+	if err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+
+	_, err = db.Exec("INSERT INTO pages (id, site_id, path) VALUES (?, ?, ?)",
+		"page-1", "site-1", "/test")
+	if err != nil {
+		t.Fatalf("Failed to create test page: %v", err)
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	like, _ := allowedStore.Create(ctx, "site-1", "like", "👍", "page")
+	love, _ := allowedStore.Create(ctx, "site-1", "love", "❤️", "page")
+	wow, _ := allowedStore.Create(ctx, "site-1", "wow", "😮", "page")
+
+	settings := DefaultSiteSettings("site-1")
+	settings.MaxReactionsPerTarget = 2
+	if err := NewSiteSettingsStore(db).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	reactionStore := NewReactionStore(db)
+	if _, err := reactionStore.AddPageReaction(ctx, "page-1", like.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add first reaction: %v", err)
+	}
+	if _, err := reactionStore.AddPageReaction(ctx, "page-1", love.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add second reaction: %v", err)
+	}
+
+	_, err = reactionStore.AddPageReaction(ctx, "page-1", wow.ID, "user-123")
+	if !errors.Is(err, ErrReactionLimitReached) {
+		t.Errorf("Expected ErrReactionLimitReached, got %v", err)
+	}
+
+	// Swap semantics on a page when the limit is 1
+	settings.MaxReactionsPerTarget = 1
+	if err := NewSiteSettingsStore(db).Upsert(ctx, settings); err != nil {
+		t.Fatalf("Failed to save site settings: %v", err)
+	}
+
+	toggle, err := reactionStore.AddPageReaction(ctx, "page-1", wow.ID, "user-123")
+	if err != nil {
+		t.Fatalf("Failed to swap page reaction: %v", err)
+	}
+	if toggle.Reaction == nil || toggle.Reaction.PageID != "page-1" {
+		t.Fatalf("Expected page reaction to be returned, got %+v", toggle.Reaction)
+	}
+
+	reactions, _ := reactionStore.GetReactionsByPage(ctx, "page-1", 0, 0)
+	if len(reactions) != 1 || reactions[0].Name != "wow" {
+		t.Errorf("Expected only the 'wow' reaction to remain, got %+v", reactions)
+	}
+}
+
+func TestReactionStore_GetReactionsByUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, site := range []string{"site-1", "site-2"} {
+		_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", site, "user-1", site)
+		if err != nil {
+			t.Fatalf("Failed to create test site: %v", err)
+		}
+		_, err = db.Exec("INSERT INTO pages (id, site_id, path) VALUES (?, ?, ?)", "page-"+site, site, "/test")
+		if err != nil {
+			t.Fatalf("Failed to create test page: %v", err)
+		}
+		_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+			"comment-"+site, site, "page-"+site, "John", "john", "Test comment")
+		if err != nil {
+			t.Fatalf("Failed to create test comment: %v", err)
+		}
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	heart, _ := allowedStore.Create(ctx, "site-1", "heart", "❤️", "both")
+	otherSite, _ := allowedStore.Create(ctx, "site-2", "heart", "❤️", "both")
+
+	reactionStore := NewReactionStore(db)
+	if _, err := reactionStore.AddPageReaction(ctx, "page-site-1", heart.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add page reaction: %v", err)
+	}
+	if _, err := reactionStore.AddReaction(ctx, "comment-site-1", heart.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add comment reaction: %v", err)
+	}
+	if _, err := reactionStore.AddReaction(ctx, "comment-site-1", heart.ID, "user-456"); err != nil {
+		t.Fatalf("Failed to add reaction for other user: %v", err)
+	}
+	if _, err := reactionStore.AddReaction(ctx, "comment-site-2", otherSite.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add reaction on other site: %v", err)
+	}
+
+	reactions, err := reactionStore.GetReactionsByUser(ctx, "site-1", "user-123")
+	if err != nil {
+		t.Fatalf("GetReactionsByUser failed: %v", err)
+	}
+	if len(reactions) != 2 {
+		t.Fatalf("Expected 2 reactions on site-1, got %d", len(reactions))
+	}
+
+	// Newest first: the comment reaction was added after the page reaction
+	if reactions[0].CommentID != "comment-site-1" || reactions[0].PageID != "" {
+		t.Errorf("Expected comment reaction first, got %+v", reactions[0])
+	}
+	if reactions[1].PageID != "page-site-1" || reactions[1].CommentID != "" {
+		t.Errorf("Expected page reaction second, got %+v", reactions[1])
+	}
+	for _, reaction := range reactions {
+		if reaction.UserID != "user-123" || reaction.Emoji != "❤️" {
+			t.Errorf("Unexpected reaction %+v", reaction)
+		}
+	}
+
+	empty, err := reactionStore.GetReactionsByUser(ctx, "site-1", "nobody")
+	if err != nil {
+		t.Fatalf("GetReactionsByUser failed: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("Expected no reactions, got %d", len(empty))
+	}
+}
+
+func TestReactionStore_GetReactionCountsForUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)",
+		"site-1", "user-1", "Test Site")
+	if err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO pages (id, site_id, path) VALUES (?, ?, ?)",
+		"page-1", "site-1", "/test")
+	if err != nil {
+		t.Fatalf("Failed to create test page: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment")
+	if err != nil {
+		t.Fatalf("Failed to create test comment: %v", err)
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	thumbsUp, _ := allowedStore.Create(ctx, "site-1", "thumbs_up", "👍", "both")
+	heart, _ := allowedStore.Create(ctx, "site-1", "heart", "❤️", "both")
+
+	reactionStore := NewReactionStore(db)
+	reactionStore.AddReaction(ctx, "comment-1", thumbsUp.ID, "user-1")
+	reactionStore.AddReaction(ctx, "comment-1", thumbsUp.ID, "user-2")
+	reactionStore.AddReaction(ctx, "comment-1", heart.ID, "user-2")
+	reactionStore.AddPageReaction(ctx, "page-1", heart.ID, "user-1")
+
+	counts, err := reactionStore.GetReactionCountsForUser(ctx, "comment-1", "user-1")
+	if err != nil {
+		t.Fatalf("GetReactionCountsForUser failed: %v", err)
+	}
+	if len(counts) != 2 {
+		t.Fatalf("Expected 2 reaction counts, got %d", len(counts))
+	}
+	if counts[0].AllowedReactionID != thumbsUp.ID || counts[0].Count != 2 || !counts[0].Mine {
+		t.Errorf("Expected thumbs_up with count 2 marked mine, got %+v", counts[0])
+	}
+	if counts[1].AllowedReactionID != heart.ID || counts[1].Count != 1 || counts[1].Mine {
+		t.Errorf("Expected heart with count 1 not marked mine, got %+v", counts[1])
+	}
+
+	pageCounts, err := reactionStore.GetPageReactionCountsForUser(ctx, "page-1", "user-2")
+	if err != nil {
+		t.Fatalf("GetPageReactionCountsForUser failed: %v", err)
+	}
+	if len(pageCounts) != 1 || pageCounts[0].Name != "heart" || pageCounts[0].Mine {
+		t.Errorf("Expected one heart page count not marked mine, got %+v", pageCounts)
+	}
+}
+
+func TestReactionStore_RejectsOtherSitesReaction(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"site-1", "site-2"} {
+		if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", id, "user-1", "Test Site"); err != nil {
+			t.Fatalf("Failed to create test site: %v", err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment"); err != nil {
+		t.Fatalf("Failed to create test comment: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO pages (id, site_id, path, title) VALUES (?, ?, ?, ?)",
+		"page-1", "site-1", "/test", "Test Page"); err != nil {
+		t.Fatalf("Failed to create test page: %v", err)
+	}
+
+	otherSite, err := NewAllowedReactionStore(db).Create(ctx, "site-2", "heart", "❤️", "both")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+
+	reactionStore := NewReactionStore(db)
+	if _, err := reactionStore.AddReaction(ctx, "comment-1", otherSite.ID, "user-1"); !errors.Is(err, ErrReactionSiteMismatch) {
+		t.Errorf("Expected ErrReactionSiteMismatch on comment, got %v", err)
+	}
+	if _, err := reactionStore.AddPageReaction(ctx, "page-1", otherSite.ID, "user-1"); !errors.Is(err, ErrReactionSiteMismatch) {
+		t.Errorf("Expected ErrReactionSiteMismatch on page, got %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM reactions").Scan(&count); err != nil {
+		t.Fatalf("Failed to count reactions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no reactions to be stored, got %d", count)
+	}
+}
+
+func TestReactionStore_RejectsWrongReactionType(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", "site-1", "user-1", "Test Site"); err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES (?, ?, ?, ?, ?, ?)",
+		"comment-1", "site-1", "page-1", "John", "john", "Test comment"); err != nil {
+		t.Fatalf("Failed to create test comment: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO pages (id, site_id, path, title) VALUES (?, ?, ?, ?)",
+		"page-1", "site-1", "/test", "Test Page"); err != nil {
+		t.Fatalf("Failed to create test page: %v", err)
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	pageOnly, _ := allowedStore.Create(ctx, "site-1", "clap", "👏", "page")
+	commentOnly, _ := allowedStore.Create(ctx, "site-1", "heart", "❤️", "comment")
+	both, _ := allowedStore.Create(ctx, "site-1", "celebrate", "🎉", "both")
+
+	reactionStore := NewReactionStore(db)
+	if _, err := reactionStore.AddReaction(ctx, "comment-1", pageOnly.ID, "user-1"); !errors.Is(err, ErrReactionTypeMismatch) {
+		t.Errorf("Expected ErrReactionTypeMismatch for page reaction on comment, got %v", err)
+	}
+	if _, err := reactionStore.AddPageReaction(ctx, "page-1", commentOnly.ID, "user-1"); !errors.Is(err, ErrReactionTypeMismatch) {
+		t.Errorf("Expected ErrReactionTypeMismatch for comment reaction on page, got %v", err)
+	}
+
+	// Reactions allowed on both kinds work everywhere
+	if _, err := reactionStore.AddReaction(ctx, "comment-1", both.ID, "user-1"); err != nil {
+		t.Errorf("Expected 'both' reaction on comment to succeed, got %v", err)
+	}
+	if _, err := reactionStore.AddPageReaction(ctx, "page-1", both.ID, "user-1"); err != nil {
+		t.Errorf("Expected 'both' reaction on page to succeed, got %v", err)
+	}
+}
+
+func TestReactionStore_GetReactions_Pagination(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		`INSERT INTO sites (id, owner_id, name) VALUES ('site-1', 'user-1', 'Test Site')`,
+		`INSERT INTO pages (id, site_id, path) VALUES ('page-1', 'site-1', '/test')`,
+		`INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES ('comment-1', 'site-1', 'page-1', 'John', 'john', 'Viral')`,
+		`INSERT INTO allowed_reactions (id, site_id, name, emoji, reaction_type) VALUES ('heart', 'site-1', 'heart', '❤️', 'both')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed data: %v", err)
+		}
+	}
+
+	// Five reactions on each target, inserted newest first so the order has to
+	// come from created_at
+	base := time.Now().Add(-time.Hour)
+	for i := 4; i >= 0; i-- {
+		at := base.Add(time.Duration(i) * time.Minute)
+		user := fmt.Sprintf("user-%d", i)
+		if _, err := db.Exec(`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id, created_at) VALUES (?, 'comment-1', 'heart', ?, ?)`,
+			"c-"+user, user, at); err != nil {
+			t.Fatalf("Failed to add comment reaction: %v", err)
+		}
+		if _, err := db.Exec(`INSERT INTO reactions (id, page_id, allowed_reaction_id, user_id, created_at) VALUES (?, 'page-1', 'heart', ?, ?)`,
+			"p-"+user, user, at); err != nil {
+			t.Fatalf("Failed to add page reaction: %v", err)
+		}
+	}
+
+	reactionStore := NewReactionStore(db)
+	list := map[string]func(limit, offset int) ([]ReactionWithDetails, error){
+		"comment": func(limit, offset int) ([]ReactionWithDetails, error) {
+			return reactionStore.GetReactionsByComment(ctx, "comment-1", limit, offset)
+		},
+		"page": func(limit, offset int) ([]ReactionWithDetails, error) {
+			return reactionStore.GetReactionsByPage(ctx, "page-1", limit, offset)
+		},
+	}
+
+	for target, get := range list {
+		t.Run(target, func(t *testing.T) {
+			users := func(limit, offset int) []string {
+				t.Helper()
+				reactions, err := get(limit, offset)
+				if err != nil {
+					t.Fatalf("Failed to list reactions: %v", err)
+				}
+				var ids []string
+				for _, r := range reactions {
+					ids = append(ids, r.UserID)
+				}
+				return ids
+			}
+
+			if got := users(2, 0); !reflect.DeepEqual(got, []string{"user-0", "user-1"}) {
+				t.Errorf("First page: got %v", got)
+			}
+			if got := users(2, 2); !reflect.DeepEqual(got, []string{"user-2", "user-3"}) {
+				t.Errorf("Second page: got %v", got)
+			}
+			if got := users(2, 4); !reflect.DeepEqual(got, []string{"user-4"}) {
+				t.Errorf("Last page: got %v", got)
+			}
+			if got := users(2, 6); len(got) != 0 {
+				t.Errorf("Expected nothing past the end, got %v", got)
+			}
+			if got := users(0, 0); len(got) != 5 {
+				t.Errorf("Expected limit 0 to return all 5 reactions, got %d", len(got))
+			}
+			if _, err := get(-1, 0); err == nil {
+				t.Error("Expected an error for a negative limit")
+			}
+		})
+	}
+}
+
+func TestAllowedReactionStore_ChangeType(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		`INSERT INTO sites (id, owner_id, name) VALUES ('site-1', 'user-1', 'Test Site')`,
+		`INSERT INTO pages (id, site_id, path) VALUES ('page-1', 'site-1', '/test')`,
+		`INSERT INTO comments (id, site_id, page_id, author, author_id, text) VALUES ('comment-1', 'site-1', 'page-1', 'John', 'john', 'Test comment')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed data: %v", err)
+		}
+	}
+
+	allowedStore := NewAllowedReactionStore(db)
+	reactionStore := NewReactionStore(db)
+	heart, _ := allowedStore.Create(ctx, "site-1", "heart", "❤️", "comment")
+	if _, err := reactionStore.AddReaction(ctx, "comment-1", heart.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add comment reaction: %v", err)
+	}
+
+	// Widening keeps the comment reaction
+	removed, err := allowedStore.ChangeType(ctx, heart.ID, "both")
+	if err != nil {
+		t.Fatalf("Failed to widen reaction type: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("Expected no reactions removed when widening, got %d", removed)
+	}
+	if _, err := reactionStore.AddPageReaction(ctx, "page-1", heart.ID, "user-123"); err != nil {
+		t.Fatalf("Failed to add page reaction: %v", err)
+	}
+
+	// Narrowing to page drops the comment reaction only
+	removed, err = allowedStore.ChangeType(ctx, heart.ID, "page")
+	if err != nil {
+		t.Fatalf("Failed to narrow reaction type: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 reaction removed when narrowing, got %d", removed)
+	}
+	if reactions, _ := reactionStore.GetReactionsByComment(ctx, "comment-1", 0, 0); len(reactions) != 0 {
+		t.Errorf("Expected no comment reactions left, got %d", len(reactions))
+	}
+	if reactions, _ := reactionStore.GetReactionsByPage(ctx, "page-1", 0, 0); len(reactions) != 1 {
+		t.Errorf("Expected the page reaction to remain, got %d", len(reactions))
+	}
+	updated, _ := allowedStore.GetByID(ctx, heart.ID)
+	if updated.ReactionType != "page" {
+		t.Errorf("Expected reaction type page, got %s", updated.ReactionType)
+	}
+
+	// An invalid type changes nothing
+	if _, err := allowedStore.ChangeType(ctx, heart.ID, "everything"); !errors.Is(err, ErrInvalidReactionType) {
+		t.Errorf("Expected ErrInvalidReactionType, got %v", err)
+	}
+	if reactions, _ := reactionStore.GetReactionsByPage(ctx, "page-1", 0, 0); len(reactions) != 1 {
+		t.Errorf("Expected the page reaction to survive an invalid change, got %d", len(reactions))
+	}
+
+	// Update prunes the same way instead of leaving dangling reactions
+	if err := allowedStore.Update(ctx, heart.ID, "heart", "❤️", "comment"); err != nil {
+		t.Fatalf("Failed to update reaction: %v", err)
+	}
+	if reactions, _ := reactionStore.GetReactionsByPage(ctx, "page-1", 0, 0); len(reactions) != 0 {
+		t.Errorf("Expected Update to remove the page reaction, got %d", len(reactions))
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Reputation changes applied when one of a user's comments is moderated
+const (
+	ReputationApprovedDelta = 1
+	ReputationRejectedDelta = -3
+)
+
+// MinReputationScore is the lowest score a user can reach, so a burst of
+// rejections doesn't bury an account beyond recovery
+const MinReputationScore = -20
+
+// defaultLeaderboardLimit is the number of users returned when no limit is given
+const defaultLeaderboardLimit = 10
+
+// ReputationStore adjusts per-site user reputation scores
+type ReputationStore struct {
+	db *sql.DB
+}
+
+// NewReputationStore creates a new reputation store
+func NewReputationStore(db *sql.DB) *ReputationStore {
+	return &ReputationStore{db: db}
+}
+
+// ReputationDelta returns the reputation a comment with the given status earns
+// its author. Statuses other than approved and rejected are worth nothing.
+func ReputationDelta(status string) int {
+	switch status {
+	case "approved":
+		return ReputationApprovedDelta
+	case "rejected":
+		return ReputationRejectedDelta
+	default:
+		return 0
+	}
+}
+
+// Adjust adds delta to a user's score on a site, never going below
+// MinReputationScore. Unknown users are ignored.
+func (s *ReputationStore) Adjust(ctx context.Context, siteID, userID string, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE users
+		SET reputation_score = CASE
+				WHEN COALESCE(reputation_score, 0) + ? < ? THEN ?
+				ELSE COALESCE(reputation_score, 0) + ?
+			END,
+			updated_at = ?
+		WHERE site_id = ? AND id = ?
+	`
+
+	_, err := s.db.ExecContext(ctx, query, delta, MinReputationScore, MinReputationScore, delta, time.Now(), siteID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to adjust reputation score: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyStatusChange adjusts the author's score for a comment moving from
+// oldStatus to newStatus, undoing what the old status earned so re-moderating
+// a comment doesn't count twice
+func (s *ReputationStore) ApplyStatusChange(ctx context.Context, siteID, userID, oldStatus, newStatus string) error {
+	return s.Adjust(ctx, siteID, userID, ReputationDelta(newStatus)-ReputationDelta(oldStatus))
+}
+
+// GetTopUsersByReputation returns a site's users with the highest scores, best
+// first. A limit <= 0 returns the top 10.
+func (s *ReputationStore) GetTopUsersByReputation(ctx context.Context, siteID string, limit int) ([]*User, error) {
+	if limit <= 0 {
+		limit = defaultLeaderboardLimit
+	}
+
+	query := `
+		SELECT id, site_id, name, email, avatar_url, profile_url, is_verified, roles,
+		       COALESCE(reputation_score, 0), first_seen, last_seen, created_at, updated_at
+		FROM users
+		WHERE site_id = ?
+		ORDER BY COALESCE(reputation_score, 0) DESC, name ASC
+		LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, siteID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		var u User
+		var email, avatarURL, profileURL sql.NullString
+		var rolesJSON sql.NullString
+
+		err := rows.Scan(
+			&u.ID, &u.SiteID, &u.Name, &email, &avatarURL, &profileURL,
+			&u.IsVerified, &rolesJSON, &u.ReputationScore, &u.FirstSeen, &u.LastSeen, &u.CreatedAt, &u.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		u.Email = email.String
+		u.AvatarURL = avatarURL.String
+		u.ProfileURL = profileURL.String
+		if rolesJSON.Valid && rolesJSON.String != "" {
+			if err := json.Unmarshal([]byte(rolesJSON.String), &u.Roles); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal roles: %w", err)
+			}
+		}
+
+		users = append(users, &u)
+	}
+
+	return users, rows.Err()
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// createReputationTestUsers creates one user per ID on the site, all starting at 0
+func createReputationTestUsers(t *testing.T, userStore *UserStore, siteID string, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		user := &User{ID: id, SiteID: siteID, Name: "User " + id, FirstSeen: time.Now(), LastSeen: time.Now()}
+		if err := userStore.CreateOrUpdate(context.Background(), user); err != nil {
+			t.Fatalf("CreateOrUpdate failed: %v", err)
+		}
+	}
+}
+
+func TestReputationStore_ApplyStatusChange(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	otherSite, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Other Site", "other.example.com", "")
+
+	userStore := NewUserStore(db)
+	store := NewReputationStore(db)
+	createReputationTestUsers(t, userStore, site.ID, "user-1")
+	createReputationTestUsers(t, userStore, otherSite.ID, "user-1")
+
+	score := func(siteID string) int {
+		t.Helper()
+		u, err := userStore.GetBySiteAndID(ctx, siteID, "user-1")
+		if err != nil || u == nil {
+			t.Fatalf("GetBySiteAndID failed: %v", err)
+		}
+		return u.ReputationScore
+	}
+
+	steps := []struct {
+		name          string
+		from, to      string
+		expectedScore int
+	}{
+		{"approve pending comment", "pending", "approved", 1},
+		{"approve another comment", "pending", "approved", 2},
+		{"reject pending comment", "pending", "rejected", -1},
+		{"reject previously approved comment", "approved", "rejected", -5},
+		{"re-approve rejected comment", "rejected", "approved", -1},
+		{"no-op status change", "approved", "approved", -1},
+	}
+
+	for _, step := range steps {
+		if err := store.ApplyStatusChange(ctx, site.ID, "user-1", step.from, step.to); err != nil {
+			t.Fatalf("%s: ApplyStatusChange failed: %v", step.name, err)
+		}
+		if got := score(site.ID); got != step.expectedScore {
+			t.Errorf("%s: expected score %d, got %d", step.name, step.expectedScore, got)
+		}
+	}
+
+	// Scores are scoped to the site
+	if got := score(otherSite.ID); got != 0 {
+		t.Errorf("Expected user on another site to keep score 0, got %d", got)
+	}
+
+	// JWT logins re-save the user but must not reset the score
+	user := &User{ID: "user-1", SiteID: site.ID, Name: "Renamed", LastSeen: time.Now()}
+	if err := userStore.CreateOrUpdate(ctx, user); err != nil {
+		t.Fatalf("CreateOrUpdate failed: %v", err)
+	}
+	if got := score(site.ID); got != -1 {
+		t.Errorf("Expected CreateOrUpdate to preserve score -1, got %d", got)
+	}
+}
+
+func TestReputationStore_ClampsToFloor(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+
+	userStore := NewUserStore(db)
+	store := NewReputationStore(db)
+	createReputationTestUsers(t, userStore, site.ID, "user-1")
+
+	for i := 0; i < 20; i++ {
+		if err := store.ApplyStatusChange(ctx, site.ID, "user-1", "pending", "rejected"); err != nil {
+			t.Fatalf("ApplyStatusChange failed: %v", err)
+		}
+	}
+
+	u, err := userStore.GetBySiteAndID(ctx, site.ID, "user-1")
+	if err != nil {
+		t.Fatalf("GetBySiteAndID failed: %v", err)
+	}
+	if u.ReputationScore != MinReputationScore {
+		t.Errorf("Expected score clamped to %d, got %d", MinReputationScore, u.ReputationScore)
+	}
+
+	// Unknown users are ignored rather than erroring
+	if err := store.Adjust(ctx, site.ID, "missing", ReputationApprovedDelta); err != nil {
+		t.Errorf("Expected no error for an unknown user, got %v", err)
+	}
+}
+
+func TestReputationStore_GetTopUsersByReputation(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+
+	userStore := NewUserStore(db)
+	store := NewReputationStore(db)
+	createReputationTestUsers(t, userStore, site.ID, "low", "mid", "high")
+
+	for id, delta := range map[string]int{"low": -3, "mid": 2, "high": 5} {
+		if err := store.Adjust(ctx, site.ID, id, delta); err != nil {
+			t.Fatalf("Adjust failed: %v", err)
+		}
+	}
+
+	top, err := store.GetTopUsersByReputation(ctx, site.ID, 2)
+	if err != nil {
+		t.Fatalf("GetTopUsersByReputation failed: %v", err)
+	}
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(top))
+	}
+	if top[0].ID != "high" || top[0].ReputationScore != 5 || top[1].ID != "mid" {
+		t.Errorf("Unexpected leaderboard order: %s(%d), %s(%d)", top[0].ID, top[0].ReputationScore, top[1].ID, top[1].ReputationScore)
+	}
+}
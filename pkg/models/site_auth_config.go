@@ -4,28 +4,70 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrSiteAuthConfigNotFound is returned when a site has no auth configuration
+var ErrSiteAuthConfigNotFound = errors.New("site auth config not found")
+
+// Default lifetimes of Kotomi auth session tokens, in seconds
+const (
+	DefaultAccessTokenLifetime  = 60 * 60           // 1 hour
+	DefaultRefreshTokenLifetime = 30 * 24 * 60 * 60 // 30 days
+)
+
+// ErrInvalidTokenLifetimes is returned when a site's access token would not
+// expire before its refresh token
+var ErrInvalidTokenLifetimes = errors.New("access_token_lifetime must be positive and shorter than refresh_token_lifetime")
+
 // SiteAuthConfig represents authentication configuration for a site
 type SiteAuthConfig struct {
 	ID                    string    `json:"id"`
 	SiteID                string    `json:"site_id"`
 	AuthMode              string    `json:"auth_mode"` // "external" for Phase 1 (kotomi auth in future phases)
-	JWTValidationType     string    `json:"jwt_validation_type,omitempty"` // "hmac", "rsa", "ecdsa", "jwks"
+	JWTValidationType     string    `json:"jwt_validation_type,omitempty"` // "hmac", "secret" (HS256 only), "rsa", "ecdsa", "jwks"
 	JWTSecret             string    `json:"jwt_secret,omitempty"`          // For HMAC (symmetric) - stored encrypted
 	JWTPublicKey          string    `json:"jwt_public_key,omitempty"`      // For RSA/ECDSA (asymmetric)
 	JWKSEndpoint          string    `json:"jwks_endpoint,omitempty"`       // For JWKS (JSON Web Key Set) URL
 	JWTIssuer             string    `json:"jwt_issuer,omitempty"`          // Expected issuer claim
 	JWTAudience           string    `json:"jwt_audience,omitempty"`        // Expected audience claim
 	TokenExpirationBuffer int       `json:"token_expiration_buffer"`       // Grace period in seconds
+	AccessTokenLifetime   int       `json:"access_token_lifetime"`         // Kotomi auth access token lifetime in seconds
+	RefreshTokenLifetime  int       `json:"refresh_token_lifetime"`        // Kotomi auth refresh token lifetime in seconds
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 }
 
+// TokenLifetimes returns how long the site's Kotomi auth access and refresh
+// tokens are valid, using the defaults for unset lifetimes
+func (c *SiteAuthConfig) TokenLifetimes() (access, refresh time.Duration) {
+	accessSeconds, refreshSeconds := c.AccessTokenLifetime, c.RefreshTokenLifetime
+	if accessSeconds == 0 {
+		accessSeconds = DefaultAccessTokenLifetime
+	}
+	if refreshSeconds == 0 {
+		refreshSeconds = DefaultRefreshTokenLifetime
+	}
+	return time.Duration(accessSeconds) * time.Second, time.Duration(refreshSeconds) * time.Second
+}
+
+// ValidateTokenLifetimes checks that the access token expires before the
+// refresh token that renews it. Unset lifetimes count as their defaults.
+func (c *SiteAuthConfig) ValidateTokenLifetimes() error {
+	if c.AccessTokenLifetime < 0 || c.RefreshTokenLifetime < 0 {
+		return ErrInvalidTokenLifetimes
+	}
+	access, refresh := c.TokenLifetimes()
+	if access < time.Minute || access >= refresh {
+		return ErrInvalidTokenLifetimes
+	}
+	return nil
+}
+
 // SiteAuthConfigStore handles site_auth_configs database operations
 type SiteAuthConfigStore struct {
 	db *sql.DB
@@ -41,7 +83,7 @@ func (s *SiteAuthConfigStore) GetBySiteID(ctx context.Context, siteID string) (*
 	query := `
 		SELECT id, site_id, auth_mode, jwt_validation_type, jwt_secret, jwt_public_key,
 		       jwks_endpoint, jwt_issuer, jwt_audience, token_expiration_buffer,
-		       created_at, updated_at
+		       access_token_lifetime, refresh_token_lifetime, created_at, updated_at
 		FROM site_auth_configs
 		WHERE site_id = ?
 	`
@@ -51,11 +93,11 @@ func (s *SiteAuthConfigStore) GetBySiteID(ctx context.Context, siteID string) (*
 		&config.ID, &config.SiteID, &config.AuthMode, &config.JWTValidationType,
 		&config.JWTSecret, &config.JWTPublicKey, &config.JWKSEndpoint,
 		&config.JWTIssuer, &config.JWTAudience, &config.TokenExpirationBuffer,
-		&config.CreatedAt, &config.UpdatedAt,
+		&config.AccessTokenLifetime, &config.RefreshTokenLifetime, &config.CreatedAt, &config.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("site auth config not found")
+			return nil, ErrSiteAuthConfigNotFound
 		}
 		return nil, fmt.Errorf("failed to query site auth config: %w", err)
 	}
@@ -77,20 +119,24 @@ func (s *SiteAuthConfigStore) Create(ctx context.Context, config *SiteAuthConfig
 	if config.TokenExpirationBuffer == 0 {
 		config.TokenExpirationBuffer = 60 // Default 60 seconds
 	}
+	if err := config.ValidateTokenLifetimes(); err != nil {
+		return err
+	}
+	config.AccessTokenLifetime, config.RefreshTokenLifetime = lifetimeSeconds(config)
 
 	query := `
 		INSERT INTO site_auth_configs (
 			id, site_id, auth_mode, jwt_validation_type, jwt_secret, jwt_public_key,
 			jwks_endpoint, jwt_issuer, jwt_audience, token_expiration_buffer,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			access_token_lifetime, refresh_token_lifetime, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
 		config.ID, config.SiteID, config.AuthMode, config.JWTValidationType,
 		config.JWTSecret, config.JWTPublicKey, config.JWKSEndpoint,
 		config.JWTIssuer, config.JWTAudience, config.TokenExpirationBuffer,
-		config.CreatedAt, config.UpdatedAt,
+		config.AccessTokenLifetime, config.RefreshTokenLifetime, config.CreatedAt, config.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create site auth config: %w", err)
@@ -101,20 +147,24 @@ func (s *SiteAuthConfigStore) Create(ctx context.Context, config *SiteAuthConfig
 
 // Update updates an existing site auth configuration
 func (s *SiteAuthConfigStore) Update(ctx context.Context, config *SiteAuthConfig) error {
+	if err := config.ValidateTokenLifetimes(); err != nil {
+		return err
+	}
+	config.AccessTokenLifetime, config.RefreshTokenLifetime = lifetimeSeconds(config)
 	config.UpdatedAt = time.Now()
 
 	query := `
 		UPDATE site_auth_configs
 		SET auth_mode = ?, jwt_validation_type = ?, jwt_secret = ?, jwt_public_key = ?,
 		    jwks_endpoint = ?, jwt_issuer = ?, jwt_audience = ?, token_expiration_buffer = ?,
-		    updated_at = ?
+		    access_token_lifetime = ?, refresh_token_lifetime = ?, updated_at = ?
 		WHERE id = ?
 	`
 
 	result, err := s.db.ExecContext(ctx, query,
 		config.AuthMode, config.JWTValidationType, config.JWTSecret, config.JWTPublicKey,
 		config.JWKSEndpoint, config.JWTIssuer, config.JWTAudience, config.TokenExpirationBuffer,
-		config.UpdatedAt, config.ID,
+		config.AccessTokenLifetime, config.RefreshTokenLifetime, config.UpdatedAt, config.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update site auth config: %w", err)
@@ -125,12 +175,18 @@ func (s *SiteAuthConfigStore) Update(ctx context.Context, config *SiteAuthConfig
 		return fmt.Errorf("failed to check rows affected: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("site auth config not found")
+		return ErrSiteAuthConfigNotFound
 	}
 
 	return nil
 }
 
+// lifetimeSeconds returns the config's token lifetimes with defaults filled in
+func lifetimeSeconds(config *SiteAuthConfig) (access, refresh int) {
+	accessTTL, refreshTTL := config.TokenLifetimes()
+	return int(accessTTL / time.Second), int(refreshTTL / time.Second)
+}
+
 // Delete deletes a site auth configuration
 func (s *SiteAuthConfigStore) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM site_auth_configs WHERE id = ?`
@@ -145,7 +201,7 @@ func (s *SiteAuthConfigStore) Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to check rows affected: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("site auth config not found")
+		return ErrSiteAuthConfigNotFound
 	}
 
 	return nil
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultAuthConfigCacheTTL is how long a site's auth config is reused before
+// it is read from the database again
+const DefaultAuthConfigCacheTTL = time.Minute
+
+// authConfigEntry is a cached lookup. A nil config records that the site has
+// none, so unconfigured sites don't hit the database on every request either.
+type authConfigEntry struct {
+	config    *SiteAuthConfig
+	expiresAt time.Time
+}
+
+// CachedSiteAuthConfigStore memoizes site auth configs so authenticating a
+// request doesn't need a database query. Changes made through the admin panel
+// invalidate the site's entry; other changes show up within the TTL.
+type CachedSiteAuthConfigStore struct {
+	store *SiteAuthConfigStore
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]authConfigEntry
+}
+
+// NewCachedSiteAuthConfigStore creates a caching auth config store. A ttl <= 0
+// uses DefaultAuthConfigCacheTTL.
+func NewCachedSiteAuthConfigStore(db *sql.DB, ttl time.Duration) *CachedSiteAuthConfigStore {
+	if ttl <= 0 {
+		ttl = DefaultAuthConfigCacheTTL
+	}
+	return &CachedSiteAuthConfigStore{
+		store:   NewSiteAuthConfigStore(db),
+		ttl:     ttl,
+		entries: make(map[string]authConfigEntry),
+	}
+}
+
+// GetBySiteID returns the site's auth config, or nil when the site has none.
+// Callers must not modify the returned config.
+func (c *CachedSiteAuthConfigStore) GetBySiteID(ctx context.Context, siteID string) (*SiteAuthConfig, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[siteID]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.config, nil
+	}
+
+	config, err := c.store.GetBySiteID(ctx, siteID)
+	if errors.Is(err, ErrSiteAuthConfigNotFound) {
+		config = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[siteID] = authConfigEntry{config: config, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return config, nil
+}
+
+// Invalidate drops the cached config of a site
+func (c *CachedSiteAuthConfigStore) Invalidate(siteID string) {
+	c.mu.Lock()
+	delete(c.entries, siteID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCachedSiteAuthConfigStore(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "A test site")
+	if err := NewSiteAuthConfigStore(db).Create(ctx, &SiteAuthConfig{
+		SiteID: site.ID, AuthMode: "external", JWTValidationType: "secret", JWTSecret: "secret", JWTAudience: "kotomi",
+	}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	configs := NewCachedSiteAuthConfigStore(db, time.Minute)
+	config, err := configs.GetBySiteID(ctx, site.ID)
+	if err != nil || config == nil || config.JWTValidationType != "secret" {
+		t.Fatalf("Expected the site's config, got %+v (%v)", config, err)
+	}
+
+	// Served from the cache until invalidated
+	if _, err := db.Exec(`UPDATE site_auth_configs SET jwt_audience = 'changed' WHERE site_id = ?`, site.ID); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if config, _ := configs.GetBySiteID(ctx, site.ID); config.JWTAudience != "kotomi" {
+		t.Errorf("Expected the cached audience, got %q", config.JWTAudience)
+	}
+	configs.Invalidate(site.ID)
+	if config, _ := configs.GetBySiteID(ctx, site.ID); config.JWTAudience != "changed" {
+		t.Errorf("Expected the updated audience after invalidation, got %q", config.JWTAudience)
+	}
+
+	if config, err := configs.GetBySiteID(ctx, "no-such-site"); err != nil || config != nil {
+		t.Errorf("Expected nil for a site without config, got %+v (%v)", config, err)
+	}
+}
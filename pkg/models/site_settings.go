@@ -0,0 +1,275 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/i18n"
+)
+
+// DefaultMaxCommentLength is the maximum comment length (in runes) used when a
+// site has not configured its own limit
+const DefaultMaxCommentLength = 10000
+
+// DefaultReportThreshold is the number of user reports that sends a comment back
+// to the moderation queue when a site has not configured its own threshold
+const DefaultReportThreshold = 3
+
+// Statuses a site may give new comments that moderation leaves undecided
+const (
+	CommentStatusApproved = "approved"
+	CommentStatusPending  = "pending"
+)
+
+// DefaultCommentCooldownSeconds is how long an author must wait between comments
+// on a site when the site has not configured its own cooldown
+const DefaultCommentCooldownSeconds = 15
+
+// SiteSettings holds per-site behaviour settings
+type SiteSettings struct {
+	SiteID                 string    `json:"site_id"`
+	MaxCommentLength       int       `json:"max_comment_length"`
+	MaxReactionsPerTarget  int       `json:"max_reactions_per_target"`       // Per user on one comment or page; 0 = unlimited, 1 = a new reaction replaces the previous one
+	CORSAllowedOrigins     []string  `json:"cors_allowed_origins"`           // e.g. "https://blog.example.com"; "*" only without credentials; empty = server-wide policy
+	CORSAllowCredentials   bool      `json:"cors_allow_credentials"`         // Lets browsers send cookies with cross-origin requests
+	ReportThreshold        int       `json:"report_threshold"`               // Reports after which a comment goes back to pending; 0 disables
+	CommentCooldownSeconds int       `json:"comment_cooldown_seconds"`       // Minimum time between an author's comments; trusted authors are exempt, 0 disables
+	MaxAllowedReactions    int       `json:"max_allowed_reactions_per_site"` // Reaction types offered on comments and on pages ("both" counts towards each); 0 = unlimited
+	ContentPolicy          string    `json:"content_policy"`                 // "plain", "markdown" or "limited-html" (see the comments package)
+	GravatarEnabled        bool      `json:"gravatar_enabled"`               // Gravatar for authors without an avatar; off sends no email hashes to Gravatar
+	GravatarStyle          string    `json:"gravatar_style"`                 // Default Gravatar image for unknown emails, e.g. "identicon" or "retro"
+	Locale                 string    `json:"locale"`                         // Language of notifications and moderation reasons, e.g. "en" (see the i18n package)
+	DefaultCommentStatus   string    `json:"default_comment_status"`         // Status when neither blocklist nor AI moderation decided: "pending" or "approved"
+	AutoSubscribe          bool      `json:"auto_subscribe"`                 // Subscribe authors to pages they comment on, unless they unsubscribed before
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// DefaultSiteSettings returns the settings applied to a site without a stored row
+func DefaultSiteSettings(siteID string) *SiteSettings {
+	return &SiteSettings{
+		SiteID:                 siteID,
+		MaxCommentLength:       DefaultMaxCommentLength,
+		CORSAllowedOrigins:     []string{},
+		ReportThreshold:        DefaultReportThreshold,
+		CommentCooldownSeconds: DefaultCommentCooldownSeconds,
+		ContentPolicy:          comments.DefaultContentPolicy,
+		GravatarEnabled:        true,
+		GravatarStyle:          DefaultGravatarStyle,
+		Locale:                 i18n.DefaultLocale,
+		DefaultCommentStatus:   CommentStatusPending,
+		AutoSubscribe:          true,
+	}
+}
+
+// CommentLength returns the length of a comment in runes, ignoring trailing whitespace
+func CommentLength(text string) int {
+	return utf8.RuneCountInString(strings.TrimRightFunc(text, unicode.IsSpace))
+}
+
+// ExceedsMaxCommentLength reports whether text is longer than the site's limit
+func (s *SiteSettings) ExceedsMaxCommentLength(text string) bool {
+	limit := s.MaxCommentLength
+	if limit <= 0 {
+		limit = DefaultMaxCommentLength
+	}
+	return CommentLength(text) > limit
+}
+
+// NormalizeOrigin canonicalizes an origin to lowercase scheme://host[:port] so it
+// can be compared against a request's Origin header. "*" is returned unchanged.
+func NormalizeOrigin(origin string) (string, error) {
+	origin = strings.TrimSpace(origin)
+	if origin == "*" {
+		return origin, nil
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(origin, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("invalid origin %q: expected scheme://host[:port]", origin)
+	}
+
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// ParseOrigins splits a comma- or newline-separated origin list, normalizing
+// each entry and dropping blanks and duplicates
+func ParseOrigins(list string) ([]string, error) {
+	fields := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+
+	origins := []string{}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		origin, err := NormalizeOrigin(field)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins, nil
+}
+
+// validateCORS checks the site's CORS origins, normalizing them in place
+func (s *SiteSettings) validateCORS() error {
+	origins, err := ParseOrigins(strings.Join(s.CORSAllowedOrigins, ","))
+	if err != nil {
+		return err
+	}
+	for _, origin := range origins {
+		if origin == "*" && s.CORSAllowCredentials {
+			return fmt.Errorf("wildcard CORS origin cannot be used with credentials")
+		}
+	}
+	s.CORSAllowedOrigins = origins
+	return nil
+}
+
+// SiteSettingsStore handles site settings database operations
+type SiteSettingsStore struct {
+	db *sql.DB
+}
+
+// NewSiteSettingsStore creates a new site settings store
+func NewSiteSettingsStore(db *sql.DB) *SiteSettingsStore {
+	return &SiteSettingsStore{db: db}
+}
+
+// GetBySiteID retrieves settings for a site, falling back to defaults if none are stored
+func (s *SiteSettingsStore) GetBySiteID(ctx context.Context, siteID string) (*SiteSettings, error) {
+	return getSiteSettings(ctx, s.db, siteID)
+}
+
+// getSiteSettings reads a site's settings through q, which may be a transaction
+func getSiteSettings(ctx context.Context, q RowQuerier, siteID string) (*SiteSettings, error) {
+	query := `
+		SELECT site_id, max_comment_length, max_reactions_per_target,
+			cors_allowed_origins, cors_allow_credentials, report_threshold, comment_cooldown_seconds,
+			max_allowed_reactions_per_site, content_policy, gravatar_enabled, gravatar_style,
+			locale, default_comment_status, auto_subscribe, created_at, updated_at
+		FROM site_settings
+		WHERE site_id = ?
+	`
+
+	var settings SiteSettings
+	var corsOrigins string
+	err := q.QueryRowContext(ctx, query, siteID).Scan(
+		&settings.SiteID, &settings.MaxCommentLength, &settings.MaxReactionsPerTarget,
+		&corsOrigins, &settings.CORSAllowCredentials, &settings.ReportThreshold, &settings.CommentCooldownSeconds,
+		&settings.MaxAllowedReactions, &settings.ContentPolicy,
+		&settings.GravatarEnabled, &settings.GravatarStyle, &settings.Locale, &settings.DefaultCommentStatus, &settings.AutoSubscribe, &settings.CreatedAt, &settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultSiteSettings(siteID), nil
+		}
+		return nil, fmt.Errorf("failed to query site settings: %w", err)
+	}
+
+	settings.CORSAllowedOrigins = []string{}
+	if corsOrigins != "" {
+		settings.CORSAllowedOrigins = strings.Split(corsOrigins, ",")
+	}
+
+	return &settings, nil
+}
+
+// Upsert creates or updates the settings for a site
+func (s *SiteSettingsStore) Upsert(ctx context.Context, settings *SiteSettings) error {
+	if settings.MaxCommentLength <= 0 {
+		return fmt.Errorf("max comment length must be positive")
+	}
+	if settings.MaxReactionsPerTarget < 0 {
+		return fmt.Errorf("max reactions per target must not be negative")
+	}
+	if settings.ReportThreshold < 0 {
+		return fmt.Errorf("report threshold must not be negative")
+	}
+	if settings.CommentCooldownSeconds < 0 {
+		return fmt.Errorf("comment cooldown must not be negative")
+	}
+	if settings.MaxAllowedReactions < 0 {
+		return fmt.Errorf("max allowed reactions must not be negative")
+	}
+	if settings.ContentPolicy == "" {
+		settings.ContentPolicy = comments.DefaultContentPolicy
+	}
+	if !comments.IsValidContentPolicy(settings.ContentPolicy) {
+		return fmt.Errorf("invalid content policy %q: must be plain, markdown or limited-html", settings.ContentPolicy)
+	}
+	if settings.GravatarStyle == "" {
+		settings.GravatarStyle = DefaultGravatarStyle
+	}
+	if !IsValidGravatarStyle(settings.GravatarStyle) {
+		return fmt.Errorf("invalid gravatar style %q", settings.GravatarStyle)
+	}
+	if settings.Locale == "" {
+		settings.Locale = i18n.DefaultLocale
+	}
+	locale := i18n.Normalize(settings.Locale)
+	if locale == "" {
+		return fmt.Errorf("unsupported locale %q", settings.Locale)
+	}
+	settings.Locale = locale
+	if settings.DefaultCommentStatus == "" {
+		settings.DefaultCommentStatus = CommentStatusPending
+	}
+	if settings.DefaultCommentStatus != CommentStatusPending && settings.DefaultCommentStatus != CommentStatusApproved {
+		return fmt.Errorf("invalid default comment status %q: must be pending or approved", settings.DefaultCommentStatus)
+	}
+	if err := settings.validateCORS(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO site_settings (site_id, max_comment_length, max_reactions_per_target,
+			cors_allowed_origins, cors_allow_credentials, report_threshold, comment_cooldown_seconds,
+			max_allowed_reactions_per_site, content_policy, gravatar_enabled, gravatar_style,
+			locale, default_comment_status, auto_subscribe, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(site_id) DO UPDATE SET
+			max_comment_length = excluded.max_comment_length,
+			max_reactions_per_target = excluded.max_reactions_per_target,
+			cors_allowed_origins = excluded.cors_allowed_origins,
+			cors_allow_credentials = excluded.cors_allow_credentials,
+			report_threshold = excluded.report_threshold,
+			comment_cooldown_seconds = excluded.comment_cooldown_seconds,
+			max_allowed_reactions_per_site = excluded.max_allowed_reactions_per_site,
+			content_policy = excluded.content_policy,
+			gravatar_enabled = excluded.gravatar_enabled,
+			gravatar_style = excluded.gravatar_style,
+			locale = excluded.locale,
+			default_comment_status = excluded.default_comment_status,
+			auto_subscribe = excluded.auto_subscribe,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query, settings.SiteID, settings.MaxCommentLength,
+		settings.MaxReactionsPerTarget, strings.Join(settings.CORSAllowedOrigins, ","),
+		settings.CORSAllowCredentials, settings.ReportThreshold, settings.CommentCooldownSeconds,
+		settings.MaxAllowedReactions, settings.ContentPolicy,
+		settings.GravatarEnabled, settings.GravatarStyle, settings.Locale, settings.DefaultCommentStatus, settings.AutoSubscribe, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save site settings: %w", err)
+	}
+
+	settings.UpdatedAt = now
+	return nil
+}
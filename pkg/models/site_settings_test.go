@@ -0,0 +1,263 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+)
+
+func TestSiteSettings_ExceedsMaxCommentLength(t *testing.T) {
+	settings := &SiteSettings{SiteID: "site-1", MaxCommentLength: 5}
+
+	tests := []struct {
+		name   string
+		text   string
+		exceed bool
+	}{
+		{"exactly at limit", "hello", false},
+		{"one rune over", "hello!", true},
+		{"multibyte at limit", "こんにちは", false},
+		{"multibyte one rune over", "こんにちは!", true},
+		{"trailing whitespace ignored", "hello   \n\t", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settings.ExceedsMaxCommentLength(tt.text); got != tt.exceed {
+				t.Errorf("ExceedsMaxCommentLength(%q) = %v, want %v", tt.text, got, tt.exceed)
+			}
+		})
+	}
+}
+
+func TestSiteSettingsStore_GetAndUpsert(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	adminUser, _ := NewAdminUserStore(db).Create(context.Background(), "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(context.Background(), adminUser.ID, "Test Site", "example.com", "A test site")
+
+	store := NewSiteSettingsStore(db)
+
+	// Sites without stored settings get the defaults
+	settings, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if settings.MaxCommentLength != DefaultMaxCommentLength {
+		t.Errorf("Expected default max length %d, got %d", DefaultMaxCommentLength, settings.MaxCommentLength)
+	}
+	if settings.ExceedsMaxCommentLength(strings.Repeat("a", DefaultMaxCommentLength)) {
+		t.Error("Expected comment at default limit to be accepted")
+	}
+
+	settings.MaxCommentLength = 200
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	retrieved, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if retrieved.MaxCommentLength != 200 {
+		t.Errorf("Expected max length 200, got %d", retrieved.MaxCommentLength)
+	}
+
+	if err := store.Upsert(context.Background(), &SiteSettings{SiteID: site.ID, MaxCommentLength: 0}); err == nil {
+		t.Error("Expected error for non-positive max length")
+	}
+}
+
+func TestSiteSettingsStore_CORS(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	adminUser, _ := NewAdminUserStore(db).Create(context.Background(), "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(context.Background(), adminUser.ID, "Test Site", "example.com", "A test site")
+
+	store := NewSiteSettingsStore(db)
+
+	settings := DefaultSiteSettings(site.ID)
+	settings.CORSAllowedOrigins = []string{"https://Blog.Example.com/", "https://blog.example.com", "http://localhost:3000"}
+	settings.CORSAllowCredentials = true
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	retrieved, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	want := []string{"https://blog.example.com", "http://localhost:3000"}
+	if strings.Join(retrieved.CORSAllowedOrigins, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected origins %v, got %v", want, retrieved.CORSAllowedOrigins)
+	}
+	if !retrieved.CORSAllowCredentials {
+		t.Error("Expected credentials to be allowed")
+	}
+
+	// A wildcard origin must not be combined with credentials
+	settings.CORSAllowedOrigins = []string{"*"}
+	if err := store.Upsert(context.Background(), settings); err == nil {
+		t.Error("Expected error for wildcard origin with credentials")
+	}
+	settings.CORSAllowCredentials = false
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Errorf("Expected wildcard origin without credentials to be accepted: %v", err)
+	}
+
+	settings.CORSAllowedOrigins = []string{"blog.example.com/path"}
+	if err := store.Upsert(context.Background(), settings); err == nil {
+		t.Error("Expected error for malformed origin")
+	}
+}
+
+func TestSiteSettingsStore_ContentPolicy(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	adminUser, _ := NewAdminUserStore(db).Create(context.Background(), "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(context.Background(), adminUser.ID, "Test Site", "example.com", "A test site")
+
+	store := NewSiteSettingsStore(db)
+
+	settings, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if settings.ContentPolicy != comments.ContentPolicyMarkdown {
+		t.Errorf("Expected default policy %q, got %q", comments.ContentPolicyMarkdown, settings.ContentPolicy)
+	}
+
+	settings.ContentPolicy = comments.ContentPolicyLimitedHTML
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	retrieved, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if retrieved.ContentPolicy != comments.ContentPolicyLimitedHTML {
+		t.Errorf("Expected policy %q, got %q", comments.ContentPolicyLimitedHTML, retrieved.ContentPolicy)
+	}
+
+	settings.ContentPolicy = "full-html"
+	if err := store.Upsert(context.Background(), settings); err == nil {
+		t.Error("Expected error for an unknown content policy")
+	}
+}
+
+func TestSiteSettingsStore_Gravatar(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	adminUser, _ := NewAdminUserStore(db).Create(context.Background(), "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(context.Background(), adminUser.ID, "Test Site", "example.com", "A test site")
+
+	store := NewSiteSettingsStore(db)
+
+	settings, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if !settings.GravatarEnabled || settings.GravatarStyle != DefaultGravatarStyle {
+		t.Errorf("Expected Gravatar enabled with %q by default, got %v %q", DefaultGravatarStyle, settings.GravatarEnabled, settings.GravatarStyle)
+	}
+
+	settings.GravatarEnabled = false
+	settings.GravatarStyle = GravatarRetro
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	retrieved, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if retrieved.GravatarEnabled || retrieved.GravatarStyle != GravatarRetro {
+		t.Errorf("Expected Gravatar disabled with retro style, got %v %q", retrieved.GravatarEnabled, retrieved.GravatarStyle)
+	}
+
+	settings.GravatarStyle = "blank-ish"
+	if err := store.Upsert(context.Background(), settings); err == nil {
+		t.Error("Expected error for an unknown Gravatar style")
+	}
+}
+
+func TestSiteSettingsStore_Locale(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	adminUser, _ := NewAdminUserStore(db).Create(context.Background(), "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(context.Background(), adminUser.ID, "Test Site", "example.com", "A test site")
+
+	store := NewSiteSettingsStore(db)
+
+	settings, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if settings.Locale != "en" {
+		t.Errorf("Expected locale en by default, got %q", settings.Locale)
+	}
+
+	settings.Locale = "ja-JP"
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	retrieved, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if retrieved.Locale != "ja" {
+		t.Errorf("Expected normalized locale ja, got %q", retrieved.Locale)
+	}
+
+	settings.Locale = "xx"
+	if err := store.Upsert(context.Background(), settings); err == nil {
+		t.Error("Expected error for an unsupported locale")
+	}
+}
+
+func TestSiteSettingsStore_DefaultCommentStatus(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+
+	db := sqliteStore.GetDB()
+	adminUser, _ := NewAdminUserStore(db).Create(context.Background(), "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(context.Background(), adminUser.ID, "Test Site", "example.com", "A test site")
+
+	store := NewSiteSettingsStore(db)
+
+	settings, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if settings.DefaultCommentStatus != CommentStatusPending {
+		t.Errorf("Expected pending by default, got %q", settings.DefaultCommentStatus)
+	}
+
+	settings.DefaultCommentStatus = CommentStatusApproved
+	if err := store.Upsert(context.Background(), settings); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	retrieved, err := store.GetBySiteID(context.Background(), site.ID)
+	if err != nil {
+		t.Fatalf("GetBySiteID failed: %v", err)
+	}
+	if retrieved.DefaultCommentStatus != CommentStatusApproved {
+		t.Errorf("Expected approved, got %q", retrieved.DefaultCommentStatus)
+	}
+
+	settings.DefaultCommentStatus = "rejected"
+	if err := store.Upsert(context.Background(), settings); err == nil {
+		t.Error("Expected error for a default status other than pending or approved")
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+)
+
+// ReactorStat is a user with the number of reactions they left on a site
+type ReactorStat struct {
+	UserID        string `json:"user_id"`
+	Name          string `json:"name"` // Empty when the user has no users row
+	ReactionCount int    `json:"reaction_count"`
+}
+
+// GetTopReactors returns up to limit users ranked by how many reactions they
+// left on the site's pages and comments within dateRange, most first.
+// Reactions carry no site_id, so they are scoped through allowed_reactions.
+// With excludeOwner, the site owner's own reactions are left out, matched by
+// their admin user ID or email.
+func (s *ReactionStore) GetTopReactors(ctx context.Context, siteID string, dateRange analytics.DateRange, limit int, excludeOwner bool) ([]ReactorStat, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	ownerFilter := ""
+	if excludeOwner {
+		ownerFilter = `
+		  AND NOT EXISTS (
+			SELECT 1 FROM sites st
+			JOIN admin_users au ON au.id = st.owner_id
+			WHERE st.id = ar.site_id
+			  AND (r.user_id = st.owner_id OR (COALESCE(u.email, '') <> '' AND LOWER(u.email) = LOWER(au.email)))
+		  )`
+	}
+
+	query := `
+		SELECT r.user_id, COALESCE(MAX(u.name), ''), COUNT(*) AS reaction_count
+		FROM reactions r
+		INNER JOIN allowed_reactions ar ON r.allowed_reaction_id = ar.id
+		LEFT JOIN users u ON u.site_id = ar.site_id AND u.id = r.user_id
+		WHERE ar.site_id = ? AND r.created_at BETWEEN ? AND ?` + ownerFilter + `
+		GROUP BY r.user_id
+		ORDER BY reaction_count DESC, r.user_id
+		LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, siteID, dateRange.From, dateRange.To, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top reactors: %w", err)
+	}
+	defer rows.Close()
+
+	reactors := []ReactorStat{}
+	for rows.Next() {
+		var stat ReactorStat
+		if err := rows.Scan(&stat.UserID, &stat.Name, &stat.ReactionCount); err != nil {
+			return nil, fmt.Errorf("failed to scan top reactor: %w", err)
+		}
+		reactors = append(reactors, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate top reactors: %w", err)
+	}
+
+	return reactors, nil
+}
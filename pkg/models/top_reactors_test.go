@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+)
+
+func TestReactionStore_GetTopReactors(t *testing.T) {
+	sqliteStore := createTestDB(t)
+	defer sqliteStore.Close()
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+
+	owner, err := NewAdminUserStore(db).Create(ctx, "owner@example.com", "Owner", "auth0|owner")
+	if err != nil {
+		t.Fatalf("Failed to create admin user: %v", err)
+	}
+	site, err := NewSiteStore(db).Create(ctx, owner.ID, "Blog", "blog.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+	otherSite, err := NewSiteStore(db).Create(ctx, owner.ID, "Other", "other.example.com", "")
+	if err != nil {
+		t.Fatalf("Failed to create site: %v", err)
+	}
+
+	users := NewUserStore(db)
+	for _, user := range []*User{
+		{ID: "alice", SiteID: site.ID, Name: "Alice"},
+		{ID: "bob", SiteID: site.ID, Name: "Bob"},
+		{ID: "owner-reader", SiteID: site.ID, Name: "Owner", Email: "OWNER@example.com"},
+	} {
+		if err := users.CreateOrUpdate(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	allowed := NewAllowedReactionStore(db)
+	reactionTypes := make([]string, 3)
+	for i, emoji := range []string{"👍", "❤️", "🎉"} {
+		reaction, err := allowed.Create(ctx, site.ID, emoji, emoji, "page")
+		if err != nil {
+			t.Fatalf("Failed to create allowed reaction: %v", err)
+		}
+		reactionTypes[i] = reaction.ID
+	}
+	otherReaction, err := allowed.Create(ctx, otherSite.ID, "like", "👍", "page")
+	if err != nil {
+		t.Fatalf("Failed to create allowed reaction: %v", err)
+	}
+
+	pages := NewPageStore(db)
+	page, err := pages.Create(ctx, site.ID, "/post", "Post")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	otherPage, err := pages.Create(ctx, otherSite.ID, "/post", "Post")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	reactions := NewReactionStore(db)
+	react := func(pageID, allowedReactionID, userID string) {
+		t.Helper()
+		if _, err := reactions.AddPageReaction(ctx, pageID, allowedReactionID, userID); err != nil {
+			t.Fatalf("Failed to add reaction: %v", err)
+		}
+	}
+	for _, id := range reactionTypes {
+		react(page.ID, id, "alice")
+	}
+	react(page.ID, reactionTypes[0], "bob")
+	react(page.ID, reactionTypes[1], "bob")
+	react(page.ID, reactionTypes[0], "owner-reader")
+	react(page.ID, reactionTypes[1], "owner-reader")
+	react(page.ID, reactionTypes[2], "owner-reader")
+	react(page.ID, reactionTypes[2], owner.ID)
+	react(otherPage.ID, otherReaction.ID, "bob")
+	react(otherPage.ID, otherReaction.ID, "bob")
+
+	// Bob's third reaction on the site predates the range
+	react(page.ID, reactionTypes[2], "bob")
+	if _, err := db.ExecContext(ctx, `UPDATE reactions SET created_at = ? WHERE user_id = 'bob' AND allowed_reaction_id = ?`,
+		time.Now().AddDate(0, -2, 0), reactionTypes[2]); err != nil {
+		t.Fatalf("Failed to backdate reaction: %v", err)
+	}
+
+	dateRange := analytics.DateRange{From: time.Now().AddDate(0, 0, -30), To: time.Now().Add(time.Minute)}
+
+	t.Run("excluding the owner", func(t *testing.T) {
+		top, err := reactions.GetTopReactors(ctx, site.ID, dateRange, 10, true)
+		if err != nil {
+			t.Fatalf("GetTopReactors failed: %v", err)
+		}
+		want := []ReactorStat{
+			{UserID: "alice", Name: "Alice", ReactionCount: 3},
+			{UserID: "bob", Name: "Bob", ReactionCount: 2},
+		}
+		if len(top) != len(want) {
+			t.Fatalf("Expected %d reactors, got %+v", len(want), top)
+		}
+		for i := range want {
+			if top[i] != want[i] {
+				t.Errorf("Expected %+v at %d, got %+v", want[i], i, top[i])
+			}
+		}
+	})
+
+	t.Run("including the owner", func(t *testing.T) {
+		top, err := reactions.GetTopReactors(ctx, site.ID, dateRange, 10, false)
+		if err != nil {
+			t.Fatalf("GetTopReactors failed: %v", err)
+		}
+		if len(top) != 4 {
+			t.Fatalf("Expected 4 reactors, got %+v", top)
+		}
+		if top[0].UserID != "alice" || top[1].UserID != "owner-reader" || top[3].UserID != owner.ID {
+			t.Errorf("Unexpected ranking: %+v", top)
+		}
+		if top[3].Name != "" {
+			t.Errorf("Expected no name for a reactor without a users row, got %q", top[3].Name)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		top, err := reactions.GetTopReactors(ctx, site.ID, dateRange, 1, true)
+		if err != nil {
+			t.Fatalf("GetTopReactors failed: %v", err)
+		}
+		if len(top) != 1 || top[0].UserID != "alice" {
+			t.Errorf("Expected only alice, got %+v", top)
+		}
+		if _, err := reactions.GetTopReactors(ctx, site.ID, dateRange, 0, true); err == nil {
+			t.Error("Expected an error for a zero limit")
+		}
+	})
+}
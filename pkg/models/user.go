@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
 
+// ErrUserNotFound is returned when changing a user the site has no record of
+var ErrUserNotFound = errors.New("user not found")
+
 // User represents a JWT-authenticated commenter/reactor user (Phase 2)
 type User struct {
 	ID              string    `json:"id"`          // User ID from JWT
@@ -151,11 +155,17 @@ func (s *UserStore) CreateOrUpdate(ctx context.Context, user *User) error {
 	}
 
 	if existing != nil {
-		// Update existing user
+		// Update existing user. The reputation score is left alone: it is owned by
+		// ReputationStore and JWT logins would otherwise reset it on every request.
+		// For the same reason a token can mark the user verified but never clears
+		// a verification the site owner granted with VerifyUser.
+		if user.LastSeen.IsZero() {
+			user.LastSeen = now
+		}
 		query := `
 			UPDATE users
 			SET name = ?, email = ?, avatar_url = ?, profile_url = ?, 
-			    is_verified = ?, roles = ?, reputation_score = ?, last_seen = ?, updated_at = ?
+			    is_verified = MAX(COALESCE(is_verified, 0), ?), roles = ?, last_seen = ?, updated_at = ?
 			WHERE site_id = ? AND id = ?
 		`
 
@@ -174,7 +184,7 @@ func (s *UserStore) CreateOrUpdate(ctx context.Context, user *User) error {
 		}
 
 		_, err = s.db.ExecContext(ctx, query, user.Name, email, avatarURL, profileURL,
-			user.IsVerified, rolesJSON, user.ReputationScore, user.LastSeen, now, user.SiteID, user.ID)
+			user.IsVerified, rolesJSON, user.LastSeen, now, user.SiteID, user.ID)
 		if err != nil {
 			return fmt.Errorf("failed to update user: %w", err)
 		}
@@ -238,6 +248,71 @@ func (s *UserStore) UpdateLastSeen(ctx context.Context, siteID, userID string) e
 	return nil
 }
 
+// VerifyUser marks a commenter as verified on a site, so their comments show
+// the verified badge. It returns ErrUserNotFound for an unknown user.
+func (s *UserStore) VerifyUser(ctx context.Context, siteID, userID string) error {
+	return s.setVerified(ctx, siteID, userID, true)
+}
+
+// UnverifyUser removes a commenter's verified badge on a site. It returns
+// ErrUserNotFound for an unknown user.
+func (s *UserStore) UnverifyUser(ctx context.Context, siteID, userID string) error {
+	return s.setVerified(ctx, siteID, userID, false)
+}
+
+func (s *UserStore) setVerified(ctx context.Context, siteID, userID string, verified bool) error {
+	query := `
+		UPDATE users
+		SET is_verified = ?, updated_at = ?
+		WHERE site_id = ? AND id = ?
+	`
+
+	result, err := s.db.ExecContext(ctx, query, verified, time.Now(), siteID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update verification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RecordCommenter marks a comment by the given user on a site, creating their
+// users row on their first comment (first_seen and last_seen set to now) and
+// refreshing last_seen, name and email on later ones. first_seen, verification
+// and reputation of an existing row are left alone.
+func (s *UserStore) RecordCommenter(ctx context.Context, siteID, userID, name, email string) error {
+	query := `
+		INSERT INTO users (id, site_id, name, email, first_seen, last_seen, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(site_id, id) DO UPDATE SET
+			name = excluded.name,
+			email = COALESCE(excluded.email, users.email),
+			last_seen = excluded.last_seen,
+			updated_at = excluded.updated_at
+	`
+
+	var nullEmail sql.NullString
+	if email != "" {
+		nullEmail.String = email
+		nullEmail.Valid = true
+	}
+
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, query, userID, siteID, name, nullEmail, now, now, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to record commenter: %w", err)
+	}
+
+	return nil
+}
+
 // Delete removes a user and all their comments/reactions
 func (s *UserStore) Delete(ctx context.Context, siteID, userID string) error {
 	// Note: Foreign key constraints will cascade delete comments and reactions
@@ -271,6 +346,29 @@ func (s *UserStore) UpdateReputationScore(ctx context.Context, siteID, userID st
 	return nil
 }
 
+// LastCommentAt returns when userID last commented on siteID, or the zero time
+// if they never have
+func (s *UserStore) LastCommentAt(ctx context.Context, siteID, userID string) (time.Time, error) {
+	query := `
+		SELECT created_at
+		FROM comments
+		WHERE site_id = ? AND author_id = ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var createdAt time.Time
+	err := s.db.QueryRowContext(ctx, query, siteID, userID).Scan(&createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to query last comment: %w", err)
+	}
+
+	return createdAt, nil
+}
+
 // CalculateReputationScore calculates basic reputation score based on user activity
 // Phase 3 foundation: Simple calculation based on approved comments
 func (s *UserStore) CalculateReputationScore(ctx context.Context, siteID, userID string) (int, error) {
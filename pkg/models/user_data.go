@@ -0,0 +1,302 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Ways DeleteUserData can erase a commenter
+const (
+	// DeletionModeAnonymize keeps the user's comments, reactions and reports but
+	// removes everything identifying them
+	DeletionModeAnonymize = "anonymize"
+	// DeletionModePurge deletes the user's comments, reactions and reports
+	DeletionModePurge = "purge"
+)
+
+// DeletedUserName replaces the author name of anonymized comments
+const DeletedUserName = "Deleted user"
+
+// UserDataDeletion counts the rows DeleteUserData changed. Purged counts
+// include other users' reactions and reports on the purged comments.
+type UserDataDeletion struct {
+	Mode      string `json:"mode"`
+	Comments  int64  `json:"comments"`
+	Reactions int64  `json:"reactions"`
+	Reports   int64  `json:"reports"`
+	Users     int64  `json:"users"`
+}
+
+// IsValidDeletionMode reports whether mode is a DeleteUserData mode
+func IsValidDeletionMode(mode string) bool {
+	return mode == DeletionModeAnonymize || mode == DeletionModePurge
+}
+
+// DeleteUserData erases a commenter from a site to honor a deletion request.
+// Anonymizing replaces the author name of their comments with DeletedUserName
+// and removes the email and author ID; their reactions and reports are
+// kept under IDs that can't be linked to them or to each other. Purging
+// deletes their comments (with the comments' reactions, reports, attachments
+// and moderation events), reactions and reports; other users' replies to the
+// deleted comments are attached to the nearest remaining ancestor instead.
+// Either way the users row, page subscriptions and idempotency keys are
+// deleted, all in a single transaction.
+func (s *UserStore) DeleteUserData(ctx context.Context, siteID, authorID, mode string) (*UserDataDeletion, error) {
+	if !IsValidDeletionMode(mode) {
+		return nil, fmt.Errorf("invalid deletion mode %q: must be anonymize or purge", mode)
+	}
+	if authorID == "" {
+		return nil, fmt.Errorf("author ID is required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &UserDataDeletion{Mode: mode}
+
+	// The user's reactions and reports, identified through the site's allowed
+	// reactions and comments since neither table has a site column
+	const siteReactions = `user_id = ? AND allowed_reaction_id IN (SELECT id FROM allowed_reactions WHERE site_id = ?)`
+	const siteReports = `reporter_user_id = ? AND comment_id IN (SELECT id FROM comments WHERE site_id = ?)`
+	const authoredComments = `SELECT id FROM comments WHERE site_id = ? AND author_id = ?`
+
+	type step struct {
+		counter *int64
+		query   string
+		args    []interface{}
+	}
+	var steps []step
+	if mode == DeletionModePurge {
+		steps = []step{
+			{&result.Reactions, `DELETE FROM reactions WHERE comment_id IN (` + authoredComments + `)`, []interface{}{siteID, authorID}},
+			{&result.Reactions, `DELETE FROM reactions WHERE ` + siteReactions, []interface{}{authorID, siteID}},
+			{&result.Reports, `DELETE FROM comment_reports WHERE comment_id IN (` + authoredComments + `)`, []interface{}{siteID, authorID}},
+			{&result.Reports, `DELETE FROM comment_reports WHERE ` + siteReports, []interface{}{authorID, siteID}},
+			{nil, `DELETE FROM attachments WHERE comment_id IN (` + authoredComments + `)`, []interface{}{siteID, authorID}},
+			{nil, `DELETE FROM moderation_events WHERE comment_id IN (` + authoredComments + `)`, []interface{}{siteID, authorID}},
+			{&result.Comments, `DELETE FROM comments WHERE site_id = ? AND author_id = ?`, []interface{}{siteID, authorID}},
+		}
+	} else {
+		// author_id is NOT NULL, so it is cleared rather than nulled. Reactions
+		// and reports get a per-row placeholder to keep their unique constraints
+		// satisfied.
+		steps = []step{
+			{&result.Comments, `UPDATE comments SET author = ?, author_email = NULL, author_id = '' WHERE site_id = ? AND author_id = ?`, []interface{}{DeletedUserName, siteID, authorID}},
+			{&result.Reactions, `UPDATE reactions SET user_id = 'deleted:' || id WHERE ` + siteReactions, []interface{}{authorID, siteID}},
+			{&result.Reports, `UPDATE comment_reports SET reporter_user_id = 'deleted:' || id WHERE ` + siteReports, []interface{}{authorID, siteID}},
+		}
+	}
+	steps = append(steps,
+		step{nil, `DELETE FROM page_subscriptions WHERE site_id = ? AND user_id = ?`, []interface{}{siteID, authorID}},
+		step{nil, `DELETE FROM comment_idempotency_keys WHERE site_id = ? AND user_id = ?`, []interface{}{siteID, authorID}},
+		step{&result.Users, `DELETE FROM users WHERE site_id = ? AND id = ?`, []interface{}{siteID, authorID}},
+	)
+
+	if mode == DeletionModePurge {
+		// Other users' replies to purged comments move up to the nearest
+		// ancestor that stays, one level per pass. A chain can't be longer
+		// than the user's comment count; replies still left pointing at them
+		// (only possible with a parent cycle) become top-level.
+		var authored int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE site_id = ? AND author_id = ?`, siteID, authorID).Scan(&authored); err != nil {
+			return nil, fmt.Errorf("failed to count comments: %w", err)
+		}
+		for pass := 0; pass < authored; pass++ {
+			res, err := tx.ExecContext(ctx, `
+				UPDATE comments
+				SET parent_id = (SELECT p.parent_id FROM comments p WHERE p.id = comments.parent_id)
+				WHERE site_id = ? AND author_id != ? AND parent_id IN (`+authoredComments+`)
+			`, siteID, authorID, siteID, authorID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to reparent replies: %w", err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("failed to count affected rows: %w", err)
+			}
+			if n == 0 {
+				break
+			}
+		}
+		steps = append([]step{
+			{nil, `UPDATE comments SET parent_id = NULL WHERE site_id = ? AND author_id != ? AND parent_id IN (` + authoredComments + `)`, []interface{}{siteID, authorID, siteID, authorID}},
+		}, steps...)
+	}
+
+	for _, st := range steps {
+		res, err := tx.ExecContext(ctx, st.query, st.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s user data: %w", mode, err)
+		}
+		if st.counter != nil {
+			n, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("failed to count affected rows: %w", err)
+			}
+			*st.counter += n
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// UserDataExport is everything a site holds about one commenter, for answering
+// a data-subject access request. It contains nothing written by other users.
+type UserDataExport struct {
+	SiteID     string                `json:"site_id"`
+	UserID     string                `json:"user_id"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Profile    *User                 `json:"profile"`
+	Comments   []ExportedUserComment `json:"comments"`
+	Reactions  []ExportedReaction    `json:"reactions"`
+	Reports    []ExportedReport      `json:"reports"`
+}
+
+// ExportedPage is the page an exported item belongs to
+type ExportedPage struct {
+	PageID    string `json:"page_id"`
+	PagePath  string `json:"page_path"`
+	PageTitle string `json:"page_title"`
+}
+
+// ExportedUserComment is a comment written by the exported user
+type ExportedUserComment struct {
+	ID string `json:"id"`
+	ExportedPage
+	ParentID    string    `json:"parent_id,omitempty"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email,omitempty"`
+	Text        string    `json:"text"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ExportedReaction is a reaction left by the exported user on a page or comment
+type ExportedReaction struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Emoji     string `json:"emoji"`
+	CommentID string `json:"comment_id,omitempty"`
+	ExportedPage
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportedReport is a report the exported user filed against a comment. The
+// reported comment itself belongs to someone else and isn't included.
+type ExportedReport struct {
+	ID        string `json:"id"`
+	CommentID string `json:"comment_id"`
+	ExportedPage
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportUserData collects a commenter's profile, comments, reactions and
+// report submissions on a site, each with the page it belongs to
+func (s *UserStore) ExportUserData(ctx context.Context, siteID, authorID string) (*UserDataExport, error) {
+	if authorID == "" {
+		return nil, fmt.Errorf("author ID is required")
+	}
+
+	profile, err := s.GetBySiteAndID(ctx, siteID, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserDataExport{
+		SiteID:     siteID,
+		UserID:     authorID,
+		ExportedAt: time.Now().UTC(),
+		Profile:    profile,
+		Comments:   []ExportedUserComment{},
+		Reactions:  []ExportedReaction{},
+		Reports:    []ExportedReport{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.page_id, COALESCE(p.path, ''), COALESCE(p.title, ''), COALESCE(c.parent_id, ''),
+			c.author, COALESCE(c.author_email, ''), c.text, COALESCE(c.status, ''), c.created_at, c.updated_at
+		FROM comments c
+		LEFT JOIN pages p ON p.id = c.page_id
+		WHERE c.site_id = ? AND c.author_id = ?
+		ORDER BY c.created_at ASC
+	`, siteID, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ExportedUserComment
+		if err := rows.Scan(&c.ID, &c.PageID, &c.PagePath, &c.PageTitle, &c.ParentID,
+			&c.Author, &c.AuthorEmail, &c.Text, &c.Status, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		export.Comments = append(export.Comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+
+	// A comment reaction's page is the page of the comment
+	reactionRows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, ar.name, ar.emoji, COALESCE(r.comment_id, ''),
+			COALESCE(r.page_id, c.page_id, ''), COALESCE(p.path, ''), COALESCE(p.title, ''), r.created_at
+		FROM reactions r
+		INNER JOIN allowed_reactions ar ON ar.id = r.allowed_reaction_id
+		LEFT JOIN comments c ON c.id = r.comment_id
+		LEFT JOIN pages p ON p.id = COALESCE(r.page_id, c.page_id)
+		WHERE ar.site_id = ? AND r.user_id = ?
+		ORDER BY r.created_at ASC
+	`, siteID, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions: %w", err)
+	}
+	defer reactionRows.Close()
+	for reactionRows.Next() {
+		var r ExportedReaction
+		if err := reactionRows.Scan(&r.ID, &r.Name, &r.Emoji, &r.CommentID,
+			&r.PageID, &r.PagePath, &r.PageTitle, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		export.Reactions = append(export.Reactions, r)
+	}
+	if err := reactionRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reactions: %w", err)
+	}
+
+	reportRows, err := s.db.QueryContext(ctx, `
+		SELECT rep.id, rep.comment_id, c.page_id, COALESCE(p.path, ''), COALESCE(p.title, ''),
+			COALESCE(rep.reason, ''), rep.created_at
+		FROM comment_reports rep
+		INNER JOIN comments c ON c.id = rep.comment_id
+		LEFT JOIN pages p ON p.id = c.page_id
+		WHERE c.site_id = ? AND rep.reporter_user_id = ?
+		ORDER BY rep.created_at ASC
+	`, siteID, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer reportRows.Close()
+	for reportRows.Next() {
+		var rep ExportedReport
+		if err := reportRows.Scan(&rep.ID, &rep.CommentID, &rep.PageID, &rep.PagePath, &rep.PageTitle,
+			&rep.Reason, &rep.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		export.Reports = append(export.Reports, rep)
+	}
+	if err := reportRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reports: %w", err)
+	}
+
+	return export, nil
+}
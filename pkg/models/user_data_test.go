@@ -0,0 +1,246 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedUserData creates a site where alice and bob comment on and react to each
+// other's comments and report each other, returning the database and site ID
+func seedUserData(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	sqliteStore := createTestDB(t)
+	t.Cleanup(func() { sqliteStore.Close() })
+
+	db := sqliteStore.GetDB()
+	ctx := context.Background()
+	adminUser, _ := NewAdminUserStore(db).Create(ctx, "admin@example.com", "Admin", "auth0|admin")
+	site, _ := NewSiteStore(db).Create(ctx, adminUser.ID, "Test Site", "example.com", "")
+	page, _ := NewPageStore(db).Create(ctx, site.ID, "/post", "Post")
+
+	userStore := NewUserStore(db)
+	for _, id := range []string{"alice", "bob"} {
+		if err := userStore.CreateOrUpdate(ctx, &User{ID: id, SiteID: site.ID, Name: id, Email: id + "@example.com"}); err != nil {
+			t.Fatalf("Failed to create user %s: %v", id, err)
+		}
+	}
+
+	now := time.Now()
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO comments (id, site_id, page_id, author, author_id, author_email, text, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, 'approved', ?, ?)`,
+			[]interface{}{"alice-1", site.ID, page.ID, "Alice", "alice", "alice@example.com", "First", now, now}},
+		{`INSERT INTO comments (id, site_id, page_id, author, author_id, author_email, text, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, 'approved', ?, ?)`,
+			[]interface{}{"alice-2", site.ID, page.ID, "Alice", "alice", "alice@example.com", "Second", now, now}},
+		{`INSERT INTO comments (id, site_id, page_id, author, author_id, author_email, text, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, 'approved', ?, ?)`,
+			[]interface{}{"bob-1", site.ID, page.ID, "Bob", "bob", "bob@example.com", "Reply", now, now}},
+		{`INSERT INTO allowed_reactions (id, site_id, name, emoji, reaction_type) VALUES ('like', ?, 'like', '👍', 'comment')`, []interface{}{site.ID}},
+		{`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES ('r-alice', 'bob-1', 'like', 'alice')`, nil},
+		{`INSERT INTO reactions (id, comment_id, allowed_reaction_id, user_id) VALUES ('r-bob', 'alice-1', 'like', 'bob')`, nil},
+		{`INSERT INTO comment_reports (id, comment_id, reporter_user_id) VALUES ('rep-alice', 'bob-1', 'alice')`, nil},
+		{`INSERT INTO comment_reports (id, comment_id, reporter_user_id) VALUES ('rep-bob', 'alice-2', 'bob')`, nil},
+		{`INSERT INTO page_subscriptions (site_id, page_id, user_id) VALUES (?, ?, 'alice')`, []interface{}{site.ID, page.ID}},
+		{`INSERT INTO page_subscriptions (site_id, page_id, user_id) VALUES (?, ?, 'bob')`, []interface{}{site.ID, page.ID}},
+	}
+	for _, st := range statements {
+		if _, err := db.Exec(st.query, st.args...); err != nil {
+			t.Fatalf("Failed to seed data: %v", err)
+		}
+	}
+
+	return db, site.ID
+}
+
+func countRows(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	return n
+}
+
+func TestUserStore_DeleteUserData_Anonymize(t *testing.T) {
+	db, siteID := seedUserData(t)
+
+	result, err := NewUserStore(db).DeleteUserData(context.Background(), siteID, "alice", DeletionModeAnonymize)
+	if err != nil {
+		t.Fatalf("DeleteUserData failed: %v", err)
+	}
+	if result.Comments != 2 || result.Reactions != 1 || result.Reports != 1 || result.Users != 1 {
+		t.Errorf("Unexpected counts: %+v", result)
+	}
+
+	// Alice's comments stay, without anything identifying her
+	rows, err := db.Query(`SELECT author, author_id, author_email FROM comments WHERE id IN ('alice-1', 'alice-2')`)
+	if err != nil {
+		t.Fatalf("Failed to query comments: %v", err)
+	}
+	defer rows.Close()
+	kept := 0
+	for rows.Next() {
+		var author, authorID string
+		var email sql.NullString
+		if err := rows.Scan(&author, &authorID, &email); err != nil {
+			t.Fatalf("Failed to scan comment: %v", err)
+		}
+		if author != DeletedUserName || authorID != "" || email.Valid {
+			t.Errorf("Expected an anonymized comment, got author=%q author_id=%q email=%v", author, authorID, email)
+		}
+		kept++
+	}
+	if kept != 2 {
+		t.Errorf("Expected both comments to be kept, got %d", kept)
+	}
+
+	// Her reaction and report still count but no longer point at her
+	if n := countRows(t, db, `SELECT COUNT(*) FROM reactions WHERE user_id = 'alice'`); n != 0 {
+		t.Errorf("Expected no reactions by alice, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM reactions WHERE comment_id = 'bob-1'`); n != 1 {
+		t.Errorf("Expected bob's comment to keep its reaction, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comment_reports WHERE reporter_user_id = 'alice'`); n != 0 {
+		t.Errorf("Expected no reports by alice, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comment_reports`); n != 2 {
+		t.Errorf("Expected both reports to be kept, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM users WHERE site_id = ? AND id = 'alice'`, siteID); n != 0 {
+		t.Errorf("Expected alice's user row to be deleted")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM page_subscriptions WHERE user_id = 'alice'`); n != 0 {
+		t.Errorf("Expected alice's page subscriptions to be deleted, got %d", n)
+	}
+
+	// Bob is untouched
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comments WHERE author_id = 'bob' AND author = 'Bob'`); n != 1 {
+		t.Errorf("Expected bob's comment to be untouched")
+	}
+}
+
+func TestUserStore_DeleteUserData_Purge(t *testing.T) {
+	db, siteID := seedUserData(t)
+
+	result, err := NewUserStore(db).DeleteUserData(context.Background(), siteID, "alice", DeletionModePurge)
+	if err != nil {
+		t.Fatalf("DeleteUserData failed: %v", err)
+	}
+	// Her reaction on bob's comment and bob's reaction on hers; her report and
+	// bob's report on her comment
+	if result.Comments != 2 || result.Reactions != 2 || result.Reports != 2 || result.Users != 1 {
+		t.Errorf("Unexpected counts: %+v", result)
+	}
+
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comments WHERE id IN ('alice-1', 'alice-2')`); n != 0 {
+		t.Errorf("Expected alice's comments to be deleted, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM reactions`); n != 0 {
+		t.Errorf("Expected no reactions left, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comment_reports`); n != 0 {
+		t.Errorf("Expected no reports left, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM users WHERE site_id = ?`, siteID); n != 1 {
+		t.Errorf("Expected only bob's user row to remain, got %d", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comments WHERE id = 'bob-1'`); n != 1 {
+		t.Errorf("Expected bob's comment to remain")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM page_subscriptions`); n != 1 {
+		t.Errorf("Expected only bob's page subscription to remain, got %d", n)
+	}
+}
+
+func TestUserStore_DeleteUserData_PurgeReparentsReplies(t *testing.T) {
+	db, siteID := seedUserData(t)
+
+	// bob-1 <- alice-3 <- alice-4 <- bob-2, and alice-1 <- bob-3
+	now := time.Now()
+	for _, c := range []struct{ id, author, parent string }{
+		{"alice-3", "alice", "bob-1"},
+		{"alice-4", "alice", "alice-3"},
+		{"bob-2", "bob", "alice-4"},
+		{"bob-3", "bob", "alice-1"},
+	} {
+		_, err := db.Exec(`INSERT INTO comments (id, site_id, page_id, author, author_id, text, parent_id, status, created_at, updated_at)
+			SELECT ?, site_id, page_id, ?, ?, 'Reply', ?, 'approved', ?, ? FROM comments WHERE id = 'bob-1'`,
+			c.id, c.author, c.author, c.parent, now, now)
+		if err != nil {
+			t.Fatalf("Failed to add comment %s: %v", c.id, err)
+		}
+	}
+
+	if _, err := NewUserStore(db).DeleteUserData(context.Background(), siteID, "alice", DeletionModePurge); err != nil {
+		t.Fatalf("DeleteUserData failed: %v", err)
+	}
+
+	// Bob's replies stay, attached to the nearest ancestor that remains
+	for id, want := range map[string]string{"bob-2": "bob-1", "bob-3": ""} {
+		var parent sql.NullString
+		if err := db.QueryRow(`SELECT parent_id FROM comments WHERE id = ?`, id).Scan(&parent); err != nil {
+			t.Fatalf("Expected %s to remain: %v", id, err)
+		}
+		if parent.String != want {
+			t.Errorf("Expected %s to have parent %q, got %q", id, want, parent.String)
+		}
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comments c WHERE c.parent_id IS NOT NULL AND c.parent_id != '' AND NOT EXISTS (SELECT 1 FROM comments p WHERE p.id = c.parent_id)`); n != 0 {
+		t.Errorf("Expected no replies to missing comments, got %d", n)
+	}
+}
+
+func TestUserStore_DeleteUserData_InvalidMode(t *testing.T) {
+	db, siteID := seedUserData(t)
+
+	if _, err := NewUserStore(db).DeleteUserData(context.Background(), siteID, "alice", "shred"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM comments WHERE author_id = 'alice'`); n != 2 {
+		t.Errorf("Expected nothing to change, got %d comments", n)
+	}
+}
+
+func TestUserStore_ExportUserData(t *testing.T) {
+	db, siteID := seedUserData(t)
+
+	export, err := NewUserStore(db).ExportUserData(context.Background(), siteID, "alice")
+	if err != nil {
+		t.Fatalf("ExportUserData failed: %v", err)
+	}
+
+	if export.Profile == nil || export.Profile.ID != "alice" {
+		t.Errorf("Expected alice's profile, got %+v", export.Profile)
+	}
+	if len(export.Comments) != 2 {
+		t.Fatalf("Expected alice's 2 comments, got %d", len(export.Comments))
+	}
+	for _, c := range export.Comments {
+		if c.Author != "Alice" || c.PagePath != "/post" || c.PageTitle != "Post" || c.CreatedAt.IsZero() {
+			t.Errorf("Unexpected exported comment: %+v", c)
+		}
+	}
+	if len(export.Reactions) != 1 || export.Reactions[0].ID != "r-alice" || export.Reactions[0].CommentID != "bob-1" || export.Reactions[0].PagePath != "/post" {
+		t.Errorf("Expected only alice's reaction with its page, got %+v", export.Reactions)
+	}
+	if len(export.Reports) != 1 || export.Reports[0].ID != "rep-alice" || export.Reports[0].PagePath != "/post" {
+		t.Errorf("Expected only alice's report with its page, got %+v", export.Reports)
+	}
+
+	// Nothing written by bob is included
+	body, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("Failed to encode export: %v", err)
+	}
+	for _, leaked := range []string{"bob@example.com", `"Reply"`, "r-bob", "rep-bob"} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("Expected export not to contain %s", leaked)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package moderation
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Compile-time check to ensure AkismetModerator implements DetailedModerator interface
+var _ DetailedModerator = (*AkismetModerator)(nil)
+
+// DefaultAkismetBaseURL is the Akismet REST API endpoint
+const DefaultAkismetBaseURL = "https://rest.akismet.com/1.1"
+
+// Confidence scores reported for Akismet verdicts. Akismet gives a binary answer,
+// so spam maps above the default auto-reject threshold and ham below auto-approve.
+const (
+	akismetSpamConfidence    = 0.95
+	akismetDiscardConfidence = 1.0 // Akismet is certain ("pro-tip: discard")
+	akismetHamConfidence     = 0.05
+)
+
+// AkismetModerator implements spam checking using the Akismet API
+type AkismetModerator struct {
+	APIKey     string // used when the site's config doesn't set AkismetAPIKey
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewAkismetModerator creates a new Akismet-based moderator
+func NewAkismetModerator(apiKey string) *AkismetModerator {
+	return &AkismetModerator{
+		APIKey:     apiKey,
+		BaseURL:    DefaultAkismetBaseURL,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AnalyzeComment checks comment text for spam without author or site details.
+// Akismet requires a site URL, so prefer AnalyzeCommentDetails.
+func (m *AkismetModerator) AnalyzeComment(text string, config ModerationConfig) (*ModerationResult, error) {
+	return m.AnalyzeCommentDetails(CommentDetails{Text: text}, config)
+}
+
+// AnalyzeCommentDetails checks a comment with Akismet's comment-check endpoint
+func (m *AkismetModerator) AnalyzeCommentDetails(details CommentDetails, config ModerationConfig) (*ModerationResult, error) {
+	if !config.CheckSpam {
+		return &ModerationResult{
+			Decision:   "approve",
+			Confidence: 0.0,
+			Reason:     "Spam checking disabled",
+			Categories: []string{},
+			AnalyzedAt: time.Now(),
+		}, nil
+	}
+
+	apiKey := m.apiKey(config)
+	if apiKey == "" {
+		return nil, fmt.Errorf("Akismet API key is not configured")
+	}
+	if details.SiteURL == "" {
+		return nil, fmt.Errorf("site URL is required for Akismet")
+	}
+
+	form := url.Values{
+		"api_key":              {apiKey},
+		"blog":                 {details.SiteURL},
+		"user_ip":              {details.UserIP},
+		"user_agent":           {details.UserAgent},
+		"comment_type":         {"comment"},
+		"comment_author":       {details.Author},
+		"comment_author_email": {details.AuthorEmail},
+		"comment_content":      {details.Text},
+	}
+	if details.Permalink != "" {
+		form.Set("permalink", details.Permalink)
+	}
+
+	resp, body, err := m.post("comment-check", form)
+	if err != nil {
+		return nil, err
+	}
+
+	switch body {
+	case "true":
+		confidence := akismetSpamConfidence
+		if resp.Header.Get("X-akismet-pro-tip") == "discard" {
+			confidence = akismetDiscardConfidence
+		}
+		return &ModerationResult{
+			Decision:   "reject",
+			Confidence: confidence,
+			Reason:     "Akismet classified the comment as spam",
+			Categories: []string{"spam"},
+			AnalyzedAt: time.Now(),
+		}, nil
+	case "false":
+		return &ModerationResult{
+			Decision:   "approve",
+			Confidence: akismetHamConfidence,
+			Reason:     "Akismet classified the comment as ham",
+			Categories: []string{},
+			AnalyzedAt: time.Now(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected Akismet response: %s", akismetError(resp, body))
+	}
+}
+
+// VerifyKey checks an API key against Akismet's verify-key endpoint
+func (m *AkismetModerator) VerifyKey(apiKey, siteURL string) (bool, error) {
+	resp, body, err := m.post("verify-key", url.Values{
+		"api_key": {apiKey},
+		"blog":    {siteURL},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch body {
+	case "valid":
+		return true, nil
+	case "invalid":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected Akismet response: %s", akismetError(resp, body))
+	}
+}
+
+// apiKey returns the site's Akismet key, falling back to the moderator's default
+func (m *AkismetModerator) apiKey(config ModerationConfig) string {
+	if config.AkismetAPIKey != "" {
+		return config.AkismetAPIKey
+	}
+	return m.APIKey
+}
+
+// post sends a form-encoded request to an Akismet endpoint and returns the
+// trimmed response body
+func (m *AkismetModerator) post(endpoint string, form url.Values) (*http.Response, string, error) {
+	baseURL := strings.TrimRight(m.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultAkismetBaseURL
+	}
+
+	req, err := http.NewRequest("POST", baseURL+"/"+endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Kotomi/1.0 | Akismet/1.0")
+
+	client := m.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to call Akismet API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("Akismet API returned status %d", resp.StatusCode)
+	}
+
+	return resp, strings.TrimSpace(string(body)), nil
+}
+
+// akismetError describes an unexpected Akismet response, including the debug
+// help header Akismet sets for invalid requests
+func akismetError(resp *http.Response, body string) string {
+	if help := resp.Header.Get("X-akismet-debug-help"); help != "" {
+		return fmt.Sprintf("%s (%s)", body, help)
+	}
+	return body
+}
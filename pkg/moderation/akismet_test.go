@@ -0,0 +1,130 @@
+package moderation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newAkismetTestServer mocks Akismet's verify-key and comment-check endpoints.
+// Comments containing "viagra" are spam; the key "valid-key" is the only valid one.
+func newAkismetTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/1.1/verify-key", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("api_key") == "valid-key" && r.FormValue("blog") != "" {
+			w.Write([]byte("valid"))
+			return
+		}
+		w.Write([]byte("invalid"))
+	})
+	mux.HandleFunc("/1.1/comment-check", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("api_key") != "valid-key" {
+			w.Header().Set("X-akismet-debug-help", "Empty or invalid API key")
+			w.Write([]byte("invalid"))
+			return
+		}
+		if r.FormValue("comment_author_email") != "author@example.com" || r.FormValue("blog") != "https://example.com" {
+			t.Errorf("unexpected comment details: %v", r.Form)
+		}
+		switch r.FormValue("comment_content") {
+		case "buy viagra":
+			w.Write([]byte("true"))
+		case "buy viagra now":
+			w.Header().Set("X-akismet-pro-tip", "discard")
+			w.Write([]byte("true"))
+		default:
+			w.Write([]byte("false"))
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAkismetModerator_VerifyKey(t *testing.T) {
+	server := newAkismetTestServer(t)
+	moderator := NewAkismetModerator("")
+	moderator.BaseURL = server.URL + "/1.1"
+
+	valid, err := moderator.VerifyKey("valid-key", "https://example.com")
+	if err != nil || !valid {
+		t.Errorf("Expected valid key, got %v (%v)", valid, err)
+	}
+
+	valid, err = moderator.VerifyKey("wrong-key", "https://example.com")
+	if err != nil || valid {
+		t.Errorf("Expected invalid key, got %v (%v)", valid, err)
+	}
+}
+
+func TestAkismetModerator_AnalyzeCommentDetails(t *testing.T) {
+	server := newAkismetTestServer(t)
+	moderator := NewAkismetModerator("")
+	moderator.BaseURL = server.URL + "/1.1"
+
+	config := DefaultModerationConfig()
+	config.AkismetAPIKey = "valid-key"
+	details := CommentDetails{
+		Author:      "Author",
+		AuthorEmail: "author@example.com",
+		SiteURL:     "https://example.com",
+	}
+
+	tests := []struct {
+		text       string
+		decision   string
+		confidence float64
+		status     string
+	}{
+		{"buy viagra", "reject", akismetSpamConfidence, "rejected"},
+		{"buy viagra now", "reject", akismetDiscardConfidence, "rejected"},
+		{"Great article, thanks!", "approve", akismetHamConfidence, "approved"},
+	}
+
+	for _, tt := range tests {
+		details.Text = tt.text
+		result, err := moderator.AnalyzeCommentDetails(details, config)
+		if err != nil {
+			t.Fatalf("AnalyzeCommentDetails(%q) failed: %v", tt.text, err)
+		}
+		if result.Decision != tt.decision || result.Confidence != tt.confidence {
+			t.Errorf("AnalyzeCommentDetails(%q) = %s/%.2f, want %s/%.2f", tt.text, result.Decision, result.Confidence, tt.decision, tt.confidence)
+		}
+		if status := DetermineStatus(result, config); status != tt.status {
+			t.Errorf("Expected status %s for %q, got %s", tt.status, tt.text, status)
+		}
+	}
+}
+
+func TestAkismetModerator_Errors(t *testing.T) {
+	server := newAkismetTestServer(t)
+	config := DefaultModerationConfig()
+	details := CommentDetails{Text: "hello", AuthorEmail: "author@example.com", SiteURL: "https://example.com"}
+
+	moderator := NewAkismetModerator("")
+	moderator.BaseURL = server.URL + "/1.1"
+	if _, err := moderator.AnalyzeCommentDetails(details, config); err == nil {
+		t.Error("Expected error without an API key")
+	}
+
+	// The moderator's default key applies when the site has none, and an
+	// invalid key is reported as an error rather than a verdict
+	moderator.APIKey = "wrong-key"
+	if _, err := moderator.AnalyzeCommentDetails(details, config); err == nil {
+		t.Error("Expected error for an invalid API key")
+	}
+
+	// Network failures must surface as errors so the handler keeps the default status
+	unreachable := NewAkismetModerator("valid-key")
+	unreachable.BaseURL = "http://127.0.0.1:1/1.1"
+	if _, err := unreachable.AnalyzeCommentDetails(details, config); err == nil {
+		t.Error("Expected error when Akismet is unreachable")
+	}
+
+	config.CheckSpam = false
+	result, err := unreachable.AnalyzeCommentDetails(details, config)
+	if err != nil || result.Decision != "approve" {
+		t.Errorf("Expected approval without a call when spam checks are disabled, got %v (%v)", result, err)
+	}
+}
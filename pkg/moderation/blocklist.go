@@ -0,0 +1,160 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses a comment can be given when it contains a blocked word
+const (
+	BlockedWordActionReject = "rejected"
+	BlockedWordActionFlag   = "pending"
+)
+
+// BlockedWordMatcher finds blocked words in comment text. Words match
+// case-insensitively and only as whole words, so "ass" does not match "class".
+type BlockedWordMatcher struct {
+	pattern *regexp.Regexp
+}
+
+// NewBlockedWordMatcher compiles a matcher for words. A matcher built from an
+// empty list never matches.
+func NewBlockedWordMatcher(words []string) (*BlockedWordMatcher, error) {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		word = normalizeBlockedWord(word)
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return &BlockedWordMatcher{}, nil
+	}
+
+	// Go's \b only understands ASCII word characters, so boundaries are spelled
+	// out with Unicode letter and number classes instead
+	pattern, err := regexp.Compile(`(?i)(?:^|[^\p{L}\p{N}_])(` + strings.Join(quoted, "|") + `)(?:$|[^\p{L}\p{N}_])`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile blocked words: %w", err)
+	}
+
+	return &BlockedWordMatcher{pattern: pattern}, nil
+}
+
+// Match returns the first blocked word found in text
+func (m *BlockedWordMatcher) Match(text string) (string, bool) {
+	if m == nil || m.pattern == nil {
+		return "", false
+	}
+	match := m.pattern.FindStringSubmatch(text)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// blocklistCacheEntry is a compiled matcher together with the word list it was built from
+type blocklistCacheEntry struct {
+	words   string
+	matcher *BlockedWordMatcher
+}
+
+// AddBlockedWord adds a word to a site's blocklist. Adding an existing word is a no-op.
+func (s *ConfigStore) AddBlockedWord(ctx context.Context, siteID, word string) error {
+	word = normalizeBlockedWord(word)
+	if word == "" {
+		return fmt.Errorf("blocked word cannot be empty")
+	}
+
+	query := `
+		INSERT INTO blocked_words (id, site_id, word, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(site_id, word) DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), uuid.NewString(), siteID, word, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add blocked word: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveBlockedWord removes a word from a site's blocklist
+func (s *ConfigStore) RemoveBlockedWord(ctx context.Context, siteID, word string) error {
+	query := `DELETE FROM blocked_words WHERE site_id = ? AND word = ?`
+
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), siteID, normalizeBlockedWord(word))
+	if err != nil {
+		return fmt.Errorf("failed to remove blocked word: %w", err)
+	}
+
+	return nil
+}
+
+// ListBlockedWords returns a site's blocked words in alphabetical order
+func (s *ConfigStore) ListBlockedWords(ctx context.Context, siteID string) ([]string, error) {
+	query := `SELECT word FROM blocked_words WHERE site_id = ? ORDER BY word`
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query), siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked words: %w", err)
+	}
+	defer rows.Close()
+
+	words := []string{}
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked word: %w", err)
+		}
+		words = append(words, word)
+	}
+
+	return words, rows.Err()
+}
+
+// MatchBlockedWords checks text against a site's blocklist and returns the first
+// blocked word found. The compiled matcher is cached per site and only rebuilt
+// when the site's word list changes, including changes made through another
+// ConfigStore or process.
+func (s *ConfigStore) MatchBlockedWords(ctx context.Context, siteID, text string) (string, bool, error) {
+	words, err := s.ListBlockedWords(ctx, siteID)
+	if err != nil {
+		return "", false, err
+	}
+	if len(words) == 0 {
+		return "", false, nil
+	}
+	key := strings.Join(words, "\n")
+
+	s.mu.Lock()
+	entry, ok := s.matchers[siteID]
+	s.mu.Unlock()
+
+	if !ok || entry.words != key {
+		matcher, err := NewBlockedWordMatcher(words)
+		if err != nil {
+			return "", false, err
+		}
+		entry = blocklistCacheEntry{words: key, matcher: matcher}
+
+		s.mu.Lock()
+		s.matchers[siteID] = entry
+		s.mu.Unlock()
+	}
+
+	word, matched := entry.matcher.Match(text)
+	return word, matched, nil
+}
+
+// normalizeBlockedWord trims and lowercases a word so the blocklist stays free of
+// case-only duplicates
+func normalizeBlockedWord(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
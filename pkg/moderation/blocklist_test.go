@@ -0,0 +1,59 @@
+package moderation
+
+import "testing"
+
+func TestBlockedWordMatcher(t *testing.T) {
+	matcher, err := NewBlockedWordMatcher([]string{"ass", "Straße", "日本", "c++", "so much"})
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		match string
+	}{
+		{"exact word", "you ass", "ass"},
+		{"case insensitive", "YOU ASS!", "ASS"},
+		{"punctuation boundary", "(ass)", "ass"},
+		{"substring inside word", "a classic class", ""},
+		{"prefix of longer word", "assessment", ""},
+		{"unicode case folding", "Die STRASSE ist lang, die STRAßE auch", "STRAßE"},
+		{"unicode letters are word characters", "straßenbahn", ""},
+		{"accented neighbour", "éass", ""},
+		{"non-latin script", "日本 is great", "日本"},
+		{"regex metacharacters", "I write c++ daily", "c++"},
+		{"multi-word phrase", "there is so much spam", "so much"},
+		{"clean text", "Thanks for the article!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			word, matched := matcher.Match(tt.text)
+			if tt.match == "" {
+				if matched {
+					t.Errorf("Expected no match in %q, got %q", tt.text, word)
+				}
+				return
+			}
+			if !matched || word != tt.match {
+				t.Errorf("Expected %q to match %q, got %q (%v)", tt.text, tt.match, word, matched)
+			}
+		})
+	}
+}
+
+func TestBlockedWordMatcher_Empty(t *testing.T) {
+	matcher, err := NewBlockedWordMatcher([]string{"", "  "})
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+	if _, matched := matcher.Match("anything"); matched {
+		t.Error("Expected empty matcher never to match")
+	}
+
+	var nilMatcher *BlockedWordMatcher
+	if _, matched := nilMatcher.Match("anything"); matched {
+		t.Error("Expected nil matcher never to match")
+	}
+}
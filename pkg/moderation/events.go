@@ -0,0 +1,117 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sources of a moderation decision
+const (
+	SourceAI         = "ai"
+	SourceManual     = "manual"
+	SourceBlocklist  = "blocklist"
+	SourceReports    = "reports"    // user reports crossed the site's threshold
+	SourceReputation = "reputation" // trusted author skipped AI moderation
+	SourceLinks      = "links"      // too many links, or mostly links
+)
+
+// Event is a recorded change to a comment's moderation status
+type Event struct {
+	ID          string    `json:"id"`
+	CommentID   string    `json:"comment_id"`
+	SiteID      string    `json:"site_id"`
+	Decision    string    `json:"decision"` // resulting status: approved, rejected, pending
+	Source      string    `json:"source"`   // ai, manual, blocklist, reports, reputation, links
+	Confidence  *float64  `json:"confidence,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	ModeratorID string    `json:"moderator_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// EventStore handles the moderation_events audit table
+type EventStore struct {
+	db *sql.DB
+}
+
+// NewEventStore creates a new moderation event store
+func NewEventStore(db *sql.DB) *EventStore {
+	return &EventStore{db: db}
+}
+
+// Record saves a moderation event. It is safe to call on a nil EventStore so
+// callers without a SQL database don't need to check.
+func (s *EventStore) Record(ctx context.Context, event Event) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	var confidence sql.NullFloat64
+	if event.Confidence != nil {
+		confidence = sql.NullFloat64{Float64: *event.Confidence, Valid: true}
+	}
+	var reason, moderatorID sql.NullString
+	if event.Reason != "" {
+		reason = sql.NullString{String: event.Reason, Valid: true}
+	}
+	if event.ModeratorID != "" {
+		moderatorID = sql.NullString{String: event.ModeratorID, Valid: true}
+	}
+
+	query := `
+		INSERT INTO moderation_events (id, comment_id, site_id, decision, source, confidence, reason, moderator_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, event.ID, event.CommentID, event.SiteID, event.Decision, event.Source,
+		confidence, reason, moderatorID, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record moderation event: %w", err)
+	}
+
+	return nil
+}
+
+// ListByComment returns a comment's moderation events, oldest first
+func (s *EventStore) ListByComment(ctx context.Context, commentID string) ([]Event, error) {
+	query := `
+		SELECT id, comment_id, site_id, decision, source, confidence, reason, moderator_id, created_at
+		FROM moderation_events
+		WHERE comment_id = ?
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderation events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		var confidence sql.NullFloat64
+		var reason, moderatorID sql.NullString
+		if err := rows.Scan(&event.ID, &event.CommentID, &event.SiteID, &event.Decision, &event.Source,
+			&confidence, &reason, &moderatorID, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation event: %w", err)
+		}
+		if confidence.Valid {
+			event.Confidence = &confidence.Float64
+		}
+		event.Reason = reason.String
+		event.ModeratorID = moderatorID.String
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
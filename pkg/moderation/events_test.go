@@ -0,0 +1,73 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+)
+
+func TestEventStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := comments.ApplySchema(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	ctx := context.Background()
+	store := NewEventStore(db)
+	confidence := 0.92
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := store.Record(ctx, Event{
+		CommentID:  "comment-1",
+		SiteID:     "site1",
+		Decision:   "rejected",
+		Source:     SourceAI,
+		Confidence: &confidence,
+		Reason:     "spam",
+		CreatedAt:  now,
+	}); err != nil {
+		t.Fatalf("Failed to record AI event: %v", err)
+	}
+	if err := store.Record(ctx, Event{
+		CommentID:   "comment-1",
+		SiteID:      "site1",
+		Decision:    "approved",
+		Source:      SourceManual,
+		ModeratorID: "admin-1",
+		CreatedAt:   now.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to record manual event: %v", err)
+	}
+
+	events, err := store.ListByComment(ctx, "comment-1")
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Source != SourceAI || events[0].Confidence == nil || *events[0].Confidence != confidence {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if events[1].Source != SourceManual || events[1].ModeratorID != "admin-1" || events[1].Confidence != nil {
+		t.Errorf("Unexpected second event: %+v", events[1])
+	}
+	if events[0].ID == "" || events[0].ID == events[1].ID {
+		t.Error("Expected generated unique event IDs")
+	}
+
+	var nilStore *EventStore
+	if err := nilStore.Record(ctx, Event{CommentID: "comment-1"}); err != nil {
+		t.Errorf("Expected nil store Record to be a no-op, got %v", err)
+	}
+}
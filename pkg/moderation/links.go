@@ -0,0 +1,61 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxLinks is the number of links a comment may contain before the link
+// rule applies
+const DefaultMaxLinks = 3
+
+// MaxLinkTextRatio is the share of a comment's characters that may be links
+// before the comment counts as mostly links
+const MaxLinkTextRatio = 0.5
+
+// linkURLPattern matches http(s) URLs and bare www. hosts
+var linkURLPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s<>()"']+`)
+
+// CountLinks returns the number of links in text and how many characters they span
+func CountLinks(text string) (count, chars int) {
+	for _, link := range linkURLPattern.FindAllString(text, -1) {
+		// Sentence punctuation right after a URL isn't part of it
+		link = strings.TrimRight(link, ".,;:!?")
+		count++
+		chars += utf8.RuneCountInString(link)
+	}
+	return count, chars
+}
+
+// CheckLinks applies the link rule to text and returns the reason it tripped.
+// A comment trips the rule when it has more than maxLinks links, or when links
+// make up more than MaxLinkTextRatio of its non-space characters. A maxLinks of
+// 0 or less turns the rule off.
+func CheckLinks(text string, maxLinks int) (string, bool) {
+	if maxLinks <= 0 {
+		return "", false
+	}
+
+	count, chars := CountLinks(text)
+	if count > maxLinks {
+		return fmt.Sprintf("Contains %d links (limit %d)", count, maxLinks), true
+	}
+
+	total := utf8.RuneCountInString(strings.Join(strings.Fields(text), ""))
+	if count > 0 && total > 0 && float64(chars)/float64(total) > MaxLinkTextRatio {
+		return fmt.Sprintf("Mostly links (%d of %d characters)", chars, total), true
+	}
+
+	return "", false
+}
+
+// linkAction returns the configured link rule action, defaulting to holding the
+// comment for review
+func linkAction(config ModerationConfig) string {
+	if config.LinkAction == BlockedWordActionReject {
+		return BlockedWordActionReject
+	}
+	return BlockedWordActionFlag
+}
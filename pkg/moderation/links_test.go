@@ -0,0 +1,41 @@
+package moderation
+
+import "testing"
+
+func TestCheckLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxLinks int
+		tripped  bool
+	}{
+		{"no links", "Thanks for writing this up, it cleared a lot up for me.", 3, false},
+		{"single link in prose", "I wrote a follow-up on this at https://example.com/posts/follow-up if anyone is interested in the details.", 3, false},
+		{"at the limit", "See https://a.example and https://b.example and www.c.example for three takes on the same problem discussed here.", 3, false},
+		{"link heavy", "cheap https://a.example https://b.example https://c.example https://d.example", 3, true},
+		{"mostly links", "nice https://spam.example/buy-now-limited-offer", 3, true},
+		{"rule off", "https://a.example https://b.example https://c.example https://d.example", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, tripped := CheckLinks(tt.text, tt.maxLinks)
+			if tripped != tt.tripped {
+				t.Errorf("CheckLinks(%q, %d) = %v (%q), want %v", tt.text, tt.maxLinks, tripped, reason, tt.tripped)
+			}
+			if tripped && reason == "" {
+				t.Error("Expected a reason when the rule trips")
+			}
+		})
+	}
+}
+
+func TestCountLinks(t *testing.T) {
+	count, chars := CountLinks("read (https://example.com/a) and www.example.org, then http://x.io")
+	if count != 3 {
+		t.Errorf("Expected 3 links, got %d", count)
+	}
+	if want := len("https://example.com/a") + len("www.example.org") + len("http://x.io"); chars != want {
+		t.Errorf("Expected %d link characters, got %d", want, chars)
+	}
+}
@@ -1,6 +1,7 @@
 package moderation
 
 import (
+	"strings"
 	"time"
 )
 
@@ -10,9 +11,29 @@ type ModerationResult struct {
 	Confidence float64 `json:"confidence"`  // 0.0 to 1.0
 	Reason     string  `json:"reason"`      // Explanation for the decision
 	Categories []string `json:"categories"` // List of detected issues (spam, offensive, etc.)
+	Sentiment  string   `json:"sentiment,omitempty"` // Coarse tone, when the moderator reports one
 	AnalyzedAt time.Time `json:"analyzed_at"`
 }
 
+// Sentiment buckets a moderator may report for a comment
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// NormalizeSentiment maps a moderator's sentiment label onto one of the
+// Sentiment buckets, returning "" for anything it doesn't recognize
+func NormalizeSentiment(sentiment string) string {
+	switch s := strings.ToLower(strings.TrimSpace(sentiment)); s {
+	case SentimentPositive, SentimentNeutral, SentimentNegative:
+		return s
+	case "mixed":
+		return SentimentNeutral
+	}
+	return ""
+}
+
 // ModerationConfig represents moderation settings for a site
 type ModerationConfig struct {
 	Enabled            bool    `json:"enabled"`
@@ -22,13 +43,45 @@ type ModerationConfig struct {
 	CheckOffensive     bool    `json:"check_offensive"`
 	CheckAggressive    bool    `json:"check_aggressive"`
 	CheckOffTopic      bool    `json:"check_off_topic"`
+	BlockedWordAction  string  `json:"blocked_word_action"` // status for comments with blocked words: "rejected" or "pending"
+	AkismetAPIKey      string  `json:"akismet_api_key,omitempty"` // when set, spam checks use Akismet for this site
+	// TrustedReputationThreshold lets authors whose reputation score exceeds it skip
+	// AI moderation and be approved directly
+	TrustedReputationThreshold int `json:"trusted_reputation_threshold"`
+	// MaxLinks is how many links a comment may contain before the link rule
+	// applies; 0 turns the rule off
+	MaxLinks   int    `json:"max_links"`
+	LinkAction string `json:"link_action"` // status for link-heavy comments: "rejected" or "pending"
 }
 
+// DefaultTrustedReputationThreshold is high enough that no author reaches it,
+// which leaves trusted auto-approval off until a site lowers it
+const DefaultTrustedReputationThreshold = 1000000
+
 // Moderator is the interface for content moderation
 type Moderator interface {
 	AnalyzeComment(text string, config ModerationConfig) (*ModerationResult, error)
 }
 
+// CommentDetails carries the comment metadata some moderation services need
+// beyond the text itself
+type CommentDetails struct {
+	Text        string
+	Author      string
+	AuthorEmail string
+	SiteURL     string
+	Permalink   string
+	UserIP      string
+	UserAgent   string
+}
+
+// DetailedModerator is implemented by moderators that use comment metadata.
+// Callers with the details available should prefer AnalyzeCommentDetails.
+type DetailedModerator interface {
+	Moderator
+	AnalyzeCommentDetails(details CommentDetails, config ModerationConfig) (*ModerationResult, error)
+}
+
 // DefaultModerationConfig returns the default moderation configuration
 func DefaultModerationConfig() ModerationConfig {
 	return ModerationConfig{
@@ -39,9 +92,18 @@ func DefaultModerationConfig() ModerationConfig {
 		CheckOffensive:       true,
 		CheckAggressive:      true,
 		CheckOffTopic:        false, // Off by default as it's subjective
+		BlockedWordAction:    BlockedWordActionReject,
+		TrustedReputationThreshold: DefaultTrustedReputationThreshold,
+		MaxLinks:             DefaultMaxLinks,
+		LinkAction:           BlockedWordActionFlag,
 	}
 }
 
+// IsTrusted reports whether an author with the given reputation score skips AI moderation
+func (c ModerationConfig) IsTrusted(reputationScore int) bool {
+	return reputationScore > c.TrustedReputationThreshold
+}
+
 // DetermineStatus determines the comment status based on moderation result
 func DetermineStatus(result *ModerationResult, config ModerationConfig) string {
 	if result.Confidence >= config.AutoRejectThreshold {
@@ -78,6 +78,42 @@ func TestDetermineStatus(t *testing.T) {
 	}
 }
 
+func TestNormalizeSentiment(t *testing.T) {
+	tests := map[string]string{
+		"positive":  SentimentPositive,
+		" Negative": SentimentNegative,
+		"NEUTRAL":   SentimentNeutral,
+		"mixed":     SentimentNeutral,
+		"angry":     "",
+		"":          "",
+	}
+	for input, want := range tests {
+		if got := NormalizeSentiment(input); got != want {
+			t.Errorf("NormalizeSentiment(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestOpenAIModerator_ParseSentiment(t *testing.T) {
+	m := &OpenAIModerator{}
+	result, err := m.parseAIResponse(`{"confidence": 0.1, "reason": "Friendly", "categories": [], "sentiment": "Positive"}`)
+	if err != nil {
+		t.Fatalf("parseAIResponse failed: %v", err)
+	}
+	if result.Sentiment != SentimentPositive {
+		t.Errorf("Expected sentiment %q, got %q", SentimentPositive, result.Sentiment)
+	}
+
+	// Responses without a sentiment leave it empty
+	result, err = m.parseAIResponse(`{"confidence": 0.1, "reason": "Friendly", "categories": []}`)
+	if err != nil {
+		t.Fatalf("parseAIResponse failed: %v", err)
+	}
+	if result.Sentiment != "" {
+		t.Errorf("Expected no sentiment, got %q", result.Sentiment)
+	}
+}
+
 func TestMockModerator_CleanComment(t *testing.T) {
 	moderator := NewMockModerator()
 	config := DefaultModerationConfig()
@@ -149,7 +149,8 @@ Respond with a JSON object in this exact format:
 {
   "confidence": <number between 0 and 1, where 1 means definitely problematic>,
   "reason": "<brief explanation>",
-  "categories": [<list of detected issues from: "spam", "offensive", "aggressive", "off_topic">]
+  "categories": [<list of detected issues from: "spam", "offensive", "aggressive", "off_topic">],
+  "sentiment": <overall tone of the comment, one of: "positive", "neutral", "negative">
 }
 
 Be strict but fair. Only flag content that clearly violates standards.`, checksStr, text)
@@ -169,6 +170,7 @@ func (m *OpenAIModerator) parseAIResponse(content string) (*ModerationResult, er
 		Confidence float64  `json:"confidence"`
 		Reason     string   `json:"reason"`
 		Categories []string `json:"categories"`
+		Sentiment  string   `json:"sentiment"`
 	}
 
 	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
@@ -190,5 +192,6 @@ func (m *OpenAIModerator) parseAIResponse(content string) (*ModerationResult, er
 		Confidence: parsed.Confidence,
 		Reason:     parsed.Reason,
 		Categories: parsed.Categories,
+		Sentiment:  NormalizeSentiment(parsed.Sentiment),
 	}, nil
 }
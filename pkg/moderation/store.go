@@ -4,36 +4,72 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
 )
 
-// ConfigStore handles moderation configuration database operations
+// ConfigRepository is the moderation configuration storage used while handling
+// comments, so callers don't depend on a particular database backend
+type ConfigRepository interface {
+	// GetBySiteID retrieves moderation configuration for a site
+	GetBySiteID(ctx context.Context, siteID string) (*ModerationConfig, error)
+	// Create creates moderation configuration for a site
+	Create(ctx context.Context, siteID string, config ModerationConfig) error
+	// Update updates moderation configuration for a site
+	Update(ctx context.Context, siteID string, config ModerationConfig) error
+	// Delete deletes moderation configuration for a site
+	Delete(ctx context.Context, siteID string) error
+	// MatchBlockedWords checks text against a site's blocklist
+	MatchBlockedWords(ctx context.Context, siteID, text string) (string, bool, error)
+}
+
+var _ ConfigRepository = (*ConfigStore)(nil)
+
+// ConfigStore handles moderation configuration database operations. Queries are
+// written with ? placeholders and rebound for the store's SQL dialect, so the
+// same store works on SQLite and PostgreSQL.
 type ConfigStore struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect analytics.Dialect
+
+	mu       sync.Mutex
+	matchers map[string]blocklistCacheEntry // compiled blocklists by site ID
 }
 
-// NewConfigStore creates a new moderation config store
+// NewConfigStore creates a new moderation config store, detecting the SQL dialect from the driver
 func NewConfigStore(db *sql.DB) *ConfigStore {
-	return &ConfigStore{db: db}
+	return NewConfigStoreWithDialect(db, analytics.DetectDialect(db))
+}
+
+// NewConfigStoreWithDialect creates a new moderation config store using an explicit SQL dialect
+func NewConfigStoreWithDialect(db *sql.DB, dialect analytics.Dialect) *ConfigStore {
+	return &ConfigStore{db: db, dialect: dialect, matchers: make(map[string]blocklistCacheEntry)}
 }
 
 // GetBySiteID retrieves moderation configuration for a site
 func (s *ConfigStore) GetBySiteID(ctx context.Context, siteID string) (*ModerationConfig, error) {
 	query := `
 		SELECT enabled, auto_reject_threshold, auto_approve_threshold,
-		       check_spam, check_offensive, check_aggressive, check_off_topic
+		       check_spam, check_offensive, check_aggressive, check_off_topic,
+		       blocked_word_action, akismet_api_key, trusted_reputation_threshold,
+		       max_links, link_action
 		FROM moderation_config
 		WHERE site_id = ?
 	`
 
 	var config ModerationConfig
 	var enabled, checkSpam, checkOffensive, checkAggressive, checkOffTopic int
+	var blockedWordAction, akismetAPIKey, linkActionValue sql.NullString
+	var trustedThreshold, maxLinks sql.NullInt64
 
-	err := s.db.QueryRowContext(ctx, query, siteID).Scan(
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(query), siteID).Scan(
 		&enabled, &config.AutoRejectThreshold, &config.AutoApproveThreshold,
 		&checkSpam, &checkOffensive, &checkAggressive, &checkOffTopic,
+		&blockedWordAction, &akismetAPIKey, &trustedThreshold,
+		&maxLinks, &linkActionValue,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -49,6 +85,20 @@ func (s *ConfigStore) GetBySiteID(ctx context.Context, siteID string) (*Moderati
 	config.CheckOffensive = checkOffensive == 1
 	config.CheckAggressive = checkAggressive == 1
 	config.CheckOffTopic = checkOffTopic == 1
+	config.BlockedWordAction = BlockedWordActionReject
+	if blockedWordAction.String == BlockedWordActionFlag {
+		config.BlockedWordAction = BlockedWordActionFlag
+	}
+	config.AkismetAPIKey = akismetAPIKey.String
+	config.TrustedReputationThreshold = DefaultTrustedReputationThreshold
+	if trustedThreshold.Valid {
+		config.TrustedReputationThreshold = int(trustedThreshold.Int64)
+	}
+	config.MaxLinks = DefaultMaxLinks
+	if maxLinks.Valid {
+		config.MaxLinks = int(maxLinks.Int64)
+	}
+	config.LinkAction = linkAction(ModerationConfig{LinkAction: linkActionValue.String})
 
 	return &config, nil
 }
@@ -62,8 +112,9 @@ func (s *ConfigStore) Create(ctx context.Context, siteID string, config Moderati
 		INSERT INTO moderation_config 
 		(id, site_id, enabled, auto_reject_threshold, auto_approve_threshold,
 		 check_spam, check_offensive, check_aggressive, check_off_topic,
+		 blocked_word_action, akismet_api_key, trusted_reputation_threshold, max_links, link_action,
 		 created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	// Convert booleans to integers
@@ -88,8 +139,9 @@ func (s *ConfigStore) Create(ctx context.Context, siteID string, config Moderati
 		checkOffTopic = 1
 	}
 
-	_, err := s.db.ExecContext(ctx, query, id, siteID, enabled, config.AutoRejectThreshold, config.AutoApproveThreshold,
-		checkSpam, checkOffensive, checkAggressive, checkOffTopic, now, now)
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), id, siteID, enabled, config.AutoRejectThreshold, config.AutoApproveThreshold,
+		checkSpam, checkOffensive, checkAggressive, checkOffTopic, blockedWordAction(config), config.AkismetAPIKey,
+		config.TrustedReputationThreshold, config.MaxLinks, linkAction(config), now, now)
 	if err != nil {
 		return fmt.Errorf("failed to create moderation config: %w", err)
 	}
@@ -103,7 +155,8 @@ func (s *ConfigStore) Update(ctx context.Context, siteID string, config Moderati
 		UPDATE moderation_config
 		SET enabled = ?, auto_reject_threshold = ?, auto_approve_threshold = ?,
 		    check_spam = ?, check_offensive = ?, check_aggressive = ?, check_off_topic = ?,
-		    updated_at = ?
+		    blocked_word_action = ?, akismet_api_key = ?, trusted_reputation_threshold = ?,
+		    max_links = ?, link_action = ?, updated_at = ?
 		WHERE site_id = ?
 	`
 
@@ -129,8 +182,9 @@ func (s *ConfigStore) Update(ctx context.Context, siteID string, config Moderati
 		checkOffTopic = 1
 	}
 
-	result, err := s.db.ExecContext(ctx, query, enabled, config.AutoRejectThreshold, config.AutoApproveThreshold,
-		checkSpam, checkOffensive, checkAggressive, checkOffTopic, time.Now(), siteID)
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), enabled, config.AutoRejectThreshold, config.AutoApproveThreshold,
+		checkSpam, checkOffensive, checkAggressive, checkOffTopic, blockedWordAction(config), config.AkismetAPIKey,
+		config.TrustedReputationThreshold, config.MaxLinks, linkAction(config), time.Now(), siteID)
 	if err != nil {
 		return fmt.Errorf("failed to update moderation config: %w", err)
 	}
@@ -150,10 +204,18 @@ func (s *ConfigStore) Update(ctx context.Context, siteID string, config Moderati
 func (s *ConfigStore) Delete(ctx context.Context, siteID string) error {
 	query := `DELETE FROM moderation_config WHERE site_id = ?`
 
-	_, err := s.db.ExecContext(ctx, query, siteID)
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), siteID)
 	if err != nil {
 		return fmt.Errorf("failed to delete moderation config: %w", err)
 	}
 
 	return nil
 }
+
+// blockedWordAction returns the configured blocklist action, defaulting to reject
+func blockedWordAction(config ModerationConfig) string {
+	if config.BlockedWordAction == BlockedWordActionFlag {
+		return BlockedWordActionFlag
+	}
+	return BlockedWordActionReject
+}
@@ -0,0 +1,84 @@
+//go:build postgres
+
+package moderation
+
+import (
+	"database/sql"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+)
+
+// postgresModerationSchema mirrors the SQLite moderation tables with PostgreSQL types.
+// Flags stay INTEGER so both drivers scan them the same way.
+const postgresModerationSchema = `
+	CREATE TABLE IF NOT EXISTS sites (
+		id TEXT PRIMARY KEY,
+		owner_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		domain TEXT,
+		description TEXT,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS moderation_config (
+		id TEXT PRIMARY KEY,
+		site_id TEXT NOT NULL UNIQUE REFERENCES sites(id) ON DELETE CASCADE,
+		enabled INTEGER DEFAULT 0,
+		auto_reject_threshold DOUBLE PRECISION DEFAULT 0.85,
+		auto_approve_threshold DOUBLE PRECISION DEFAULT 0.30,
+		check_spam INTEGER DEFAULT 1,
+		check_offensive INTEGER DEFAULT 1,
+		check_aggressive INTEGER DEFAULT 1,
+		check_off_topic INTEGER DEFAULT 0,
+		blocked_word_action TEXT DEFAULT 'rejected',
+		akismet_api_key TEXT,
+		trusted_reputation_threshold INTEGER DEFAULT 1000000,
+		max_links INTEGER DEFAULT 3,
+		link_action TEXT DEFAULT 'pending',
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+// TestConfigStore_PostgresRoundTrip runs against the database in
+// KOTOMI_TEST_POSTGRES_URL. The binary must link a Postgres driver registered as
+// KOTOMI_TEST_POSTGRES_DRIVER ("postgres" by default).
+func TestConfigStore_PostgresRoundTrip(t *testing.T) {
+	url := os.Getenv("KOTOMI_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("KOTOMI_TEST_POSTGRES_URL not set")
+	}
+	driver := os.Getenv("KOTOMI_TEST_POSTGRES_DRIVER")
+	if driver == "" {
+		driver = "postgres"
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		t.Skipf("no %q database driver registered", driver)
+	}
+
+	db, err := sql.Open(driver, url)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(postgresModerationSchema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	siteID := "moderation-roundtrip-site"
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING", siteID, "owner1", "Test Site"); err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+	defer db.Exec("DELETE FROM sites WHERE id = $1", siteID)
+
+	store := NewConfigStore(db)
+	if store.dialect.Name() != (analytics.PostgresDialect{}).Name() {
+		t.Fatalf("Expected Postgres dialect to be detected, got %s", store.dialect.Name())
+	}
+	assertConfigRoundTrip(t, store, siteID)
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/saasuke-labs/kotomi/pkg/analytics"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
 )
 
 func TestModerationConfigStore(t *testing.T) {
@@ -21,35 +23,7 @@ func TestModerationConfigStore(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Create moderation_config table
-	schema := `
-	CREATE TABLE IF NOT EXISTS sites (
-		id TEXT PRIMARY KEY,
-		owner_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		domain TEXT,
-		description TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS moderation_config (
-		id TEXT PRIMARY KEY,
-		site_id TEXT NOT NULL UNIQUE,
-		enabled INTEGER DEFAULT 0,
-		auto_reject_threshold REAL DEFAULT 0.85,
-		auto_approve_threshold REAL DEFAULT 0.30,
-		check_spam INTEGER DEFAULT 1,
-		check_offensive INTEGER DEFAULT 1,
-		check_aggressive INTEGER DEFAULT 1,
-		check_off_topic INTEGER DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
-	);
-	`
-
-	if _, err := db.Exec(schema); err != nil {
+	if err := comments.ApplySchema(db); err != nil {
 		t.Fatalf("Failed to create schema: %v", err)
 	}
 
@@ -63,13 +37,14 @@ func TestModerationConfigStore(t *testing.T) {
 
 	t.Run("CreateAndGetConfig", func(t *testing.T) {
 		config := ModerationConfig{
-			Enabled:              true,
-			AutoRejectThreshold:  0.9,
-			AutoApproveThreshold: 0.2,
-			CheckSpam:            true,
-			CheckOffensive:       false,
-			CheckAggressive:      true,
-			CheckOffTopic:        true,
+			Enabled:                    true,
+			AutoRejectThreshold:        0.9,
+			AutoApproveThreshold:       0.2,
+			CheckSpam:                  true,
+			CheckOffensive:             false,
+			CheckAggressive:            true,
+			CheckOffTopic:              true,
+			TrustedReputationThreshold: 25,
 		}
 
 		// Create config
@@ -105,6 +80,9 @@ func TestModerationConfigStore(t *testing.T) {
 		if !retrieved.CheckOffTopic {
 			t.Error("Expected CheckOffTopic to be true")
 		}
+		if retrieved.TrustedReputationThreshold != 25 {
+			t.Errorf("Expected TrustedReputationThreshold 25, got %d", retrieved.TrustedReputationThreshold)
+		}
 	})
 
 	t.Run("UpdateConfig", func(t *testing.T) {
@@ -144,6 +122,78 @@ func TestModerationConfigStore(t *testing.T) {
 		}
 	})
 
+	t.Run("BlockedWordAction", func(t *testing.T) {
+		config := DefaultModerationConfig()
+		config.BlockedWordAction = BlockedWordActionFlag
+		if err := store.Update(context.Background(), "site1", config); err != nil {
+			t.Fatalf("Failed to update config: %v", err)
+		}
+
+		retrieved, err := store.GetBySiteID(context.Background(), "site1")
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		if retrieved.BlockedWordAction != BlockedWordActionFlag {
+			t.Errorf("Expected BlockedWordAction %q, got %q", BlockedWordActionFlag, retrieved.BlockedWordAction)
+		}
+	})
+
+	t.Run("LinkRule", func(t *testing.T) {
+		config := DefaultModerationConfig()
+		config.MaxLinks = 1
+		config.LinkAction = BlockedWordActionReject
+		if err := store.Update(context.Background(), "site1", config); err != nil {
+			t.Fatalf("Failed to update config: %v", err)
+		}
+
+		retrieved, err := store.GetBySiteID(context.Background(), "site1")
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		if retrieved.MaxLinks != 1 || retrieved.LinkAction != BlockedWordActionReject {
+			t.Errorf("Expected max links 1 and action %q, got %d and %q", BlockedWordActionReject, retrieved.MaxLinks, retrieved.LinkAction)
+		}
+	})
+
+	t.Run("BlockedWords", func(t *testing.T) {
+		ctx := context.Background()
+		for _, word := range []string{"Spam", "spam", "straße"} {
+			if err := store.AddBlockedWord(ctx, "site1", word); err != nil {
+				t.Fatalf("Failed to add blocked word: %v", err)
+			}
+		}
+		if err := store.AddBlockedWord(ctx, "site1", "   "); err == nil {
+			t.Error("Expected error for empty blocked word")
+		}
+
+		words, err := store.ListBlockedWords(ctx, "site1")
+		if err != nil {
+			t.Fatalf("Failed to list blocked words: %v", err)
+		}
+		if len(words) != 2 || words[0] != "spam" || words[1] != "straße" {
+			t.Errorf("Expected [spam straße], got %v", words)
+		}
+
+		if word, matched, err := store.MatchBlockedWords(ctx, "site1", "Buy SPAM now"); err != nil || !matched || word != "SPAM" {
+			t.Errorf("Expected SPAM to match, got %q %v %v", word, matched, err)
+		}
+
+		// A word added after the matcher was cached must be picked up
+		if err := store.AddBlockedWord(ctx, "site1", "scam"); err != nil {
+			t.Fatalf("Failed to add blocked word: %v", err)
+		}
+		if _, matched, _ := store.MatchBlockedWords(ctx, "site1", "what a scam"); !matched {
+			t.Error("Expected newly added word to match")
+		}
+
+		if err := store.RemoveBlockedWord(ctx, "site1", "SPAM"); err != nil {
+			t.Fatalf("Failed to remove blocked word: %v", err)
+		}
+		if _, matched, _ := store.MatchBlockedWords(ctx, "site1", "Buy spam now"); matched {
+			t.Error("Expected removed word not to match")
+		}
+	})
+
 	t.Run("GetNonExistentConfig", func(t *testing.T) {
 		_, err := store.GetBySiteID(context.Background(), "nonexistent")
 		if err == nil {
@@ -164,3 +214,68 @@ func TestModerationConfigStore(t *testing.T) {
 		}
 	})
 }
+
+// assertConfigRoundTrip creates, reads, updates and deletes a config for siteID,
+// checking every field survives each step unchanged
+func assertConfigRoundTrip(t *testing.T, store *ConfigStore, siteID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	config := DefaultModerationConfig()
+	if err := store.Create(ctx, siteID, config); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	got, err := store.GetBySiteID(ctx, siteID)
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if *got != config {
+		t.Errorf("Default config did not round-trip: got %+v, want %+v", *got, config)
+	}
+
+	config.Enabled = true
+	config.AutoRejectThreshold = 0.95
+	config.CheckOffTopic = true
+	config.BlockedWordAction = BlockedWordActionFlag
+	config.AkismetAPIKey = "akismet-key"
+	config.TrustedReputationThreshold = 25
+	if err := store.Update(ctx, siteID, config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	got, err = store.GetBySiteID(ctx, siteID)
+	if err != nil {
+		t.Fatalf("Failed to get updated config: %v", err)
+	}
+	if *got != config {
+		t.Errorf("Updated config did not round-trip: got %+v, want %+v", *got, config)
+	}
+
+	if err := store.Delete(ctx, siteID); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	if _, err := store.GetBySiteID(ctx, siteID); err == nil {
+		t.Error("Expected error getting deleted config")
+	}
+}
+
+// TestConfigStore_PostgresPlaceholders runs the store with Postgres-style $n
+// placeholders, which SQLite also accepts, to check every query rebinds cleanly
+func TestConfigStore_PostgresPlaceholders(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+
+	if err := comments.ApplySchema(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES (?, ?, ?)", "site1", "owner1", "Test Site"); err != nil {
+		t.Fatalf("Failed to create test site: %v", err)
+	}
+
+	assertConfigRoundTrip(t, NewConfigStoreWithDialect(db, analytics.PostgresDialect{}), "site1")
+	assertConfigRoundTrip(t, NewConfigStoreWithDialect(db, analytics.SQLiteDialect{}), "site1")
+}
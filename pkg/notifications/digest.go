@@ -0,0 +1,228 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/i18n"
+)
+
+// DigestComment is a single comment listed in a daily digest
+type DigestComment struct {
+	Author    string
+	Text      string
+	PageTitle string
+	PagePath  string
+	CreatedAt time.Time
+}
+
+// DigestData is the template data for a daily digest email
+type DigestData struct {
+	SiteName       string
+	Date           string
+	CommentCount   int
+	Comments       []DigestComment
+	UnsubscribeURL string
+}
+
+// GetSitesDueForDigest returns the IDs of sites in daily digest mode whose last
+// digest was sent before periodEnd
+func (s *Store) GetSitesDueForDigest(periodEnd time.Time) ([]string, error) {
+	query := `
+		SELECT site_id
+		FROM notification_settings
+		WHERE enabled = 1 AND notify_new_comment = 1 AND notify_new_comment_mode = ?
+		  AND (last_digest_sent_at IS NULL OR last_digest_sent_at < ?)
+	`
+
+	rows, err := s.db.Query(query, NewCommentModeDaily, periodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest sites: %w", err)
+	}
+	defer rows.Close()
+
+	var siteIDs []string
+	for rows.Next() {
+		var siteID string
+		if err := rows.Scan(&siteID); err != nil {
+			return nil, fmt.Errorf("failed to scan digest site: %w", err)
+		}
+		siteIDs = append(siteIDs, siteID)
+	}
+
+	return siteIDs, rows.Err()
+}
+
+// GetDigestComments returns the comments posted on a site in [from, to)
+func (s *Store) GetDigestComments(siteID string, from, to time.Time) ([]DigestComment, error) {
+	query := `
+		SELECT c.author, c.text, COALESCE(p.title, ''), COALESCE(p.path, ''), c.created_at
+		FROM comments c
+		LEFT JOIN pages p ON c.page_id = p.id
+		WHERE c.site_id = ? AND c.created_at >= ? AND c.created_at < ?
+		ORDER BY c.created_at ASC
+	`
+
+	rows, err := s.db.Query(query, siteID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []DigestComment
+	for rows.Next() {
+		var c DigestComment
+		if err := rows.Scan(&c.Author, &c.Text, &c.PageTitle, &c.PagePath, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
+
+// SaveDigest records that the digest for the period ending at periodEnd was sent
+// and enqueues n (if not nil) in the same transaction. It returns false without
+// enqueuing when another run already covered the period, so restarts and
+// concurrent processors never double-send.
+func (s *Store) SaveDigest(siteID string, periodEnd time.Time, n *Notification) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE notification_settings
+		SET last_digest_sent_at = ?
+		WHERE site_id = ? AND (last_digest_sent_at IS NULL OR last_digest_sent_at < ?)
+	`, periodEnd, siteID, periodEnd)
+	if err != nil {
+		return false, fmt.Errorf("failed to record digest: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record digest: %w", err)
+	}
+	if claimed == 0 {
+		return false, nil
+	}
+
+	if n != nil {
+		if err := saveNotification(tx, n); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit digest: %w", err)
+	}
+
+	return true, nil
+}
+
+// ProcessDailyDigests enqueues one digest per daily-mode site covering the
+// previous UTC day. Sites already sent a digest for that day are skipped. It
+// returns the number of digests enqueued.
+func (q *Queue) ProcessDailyDigests(ctx context.Context, now time.Time) (int, error) {
+	periodEnd := now.UTC().Truncate(24 * time.Hour)
+	periodStart := periodEnd.Add(-24 * time.Hour)
+
+	siteIDs, err := q.store.GetSitesDueForDigest(periodEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, siteID := range siteIDs {
+		if ctx.Err() != nil {
+			return enqueued, ctx.Err()
+		}
+
+		n, err := q.buildDailyDigest(siteID, periodStart, periodEnd)
+		if err != nil {
+			log.Printf("Error building daily digest for site %s: %v", siteID, err)
+			continue
+		}
+
+		saved, err := q.store.SaveDigest(siteID, periodEnd, n)
+		if err != nil {
+			log.Printf("Error saving daily digest for site %s: %v", siteID, err)
+			continue
+		}
+		if saved && n != nil {
+			enqueued++
+		}
+	}
+
+	return enqueued, nil
+}
+
+// buildDailyDigest renders the digest notification for a site, returning nil
+// when no comments were posted during the period or the owner unsubscribed
+func (q *Queue) buildDailyDigest(siteID string, from, to time.Time) (*Notification, error) {
+	settings, err := q.store.GetSettings(siteID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, fmt.Errorf("notification settings not found")
+	}
+
+	unsubscribed, err := q.unsubscribes.IsUnsubscribed(siteID, settings.OwnerEmail)
+	if err != nil {
+		return nil, err
+	}
+	if unsubscribed {
+		return nil, nil
+	}
+
+	comments, err := q.store.GetDigestComments(siteID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, nil
+	}
+
+	var siteName string
+	err = q.db.QueryRow("SELECT name FROM sites WHERE id = ?", siteID).Scan(&siteName)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get site: %w", err)
+	}
+	if siteName == "" {
+		siteName = siteID
+	}
+
+	date := from.Format("2006-01-02")
+	digest := DigestData{
+		SiteName:       siteName,
+		Date:           date,
+		CommentCount:   len(comments),
+		Comments:       comments,
+		UnsubscribeURL: q.tokens.URL(siteID, settings.OwnerEmail),
+	}
+
+	body, err := q.templates.RenderDailyDigest(digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return &Notification{
+		SiteID:  siteID,
+		Type:    NotificationDailyDigest,
+		To:      settings.OwnerEmail,
+		Subject: i18n.T(q.siteLocale(siteID), i18n.SubjectDailyDigest, len(comments), siteName),
+		Body:    body,
+		Data: map[string]string{
+			"SiteName":     siteName,
+			"Date":         date,
+			"CommentCount": strconv.Itoa(len(comments)),
+		},
+		Status: "pending",
+	}, nil
+}
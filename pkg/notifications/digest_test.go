@@ -0,0 +1,108 @@
+package notifications
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+)
+
+func TestProcessDailyDigests(t *testing.T) {
+	store, err := comments.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer store.Close()
+	db := store.GetDB()
+
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+
+	for i, text := range []string{"First", "Second", "Third"} {
+		comment := comments.Comment{
+			ID:        string(rune('a' + i)),
+			Author:    "Author",
+			AuthorID:  "author-1",
+			Text:      text,
+			Status:    "approved",
+			CreatedAt: yesterday.Add(time.Duration(i) * time.Hour),
+		}
+		if err := store.AddPageComment(context.Background(), "site-1", "page-1", comment); err != nil {
+			t.Fatalf("failed to add comment: %v", err)
+		}
+	}
+
+	notifStore := NewStore(db)
+	err = notifStore.SaveSettings(&NotificationSettings{
+		SiteID:               "site-1",
+		Enabled:              true,
+		Provider:             "smtp",
+		FromEmail:            "noreply@example.com",
+		FromName:             "Kotomi",
+		OwnerEmail:           "owner@example.com",
+		NotifyNewComment:     true,
+		NotifyNewCommentMode: NewCommentModeDaily,
+	})
+	if err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+
+	queue := NewQueue(db, time.Minute, 10)
+	enqueued, err := queue.ProcessDailyDigests(context.Background(), now)
+	if err != nil {
+		t.Fatalf("failed to process digests: %v", err)
+	}
+	if enqueued != 1 {
+		t.Fatalf("expected 1 digest, got %d", enqueued)
+	}
+
+	pending, err := notifStore.GetPendingNotifications(10)
+	if err != nil {
+		t.Fatalf("failed to get pending notifications: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected 1 queued notification, got %d", len(pending))
+	}
+	if pending[0].Type != NotificationDailyDigest || pending[0].Data["CommentCount"] != "3" {
+		t.Errorf("unexpected digest: type=%s count=%s", pending[0].Type, pending[0].Data["CommentCount"])
+	}
+
+	// A second run for the same day (e.g. after a restart) must not send again
+	enqueued, err = queue.ProcessDailyDigests(context.Background(), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to process digests: %v", err)
+	}
+	if enqueued != 0 {
+		t.Errorf("expected no digest on second run, got %d", enqueued)
+	}
+
+	settings, err := notifStore.GetSettings("site-1")
+	if err != nil {
+		t.Fatalf("failed to get settings: %v", err)
+	}
+	if settings.LastDigestSentAt == nil {
+		t.Error("expected last digest timestamp to be recorded")
+	}
+}
+
+func TestNewCommentMode(t *testing.T) {
+	tests := []struct {
+		notify bool
+		mode   string
+		want   string
+	}{
+		{true, "", NewCommentModeImmediate},
+		{true, NewCommentModeDaily, NewCommentModeDaily},
+		{true, "weekly", NewCommentModeImmediate},
+		{false, NewCommentModeDaily, NewCommentModeOff},
+	}
+
+	for _, tt := range tests {
+		settings := &NotificationSettings{NotifyNewComment: tt.notify, NotifyNewCommentMode: tt.mode}
+		if got := settings.NewCommentMode(); got != tt.want {
+			t.Errorf("NewCommentMode(%v, %q) = %s, want %s", tt.notify, tt.mode, got, tt.want)
+		}
+	}
+}
@@ -5,28 +5,38 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/i18n"
 )
 
 // Queue manages the notification processing queue
 type Queue struct {
-	store      *Store
-	templates  *EmailTemplate
-	db         *sql.DB
-	stopChan   chan struct{}
-	interval   time.Duration
-	batchSize  int
+	store         *Store
+	templates     *EmailTemplate
+	siteTemplates *SiteTemplateStore
+	unsubscribes  *UnsubscribeStore
+	tokens        *UnsubscribeTokens
+	db            *sql.DB
+	stopChan      chan struct{}
+	interval      time.Duration
+	batchSize     int
 }
 
 // NewQueue creates a new notification queue processor
 func NewQueue(db *sql.DB, interval time.Duration, batchSize int) *Queue {
 	return &Queue{
-		store:     NewStore(db),
-		templates: NewEmailTemplate(),
-		db:        db,
-		stopChan:  make(chan struct{}),
-		interval:  interval,
-		batchSize: batchSize,
+		store:         NewStore(db),
+		templates:     NewEmailTemplate(),
+		siteTemplates: NewSiteTemplateStore(db),
+		unsubscribes:  NewUnsubscribeStore(db),
+		tokens:        NewUnsubscribeTokens(UnsubscribeSecret()),
+		db:            db,
+		stopChan:      make(chan struct{}),
+		interval:      interval,
+		batchSize:     batchSize,
 	}
 }
 
@@ -46,6 +56,9 @@ func (q *Queue) Start(ctx context.Context) {
 			log.Println("Notification queue processor stopped")
 			return
 		case <-ticker.C:
+			if _, err := q.ProcessDailyDigests(ctx, time.Now()); err != nil {
+				log.Printf("Error processing daily digests: %v", err)
+			}
 			q.processBatch(ctx)
 		}
 	}
@@ -106,18 +119,32 @@ func (q *Queue) processNotification(ctx context.Context, n *Notification) {
 			q.store.UpdateNotificationStatus(n.ID, "failed", "New comment notifications disabled")
 			return
 		}
-	case NotificationCommentReply:
+	case NotificationDailyDigest:
+		if settings.NewCommentMode() != NewCommentModeDaily {
+			q.store.UpdateNotificationStatus(n.ID, "failed", "Daily digest notifications disabled")
+			return
+		}
+	case NotificationCommentReply, NotificationPageComment:
 		if !settings.NotifyReply {
 			q.store.UpdateNotificationStatus(n.ID, "failed", "Reply notifications disabled")
 			return
 		}
-	case NotificationModerationUpdate:
+	case NotificationModerationUpdate, NotificationCommentReported:
 		if !settings.NotifyModeration {
 			q.store.UpdateNotificationStatus(n.ID, "failed", "Moderation notifications disabled")
 			return
 		}
 	}
 
+	// Recipients may have opted out after the notification was queued
+	unsubscribed, err := q.unsubscribes.IsUnsubscribed(n.SiteID, n.To)
+	if err != nil {
+		log.Printf("Error checking unsubscribe status for notification %s: %v", n.ID, err)
+	} else if unsubscribed {
+		q.store.UpdateNotificationStatus(n.ID, "failed", "Recipient unsubscribed")
+		return
+	}
+
 	// Create email provider based on settings
 	var provider EmailProvider
 	switch settings.Provider {
@@ -150,7 +177,7 @@ func (q *Queue) processNotification(ctx context.Context, n *Notification) {
 	err = sender.Send(ctx, n.To, n.Subject, n.Body)
 	if err != nil {
 		log.Printf("Error sending notification %s: %v", n.ID, err)
-		q.store.UpdateNotificationStatus(n.ID, "failed", fmt.Sprintf("Send failed: %v", err))
+		q.store.RecordSendFailure(n.ID, fmt.Sprintf("Send failed: %v", err))
 		return
 	}
 
@@ -171,89 +198,228 @@ func (q *Queue) processNotification(ctx context.Context, n *Notification) {
 	log.Printf("Successfully sent notification %s to %s", n.ID, n.To)
 }
 
+// enqueue saves a notification unless its recipient has unsubscribed from the site
+func (q *Queue) enqueue(n *Notification) error {
+	unsubscribed, err := q.unsubscribes.IsUnsubscribed(n.SiteID, n.To)
+	if err != nil {
+		return err
+	}
+	if unsubscribed {
+		log.Printf("Skipping %s notification for site %s: recipient unsubscribed", n.Type, n.SiteID)
+		return nil
+	}
+
+	return q.store.SaveNotification(n)
+}
+
+// render builds a notification's subject and body from the site's custom
+// template for the type, falling back to the defaults when the site has none
+// or it fails to render
+func (q *Queue) render(siteID string, notificationType NotificationType, data map[string]string, defaultSubject string, renderDefault func(map[string]string) (string, error)) (subject, body string, err error) {
+	custom, err := q.siteTemplates.Get(siteID, notificationType)
+	if err != nil {
+		log.Printf("Error loading %s template for site %s: %v", notificationType, siteID, err)
+	} else if custom != nil {
+		subject, body, err = renderSiteTemplate(custom, data)
+		if err == nil {
+			return subject, body, nil
+		}
+		log.Printf("Error rendering %s template for site %s, using default: %v", notificationType, siteID, err)
+	}
+
+	body, err = renderDefault(data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return defaultSubject, body, nil
+}
+
+// siteLocale returns the site's configured locale, or the default locale if
+// the site has no settings
+func (q *Queue) siteLocale(siteID string) string {
+	var locale string
+	err := q.db.QueryRow("SELECT locale FROM site_settings WHERE site_id = ?", siteID).Scan(&locale)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error loading locale for site %s: %v", siteID, err)
+		}
+		return i18n.DefaultLocale
+	}
+	return locale
+}
+
 // EnqueueNewComment enqueues a new comment notification
-func (q *Queue) EnqueueNewComment(siteID, siteName, pageTitle, commentURL, authorName, commentText, ownerEmail, unsubscribeURL string) error {
+func (q *Queue) EnqueueNewComment(siteID, siteName, pageTitle, commentURL, authorName, commentText, ownerEmail string) error {
 	data := map[string]string{
 		"SiteName":       siteName,
 		"PageTitle":      pageTitle,
 		"CommentURL":     commentURL,
 		"AuthorName":     authorName,
 		"CommentText":    commentText,
-		"UnsubscribeURL": unsubscribeURL,
+		"Excerpt":        comments.Excerpt(commentText, ExcerptLength),
+		"UnsubscribeURL": q.tokens.URL(siteID, ownerEmail),
 	}
 
-	body, err := q.templates.RenderNewComment(data)
+	subject, body, err := q.render(siteID, NotificationNewComment, data, i18n.T(q.siteLocale(siteID), i18n.SubjectNewComment, siteName), q.templates.RenderNewComment)
 	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+		return err
 	}
 
 	notification := &Notification{
 		SiteID:  siteID,
 		Type:    NotificationNewComment,
 		To:      ownerEmail,
-		Subject: fmt.Sprintf("New comment on %s", siteName),
+		Subject: subject,
 		Body:    body,
 		Data:    data,
 		Status:  "pending",
 	}
 
-	return q.store.SaveNotification(notification)
+	return q.enqueue(notification)
 }
 
 // EnqueueCommentReply enqueues a comment reply notification
-func (q *Queue) EnqueueCommentReply(siteID, pageTitle, commentURL, authorName, replyText, originalText, recipientEmail, unsubscribeURL string) error {
+func (q *Queue) EnqueueCommentReply(siteID, pageTitle, commentURL, authorName, replyText, originalText, recipientEmail string) error {
 	data := map[string]string{
 		"PageTitle":      pageTitle,
 		"CommentURL":     commentURL,
 		"AuthorName":     authorName,
 		"ReplyText":      replyText,
 		"OriginalText":   originalText,
-		"UnsubscribeURL": unsubscribeURL,
+		"Excerpt":        comments.Excerpt(replyText, ExcerptLength),
+		"UnsubscribeURL": q.tokens.URL(siteID, recipientEmail),
 	}
 
-	body, err := q.templates.RenderCommentReply(data)
+	subject, body, err := q.render(siteID, NotificationCommentReply, data, i18n.T(q.siteLocale(siteID), i18n.SubjectCommentReply), q.templates.RenderCommentReply)
 	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+		return err
 	}
 
 	notification := &Notification{
 		SiteID:  siteID,
 		Type:    NotificationCommentReply,
 		To:      recipientEmail,
-		Subject: "Someone replied to your comment",
+		Subject: subject,
+		Body:    body,
+		Data:    data,
+		Status:  "pending",
+	}
+
+	return q.enqueue(notification)
+}
+
+// EnqueuePageComment enqueues a notification of a new comment on a page the
+// recipient is subscribed to
+func (q *Queue) EnqueuePageComment(siteID, pageID, pageTitle, commentURL, authorName, commentText, recipientEmail string) error {
+	data := map[string]string{
+		"PageTitle":          pageTitle,
+		"CommentURL":         commentURL,
+		"AuthorName":         authorName,
+		"CommentText":        commentText,
+		"Excerpt":            comments.Excerpt(commentText, ExcerptLength),
+		"UnsubscribeURL":     q.tokens.URL(siteID, recipientEmail),
+		"PageUnsubscribeURL": q.tokens.PageURL(siteID, pageID, recipientEmail),
+	}
+
+	subject, body, err := q.render(siteID, NotificationPageComment, data, i18n.T(q.siteLocale(siteID), i18n.SubjectPageComment, pageTitle), q.templates.RenderPageComment)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		SiteID:  siteID,
+		Type:    NotificationPageComment,
+		To:      recipientEmail,
+		Subject: subject,
 		Body:    body,
 		Data:    data,
 		Status:  "pending",
 	}
 
-	return q.store.SaveNotification(notification)
+	return q.enqueue(notification)
+}
+
+// EnqueuePageSubscribers notifies the subscribers of a page of a new comment,
+// except the comment's author and the addresses in skip (normalized emails,
+// e.g. recipients already notified of the comment as a reply)
+func (q *Queue) EnqueuePageSubscribers(siteID, pageID, pageTitle, commentURL, authorID, authorName, commentText string, skip map[string]bool) error {
+	subscribers, err := NewSubscriptionStore(q.db).GetSubscribers(siteID, pageID)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subscribers {
+		if sub.UserID == authorID || skip[normalizeEmail(sub.Email)] {
+			continue
+		}
+		if err := q.EnqueuePageComment(siteID, pageID, pageTitle, commentURL, authorName, commentText, sub.Email); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // EnqueueModerationUpdate enqueues a moderation update notification
-func (q *Queue) EnqueueModerationUpdate(siteID, pageTitle, commentURL, commentText, status, reason, recipientEmail, unsubscribeURL string) error {
+func (q *Queue) EnqueueModerationUpdate(siteID, pageTitle, commentURL, commentText, status, reason, recipientEmail string) error {
 	data := map[string]string{
 		"PageTitle":      pageTitle,
 		"CommentURL":     commentURL,
 		"CommentText":    commentText,
 		"Status":         status,
 		"Reason":         reason,
-		"UnsubscribeURL": unsubscribeURL,
+		"Excerpt":        comments.Excerpt(commentText, ExcerptLength),
+		"UnsubscribeURL": q.tokens.URL(siteID, recipientEmail),
 	}
 
-	body, err := q.templates.RenderModerationUpdate(data)
+	locale := q.siteLocale(siteID)
+	defaultSubject := i18n.T(locale, i18n.SubjectModerationUpdate, i18n.Status(locale, status))
+	subject, body, err := q.render(siteID, NotificationModerationUpdate, data, defaultSubject, q.templates.RenderModerationUpdate)
 	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+		return err
 	}
 
 	notification := &Notification{
 		SiteID:  siteID,
 		Type:    NotificationModerationUpdate,
 		To:      recipientEmail,
-		Subject: fmt.Sprintf("Your comment was %s", status),
+		Subject: subject,
+		Body:    body,
+		Data:    data,
+		Status:  "pending",
+	}
+
+	return q.enqueue(notification)
+}
+
+// EnqueueCommentReported tells the site owner that a comment reached the report
+// threshold and was sent back to the moderation queue
+func (q *Queue) EnqueueCommentReported(siteID, siteName, pageTitle, commentURL, authorName, commentText string, reportCount int, ownerEmail string) error {
+	data := map[string]string{
+		"SiteName":       siteName,
+		"PageTitle":      pageTitle,
+		"CommentURL":     commentURL,
+		"AuthorName":     authorName,
+		"CommentText":    commentText,
+		"ReportCount":    strconv.Itoa(reportCount),
+		"Excerpt":        comments.Excerpt(commentText, ExcerptLength),
+		"UnsubscribeURL": q.tokens.URL(siteID, ownerEmail),
+	}
+
+	subject, body, err := q.render(siteID, NotificationCommentReported, data, i18n.T(q.siteLocale(siteID), i18n.SubjectCommentReported, siteName), q.templates.RenderCommentReported)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		SiteID:  siteID,
+		Type:    NotificationCommentReported,
+		To:      ownerEmail,
+		Subject: subject,
 		Body:    body,
 		Data:    data,
 		Status:  "pending",
 	}
 
-	return q.store.SaveNotification(notification)
+	return q.enqueue(notification)
 }
@@ -0,0 +1,224 @@
+package notifications
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExcerptLength is how many characters of a comment the Excerpt template field
+// and moderation updates keep
+const ExcerptLength = 200
+
+// ErrUnsupportedTemplateType is returned when saving a template for a
+// notification type that can't be customized
+var ErrUnsupportedTemplateType = errors.New("notification type does not support custom templates")
+
+// SiteTemplate is a site's override of a notification's subject and body.
+// The subject is a text/template and the body an html/template, both executed
+// against the notification's data fields (see TemplateFields).
+type SiteTemplate struct {
+	ID              string           `json:"id"`
+	SiteID          string           `json:"site_id"`
+	Type            NotificationType `json:"type"`
+	SubjectTemplate string           `json:"subject_template"`
+	BodyTemplate    string           `json:"body_template"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// templateSamples holds example data for every customizable notification
+// type. A template is validated by rendering it against its sample, so its
+// keys are exactly the fields available to that type.
+var templateSamples = map[NotificationType]map[string]string{
+	NotificationNewComment: {
+		"SiteName":       "My Blog",
+		"PageTitle":      "Hello World",
+		"CommentURL":     "https://example.com/hello-world#comment-1",
+		"AuthorName":     "Alice",
+		"CommentText":    "Great post!",
+		"Excerpt":        "Great post!",
+		"UnsubscribeURL": "https://example.com/unsubscribe?token=sample",
+	},
+	NotificationCommentReply: {
+		"PageTitle":      "Hello World",
+		"CommentURL":     "https://example.com/hello-world#comment-2",
+		"AuthorName":     "Bob",
+		"ReplyText":      "Thanks, Alice!",
+		"OriginalText":   "Great post!",
+		"Excerpt":        "Thanks, Alice!",
+		"UnsubscribeURL": "https://example.com/unsubscribe?token=sample",
+	},
+	NotificationModerationUpdate: {
+		"PageTitle":      "Hello World",
+		"CommentURL":     "https://example.com/hello-world#comment-1",
+		"CommentText":    "Great post!",
+		"Excerpt":        "Great post!",
+		"Status":         "approved",
+		"Reason":         "",
+		"UnsubscribeURL": "https://example.com/unsubscribe?token=sample",
+	},
+	NotificationCommentReported: {
+		"SiteName":       "My Blog",
+		"PageTitle":      "Hello World",
+		"CommentURL":     "https://example.com/hello-world#comment-1",
+		"AuthorName":     "Alice",
+		"CommentText":    "Great post!",
+		"Excerpt":        "Great post!",
+		"ReportCount":    "3",
+		"UnsubscribeURL": "https://example.com/unsubscribe?token=sample",
+	},
+	NotificationPageComment: {
+		"PageTitle":          "Hello World",
+		"CommentURL":         "https://example.com/hello-world#comment-3",
+		"AuthorName":         "Carol",
+		"CommentText":        "I agree with Bob.",
+		"Excerpt":            "I agree with Bob.",
+		"UnsubscribeURL":     "https://example.com/unsubscribe?token=sample",
+		"PageUnsubscribeURL": "https://example.com/unsubscribe?token=sample&page=hello-world",
+	},
+}
+
+// TemplateFields returns the data fields a custom template for the type may
+// use, or nil if the type can't be customized
+func TemplateFields(notificationType NotificationType) []string {
+	sample, ok := templateSamples[notificationType]
+	if !ok {
+		return nil
+	}
+	fields := make([]string, 0, len(sample))
+	for field := range sample {
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// renderSiteTemplate executes a site template against data. Referencing a
+// field the notification type doesn't provide is an error.
+func renderSiteTemplate(t *SiteTemplate, data map[string]string) (subject, body string, err error) {
+	subjectTmpl, err := texttemplate.New("subject").Option("missingkey=error").Parse(t.SubjectTemplate)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subject template: %w", err)
+	}
+	bodyTmpl, err := htmltemplate.New("body").Option("missingkey=error").Parse(t.BodyTemplate)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid body template: %w", err)
+	}
+
+	var subjectBuf, bodyBuf bytes.Buffer
+	if err := subjectTmpl.Execute(&subjectBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject template: %w", err)
+	}
+	if err := bodyTmpl.Execute(&bodyBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body template: %w", err)
+	}
+
+	// Subjects are a single header line
+	subject = strings.Join(strings.Fields(subjectBuf.String()), " ")
+	return subject, bodyBuf.String(), nil
+}
+
+// SiteTemplateStore handles the notification_templates table
+type SiteTemplateStore struct {
+	db *sql.DB
+}
+
+// NewSiteTemplateStore creates a new site template store
+func NewSiteTemplateStore(db *sql.DB) *SiteTemplateStore {
+	return &SiteTemplateStore{db: db}
+}
+
+// Get returns a site's template for a notification type, or nil if the site
+// uses the default
+func (s *SiteTemplateStore) Get(siteID string, notificationType NotificationType) (*SiteTemplate, error) {
+	t := &SiteTemplate{}
+	err := s.db.QueryRow(`
+		SELECT id, site_id, type, subject_template, body_template, created_at, updated_at
+		FROM notification_templates
+		WHERE site_id = ? AND type = ?
+	`, siteID, notificationType).Scan(&t.ID, &t.SiteID, &t.Type, &t.SubjectTemplate, &t.BodyTemplate, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification template: %w", err)
+	}
+	return t, nil
+}
+
+// List returns every template a site has customized
+func (s *SiteTemplateStore) List(siteID string) ([]SiteTemplate, error) {
+	rows, err := s.db.Query(`
+		SELECT id, site_id, type, subject_template, body_template, created_at, updated_at
+		FROM notification_templates
+		WHERE site_id = ?
+		ORDER BY type
+	`, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []SiteTemplate{}
+	for rows.Next() {
+		var t SiteTemplate
+		if err := rows.Scan(&t.ID, &t.SiteID, &t.Type, &t.SubjectTemplate, &t.BodyTemplate, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// Save validates a template by rendering it against its type's sample data
+// and stores it, replacing the site's previous template for the type
+func (s *SiteTemplateStore) Save(t *SiteTemplate) error {
+	sample, ok := templateSamples[t.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedTemplateType, t.Type)
+	}
+	if strings.TrimSpace(t.SubjectTemplate) == "" || strings.TrimSpace(t.BodyTemplate) == "" {
+		return fmt.Errorf("subject and body templates are required")
+	}
+	if _, _, err := renderSiteTemplate(t, sample); err != nil {
+		return err
+	}
+
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	now := time.Now()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT INTO notification_templates (id, site_id, type, subject_template, body_template, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(site_id, type) DO UPDATE SET
+			subject_template = excluded.subject_template,
+			body_template = excluded.body_template,
+			updated_at = excluded.updated_at
+	`, t.ID, t.SiteID, t.Type, t.SubjectTemplate, t.BodyTemplate, t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification template: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a site's template for a type so the default is used again
+func (s *SiteTemplateStore) Delete(siteID string, notificationType NotificationType) error {
+	_, err := s.db.Exec(`DELETE FROM notification_templates WHERE site_id = ? AND type = ?`, siteID, notificationType)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSiteTemplateOverridesDefault(t *testing.T) {
+	db := createQueueTestDB(t)
+	queue := NewQueue(db, 0, 10)
+
+	err := NewSiteTemplateStore(db).Save(&SiteTemplate{
+		SiteID:          "site-1",
+		Type:            NotificationNewComment,
+		SubjectTemplate: "{{ .AuthorName }} commented on {{ .PageTitle }}",
+		BodyTemplate:    `<p>{{ .Excerpt }}</p><a href="{{ .CommentURL }}">Read</a>`,
+	})
+	if err != nil {
+		t.Fatalf("failed to save template: %v", err)
+	}
+
+	if err := queue.EnqueueNewComment("site-1", "Site", "Hello", "https://example.com/hello", "Alice", "Tom & <b>Jerry</b>", "owner@example.com"); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	// Types without a custom template keep the default
+	if err := queue.EnqueueModerationUpdate("site-1", "Hello", "https://example.com/hello", "Nice", "approved", "", "reader@example.com"); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	pending, err := queue.store.GetPendingNotifications(10)
+	if err != nil {
+		t.Fatalf("failed to get pending notifications: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(pending))
+	}
+	for _, n := range pending {
+		switch n.Type {
+		case NotificationNewComment:
+			if n.Subject != "Alice commented on Hello" {
+				t.Errorf("expected custom subject, got %q", n.Subject)
+			}
+			if !strings.Contains(n.Body, "<p>Tom &amp; Jerry</p>") || !strings.Contains(n.Body, `href="https://example.com/hello"`) {
+				t.Errorf("expected custom body with a plain, escaped excerpt, got %q", n.Body)
+			}
+		case NotificationModerationUpdate:
+			if n.Subject != "Your comment was approved" || !strings.Contains(n.Body, "Comment Moderation Update") {
+				t.Errorf("expected default moderation email, got %q", n.Subject)
+			}
+		}
+	}
+}
+
+func TestSiteTemplateStoreRejectsInvalidTemplates(t *testing.T) {
+	store := NewSiteTemplateStore(createQueueTestDB(t))
+
+	tests := []struct {
+		name     string
+		template SiteTemplate
+	}{
+		{"parse error", SiteTemplate{Type: NotificationNewComment, SubjectTemplate: "{{ .AuthorName", BodyTemplate: "body"}},
+		{"unknown field", SiteTemplate{Type: NotificationNewComment, SubjectTemplate: "New", BodyTemplate: "{{ .ReplyText }}"}},
+		{"execution error", SiteTemplate{Type: NotificationCommentReply, SubjectTemplate: "{{ index .ReplyText 99 }}", BodyTemplate: "body"}},
+		{"empty body", SiteTemplate{Type: NotificationNewComment, SubjectTemplate: "New", BodyTemplate: " "}},
+		{"unsupported type", SiteTemplate{Type: NotificationDailyDigest, SubjectTemplate: "Digest", BodyTemplate: "body"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.template.SiteID = "site-1"
+			if err := store.Save(&tt.template); err == nil {
+				t.Errorf("expected template to be rejected")
+			}
+		})
+	}
+
+	saved, err := store.Get("site-1", NotificationNewComment)
+	if err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+	if saved != nil {
+		t.Errorf("expected no template to be stored, got %+v", saved)
+	}
+}
+
+func TestQueueLocalizesDefaultSubjects(t *testing.T) {
+	db := createQueueTestDB(t)
+	queue := NewQueue(db, 0, 10)
+
+	if _, err := db.Exec("INSERT INTO site_settings (site_id, locale) VALUES ('site-1', 'ja')"); err != nil {
+		t.Fatalf("failed to set locale: %v", err)
+	}
+
+	if err := queue.EnqueueNewComment("site-1", "Site", "Hello", "https://example.com/hello", "Alice", "Nice", "owner@example.com"); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	if err := queue.EnqueueModerationUpdate("site-1", "Hello", "https://example.com/hello", "Nice", "rejected", "", "reader@example.com"); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	pending, err := queue.store.GetPendingNotifications(10)
+	if err != nil {
+		t.Fatalf("failed to get pending notifications: %v", err)
+	}
+	want := map[NotificationType]string{
+		NotificationNewComment:       "Siteに新しいコメントがあります",
+		NotificationModerationUpdate: "あなたのコメントは却下されました",
+	}
+	if len(pending) != len(want) {
+		t.Fatalf("expected %d notifications, got %d", len(want), len(pending))
+	}
+	for _, n := range pending {
+		if n.Subject != want[n.Type] {
+			t.Errorf("expected %s subject %q, got %q", n.Type, want[n.Type], n.Subject)
+		}
+	}
+}
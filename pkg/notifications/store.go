@@ -19,8 +19,18 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // SaveNotification saves a notification to the queue
 func (s *Store) SaveNotification(n *Notification) error {
+	return saveNotification(s.db, n)
+}
+
+// saveNotification inserts a notification using the given connection or transaction
+func saveNotification(db execer, n *Notification) error {
 	if n.ID == "" {
 		n.ID = uuid.New().String()
 	}
@@ -57,7 +67,7 @@ func (s *Store) SaveNotification(n *Notification) error {
 		errorStr.Valid = true
 	}
 
-	_, err = s.db.Exec(query, n.ID, n.SiteID, n.Type, n.To, n.Subject, n.Body, string(dataJSON), n.Status, n.Attempts, errorStr, n.CreatedAt, sentAt, n.UpdatedAt)
+	_, err = db.Exec(query, n.ID, n.SiteID, n.Type, n.To, n.Subject, n.Body, string(dataJSON), n.Status, n.Attempts, errorStr, n.CreatedAt, sentAt, n.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to save notification: %w", err)
 	}
@@ -81,6 +91,12 @@ func (s *Store) GetPendingNotifications(limit int) ([]*Notification, error) {
 	}
 	defer rows.Close()
 
+	return scanNotifications(rows)
+}
+
+// scanNotifications reads notification_queue rows in the column order used by
+// GetPendingNotifications
+func scanNotifications(rows *sql.Rows) ([]*Notification, error) {
 	var notifications []*Notification
 	for rows.Next() {
 		n := &Notification{}
@@ -100,7 +116,6 @@ func (s *Store) GetPendingNotifications(limit int) ([]*Notification, error) {
 			n.Error = errorStr.String
 		}
 
-		// Unmarshal data
 		if err := json.Unmarshal([]byte(dataJSON), &n.Data); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal data: %w", err)
 		}
@@ -108,7 +123,7 @@ func (s *Store) GetPendingNotifications(limit int) ([]*Notification, error) {
 		notifications = append(notifications, n)
 	}
 
-	return notifications, nil
+	return notifications, rows.Err()
 }
 
 // UpdateNotificationStatus updates the status of a notification
@@ -146,14 +161,15 @@ func (s *Store) GetSettings(siteID string) (*NotificationSettings, error) {
 		SELECT id, site_id, enabled, provider, from_email, from_name, reply_to,
 		       smtp_host, smtp_port, smtp_user, smtp_password, smtp_encryption,
 		       sendgrid_api_key, notify_new_comment, notify_reply, notify_moderation,
-		       owner_email, created_at, updated_at
+		       owner_email, notify_new_comment_mode, last_digest_sent_at, created_at, updated_at
 		FROM notification_settings
 		WHERE site_id = ?
 	`
 
 	settings := &NotificationSettings{}
-	var smtpHost, smtpUser, smtpPassword, smtpEncryption, sendGridAPIKey, replyTo sql.NullString
+	var smtpHost, smtpUser, smtpPassword, smtpEncryption, sendGridAPIKey, replyTo, newCommentMode sql.NullString
 	var smtpPort sql.NullInt64
+	var lastDigestSentAt sql.NullTime
 
 	err := s.db.QueryRow(query, siteID).Scan(
 		&settings.ID, &settings.SiteID, &settings.Enabled, &settings.Provider,
@@ -161,6 +177,7 @@ func (s *Store) GetSettings(siteID string) (*NotificationSettings, error) {
 		&smtpHost, &smtpPort, &smtpUser, &smtpPassword, &smtpEncryption,
 		&sendGridAPIKey, &settings.NotifyNewComment, &settings.NotifyReply,
 		&settings.NotifyModeration, &settings.OwnerEmail,
+		&newCommentMode, &lastDigestSentAt,
 		&settings.CreatedAt, &settings.UpdatedAt,
 	)
 
@@ -193,6 +210,13 @@ func (s *Store) GetSettings(siteID string) (*NotificationSettings, error) {
 	if sendGridAPIKey.Valid {
 		settings.SendGridAPIKey = sendGridAPIKey.String
 	}
+	settings.NotifyNewCommentMode = NewCommentModeImmediate
+	if newCommentMode.Valid && newCommentMode.String != "" {
+		settings.NotifyNewCommentMode = newCommentMode.String
+	}
+	if lastDigestSentAt.Valid {
+		settings.LastDigestSentAt = &lastDigestSentAt.Time
+	}
 
 	return settings, nil
 }
@@ -206,6 +230,9 @@ func (s *Store) SaveSettings(settings *NotificationSettings) error {
 		settings.CreatedAt = time.Now()
 	}
 	settings.UpdatedAt = time.Now()
+	if settings.NotifyNewCommentMode == "" {
+		settings.NotifyNewCommentMode = NewCommentModeImmediate
+	}
 
 	// Check if settings exist
 	existing, err := s.GetSettings(settings.SiteID)
@@ -252,7 +279,7 @@ func (s *Store) SaveSettings(settings *NotificationSettings) error {
 			SET enabled = ?, provider = ?, from_email = ?, from_name = ?, reply_to = ?,
 			    smtp_host = ?, smtp_port = ?, smtp_user = ?, smtp_password = ?, smtp_encryption = ?,
 			    sendgrid_api_key = ?, notify_new_comment = ?, notify_reply = ?, notify_moderation = ?,
-			    owner_email = ?, updated_at = ?
+			    owner_email = ?, notify_new_comment_mode = ?, updated_at = ?
 			WHERE site_id = ?
 		`
 
@@ -260,7 +287,7 @@ func (s *Store) SaveSettings(settings *NotificationSettings) error {
 			settings.Enabled, settings.Provider, settings.FromEmail, settings.FromName, replyTo,
 			smtpHost, smtpPort, smtpUser, smtpPassword, smtpEncryption,
 			sendGridAPIKey, settings.NotifyNewComment, settings.NotifyReply, settings.NotifyModeration,
-			settings.OwnerEmail, settings.UpdatedAt, settings.SiteID,
+			settings.OwnerEmail, settings.NotifyNewCommentMode, settings.UpdatedAt, settings.SiteID,
 		)
 	} else {
 		// Insert
@@ -269,8 +296,8 @@ func (s *Store) SaveSettings(settings *NotificationSettings) error {
 				id, site_id, enabled, provider, from_email, from_name, reply_to,
 				smtp_host, smtp_port, smtp_user, smtp_password, smtp_encryption,
 				sendgrid_api_key, notify_new_comment, notify_reply, notify_moderation,
-				owner_email, created_at, updated_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				owner_email, notify_new_comment_mode, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 
 		_, err = s.db.Exec(query,
@@ -278,7 +305,7 @@ func (s *Store) SaveSettings(settings *NotificationSettings) error {
 			settings.FromEmail, settings.FromName, replyTo,
 			smtpHost, smtpPort, smtpUser, smtpPassword, smtpEncryption,
 			sendGridAPIKey, settings.NotifyNewComment, settings.NotifyReply, settings.NotifyModeration,
-			settings.OwnerEmail, settings.CreatedAt, settings.UpdatedAt,
+			settings.OwnerEmail, settings.NotifyNewCommentMode, settings.CreatedAt, settings.UpdatedAt,
 		)
 	}
 
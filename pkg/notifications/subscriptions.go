@@ -0,0 +1,141 @@
+package notifications
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Subscriber is a user following new comments on a page
+type Subscriber struct {
+	UserID string
+	Name   string
+	Email  string
+}
+
+// SubscriptionStore records which users follow new comments on a page
+type SubscriptionStore struct {
+	db *sql.DB
+}
+
+// NewSubscriptionStore creates a new page subscription store
+func NewSubscriptionStore(db *sql.DB) *SubscriptionStore {
+	return &SubscriptionStore{db: db}
+}
+
+// Subscribe subscribes userID to new comments on a page, undoing an earlier
+// unsubscribe. Repeated calls are no-ops.
+func (s *SubscriptionStore) Subscribe(siteID, pageID, userID string) error {
+	query := `
+		INSERT INTO page_subscriptions (site_id, page_id, user_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(site_id, page_id, user_id) DO UPDATE SET unsubscribed_at = NULL
+	`
+
+	_, err := s.db.Exec(query, siteID, pageID, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	return nil
+}
+
+// SubscribeImplicitly subscribes userID to a page they commented on, unless
+// they subscribed or unsubscribed from it before
+func (s *SubscriptionStore) SubscribeImplicitly(siteID, pageID, userID string) error {
+	query := `
+		INSERT INTO page_subscriptions (site_id, page_id, user_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(site_id, page_id, user_id) DO NOTHING
+	`
+
+	_, err := s.db.Exec(query, siteID, pageID, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	return nil
+}
+
+// Unsubscribe stops new comment notifications for userID on a page. The
+// unsubscribe is remembered even if userID was never subscribed, so a later
+// comment doesn't subscribe them implicitly.
+func (s *SubscriptionStore) Unsubscribe(siteID, pageID, userID string) error {
+	now := time.Now()
+	query := `
+		INSERT INTO page_subscriptions (site_id, page_id, user_id, created_at, unsubscribed_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(site_id, page_id, user_id) DO UPDATE SET unsubscribed_at = excluded.unsubscribed_at
+	`
+
+	_, err := s.db.Exec(query, siteID, pageID, userID, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	return nil
+}
+
+// UnsubscribeEmail unsubscribes every user of the site with email from a
+// page, for unsubscribe links in notification emails
+func (s *SubscriptionStore) UnsubscribeEmail(siteID, pageID, email string) error {
+	query := `
+		UPDATE page_subscriptions SET unsubscribed_at = ?
+		WHERE site_id = ? AND page_id = ? AND unsubscribed_at IS NULL AND user_id IN (
+			SELECT id FROM users WHERE site_id = ? AND LOWER(TRIM(email)) = ?
+		)
+	`
+
+	_, err := s.db.Exec(query, time.Now(), siteID, pageID, siteID, normalizeEmail(email))
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	return nil
+}
+
+// IsSubscribed reports whether userID follows new comments on a page
+func (s *SubscriptionStore) IsSubscribed(siteID, pageID, userID string) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(
+		"SELECT 1 FROM page_subscriptions WHERE site_id = ? AND page_id = ? AND user_id = ? AND unsubscribed_at IS NULL",
+		siteID, pageID, userID,
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check subscription: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetSubscribers returns the subscribers of a page that have an email address
+func (s *SubscriptionStore) GetSubscribers(siteID, pageID string) ([]Subscriber, error) {
+	query := `
+		SELECT u.id, u.name, u.email
+		FROM page_subscriptions ps
+		JOIN users u ON u.site_id = ps.site_id AND u.id = ps.user_id
+		WHERE ps.site_id = ? AND ps.page_id = ? AND ps.unsubscribed_at IS NULL
+			AND u.email IS NOT NULL AND u.email != ''
+		ORDER BY ps.created_at
+	`
+
+	rows, err := s.db.Query(query, siteID, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	var subscribers []Subscriber
+	for rows.Next() {
+		var sub Subscriber
+		if err := rows.Scan(&sub.UserID, &sub.Name, &sub.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		subscribers = append(subscribers, sub)
+	}
+
+	return subscribers, rows.Err()
+}
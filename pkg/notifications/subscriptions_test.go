@@ -0,0 +1,110 @@
+package notifications
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func createSubscriptionTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := createQueueTestDB(t)
+	if _, err := db.Exec("INSERT INTO pages (id, site_id, path, title) VALUES ('page-1', 'site-1', '/post', 'Post')"); err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+	for _, u := range []struct{ id, email string }{
+		{"alice", "alice@example.com"},
+		{"bob", "Bob@Example.com"},
+		{"carol", "carol@example.com"},
+		{"anon", ""},
+	} {
+		if _, err := db.Exec("INSERT INTO users (id, site_id, name, email, first_seen, last_seen) VALUES (?, 'site-1', ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)", u.id, u.id, u.email); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	return db
+}
+
+func subscriberIDs(t *testing.T, store *SubscriptionStore) []string {
+	t.Helper()
+	subscribers, err := store.GetSubscribers("site-1", "page-1")
+	if err != nil {
+		t.Fatalf("failed to get subscribers: %v", err)
+	}
+	var ids []string
+	for _, sub := range subscribers {
+		ids = append(ids, sub.UserID)
+	}
+	return ids
+}
+
+func TestSubscriptionStore(t *testing.T) {
+	db := createSubscriptionTestDB(t)
+	store := NewSubscriptionStore(db)
+
+	for _, userID := range []string{"alice", "bob", "anon"} {
+		if err := store.SubscribeImplicitly("site-1", "page-1", userID); err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+	if err := store.Unsubscribe("site-1", "page-1", "carol"); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	if err := store.SubscribeImplicitly("site-1", "page-1", "carol"); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	// Users without an email can't be notified, and commenting doesn't undo an unsubscribe
+	if got := subscriberIDs(t, store); !reflect.DeepEqual(got, []string{"alice", "bob"}) {
+		t.Errorf("expected alice and bob, got %v", got)
+	}
+
+	// Unsubscribe links match the email case-insensitively
+	if err := store.UnsubscribeEmail("site-1", "page-1", "bob@example.com"); err != nil {
+		t.Fatalf("failed to unsubscribe email: %v", err)
+	}
+	if subscribed, err := store.IsSubscribed("site-1", "page-1", "bob"); err != nil || subscribed {
+		t.Errorf("expected bob to be unsubscribed, got %v (err %v)", subscribed, err)
+	}
+
+	// Subscribing explicitly undoes an unsubscribe
+	if err := store.Subscribe("site-1", "page-1", "carol"); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if got := subscriberIDs(t, store); !reflect.DeepEqual(got, []string{"alice", "carol"}) {
+		t.Errorf("expected alice and carol, got %v", got)
+	}
+}
+
+func TestEnqueuePageSubscribers(t *testing.T) {
+	db := createSubscriptionTestDB(t)
+	queue := NewQueue(db, 0, 10)
+	store := NewSubscriptionStore(db)
+	for _, userID := range []string{"alice", "bob", "carol"} {
+		if err := store.Subscribe("site-1", "page-1", userID); err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+
+	// Alice wrote the comment and Bob was already notified of it as a reply
+	err := queue.EnqueuePageSubscribers("site-1", "page-1", "Post", "/post?comment=c1", "alice", "Alice", "Hello", map[string]bool{"bob@example.com": true})
+	if err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	var recipient, body string
+	if err := db.QueryRow("SELECT recipient, body FROM notification_queue WHERE type = ?", NotificationPageComment).Scan(&recipient, &body); err != nil {
+		t.Fatalf("expected exactly one notification: %v", err)
+	}
+	if recipient != "carol@example.com" {
+		t.Errorf("expected carol to be notified, got %s", recipient)
+	}
+	if !strings.Contains(body, "page=page-1") {
+		t.Error("expected the email to link to unsubscribing from the page")
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notification_queue").Scan(&count); err != nil || count != 1 {
+		t.Errorf("expected 1 notification, got %d (err %v)", count, err)
+	}
+}
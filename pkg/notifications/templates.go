@@ -81,10 +81,10 @@ func NewEmailTemplate() *EmailTemplate {
         <div class="comment">
             <p>{{ .ReplyText }}</p>
         </div>
-        <div class="original">
+        {{ if .OriginalText }}<div class="original">
             <p><em>Your original comment:</em></p>
             <p>{{ .OriginalText }}</p>
-        </div>
+        </div>{{ end }}
         <a href="{{ .CommentURL }}" class="button">View Reply</a>
     </div>
     <div class="footer">
@@ -136,6 +136,122 @@ func NewEmailTemplate() *EmailTemplate {
     </div>
 </body>
 </html>
+`))
+
+	// Comment reported template
+	template.Must(tmpl.New("comment_reported").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Comment Reported</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f44336; color: white; padding: 20px; text-align: center; }
+        .content { background-color: #f9f9f9; padding: 20px; margin: 20px 0; border-left: 4px solid #f44336; }
+        .comment { background-color: white; padding: 15px; margin: 10px 0; border-radius: 5px; }
+        .author { font-weight: bold; color: #f44336; }
+        .footer { text-align: center; color: #777; font-size: 12px; padding: 20px; }
+        .button { display: inline-block; padding: 10px 20px; background-color: #f44336; color: white; text-decoration: none; border-radius: 5px; margin: 10px 0; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>Comment Reported on {{ .SiteName }}</h1>
+    </div>
+    <div class="content">
+        <p>A comment on <strong>{{ .PageTitle }}</strong> has been reported by {{ .ReportCount }} users and is hidden until you review it:</p>
+        <div class="comment">
+            <p class="author">{{ .AuthorName }}</p>
+            <p>{{ .CommentText }}</p>
+        </div>
+        <a href="{{ .CommentURL }}" class="button">View Comment</a>
+    </div>
+    <div class="footer">
+        <p>You're receiving this because you're the owner of {{ .SiteName }}.</p>
+        <p><a href="{{ .UnsubscribeURL }}">Unsubscribe</a> from these notifications</p>
+    </div>
+</body>
+</html>
+`))
+
+	// Page comment template, sent to subscribers of the page
+	template.Must(tmpl.New("page_comment").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>New Comment</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #2196F3; color: white; padding: 20px; text-align: center; }
+        .content { background-color: #f9f9f9; padding: 20px; margin: 20px 0; border-left: 4px solid #2196F3; }
+        .comment { background-color: white; padding: 15px; margin: 10px 0; border-radius: 5px; }
+        .author { font-weight: bold; color: #2196F3; }
+        .footer { text-align: center; color: #777; font-size: 12px; padding: 20px; }
+        .button { display: inline-block; padding: 10px 20px; background-color: #2196F3; color: white; text-decoration: none; border-radius: 5px; margin: 10px 0; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>New Comment on {{ .PageTitle }}</h1>
+    </div>
+    <div class="content">
+        <p>A new comment was posted on <strong>{{ .PageTitle }}</strong>, which you follow:</p>
+        <div class="comment">
+            <p class="author">{{ .AuthorName }}</p>
+            <p>{{ .CommentText }}</p>
+        </div>
+        <a href="{{ .CommentURL }}" class="button">View Comment</a>
+    </div>
+    <div class="footer">
+        <p>You're receiving this because you're subscribed to comments on this page.</p>
+        <p><a href="{{ .PageUnsubscribeURL }}">Stop following this page</a> or <a href="{{ .UnsubscribeURL }}">unsubscribe</a> from all notifications</p>
+    </div>
+</body>
+</html>
+`))
+
+	// Daily digest template
+	template.Must(tmpl.New("daily_digest").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Daily Comment Digest</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #4CAF50; color: white; padding: 20px; text-align: center; }
+        .content { background-color: #f9f9f9; padding: 20px; margin: 20px 0; border-left: 4px solid #4CAF50; }
+        .comment { background-color: white; padding: 15px; margin: 10px 0; border-radius: 5px; }
+        .author { font-weight: bold; color: #4CAF50; }
+        .page { color: #777; font-size: 12px; }
+        .footer { text-align: center; color: #777; font-size: 12px; padding: 20px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>Daily Digest for {{ .SiteName }}</h1>
+    </div>
+    <div class="content">
+        <p><strong>{{ .CommentCount }}</strong> new comment(s) were posted on {{ .Date }}:</p>
+        {{ range .Comments }}
+        <div class="comment">
+            <p class="author">{{ .Author }}</p>
+            <p class="page">on {{ if .PageTitle }}{{ .PageTitle }}{{ else }}{{ .PagePath }}{{ end }}</p>
+            <p>{{ .Text }}</p>
+        </div>
+        {{ end }}
+    </div>
+    <div class="footer">
+        <p>You're receiving this because you're the owner of {{ .SiteName }}.</p>
+        <p><a href="{{ .UnsubscribeURL }}">Unsubscribe</a> from these notifications</p>
+    </div>
+</body>
+</html>
 `))
 
 	return &EmailTemplate{templates: tmpl}
@@ -167,3 +283,30 @@ func (e *EmailTemplate) RenderModerationUpdate(data map[string]string) (string,
 	}
 	return buf.String(), nil
 }
+
+// RenderCommentReported renders the comment reported email template
+func (e *EmailTemplate) RenderCommentReported(data map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := e.templates.ExecuteTemplate(&buf, "comment_reported", data); err != nil {
+		return "", fmt.Errorf("failed to render comment_reported template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderPageComment renders the page comment email template
+func (e *EmailTemplate) RenderPageComment(data map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := e.templates.ExecuteTemplate(&buf, "page_comment", data); err != nil {
+		return "", fmt.Errorf("failed to render page_comment template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderDailyDigest renders the daily digest email template
+func (e *EmailTemplate) RenderDailyDigest(data DigestData) (string, error) {
+	var buf bytes.Buffer
+	if err := e.templates.ExecuteTemplate(&buf, "daily_digest", data); err != nil {
+		return "", fmt.Errorf("failed to render daily_digest template: %w", err)
+	}
+	return buf.String(), nil
+}
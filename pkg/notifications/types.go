@@ -9,6 +9,16 @@ const (
 	NotificationNewComment       NotificationType = "new_comment"
 	NotificationCommentReply     NotificationType = "comment_reply"
 	NotificationModerationUpdate NotificationType = "moderation_update"
+	NotificationCommentReported  NotificationType = "comment_reported"
+	NotificationDailyDigest      NotificationType = "daily_digest"
+	NotificationPageComment      NotificationType = "page_comment" // to subscribers of the page
+)
+
+// Delivery modes for new comment notifications
+const (
+	NewCommentModeImmediate = "immediate" // one email per comment
+	NewCommentModeDaily     = "daily"     // one digest email per day
+	NewCommentModeOff       = "off"
 )
 
 // Notification represents a notification to be sent
@@ -44,9 +54,24 @@ type NotificationSettings struct {
 	SMTPEncryption       string    `json:"smtp_encryption,omitempty"` // tls, starttls, none
 	SendGridAPIKey       string    `json:"sendgrid_api_key,omitempty"`
 	NotifyNewComment     bool      `json:"notify_new_comment"`
+	NotifyNewCommentMode string    `json:"notify_new_comment_mode"` // immediate, daily, off
+	LastDigestSentAt     *time.Time `json:"last_digest_sent_at,omitempty"`
 	NotifyReply          bool      `json:"notify_reply"`
 	NotifyModeration     bool      `json:"notify_moderation"`
 	OwnerEmail           string    `json:"owner_email"` // Site owner email for notifications
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
+
+// NewCommentMode returns the effective delivery mode for new comment notifications
+func (s *NotificationSettings) NewCommentMode() string {
+	if !s.NotifyNewComment {
+		return NewCommentModeOff
+	}
+	switch s.NotifyNewCommentMode {
+	case NewCommentModeDaily, NewCommentModeOff:
+		return s.NotifyNewCommentMode
+	default:
+		return NewCommentModeImmediate
+	}
+}
@@ -0,0 +1,145 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ErrInvalidUnsubscribeToken is returned when an unsubscribe token is malformed or
+// its signature doesn't match
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// defaultUnsubscribeSecret is used when neither UNSUBSCRIBE_SECRET nor
+// SESSION_SECRET is set, which is only acceptable in development
+const defaultUnsubscribeSecret = "kotomi-unsubscribe-secret-change-in-production"
+
+// UnsubscribeSecret returns the key used to sign unsubscribe tokens, read from
+// UNSUBSCRIBE_SECRET and falling back to SESSION_SECRET
+func UnsubscribeSecret() []byte {
+	if secret := os.Getenv("UNSUBSCRIBE_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte(defaultUnsubscribeSecret)
+}
+
+// UnsubscribeTokens signs and verifies per-recipient unsubscribe tokens
+type UnsubscribeTokens struct {
+	secret []byte
+}
+
+// NewUnsubscribeTokens creates a token signer using secret
+func NewUnsubscribeTokens(secret []byte) *UnsubscribeTokens {
+	return &UnsubscribeTokens{secret: secret}
+}
+
+// Generate returns a token binding email to siteID. The token is the encoded
+// site and email followed by an HMAC-SHA256 signature over both.
+func (t *UnsubscribeTokens) Generate(siteID, email string) string {
+	payload := siteID + "\n" + normalizeEmail(email)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(payload))
+}
+
+// Parse verifies a token and returns the site ID and email it was issued for
+func (t *UnsubscribeTokens) Parse(token string) (siteID, email string, err error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	if !hmac.Equal(mac, t.sign(string(payload))) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	siteID, email, ok = strings.Cut(string(payload), "\n")
+	if !ok || siteID == "" || email == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	return siteID, email, nil
+}
+
+// URL returns the unsubscribe link for a recipient
+func (t *UnsubscribeTokens) URL(siteID, email string) string {
+	return "/unsubscribe?token=" + url.QueryEscape(t.Generate(siteID, email))
+}
+
+// PageURL returns the link unsubscribing a recipient from new comments on one
+// page. The page isn't signed: the token already allows unsubscribing from the
+// whole site, so a changed page can only narrow what it does.
+func (t *UnsubscribeTokens) PageURL(siteID, pageID, email string) string {
+	return t.URL(siteID, email) + "&page=" + url.QueryEscape(pageID)
+}
+
+// sign computes the HMAC of payload
+func (t *UnsubscribeTokens) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// UnsubscribeStore records which email addresses opted out of notifications per site
+type UnsubscribeStore struct {
+	db *sql.DB
+}
+
+// NewUnsubscribeStore creates a new unsubscribe store
+func NewUnsubscribeStore(db *sql.DB) *UnsubscribeStore {
+	return &UnsubscribeStore{db: db}
+}
+
+// Unsubscribe opts email out of all notifications for a site. Repeated calls are no-ops.
+func (s *UnsubscribeStore) Unsubscribe(siteID, email string) error {
+	query := `
+		INSERT INTO notification_unsubscribes (site_id, email)
+		VALUES (?, ?)
+		ON CONFLICT(site_id, email) DO NOTHING
+	`
+
+	_, err := s.db.Exec(query, siteID, normalizeEmail(email))
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	return nil
+}
+
+// IsUnsubscribed reports whether email opted out of notifications for a site
+func (s *UnsubscribeStore) IsUnsubscribed(siteID, email string) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(
+		"SELECT 1 FROM notification_unsubscribes WHERE site_id = ? AND email = ?",
+		siteID, normalizeEmail(email),
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check unsubscribe status: %w", err)
+	}
+
+	return true, nil
+}
+
+// normalizeEmail makes opt-out matching case-insensitive
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeTokens(t *testing.T) {
+	tokens := NewUnsubscribeTokens([]byte("test-secret"))
+	token := tokens.Generate("site-1", "Reader@Example.com")
+
+	siteID, email, err := tokens.Parse(token)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	if siteID != "site-1" || email != "reader@example.com" {
+		t.Errorf("unexpected token contents: site=%s email=%s", siteID, email)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged := NewUnsubscribeTokens([]byte("test-secret")).Generate("site-1", "victim@example.com")
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tampered := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"missing signature", payload},
+		{"swapped payload", forgedPayload + "." + signature},
+		{"truncated signature", payload + "." + signature[:len(signature)-2]},
+		{"not base64", "!!!." + signature},
+		{"other secret", NewUnsubscribeTokens([]byte("other-secret")).Generate("site-1", "reader@example.com")},
+	}
+
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tokens.Parse(tt.token); err != ErrInvalidUnsubscribeToken {
+				t.Errorf("expected ErrInvalidUnsubscribeToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEnqueueSkipsUnsubscribedRecipients(t *testing.T) {
+	db := createQueueTestDB(t)
+	queue := NewQueue(db, 0, 10)
+
+	if err := NewUnsubscribeStore(db).Unsubscribe("site-1", "Reader@example.com"); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+
+	err := queue.EnqueueModerationUpdate("site-1", "Page", "/page", "text", "approved", "", "reader@example.com")
+	if err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	err = queue.EnqueueModerationUpdate("site-1", "Page", "/page", "text", "approved", "", "other@example.com")
+	if err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	pending, err := queue.store.GetPendingNotifications(10)
+	if err != nil {
+		t.Fatalf("failed to get pending notifications: %v", err)
+	}
+	if len(pending) != 1 || pending[0].To != "other@example.com" {
+		t.Fatalf("expected only the subscribed recipient to be queued, got %d notifications", len(pending))
+	}
+	if !strings.Contains(pending[0].Data["UnsubscribeURL"], "/unsubscribe?token=") {
+		t.Errorf("expected a tokenized unsubscribe URL, got %s", pending[0].Data["UnsubscribeURL"])
+	}
+}
@@ -0,0 +1,212 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/db"
+)
+
+// maxAttempts is the number of sends attempted before a notification is marked failed
+const maxAttempts = 3
+
+// claimTimeout is how long a notification may stay in "sending" before it is
+// considered abandoned (e.g. the process crashed mid-send) and returned to the queue
+const claimTimeout = 5 * time.Minute
+
+// ClaimPendingNotifications atomically moves up to limit pending notifications to
+// "sending" and returns them, so concurrent workers never process the same row
+func (s *Store) ClaimPendingNotifications(limit int) ([]*Notification, error) {
+	if db.IsPostgres(s.db) {
+		return s.claimPendingPostgres(limit)
+	}
+	return s.claimPendingSQLite(limit)
+}
+
+// claimPendingPostgres claims rows in a single statement, skipping rows locked by
+// other workers
+func (s *Store) claimPendingPostgres(limit int) ([]*Notification, error) {
+	query := `
+		UPDATE notification_queue
+		SET status = 'sending', updated_at = $1
+		WHERE id IN (
+			SELECT id FROM notification_queue
+			WHERE status = 'pending' AND attempts < $2
+			ORDER BY created_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, site_id, type, recipient, subject, body, data, status, attempts, error, created_at, sent_at, updated_at
+	`
+
+	rows, err := s.db.Query(query, time.Now(), maxAttempts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// claimPendingSQLite selects candidate rows and claims each with an UPDATE guarded
+// on status = 'pending'; rows another worker claimed first are skipped
+func (s *Store) claimPendingSQLite(limit int) ([]*Notification, error) {
+	candidates, err := s.GetPendingNotifications(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed []*Notification
+	for _, n := range candidates {
+		now := time.Now()
+		result, err := s.db.Exec(`
+			UPDATE notification_queue
+			SET status = 'sending', updated_at = ?
+			WHERE id = ? AND status = 'pending'
+		`, now, n.ID)
+		if err != nil {
+			return claimed, fmt.Errorf("failed to claim notification: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		n.Status = "sending"
+		n.UpdatedAt = now
+		claimed = append(claimed, n)
+	}
+
+	return claimed, nil
+}
+
+// RecordSendFailure stores a send error and returns the notification to the queue,
+// or marks it failed once it has used up its attempts
+func (s *Store) RecordSendFailure(id, errorMsg string) error {
+	query := `
+		UPDATE notification_queue
+		SET status = CASE WHEN attempts + 1 >= ? THEN 'failed' ELSE 'pending' END,
+		    error = ?, updated_at = ?, attempts = attempts + 1
+		WHERE id = ?
+	`
+
+	_, err := s.db.Exec(query, maxAttempts, errorMsg, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record send failure: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseStaleClaims returns notifications stuck in "sending" since before
+// olderThan to the queue
+func (s *Store) ReleaseStaleClaims(olderThan time.Time) (int, error) {
+	result, err := s.db.Exec(`
+		UPDATE notification_queue
+		SET status = 'pending', updated_at = ?
+		WHERE status = 'sending' AND updated_at < ?
+	`, time.Now(), olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale claims: %w", err)
+	}
+
+	released, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale claims: %w", err)
+	}
+
+	return int(released), nil
+}
+
+// StartWorkers processes the queue with n concurrent workers and blocks until ctx
+// is cancelled. Sends already in flight when ctx is cancelled are allowed to
+// finish before StartWorkers returns.
+func (q *Queue) StartWorkers(ctx context.Context, n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	if released, err := q.store.ReleaseStaleClaims(time.Now().Add(-claimTimeout)); err != nil {
+		log.Printf("Error releasing stale notification claims: %v", err)
+	} else if released > 0 {
+		log.Printf("Returned %d abandoned notifications to the queue", released)
+	}
+
+	log.Printf("Notification queue started with %d workers", n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.runWorker(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.runMaintenance(ctx)
+	}()
+
+	wg.Wait()
+	log.Println("Notification queue workers stopped")
+}
+
+// runWorker claims and sends notifications one at a time until ctx is cancelled,
+// sleeping for the queue interval whenever the queue is empty
+func (q *Queue) runWorker(ctx context.Context) {
+	// Sends run on a context that isn't cancelled with ctx so shutdown drains
+	// them instead of aborting them halfway
+	sendCtx := context.WithoutCancel(ctx)
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		claimed, err := q.store.ClaimPendingNotifications(1)
+		if err != nil {
+			log.Printf("Error claiming notifications: %v", err)
+		}
+
+		if len(claimed) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.stopChan:
+				return
+			case <-time.After(q.interval):
+			}
+			continue
+		}
+
+		for _, notification := range claimed {
+			q.processNotification(sendCtx, notification)
+		}
+	}
+}
+
+// runMaintenance enqueues daily digests and cleans up processed notifications
+// once per queue interval
+func (q *Queue) runMaintenance(ctx context.Context) {
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.stopChan:
+			return
+		case <-ticker.C:
+			if _, err := q.ProcessDailyDigests(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Printf("Error processing daily digests: %v", err)
+			}
+			if err := q.store.DeleteProcessedNotifications(time.Now().AddDate(0, 0, -7)); err != nil {
+				log.Printf("Error cleaning up old notifications: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+)
+
+func createQueueTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := comments.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	db := store.GetDB()
+	if _, err := db.Exec("INSERT INTO admin_users (id, email, name, auth0_sub) VALUES ('owner-1', 'owner@example.com', 'Owner', 'auth0|owner-1')"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES ('site-1', 'owner-1', 'Site')"); err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	return db
+}
+
+func TestClaimPendingNotifications(t *testing.T) {
+	db := createQueueTestDB(t)
+	store := NewStore(db)
+
+	for i := 0; i < 3; i++ {
+		if err := store.SaveNotification(&Notification{SiteID: "site-1", Type: NotificationNewComment, To: "owner@example.com", Subject: "s", Body: "b"}); err != nil {
+			t.Fatalf("failed to save notification: %v", err)
+		}
+	}
+
+	first, err := store.ClaimPendingNotifications(2)
+	if err != nil {
+		t.Fatalf("failed to claim notifications: %v", err)
+	}
+	second, err := store.ClaimPendingNotifications(2)
+	if err != nil {
+		t.Fatalf("failed to claim notifications: %v", err)
+	}
+	if len(first) != 2 || len(second) != 1 {
+		t.Fatalf("expected claims of 2 and 1, got %d and %d", len(first), len(second))
+	}
+	if second[0].ID == first[0].ID || second[0].ID == first[1].ID {
+		t.Error("expected a notification to be claimed only once")
+	}
+
+	// Failed sends go back to the queue until the attempts run out
+	id := second[0].ID
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := store.RecordSendFailure(id, "boom"); err != nil {
+			t.Fatalf("failed to record send failure: %v", err)
+		}
+		var status string
+		if err := db.QueryRow("SELECT status FROM notification_queue WHERE id = ?", id).Scan(&status); err != nil {
+			t.Fatalf("failed to read status: %v", err)
+		}
+		want := "pending"
+		if attempt == maxAttempts {
+			want = "failed"
+		}
+		if status != want {
+			t.Errorf("after attempt %d expected status %s, got %s", attempt, want, status)
+		}
+	}
+}
+
+func TestStartWorkers(t *testing.T) {
+	db := createQueueTestDB(t)
+	store := NewStore(db)
+
+	// Without notification settings each send fails fast, which is enough to
+	// check every row is processed exactly once
+	for i := 0; i < 10; i++ {
+		if err := store.SaveNotification(&Notification{SiteID: "site-1", Type: NotificationNewComment, To: "owner@example.com", Subject: "s", Body: "b"}); err != nil {
+			t.Fatalf("failed to save notification: %v", err)
+		}
+	}
+
+	queue := NewQueue(db, 10*time.Millisecond, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.StartWorkers(ctx, 3)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var remaining int
+		if err := db.QueryRow("SELECT COUNT(*) FROM notification_queue WHERE status IN ('pending', 'sending')").Scan(&remaining); err != nil {
+			t.Fatalf("failed to count notifications: %v", err)
+		}
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out with %d notifications unprocessed", remaining)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartWorkers did not return after cancellation")
+	}
+
+	var attempts int
+	if err := db.QueryRow("SELECT COALESCE(SUM(attempts), 0) FROM notification_queue").Scan(&attempts); err != nil {
+		t.Fatalf("failed to sum attempts: %v", err)
+	}
+	if attempts != 10 {
+		t.Errorf("expected each notification to be processed once (10 attempts), got %d", attempts)
+	}
+}
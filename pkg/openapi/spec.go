@@ -0,0 +1,761 @@
+// Package openapi describes the public Kotomi API as an OpenAPI 3 document.
+//
+// The spec is assembled by hand rather than generated, because swag only emits
+// Swagger 2.0. Keep it in step with the @Router annotations on the handlers.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Version is the OpenAPI specification version the document conforms to
+const Version = "3.0.3"
+
+// BearerAuth is the name of the JWT security scheme
+const BearerAuth = "BearerAuth"
+
+// Document is the root of an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info carries the API metadata
+type Info struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version"`
+	Contact     *Contact `json:"contact,omitempty"`
+	License     *License `json:"license,omitempty"`
+}
+
+// Contact is the API support contact
+type Contact struct {
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// License is the API license
+type License struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Server is a base URL the paths are relative to
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations in the UI
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations available on a path
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+// Operations returns the item's operations keyed by lowercase HTTP method
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+	for method, op := range map[string]*Operation{"get": p.Get, "post": p.Post, "put": p.Put, "delete": p.Delete} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// Operation is a single API operation on a path
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's body
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response status
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType pairs a content type with its schema
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of the OpenAPI schema object the spec uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Components holds the reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how requests authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+	specErr  error
+)
+
+// Handler serves the spec as JSON. The document is built and encoded once.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		specOnce.Do(func() {
+			specJSON, specErr = json.MarshalIndent(Spec(), "", "  ")
+		})
+		if specErr != nil {
+			http.Error(w, "Failed to encode API spec", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(specJSON)
+	})
+}
+
+// Spec builds the OpenAPI document for the comment, reaction, subscription and auth APIs
+func Spec() *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "Kotomi API",
+			Description: "A comment and reaction system API for static sites",
+			Version:     "1.0",
+			Contact:     &Contact{Name: "API Support", URL: "https://github.com/saasuke-labs/kotomi", Email: "support@kotomi.dev"},
+			License:     &License{Name: "MIT", URL: "https://opensource.org/licenses/MIT"},
+		},
+		Servers: []Server{{URL: "/api/v1"}},
+		Tags: []Tag{
+			{Name: "comments", Description: "Reading and writing comments"},
+			{Name: "reactions", Description: "Reactions on comments and pages"},
+			{Name: "subscriptions", Description: "Email notifications of new comments on a page"},
+			{Name: "auth", Description: "Kotomi-managed authentication"},
+		},
+		Paths: paths(),
+		Components: Components{
+			Schemas: schemas(),
+			SecuritySchemes: map[string]*SecurityScheme{
+				BearerAuth: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "JWT issued by the site or by Kotomi, sent as \"Authorization: Bearer {token}\"",
+				},
+			},
+		},
+	}
+}
+
+func paths() map[string]*PathItem {
+	return map[string]*PathItem{
+		"/site/{siteId}/page/{pageId}/comments": {
+			Get: &Operation{
+				Tags: []string{"comments"}, OperationID: "getPageComments",
+				Summary:     "Get comments for a page",
+				Description: "Retrieve the approved comments for a specific page, each with its reaction counts. The site owner (admin session) also sees pending and rejected comments.",
+				Parameters: []Parameter{pathParam("siteId", "Site ID"), pathParam("pageId", "Page ID"), renderParam(),
+					{Name: "order", In: "query", Description: "Comment order (default oldest)", Schema: &Schema{Type: "string", Enum: []string{"oldest", "newest", "most_reacted"}}},
+					{Name: "since", In: "query", Description: "Only return comments created or updated after this time, oldest first, as a CommentsSinceResponse", Schema: &Schema{Type: "string", Format: "date-time"}},
+					{Name: "If-None-Match", In: "header", Description: "ETag from a previous response", Schema: str()}},
+				Responses: map[string]*Response{
+					"200": jsonResponse("Comments on the page, with ETag, Last-Modified and Cache-Control headers", arrayOf(ref("CommentResponse"))),
+					"304": {Description: "The comments haven't changed since the ETag in If-None-Match"},
+					"400": errorResponse("Unsupported order or invalid since"),
+					"500": errorResponse("Failed to retrieve comments or reaction counts"),
+				},
+			},
+			Post: &Operation{
+				Tags: []string{"comments"}, OperationID: "createComment",
+				Summary:     "Create a comment",
+				Description: "Create a new comment on a page. The author is taken from the JWT.",
+				Parameters: []Parameter{
+					pathParam("siteId", "Site ID"), pathParam("pageId", "Page ID"),
+					{Name: "Idempotency-Key", In: "header", Description: "Client-generated key; replays within an hour return the original comment", Schema: str()},
+				},
+				RequestBody: jsonBody("Comment to create", object(map[string]*Schema{
+					"text":      str(),
+					"parent_id": str(),
+				}, "text")),
+				Responses: map[string]*Response{
+					"200": jsonResponse("The created comment", ref("Comment")),
+					"400": errorResponse("Invalid request body"),
+					"401": errorResponse("Authentication required"),
+					"403": errorResponse("Author is blocked on this site"),
+					"413": errorResponse("Request body too large"),
+					"429": errorResponse("Author is within the site's comment cooldown; see Retry-After"),
+					"500": errorResponse("Failed to create comment"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/site/{siteId}/comments/{commentId}": {
+			Get: &Operation{
+				Tags: []string{"comments"}, OperationID: "getComment",
+				Summary:     "Get a comment",
+				Description: "Retrieve a single comment and its reaction counts. Pending comments are only visible to their author or the site owner.",
+				Parameters:  append(commentParams(), renderParam()),
+				Responses: map[string]*Response{
+					"200": jsonResponse("The comment", ref("CommentResponse")),
+					"404": errorResponse("Comment not found"),
+				},
+			},
+			Put: &Operation{
+				Tags: []string{"comments"}, OperationID: "updateComment",
+				Summary:     "Update a comment",
+				Description: "Update the text of your own comment",
+				Parameters:  commentParams(),
+				RequestBody: jsonBody("Updated comment text, and the updated_at of the version being edited", object(map[string]*Schema{
+					"text":       str(),
+					"updated_at": {Type: "string", Format: "date-time"},
+				}, "text")),
+				Responses: map[string]*Response{
+					"200": jsonResponse("The updated comment", ref("Comment")),
+					"401": errorResponse("Authentication required"),
+					"403": errorResponse("Not the comment author"),
+					"404": errorResponse("Comment not found"),
+					"409": errorResponse("Comment changed since updated_at; refetch and retry"),
+					"413": errorResponse("Request body too large"),
+				},
+				Security: bearer(),
+			},
+			Delete: &Operation{
+				Tags: []string{"comments"}, OperationID: "deleteComment",
+				Summary:     "Delete a comment",
+				Description: "Delete your own comment",
+				Parameters:  commentParams(),
+				Responses: map[string]*Response{
+					"204": {Description: "Comment deleted"},
+					"401": errorResponse("Authentication required"),
+					"403": errorResponse("Not the comment author"),
+					"404": errorResponse("Comment not found"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/site/{siteId}/comments/{commentId}/report": {
+			Post: &Operation{
+				Tags: []string{"comments"}, OperationID: "reportComment",
+				Summary:     "Report a comment",
+				Description: "Report an abusive comment. Comments reaching the site's report threshold go back to pending review.",
+				Parameters:  commentParams(),
+				RequestBody: &RequestBody{
+					Description: "Optional reason for the report",
+					Content:     map[string]MediaType{"application/json": {Schema: object(map[string]*Schema{"reason": str()})}},
+				},
+				Responses: map[string]*Response{
+					"200": jsonResponse("The recorded report", ref("Report")),
+					"401": errorResponse("Authentication required"),
+					"404": errorResponse("Comment not found"),
+					"409": errorResponse("Comment already reported"),
+					"413": errorResponse("Request body too large"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/site/{siteId}/page/{pageId}/comments/search": {
+			Get: &Operation{
+				Tags: []string{"comments"}, OperationID: "searchPageComments",
+				Summary:     "Search comments on a page",
+				Description: "Search the approved comments on a page by text, newest first. Each result has an HTML snippet with matches wrapped in <mark>.",
+				Parameters: []Parameter{pathParam("siteId", "Site ID"), pathParam("pageId", "Page ID"),
+					{Name: "q", In: "query", Required: true, Description: "Search text (3 to 100 characters)", Schema: str()},
+					{Name: "limit", In: "query", Description: "Maximum results (default 20, max 50)", Schema: &Schema{Type: "integer"}}},
+				Responses: map[string]*Response{
+					"200": jsonResponse("Matching comments", ref("CommentSearchResults")),
+					"400": errorResponse("Query too short or too long, or invalid limit"),
+					"500": errorResponse("Failed to search comments"),
+				},
+			},
+		},
+		"/site/{siteId}/comment-counts": {
+			Get: &Operation{
+				Tags: []string{"comments"}, OperationID: "getCommentCounts",
+				Summary:     "Get comment counts for pages",
+				Description: "Get the number of approved comments on each of a site's pages, e.g. for badges next to article links. Pages without comments are returned with 0.",
+				Parameters: []Parameter{pathParam("siteId", "Site ID"),
+					{Name: "pages", In: "query", Required: true, Description: "Comma-separated page IDs (at most 1000)", Schema: str()}},
+				Responses: map[string]*Response{
+					"200": jsonResponse("Approved comment counts by page ID", ref("CommentCounts")),
+					"400": errorResponse("Missing pages or too many pages"),
+					"500": errorResponse("Failed to count comments"),
+				},
+			},
+		},
+		"/site/{siteId}/users/me/comments": {
+			Get: &Operation{
+				Tags: []string{"comments"}, OperationID: "getMyComments",
+				Summary:     "List my comments",
+				Description: "List the authenticated user's comments on a site across all pages, newest first, with each comment's page path. Pending and rejected comments are included.",
+				Parameters: []Parameter{pathParam("siteId", "Site ID"),
+					{Name: "limit", In: "query", Description: "Page size (default 20, max 100)", Schema: &Schema{Type: "integer"}},
+					{Name: "offset", In: "query", Description: "Number of comments to skip", Schema: &Schema{Type: "integer"}}},
+				Responses: map[string]*Response{
+					"200": jsonResponse("A page of the user's comments", ref("MyComments")),
+					"400": errorResponse("Invalid limit or offset"),
+					"401": errorResponse("Authentication required"),
+					"500": errorResponse("Failed to retrieve comments"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/site/{siteId}/page/{pageId}/subscribe": {
+			Get:    pageSubscription("getPageSubscription", "Get page subscription", "Report whether the authenticated user is notified of new comments on a page", "Failed to load subscription"),
+			Post:   pageSubscription("subscribePage", "Subscribe to a page", "Notify the authenticated user by email of new comments on a page, including after an earlier unsubscribe", "Failed to subscribe"),
+			Delete: pageSubscription("unsubscribePage", "Unsubscribe from a page", "Stop notifying the authenticated user of new comments on a page. Commenting on the page again doesn't resubscribe them.", "Failed to unsubscribe"),
+		},
+		"/site/{siteId}/allowed-reactions": {
+			Get: &Operation{
+				Tags: []string{"reactions"}, OperationID: "getAllowedReactions",
+				Summary:     "Get allowed reactions",
+				Description: "Retrieve the reactions a site allows",
+				Parameters: []Parameter{
+					pathParam("siteId", "Site ID"),
+					{Name: "type", In: "query", Description: "Reaction type filter", Schema: &Schema{Type: "string", Enum: []string{"page", "comment"}}},
+				},
+				Responses: map[string]*Response{
+					"200": jsonResponse("Allowed reactions", arrayOf(ref("AllowedReaction"))),
+					"500": errorResponse("Failed to retrieve allowed reactions"),
+				},
+			},
+		},
+		"/site/{siteId}/comments/{commentId}/reactions": {
+			Get: &Operation{
+				Tags: []string{"reactions"}, OperationID: "getCommentReactions",
+				Summary:     "Get reactions on a comment",
+				Description: "List the comment's reactions oldest first, one page at a time. Use the counts endpoint for totals.",
+				Parameters:  append(commentParams(), reactionsPageParams()...),
+				Responses: map[string]*Response{
+					"200": jsonResponse("A page of reactions on the comment", arrayOf(ref("Reaction"))),
+					"400": errorResponse("Invalid limit or offset"),
+					"500": errorResponse("Failed to retrieve reactions"),
+				},
+			},
+			Post: reactionToggle("addCommentReaction", "React to a comment", commentParams()),
+		},
+		"/site/{siteId}/comments/{commentId}/reactions/counts": {
+			Get: reactionCounts("getCommentReactionCounts", "Get reaction counts for a comment", commentParams()),
+		},
+		"/site/{siteId}/pages/{pageId}/reactions": {
+			Get: &Operation{
+				Tags: []string{"reactions"}, OperationID: "getPageReactions",
+				Summary:     "Get reactions on a page",
+				Description: "List the page's reactions oldest first, one page at a time. Use the counts endpoint for totals.",
+				Parameters:  append(pageParams(), reactionsPageParams()...),
+				Responses: map[string]*Response{
+					"200": jsonResponse("A page of reactions on the page", arrayOf(ref("Reaction"))),
+					"400": errorResponse("Invalid limit or offset"),
+					"500": errorResponse("Failed to retrieve reactions"),
+				},
+			},
+			Post: reactionToggle("addPageReaction", "React to a page", pageParams()),
+		},
+		"/site/{siteId}/pages/{pageId}/reactions/counts": {
+			Get: reactionCounts("getPageReactionCounts", "Get reaction counts for a page", pageParams()),
+		},
+		"/site/{siteId}/reactions/{reactionId}": {
+			Delete: &Operation{
+				Tags: []string{"reactions"}, OperationID: "removeReaction",
+				Summary:    "Remove a reaction",
+				Parameters: []Parameter{pathParam("siteId", "Site ID"), pathParam("reactionId", "Reaction ID")},
+				Responses: map[string]*Response{
+					"204": {Description: "Reaction removed"},
+					"401": errorResponse("Authentication required"),
+					"500": errorResponse("Failed to remove reaction"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/auth/login": {
+			Get: &Operation{
+				Tags: []string{"auth"}, OperationID: "login",
+				Summary:     "Login with Auth0",
+				Description: "Redirect to Auth0 for login (Kotomi auth mode only)",
+				Parameters: []Parameter{
+					{Name: "siteId", In: "query", Description: "Site ID", Required: true, Schema: str()},
+					{Name: "redirect_uri", In: "query", Description: "Redirect URI after login", Schema: str()},
+				},
+				Responses: map[string]*Response{
+					"302": {Description: "Redirect to Auth0"},
+					"400": errorResponse("Missing site ID"),
+				},
+			},
+		},
+		"/auth/callback": {
+			Get: &Operation{
+				Tags: []string{"auth"}, OperationID: "authCallback",
+				Summary: "Auth0 callback",
+				Parameters: []Parameter{
+					{Name: "code", In: "query", Description: "Authorization code", Required: true, Schema: str()},
+					{Name: "state", In: "query", Description: "State parameter", Required: true, Schema: str()},
+				},
+				Responses: map[string]*Response{
+					"200": jsonResponse("The signed-in user and tokens", ref("AuthResponse")),
+					"400": errorResponse("Invalid callback"),
+				},
+			},
+		},
+		"/auth/logout": {
+			Post: &Operation{
+				Tags: []string{"auth"}, OperationID: "logout",
+				Summary: "Logout",
+				Responses: map[string]*Response{
+					"200": jsonResponse("Logged out", object(map[string]*Schema{"message": str()})),
+					"401": errorResponse("No token provided"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/auth/user": {
+			Get: &Operation{
+				Tags: []string{"auth"}, OperationID: "getCurrentUser",
+				Summary: "Get current user",
+				Responses: map[string]*Response{
+					"200": jsonResponse("The authenticated user", ref("KotomiAuthUser")),
+					"401": errorResponse("Unauthorized"),
+				},
+				Security: bearer(),
+			},
+		},
+		"/auth/config": {
+			Get: &Operation{
+				Tags: []string{"auth"}, OperationID: "getAuthConfig",
+				Summary:     "Get auth config",
+				Description: "Tells clients which auth flow a site uses and how comment text is interpreted (content_policy: plain, markdown or limited-html), so the widget can pick its editor",
+				Parameters:  []Parameter{{Name: "siteId", In: "query", Description: "Site ID", Required: true, Schema: str()}},
+				Responses: map[string]*Response{
+					"200": jsonResponse("Public auth configuration", object(map[string]*Schema{
+						"site_id":         str(),
+						"auth_mode":       str(),
+						"auth0_domain":    str(),
+						"auth0_client_id": str(),
+						"content_policy":  {Type: "string", Enum: []string{"plain", "markdown", "limited-html"}},
+					})),
+					"400": errorResponse("siteId is required"),
+					"404": errorResponse("Site not found or auth not configured"),
+				},
+			},
+		},
+		"/auth/{siteId}/refresh": {
+			Post: &Operation{
+				Tags: []string{"auth"}, OperationID: "refreshToken",
+				Summary:     "Refresh access token",
+				Description: "Issue a new access token for a session; the refresh token is rotated when close to expiry",
+				Parameters:  []Parameter{pathParam("siteId", "Site ID")},
+				RequestBody: jsonBody("The session's refresh token", object(map[string]*Schema{"refresh_token": str()}, "refresh_token")),
+				Responses: map[string]*Response{
+					"200": jsonResponse("New tokens", ref("RefreshResponse")),
+					"401": errorResponse("Invalid or expired refresh token"),
+				},
+			},
+		},
+		"/auth/{siteId}/logout-all": {
+			Post: &Operation{
+				Tags: []string{"auth"}, OperationID: "logoutAll",
+				Summary:    "Logout everywhere",
+				Parameters: []Parameter{pathParam("siteId", "Site ID")},
+				Responses: map[string]*Response{
+					"200": jsonResponse("Sessions revoked", object(map[string]*Schema{
+						"message":          str(),
+						"sessions_removed": {Type: "integer"},
+					})),
+					"401": errorResponse("Unauthorized"),
+				},
+				Security: bearer(),
+			},
+		},
+	}
+}
+
+func schemas() map[string]*Schema {
+	dateTime := func() *Schema { return &Schema{Type: "string", Format: "date-time"} }
+	return map[string]*Schema{
+		"Comment": object(map[string]*Schema{
+			"id":                str(),
+			"site_id":           str(),
+			"author":            str(),
+			"author_id":         str(),
+			"author_email":      str(),
+			"author_avatar_url": {Type: "string", Description: "The author's own avatar, when they have one"},
+			"author_verified":   {Type: "boolean"},
+			"author_reputation": {Type: "integer"},
+			"report_count":      {Type: "integer"},
+			"page_id":           {Type: "string", Description: "Only in the user's own comment list"},
+			"page_path":         {Type: "string", Description: "Only in the user's own comment list"},
+			"text":              str(),
+			"parent_id":         str(),
+			"status":            {Type: "string", Enum: []string{"pending", "approved", "rejected"}},
+			"moderated_by":      str(),
+			"moderated_at":      dateTime(),
+			"language":          {Type: "string", Description: "ISO 639-1 code detected when the comment was posted"},
+			"sentiment":         {Type: "string", Enum: []string{"positive", "neutral", "negative"}, Description: "Reported by AI moderation"},
+			"attachments":       {Type: "array", Items: ref("Attachment"), Description: "Images attached when posting (at most 4)"},
+			"created_at":        dateTime(),
+			"updated_at":        dateTime(),
+		}, "id", "author", "text", "status", "created_at", "updated_at"),
+		"Attachment": object(map[string]*Schema{
+			"id":         str(),
+			"comment_id": str(),
+			"url":        {Type: "string", Description: "Absolute http(s) URL of the image"},
+			"mime_type":  {Type: "string", Enum: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}},
+			"width":      {Type: "integer"},
+			"height":     {Type: "integer"},
+			"created_at": dateTime(),
+		}, "url", "mime_type"),
+		"CommentResponse": {AllOf: []*Schema{
+			ref("Comment"),
+			object(map[string]*Schema{
+				"reactions":     arrayOf(ref("ReactionCount")),
+				"rendered_html": {Type: "string", Description: "Sanitized HTML rendered from the comment's Markdown; only with render=html"},
+				"avatar":        {Type: "string", Description: "author_avatar_url, or a Gravatar for author_email when the author has none and the site allows it"},
+			}, "reactions"),
+		}},
+		"CommentsSinceResponse": object(map[string]*Schema{
+			"comments":    arrayOf(ref("CommentResponse")),
+			"server_time": {Type: "string", Format: "date-time", Description: "Pass as the next since"},
+		}, "comments", "server_time"),
+		"CommentSearchResults": object(map[string]*Schema{
+			"query": str(),
+			"results": arrayOf(&Schema{AllOf: []*Schema{
+				ref("Comment"),
+				object(map[string]*Schema{
+					"snippet": {Type: "string", Description: "HTML excerpt around the first match, with matches wrapped in <mark>"},
+				}, "snippet"),
+			}}),
+		}, "query", "results"),
+		"CommentCounts": object(map[string]*Schema{
+			"counts": {Type: "object", AdditionalProperties: &Schema{Type: "integer"}},
+		}, "counts"),
+		"MyComments": object(map[string]*Schema{
+			"comments": arrayOf(ref("Comment")),
+			"limit":    {Type: "integer"},
+			"offset":   {Type: "integer"},
+		}, "comments", "limit", "offset"),
+		"ReactionCount": object(map[string]*Schema{
+			"name":  str(),
+			"emoji": str(),
+			"count": {Type: "integer"},
+		}, "name", "emoji", "count"),
+		"ReactionCountWithMine": {AllOf: []*Schema{
+			ref("ReactionCount"),
+			object(map[string]*Schema{"allowed_reaction_id": str(), "mine": {Type: "boolean"}}),
+		}},
+		"AllowedReaction": object(map[string]*Schema{
+			"id":            str(),
+			"site_id":       str(),
+			"name":          str(),
+			"emoji":         str(),
+			"reaction_type": {Type: "string", Enum: []string{"page", "comment", "both"}},
+			"created_at":    dateTime(),
+			"updated_at":    dateTime(),
+		}, "id", "site_id", "name", "emoji", "reaction_type"),
+		"Reaction": object(map[string]*Schema{
+			"id":                  str(),
+			"page_id":             str(),
+			"comment_id":          str(),
+			"allowed_reaction_id": str(),
+			"user_id":             str(),
+			"created_at":          dateTime(),
+		}, "id", "allowed_reaction_id", "user_id", "created_at"),
+		"PageSubscription": object(map[string]*Schema{
+			"site_id":    str(),
+			"page_id":    str(),
+			"subscribed": {Type: "boolean"},
+		}, "site_id", "page_id", "subscribed"),
+		"Report": object(map[string]*Schema{
+			"id":               str(),
+			"comment_id":       str(),
+			"reporter_user_id": str(),
+			"reason":           str(),
+			"created_at":       dateTime(),
+		}, "id", "comment_id", "reporter_user_id", "created_at"),
+		"KotomiAuthUser": object(map[string]*Schema{
+			"id":          str(),
+			"site_id":     str(),
+			"email":       str(),
+			"auth0_sub":   str(),
+			"name":        str(),
+			"avatar_url":  str(),
+			"is_verified": {Type: "boolean"},
+			"created_at":  dateTime(),
+			"updated_at":  dateTime(),
+		}, "id", "site_id", "name"),
+		"AuthResponse": object(map[string]*Schema{
+			"user":          ref("KotomiAuthUser"),
+			"token":         str(),
+			"refresh_token": str(),
+			"expires_at":    dateTime(),
+		}, "user", "token", "expires_at"),
+		"RefreshResponse": object(map[string]*Schema{
+			"token":              str(),
+			"refresh_token":      str(),
+			"expires_at":         dateTime(),
+			"refresh_expires_at": dateTime(),
+		}, "token", "expires_at"),
+		"Error": object(map[string]*Schema{
+			"code":       str(),
+			"message":    str(),
+			"details":    str(),
+			"request_id": str(),
+		}, "code", "message"),
+		"ErrorResponse": object(map[string]*Schema{"error": ref("Error")}, "error"),
+	}
+}
+
+func reactionToggle(operationID, summary string, params []Parameter) *Operation {
+	return &Operation{
+		Tags: []string{"reactions"}, OperationID: operationID,
+		Summary:     summary,
+		Description: "Add a reaction, or remove it if you already left the same one",
+		Parameters:  params,
+		RequestBody: jsonBody("The reaction to toggle", object(map[string]*Schema{"allowed_reaction_id": str()}, "allowed_reaction_id")),
+		Responses: map[string]*Response{
+			"200": jsonResponse("The added reaction", ref("Reaction")),
+			"204": {Description: "Reaction toggled off"},
+			"400": errorResponse("Reaction not allowed on this target"),
+			"401": errorResponse("Authentication required"),
+			"409": errorResponse("Reaction limit reached for this target"),
+			"413": errorResponse("Request body too large"),
+		},
+		Security: bearer(),
+	}
+}
+
+func reactionCounts(operationID, summary string, params []Parameter) *Operation {
+	return &Operation{
+		Tags: []string{"reactions"}, OperationID: operationID,
+		Summary:     summary,
+		Description: "Authenticated viewers also learn which reactions are theirs",
+		Parameters:  params,
+		Responses: map[string]*Response{
+			"200": jsonResponse("Reaction counts", arrayOf(ref("ReactionCountWithMine"))),
+			"500": errorResponse("Failed to retrieve reaction counts"),
+		},
+		Security: []map[string][]string{{}, {BearerAuth: {}}},
+	}
+}
+
+func pageSubscription(operationID, summary, description, failure string) *Operation {
+	return &Operation{
+		Tags: []string{"subscriptions"}, OperationID: operationID,
+		Summary:     summary,
+		Description: description,
+		Parameters:  pageParams(),
+		Responses: map[string]*Response{
+			"200": jsonResponse("The user's subscription to the page", ref("PageSubscription")),
+			"401": errorResponse("Authentication required"),
+			"404": errorResponse("Page not found"),
+			"500": errorResponse(failure),
+		},
+		Security: bearer(),
+	}
+}
+
+func commentParams() []Parameter {
+	return []Parameter{pathParam("siteId", "Site ID"), pathParam("commentId", "Comment ID")}
+}
+
+func pageParams() []Parameter {
+	return []Parameter{pathParam("siteId", "Site ID"), pathParam("pageId", "Page ID")}
+}
+
+// reactionsPageParams are the pagination parameters of the reaction lists
+func reactionsPageParams() []Parameter {
+	return []Parameter{
+		{Name: "limit", In: "query", Description: "Page size (default 50, max 200)", Schema: &Schema{Type: "integer"}},
+		{Name: "offset", In: "query", Description: "Number of reactions to skip", Schema: &Schema{Type: "integer"}},
+	}
+}
+
+func renderParam() Parameter {
+	return Parameter{Name: "render", In: "query", Description: "Set to html to include rendered_html", Schema: &Schema{Type: "string", Enum: []string{"html"}}}
+}
+
+func pathParam(name, description string) Parameter {
+	return Parameter{Name: name, In: "path", Description: description, Required: true, Schema: str()}
+}
+
+func bearer() []map[string][]string {
+	return []map[string][]string{{BearerAuth: {}}}
+}
+
+func jsonBody(description string, schema *Schema) *RequestBody {
+	return &RequestBody{Description: description, Required: true, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+}
+
+func jsonResponse(description string, schema *Schema) *Response {
+	return &Response{Description: description, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+}
+
+func errorResponse(description string) *Response {
+	return jsonResponse(description, ref("ErrorResponse"))
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func str() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func arrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+func object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
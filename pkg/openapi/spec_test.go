@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	versionPattern    = regexp.MustCompile(`^3\.0\.\d+$`)
+	statusPattern     = regexp.MustCompile(`^([1-5][0-9][0-9]|[1-5]XX|default)$`)
+	pathParamPattern  = regexp.MustCompile(`\{([^}]+)\}`)
+	componentNameRule = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+)
+
+// fetchSpec requests the spec through Handler and decodes it generically, so the
+// checks below see exactly what clients receive
+func fetchSpec(t *testing.T) map[string]interface{} {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/swagger.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+// TestSpec_ValidOpenAPI3 checks the served document against the structural rules
+// of the OpenAPI 3.0 schema: required fields, allowed values, unique operation
+// IDs, declared path parameters and resolvable references
+func TestSpec_ValidOpenAPI3(t *testing.T) {
+	doc := fetchSpec(t)
+
+	allowedRoot := map[string]bool{"openapi": true, "info": true, "servers": true, "paths": true, "components": true, "security": true, "tags": true, "externalDocs": true}
+	for key := range doc {
+		if !allowedRoot[key] && !strings.HasPrefix(key, "x-") {
+			t.Errorf("Unexpected root field %q", key)
+		}
+	}
+
+	if v, _ := doc["openapi"].(string); !versionPattern.MatchString(v) {
+		t.Errorf("Expected openapi 3.0.x, got %q", v)
+	}
+	info, _ := doc["info"].(map[string]interface{})
+	if info["title"] == "" || info["title"] == nil || info["version"] == "" || info["version"] == nil {
+		t.Errorf("info.title and info.version are required, got %v", info)
+	}
+
+	components, _ := doc["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+	schemes, _ := components["securitySchemes"].(map[string]interface{})
+	for name := range schemas {
+		if !componentNameRule.MatchString(name) {
+			t.Errorf("Invalid component name %q", name)
+		}
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	if len(paths) == 0 {
+		t.Fatal("Expected paths")
+	}
+
+	operationIDs := map[string]string{}
+	allowedMethods := map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+	for path, rawItem := range paths {
+		if !strings.HasPrefix(path, "/") {
+			t.Errorf("Path %q must start with /", path)
+		}
+		item, _ := rawItem.(map[string]interface{})
+		for method, rawOp := range item {
+			if !allowedMethods[method] {
+				t.Errorf("%s: unexpected method %q", path, method)
+				continue
+			}
+			op, _ := rawOp.(map[string]interface{})
+			where := strings.ToUpper(method) + " " + path
+
+			if id, _ := op["operationId"].(string); id != "" {
+				if other, dup := operationIDs[id]; dup {
+					t.Errorf("%s: operationId %q already used by %s", where, id, other)
+				}
+				operationIDs[id] = where
+			}
+
+			declared := map[string]bool{}
+			params, _ := op["parameters"].([]interface{})
+			for _, rawParam := range params {
+				param, _ := rawParam.(map[string]interface{})
+				name, _ := param["name"].(string)
+				in, _ := param["in"].(string)
+				switch in {
+				case "path":
+					if param["required"] != true {
+						t.Errorf("%s: path parameter %q must be required", where, name)
+					}
+					declared[name] = true
+				case "query", "header", "cookie":
+				default:
+					t.Errorf("%s: parameter %q has invalid location %q", where, name, in)
+				}
+				if _, ok := param["schema"].(map[string]interface{}); !ok {
+					t.Errorf("%s: parameter %q needs a schema", where, name)
+				}
+			}
+			for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+				if !declared[match[1]] {
+					t.Errorf("%s: path parameter %q is not declared", where, match[1])
+				}
+			}
+
+			responses, _ := op["responses"].(map[string]interface{})
+			if len(responses) == 0 {
+				t.Errorf("%s: at least one response is required", where)
+			}
+			for status, rawResp := range responses {
+				if !statusPattern.MatchString(status) {
+					t.Errorf("%s: invalid response key %q", where, status)
+				}
+				resp, _ := rawResp.(map[string]interface{})
+				if d, _ := resp["description"].(string); d == "" {
+					t.Errorf("%s %s: response description is required", where, status)
+				}
+			}
+
+			security, _ := op["security"].([]interface{})
+			for _, rawReq := range security {
+				req, _ := rawReq.(map[string]interface{})
+				for scheme := range req {
+					if _, ok := schemes[scheme]; !ok {
+						t.Errorf("%s: security scheme %q is not defined", where, scheme)
+					}
+				}
+			}
+		}
+	}
+
+	// Every $ref must point at a defined component schema
+	var walk func(v interface{}, at string)
+	walk = func(v interface{}, at string) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if name == ref {
+					t.Errorf("%s: unsupported reference %q", at, ref)
+				} else if _, ok := schemas[name]; !ok {
+					t.Errorf("%s: reference %q does not resolve", at, ref)
+				}
+			}
+			for key, child := range v {
+				walk(child, at+"/"+key)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child, at)
+			}
+		}
+	}
+	walk(doc, "#")
+}
+
+// TestSpec_BearerAuth checks the security scheme the UI's Authorize button uses
+func TestSpec_BearerAuth(t *testing.T) {
+	spec := Spec()
+	scheme := spec.Components.SecuritySchemes[BearerAuth]
+	if scheme == nil || scheme.Type != "http" || scheme.Scheme != "bearer" {
+		t.Fatalf("Expected an http bearer scheme, got %+v", scheme)
+	}
+
+	post := spec.Paths["/site/{siteId}/page/{pageId}/comments"].Post
+	if len(post.Security) != 1 || post.Security[0][BearerAuth] == nil {
+		t.Errorf("Expected creating a comment to require BearerAuth, got %v", post.Security)
+	}
+	if get := spec.Paths["/site/{siteId}/page/{pageId}/comments"].Get; len(get.Security) != 0 {
+		t.Errorf("Expected reading comments to be public, got %v", get.Security)
+	}
+}
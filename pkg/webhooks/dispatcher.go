@@ -0,0 +1,277 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+	"github.com/saasuke-labs/kotomi/pkg/models"
+)
+
+// Delivery request headers
+const (
+	SignatureHeader = "X-Kotomi-Signature" // "sha256=" + hex HMAC-SHA256 of the body keyed by the subscription secret
+	EventHeader     = "X-Kotomi-Event"
+	DeliveryHeader  = "X-Kotomi-Delivery" // The event ID, the same on every retry
+)
+
+const (
+	// DefaultMaxAttempts is how many times an event is sent before giving up
+	DefaultMaxAttempts = 4
+	// DefaultBackoff is the wait before the first retry; it doubles after each failure
+	DefaultBackoff = 2 * time.Second
+	// deliveryTimeout bounds a single attempt
+	deliveryTimeout = 10 * time.Second
+)
+
+// Payload is the JSON body of every delivery
+type Payload struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	SiteID    string          `json:"site_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CommentData is the data of comment events. The author's email is never sent.
+type CommentData struct {
+	ID        string    `json:"id"`
+	PageID    string    `json:"page_id,omitempty"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Author    string    `json:"author"`
+	AuthorID  string    `json:"author_id"`
+	Text      string    `json:"text"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewCommentData builds comment event data; pageID is used when the comment
+// doesn't carry its page
+func NewCommentData(c comments.Comment, pageID string) CommentData {
+	if c.PageID != "" {
+		pageID = c.PageID
+	}
+	return CommentData{
+		ID:        c.ID,
+		PageID:    pageID,
+		ParentID:  c.ParentID,
+		Author:    c.Author,
+		AuthorID:  c.AuthorID,
+		Text:      c.Text,
+		Status:    c.Status,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// ReactionData is the data of reaction events
+type ReactionData struct {
+	ID                string    `json:"id"`
+	CommentID         string    `json:"comment_id,omitempty"`
+	PageID            string    `json:"page_id,omitempty"`
+	AllowedReactionID string    `json:"allowed_reaction_id"`
+	Name              string    `json:"name"`
+	Emoji             string    `json:"emoji"`
+	UserID            string    `json:"user_id"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// NewReactionData builds reaction event data
+func NewReactionData(r models.Reaction, allowed models.AllowedReaction) ReactionData {
+	return ReactionData{
+		ID:                r.ID,
+		CommentID:         r.CommentID,
+		PageID:            r.PageID,
+		AllowedReactionID: r.AllowedReactionID,
+		Name:              allowed.Name,
+		Emoji:             allowed.Emoji,
+		UserID:            r.UserID,
+		CreatedAt:         r.CreatedAt,
+	}
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher delivers events to a site's subscriptions in the background,
+// retrying failed deliveries with exponential backoff and logging every
+// attempt. A nil Dispatcher drops events, so callers needn't check for one.
+type Dispatcher struct {
+	store       *SubscriptionStore
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	wg          sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher with the default retry policy
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	return NewDispatcherWithRetry(db, DefaultMaxAttempts, DefaultBackoff)
+}
+
+// NewDispatcherWithRetry creates a dispatcher that makes up to maxAttempts
+// attempts per delivery, waiting backoff before the first retry
+func NewDispatcherWithRetry(db *sql.DB, maxAttempts int, backoff time.Duration) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Dispatcher{
+		store:       NewSubscriptionStore(db),
+		client:      &http.Client{Timeout: deliveryTimeout},
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+// Dispatch sends event to every subscription of the site that receives it.
+// It returns immediately; deliveries happen in the background.
+func (d *Dispatcher) Dispatch(siteID, event string, data interface{}) {
+	if d == nil {
+		return
+	}
+
+	// Marshal now so later changes to data by the caller aren't sent
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding %s event for site %s: %v", event, siteID, err)
+		return
+	}
+	payload := Payload{
+		ID:        uuid.NewString(),
+		Event:     event,
+		SiteID:    siteID,
+		CreatedAt: time.Now().UTC(),
+		Data:      raw,
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliverAll(payload)
+	}()
+}
+
+// Wait blocks until every dispatched event has been delivered or given up on,
+// or ctx is done
+func (d *Dispatcher) Wait(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliverAll sends the payload to each matching subscription concurrently
+func (d *Dispatcher) deliverAll(payload Payload) {
+	subs, err := d.store.ListForEvent(context.Background(), payload.SiteID, payload.Event)
+	if err != nil {
+		log.Printf("Error loading subscriptions for %s event on site %s: %v", payload.Event, payload.SiteID, err)
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding %s event for site %s: %v", payload.Event, payload.SiteID, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func(sub Subscription) {
+			defer wg.Done()
+			d.deliver(sub, payload, body)
+		}(sub)
+	}
+	wg.Wait()
+}
+
+// deliver posts body to one subscription until it gets a 2xx response or
+// runs out of attempts
+func (d *Dispatcher) deliver(sub Subscription, payload Payload, body []byte) {
+	signature := Sign(sub.Secret, body)
+	wait := d.backoff
+
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		statusCode, err := d.post(sub.URL, payload, body, signature)
+
+		delivery := &Delivery{
+			SubscriptionID: sub.ID,
+			EventID:        payload.ID,
+			Event:          payload.Event,
+			Attempt:        attempt,
+			StatusCode:     statusCode,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if recordErr := d.store.RecordDelivery(context.Background(), delivery); recordErr != nil {
+			log.Printf("Error recording webhook delivery: %v", recordErr)
+		}
+
+		if err == nil {
+			return
+		}
+		if attempt == d.maxAttempts {
+			log.Printf("Giving up on %s event %s for subscription %s after %d attempts: %v", payload.Event, payload.ID, sub.ID, attempt, err)
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post makes one delivery attempt, returning the response status and an
+// error unless the receiver answered 2xx
+func (d *Dispatcher) post(url string, payload Payload, body []byte, signature string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kotomi-Webhooks/1.0")
+	req.Header.Set(EventHeader, payload.Event)
+	req.Header.Set(DeliveryHeader, payload.ID)
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
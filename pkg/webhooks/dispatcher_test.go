@@ -0,0 +1,193 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saasuke-labs/kotomi/pkg/comments"
+)
+
+func createTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := comments.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	db := store.GetDB()
+	if _, err := db.Exec("INSERT INTO admin_users (id, email, name, auth0_sub) VALUES ('owner-1', 'owner@example.com', 'Owner', 'auth0|owner-1')"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO sites (id, owner_id, name) VALUES ('site-1', 'owner-1', 'Site')"); err != nil {
+		t.Fatalf("failed to create site: %v", err)
+	}
+	return db
+}
+
+// receivedRequest is what the test receiver saw
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func wait(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Wait(ctx); err != nil {
+		t.Fatalf("deliveries did not finish: %v", err)
+	}
+}
+
+func TestDispatchCommentCreated(t *testing.T) {
+	db := createTestDB(t)
+
+	var mu sync.Mutex
+	var received []receivedRequest
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := NewSubscriptionStore(db)
+	sub, err := store.Create(context.Background(), "site-1", receiver.URL, []string{EventCommentCreated})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	// Subscribed to other events only, so it must not be called
+	if _, err := store.Create(context.Background(), "site-1", receiver.URL+"/reactions", []string{EventReactionAdded}); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	dispatcher := NewDispatcherWithRetry(db, 3, time.Millisecond)
+	comment := comments.Comment{ID: "comment-1", Author: "Alice", AuthorID: "user-1", AuthorEmail: "alice@example.com", Text: "Hello", Status: "pending"}
+	dispatcher.Dispatch("site-1", EventCommentCreated, NewCommentData(comment, "page-1"))
+	wait(t, dispatcher)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(received))
+	}
+	got := received[0]
+	if signature := got.header.Get(SignatureHeader); signature != Sign(sub.Secret, got.body) {
+		t.Errorf("signature %q does not match body", signature)
+	}
+	if got.header.Get(EventHeader) != EventCommentCreated {
+		t.Errorf("expected event header %q, got %q", EventCommentCreated, got.header.Get(EventHeader))
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload.Event != EventCommentCreated || payload.SiteID != "site-1" || payload.ID != got.header.Get(DeliveryHeader) {
+		t.Errorf("unexpected payload envelope: %+v", payload)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload.Data, &data); err != nil {
+		t.Fatalf("failed to decode payload data: %v", err)
+	}
+	if data["id"] != "comment-1" || data["page_id"] != "page-1" || data["text"] != "Hello" {
+		t.Errorf("unexpected comment data: %v", data)
+	}
+	if _, ok := data["author_email"]; ok {
+		t.Error("author email must not be sent")
+	}
+
+	deliveries, err := store.ListDeliveries(context.Background(), sub.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].StatusCode != http.StatusNoContent || deliveries[0].Error != "" {
+		t.Errorf("expected one successful delivery, got %+v", deliveries)
+	}
+}
+
+func TestDispatchRetriesFailedDeliveries(t *testing.T) {
+	db := createTestDB(t)
+
+	var mu sync.Mutex
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		failing := calls < 3
+		mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := NewSubscriptionStore(db)
+	sub, err := store.Create(context.Background(), "site-1", receiver.URL, []string{EventCommentApproved})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	dispatcher := NewDispatcherWithRetry(db, 4, time.Millisecond)
+	dispatcher.Dispatch("site-1", EventCommentApproved, NewCommentData(comments.Comment{ID: "comment-1", Status: "approved"}, ""))
+	wait(t, dispatcher)
+
+	deliveries, err := store.ListDeliveries(context.Background(), sub.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(deliveries))
+	}
+	failed := 0
+	for _, d := range deliveries {
+		if d.EventID != deliveries[0].EventID {
+			t.Error("expected every attempt to share the event ID")
+		}
+		if d.StatusCode == http.StatusServiceUnavailable && d.Error != "" {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Errorf("expected 2 failed attempts, got %+v", deliveries)
+	}
+}
+
+func TestSubscriptionStoreValidation(t *testing.T) {
+	store := NewSubscriptionStore(createTestDB(t))
+	ctx := context.Background()
+
+	if _, err := store.Create(ctx, "site-1", "ftp://example.com/hook", []string{EventCommentCreated}); err == nil {
+		t.Error("expected non-http URL to be rejected")
+	}
+	if _, err := store.Create(ctx, "site-1", "https://example.com/hook", []string{"comment.deleted"}); err == nil {
+		t.Error("expected unknown event to be rejected")
+	}
+	if _, err := store.Create(ctx, "site-1", "https://example.com/hook", nil); err == nil {
+		t.Error("expected subscription without events to be rejected")
+	}
+	if err := store.Delete(ctx, "site-1", "missing"); err != ErrSubscriptionNotFound {
+		t.Errorf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+}
+
+func TestNilDispatcher(t *testing.T) {
+	var d *Dispatcher
+	d.Dispatch("site-1", EventCommentCreated, nil)
+	if err := d.Wait(context.Background()); err != nil {
+		t.Errorf("expected nil dispatcher to wait trivially, got %v", err)
+	}
+}
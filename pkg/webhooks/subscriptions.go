@@ -0,0 +1,292 @@
+// Package webhooks delivers comment and reaction events to URLs registered by
+// site owners, signing each request so receivers can verify it came from Kotomi.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event names
+const (
+	EventCommentCreated  = "comment.created"
+	EventCommentApproved = "comment.approved"
+	EventCommentRejected = "comment.rejected"
+	EventReactionAdded   = "reaction.added"
+)
+
+// Events lists every event a subscription can receive
+var Events = []string{EventCommentCreated, EventCommentApproved, EventCommentRejected, EventReactionAdded}
+
+// ErrSubscriptionNotFound is returned when a subscription doesn't exist on the site
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// Subscription is a URL that receives a site's events. The secret signs every
+// delivery and is only shown when the subscription is created.
+type Subscription struct {
+	ID        string    `json:"id"`
+	SiteID    string    `json:"site_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Delivery is one attempt to deliver an event to a subscription
+type Delivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	EventID        string    `json:"event_id"`
+	Event          string    `json:"event"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"status_code"` // 0 when no response was received
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ValidateURL checks that a subscription URL is an absolute http(s) URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// ValidateEvents checks that events is a non-empty list of known events and
+// returns it without duplicates
+func ValidateEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+	seen := make(map[string]bool)
+	var valid []string
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !isEvent(event) {
+			return nil, fmt.Errorf("unknown event %q: must be one of %s", event, strings.Join(Events, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	return valid, nil
+}
+
+func isEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionStore handles the event_subscriptions and event_deliveries tables
+type SubscriptionStore struct {
+	db *sql.DB
+}
+
+// NewSubscriptionStore creates a new subscription store
+func NewSubscriptionStore(db *sql.DB) *SubscriptionStore {
+	return &SubscriptionStore{db: db}
+}
+
+// Create registers a URL for the site's events with a newly generated secret
+func (s *SubscriptionStore) Create(ctx context.Context, siteID, rawURL string, events []string) (*Subscription, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+	events, err := ValidateEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate subscription secret: %w", err)
+	}
+
+	now := time.Now()
+	sub := &Subscription{
+		ID:        uuid.NewString(),
+		SiteID:    siteID,
+		URL:       rawURL,
+		Secret:    "whsec_" + hex.EncodeToString(random),
+		Events:    events,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO event_subscriptions (id, site_id, url, secret, events, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, sub.ID, sub.SiteID, sub.URL, sub.Secret, strings.Join(sub.Events, ","), sub.CreatedAt, sub.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Get returns one of the site's subscriptions
+func (s *SubscriptionStore) Get(ctx context.Context, siteID, id string) (*Subscription, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, site_id, url, secret, events, created_at, updated_at
+		FROM event_subscriptions
+		WHERE site_id = ? AND id = ?
+	`, siteID, id)
+	sub, err := scanSubscription(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+// ListBySite returns the site's subscriptions, oldest first
+func (s *SubscriptionStore) ListBySite(ctx context.Context, siteID string) ([]Subscription, error) {
+	return s.query(ctx, `
+		SELECT id, site_id, url, secret, events, created_at, updated_at
+		FROM event_subscriptions
+		WHERE site_id = ?
+		ORDER BY created_at ASC
+	`, siteID)
+}
+
+// ListForEvent returns the site's subscriptions that receive event
+func (s *SubscriptionStore) ListForEvent(ctx context.Context, siteID, event string) ([]Subscription, error) {
+	return s.query(ctx, `
+		SELECT id, site_id, url, secret, events, created_at, updated_at
+		FROM event_subscriptions
+		WHERE site_id = ? AND ',' || events || ',' LIKE ?
+		ORDER BY created_at ASC
+	`, siteID, "%,"+event+",%")
+}
+
+// Update changes a subscription's URL and events; the secret is kept
+func (s *SubscriptionStore) Update(ctx context.Context, siteID, id, rawURL string, events []string) (*Subscription, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+	events, err := ValidateEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE event_subscriptions SET url = ?, events = ?, updated_at = ?
+		WHERE site_id = ? AND id = ?
+	`, rawURL, strings.Join(events, ","), time.Now(), siteID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, ErrSubscriptionNotFound
+	}
+	return s.Get(ctx, siteID, id)
+}
+
+// Delete removes one of the site's subscriptions and its delivery log
+func (s *SubscriptionStore) Delete(ctx context.Context, siteID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM event_subscriptions WHERE site_id = ? AND id = ?`, siteID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM event_deliveries WHERE subscription_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete subscription deliveries: %w", err)
+	}
+	return nil
+}
+
+// RecordDelivery logs a delivery attempt
+func (s *SubscriptionStore) RecordDelivery(ctx context.Context, d *Delivery) error {
+	if d.ID == "" {
+		d.ID = uuid.NewString()
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO event_deliveries (id, subscription_id, event_id, event, attempt, status_code, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.ID, d.SubscriptionID, d.EventID, d.Event, d.Attempt, d.StatusCode, d.Error, d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns a subscription's most recent delivery attempts, newest first
+func (s *SubscriptionStore) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, subscription_id, event_id, event, attempt, status_code, error, created_at
+		FROM event_deliveries
+		WHERE subscription_id = ?
+		ORDER BY created_at DESC
+		LIMIT ?
+	`, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *SubscriptionStore) query(ctx context.Context, query string, args ...interface{}) ([]Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row scanner) (*Subscription, error) {
+	var sub Subscription
+	var events string
+	if err := row.Scan(&sub.ID, &sub.SiteID, &sub.URL, &sub.Secret, &events, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan subscription: %w", err)
+	}
+	sub.Events = strings.Split(events, ",")
+	return &sub, nil
+}
@@ -78,6 +78,8 @@ func setupTestEnvironment() error {
 		"TEST_MODE=true",
 		"RATE_LIMIT_GET=1000",  // High limit for E2E testing
 		"RATE_LIMIT_POST=1000", // High limit for E2E testing
+		"RATE_LIMIT_COMMENT_POST_LIMIT=1000",
+		"RATE_LIMIT_REACTION_POST_LIMIT=1000",
 	)
 	
 	// Always log to file for debugging